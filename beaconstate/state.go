@@ -0,0 +1,307 @@
+// Package beaconstate tracks the beacon chain state of the mock consensus client: the slot and
+// epoch, the checkpoints, the randao mixes and the proposers, and the validator balances and
+// the withdrawals sweep over them.
+package beaconstate
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// minSeedLookahead is the number of epochs the randao mix is behind the epoch it seeds.
+const minSeedLookahead = 1
+
+type Config struct {
+	GenesisTime   uint64
+	SlotTime      time.Duration
+	SlotsPerEpoch uint64
+	// Validators is the number of validators, to select proposers from and sweep for withdrawals.
+	Validators int
+	// Pending is the number of the last validators that are pending activation at genesis.
+	Pending int
+}
+
+// State is the beacon state of the mock chain. It is safe for concurrent use.
+type State struct {
+	cfg Config
+
+	mu        sync.RWMutex
+	slot      uint64
+	justified types.Checkpoint
+	finalized types.Checkpoint
+	genesis   types.Root
+	// whether epochs neither justify nor finalize, like in a period of non-finality
+	stalled bool
+	// randao mix by epoch, of which the one of the current epoch changes with every block
+	mixes map[uint64]types.Root
+
+	// balances of the validators in gwei, whether they are pending activation, and whether they
+	// exited or were slashed
+	balances []uint64
+	pending  []bool
+	exited   []bool
+	slashed  []bool
+	// withdrawal index of the next withdrawal, and validator the next sweep starts at
+	nextWithdrawalIndex     uint64
+	nextWithdrawalValidator uint64
+}
+
+// New returns the state at genesis. The randao mixes start out as the genesis block hash, and
+// the validators with the max effective balance.
+func New(cfg Config, genesis common.Hash) *State {
+	s := &State{
+		cfg:     cfg,
+		genesis: types.Root(genesis),
+		mixes:   map[uint64]types.Root{0: types.Root(genesis)},
+	}
+	s.resetBalances()
+	return s
+}
+
+func (s *State) Config() Config {
+	return s.cfg
+}
+
+// SlotTimestamp returns the start time of the slot, in seconds.
+func (s *State) SlotTimestamp(slot uint64) uint64 {
+	return s.cfg.GenesisTime + uint64((time.Duration(slot) * s.cfg.SlotTime).Seconds())
+}
+
+// SlotAt returns the slot at the time, negative before genesis.
+func (s *State) SlotAt(t time.Time) int64 {
+	genesis := time.Unix(int64(s.cfg.GenesisTime), 0)
+	return int64(math.Floor(float64(t.Sub(genesis)) / float64(s.cfg.SlotTime)))
+}
+
+// EpochAt returns the epoch of the slot.
+func (s *State) EpochAt(slot uint64) uint64 {
+	return slot / s.cfg.SlotsPerEpoch
+}
+
+// Slot returns the last processed slot.
+func (s *State) Slot() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slot
+}
+
+// Epoch returns the epoch of the last processed slot.
+func (s *State) Epoch() uint64 {
+	return s.EpochAt(s.Slot())
+}
+
+// ProcessSlot advances the state to the slot, with the head block at its start, and returns
+// true if it starts a new epoch. At the start of an epoch, the justified checkpoint becomes
+// final, and the head is justified as checkpoint of the new epoch: every epoch succeeds to
+// justify and finalize, unless finality is stalled. The active validators earn their rewards for
+// the epochs that passed.
+func (s *State) ProcessSlot(slot uint64, head common.Hash) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev := s.EpochAt(s.slot)
+	epoch := s.EpochAt(slot)
+	s.slot = slot
+	if epoch <= prev {
+		return false
+	}
+	if !s.stalled {
+		s.finalized = s.justified
+		s.justified = types.Checkpoint{Epoch: epoch, Root: types.Root(head)}
+	}
+	s.reward(epoch - prev)
+	// the mix of the new epoch starts out as that of the previous one
+	s.mixes[epoch] = s.mixes[prev]
+	for e := range s.mixes {
+		if e+minSeedLookahead+1 < epoch {
+			delete(s.mixes, e)
+		}
+	}
+	return true
+}
+
+// StallFinality stops the epochs from justifying and finalizing their checkpoints, until it is
+// called again with false, so the chain can reorg deeper than the last finalized checkpoint.
+func (s *State) StallFinality(stall bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stalled = stall
+}
+
+// Bootstrap moves the state to the start of the epoch of the checkpoint, with the checkpoint
+// both justified and finalized, to start mid-chain. The randao mixes restart from its root, and
+// the balances and the withdrawals sweep from genesis.
+func (s *State) Bootstrap(cp types.Checkpoint) {
+	s.Restore(Snapshot{Slot: cp.Epoch * s.cfg.SlotsPerEpoch, Justified: cp, Finalized: cp, RandaoMix: cp.Root})
+}
+
+// Snapshot is the part of the state to persist, to restore the state from after a restart.
+type Snapshot struct {
+	Slot      uint64           `json:"slot"`
+	Justified types.Checkpoint `json:"justified"`
+	Finalized types.Checkpoint `json:"finalized"`
+	RandaoMix types.Root       `json:"randaoMix"`
+
+	Balances                []uint64 `json:"balances,omitempty"`
+	Pending                 []uint64 `json:"pending,omitempty"`
+	Exited                  []uint64 `json:"exited,omitempty"`
+	Slashed                 []uint64 `json:"slashed,omitempty"`
+	NextWithdrawalIndex     uint64   `json:"nextWithdrawalIndex"`
+	NextWithdrawalValidator uint64   `json:"nextWithdrawalValidator"`
+}
+
+func (s *State) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := Snapshot{
+		Slot:                    s.slot,
+		Justified:               s.justified,
+		Finalized:               s.finalized,
+		RandaoMix:               s.mixes[s.EpochAt(s.slot)],
+		Balances:                append([]uint64(nil), s.balances...),
+		NextWithdrawalIndex:     s.nextWithdrawalIndex,
+		NextWithdrawalValidator: s.nextWithdrawalValidator,
+	}
+	for i := range s.balances {
+		if s.pending[i] {
+			snapshot.Pending = append(snapshot.Pending, uint64(i))
+		}
+		if s.exited[i] {
+			snapshot.Exited = append(snapshot.Exited, uint64(i))
+		}
+		if s.slashed[i] {
+			snapshot.Slashed = append(snapshot.Slashed, uint64(i))
+		}
+	}
+	return snapshot
+}
+
+// Restore returns the state to the snapshot. The randao mixes of earlier epochs are lost, and
+// restart from the current mix. Without balances of all validators, the balances and the sweep
+// restart from genesis.
+func (s *State) Restore(snapshot Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	epoch := s.EpochAt(snapshot.Slot)
+	s.slot = snapshot.Slot
+	s.justified = snapshot.Justified
+	s.finalized = snapshot.Finalized
+	s.mixes = map[uint64]types.Root{epoch: snapshot.RandaoMix}
+	if epoch > 0 {
+		s.mixes[epoch-1] = snapshot.RandaoMix
+	}
+	s.resetBalances()
+	if len(snapshot.Balances) != s.cfg.Validators {
+		return
+	}
+	copy(s.balances, snapshot.Balances)
+	for i := range s.pending {
+		s.pending[i] = false
+	}
+	for _, i := range snapshot.Pending {
+		if i < uint64(len(s.pending)) {
+			s.pending[i] = true
+		}
+	}
+	for _, i := range snapshot.Exited {
+		if i < uint64(len(s.exited)) {
+			s.exited[i] = true
+		}
+	}
+	for _, i := range snapshot.Slashed {
+		if i < uint64(len(s.slashed)) {
+			s.slashed[i] = true
+		}
+	}
+	s.nextWithdrawalIndex = snapshot.NextWithdrawalIndex
+	s.nextWithdrawalValidator = snapshot.NextWithdrawalValidator
+}
+
+// Justified returns the checkpoint of the current epoch, which attestations vote for.
+func (s *State) Justified() types.Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.justified
+}
+
+// Finalized returns the checkpoint of the previous epoch, the source of attestations.
+func (s *State) Finalized() types.Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.finalized
+}
+
+// MoveCheckpoint moves the checkpoint of the current epoch to another block, after a reorg of the
+// blocks at the start of the epoch, before attestations justified it.
+func (s *State) MoveCheckpoint(root common.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.justified.Root = types.Root(root)
+}
+
+// RandaoMix returns the current randao mix, the prev_randao of the next payload.
+func (s *State) RandaoMix() types.Root {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mixes[s.EpochAt(s.slot)]
+}
+
+// ProcessRandao mixes the randao reveal of the block of the current slot into the randao mix.
+func (s *State) ProcessRandao(reveal types.Signature) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	epoch := s.EpochAt(s.slot)
+	mix := s.mixes[epoch]
+	h := sha256.Sum256(reveal[:])
+	for i := range mix {
+		mix[i] ^= h[i]
+	}
+	s.mixes[epoch] = mix
+}
+
+// ProposerSeed returns the randao mix that seeds the proposers of the epoch, which changes when
+// the proposers do, unless the validator set does.
+func (s *State) ProposerSeed(epoch uint64) types.Root {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.seed(epoch)
+}
+
+func (s *State) seed(epoch uint64) types.Root {
+	if epoch > minSeedLookahead {
+		return s.mixes[epoch-minSeedLookahead-1]
+	}
+	return s.genesis
+}
+
+// Proposer returns the index of the validator that proposes in the slot, seeded by the
+// randao mix at the end of an earlier epoch, so the proposers of an epoch are known at its
+// start. The first epochs are seeded by the genesis mix. The proposer is an active validator:
+// the seed selects the first active one from its index on.
+func (s *State) Proposer(slot uint64) int {
+	if s.cfg.Validators == 0 {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mix := s.seed(s.EpochAt(slot))
+
+	var buf [40]byte
+	copy(buf[:32], mix[:])
+	binary.LittleEndian.PutUint64(buf[32:], slot)
+	seed := sha256.Sum256(buf[:])
+	n := uint64(s.cfg.Validators)
+	index := binary.LittleEndian.Uint64(seed[:8]) % n
+	for i := uint64(0); i < n; i++ {
+		if s.active(int((index + i) % n)) {
+			return int((index + i) % n)
+		}
+	}
+	return int(index)
+}
@@ -0,0 +1,180 @@
+package beaconstate
+
+import (
+	"testing"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func newState() *State {
+	return New(Config{GenesisTime: 1000, SlotTime: 2 * time.Second, SlotsPerEpoch: 4, Validators: 16}, common.Hash{0xaa})
+}
+
+func TestSlotClock(t *testing.T) {
+	s := newState()
+	require.Equal(t, uint64(1000), s.SlotTimestamp(0))
+	require.Equal(t, uint64(1010), s.SlotTimestamp(5))
+	require.Equal(t, int64(5), s.SlotAt(time.Unix(1010, 0)))
+	require.Equal(t, int64(5), s.SlotAt(time.Unix(1011, 0)))
+	require.Equal(t, int64(-2), s.SlotAt(time.Unix(996, 0)))
+	require.Equal(t, int64(-3), s.SlotAt(time.Unix(995, 0)))
+	require.Equal(t, uint64(1), s.EpochAt(5))
+}
+
+func TestCheckpoints(t *testing.T) {
+	s := newState()
+	for slot := uint64(1); slot < 4; slot++ {
+		require.False(t, s.ProcessSlot(slot, common.Hash{byte(slot)}))
+	}
+	require.Equal(t, types.Checkpoint{}, s.Justified())
+
+	require.True(t, s.ProcessSlot(4, common.Hash{0x04}))
+	require.Equal(t, types.Checkpoint{Epoch: 1, Root: types.Root{0x04}}, s.Justified())
+	require.Equal(t, types.Checkpoint{}, s.Finalized())
+
+	// a missed epoch start still transitions
+	require.False(t, s.ProcessSlot(5, common.Hash{0x05}))
+	require.True(t, s.ProcessSlot(9, common.Hash{0x09}))
+	require.Equal(t, uint64(9), s.Slot())
+	require.Equal(t, uint64(2), s.Epoch())
+	require.Equal(t, types.Checkpoint{Epoch: 2, Root: types.Root{0x09}}, s.Justified())
+	require.Equal(t, types.Checkpoint{Epoch: 1, Root: types.Root{0x04}}, s.Finalized())
+
+	// a reorg at the start of the epoch moves its checkpoint, which is then finalized
+	s.MoveCheckpoint(common.Hash{0x08})
+	require.Equal(t, types.Checkpoint{Epoch: 2, Root: types.Root{0x08}}, s.Justified())
+	require.True(t, s.ProcessSlot(12, common.Hash{0x0c}))
+	require.Equal(t, types.Checkpoint{Epoch: 2, Root: types.Root{0x08}}, s.Finalized())
+
+	// stalled epochs neither justify nor finalize
+	s.StallFinality(true)
+	require.True(t, s.ProcessSlot(16, common.Hash{0x10}))
+	require.True(t, s.ProcessSlot(20, common.Hash{0x14}))
+	require.Equal(t, types.Checkpoint{Epoch: 3, Root: types.Root{0x0c}}, s.Justified())
+	require.Equal(t, types.Checkpoint{Epoch: 2, Root: types.Root{0x08}}, s.Finalized())
+	s.StallFinality(false)
+	require.True(t, s.ProcessSlot(24, common.Hash{0x18}))
+	require.Equal(t, types.Checkpoint{Epoch: 6, Root: types.Root{0x18}}, s.Justified())
+	require.Equal(t, types.Checkpoint{Epoch: 3, Root: types.Root{0x0c}}, s.Finalized())
+}
+
+func TestRandao(t *testing.T) {
+	s := newState()
+	require.Equal(t, types.Root{0xaa}, s.RandaoMix())
+	proposers := make([]int, 8)
+	for slot := range proposers {
+		proposers[slot] = s.Proposer(uint64(slot))
+	}
+
+	s.ProcessSlot(1, common.Hash{})
+	s.ProcessRandao(types.Signature{0x01})
+	mix := s.RandaoMix()
+	require.NotEqual(t, types.Root{0xaa}, mix)
+
+	// the next epoch starts with the mix of the previous one
+	s.ProcessSlot(4, common.Hash{})
+	require.Equal(t, mix, s.RandaoMix())
+	s.ProcessRandao(types.Signature{0x02})
+	require.NotEqual(t, mix, s.RandaoMix())
+
+	// the proposers of the first epochs are seeded by the genesis mix, later ones by the mix
+	// at the end of the epoch before the previous one
+	for slot := 0; slot < 8; slot++ {
+		require.Equal(t, proposers[slot], s.Proposer(uint64(slot)))
+	}
+	s.ProcessSlot(8, common.Hash{})
+	changed := false
+	for slot := 8; slot < 12; slot++ {
+		require.Less(t, s.Proposer(uint64(slot)), 16)
+		if s.Proposer(uint64(slot)) != New(s.Config(), common.Hash{0xaa}).Proposer(uint64(slot)) {
+			changed = true
+		}
+	}
+	require.True(t, changed)
+}
+
+func TestWithdrawalsSweep(t *testing.T) {
+	s := newState()
+	// no rewards yet, nothing to withdraw
+	require.Empty(t, s.ExpectedWithdrawals())
+
+	// an epoch of rewards makes every active validator partially withdrawable
+	s.Exit(3)
+	require.True(t, s.ProcessSlot(4, common.Hash{0x04}))
+	require.Equal(t, uint64(MaxEffectiveBalance+EpochReward), s.Balance(0))
+	require.Equal(t, uint64(MaxEffectiveBalance), s.Balance(3))
+	ws := s.ExpectedWithdrawals()
+	require.Len(t, ws, MaxWithdrawalsPerPayload)
+	require.Equal(t, types.Withdrawal{Index: 0, Validator: 0, Address: WithdrawalAddress(0), Amount: EpochReward}, *ws[0])
+	require.Equal(t, types.Withdrawal{Index: 3, Validator: 3, Address: WithdrawalAddress(3), Amount: MaxEffectiveBalance}, *ws[3])
+	require.NoError(t, s.ProcessWithdrawals(ws))
+	require.Equal(t, uint64(MaxEffectiveBalance), s.Balance(0))
+	require.Zero(t, s.Balance(3))
+
+	// stale withdrawals are refused
+	require.Error(t, s.ProcessWithdrawals(ws))
+
+	// the sweep wraps around, and skips the fully withdrawn validator
+	require.True(t, s.ProcessSlot(8, common.Hash{0x08}))
+	ws = s.ExpectedWithdrawals()
+	require.Len(t, ws, 15)
+	require.Equal(t, uint64(16), ws[0].Index)
+	require.Equal(t, uint64(0), ws[0].Validator)
+	require.Equal(t, uint64(4), ws[3].Validator)
+	require.NoError(t, s.ProcessWithdrawals(ws))
+
+	// the sweep survives a restart
+	restored := newState()
+	restored.Restore(s.Snapshot())
+	require.Zero(t, restored.Balance(3))
+	require.Equal(t, s.ExpectedWithdrawals(), restored.ExpectedWithdrawals())
+	require.True(t, restored.ProcessSlot(12, common.Hash{0x0c}))
+	require.Equal(t, uint64(31), restored.ExpectedWithdrawals()[0].Index)
+}
+
+func TestValidatorChurn(t *testing.T) {
+	s := New(Config{GenesisTime: 1000, SlotTime: 2 * time.Second, SlotsPerEpoch: 4, Validators: 16, Pending: 8}, common.Hash{0xaa})
+	require.Len(t, s.ActiveValidators(), 8)
+	require.Equal(t, []int{8, 9, 10, 11, 12, 13, 14, 15}, s.PendingValidators())
+	require.Equal(t, StatusPendingQueued, s.Status(8))
+	for slot := uint64(0); slot < 32; slot++ {
+		require.Less(t, s.Proposer(slot), 8)
+	}
+
+	// pending validators earn no rewards until activated, and then propose
+	require.True(t, s.Activate(8))
+	require.False(t, s.Activate(8))
+	require.True(t, s.ProcessSlot(4, common.Hash{0x04}))
+	require.Equal(t, uint64(MaxEffectiveBalance+EpochReward), s.Balance(8))
+	require.Equal(t, uint64(MaxEffectiveBalance), s.Balance(9))
+	require.Equal(t, StatusActiveOngoing, s.Status(8))
+
+	// exited and slashed validators no longer propose, and are withdrawn in full
+	for i := 0; i < 8; i++ {
+		s.Exit(i)
+	}
+	s.Slash(8)
+	require.True(t, s.Slashed(8))
+	require.Equal(t, uint64(MaxEffectiveBalance+EpochReward-MaxEffectiveBalance/MinSlashingPenaltyQuotient), s.Balance(8))
+	require.Equal(t, StatusWithdrawalPossible, s.Status(8))
+	require.Empty(t, s.ActiveValidators())
+	require.True(t, s.Activate(9))
+	for slot := uint64(0); slot < 32; slot++ {
+		require.Equal(t, 9, s.Proposer(slot))
+	}
+	ws := s.ExpectedWithdrawals()
+	require.Equal(t, types.Withdrawal{Index: 8, Validator: 8, Address: WithdrawalAddress(8), Amount: s.Balance(8)}, *ws[8])
+	require.NoError(t, s.ProcessWithdrawals(ws))
+	require.Equal(t, StatusWithdrawalDone, s.Status(8))
+
+	// the churn survives a restart
+	restored := New(Config{GenesisTime: 1000, SlotTime: 2 * time.Second, SlotsPerEpoch: 4, Validators: 16, Pending: 8}, common.Hash{0xaa})
+	restored.Restore(s.Snapshot())
+	require.Equal(t, s.ActiveValidators(), restored.ActiveValidators())
+	require.Equal(t, s.PendingValidators(), restored.PendingValidators())
+	require.True(t, restored.Slashed(8))
+}
@@ -0,0 +1,255 @@
+package beaconstate
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// MaxEffectiveBalance is the balance of a full validator, in gwei. Any balance above it
+	// is withdrawn by the sweep.
+	MaxEffectiveBalance = 32_000_000_000
+
+	// EpochReward is the reward of an active validator per epoch, in gwei: about 3% a year on
+	// the max effective balance, at mainnet epochs.
+	EpochReward = 12_000
+
+	// MaxWithdrawalsPerPayload is the number of withdrawals after which the sweep stops.
+	MaxWithdrawalsPerPayload = 16
+
+	// MaxValidatorsPerWithdrawalsSweep is the number of validators the sweep visits per payload.
+	MaxValidatorsPerWithdrawalsSweep = 16384
+
+	// MinSlashingPenaltyQuotient is the quotient of the effective balance a slashed validator
+	// loses right away, from bellatrix on.
+	MinSlashingPenaltyQuotient = 32
+)
+
+// WithdrawalAddress returns the execution address the validator withdraws to, from its 0x01
+// withdrawal credentials.
+func WithdrawalAddress(index uint64) common.Address {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], index)
+	h := sha256.Sum256(buf[:])
+	return common.BytesToAddress(h[12:])
+}
+
+// Balance returns the balance of the validator, in gwei.
+func (s *State) Balance(index int) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.balances[index]
+}
+
+// Exit exits the validator, which stops earning rewards, and withdraws its full balance in
+// the next sweep. The mock skips the exit queue and withdrawability delay.
+func (s *State) Exit(index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exited[index] = true
+}
+
+// Slash slashes the validator with the minimum penalty of bellatrix, an effective balance
+// divided by 32, and exits it. The mock skips the correlation penalty and the withdrawability
+// delay of slashed validators.
+func (s *State) Slash(index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	penalty := uint64(MaxEffectiveBalance / MinSlashingPenaltyQuotient)
+	if penalty > s.balances[index] {
+		penalty = s.balances[index]
+	}
+	s.balances[index] -= penalty
+	s.slashed[index] = true
+	s.exited[index] = true
+}
+
+// Activate activates the validator if it is pending activation, and returns whether it was. The
+// mock skips the activation queue and eligibility delay.
+func (s *State) Activate(index int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.pending[index] {
+		return false
+	}
+	s.pending[index] = false
+	return true
+}
+
+// Active returns whether the validator is active: activated, and not exited.
+func (s *State) Active(index int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active(index)
+}
+
+func (s *State) active(index int) bool {
+	return !s.pending[index] && !s.exited[index]
+}
+
+// ActiveValidators returns the indices of the active validators.
+func (s *State) ActiveValidators() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var indices []int
+	for i := range s.balances {
+		if s.active(i) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Slashed returns whether the validator was slashed.
+func (s *State) Slashed(index int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slashed[index]
+}
+
+// PendingValidators returns the indices of the validators pending activation.
+func (s *State) PendingValidators() []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var indices []int
+	for i, pending := range s.pending {
+		if pending {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Validator status of the beacon API.
+const (
+	StatusPendingQueued      = "pending_queued"
+	StatusActiveOngoing      = "active_ongoing"
+	StatusWithdrawalDone     = "withdrawal_done"
+	StatusWithdrawalPossible = "withdrawal_possible"
+)
+
+// Status returns the status of the validator of the beacon API. The mock skips the delays, so
+// an exited validator is withdrawable right away, until the sweep withdrew its balance.
+func (s *State) Status(index int) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch {
+	case s.pending[index]:
+		return StatusPendingQueued
+	case !s.exited[index]:
+		return StatusActiveOngoing
+	case s.balances[index] == 0:
+		return StatusWithdrawalDone
+	default:
+		return StatusWithdrawalPossible
+	}
+}
+
+// ExpectedWithdrawals returns the withdrawals of the next payload, like the capella
+// get_expected_withdrawals: a sweep over the validators from where the previous one stopped,
+// withdrawing the full balance of exited validators, and the balance above the max effective
+// balance of active ones.
+func (s *State) ExpectedWithdrawals() types.Withdrawals {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.expectedWithdrawals()
+}
+
+func (s *State) expectedWithdrawals() types.Withdrawals {
+	withdrawals := types.Withdrawals{}
+	n := uint64(len(s.balances))
+	if n == 0 {
+		return withdrawals
+	}
+	bound := n
+	if bound > MaxValidatorsPerWithdrawalsSweep {
+		bound = MaxValidatorsPerWithdrawalsSweep
+	}
+	index := s.nextWithdrawalIndex
+	validator := s.nextWithdrawalValidator
+	for i := uint64(0); i < bound; i++ {
+		balance := s.balances[validator]
+		amount := uint64(0)
+		if s.exited[validator] {
+			amount = balance
+		} else if balance > MaxEffectiveBalance {
+			amount = balance - MaxEffectiveBalance
+		}
+		if amount > 0 {
+			withdrawals = append(withdrawals, &types.Withdrawal{
+				Index:     index,
+				Validator: validator,
+				Address:   WithdrawalAddress(validator),
+				Amount:    amount,
+			})
+			index++
+		}
+		if len(withdrawals) == MaxWithdrawalsPerPayload {
+			break
+		}
+		validator = (validator + 1) % n
+	}
+	return withdrawals
+}
+
+// ProcessWithdrawals deducts the withdrawals of a payload from the balances, and moves the
+// sweep on, like the capella process_withdrawals. The withdrawals must be the expected ones;
+// those of a payload built before the sweep moved on are refused.
+func (s *State) ProcessWithdrawals(withdrawals types.Withdrawals) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expected := s.expectedWithdrawals()
+	if len(withdrawals) != len(expected) {
+		return fmt.Errorf("expected %d withdrawals, got %d", len(expected), len(withdrawals))
+	}
+	for i, w := range withdrawals {
+		if *w != *expected[i] {
+			return fmt.Errorf("withdrawal %d does not match the sweep: %+v, expected %+v", i, *w, *expected[i])
+		}
+	}
+	n := uint64(len(s.balances))
+	if n == 0 {
+		return nil
+	}
+	for _, w := range withdrawals {
+		s.balances[w.Validator] -= w.Amount
+	}
+	if len(withdrawals) > 0 {
+		s.nextWithdrawalIndex = withdrawals[len(withdrawals)-1].Index + 1
+	}
+	if len(withdrawals) == MaxWithdrawalsPerPayload {
+		s.nextWithdrawalValidator = (withdrawals[len(withdrawals)-1].Validator + 1) % n
+	} else if n > MaxValidatorsPerWithdrawalsSweep {
+		s.nextWithdrawalValidator = (s.nextWithdrawalValidator + MaxValidatorsPerWithdrawalsSweep) % n
+	}
+	return nil
+}
+
+// reward credits the epoch rewards to the active validators, for the epochs that passed.
+func (s *State) reward(epochs uint64) {
+	for i := range s.balances {
+		if s.active(i) {
+			s.balances[i] += epochs * EpochReward
+		}
+	}
+}
+
+// resetBalances gives every validator the max effective balance, without exits, with the last
+// ones of the config pending activation, and restarts the sweep.
+func (s *State) resetBalances() {
+	s.balances = make([]uint64, s.cfg.Validators)
+	s.pending = make([]bool, s.cfg.Validators)
+	for i := range s.balances {
+		s.balances[i] = MaxEffectiveBalance
+		s.pending[i] = i >= s.cfg.Validators-s.cfg.Pending
+	}
+	s.exited = make([]bool, s.cfg.Validators)
+	s.slashed = make([]bool, s.cfg.Validators)
+	s.nextWithdrawalIndex = 0
+	s.nextWithdrawalValidator = 0
+}
@@ -0,0 +1,394 @@
+// Package builderclient is a client of the builder API and the data API of relays, with the
+// request and response types of the types package.
+package builderclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mergemock/rpc"
+	"mergemock/types"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pathStatus            = "/eth/v1/builder/status"
+	pathRegisterValidator = "/eth/v1/builder/validators"
+	pathGetHeader         = "/eth/v1/builder/header/%d/%s/%s"
+	pathGetPayload        = "/eth/v1/builder/blinded_blocks"
+	pathDataDelivered     = "/relay/v1/data/bidtraces/proposer_payload_delivered"
+	pathDataReceived      = "/relay/v1/data/bidtraces/builder_blocks_received"
+	pathDataPayload       = "/relay/v1/data/payload"
+
+	contentTypeJSON = "application/json"
+	contentTypeSSZ  = "application/octet-stream"
+
+	// acceptSSZ prefers SSZ responses, and accepts JSON from builders that only answer JSON.
+	acceptSSZ = "application/octet-stream;q=1.0,application/json;q=0.9"
+)
+
+var (
+	// ErrNoBid is returned when the builder has no bid for the slot.
+	ErrNoBid = errors.New("no bid")
+	// ErrInvalidBid is returned for bids a proposer must not accept.
+	ErrInvalidBid = errors.New("invalid bid")
+)
+
+// StatusError is returned for responses with an unexpected status code.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("builder API returned status code %d: %s", e.Code, e.Body)
+}
+
+// retryable returns whether a request answered with the status code may succeed when retried:
+// the server failed, or asked to come back later.
+func retryable(code int) bool {
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
+
+// Client calls the builder API of a builder or relay.
+type Client struct {
+	addr    string
+	http    *http.Client
+	log     logrus.Ext1FieldLogger
+	schemas *rpc.SchemaChecker
+
+	retries int
+	backoff time.Duration
+	ssz     bool
+}
+
+// New returns a client of the builder API at the address, with JSON requests and no retries.
+func New(addr string, log logrus.Ext1FieldLogger) *Client {
+	return &Client{addr: strings.TrimSuffix(addr, "/"), http: http.DefaultClient, log: log}
+}
+
+// SetHTTPClient sends the requests with the HTTP client. It must be called before any requests.
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.http = client
+}
+
+// SetRetries retries requests that failed to connect, or that the server failed, up to the
+// number of times, waiting twice as long before every retry, starting with the backoff. It must
+// be called before any requests.
+func (c *Client) SetRetries(retries int, backoff time.Duration) {
+	c.retries = retries
+	c.backoff = backoff
+}
+
+// SetSSZ sends SSZ request bodies, and accepts SSZ responses, falling back to JSON for builders
+// that answer 415 Unsupported Media Type. It must be called before any requests.
+func (c *Client) SetSSZ(ssz bool) {
+	c.ssz = ssz
+}
+
+// SetSchemaCheck validates the JSON responses against the builder-specs schemas. It must be
+// called before any requests.
+func (c *Client) SetSchemaCheck(schemas *rpc.SchemaChecker) {
+	c.schemas = schemas
+}
+
+// request is a request to the builder API, with the body in the encodings it may be sent in.
+type request struct {
+	method  string
+	path    string
+	query   url.Values
+	version string
+	json    interface{}
+	// ssz encodes the body as SSZ, nil for requests without an SSZ body
+	ssz func() ([]byte, error)
+	// accept requests an SSZ response, if the client sends SSZ
+	accept bool
+}
+
+// response is the answer to a request, with its body read.
+type response struct {
+	code        int
+	contentType string
+	version     string
+	body        []byte
+}
+
+func (r *response) isSSZ() bool {
+	return strings.HasPrefix(r.contentType, contentTypeSSZ)
+}
+
+// do sends the request, in SSZ if the client sends SSZ and the request has an SSZ body, and
+// retries it. Responses with other status codes than the expected ones are errors.
+func (c *Client) do(ctx context.Context, req *request, expected ...int) (*response, error) {
+	ssz := c.ssz && req.ssz != nil
+	resp, err := c.retry(ctx, req, ssz)
+	if err == nil && ssz && resp.code == http.StatusUnsupportedMediaType {
+		c.log.WithField("path", req.path).Debug("Builder does not accept SSZ, falling back to JSON")
+		resp, err = c.retry(ctx, req, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range expected {
+		if resp.code == code {
+			return resp, nil
+		}
+	}
+	return nil, &StatusError{Code: resp.code, Body: strings.TrimSpace(string(resp.body))}
+}
+
+// retry sends the request until it gets an answer that is not a server failure, or runs out of
+// retries.
+func (c *Client) retry(ctx context.Context, req *request, ssz bool) (*response, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, ssz)
+		if err == nil && !retryable(resp.code) {
+			return resp, nil
+		}
+		if attempt >= c.retries || ctx.Err() != nil {
+			return resp, err
+		}
+		c.log.WithField("path", req.path).WithField("attempt", attempt+1).Debug("Retrying builder API request")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// send sends the request once.
+func (c *Client) send(ctx context.Context, req *request, ssz bool) (*response, error) {
+	var body io.Reader
+	contentType := contentTypeJSON
+	switch {
+	case ssz:
+		enc, err := req.ssz()
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(enc)
+		contentType = contentTypeSSZ
+	case req.json != nil:
+		enc, err := json.Marshal(req.json)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(enc)
+	}
+	u := c.addr + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if req.version != "" {
+		httpReq.Header.Set("Eth-Consensus-Version", req.version)
+	}
+	if c.ssz && req.accept {
+		httpReq.Header.Set("Accept", acceptSSZ)
+	} else {
+		httpReq.Header.Set("Accept", contentTypeJSON)
+	}
+	httpResp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{
+		code:        httpResp.StatusCode,
+		contentType: httpResp.Header.Get("Content-Type"),
+		version:     strings.ToLower(httpResp.Header.Get("Eth-Consensus-Version")),
+		body:        respBody,
+	}, nil
+}
+
+// Status returns nil if the builder is up.
+func (c *Client) Status(ctx context.Context) error {
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: pathStatus}, http.StatusOK)
+	return err
+}
+
+// RegisterValidators registers the fee recipients and gas limits of the validators.
+func (c *Client) RegisterValidators(ctx context.Context, regs []types.SignedValidatorRegistration) error {
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   pathRegisterValidator,
+		json:   regs,
+		ssz:    func() ([]byte, error) { return types.MarshalSignedValidatorRegistrations(regs) },
+	}, http.StatusOK)
+	return err
+}
+
+// GetHeader returns the bid of the builder on the parent for the proposer, after checking it is
+// signed by the builder, has a value and builds on the parent. It returns ErrNoBid if the
+// builder has no bid, and an ErrInvalidBid error for bids a proposer must not accept.
+func (c *Client) GetHeader(ctx context.Context, slot uint64, parent common.Hash, pubkey types.PublicKey) (*types.VersionedSignedBuilderBid, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf(pathGetHeader, slot, parent.Hex(), pubkey.String()),
+		accept: true,
+	}, http.StatusOK, http.StatusNoContent)
+	if err != nil {
+		return nil, err
+	}
+	if resp.code == http.StatusNoContent {
+		return nil, ErrNoBid
+	}
+	bid := new(types.VersionedSignedBuilderBid)
+	if resp.isSSZ() {
+		bid.Version = resp.version
+		err = bid.UnmarshalSSZ(resp.body)
+	} else if err = c.schemas.Check("getHeader", resp.body); err == nil {
+		err = json.Unmarshal(resp.body, bid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bid, checkBid(bid, parent)
+}
+
+// checkBid returns an ErrInvalidBid error if the bid is not signed by its builder, has no value,
+// or does not build on the parent.
+func checkBid(bid *types.VersionedSignedBuilderBid, parent common.Hash) error {
+	msg, err := bid.Message()
+	if err != nil {
+		return err
+	}
+	builder, err := bid.Pubkey()
+	if err != nil {
+		return err
+	}
+	sig, err := bid.Signature()
+	if err != nil {
+		return err
+	}
+	if ok, err := types.VerifySignature(msg, types.DomainBuilder, builder[:], sig[:]); !ok || err != nil {
+		return fmt.Errorf("%w: signature does not verify", ErrInvalidBid)
+	}
+	value, err := bid.Value()
+	if err != nil {
+		return err
+	}
+	if value == (types.U256Str{}) {
+		return fmt.Errorf("%w: zero value", ErrInvalidBid)
+	}
+	parentHash, err := bid.ParentHash()
+	if err != nil {
+		return err
+	}
+	if common.Hash(parentHash) != parent {
+		return fmt.Errorf("%w: parent hash %s, requested %s", ErrInvalidBid, common.Hash(parentHash), parent)
+	}
+	return nil
+}
+
+// SubmitBlindedBlock submits the signed blinded block of a bid, and returns the payload the
+// builder reveals for it.
+func (c *Client) SubmitBlindedBlock(ctx context.Context, block *types.VersionedSignedBlindedBeaconBlock) (*types.VersionedExecutionPayload, error) {
+	var signed interface{}
+	switch block.Version {
+	case types.VersionBellatrix:
+		signed = block.Bellatrix
+	case types.VersionCapella:
+		signed = block.Capella
+	default:
+		return nil, fmt.Errorf("%w: %q", types.ErrUnknownVersion, block.Version)
+	}
+	resp, err := c.do(ctx, &request{
+		method:  http.MethodPost,
+		path:    pathGetPayload,
+		version: block.Version,
+		json:    signed,
+		ssz:     block.MarshalSSZ,
+		accept:  true,
+	}, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.isSSZ() {
+		if err := c.schemas.Check("submitBlindedBlock", resp.body); err != nil {
+			return nil, err
+		}
+	}
+	return decodePayload(resp, block.Version)
+}
+
+// decodePayload decodes the execution payload of the response, of the version in its
+// Eth-Consensus-Version header if it is SSZ, or else of the version given.
+func decodePayload(resp *response, version string) (*types.VersionedExecutionPayload, error) {
+	payload := new(types.VersionedExecutionPayload)
+	if !resp.isSSZ() {
+		return payload, json.Unmarshal(resp.body, payload)
+	}
+	payload.Version = version
+	if resp.version != "" {
+		payload.Version = resp.version
+	}
+	return payload, payload.UnmarshalSSZ(resp.body)
+}
+
+// slotQuery returns the slot argument of the data API, none for a nil slot.
+func slotQuery(slot *uint64) url.Values {
+	if slot == nil {
+		return nil
+	}
+	return url.Values{"slot": {strconv.FormatUint(*slot, 10)}}
+}
+
+// DeliveredPayloads returns the traces of the payloads the relay delivered, for the slot or all
+// recent slots if it is nil.
+func (c *Client) DeliveredPayloads(ctx context.Context, slot *uint64) ([]types.BidTraceV2, error) {
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: pathDataDelivered, query: slotQuery(slot)}, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var traces []types.BidTraceV2
+	return traces, json.Unmarshal(resp.body, &traces)
+}
+
+// ReceivedBlocks returns the traces of the blocks the relay received from builders, for the
+// slot or all recent slots if it is nil.
+func (c *Client) ReceivedBlocks(ctx context.Context, slot *uint64) ([]types.BidTraceV2WithTimestamp, error) {
+	resp, err := c.do(ctx, &request{method: http.MethodGet, path: pathDataReceived, query: slotQuery(slot)}, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var traces []types.BidTraceV2WithTimestamp
+	return traces, json.Unmarshal(resp.body, &traces)
+}
+
+// Payload returns a payload the relay delivered, by its block hash.
+func (c *Client) Payload(ctx context.Context, blockHash common.Hash) (*types.VersionedExecutionPayload, error) {
+	resp, err := c.do(ctx, &request{
+		method: http.MethodGet,
+		path:   pathDataPayload,
+		query:  url.Values{"block_hash": {blockHash.Hex()}},
+		accept: true,
+	}, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return decodePayload(resp, "")
+}
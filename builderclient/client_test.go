@@ -0,0 +1,108 @@
+package builderclient
+
+import (
+	"context"
+	"io"
+	"mergemock/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return New(srv.URL, logrus.New())
+}
+
+func TestRetries(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	ctx := context.Background()
+
+	err := client.Status(ctx)
+	require.Error(t, err)
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	require.Equal(t, http.StatusServiceUnavailable, statusErr.Code)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	client.SetRetries(2, time.Millisecond)
+	require.NoError(t, client.Status(ctx))
+	require.Equal(t, 3, calls)
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+	client.SetRetries(3, time.Millisecond)
+
+	err := client.Status(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad request")
+	require.Equal(t, 1, calls)
+}
+
+func TestSSZFallback(t *testing.T) {
+	var contentTypes []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		io.Copy(io.Discard, r.Body)
+		if r.Header.Get("Content-Type") == contentTypeSSZ {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	})
+	client.SetSSZ(true)
+
+	regs := []types.SignedValidatorRegistration{{Message: &types.RegisterValidatorRequestMessage{FeeRecipient: types.Address{0x42}}}}
+	require.NoError(t, client.RegisterValidators(context.Background(), regs))
+	require.Equal(t, []string{contentTypeSSZ, contentTypeJSON}, contentTypes)
+}
+
+func TestGetHeaderNoBid(t *testing.T) {
+	var path, accept string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		path, accept = r.URL.Path, r.Header.Get("Accept")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	client.SetSSZ(true)
+
+	parent := common.Hash{0x01}
+	_, err := client.GetHeader(context.Background(), 7, parent, types.PublicKey{0x02})
+	require.ErrorIs(t, err, ErrNoBid)
+	require.Equal(t, "/eth/v1/builder/header/7/"+parent.Hex()+"/"+types.PublicKey{0x02}.String(), path)
+	require.Equal(t, acceptSSZ, accept)
+}
+
+func TestDeliveredPayloads(t *testing.T) {
+	var query string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`[]`))
+	})
+	ctx := context.Background()
+
+	traces, err := client.DeliveredPayloads(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, traces)
+	require.Equal(t, "", query)
+
+	slot := uint64(12)
+	_, err = client.DeliveredPayloads(ctx, &slot)
+	require.NoError(t, err)
+	require.Equal(t, "slot=12", query)
+}
@@ -0,0 +1,126 @@
+package contracts
+
+import (
+	_ "embed"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Contracts of the tx profiles and of the fee recipients, in the EVM assembly of go-ethereum, with
+// the ABI of their Solidity counterparts.
+var (
+	//go:embed token.easm
+	tokenAsm string
+
+	//go:embed churner.easm
+	churnerAsm string
+
+	//go:embed factory.easm
+	factoryAsm string
+
+	//go:embed pool.easm
+	poolAsm string
+
+	//go:embed recipient.easm
+	recipientAsm string
+
+	//go:embed rejecter.easm
+	rejecterAsm string
+
+	//go:embed bloomer.easm
+	bloomerAsm string
+)
+
+var (
+	// TokenCode is the creation code of an ERC20 subset, which mints the supply to the deployer.
+	TokenCode = creationCode(mintToCaller(TokenSupply), mustAssemble(tokenAsm))
+
+	// ChurnerCode is the creation code of a contract that writes fresh storage slots on every call.
+	ChurnerCode = creationCode(nil, mustAssemble(churnerAsm))
+
+	// FactoryCode is the creation code of a CREATE2 factory, which calls the children it creates.
+	FactoryCode = creationCode(nil, mustAssemble(factoryAsm))
+
+	// PoolCode is the creation code of a price pool, which swaps move and arbitrages take back,
+	// for the bundles of the bundles tx profile.
+	PoolCode = creationCode(nil, mustAssemble(poolAsm))
+
+	// RecipientCode is the creation code of a fee recipient contract, which logs the value it receives.
+	RecipientCode = creationCode(nil, mustAssemble(recipientAsm))
+
+	// RejecterCode is the creation code of a fee recipient contract, which reverts on receive.
+	RejecterCode = creationCode(nil, mustAssemble(rejecterAsm))
+
+	// BloomerCode is the creation code of a contract without code, whose constructor emits a log
+	// for each topic from n down to 1, for the n appended to the creation code.
+	BloomerCode = creationCode(mustAssemble(bloomerAsm), nil)
+)
+
+// TokenSupply is the supply of the token, minted to its deployer.
+var TokenSupply = new(big.Int).Lsh(common.Big1, 128)
+
+// Event topics of the contracts.
+var (
+	TransferEventTopic   = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	ChurnedEventTopic    = crypto.Keccak256Hash([]byte("Churned(uint256)"))
+	DeployedEventTopic   = crypto.Keccak256Hash([]byte("Deployed(address)"))
+	SwappedEventTopic    = crypto.Keccak256Hash([]byte("Swapped(uint256)"))
+	ArbitragedEventTopic = crypto.Keccak256Hash([]byte("Arbitraged(uint256)"))
+	ReceivedEventTopic   = crypto.Keccak256Hash([]byte("Received(uint256)"))
+)
+
+// Selectors of the contract methods.
+var (
+	TransferSelector  = selector("transfer(address,uint256)")
+	BalanceOfSelector = selector("balanceOf(address)")
+	ChurnSelector     = selector("churn(uint256)")
+	CountSelector     = selector("count()")
+	DeploySelector    = selector("deploy(bytes32)")
+	SwapSelector      = selector("swap(uint256)")
+	ArbSelector       = selector("arb(uint256)")
+	PriceSelector     = selector("price()")
+)
+
+func selector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+func mustAssemble(source string) []byte {
+	compiler := asm.NewCompiler(false)
+	compiler.Feed(asm.Lex([]byte(source), false))
+	code, errs := compiler.Compile()
+	if len(errs) > 0 {
+		panic(fmt.Sprintf("failed to assemble contract: %v", errs))
+	}
+	return common.FromHex(code)
+}
+
+// mintToCaller is constructor code that stores the amount in the slot of the deployer.
+func mintToCaller(amount *big.Int) []byte {
+	code := []byte{byte(vm.PUSH32)}
+	code = append(code, common.BigToHash(amount).Bytes()...)
+	return append(code, byte(vm.CALLER), byte(vm.SSTORE))
+}
+
+// creationCode returns the constructor code, followed by code that copies the runtime code
+// after it into memory, and returns it as the code of the contract.
+func creationCode(constructor, runtime []byte) []byte {
+	const deployLen = 13
+	offset := len(constructor) + deployLen
+	code := append([]byte{}, constructor...)
+	code = append(code,
+		byte(vm.PUSH2), byte(len(runtime)>>8), byte(len(runtime)),
+		byte(vm.DUP1),
+		byte(vm.PUSH2), byte(offset>>8), byte(offset),
+		byte(vm.PUSH1), 0,
+		byte(vm.CODECOPY),
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	)
+	return append(code, runtime...)
+}
@@ -0,0 +1,51 @@
+// Package contracts embeds the contract artifacts mergemock deploys or interacts with.
+package contracts
+
+import (
+	_ "embed"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Deposit contract, as found in the consensus-specs solidity_deposit_contract (solc 0.6.11).
+var (
+	//go:embed deposit_contract.bin
+	depositContractBin string
+
+	//go:embed deposit_contract.abi
+	depositContractABI string
+)
+
+var (
+	// DepositContractCode is the creation code of the deposit contract.
+	DepositContractCode = common.FromHex(depositContractBin)
+
+	// DepositContractABI is the parsed ABI of the deposit contract.
+	DepositContractABI = mustParseABI(depositContractABI)
+
+	// DepositEventTopic is the topic of the DepositEvent log emitted for every deposit.
+	DepositEventTopic = crypto.Keccak256Hash([]byte("DepositEvent(bytes,bytes,bytes,bytes,bytes)"))
+)
+
+// Beacon roots contract of EIP-4788, as deployed on all networks.
+const BeaconRootsHistoryBufferLength = 8191
+
+// BeaconRootsAddress is the address of the EIP-4788 beacon roots contract.
+var BeaconRootsAddress = common.HexToAddress("0x000F3df6D732807Ef1319fB7B8bB8522d0Beac02")
+
+// System contracts of EIP-7002 and EIP-7251, queueing execution layer triggered requests.
+var (
+	WithdrawalRequestAddress    = common.HexToAddress("0x00000961Ef480Eb55e80D19ad83579A64c007002")
+	ConsolidationRequestAddress = common.HexToAddress("0x0000BBdDc7CE488642fb579F8B00f3a590007251")
+)
+
+func mustParseABI(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -0,0 +1,209 @@
+package engineclient
+
+import (
+	"context"
+	"fmt"
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethRpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+func (c *Client) GetPayloadV1(ctx context.Context, payloadId types.PayloadID) (*types.ExecutionPayloadV1, error) {
+	var result types.ExecutionPayloadV1
+	if err := c.getPayload(ctx, "engine_getPayloadV1", payloadId, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) GetPayloadV2(ctx context.Context, payloadId types.PayloadID) (*types.GetPayloadV2Response, error) {
+	var result types.GetPayloadV2Response
+	if err := c.getPayload(ctx, "engine_getPayloadV2", payloadId, &result); err != nil {
+		return nil, err
+	}
+	if result.ExecutionPayload == nil || result.BlockValue == nil {
+		return nil, fmt.Errorf("%w: engine_getPayloadV2 response without payload or block value", ErrInvalidResponse)
+	}
+	if result.ExecutionPayload.Withdrawals == nil {
+		return nil, fmt.Errorf("%w: engine_getPayloadV2 payload without withdrawals", ErrInvalidResponse)
+	}
+	return &result, nil
+}
+
+func (c *Client) GetPayloadV3(ctx context.Context, payloadId types.PayloadID) (*types.GetPayloadV3Response, error) {
+	var result types.GetPayloadV3Response
+	if err := c.getPayload(ctx, "engine_getPayloadV3", payloadId, &result); err != nil {
+		return nil, err
+	}
+	if err := checkPayloadV3("engine_getPayloadV3", result.ExecutionPayload, result.BlockValue != nil, result.BlobsBundle); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) GetPayloadV4(ctx context.Context, payloadId types.PayloadID) (*types.GetPayloadV4Response, error) {
+	var result types.GetPayloadV4Response
+	if err := c.getPayload(ctx, "engine_getPayloadV4", payloadId, &result); err != nil {
+		return nil, err
+	}
+	if err := checkPayloadV3("engine_getPayloadV4", result.ExecutionPayload, result.BlockValue != nil, result.BlobsBundle); err != nil {
+		return nil, err
+	}
+	if result.ExecutionRequests == nil {
+		return nil, fmt.Errorf("%w: engine_getPayloadV4 response without execution requests", ErrInvalidResponse)
+	}
+	return &result, nil
+}
+
+// checkPayloadV3 checks a V3 or V4 getPayload response has a payload with withdrawals, a block
+// value, and a blobs bundle with a commitment and proof for every blob.
+func checkPayloadV3(method string, payload *types.ExecutionPayloadV3, hasValue bool, bundle *types.BlobsBundleV1) error {
+	if payload == nil || !hasValue || bundle == nil {
+		return fmt.Errorf("%w: %s response without payload, block value or blobs bundle", ErrInvalidResponse, method)
+	}
+	if payload.Withdrawals == nil {
+		return fmt.Errorf("%w: %s payload without withdrawals", ErrInvalidResponse, method)
+	}
+	if len(bundle.Commitments) != len(bundle.Blobs) || len(bundle.Proofs) != len(bundle.Blobs) {
+		return fmt.Errorf("%w: %s blobs bundle with %d blobs, %d commitments and %d proofs", ErrInvalidResponse, method, len(bundle.Blobs), len(bundle.Commitments), len(bundle.Proofs))
+	}
+	return nil
+}
+
+func (c *Client) getPayload(ctx context.Context, method string, payloadId types.PayloadID, result interface{}) error {
+	e := c.logger(ctx).WithField("payload_id", payloadId)
+	err := c.CallContext(ctx, result, method, payloadId)
+	if err != nil {
+		e = e.WithError(err)
+		if rpcErr, ok := err.(gethRpc.Error); ok {
+			code := api.ErrorCode(rpcErr.ErrorCode())
+			if code != api.UnavailablePayload {
+				e.WithField("code", code).Warn("unexpected error code in get-payload response")
+			} else {
+				e.Warn("unavailable payload in get-payload request")
+			}
+		} else {
+			e.Error("failed to get payload")
+		}
+		return err
+	}
+	e.Debug("Received payload")
+	return nil
+}
+
+func (c *Client) NewPayloadV1(ctx context.Context, payload *types.ExecutionPayloadV1) (*types.PayloadStatusV1, error) {
+	return c.newPayload(ctx, c.logger(ctx).WithField("block_hash", payload.BlockHash), "engine_newPayloadV1", payload)
+}
+
+func (c *Client) NewPayloadV2(ctx context.Context, payload *types.ExecutionPayloadV2) (*types.PayloadStatusV1, error) {
+	return c.newPayload(ctx, c.logger(ctx).WithField("block_hash", payload.BlockHash), "engine_newPayloadV2", payload)
+}
+
+func (c *Client) NewPayloadV3(ctx context.Context, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot common.Hash) (*types.PayloadStatusV1, error) {
+	e := c.logger(ctx).WithField("block_hash", payload.BlockHash).WithField("beacon_root", beaconRoot)
+	return c.newPayload(ctx, e, "engine_newPayloadV3", payload, versionedHashes, beaconRoot)
+}
+
+func (c *Client) NewPayloadV4(ctx context.Context, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	e := c.logger(ctx).WithField("block_hash", payload.BlockHash).WithField("beacon_root", beaconRoot).WithField("requests", len(requests))
+	return c.newPayload(ctx, e, "engine_newPayloadV4", payload, versionedHashes, beaconRoot, requests)
+}
+
+func (c *Client) newPayload(ctx context.Context, e logrus.Ext1FieldLogger, method string, args ...interface{}) (*types.PayloadStatusV1, error) {
+	var result types.PayloadStatusV1
+	err := c.CallContext(ctx, &result, method, args...)
+	if err != nil {
+		e.WithError(err).Error("Payload execution failed")
+		return nil, err
+	}
+	switch result.Status {
+	case types.ExecutionValid, types.ExecutionInvalid, types.ExecutionSyncing, types.ExecutionAccepted, types.ExecutionInvalidBlockHash, types.ExecutionInvalidTerminalBlock:
+	default:
+		return nil, fmt.Errorf("%w: %s status %q", ErrInvalidResponse, method, result.Status)
+	}
+	e.WithField("status", result.Status).WithField("latestValidHash", result.LatestValidHash).WithField("validationError", result.ValidationError).Debug("Received payload execution result")
+	return &result, nil
+}
+
+func (c *Client) ExecuteStatelessPayloadV1(ctx context.Context, payload *types.ExecutionPayloadV1, witness []byte) (*types.StatelessPayloadStatusV1, error) {
+	return c.executeStatelessPayload(ctx, c.logger(ctx).WithField("block_hash", payload.BlockHash), "engine_executeStatelessPayloadV1", payload, hexutil.Bytes(witness))
+}
+
+func (c *Client) ExecuteStatelessPayloadV2(ctx context.Context, payload *types.ExecutionPayloadV2, witness []byte) (*types.StatelessPayloadStatusV1, error) {
+	return c.executeStatelessPayload(ctx, c.logger(ctx).WithField("block_hash", payload.BlockHash), "engine_executeStatelessPayloadV2", payload, hexutil.Bytes(witness))
+}
+
+func (c *Client) ExecuteStatelessPayloadV3(ctx context.Context, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot common.Hash, witness []byte) (*types.StatelessPayloadStatusV1, error) {
+	e := c.logger(ctx).WithField("block_hash", payload.BlockHash).WithField("beacon_root", beaconRoot)
+	return c.executeStatelessPayload(ctx, e, "engine_executeStatelessPayloadV3", payload, versionedHashes, beaconRoot, hexutil.Bytes(witness))
+}
+
+func (c *Client) ExecuteStatelessPayloadV4(ctx context.Context, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot common.Hash, requests types.ExecutionRequests, witness []byte) (*types.StatelessPayloadStatusV1, error) {
+	e := c.logger(ctx).WithField("block_hash", payload.BlockHash).WithField("beacon_root", beaconRoot).WithField("requests", len(requests))
+	return c.executeStatelessPayload(ctx, e, "engine_executeStatelessPayloadV4", payload, versionedHashes, beaconRoot, requests, hexutil.Bytes(witness))
+}
+
+func (c *Client) executeStatelessPayload(ctx context.Context, e logrus.Ext1FieldLogger, method string, args ...interface{}) (*types.StatelessPayloadStatusV1, error) {
+	var result types.StatelessPayloadStatusV1
+	err := c.CallContext(ctx, &result, method, args...)
+	if err != nil {
+		e.WithError(err).Error("Stateless payload execution failed")
+		return nil, err
+	}
+	switch result.Status {
+	case types.ExecutionValid, types.ExecutionInvalid, types.ExecutionInvalidBlockHash:
+	default:
+		return nil, fmt.Errorf("%w: %s status %q", ErrInvalidResponse, method, result.Status)
+	}
+	e.WithField("status", result.Status).WithField("stateRoot", result.StateRoot).WithField("receiptsRoot", result.ReceiptsRoot).WithField("validationError", result.ValidationError).Debug("Received stateless payload execution result")
+	return &result, nil
+}
+
+func (c *Client) ForkchoiceUpdatedV1(ctx context.Context, head, safe, finalized common.Hash, payload *types.PayloadAttributesV1) (types.ForkchoiceUpdatedResult, error) {
+	return c.forkchoiceUpdated(ctx, c.logger(ctx).WithField("payload", payload), "engine_forkchoiceUpdatedV1", head, safe, finalized, payload, payload != nil)
+}
+
+func (c *Client) ForkchoiceUpdatedV2(ctx context.Context, head, safe, finalized common.Hash, payload *types.PayloadAttributesV2) (types.ForkchoiceUpdatedResult, error) {
+	return c.forkchoiceUpdated(ctx, c.logger(ctx).WithField("payload", payload), "engine_forkchoiceUpdatedV2", head, safe, finalized, payload, payload != nil)
+}
+
+func (c *Client) ForkchoiceUpdatedV3(ctx context.Context, head, safe, finalized common.Hash, payload *types.PayloadAttributesV3) (types.ForkchoiceUpdatedResult, error) {
+	return c.forkchoiceUpdated(ctx, c.logger(ctx).WithField("payload", payload), "engine_forkchoiceUpdatedV3", head, safe, finalized, payload, payload != nil)
+}
+
+func (c *Client) forkchoiceUpdated(ctx context.Context, log logrus.Ext1FieldLogger, method string, head, safe, finalized common.Hash, payload interface{}, hasPayload bool) (types.ForkchoiceUpdatedResult, error) {
+	heads := &types.ForkchoiceStateV1{HeadBlockHash: head, SafeBlockHash: safe, FinalizedBlockHash: finalized}
+
+	e := log.WithField("head", head).WithField("safe", safe).WithField("finalized", finalized)
+	e.Debug("Sharing forkchoice-updated signal")
+
+	var result types.ForkchoiceUpdatedResult
+	err := c.CallContext(ctx, &result, method, &heads, payload)
+	if err != nil {
+		e = e.WithError(err)
+		if rpcErr, ok := err.(gethRpc.Error); ok {
+			code := api.ErrorCode(rpcErr.ErrorCode())
+			e.WithField("code", code).Warn("Unexpected error code in forkchoice-updated response")
+		} else {
+			e.Error("Failed to share forkchoice-updated signal")
+		}
+		return result, err
+	}
+	switch result.PayloadStatus.Status {
+	case types.ExecutionValid, types.ExecutionInvalid, types.ExecutionSyncing, types.ExecutionInvalidTerminalBlock:
+	default:
+		return result, fmt.Errorf("%w: %s status %q", ErrInvalidResponse, method, result.PayloadStatus.Status)
+	}
+	if hasPayload && result.PayloadStatus.Status == types.ExecutionValid && result.PayloadID == nil {
+		return result, fmt.Errorf("%w: %s without payload id for valid update with attributes", ErrInvalidResponse, method)
+	}
+	e.Debug("Shared forkchoice-updated signal")
+	if hasPayload {
+		e.WithField("payloadId", result.PayloadID).WithField("status", result.PayloadStatus).Debug("Received payload id")
+	}
+	return result, nil
+}
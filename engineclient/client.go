@@ -0,0 +1,284 @@
+// Package engineclient is a client of the engine API of execution clients, with a typed method
+// for every version of every call, checks of the responses against the rules of the spec, and
+// calls by fork that fall back to the versions the engine supports.
+package engineclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"mergemock/api"
+	"mergemock/rpc"
+	"mergemock/types"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethRpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidResponse is returned for responses that break the rules of the engine API spec.
+var ErrInvalidResponse = errors.New("invalid engine response")
+
+// Capabilities are the engine methods the client calls, as it exchanges them with the engine.
+var Capabilities = []string{
+	"engine_newPayloadV1",
+	"engine_newPayloadV2",
+	"engine_newPayloadV3",
+	"engine_newPayloadV4",
+	"engine_forkchoiceUpdatedV1",
+	"engine_forkchoiceUpdatedV2",
+	"engine_forkchoiceUpdatedV3",
+	"engine_getPayloadV1",
+	"engine_getPayloadV2",
+	"engine_getPayloadV3",
+	"engine_getPayloadV4",
+	"engine_executeStatelessPayloadV1",
+	"engine_executeStatelessPayloadV2",
+	"engine_executeStatelessPayloadV3",
+	"engine_executeStatelessPayloadV4",
+}
+
+// Client calls the engine API of an execution client. The embedded rpc.Client serves the other
+// calls, like those of the eth namespace.
+type Client struct {
+	*rpc.Client
+	log logrus.Ext1FieldLogger
+
+	mu sync.Mutex
+	// capabilities of the engine, nil until negotiated, or if the engine does not support the
+	// exchange
+	capabilities map[string]bool
+}
+
+// New returns a client of the engine API over the RPC client.
+func New(client *rpc.Client, log logrus.Ext1FieldLogger) *Client {
+	return &Client{Client: client, log: log}
+}
+
+type logFieldsKey struct{}
+
+// WithLogFields returns a context whose calls the client logs with the fields, like the
+// correlation ID of the slot the caller makes them for.
+func WithLogFields(ctx context.Context, fields logrus.Fields) context.Context {
+	if prev, ok := ctx.Value(logFieldsKey{}).(logrus.Fields); ok {
+		merged := make(logrus.Fields, len(prev)+len(fields))
+		for k, v := range prev {
+			merged[k] = v
+		}
+		for k, v := range fields {
+			merged[k] = v
+		}
+		fields = merged
+	}
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// logger returns the logger of the client, with the log fields of the context.
+func (c *Client) logger(ctx context.Context) logrus.Ext1FieldLogger {
+	if fields, ok := ctx.Value(logFieldsKey{}).(logrus.Fields); ok {
+		return c.log.WithFields(fields)
+	}
+	return c.log
+}
+
+// ExchangeCapabilities sends the Capabilities of the client to the engine, and returns those of
+// the engine.
+func (c *Client) ExchangeCapabilities(ctx context.Context) ([]string, error) {
+	var result []string
+	if err := c.CallContext(ctx, &result, "engine_exchangeCapabilities", Capabilities); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Negotiate exchanges the capabilities with the engine, after which the calls by fork fall back
+// to the versions the engine supports. Until then, or if the engine does not support the
+// exchange, the client assumes the engine supports all versions.
+func (c *Client) Negotiate(ctx context.Context) error {
+	result, err := c.ExchangeCapabilities(ctx)
+	if err != nil {
+		if rpcErr, ok := err.(gethRpc.Error); ok && api.ErrorCode(rpcErr.ErrorCode()) == api.MethodNotFound {
+			c.log.Debug("Engine does not exchange capabilities, assuming it supports all methods")
+			return nil
+		}
+		return err
+	}
+	capabilities := make(map[string]bool, len(result))
+	for _, method := range result {
+		capabilities[method] = true
+	}
+	c.mu.Lock()
+	c.capabilities = capabilities
+	c.mu.Unlock()
+	c.log.WithField("capabilities", result).Debug("Exchanged capabilities")
+	return nil
+}
+
+// version returns the latest version of the method up to the given one the engine supports,
+// or the given version if the engine supports none, or did not exchange its capabilities.
+func (c *Client) version(method string, version int) int {
+	c.mu.Lock()
+	supported := c.capabilities
+	c.mu.Unlock()
+	if supported == nil {
+		return version
+	}
+	for v := version; v > 0; v-- {
+		if supported[fmt.Sprintf("%sV%d", method, v)] {
+			if v != version {
+				c.log.WithField("method", method).WithField("version", version).WithField("fallback", v).Warn("Engine does not support method version, falling back")
+			}
+			return v
+		}
+	}
+	return version
+}
+
+// Fork is a fork of the execution layer, which decides the versions of the engine calls.
+type Fork int
+
+const (
+	Paris Fork = iota
+	Shanghai
+	Cancun
+	Prague
+)
+
+func (f Fork) String() string {
+	switch f {
+	case Paris:
+		return "paris"
+	case Shanghai:
+		return "shanghai"
+	case Cancun:
+		return "cancun"
+	case Prague:
+		return "prague"
+	default:
+		return fmt.Sprintf("fork(%d)", int(f))
+	}
+}
+
+// newPayloadVersion is the version of engine_newPayload of the fork.
+func (f Fork) newPayloadVersion() int {
+	return int(f) + 1
+}
+
+// forkchoiceUpdatedVersion is the version of engine_forkchoiceUpdated of the fork, which Prague
+// did not change.
+func (f Fork) forkchoiceUpdatedVersion() int {
+	if f >= Cancun {
+		return 3
+	}
+	return int(f) + 1
+}
+
+// getPayloadVersion is the version of engine_getPayload of the fork.
+func (f Fork) getPayloadVersion() int {
+	return int(f) + 1
+}
+
+// NewPayload sends the payload of the fork to the engine, with the latest version of
+// engine_newPayload of the fork the engine supports. The versioned hashes and the parent beacon
+// block root are only sent from Cancun, the requests from Prague.
+func (c *Client) NewPayload(ctx context.Context, fork Fork, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	switch c.version("engine_newPayload", fork.newPayloadVersion()) {
+	case 4:
+		return c.NewPayloadV4(ctx, payload, versionedHashes, beaconRoot, requests)
+	case 3:
+		return c.NewPayloadV3(ctx, payload, versionedHashes, beaconRoot)
+	case 2:
+		return c.NewPayloadV2(ctx, payload.V2())
+	default:
+		return c.NewPayloadV1(ctx, payload.V1())
+	}
+}
+
+// ExecuteStatelessPayload sends the payload of the fork to the engine with its execution witness,
+// to execute it without the state of its parent, with the latest version of
+// engine_executeStatelessPayload of the fork the engine supports. The engine returns the state
+// and receipts roots it computed.
+func (c *Client) ExecuteStatelessPayload(ctx context.Context, fork Fork, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot common.Hash, requests types.ExecutionRequests, witness []byte) (*types.StatelessPayloadStatusV1, error) {
+	switch c.version("engine_executeStatelessPayload", fork.newPayloadVersion()) {
+	case 4:
+		return c.ExecuteStatelessPayloadV4(ctx, payload, versionedHashes, beaconRoot, requests, witness)
+	case 3:
+		return c.ExecuteStatelessPayloadV3(ctx, payload, versionedHashes, beaconRoot, witness)
+	case 2:
+		return c.ExecuteStatelessPayloadV2(ctx, payload.V2(), witness)
+	default:
+		return c.ExecuteStatelessPayloadV1(ctx, payload.V1(), witness)
+	}
+}
+
+// ForkchoiceUpdated updates the forkchoice of the engine, and starts building a payload with the
+// attributes if not nil, with the latest version of engine_forkchoiceUpdated of the fork the
+// engine supports. The withdrawals of the attributes are only sent from Shanghai, the parent
+// beacon block root from Cancun.
+func (c *Client) ForkchoiceUpdated(ctx context.Context, fork Fork, head, safe, finalized common.Hash, attributes *types.PayloadAttributesV3) (types.ForkchoiceUpdatedResult, error) {
+	switch c.version("engine_forkchoiceUpdated", fork.forkchoiceUpdatedVersion()) {
+	case 3:
+		return c.ForkchoiceUpdatedV3(ctx, head, safe, finalized, attributes)
+	case 2:
+		var attrs *types.PayloadAttributesV2
+		if attributes != nil {
+			attrs = &types.PayloadAttributesV2{
+				Timestamp:             attributes.Timestamp,
+				PrevRandao:            attributes.PrevRandao,
+				SuggestedFeeRecipient: attributes.SuggestedFeeRecipient,
+				Withdrawals:           attributes.Withdrawals,
+			}
+		}
+		return c.ForkchoiceUpdatedV2(ctx, head, safe, finalized, attrs)
+	default:
+		var attrs *types.PayloadAttributesV1
+		if attributes != nil {
+			attrs = &types.PayloadAttributesV1{
+				Timestamp:             attributes.Timestamp,
+				PrevRandao:            attributes.PrevRandao,
+				SuggestedFeeRecipient: attributes.SuggestedFeeRecipient,
+			}
+		}
+		return c.ForkchoiceUpdatedV1(ctx, head, safe, finalized, attrs)
+	}
+}
+
+// GetPayload returns the payload the engine built for the fork, with its execution requests
+// from Prague, with the latest version of engine_getPayload of the fork the engine supports.
+func (c *Client) GetPayload(ctx context.Context, fork Fork, payloadId types.PayloadID) (*types.ExecutionPayloadV3, types.ExecutionRequests, error) {
+	payload, requests, _, err := c.GetPayloadWithValue(ctx, fork, payloadId)
+	return payload, requests, err
+}
+
+// GetPayloadWithValue is GetPayload, also returning the block value of the payload, the fees it
+// pays to its fee recipient, from engine_getPayloadV2. The value of older payloads is nil.
+func (c *Client) GetPayloadWithValue(ctx context.Context, fork Fork, payloadId types.PayloadID) (*types.ExecutionPayloadV3, types.ExecutionRequests, *big.Int, error) {
+	switch c.version("engine_getPayload", fork.getPayloadVersion()) {
+	case 4:
+		res, err := c.GetPayloadV4(ctx, payloadId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return res.ExecutionPayload, res.ExecutionRequests, res.BlockValue.ToInt(), nil
+	case 3:
+		res, err := c.GetPayloadV3(ctx, payloadId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return res.ExecutionPayload, nil, res.BlockValue.ToInt(), nil
+	case 2:
+		res, err := c.GetPayloadV2(ctx, payloadId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return res.ExecutionPayload.V3(), nil, res.BlockValue.ToInt(), nil
+	default:
+		payload, err := c.GetPayloadV1(ctx, payloadId)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return payload.V3(), nil, nil, nil
+	}
+}
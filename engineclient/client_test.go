@@ -0,0 +1,171 @@
+package engineclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"mergemock/rpc"
+	"mergemock/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+// testEngine answers engine calls with the results by method, records the methods called, and
+// answers methods without a result as not found.
+type testEngine struct {
+	results map[string]interface{}
+	calls   []string
+}
+
+func (e *testEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e.calls = append(e.calls, req.Method)
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	if result, ok := e.results[req.Method]; ok {
+		resp["result"] = result
+	} else {
+		resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newTestClient(t *testing.T, engine *testEngine) *Client {
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+	client, err := rpc.DialContext(context.Background(), srv.URL, []byte{})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return New(client, logrus.New())
+}
+
+var validStatus = map[string]interface{}{"status": "VALID"}
+
+func TestVersionFallback(t *testing.T) {
+	engine := &testEngine{results: map[string]interface{}{
+		"engine_exchangeCapabilities": []string{"engine_forkchoiceUpdatedV1", "engine_forkchoiceUpdatedV2"},
+		"engine_forkchoiceUpdatedV2":  map[string]interface{}{"payloadStatus": validStatus},
+	}}
+	client := newTestClient(t, engine)
+	ctx := context.Background()
+	require.NoError(t, client.Negotiate(ctx))
+
+	_, err := client.ForkchoiceUpdated(ctx, Cancun, common.Hash{1}, common.Hash{1}, common.Hash{1}, nil)
+	require.NoError(t, err)
+	_, err = client.ForkchoiceUpdated(ctx, Shanghai, common.Hash{1}, common.Hash{1}, common.Hash{1}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"engine_exchangeCapabilities", "engine_forkchoiceUpdatedV2", "engine_forkchoiceUpdatedV2"}, engine.calls)
+}
+
+func TestNoCapabilities(t *testing.T) {
+	engine := &testEngine{results: map[string]interface{}{
+		"engine_newPayloadV4": validStatus,
+	}}
+	client := newTestClient(t, engine)
+	ctx := context.Background()
+	require.NoError(t, client.Negotiate(ctx))
+
+	payload := &types.ExecutionPayloadV3{BaseFeePerGas: common.Big1, Withdrawals: types.Withdrawals{}}
+	for i := 0; i < 2; i++ {
+		res, err := client.NewPayload(ctx, Prague, payload, []common.Hash{}, common.Hash{2}, types.ExecutionRequests{})
+		require.NoError(t, err)
+		require.Equal(t, types.ExecutionValid, res.Status)
+	}
+	require.Equal(t, []string{"engine_exchangeCapabilities", "engine_newPayloadV4", "engine_newPayloadV4"}, engine.calls)
+}
+
+func TestGetPayloadWithValue(t *testing.T) {
+	payload := &types.ExecutionPayloadV2{BaseFeePerGas: common.Big1, Transactions: [][]byte{}, Withdrawals: types.Withdrawals{}}
+	engine := &testEngine{results: map[string]interface{}{
+		"engine_getPayloadV1": payload.V1(),
+		"engine_getPayloadV2": map[string]interface{}{"executionPayload": payload, "blockValue": "0x2a"},
+	}}
+	client := newTestClient(t, engine)
+	ctx := context.Background()
+
+	_, _, value, err := client.GetPayloadWithValue(ctx, Shanghai, types.PayloadID{1})
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(42), value)
+	// payloads before Shanghai have no value
+	_, _, value, err = client.GetPayloadWithValue(ctx, Paris, types.PayloadID{1})
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestInvalidResponses(t *testing.T) {
+	engine := &testEngine{results: map[string]interface{}{
+		"engine_newPayloadV1":        map[string]interface{}{"status": "DONE"},
+		"engine_forkchoiceUpdatedV1": map[string]interface{}{"payloadStatus": validStatus},
+		"engine_getPayloadV2":        map[string]interface{}{"executionPayload": nil, "blockValue": "0x0"},
+	}}
+	client := newTestClient(t, engine)
+	ctx := context.Background()
+
+	payload := &types.ExecutionPayloadV3{BaseFeePerGas: common.Big1}
+	_, err := client.NewPayloadV1(ctx, payload.V1())
+	require.ErrorIs(t, err, ErrInvalidResponse)
+
+	// a valid update with attributes must start building a payload
+	_, err = client.ForkchoiceUpdatedV1(ctx, common.Hash{1}, common.Hash{1}, common.Hash{1}, &types.PayloadAttributesV1{Timestamp: 1})
+	require.ErrorIs(t, err, ErrInvalidResponse)
+	_, err = client.ForkchoiceUpdatedV1(ctx, common.Hash{1}, common.Hash{1}, common.Hash{1}, nil)
+	require.NoError(t, err)
+
+	_, err = client.GetPayloadV2(ctx, types.PayloadID{1})
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
+
+func TestExecuteStatelessPayload(t *testing.T) {
+	engine := &testEngine{results: map[string]interface{}{
+		"engine_exchangeCapabilities":      []string{"engine_executeStatelessPayloadV3"},
+		"engine_executeStatelessPayloadV3": map[string]interface{}{"status": "VALID", "stateRoot": common.Hash{1}, "receiptsRoot": common.Hash{2}},
+		"engine_executeStatelessPayloadV1": map[string]interface{}{"status": "SYNCING"},
+	}}
+	client := newTestClient(t, engine)
+	ctx := context.Background()
+	require.NoError(t, client.Negotiate(ctx))
+
+	payload := &types.ExecutionPayloadV3{BaseFeePerGas: common.Big1, Withdrawals: types.Withdrawals{}}
+	res, err := client.ExecuteStatelessPayload(ctx, Prague, payload, []common.Hash{}, common.Hash{3}, types.ExecutionRequests{}, []byte{0xc0})
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{1}, res.StateRoot)
+	require.Equal(t, common.Hash{2}, res.ReceiptsRoot)
+
+	// there is no syncing without the state
+	_, err = client.ExecuteStatelessPayloadV1(ctx, payload.V1(), []byte{0xc0})
+	require.ErrorIs(t, err, ErrInvalidResponse)
+	require.Equal(t, []string{"engine_exchangeCapabilities", "engine_executeStatelessPayloadV3", "engine_executeStatelessPayloadV1"}, engine.calls)
+}
+
+func TestLogFields(t *testing.T) {
+	engine := &testEngine{results: map[string]interface{}{
+		"engine_newPayloadV1": validStatus,
+	}}
+	client := newTestClient(t, engine)
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+	client.log = log
+
+	ctx := WithLogFields(context.Background(), logrus.Fields{"corr": "slot-1", "slot": 1})
+	ctx = WithLogFields(ctx, logrus.Fields{"slot": 2})
+	payload := &types.ExecutionPayloadV3{BaseFeePerGas: common.Big1}
+	_, err := client.NewPayloadV1(ctx, payload.V1())
+	require.NoError(t, err)
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "slot-1", entry.Data["corr"])
+	require.Equal(t, 2, entry.Data["slot"])
+}
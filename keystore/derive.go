@@ -0,0 +1,119 @@
+package keystore
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// curveOrder is the order r of the BLS12-381 curve, which secret keys are reduced modulo.
+var curveOrder, _ = new(big.Int).SetString("52435875175126190479447740508185965837690552500527637822603658699938581184513", 10)
+
+// Seed returns the BIP-39 seed of the mnemonic and the passphrase. The words are not checked
+// against the BIP-39 wordlist, which mergemock does not ship, and are not NFKD normalized, which
+// only matters for mnemonics and passphrases that are not ASCII.
+func Seed(mnemonic, passphrase string) []byte {
+	words := strings.Join(strings.Fields(mnemonic), " ")
+	return pbkdf2.Key([]byte(words), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+}
+
+// hkdfModR derives a secret key from the input key material, as the HKDF_mod_r function of
+// EIP-2333.
+func hkdfModR(ikm []byte) *big.Int {
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	sk := new(big.Int)
+	for sk.Sign() == 0 {
+		h := sha256.Sum256(salt)
+		salt = h[:]
+		okm := make([]byte, 48)
+		// the info is the empty key info, followed by the length of the output as two bytes
+		r := hkdf.New(sha256.New, append(ikm, 0), salt, []byte{0, 48})
+		if _, err := io.ReadFull(r, okm); err != nil {
+			panic(err)
+		}
+		sk.Mod(new(big.Int).SetBytes(okm), curveOrder)
+	}
+	return sk
+}
+
+// ikmToLamportSK expands the input key material to the 255 chunks of a Lamport secret key.
+func ikmToLamportSK(ikm, salt []byte) [][]byte {
+	okm := make([]byte, 32*255)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, nil), okm); err != nil {
+		panic(err)
+	}
+	chunks := make([][]byte, 255)
+	for i := range chunks {
+		chunks[i] = okm[i*32 : (i+1)*32]
+	}
+	return chunks
+}
+
+// parentSKToLamportPK returns the compressed Lamport public key of the parent key and the index.
+func parentSKToLamportPK(parent *big.Int, index uint32) []byte {
+	salt := make([]byte, 4)
+	binary.BigEndian.PutUint32(salt, index)
+	ikm := make([]byte, 32)
+	parent.FillBytes(ikm)
+	notIkm := make([]byte, 32)
+	for i := range ikm {
+		notIkm[i] = ^ikm[i]
+	}
+	h := sha256.New()
+	for _, chunks := range [][][]byte{ikmToLamportSK(ikm, salt), ikmToLamportSK(notIkm, salt)} {
+		for _, chunk := range chunks {
+			sum := sha256.Sum256(chunk)
+			h.Write(sum[:])
+		}
+	}
+	return h.Sum(nil)
+}
+
+// deriveMaster returns the master secret key of the seed, as derive_master_SK of EIP-2333.
+func deriveMaster(seed []byte) (*big.Int, error) {
+	if len(seed) < 32 {
+		return nil, fmt.Errorf("seed of %d bytes is shorter than 32 bytes", len(seed))
+	}
+	return hkdfModR(seed), nil
+}
+
+// deriveChild returns the child secret key of the parent at the index, as derive_child_SK of
+// EIP-2333.
+func deriveChild(parent *big.Int, index uint32) *big.Int {
+	return hkdfModR(parentSKToLamportPK(parent, index))
+}
+
+// SigningKeyPath returns the EIP-2334 path of the signing key of the validator at the index.
+func SigningKeyPath(index uint32) string {
+	return fmt.Sprintf("m/12381/3600/%d/0/0", index)
+}
+
+// DeriveKey returns the secret key of the seed at the EIP-2334 path, like m/12381/3600/0/0/0.
+func DeriveKey(seed []byte, path string) (bls.SecretKey, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 1 || parts[0] != "m" {
+		return nil, fmt.Errorf("path %q does not start at the master key m", path)
+	}
+	sk, err := deriveMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts[1:] {
+		var index uint32
+		if _, err := fmt.Sscanf(part, "%d", &index); err != nil || fmt.Sprint(index) != part {
+			return nil, fmt.Errorf("invalid index %q in path %q", part, path)
+		}
+		sk = deriveChild(sk, index)
+	}
+	raw := make([]byte, 32)
+	sk.FillBytes(raw)
+	return bls.SecretKeyFromBytes(raw)
+}
@@ -0,0 +1,36 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	// the BIP-39 test vector the EIP-2333 test vectors start from
+	seed := Seed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "TREZOR")
+	require.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04", hex.EncodeToString(seed))
+}
+
+func TestDeriveKey(t *testing.T) {
+	seed, err := hex.DecodeString("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+	require.NoError(t, err)
+	master, ok := new(big.Int).SetString("6083874454709270928345386274498605044986640685124978867557563392430687146096", 10)
+	require.True(t, ok)
+	child, ok := new(big.Int).SetString("20397789859736650942317412262472558107875392172444076792671091975210932703118", 10)
+	require.True(t, ok)
+
+	sk, err := DeriveKey(seed, "m")
+	require.NoError(t, err)
+	require.Equal(t, master.FillBytes(make([]byte, 32)), sk.Marshal())
+	sk, err = DeriveKey(seed, "m/0")
+	require.NoError(t, err)
+	require.Equal(t, child.FillBytes(make([]byte, 32)), sk.Marshal())
+
+	_, err = DeriveKey(seed, "m/x")
+	require.Error(t, err)
+	_, err = DeriveKey(seed[:16], "m")
+	require.Error(t, err)
+}
@@ -0,0 +1,303 @@
+// Package keystore derives validator keys from mnemonics as EIP-2333 and EIP-2334 do, and
+// encrypts them in EIP-2335 keystores, so the keys of the consensus mock are those of standard
+// validator tooling.
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF parameters of the keystores, those of the EIP-2335 test vectors and of the deposit CLI.
+const (
+	KDFScrypt = "scrypt"
+	KDFPBKDF2 = "pbkdf2"
+
+	scryptN = 262144
+	scryptR = 8
+	scryptP = 1
+	pbkdf2C = 262144
+	dkLen   = 32
+)
+
+// Module is a function of a keystore, with its parameters and message.
+type Module struct {
+	Function string                 `json:"function"`
+	Params   map[string]interface{} `json:"params"`
+	Message  string                 `json:"message"`
+}
+
+// Keystore is an EIP-2335 keystore of a BLS secret key.
+type Keystore struct {
+	Crypto struct {
+		KDF      Module `json:"kdf"`
+		Checksum Module `json:"checksum"`
+		Cipher   Module `json:"cipher"`
+	} `json:"crypto"`
+	Description string `json:"description"`
+	Pubkey      string `json:"pubkey"`
+	Path        string `json:"path"`
+	UUID        string `json:"uuid"`
+	Version     int    `json:"version"`
+}
+
+// processPassword removes the control codes from the password, as EIP-2335 requires. The
+// password is not NFKD normalized, which only matters for passwords that are not ASCII.
+func processPassword(password string) []byte {
+	return []byte(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, password))
+}
+
+// randomBytes returns n random bytes.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// decryptionKey derives the decryption key of the password with the KDF module.
+func decryptionKey(kdf Module, password string) ([]byte, error) {
+	salt, err := hex.DecodeString(paramString(kdf.Params, "salt"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	pw := processPassword(password)
+	switch kdf.Function {
+	case KDFScrypt:
+		return scrypt.Key(pw, salt, paramInt(kdf.Params, "n"), paramInt(kdf.Params, "r"), paramInt(kdf.Params, "p"), paramInt(kdf.Params, "dklen"))
+	case KDFPBKDF2:
+		if prf := paramString(kdf.Params, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", prf)
+		}
+		return pbkdf2.Key(pw, salt, paramInt(kdf.Params, "c"), paramInt(kdf.Params, "dklen"), sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf.Function)
+	}
+}
+
+func paramString(params map[string]interface{}, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
+
+func paramInt(params map[string]interface{}, key string) int {
+	// JSON numbers decode as float64
+	switch v := params[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// aes128CTR encrypts or decrypts the message with the first 16 bytes of the key.
+func aes128CTR(key, iv, msg []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(msg))
+	cipher.NewCTR(block, iv).XORKeyStream(out, msg)
+	return out, nil
+}
+
+// checksum returns the checksum of the cipher message with the decryption key.
+func checksum(key, cipherMessage []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{}, key[16:32]...), cipherMessage...))
+	return sum[:]
+}
+
+// Encrypt returns a keystore of the secret key at the EIP-2334 path, empty for keys not derived
+// from a mnemonic, encrypted with the password with the KDF, scrypt or pbkdf2.
+func Encrypt(sk bls.SecretKey, password, path, kdf string) (*Keystore, error) {
+	salt, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	ks := &Keystore{Pubkey: hex.EncodeToString(sk.PublicKey().Marshal()), Path: path, UUID: id, Version: 4}
+	switch kdf {
+	case KDFScrypt:
+		ks.Crypto.KDF = Module{Function: KDFScrypt, Params: map[string]interface{}{"dklen": dkLen, "n": scryptN, "r": scryptR, "p": scryptP, "salt": hex.EncodeToString(salt)}}
+	case KDFPBKDF2:
+		ks.Crypto.KDF = Module{Function: KDFPBKDF2, Params: map[string]interface{}{"dklen": dkLen, "c": pbkdf2C, "prf": "hmac-sha256", "salt": hex.EncodeToString(salt)}}
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf)
+	}
+	key, err := decryptionKey(ks.Crypto.KDF, password)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := aes128CTR(key, iv, sk.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	ks.Crypto.Cipher = Module{Function: "aes-128-ctr", Params: map[string]interface{}{"iv": hex.EncodeToString(iv)}, Message: hex.EncodeToString(msg)}
+	ks.Crypto.Checksum = Module{Function: "sha256", Params: map[string]interface{}{}, Message: hex.EncodeToString(checksum(key, msg))}
+	return ks, nil
+}
+
+// Decrypt returns the secret key of the keystore, after checking the password with the checksum
+// and the key with the public key of the keystore.
+func (ks *Keystore) Decrypt(password string) (bls.SecretKey, error) {
+	if ks.Version != 4 {
+		return nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto.Checksum.Function != "sha256" || ks.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported checksum %q or cipher %q", ks.Crypto.Checksum.Function, ks.Crypto.Cipher.Function)
+	}
+	key, err := decryptionKey(ks.Crypto.KDF, password)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < 32 {
+		return nil, fmt.Errorf("decryption key of %d bytes is shorter than 32 bytes", len(key))
+	}
+	msg, err := hex.DecodeString(ks.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cipher message: %w", err)
+	}
+	sum, err := hex.DecodeString(ks.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum: %w", err)
+	}
+	if !bytes.Equal(checksum(key, msg), sum) {
+		return nil, fmt.Errorf("wrong password for keystore %s", ks.UUID)
+	}
+	iv, err := hex.DecodeString(paramString(ks.Crypto.Cipher.Params, "iv"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid iv: %w", err)
+	}
+	raw, err := aes128CTR(key, iv, msg)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := bls.SecretKeyFromBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Pubkey != "" && hex.EncodeToString(sk.PublicKey().Marshal()) != strings.TrimPrefix(ks.Pubkey, "0x") {
+		return nil, fmt.Errorf("keystore %s does not hold the key of its public key", ks.UUID)
+	}
+	return sk, nil
+}
+
+// Load reads the keystore at the path.
+func Load(path string) (*Keystore, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ks := new(Keystore)
+	if err := json.Unmarshal(raw, ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore %s: %w", path, err)
+	}
+	return ks, nil
+}
+
+// Save writes the keystore to the path, readable only by the user.
+func (ks *Keystore) Save(path string) error {
+	raw, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0600)
+}
+
+// SigningKeyIndex returns the validator index of an EIP-2334 signing key path, and false for
+// other paths.
+func SigningKeyIndex(path string) (uint32, bool) {
+	var index uint32
+	if _, err := fmt.Sscanf(path, "m/12381/3600/%d/0/0", &index); err != nil || SigningKeyPath(index) != path {
+		return 0, false
+	}
+	return index, true
+}
+
+// LoadDir reads the keystore*.json keystores in the directory, ordered by the validator index of
+// their signing key path, and then by file name for keystores without one.
+func LoadDir(dir string) ([]*Keystore, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "keystore*.json"))
+	if err != nil {
+		return nil, err
+	}
+	keystores := make([]*Keystore, 0, len(paths))
+	for _, path := range paths {
+		ks, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		keystores = append(keystores, ks)
+	}
+	order := func(i int) (uint64, string) {
+		if index, ok := SigningKeyIndex(keystores[i].Path); ok {
+			return uint64(index), paths[i]
+		}
+		return math.MaxUint64, paths[i]
+	}
+	sort.Sort(byOrder{keystores, paths, order})
+	return keystores, nil
+}
+
+// byOrder sorts keystores and their paths together.
+type byOrder struct {
+	keystores []*Keystore
+	paths     []string
+	order     func(i int) (uint64, string)
+}
+
+func (b byOrder) Len() int { return len(b.keystores) }
+
+func (b byOrder) Less(i, j int) bool {
+	ii, pi := b.order(i)
+	ij, pj := b.order(j)
+	if ii != ij {
+		return ii < ij
+	}
+	return pi < pj
+}
+
+func (b byOrder) Swap(i, j int) {
+	b.keystores[i], b.keystores[j] = b.keystores[j], b.keystores[i]
+	b.paths[i], b.paths[j] = b.paths[j], b.paths[i]
+}
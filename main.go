@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mergemock/mock"
 	"os"
 	"os/signal"
 	"time"
@@ -11,31 +12,6 @@ import (
 	"github.com/protolambda/ask"
 )
 
-type MergeMockCmd struct {
-}
-
-func (c *MergeMockCmd) Help() string {
-	return "Run MergeMock. Either mock a consensus node or execution engine."
-}
-
-func (c *MergeMockCmd) Cmd(route string) (cmd interface{}, err error) {
-	switch route {
-	case "consensus":
-		cmd = &ConsensusCmd{}
-	case "engine":
-		cmd = &EngineCmd{}
-	case "relay":
-		cmd = &RelayCmd{}
-	default:
-		return nil, ask.UnrecognizedErr
-	}
-	return
-}
-
-func (c *MergeMockCmd) Routes() []string {
-	return []string{"consensus", "engine", "relay"}
-}
-
 type start struct {
 	cmd *ask.CommandDescription
 	err error
@@ -46,7 +22,7 @@ func main() {
 	signal.Notify(interrupt, os.Interrupt)
 	ctx, cancel := context.WithCancel(context.Background())
 
-	cmd := &MergeMockCmd{}
+	cmd := &mock.MergeMockCmd{}
 	descr, err := ask.Load(cmd)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "failed to load main command: %v", err.Error())
@@ -87,7 +63,7 @@ func main() {
 				_, _ = fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
 			} else if err == ask.HelpErr {
-				_, _ = fmt.Fprintln(os.Stderr, cmd.Usage(false))
+				_, _ = fmt.Fprintln(os.Stderr, mock.Usage(cmd))
 				os.Exit(0)
 			} else {
 				_, _ = fmt.Fprintln(os.Stderr, err.Error())
@@ -0,0 +1,132 @@
+package mock
+
+import (
+	"context"
+	"math/big"
+	"mergemock/types"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+const accessListTxGas = 60_000
+
+// accessListTxCreator makes a transfer with an access list from the first test account, alternating
+// between an EIP-2930 access list transaction and an EIP-1559 transaction with an access list.
+// The list warms the recipient with storage keys, a precompile, and the coinbase of the block.
+func accessListTxCreator(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+	if len(accounts) == 0 {
+		return nil
+	}
+	sender := accounts[0].addr
+	recipient := accounts[len(accounts)-1].addr
+	if recipient == sender {
+		recipient = common.Address{0x42}
+	}
+	accessList := ethTypes.AccessList{
+		{Address: recipient, StorageKeys: []common.Hash{{}, common.BigToHash(header.Number)}},
+		{Address: common.BytesToAddress([]byte{1})},
+		{Address: header.Coinbase},
+	}
+	feeCap := new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei))
+	cost := new(big.Int).Add(big.NewInt(1), new(big.Int).Mul(feeCap, big.NewInt(accessListTxGas)))
+	if statedb.GetBalance(sender).Cmp(cost) < 0 {
+		// out of funds, the transaction would make the block invalid
+		return nil
+	}
+	var txdata ethTypes.TxData
+	if header.Number.Uint64()%2 == 0 {
+		txdata = &ethTypes.AccessListTx{
+			ChainID:    config.ChainID,
+			Nonce:      statedb.GetNonce(sender),
+			GasPrice:   feeCap,
+			Gas:        accessListTxGas,
+			To:         &recipient,
+			Value:      big.NewInt(1),
+			AccessList: accessList,
+		}
+	} else {
+		txdata = &ethTypes.DynamicFeeTx{
+			ChainID:    config.ChainID,
+			Nonce:      statedb.GetNonce(sender),
+			GasTipCap:  big.NewInt(2),
+			GasFeeCap:  feeCap,
+			Gas:        accessListTxGas,
+			To:         &recipient,
+			Value:      big.NewInt(1),
+			AccessList: accessList,
+		}
+	}
+	tx, err := ethTypes.SignTx(ethTypes.NewTx(txdata), ethTypes.NewLondonSigner(config.ChainID), accounts[0].pk)
+	if err != nil {
+		return nil
+	}
+	return []*ethTypes.Transaction{tx}
+}
+
+// submitEngineTxs creates the transactions of the tx profile on top of the parent block, and
+// submits them to the engine over eth_sendRawTransaction, for the payload it builds next.
+func (c *ConsensusCmd) submitEngineTxs(log logrus.Ext1FieldLogger, parent *ethTypes.Block, attributes *types.PayloadAttributesV3) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	statedb, err := c.mockChain.chain.StateAt(parent.Root())
+	if err != nil {
+		log.WithError(err).Warn("Unable to create transactions for the engine")
+		return
+	}
+	header := &ethTypes.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		Time:       attributes.Timestamp,
+		Coinbase:   attributes.SuggestedFeeRecipient,
+		GasLimit:   parent.GasLimit(),
+		BaseFee:    misc.CalcBaseFee(c.mockChain.chain.Config(), parent.Header()),
+	}
+	creator := c.txCreatorFn()
+	txs := creator(c.mockChain.chain.Config(), c.mockChain.chain, statedb, header, vm.Config{}, c.ConsensusBehavior.TestAccounts.accounts)
+	if c.TxProfile == "bundles" && len(txs) > 1 {
+		c.submitEngineBundle(ctx, log, txs, header.Number.Uint64())
+		return
+	}
+	for _, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			log.WithError(err).Warn("Unable to encode transaction for the engine")
+			continue
+		}
+		var hash common.Hash
+		if err := c.engine.CallContext(ctx, &hash, "eth_sendRawTransaction", hexutil.Bytes(raw)); err != nil {
+			log.WithError(err).WithField("tx", tx.Hash()).Warn("Engine refused transaction")
+			continue
+		}
+		log.WithFields(logrus.Fields{"tx": tx.Hash(), "type": tx.Type()}).Debug("Submitted transaction to engine")
+	}
+}
+
+// submitEngineBundle submits the transactions to the engine as a bundle for the block number,
+// over eth_sendBundle, for the engine to keep them in order.
+func (c *ConsensusCmd) submitEngineBundle(ctx context.Context, log logrus.Ext1FieldLogger, txs []*ethTypes.Transaction, number uint64) {
+	args := SendBundleArgs{BlockNumber: hexutil.Uint64(number)}
+	for _, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			log.WithError(err).Warn("Unable to encode bundle for the engine")
+			return
+		}
+		args.Txs = append(args.Txs, raw)
+	}
+	var res SendBundleResult
+	if err := c.engine.CallContext(ctx, &res, "eth_sendBundle", args); err != nil {
+		log.WithError(err).Warn("Engine refused bundle")
+		return
+	}
+	log.WithFields(logrus.Fields{"bundle": res.BundleHash, "txs": len(txs)}).Debug("Submitted bundle to engine")
+}
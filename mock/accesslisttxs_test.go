@@ -0,0 +1,99 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessListTxs(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, newMergedGenesis(account.addr), rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+
+	creator := TransactionsCreator{[]TestAccount{account}, accessListTxCreator}
+	for _, txType := range []uint8{ethTypes.DynamicFeeTxType, ethTypes.AccessListTxType} {
+		parent := mc.CurrentHeader()
+		block, err := mc.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		require.Len(t, block.Transactions(), 1)
+		tx := block.Transactions()[0]
+		require.Equal(t, txType, tx.Type())
+		require.Len(t, tx.AccessList(), 3)
+		receipts := mc.chain.GetReceiptsByHash(block.Hash())
+		require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipts[0].Status)
+		// the intrinsic gas of the transfer and the access list, of three addresses and two keys
+		require.Equal(t, uint64(21_000+3*2_400+2*1_900), receipts[0].GasUsed)
+	}
+}
+
+// rawTxEngine is an engine that records the transactions submitted over eth_sendRawTransaction.
+type rawTxEngine struct {
+	txs []hexutil.Bytes
+}
+
+func (e *rawTxEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []hexutil.Bytes `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_sendRawTransaction" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	e.txs = append(e.txs, req.Params[0])
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": common.Hash{}})
+}
+
+func TestSubmitEngineTxs(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, newMergedGenesis(account.addr), rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	c := &ConsensusCmd{}
+	c.ctx = context.Background()
+	c.mockChain = mc
+	c.TxProfile = "access-list"
+	c.ConsensusBehavior.TestAccounts.accounts = []TestAccount{account}
+
+	engine := &rawTxEngine{}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c.engine = dialEngine(t, srv.URL)
+
+	head := mc.chain.CurrentBlock()
+	attributes := &types.PayloadAttributesV3{Timestamp: head.Time() + 1, SuggestedFeeRecipient: common.Address{1}}
+	c.submitEngineTxs(logrus.New(), head, attributes)
+	require.Len(t, engine.txs, 1)
+
+	// the transaction is valid on top of the head, in the next block
+	var tx ethTypes.Transaction
+	require.NoError(t, tx.UnmarshalBinary(engine.txs[0]))
+	require.Equal(t, uint8(ethTypes.DynamicFeeTxType), tx.Type())
+	creator := TransactionsCreator{nil, func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+		return []*ethTypes.Transaction{&tx}
+	}}
+	block, err := mc.AddNewBlock(head.Hash(), common.Address{1}, head.Time()+1, head.GasLimit(), creator, common.Hash{}, nil, nil, nil, nil, true)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), 1)
+}
@@ -0,0 +1,83 @@
+package mock
+
+import (
+	"bytes"
+	"io"
+	"mergemock/rpc"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogConfig configures the access log of sampled requests and engine calls.
+type AccessLogConfig struct {
+	Sample    float64  `ask:"--sample" help:"Fraction of requests to log with their sizes and bodies, from 0 for none to 1 for all"`
+	BodyLimit int      `ask:"--body-limit" help:"Bytes of request and response bodies to log, longer ones are truncated (0 to log no bodies)"`
+	Redact    []string `ask:"--redact" help:"JSON fields to log the values of as redacted, at any depth of the bodies"`
+}
+
+func (a *AccessLogConfig) Default() {
+	a.BodyLimit = 1024
+	a.Redact = []string{"secret_key", "private_key"}
+}
+
+// AccessLog returns the access log, nil if no requests are sampled.
+func (a *AccessLogConfig) AccessLog(log logrus.Ext1FieldLogger) *rpc.AccessLog {
+	return rpc.NewAccessLog(a.Sample, a.BodyLimit, a.Redact, log)
+}
+
+// accessWriter is a http.ResponseWriter that keeps the status and body of the response.
+type accessWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *accessWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// AccessLogMiddleware logs the sampled requests to the access log, with the bodies as the
+// handler reads and writes them.
+func AccessLogMiddleware(next http.Handler, access *rpc.AccessLog) http.Handler {
+	if access == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !access.Sampled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		request, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(request))
+		start := time.Now()
+		wrapped := &accessWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrapped, r)
+		access.Log("http: "+r.Method+" "+r.URL.EscapedPath(), logrus.Fields{
+			"method":        r.Method,
+			"path":          r.URL.RequestURI(),
+			"status":        wrapped.status,
+			"durationMs":    time.Since(start).Milliseconds(),
+			"request_size":  len(request),
+			"response_size": wrapped.body.Len(),
+			"request":       access.Body(request),
+			"response":      access.Body(wrapped.body.Bytes()),
+		})
+	})
+}
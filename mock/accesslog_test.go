@@ -0,0 +1,48 @@
+package mock
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mergemock/rpc"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	handler := AccessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, `{"got":%s}`, body)
+	}), rpc.NewAccessLog(1, 1024, []string{"secret_key"}, log))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/path?x=1", strings.NewReader(`{"secret_key":"0x01"}`)))
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	require.Equal(t, `{"got":{"secret_key":"0x01"}}`, rr.Body.String(), "the handler gets the request body")
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "http: POST /path", entry.Message)
+	require.Equal(t, "/path?x=1", entry.Data["path"])
+	require.Equal(t, http.StatusAccepted, entry.Data["status"])
+	require.Equal(t, 21, entry.Data["request_size"])
+	require.Equal(t, `{"secret_key":"[redacted]"}`, entry.Data["request"])
+	require.Equal(t, `{"got":{"secret_key":"[redacted]"}}`, entry.Data["response"])
+
+	// without sampling, requests pass through
+	next := http.NotFoundHandler()
+	require.NotNil(t, AccessLogMiddleware(next, nil))
+	hook.Reset()
+	rr = httptest.NewRecorder()
+	AccessLogMiddleware(next, rpc.NewAccessLog(0, 1024, nil, log)).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Nil(t, hook.LastEntry())
+}
@@ -0,0 +1,151 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// AncientConfig configures re-sending blocks far behind the finalized block to the engine, which
+// it may have pruned.
+type AncientConfig struct {
+	Depth uint64 `ask:"--depth" help:"Re-send the payload of the block this many blocks before the finalized block to the engine, and update the forkchoice to it, to classify how the engine handles blocks it may have pruned (0 to disable)"`
+	Slots uint64 `ask:"--slots" help:"Re-send an ancient block every this many slots"`
+}
+
+func (a *AncientConfig) Default() {
+	a.Slots = 32
+}
+
+// responseCounts counts the classified responses of the engine to probes, like ancient blocks, by
+// call.
+type responseCounts struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int
+}
+
+// add counts the response to the call, and returns the counts of the call so far.
+func (r *responseCounts) add(call, class string) map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counts == nil {
+		r.counts = make(map[string]map[string]int)
+	}
+	if r.counts[call] == nil {
+		r.counts[call] = make(map[string]int)
+	}
+	r.counts[call][class]++
+	counts := make(map[string]int, len(r.counts[call]))
+	for k, v := range r.counts[call] {
+		counts[k] = v
+	}
+	return counts
+}
+
+// classifyResponse returns the class of a response of the engine: its lowercase status, or the
+// error code of a JSON-RPC error.
+func classifyResponse(status types.ExecutePayloadStatus, err error) string {
+	if err != nil {
+		var rpcErr interface{ ErrorCode() int }
+		if errors.As(err, &rpcErr) {
+			return fmt.Sprintf("error %d", rpcErr.ErrorCode())
+		}
+		return "error"
+	}
+	return strings.ToLower(string(status))
+}
+
+// checkAncient re-sends the payload of the canonical block the configured depth before the
+// finalized block to the engine, followed by a forkchoice update to it, and classifies the
+// responses. The engine must not consider the block invalid: it is finalized. Any other response
+// is how the engine handles blocks it may have pruned, and is only logged. The forkchoice of the
+// engine is restored afterwards.
+func (c *ConsensusCmd) checkAncient(slot uint64) {
+	log := c.slotLog(slot)
+	final := c.state.Finalized()
+	finalHeader := c.mockChain.chain.GetHeaderByHash(common.Hash(final.Root))
+	if finalHeader == nil || finalHeader.Number.Uint64() < c.Ancient.Depth {
+		log.Debug("Finalized block is not deep enough yet for ancient blocks")
+		return
+	}
+	block := c.mockChain.chain.GetBlockByNumber(finalHeader.Number.Uint64() - c.Ancient.Depth)
+	if block == nil {
+		log.Debug("Ancient block is not in the mock chain")
+		return
+	}
+	log = log.WithField("ancient", block.NumberU64()).WithField("blockhash", block.Hash())
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	if payload, beaconRoot, requests, ok := c.ancientPayload(log, block.Hash()); ok {
+		res, err := c.newPayload(ctx, log, payload, beaconRoot, requests)
+		if err != nil && c.engineOffline(err) {
+			return
+		}
+		var status types.ExecutePayloadStatus
+		if res != nil {
+			status = res.Status
+		}
+		c.reportAncient(log, slot, block.Hash(), scenarioAncientPayload, status, err)
+	}
+
+	result, err := c.forkchoiceUpdated(c.engine, block.Hash(), common.Hash{}, common.Hash{}, nil)
+	if err != nil && c.engineOffline(err) {
+		return
+	}
+	c.reportAncient(log, slot, block.Hash(), scenarioAncientForkchoice, result.PayloadStatus.Status, err)
+	if fc := c.forkchoice.get(); fc != nil {
+		if _, err := c.forkchoiceUpdated(c.engine, fc.HeadBlockHash, fc.SafeBlockHash, fc.FinalizedBlockHash, nil); err != nil {
+			log.WithError(err).Warn("Failed to restore forkchoice after ancient block")
+		}
+	}
+}
+
+// ancientPayload returns the payload of a block of the mock chain, with the beacon root and
+// requests it was built with, if they are still known.
+func (c *ConsensusCmd) ancientPayload(log logrus.Ext1FieldLogger, hash common.Hash) (*types.ExecutionPayloadV3, *common.Hash, types.ExecutionRequests, bool) {
+	block := c.mockChain.chain.GetBlockByHash(hash)
+	withdrawals, beaconRoot := c.mockChain.Extras(hash)
+	if c.mockChain.forks.IsCancun(block.Time()) && beaconRoot == nil {
+		log.Debug("Beacon root of ancient block is not known, only updating forkchoice")
+		return nil, nil, nil, false
+	}
+	requests, ok := c.mockChain.ExecutionRequests(hash)
+	if c.mockChain.forks.IsPrague(block.Time()) && !ok {
+		log.Debug("Execution requests of ancient block are not known, only updating forkchoice")
+		return nil, nil, nil, false
+	}
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+	if err != nil {
+		log.WithError(err).Error("Failed to convert ancient block to execution payload")
+		return nil, nil, nil, false
+	}
+	return payload, beaconRoot, requests, true
+}
+
+// reportAncient logs the classified response of the engine to the ancient block, and reports
+// the scenario as failed if the engine considers the finalized block invalid.
+func (c *ConsensusCmd) reportAncient(log logrus.Ext1FieldLogger, slot uint64, hash common.Hash, scenario string, status types.ExecutePayloadStatus, err error) {
+	class := classifyResponse(status, err)
+	log = log.WithField("call", scenario).WithField("response", class).WithField("responses", c.ancient.add(scenario, class))
+	if err != nil {
+		log = log.WithError(err)
+	}
+	if err == nil && status == types.ExecutionInvalid {
+		log.Error("Engine considers finalized ancient block invalid")
+		c.report.record(scenario, slot, fmt.Errorf("engine considers finalized block %s invalid", hash))
+		c.maybeExit()
+		return
+	}
+	log.Info("Classified engine response to ancient block")
+	c.report.record(scenario, slot, nil)
+}
@@ -0,0 +1,63 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// codeError is a JSON-RPC error with a code.
+type codeError int
+
+func (e codeError) Error() string  { return "engine error" }
+func (e codeError) ErrorCode() int { return int(e) }
+
+func TestClassifyResponse(t *testing.T) {
+	require.Equal(t, "valid", classifyResponse(types.ExecutionValid, nil))
+	require.Equal(t, "syncing", classifyResponse(types.ExecutionSyncing, nil))
+	require.Equal(t, "error -38002", classifyResponse("", codeError(-38002)))
+	require.Equal(t, "error", classifyResponse("", errors.New("timeout")))
+}
+
+func TestCheckAncient(t *testing.T) {
+	engine := &statusEngine{status: types.ExecutionSyncing}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	c.Ancient = AncientConfig{Depth: 4, Slots: 1}
+	c.engine = dialEngine(t, srv.URL)
+
+	var hashes []common.Hash
+	for slot := uint64(1); slot <= 8; slot++ {
+		parent := c.mockChain.CurrentHeader()
+		beaconRoot := common.Hash{byte(slot)}
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.state.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+		require.NoError(t, err)
+		hashes = append(hashes, block.Hash())
+	}
+
+	// not finalized far enough
+	c.state.Bootstrap(types.Checkpoint{Epoch: 1, Root: types.Root(hashes[2])})
+	c.checkAncient(9)
+	require.Empty(t, c.report.cases)
+
+	// pruned blocks may be syncing, but not invalid
+	c.state.Bootstrap(types.Checkpoint{Epoch: 2, Root: types.Root(hashes[6])})
+	c.checkAncient(10)
+	require.Equal(t, 1, c.report.cases[scenarioAncientPayload].runs)
+	require.Empty(t, c.report.cases[scenarioAncientPayload].failures)
+	require.Equal(t, map[string]int{"syncing": 1}, c.ancient.counts[scenarioAncientForkchoice])
+
+	engine.status = types.ExecutionInvalid
+	c.checkAncient(11)
+	require.Equal(t, []string{"slot 11: engine considers finalized block " + hashes[2].Hex() + " invalid"}, c.report.cases[scenarioAncientPayload].failures)
+	require.Len(t, c.report.cases[scenarioAncientForkchoice].failures, 1)
+}
@@ -0,0 +1,206 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"mergemock/rpc"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// artifactSlots is how many of the last slots the payloads are kept of, for the bundles.
+const artifactSlots = 8
+
+// Artifacts configures the bundles of the failed checks of a run, to reproduce them against the
+// engine with.
+type Artifacts struct {
+	Dir   string `ask:"--dir" help:"Directory to write a bundle of every failed check to, with the payload of the slot as JSON and SSZ, the last engine calls, the head and the config of the run (empty to disable)"`
+	Calls int    `ask:"--calls" help:"Last engine calls to keep in full for the transcript of the bundles"`
+	Max   int    `ask:"--max" help:"Most bundles to write in a run, after which failures are only reported (0 for no limit)"`
+}
+
+func (a *Artifacts) Default() {
+	a.Calls = 64
+	a.Max = 16
+}
+
+// transcript returns a transcript of the last engine calls for the bundles, nil if none are
+// written.
+func (a *Artifacts) transcript() *rpc.Transcript {
+	if a.Dir == "" {
+		return nil
+	}
+	return rpc.NewTranscript(a.Calls)
+}
+
+// artifactState keeps the last payloads and engine calls of the run, and the bundles written.
+type artifactState struct {
+	mu       sync.Mutex
+	payloads map[uint64]*types.ExecutionPayloadV3
+	written  map[string]bool
+
+	// last calls of the engine and the proposer engine, nil if not recorded
+	engine, proposer *rpc.Transcript
+}
+
+// remember keeps the payload as the last one of its slot, dropping the payloads of old slots.
+func (a *artifactState) remember(slot uint64, payload *types.ExecutionPayloadV3) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.payloads == nil {
+		a.payloads = make(map[uint64]*types.ExecutionPayloadV3)
+	}
+	a.payloads[slot] = payload
+	for s := range a.payloads {
+		if s+artifactSlots <= slot {
+			delete(a.payloads, s)
+		}
+	}
+}
+
+func (a *artifactState) payload(slot uint64) *types.ExecutionPayloadV3 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.payloads[slot]
+}
+
+// claim returns whether to write the bundle of the name, once per name and up to max bundles.
+func (a *artifactState) claim(name string, max int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.written[name] || (max > 0 && len(a.written) >= max) {
+		return false
+	}
+	if a.written == nil {
+		a.written = make(map[string]bool)
+	}
+	a.written[name] = true
+	return true
+}
+
+// artifactFailure describes the failed check of a bundle.
+type artifactFailure struct {
+	Scenario string    `json:"scenario"`
+	Slot     uint64    `json:"slot"`
+	Corr     string    `json:"corr"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+	// SSZError is why the payload has no SSZ encoding, like fields over the limits of SSZ
+	SSZError string `json:"sszError,omitempty"`
+}
+
+// artifactHead is the head of the mock chain and of the engine when a check failed.
+type artifactHead struct {
+	Number      uint64                   `json:"number"`
+	Hash        common.Hash              `json:"hash"`
+	Forkchoice  *types.ForkchoiceStateV1 `json:"forkchoice"`
+	EngineHead  common.Hash              `json:"engineHead"`
+	EngineError string                   `json:"engineError,omitempty"`
+}
+
+// artifactConfig is the config of the run, by the arguments the mock was started with.
+type artifactConfig struct {
+	Args    []string `json:"args"`
+	Preset  string   `json:"preset,omitempty"`
+	Genesis string   `json:"genesis"`
+}
+
+// writeArtifacts writes the bundle of the failed check of the scenario in the slot to a directory
+// named after both, like 'slot-42-new-payload', once per scenario and slot.
+func (c *ConsensusCmd) writeArtifacts(scenario string, slot uint64, failure error) {
+	if c.Artifacts.Dir == "" {
+		return
+	}
+	name := fmt.Sprintf("%s-%s", correlationID(slot), scenario)
+	if !c.artifacts.claim(name, c.Artifacts.Max) {
+		return
+	}
+	log := c.slotLog(slot).WithField("scenario", scenario)
+	dir := filepath.Join(c.Artifacts.Dir, name)
+	if err := c.writeBundle(dir, scenario, slot, failure); err != nil {
+		log.WithError(err).Error("Failed to write the artifact bundle")
+		return
+	}
+	log.WithField("dir", dir).Info("Wrote the artifact bundle of the failure")
+}
+
+func (c *ConsensusCmd) writeBundle(dir, scenario string, slot uint64, failure error) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	info := artifactFailure{Scenario: scenario, Slot: slot, Corr: correlationID(slot), Error: errorText(failure), Time: time.Now()}
+	files := map[string]interface{}{
+		"transcript.json": c.artifacts.engine.Calls(),
+		"config.json":     artifactConfig{os.Args[1:], c.Preset, c.GenesisPath},
+	}
+	if c.proposerEngine != nil {
+		files["proposer-transcript.json"] = c.artifacts.proposer.Calls()
+	}
+	if c.mockChain != nil {
+		files["head.json"] = c.artifactHead()
+	}
+	if payload := c.artifacts.payload(slot); payload != nil {
+		files["payload.json"] = payload
+		if ssz, err := c.payloadSSZ(payload); err != nil {
+			info.SSZError = err.Error()
+		} else if err := os.WriteFile(filepath.Join(dir, "payload.ssz"), ssz, 0o644); err != nil {
+			return err
+		}
+	}
+	files["failure.json"] = info
+	for file, v := range files {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+			return err
+		}
+	}
+	// the genesis of the engine, to start a fresh one from
+	if genesis, err := os.ReadFile(c.GenesisPath); err == nil {
+		return os.WriteFile(filepath.Join(dir, "genesis.json"), genesis, 0o644)
+	}
+	return nil
+}
+
+// artifactHead returns the head of the mock chain, the forkchoice the engine accepted last, and
+// the latest block of the engine.
+func (c *ConsensusCmd) artifactHead() artifactHead {
+	header := c.mockChain.CurrentHeader()
+	head := artifactHead{Number: header.Number.Uint64(), Hash: header.Hash(), Forkchoice: c.forkchoice.get()}
+	if c.engine == nil {
+		return head
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+	var err error
+	if head.EngineHead, err = c.engineBlockHash(ctx, "latest"); err != nil {
+		head.EngineError = err.Error()
+	}
+	return head
+}
+
+// payloadSSZ returns the SSZ encoding of the payload, as the execution payload of the builder API
+// of its fork.
+func (c *ConsensusCmd) payloadSSZ(payload *types.ExecutionPayloadV3) ([]byte, error) {
+	version := types.VersionBellatrix
+	switch {
+	case c.mockChain.forks.IsCancun(payload.Timestamp):
+		version = types.VersionDeneb
+	case c.mockChain.forks.IsShanghai(payload.Timestamp):
+		version = types.VersionCapella
+	}
+	versioned, err := types.NewVersionedExecutionPayload(version, payload)
+	if err != nil {
+		return nil, err
+	}
+	return versioned.MarshalSSZ()
+}
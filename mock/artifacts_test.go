@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifacts(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.Artifacts.Default()
+	c.Artifacts.Dir = t.TempDir()
+	c.Artifacts.Max = 2
+	c.report = newScenarioReport()
+	c.report.failed = c.writeArtifacts
+
+	parent := c.mockChain.CurrentHeader()
+	withdrawals := types.Withdrawals{}
+	block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.SlotTimestamp(3), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, withdrawals, &common.Hash{}, true)
+	require.NoError(t, err)
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+	require.NoError(t, err)
+	c.artifacts.remember(3, payload)
+
+	// a bundle per scenario and slot, up to the max
+	c.report.record(scenarioNewPayload, 3, nil)
+	c.report.record(scenarioNewPayload, 3, errors.New("invalid"))
+	c.report.record(scenarioNewPayload, 3, errors.New("invalid again"))
+	c.report.record(scenarioProposal, 4, errors.New("no payload"))
+	c.report.record(scenarioProposal, 5, errors.New("no payload"))
+	entries, err := os.ReadDir(c.Artifacts.Dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	dir := filepath.Join(c.Artifacts.Dir, "slot-3-new-payload")
+	var failure artifactFailure
+	data, err := os.ReadFile(filepath.Join(dir, "failure.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &failure))
+	require.Equal(t, "invalid", failure.Error)
+	require.Equal(t, "slot-3", failure.Corr)
+	require.Empty(t, failure.SSZError)
+	var bundled types.ExecutionPayloadV3
+	data, err = os.ReadFile(filepath.Join(dir, "payload.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &bundled))
+	require.Equal(t, block.Hash(), bundled.BlockHash)
+	ssz, err := os.ReadFile(filepath.Join(dir, "payload.ssz"))
+	require.NoError(t, err)
+	require.NotEmpty(t, ssz)
+	var head artifactHead
+	data, err = os.ReadFile(filepath.Join(dir, "head.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &head))
+	require.Equal(t, block.Hash(), head.Hash)
+
+	// failures of slots without a payload have no payload files
+	_, err = os.Stat(filepath.Join(c.Artifacts.Dir, "slot-4-proposal", "payload.json"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,316 @@
+package mock
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Events of a consensus run that assertions check.
+const (
+	eventNewPayload        = "newPayload"
+	eventForkchoiceUpdated = "forkchoiceUpdated"
+	eventGetPayload        = "getPayload"
+	eventBid               = "bid"
+)
+
+type fieldKind int
+
+const (
+	kindNumber fieldKind = iota
+	kindDuration
+	kindString
+	kindBool
+)
+
+// assertionEvents are the fields of every event, by kind. Numbers are *big.Int values, and
+// durations, strings and bools are time.Duration, string and bool values.
+var assertionEvents = map[string]map[string]fieldKind{
+	eventNewPayload: {
+		"number":    kindNumber,
+		"hash":      kindString,
+		"status":    kindString,
+		"error":     kindString,
+		"latency":   kindDuration,
+		"canonical": kindBool,
+		"corr":      kindString,
+	},
+	eventForkchoiceUpdated: {
+		"head":     kindString,
+		"status":   kindString,
+		"error":    kindString,
+		"latency":  kindDuration,
+		"building": kindBool,
+		"corr":     kindString,
+	},
+	eventGetPayload: {
+		"slot":    kindNumber,
+		"number":  kindNumber,
+		"txs":     kindNumber,
+		"gasUsed": kindNumber,
+		"value":   kindNumber,
+		"error":   kindString,
+		"latency": kindDuration,
+		"corr":    kindString,
+	},
+	eventBid: {
+		"slot":    kindNumber,
+		"value":   kindNumber,
+		"builder": kindString,
+		"valid":   kindBool,
+		"latency": kindDuration,
+		"corr":    kindString,
+	},
+}
+
+// comparison compares a field of an event with a value.
+type comparison struct {
+	field string
+	kind  fieldKind
+	op    string
+	value interface{}
+}
+
+// assertion is a rule of an assertions file: a comparison that must hold for every event, or
+// for every event that matches its condition.
+type assertion struct {
+	text  string
+	event string
+	check comparison
+	when  *comparison
+}
+
+// assertions are the rules of a run, by event. A nil value checks nothing.
+type assertions struct {
+	rules map[string][]*assertion
+}
+
+// loadAssertions reads the rules of the assertions file.
+func loadAssertions(path string) (*assertions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a, err := parseAssertions(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// parseAssertions parses rules, one per line, like 'newPayload.latency < 2s' or
+// 'newPayload.status != INVALID when canonical == true'. Empty lines and lines starting with #
+// are skipped.
+func parseAssertions(r io.Reader) (*assertions, error) {
+	a := &assertions{rules: make(map[string][]*assertion)}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseAssertion(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		a.rules[rule.event] = append(a.rules[rule.event], rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func parseAssertion(line string) (*assertion, error) {
+	check, cond, _ := strings.Cut(line, " when ")
+	parts := strings.Fields(check)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected 'event.field op value', got %q", check)
+	}
+	event, field, ok := strings.Cut(parts[0], ".")
+	if !ok {
+		return nil, fmt.Errorf("expected event.field, got %q", parts[0])
+	}
+	fields, ok := assertionEvents[event]
+	if !ok {
+		return nil, fmt.Errorf("unknown event %q", event)
+	}
+	rule := &assertion{text: line, event: event}
+	c, err := parseComparison(fields, field, parts[1], parts[2])
+	if err != nil {
+		return nil, err
+	}
+	rule.check = *c
+	if cond != "" {
+		parts := strings.Fields(cond)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("expected 'when field op value', got %q", cond)
+		}
+		rule.when, err = parseComparison(fields, parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rule, nil
+}
+
+func parseComparison(fields map[string]fieldKind, field, op, value string) (*comparison, error) {
+	kind, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	c := &comparison{field: field, kind: kind, op: op}
+	switch op {
+	case "==", "!=":
+	case "<", "<=", ">", ">=":
+		if kind == kindString || kind == kindBool {
+			return nil, fmt.Errorf("field %q can only be compared with == or !=", field)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+	switch kind {
+	case kindNumber:
+		v, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid number %q", value)
+		}
+		c.value = v
+	case kindDuration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		c.value = v
+	case kindString:
+		c.value = strings.Trim(value, `"'`)
+	case kindBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		c.value = v
+	}
+	return c, nil
+}
+
+// holds returns whether the comparison holds for the fields of the event.
+func (c *comparison) holds(fields logrus.Fields) bool {
+	var cmp int
+	switch c.kind {
+	case kindNumber:
+		v, ok := fields[c.field].(*big.Int)
+		if !ok {
+			return false
+		}
+		cmp = v.Cmp(c.value.(*big.Int))
+	case kindDuration:
+		v, _ := fields[c.field].(time.Duration)
+		switch want := c.value.(time.Duration); {
+		case v < want:
+			cmp = -1
+		case v > want:
+			cmp = 1
+		}
+	default:
+		if fields[c.field] != c.value {
+			cmp = 1
+		}
+	}
+	switch c.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// check returns the first rule the event violates, or nil, and whether any rule checked it.
+func (a *assertions) check(event string, fields logrus.Fields) (violated *assertion, checked bool) {
+	if a == nil {
+		return nil, false
+	}
+	for _, rule := range a.rules[event] {
+		if rule.when != nil && !rule.when.holds(fields) {
+			continue
+		}
+		checked = true
+		if !rule.check.holds(fields) {
+			return rule, true
+		}
+	}
+	return nil, checked
+}
+
+// assert checks the event against the rules of the run, and ends the run with the
+// report on the first violation, with the fields of the event.
+func (c *ConsensusCmd) assert(event string, fields logrus.Fields) {
+	rule, checked := c.assertions.check(event, fields)
+	if !checked {
+		return
+	}
+	var slot uint64
+	if c.state != nil {
+		slot = c.state.Slot()
+	}
+	if rule == nil {
+		c.report.record(scenarioAssertions, slot, nil)
+		return
+	}
+	c.log.WithFields(fields).WithField("event", event).WithField("rule", rule.text).Error("Assertion failed")
+	c.report.record(scenarioAssertions, slot, fmt.Errorf("%s failed: %s %v", rule.text, rule.check.field, fields[rule.check.field]))
+	c.writeReport()
+	os.Exit(1)
+}
+
+// assertBid checks the bid of the builder for the slot.
+func (c *ConsensusCmd) assertBid(bid *types.VersionedSignedBuilderBid, slot uint64, valid bool, latency time.Duration) {
+	fields := logrus.Fields{"slot": new(big.Int).SetUint64(slot), "corr": correlationID(slot), "valid": valid, "latency": latency}
+	if value, err := bid.Value(); err == nil {
+		fields["value"] = value.BigInt()
+	}
+	if builder, err := bid.Pubkey(); err == nil {
+		fields["builder"] = builder.String()
+	}
+	c.assert(eventBid, fields)
+}
+
+type faultKey struct{}
+
+// withFault marks the engine calls of the context as faults, which the engine must not accept,
+// so their events are not those of canonical blocks.
+func withFault(ctx context.Context) context.Context {
+	return context.WithValue(ctx, faultKey{}, true)
+}
+
+func isFault(ctx context.Context) bool {
+	fault, _ := ctx.Value(faultKey{}).(bool)
+	return fault
+}
+
+// errorText returns the message of the error, or an empty string for no error.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
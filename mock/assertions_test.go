@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertions(t *testing.T) {
+	a, err := parseAssertions(strings.NewReader(`
+# engine
+newPayload.latency < 2s
+newPayload.status != INVALID when canonical == true
+forkchoiceUpdated.error == ""
+
+bid.value > 0
+`))
+	require.NoError(t, err)
+
+	payload := func(latency time.Duration, status string, canonical bool) logrus.Fields {
+		return logrus.Fields{"number": big.NewInt(1), "status": status, "latency": latency, "canonical": canonical}
+	}
+	rule, checked := a.check(eventNewPayload, payload(time.Second, "VALID", true))
+	require.True(t, checked)
+	require.Nil(t, rule)
+	rule, _ = a.check(eventNewPayload, payload(3*time.Second, "VALID", true))
+	require.NotNil(t, rule)
+	require.Equal(t, "newPayload.latency < 2s", rule.text)
+	rule, _ = a.check(eventNewPayload, payload(time.Second, "INVALID", true))
+	require.NotNil(t, rule)
+	require.Equal(t, "newPayload.status != INVALID when canonical == true", rule.text)
+	// faults are expected to be invalid
+	rule, _ = a.check(eventNewPayload, payload(time.Second, "INVALID", false))
+	require.Nil(t, rule)
+
+	rule, _ = a.check(eventForkchoiceUpdated, logrus.Fields{"error": "", "latency": time.Millisecond})
+	require.Nil(t, rule)
+	rule, _ = a.check(eventForkchoiceUpdated, logrus.Fields{"error": "connection refused"})
+	require.NotNil(t, rule)
+
+	rule, _ = a.check(eventBid, logrus.Fields{"value": big.NewInt(0)})
+	require.NotNil(t, rule)
+	rule, _ = a.check(eventBid, logrus.Fields{"value": big.NewInt(10)})
+	require.Nil(t, rule)
+
+	// events without rules are not checked, and nil assertions check nothing
+	_, checked = a.check(eventGetPayload, logrus.Fields{})
+	require.False(t, checked)
+	_, checked = (*assertions)(nil).check(eventBid, logrus.Fields{})
+	require.False(t, checked)
+
+	require.True(t, isFault(withFault(context.Background())))
+	require.False(t, isFault(context.Background()))
+}
+
+func TestAssertionErrors(t *testing.T) {
+	for rule, msg := range map[string]string{
+		"newPayload.latency<2s":                  "expected 'event.field op value'",
+		"latency < 2s":                           "expected event.field",
+		"newBlock.latency < 2s":                  "unknown event",
+		"newPayload.delay < 2s":                  "unknown field",
+		"newPayload.status < VALID":              "can only be compared with == or !=",
+		"newPayload.latency ~ 2s":                "unknown operator",
+		"newPayload.latency < 2":                 "missing unit",
+		"bid.value > zero":                       "invalid number",
+		"newPayload.status != INVALID when fork": "expected 'when field op value'",
+	} {
+		_, err := parseAssertions(strings.NewReader("\n" + rule))
+		require.Error(t, err, rule)
+		require.Contains(t, err.Error(), "line 2: ", rule)
+		require.Contains(t, err.Error(), msg, rule)
+	}
+}
@@ -0,0 +1,144 @@
+package mock
+
+import (
+	"sync"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+)
+
+const (
+	maxValidatorsPerCommittee = 2048
+	maxAttestations           = 128
+)
+
+// attestationPool holds the attestations of the current slot until they are aggregated, and the
+// aggregated attestations of recent slots, until a block includes them.
+type attestationPool struct {
+	mu         sync.Mutex
+	pending    []*types.Attestation
+	aggregates []*types.Attestation
+}
+
+// add adds an attestation, to aggregate at the aggregation deadline of its slot.
+func (p *attestationPool) add(att *types.Attestation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, att)
+}
+
+// aggregate makes the pending attestations up to the slot available to blocks, and drops
+// those too old to include in a block of the slot.
+func (p *attestationPool) aggregate(slot uint64, slotsPerEpoch uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	kept := p.aggregates[:0]
+	for _, a := range p.aggregates {
+		if a.Data.Slot+slotsPerEpoch >= slot {
+			kept = append(kept, a)
+		}
+	}
+	var pending []*types.Attestation
+	for _, a := range p.pending {
+		if a.Data.Slot <= slot {
+			kept = append(kept, a)
+		} else {
+			pending = append(pending, a)
+		}
+	}
+	p.aggregates, p.pending = kept, pending
+}
+
+// take removes and returns up to max attestations a block at the slot can include: those of
+// earlier slots, at most an epoch old. Attestations that are too old to include are dropped.
+func (p *attestationPool) take(slot uint64, slotsPerEpoch uint64, max int) []*types.Attestation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var included, kept []*types.Attestation
+	for _, att := range p.aggregates {
+		switch {
+		case att.Data.Slot+slotsPerEpoch < slot:
+		case att.Data.Slot < slot && len(included) < max:
+			included = append(included, att)
+		default:
+			kept = append(kept, att)
+		}
+	}
+	p.aggregates = kept
+	return included
+}
+
+// committee returns the indices of the validators that attest in the slot: every validator
+// attests once per epoch.
+func (c *ConsensusCmd) committee(slot uint64) []int {
+	var indices []int
+	for i := int(slot % c.SlotsPerEpoch); i < len(c.validators); i += int(c.SlotsPerEpoch) {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// attest has the committee of the slot attest to the head, with the configured participation
+// rate, voting for the checkpoints of the beacon state. The aggregated attestations are pending
+// until the aggregation deadline. The mock chain has no beacon blocks, so the execution block
+// hashes stand in for the beacon block roots.
+func (c *ConsensusCmd) attest(slot uint64, head common.Hash) error {
+	if len(c.validators) == 0 {
+		return nil
+	}
+	domain := types.ComputeDomain(types.DomainTypeBeaconAttester, version.Bellatrix, &c.genesisValidatorsRoot)
+	committee := c.committee(slot)
+	for index := 0; index*maxValidatorsPerCommittee < len(committee); index++ {
+		members := committee[index*maxValidatorsPerCommittee:]
+		if len(members) > maxValidatorsPerCommittee {
+			members = members[:maxValidatorsPerCommittee]
+		}
+		source, target := c.state.Finalized(), c.state.Justified()
+		data := &types.AttestationData{
+			Slot:      slot,
+			Index:     uint64(index),
+			BlockRoot: types.Root(head),
+			Source:    &source,
+			Target:    &target,
+		}
+		// bitlist of the committee size, with the length delimiting bit set
+		bits := make([]byte, len(members)/8+1)
+		bits[len(members)/8] |= 1 << (len(members) % 8)
+		var signers []int
+		for i, idx := range members {
+			// validators of the remote signer only sign proposals
+			if c.RNG.Float64() >= c.Participation || c.validators[idx].sk == nil {
+				continue
+			}
+			bits[i/8] |= 1 << (i % 8)
+			signers = append(signers, idx)
+		}
+		if len(signers) == 0 {
+			continue
+		}
+		sig, err := c.signAggregate(signers, data, domain)
+		if err != nil {
+			return err
+		}
+		c.attestations.add(&types.Attestation{AggregationBits: bits, Data: data, Signature: sig})
+	}
+	return nil
+}
+
+// signAggregate returns the aggregate signature of the validators on the object.
+func (c *ConsensusCmd) signAggregate(indices []int, obj types.HashTreeRoot, domain types.Domain) (types.Signature, error) {
+	var sig types.Signature
+	root, err := types.ComputeSigningRoot(obj, domain)
+	if err != nil {
+		return sig, err
+	}
+	sigs := make([]bls.Signature, len(indices))
+	for i, idx := range indices {
+		sigs[i] = c.validators[idx].sk.Sign(root[:])
+	}
+	sig.FromSlice(bls.AggregateSignatures(sigs).Marshal())
+	return sig, nil
+}
@@ -0,0 +1,87 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"mergemock/beaconstate"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/crypto/bls/blst"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/stretchr/testify/require"
+)
+
+func newAttestingConsensus(t *testing.T, validators int, participation float64) *ConsensusCmd {
+	c := &ConsensusCmd{SlotsPerEpoch: 4}
+	c.state = beaconstate.New(beaconstate.Config{SlotTime: time.Second, SlotsPerEpoch: 4, Validators: validators}, common.Hash{})
+	c.RNG = NewRNG(1)
+	c.Participation = participation
+	for i := 0; i < validators; i++ {
+		sk, err := blst.RandKey()
+		require.NoError(t, err)
+		var pk types.PublicKey
+		pk.FromSlice(sk.PublicKey().Marshal())
+		c.validators = append(c.validators, validator{pk: pk, sk: sk})
+	}
+	return c
+}
+
+func TestAttestations(t *testing.T) {
+	c := newAttestingConsensus(t, 10, 1)
+	head := common.Hash{0x01}
+	require.NoError(t, c.attest(1, head))
+	// not before the aggregation deadline
+	require.Empty(t, c.attestations.take(2, c.SlotsPerEpoch, maxAttestations))
+	c.attestations.aggregate(1, c.SlotsPerEpoch)
+
+	atts := c.attestations.take(2, c.SlotsPerEpoch, maxAttestations)
+	require.Len(t, atts, 1)
+	att := atts[0]
+	require.Equal(t, uint64(1), att.Data.Slot)
+	require.Equal(t, types.Root(head), att.Data.BlockRoot)
+	// validators 1, 5 and 9, and the delimiting bit
+	require.Equal(t, []byte{0b1111}, []byte(att.AggregationBits))
+
+	var pubkeys []bls.PublicKey
+	for _, idx := range c.committee(1) {
+		pubkeys = append(pubkeys, c.validators[idx].sk.PublicKey())
+	}
+	domain := types.ComputeDomain(types.DomainTypeBeaconAttester, version.Bellatrix, &c.genesisValidatorsRoot)
+	root, err := types.ComputeSigningRoot(att.Data, domain)
+	require.NoError(t, err)
+	sig, err := bls.SignatureFromBytes(att.Signature[:])
+	require.NoError(t, err)
+	require.True(t, sig.FastAggregateVerify(pubkeys, root))
+
+	// the aggregate is only included once
+	require.Empty(t, c.attestations.take(3, c.SlotsPerEpoch, maxAttestations))
+}
+
+func TestAttestationInclusion(t *testing.T) {
+	c := newAttestingConsensus(t, 4, 1)
+	for slot := uint64(1); slot <= 8; slot++ {
+		c.state.ProcessSlot(slot, common.Hash{byte(slot)})
+		require.NoError(t, c.attest(slot, common.Hash{byte(slot)}))
+		c.attestations.aggregate(slot, c.SlotsPerEpoch)
+	}
+	require.Equal(t, types.Checkpoint{Epoch: 2, Root: types.Root{0x08}}, c.state.Justified())
+	require.Equal(t, types.Checkpoint{Epoch: 1, Root: types.Root{0x04}}, c.state.Finalized())
+
+	// not of the current slot, and not older than an epoch
+	atts := c.attestations.take(8, c.SlotsPerEpoch, 2)
+	require.Len(t, atts, 2)
+	require.Equal(t, uint64(4), atts[0].Data.Slot)
+	require.Equal(t, uint64(5), atts[1].Data.Slot)
+	atts = c.attestations.take(9, c.SlotsPerEpoch, maxAttestations)
+	require.Len(t, atts, 3)
+	require.Equal(t, uint64(8), atts[2].Data.Slot)
+
+	// no participation, no attestations
+	c = newAttestingConsensus(t, 4, 0)
+	require.NoError(t, c.attest(1, common.Hash{0x01}))
+	c.attestations.aggregate(1, c.SlotsPerEpoch)
+	require.Empty(t, c.attestations.take(2, c.SlotsPerEpoch, maxAttestations))
+}
@@ -0,0 +1,185 @@
+package mock
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const DefaultRNGSeed = 1234
+
+type RNG struct {
+	*rand.Rand
+	src *countingSource
+}
+
+// NewRNG returns an RNG with the seed, which is safe for concurrent use.
+func NewRNG(seed int64) RNG {
+	src := &countingSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+	return RNG{rand.New(src), src}
+}
+
+func (i *RNG) String() string {
+	if i.src == nil {
+		return fmt.Sprintf("%d", DefaultRNGSeed)
+	}
+	return fmt.Sprintf("%d", i.src.seed)
+}
+
+func (i *RNG) Set(s string) error {
+	seed, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return err
+	}
+	*i = NewRNG(seed)
+	return nil
+}
+
+// Read fills p with random bytes, from whole numbers of the source, so no bytes are left over
+// between reads, and the state of the RNG is that of its source.
+func (i *RNG) Read(p []byte) (int, error) {
+	var buf [8]byte
+	for n := 0; n < len(p); n += 8 {
+		binary.LittleEndian.PutUint64(buf[:], i.Uint64())
+		copy(p[n:], buf[:])
+	}
+	return len(p), nil
+}
+
+// RNGState is the state of an RNG: its seed, and the numbers drawn since.
+type RNGState struct {
+	Seed  int64  `json:"seed"`
+	Draws uint64 `json:"draws"`
+}
+
+func (i *RNG) State() RNGState {
+	i.src.mu.Lock()
+	defer i.src.mu.Unlock()
+	return RNGState{i.src.seed, i.src.draws}
+}
+
+// Restore returns the RNG to the state, by drawing the same numbers from the seed again.
+func (i *RNG) Restore(state RNGState) {
+	*i = NewRNG(state.Seed)
+	for n := uint64(0); n < state.Draws; n++ {
+		i.Uint64()
+	}
+}
+
+func (i *RNG) Type() string {
+	return "RNG"
+}
+
+// countingSource is a source of random numbers that is safe for concurrent use, and counts the
+// numbers it draws.
+type countingSource struct {
+	mu    sync.Mutex
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func (s *countingSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+type TestAccount struct {
+	pk   *ecdsa.PrivateKey
+	addr common.Address
+}
+
+type TestAccounts struct {
+	accounts []TestAccount
+}
+
+func (t *TestAccounts) String() string {
+	all := make([]string, 0, len(t.accounts))
+	for _, a := range t.accounts {
+		all = append(all, a.addr.String())
+	}
+	return strings.Join(all, ",")
+}
+
+func (t *TestAccounts) Set(s string) error {
+	keys := strings.Split(s, ",")
+	t.accounts = make([]TestAccount, 0, len(keys))
+	for _, hex := range keys {
+		pk, err := crypto.HexToECDSA(hex)
+		if err != nil {
+			return fmt.Errorf("failed interpret hex private key: %s", err)
+		}
+		t.accounts = append(t.accounts, TestAccount{pk, crypto.PubkeyToAddress(pk.PublicKey)})
+	}
+	return nil
+}
+
+func (t *TestAccounts) Type() string {
+	return "TestAccount"
+}
+
+type ConsensusBehavior struct {
+	RNG          RNG          `ask:"--rng" help:"seed the RNG with an integer number"`
+	TestAccounts TestAccounts `ask:"--test-accounts" help:"comma-seperated list of hex encoded private key for an account to send test transactions from"`
+	TxProfile    string       `ask:"--tx-profile" help:"Transactions to include in mocked blocks: 'transfer', 'deposit' (requires --deposit-contract), 'withdrawal-request', 'consolidation-request', 'contracts', 'access-list', 'bundles', 'state-growth' (requires --datadir)"`
+	Freq         struct {
+		GapSlot              float64 `ask:"--gap" help:"How often an execution block is missing"`
+		ProposalFreq         float64 `ask:"--proposal" help:"How often the engine gets to propose a block"`
+		FailedProposalFreq   float64 `ask:"--ignore" help:"How often the payload produced by the engine does not become canonical"`
+		Finality             float64 `ask:"--finality" help:"How often an epoch succeeds to finalize"`
+		ReorgFreq            float64 `ask:"--reorg" help:"Frequency of chain reorgs"`
+		InvalidHashFreq      float64 `ask:"--invalid-hash" help:"Frequency of invalid payload hashes"`
+		WrongBeaconRoot      float64 `ask:"--wrong-beacon-root" help:"How often a proposal is sent to the engine with a wrong parent beacon block root (cancun)"`
+		WrongVersionedHashes float64 `ask:"--wrong-versioned-hashes" help:"How often a proposal is sent to the engine with missing, extra or reordered versioned hashes (cancun)"`
+		WrongBaseFee         float64 `ask:"--wrong-base-fee" help:"How often an external block is sent to the engine with a wrong base fee, and a block hash to match"`
+		ProposerSlashing     float64 `ask:"--proposer-slashing" help:"How often a blinded block includes a proposer slashing"`
+		AttesterSlashing     float64 `ask:"--attester-slashing" help:"How often a blinded block includes an attester slashing"`
+		Exit                 float64 `ask:"--exit" help:"How often a validator exits at the start of an epoch, to be fully withdrawn"`
+		// TODO more fun
+	} `ask:".freq" help:"Modify frequencies of certain behavior"`
+	ReorgMaxDepth     uint64  `ask:"--reorg-max-depth" help:"Max depth of a chain reorg"`
+	Participation     float64 `ask:"--participation" help:"Fraction of the emulated validators that attest in their slot"`
+	SyncParticipation float64 `ask:"--sync-participation" help:"Fraction of the sync committee that signs each block"`
+}
+
+func (b *ConsensusBehavior) Default() {
+	b.RNG = NewRNG(DefaultRNGSeed)
+	b.TxProfile = "transfer"
+	b.Freq.GapSlot = 0.05
+	b.Freq.ProposalFreq = 0.5
+	b.Freq.FailedProposalFreq = 0.1
+	b.Freq.Finality = 0.1
+	b.ReorgMaxDepth = 64
+	b.Participation = 0.95
+	b.SyncParticipation = 0.95
+	b.Freq.ReorgFreq = 0.05
+	b.Freq.InvalidHashFreq = 0.01
+	b.Freq.WrongBeaconRoot = 0.01
+	b.Freq.WrongVersionedHashes = 0.01
+	b.Freq.WrongBaseFee = 0.01
+	b.Freq.ProposerSlashing = 0.01
+	b.Freq.AttesterSlashing = 0.01
+}
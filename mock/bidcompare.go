@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"mergemock/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Payloads a proposer chooses from.
+const (
+	choiceBuilder = "builder"
+	choiceLocal   = "local"
+)
+
+// BidCompare configures the choice between the bid of the builder and the local payload of the
+// engine, like the min-bid and builder boost factor settings of beacon nodes.
+type BidCompare struct {
+	Enable      bool          `ask:"--enable" help:"Also get the local payload of the engine when the builder bids, and propose the payload of higher value"`
+	MinBid      types.U256Str `ask:"--min-bid" help:"Smallest bid value to propose the payload of the builder for, in wei"`
+	BoostFactor uint64        `ask:"--boost-factor" help:"Percentage of the bid value to compare with the value of the local payload, like the builder_boost_factor of beacon nodes: 0 always proposes the local payload, 100 compares the values as they are"`
+	Path        string        `ask:"--path" help:"File to write the bid delta of every compared slot to as JSON lines, with both values and the chosen payload (empty to disable)"`
+}
+
+func (b *BidCompare) Default() {
+	b.BoostFactor = 100
+}
+
+// bidDelta is the comparison of the bid of a slot with the local payload, a line of the bid delta
+// report. Values are decimal wei, and the local value is empty if the engine did not report it.
+type bidDelta struct {
+	Slot         uint64 `json:"slot"`
+	BidValue     string `json:"bidValue"`
+	BoostedValue string `json:"boostedValue"`
+	LocalValue   string `json:"localValue"`
+	Delta        string `json:"delta"`
+	Chosen       string `json:"chosen"`
+	Reason       string `json:"reason"`
+}
+
+// chooseBid compares the bid value, boosted by the factor, with the value of the local payload,
+// nil if unknown. The builder wins ties of the boosted value only if the local value is unknown.
+func chooseBid(bid, local, minBid *big.Int, boostFactor uint64) (delta bidDelta) {
+	boosted := new(big.Int).Mul(bid, new(big.Int).SetUint64(boostFactor))
+	boosted.Div(boosted, big.NewInt(100))
+	delta.BidValue, delta.BoostedValue = bid.String(), boosted.String()
+	if local != nil {
+		delta.LocalValue = local.String()
+		delta.Delta = new(big.Int).Sub(bid, local).String()
+	}
+	switch {
+	case boostFactor == 0:
+		delta.Chosen, delta.Reason = choiceLocal, "boost factor is 0"
+	case bid.Cmp(minBid) < 0:
+		delta.Chosen, delta.Reason = choiceLocal, "bid below min bid"
+	case local == nil:
+		delta.Chosen, delta.Reason = choiceBuilder, "no local value"
+	case boosted.Cmp(local) > 0:
+		delta.Chosen, delta.Reason = choiceBuilder, "boosted bid above local value"
+	default:
+		delta.Chosen, delta.Reason = choiceLocal, "boosted bid not above local value"
+	}
+	return delta
+}
+
+// bidDeltaReport writes the bid deltas of the run, as they are compared. A nil report writes
+// nothing.
+type bidDeltaReport struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func newBidDeltaReport(path string) (*bidDeltaReport, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &bidDeltaReport{f: f}, nil
+}
+
+func (r *bidDeltaReport) write(delta bidDelta) error {
+	if r == nil {
+		return nil
+	}
+	data, err := json.Marshal(delta)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = fmt.Fprintf(r.f, "%s\n", data)
+	return err
+}
+
+// compareBid gets the local payload of the slot, and returns it if it wins over the bid, or nil
+// to propose the payload of the builder. Without a local payload, the bid wins.
+func (c *ConsensusCmd) compareBid(log logrus.Ext1FieldLogger, bid *types.VersionedSignedBuilderBid, payloadId types.PayloadID, slot uint64) (*types.ExecutionPayloadV3, types.ExecutionRequests, error) {
+	value, err := bid.Value()
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, requests, local, err := c.getLocalProposalWithValue(payloadId, slot)
+	var delta bidDelta
+	if err != nil {
+		log.WithError(err).Warn("Failed to get the local payload to compare the bid with")
+		delta = bidDelta{BidValue: value.BigInt().String(), Chosen: choiceBuilder, Reason: "no local payload"}
+	} else {
+		delta = chooseBid(value.BigInt(), local, c.BidCompare.MinBid.BigInt(), c.BidCompare.BoostFactor)
+	}
+	delta.Slot = slot
+	log.WithFields(logrus.Fields{
+		"bid":     delta.BidValue,
+		"boosted": delta.BoostedValue,
+		"local":   delta.LocalValue,
+		"chosen":  delta.Chosen,
+		"reason":  delta.Reason,
+	}).Info("Compared bid with local payload")
+	if err := c.bidDeltas.write(delta); err != nil {
+		log.WithError(err).Warn("Failed to write the bid delta")
+	}
+	if delta.Chosen == choiceLocal {
+		return payload, requests, nil
+	}
+	return nil, nil, nil
+}
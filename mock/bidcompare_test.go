@@ -0,0 +1,73 @@
+package mock
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChooseBid(t *testing.T) {
+	gwei := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9)) }
+	for _, tc := range []struct {
+		bid, local, minBid *big.Int
+		boostFactor        uint64
+		chosen, reason     string
+	}{
+		{gwei(10), gwei(5), gwei(0), 100, choiceBuilder, "boosted bid above local value"},
+		{gwei(10), gwei(10), gwei(0), 100, choiceLocal, "boosted bid not above local value"},
+		// a boost factor of 90 lets the local payload win bids up to 11% higher
+		{gwei(10), gwei(9), gwei(0), 90, choiceLocal, "boosted bid not above local value"},
+		{gwei(10), gwei(9), gwei(0), 200, choiceBuilder, "boosted bid above local value"},
+		{gwei(10), gwei(0), gwei(0), 0, choiceLocal, "boost factor is 0"},
+		{gwei(10), gwei(0), gwei(11), 100, choiceLocal, "bid below min bid"},
+		{gwei(10), nil, gwei(10), 100, choiceBuilder, "no local value"},
+	} {
+		delta := chooseBid(tc.bid, tc.local, tc.minBid, tc.boostFactor)
+		require.Equal(t, tc.chosen, delta.Chosen, tc.reason)
+		require.Equal(t, tc.reason, delta.Reason)
+		require.Equal(t, tc.bid.String(), delta.BidValue)
+	}
+
+	delta := chooseBid(gwei(10), gwei(12), gwei(0), 150)
+	require.Equal(t, bidDelta{
+		BidValue:     "10000000000",
+		BoostedValue: "15000000000",
+		LocalValue:   "12000000000",
+		Delta:        "-2000000000",
+		Chosen:       choiceBuilder,
+		Reason:       "boosted bid above local value",
+	}, delta)
+	delta = chooseBid(gwei(10), nil, gwei(0), 100)
+	require.Empty(t, delta.LocalValue)
+	require.Empty(t, delta.Delta)
+}
+
+func TestBidDeltaReport(t *testing.T) {
+	report, err := newBidDeltaReport("")
+	require.NoError(t, err)
+	require.Nil(t, report)
+	require.NoError(t, report.write(bidDelta{Slot: 1}))
+
+	path := filepath.Join(t.TempDir(), "bids.jsonl")
+	report, err = newBidDeltaReport(path)
+	require.NoError(t, err)
+	for slot := uint64(1); slot <= 2; slot++ {
+		delta := chooseBid(big.NewInt(2), big.NewInt(1), big.NewInt(0), 100)
+		delta.Slot = slot
+		require.NoError(t, report.write(delta))
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var delta bidDelta
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &delta))
+	require.Equal(t, uint64(2), delta.Slot)
+	require.Equal(t, "1", delta.Delta)
+	require.Equal(t, choiceBuilder, delta.Chosen)
+}
@@ -0,0 +1,81 @@
+package mock
+
+import (
+	"math/big"
+	"mergemock/contracts"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// bundleTxGas is the gas of the swaps and arbitrages of the bundles tx profile.
+const bundleTxGas = 100_000
+
+// poolDeployment is the price pool of the bundles tx profile, at the address of its last deployment.
+type poolDeployment struct {
+	mu      sync.Mutex
+	address common.Address
+}
+
+// txCreator deploys the pool from the first test account, and then makes a bundle per block:
+// a swap of the first test account moving the price, followed by the arbitrage of the last test
+// account taking it back, which only succeeds right after the swap. The arbitrage pays a higher
+// tip, so a builder ordering by tip instead of keeping the bundle makes it revert. The pool is
+// deployed again if it is missing from the state of the parent, after a reorg.
+func (d *poolDeployment) txCreator() func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		if len(accounts) == 0 {
+			return nil
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		trader, arbitrageur := accounts[0], accounts[len(accounts)-1]
+		if d.address == (common.Address{}) || statedb.GetCodeSize(d.address) == 0 {
+			d.address = crypto.CreateAddress(trader.addr, statedb.GetNonce(trader.addr))
+			return contractTx(config, statedb, trader, nil, contracts.PoolCode, contractDeployGas)
+		}
+		amount := big.NewInt(1 + header.Number.Int64()%16)
+		price := new(big.Int).Add(statedb.GetState(d.address, common.Hash{}).Big(), amount)
+
+		swapNonce, arbNonce := statedb.GetNonce(trader.addr), statedb.GetNonce(arbitrageur.addr)
+		if arbitrageur.addr == trader.addr {
+			arbNonce++
+		}
+		swap := bundleTx(config, statedb, trader, swapNonce, d.address, contractInput(contracts.SwapSelector, common.BigToHash(amount)), big.NewInt(2))
+		arb := bundleTx(config, statedb, arbitrageur, arbNonce, d.address, contractInput(contracts.ArbSelector, common.BigToHash(price)), big.NewInt(params.GWei))
+		if swap == nil || arb == nil {
+			return nil
+		}
+		return []*ethTypes.Transaction{swap, arb}
+	}
+}
+
+// bundleTx returns a call of the pool with the nonce and tip, or nil if the account cannot pay it.
+func bundleTx(config *params.ChainConfig, statedb *state.StateDB, account TestAccount, nonce uint64, to common.Address, input []byte, tip *big.Int) *ethTypes.Transaction {
+	feeCap := new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei))
+	// both transactions of the bundle may come from the account
+	cost := new(big.Int).Mul(feeCap, big.NewInt(2*bundleTxGas))
+	if statedb.GetBalance(account.addr).Cmp(cost) < 0 {
+		return nil
+	}
+	tx, err := ethTypes.SignTx(ethTypes.NewTx(&ethTypes.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     nonce,
+		To:        &to,
+		Gas:       bundleTxGas,
+		GasFeeCap: feeCap,
+		GasTipCap: tip,
+		Data:      input,
+	}), ethTypes.NewLondonSigner(config.ChainID), account.pk)
+	if err != nil {
+		return nil
+	}
+	return tx
+}
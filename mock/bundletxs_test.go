@@ -0,0 +1,112 @@
+package mock
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"mergemock/contracts"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleTxs(t *testing.T) {
+	var accounts []TestAccount
+	for i := 0; i < 2; i++ {
+		key, _ := crypto.GenerateKey()
+		accounts = append(accounts, TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)})
+	}
+	genesis := newMergedGenesis(accounts[0].addr)
+	genesis.Alloc[accounts[1].addr] = core.GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(100))}
+	engine := newTestEngine(t, writeGenesis(t, genesis, ForkTimes{}))
+	chain := engine.mockChain()
+
+	var pool poolDeployment
+	creator := TransactionsCreator{accounts, pool.txCreator()}
+	addBlock := func(creator TransactionsCreator) []*ethTypes.Receipt {
+		parent := chain.CurrentHeader()
+		block, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		return chain.chain.GetReceiptsByHash(block.Hash())
+	}
+
+	// the deployment, and then a swap and its arbitrage per block
+	receipts := addBlock(creator)
+	require.Len(t, receipts, 1)
+	require.Equal(t, pool.address, receipts[0].ContractAddress)
+	for i := 0; i < 3; i++ {
+		receipts = addBlock(creator)
+		require.Len(t, receipts, 2)
+		for j, topic := range []common.Hash{contracts.SwappedEventTopic, contracts.ArbitragedEventTopic} {
+			require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipts[j].Status)
+			require.Len(t, receipts[j].Logs, 1)
+			require.Equal(t, topic, receipts[j].Logs[0].Topics[0])
+		}
+	}
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{}, statedb.GetState(pool.address, common.Hash{}))
+
+	// the next bundle
+	bundle := func() []*ethTypes.Transaction {
+		statedb, err := chain.chain.State()
+		require.NoError(t, err)
+		parent := chain.CurrentHeader()
+		header := &ethTypes.Header{Number: new(big.Int).Add(parent.Number, common.Big1)}
+		txs := pool.txCreator()(chain.gspec.Config, chain.chain, statedb, header, vm.Config{}, accounts)
+		require.Len(t, txs, 2)
+		return txs
+	}
+	poolCreator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		return engine.backend.txPool.Select(config, bc, statedb, header, cfg)
+	}}
+	eth := NewEthBackend(chain.chain, engine.backend.txPool)
+	send := func(txs []*ethTypes.Transaction, number uint64) error {
+		args := SendBundleArgs{BlockNumber: hexutil.Uint64(number)}
+		for _, tx := range txs {
+			raw, err := tx.MarshalBinary()
+			require.NoError(t, err)
+			args.Txs = append(args.Txs, raw)
+		}
+		_, err := eth.SendBundle(context.Background(), args)
+		return err
+	}
+
+	// the arbitrage pays the higher tip, so a builder ordering by tip makes it revert
+	txs := bundle()
+	for _, tx := range txs {
+		require.NoError(t, engine.backend.txPool.Add(tx, statedb))
+	}
+	receipts = addBlock(poolCreator)
+	require.Len(t, receipts, 2)
+	require.Equal(t, txs[1].Hash(), receipts[0].TxHash)
+	require.Equal(t, ethTypes.ReceiptStatusFailed, receipts[0].Status)
+
+	// the engine keeps bundles in order
+	txs = bundle()
+	require.NoError(t, send(txs, chain.CurrentHeader().Number.Uint64()+1))
+	receipts = addBlock(poolCreator)
+	require.Len(t, receipts, 2)
+	for i, receipt := range receipts {
+		require.Equal(t, txs[i].Hash(), receipt.TxHash)
+		require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipt.Status)
+	}
+
+	// a bundle that reverts, or for a past block, is left out
+	txs = bundle()
+	require.NoError(t, send(txs[1:], 0))
+	require.NoError(t, send(txs, chain.CurrentHeader().Number.Uint64()))
+	require.Empty(t, addBlock(poolCreator))
+	// only the bundle for any block is kept for later
+	require.Len(t, engine.backend.txPool.bundles, 1)
+	require.Len(t, engine.backend.txPool.bundles[0].txs, 1)
+	require.Error(t, send(nil, 0))
+}
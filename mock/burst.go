@@ -0,0 +1,123 @@
+package mock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// BurstConfig configures bursts of new payloads, like a consensus client sends when it catches
+// up after downtime.
+type BurstConfig struct {
+	Size  uint64 `ask:"--size" help:"Hold back this many external blocks from the engine, then deliver them in a burst of new payloads followed by a single forkchoice update (0 to disable)"`
+	Pause uint64 `ask:"--pause" help:"Slots to follow the chain normally between a burst and the next hold-back (0 for a single burst)"`
+}
+
+// burstBuffer holds back the external blocks of the next burst.
+type burstBuffer struct {
+	payloads []bufferedPayload
+	gas      uint64
+	// slots left to follow the chain normally, before the next hold-back
+	pause uint64
+	// set after a single burst
+	done bool
+	// the burst being delivered, if any
+	delivering sync.WaitGroup
+}
+
+// holdBlock holds back the external block from the engine, if bursts are enabled, and delivers
+// the held blocks once there are enough. It returns false if the block is to be executed by the
+// engine as usual.
+func (c *ConsensusCmd) holdBlock(log logrus.Ext1FieldLogger, slot uint64, block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash, safe, final common.Hash) bool {
+	if c.Burst.Size == 0 || c.burst.done || c.offline.isOffline() {
+		return false
+	}
+	// the next burst starts after the previous one is delivered, so the engine has its head
+	c.burst.delivering.Wait()
+	if c.burst.pause > 0 {
+		c.burst.pause--
+		return false
+	}
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+	if err != nil {
+		log.WithError(err).Error("Failed to convert execution block to execution payload")
+		return false
+	}
+	requests, _ := c.mockChain.ExecutionRequests(block.Hash())
+	c.burst.payloads = append(c.burst.payloads, bufferedPayload{payload, beaconRoot, requests})
+	c.burst.gas += block.GasUsed()
+	log.WithField("held", len(c.burst.payloads)).Debug("Holding back block for burst")
+	if uint64(len(c.burst.payloads)) < c.Burst.Size {
+		return true
+	}
+
+	payloads, gas := c.burst.payloads, c.burst.gas
+	c.burst.payloads, c.burst.gas = nil, 0
+	side, err := c.interleavedBranch(payloads)
+	if err != nil {
+		log.WithError(err).Error("Failed to build second branch to interleave")
+	}
+	c.burst.pause = c.Burst.Pause
+	c.burst.done = c.Burst.Pause == 0
+	c.burst.delivering.Add(1)
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		defer c.burst.delivering.Done()
+		err := c.deliverBurst(log, payloads, side, gas, block.Hash(), safe, final)
+		if err != nil {
+			log.WithError(err).Error("Burst failed")
+			c.exitUnlessOffline(c.burstScenario(), slot, err)
+			return
+		}
+		c.report.record(c.burstScenario(), slot, nil)
+	}()
+	return true
+}
+
+// deliverBurst sends the held payloads to the engine as fast as it takes them, in the delivery
+// order, followed by a forkchoice update to the last one, and logs the throughput of the engine.
+// Out of order, it also records the status transitions of the payloads.
+func (c *ConsensusCmd) deliverBurst(log logrus.Ext1FieldLogger, payloads, side []bufferedPayload, gas uint64, head, safe, final common.Hash) error {
+	statuses := make(map[types.ExecutePayloadStatus]int)
+	first := make(map[common.Hash]types.ExecutePayloadStatus)
+	start := time.Now()
+	for _, p := range c.deliveryOrder(payloads, side) {
+		res, err := c.newPayload(c.ctx, log, p.payload, p.beaconRoot, p.requests)
+		if err != nil {
+			return fmt.Errorf("new payload %s: %w", p.payload.BlockHash, err)
+		}
+		if res.Status == types.ExecutionInvalid {
+			return fmt.Errorf("engine considers payload %s invalid: %s", p.payload.BlockHash, res.ValidationError)
+		}
+		statuses[res.Status]++
+		first[p.payload.BlockHash] = res.Status
+	}
+	payloadTime := time.Since(start)
+	if _, err := c.sendForkchoiceUpdated(head, safe, final, nil); err != nil {
+		return fmt.Errorf("forkchoice update to head of burst: %w", err)
+	}
+	elapsed := time.Since(start)
+	log.WithField("blocks", len(payloads)).WithField("statuses", statuses).
+		WithField("elapsed", elapsed).WithField("forkchoice", elapsed-payloadTime).
+		WithField("blocks_per_sec", fmt.Sprintf("%.2f", float64(len(payloads))/payloadTime.Seconds())).
+		WithField("mgas_per_sec", fmt.Sprintf("%.2f", float64(gas)/1e6/payloadTime.Seconds())).
+		Info("Delivered burst")
+	if c.Delivery.outOfOrder() {
+		if err := c.checkTransitions(log, payloads, side, first); err != nil {
+			return err
+		}
+	}
+	for _, p := range payloads {
+		c.proposerFollow(c.ctx, log, p.payload, p.beaconRoot, p.requests)
+	}
+	c.proposerForkchoiceUpdated(log, head, safe, final)
+	return nil
+}
@@ -0,0 +1,45 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBurst(t *testing.T) {
+	engine := new(fakeEngine)
+	srv, addr := serveFakeEngine(t, "127.0.0.1:0", engine)
+	defer srv.Close()
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	c.Burst = BurstConfig{Size: 3, Pause: 1}
+	c.engine = dialEngine(t, "http://"+addr)
+
+	hold := func(slot uint64) bool {
+		parent := c.mockChain.CurrentHeader()
+		beaconRoot := common.Hash{byte(slot)}
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.state.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+		require.NoError(t, err)
+		return c.holdBlock(logrus.New(), slot, block, types.Withdrawals{}, &beaconRoot, common.Hash{}, common.Hash{})
+	}
+	require.True(t, hold(1))
+	require.True(t, hold(2))
+	require.Empty(t, engine.methods)
+	require.True(t, hold(3))
+	c.inflight.Wait()
+	require.Equal(t, []string{"engine_newPayloadV3", "engine_newPayloadV3", "engine_newPayloadV3", "engine_forkchoiceUpdatedV3"}, engine.methods)
+	require.Equal(t, c.mockChain.CurrentHeader().Hash(), c.forkchoice.get().HeadBlockHash)
+	require.Equal(t, 1, c.report.cases[scenarioBurst].runs)
+	require.Empty(t, c.report.cases[scenarioBurst].failures)
+
+	// the chain is followed normally for the pause, then held back again
+	require.False(t, hold(4))
+	require.True(t, hold(5))
+	require.Len(t, c.burst.payloads, 1)
+}
@@ -0,0 +1,139 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// CheckpointSync configures starting the consensus mock mid-chain: instead of simulating the
+// chain since genesis, it imports a chain export, and starts from a finalized checkpoint.
+type CheckpointSync struct {
+	Chain string `ask:"--chain" help:"Chain export to import, as written with --export (empty to start from genesis)"`
+	Epoch uint64 `ask:"--epoch" help:"Epoch of the finalized checkpoint"`
+	Root  string `ask:"--root" help:"Execution block hash of the finalized checkpoint"`
+}
+
+// exportedPayload is an entry of a chain export: a payload of the mock chain, with the parent
+// beacon block root it was processed with, and its execution witness if exported. Unlike blocks,
+// payloads keep the withdrawals.
+type exportedPayload struct {
+	Payload    *types.ExecutionPayloadV3 `json:"executionPayload"`
+	BeaconRoot *common.Hash              `json:"parentBeaconBlockRoot,omitempty"`
+	Witness    *types.ExecutionWitness   `json:"executionWitness,omitempty"`
+}
+
+// chainExport appends the payloads of the mock chain to a file, a JSON object per line, in the
+// order they are processed.
+type chainExport struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+func openChainExport(path string) (*chainExport, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chain export: %v", err)
+	}
+	return &chainExport{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (e *chainExport) write(payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, witness *types.ExecutionWitness) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(&exportedPayload{Payload: payload, BeaconRoot: beaconRoot, Witness: witness})
+}
+
+func (e *chainExport) Close() error {
+	return e.f.Close()
+}
+
+// exportPayload adds the payload to the chain export, if enabled.
+func (c *ConsensusCmd) exportPayload(payload *types.ExecutionPayloadV3, beaconRoot *common.Hash) {
+	if c.export == nil {
+		return
+	}
+	var witness *types.ExecutionWitness
+	if c.Witness.Export {
+		witness, _ = c.mockChain.Witness(payload.BlockHash)
+	}
+	if err := c.export.write(payload, beaconRoot, witness); err != nil {
+		c.log.WithError(err).Error("Failed to export payload")
+	}
+}
+
+// exportBlock adds a block built by the mock to the chain export, if enabled.
+func (c *ConsensusCmd) exportBlock(block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash) {
+	if c.export == nil {
+		return
+	}
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+	if err != nil {
+		c.log.WithError(err).Error("Failed to export block")
+		return
+	}
+	c.exportPayload(payload, beaconRoot)
+}
+
+// importChain processes the payloads of a chain export into the mock chain, and returns the
+// number of blocks it did not have yet.
+func (c *ConsensusCmd) importChain(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open chain export: %v", err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	imported := 0
+	for {
+		var entry exportedPayload
+		if err := dec.Decode(&entry); errors.Is(err, io.EOF) {
+			return imported, nil
+		} else if err != nil {
+			return imported, fmt.Errorf("invalid chain export entry %d: %v", imported, err)
+		}
+		if entry.Payload == nil {
+			return imported, fmt.Errorf("chain export entry without payload")
+		}
+		if c.mockChain.chain.HasBlock(entry.Payload.BlockHash, entry.Payload.Number) {
+			continue
+		}
+		if _, err := c.processPayload(entry.Payload, entry.BeaconRoot); err != nil {
+			return imported, fmt.Errorf("failed to import block %d (%s): %v", entry.Payload.Number, entry.Payload.BlockHash, err)
+		}
+		imported++
+	}
+}
+
+// checkpointSync imports the chain export, and returns the finalized checkpoint to start from.
+// The checkpoint block must be in the chain, and be no later than the start of its epoch.
+func (c *ConsensusCmd) checkpointSync() (types.Checkpoint, error) {
+	imported, err := c.importChain(c.CheckpointSync.Chain)
+	if err != nil {
+		return types.Checkpoint{}, err
+	}
+	c.log.WithField("imported", imported).WithField("head", c.mockChain.CurrentHeader().Hash()).Info("Imported chain export")
+
+	if c.CheckpointSync.Root == "" {
+		return types.Checkpoint{}, fmt.Errorf("checkpoint sync requires a checkpoint root")
+	}
+	cp := types.Checkpoint{Epoch: c.CheckpointSync.Epoch, Root: types.Root(common.HexToHash(c.CheckpointSync.Root))}
+	header := c.mockChain.chain.GetHeaderByHash(common.Hash(cp.Root))
+	if header == nil {
+		return cp, fmt.Errorf("checkpoint block %s is not in the chain export", common.Hash(cp.Root))
+	}
+	if start := c.state.SlotTimestamp(cp.Epoch * c.SlotsPerEpoch); header.Time > start {
+		return cp, fmt.Errorf("checkpoint block time %d is after the start of epoch %d at %d, check the beacon genesis time", header.Time, cp.Epoch, start)
+	}
+	return cp, nil
+}
@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"mergemock/beaconstate"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// newCheckpointConsensus returns a consensus mock with an empty mock chain, with Shanghai and
+// Cancun active from genesis.
+func newCheckpointConsensus(t *testing.T) *ConsensusCmd {
+	zero := uint64(0)
+	genesis := newMergedGenesis(common.Address{0x42})
+	mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	mc.SetForkTimes(ForkTimes{ShanghaiTime: &zero, CancunTime: &zero})
+	c := &ConsensusCmd{SlotsPerEpoch: 4, SlotTime: time.Second}
+	c.log = logrus.New()
+	c.mockChain = mc
+	c.state = beaconstate.New(beaconstate.Config{GenesisTime: genesis.Timestamp, SlotTime: time.Second, SlotsPerEpoch: 4}, mc.CurrentHeader().Hash())
+	return c
+}
+
+func TestCheckpointSync(t *testing.T) {
+	path := fmt.Sprintf("%s/chain.jsonl", t.TempDir())
+	export, err := openChainExport(path)
+	require.NoError(t, err)
+
+	src := newCheckpointConsensus(t)
+	src.export = export
+	var hashes []common.Hash
+	for slot := uint64(1); slot <= 10; slot++ {
+		parent := src.mockChain.CurrentHeader()
+		timestamp := src.state.SlotTimestamp(slot)
+		withdrawals := types.Withdrawals{{Index: slot, Validator: 1, Address: common.Address{0x01}, Amount: 1}}
+		beaconRoot := common.Hash{byte(slot)}
+		block, err := src.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, timestamp, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, withdrawals, &beaconRoot, true)
+		require.NoError(t, err)
+		src.exportBlock(block, withdrawals, &beaconRoot)
+		hashes = append(hashes, block.Hash())
+	}
+	require.NoError(t, export.Close())
+
+	c := newCheckpointConsensus(t)
+	c.CheckpointSync = CheckpointSync{Chain: path, Epoch: 2, Root: hashes[7].Hex()}
+	cp, err := c.checkpointSync()
+	require.NoError(t, err)
+	require.Equal(t, src.mockChain.CurrentHeader().Hash(), c.mockChain.CurrentHeader().Hash())
+	require.Equal(t, types.Checkpoint{Epoch: 2, Root: types.Root(hashes[7])}, cp)
+
+	c.state.Bootstrap(cp)
+	require.Equal(t, uint64(8), c.state.Slot())
+	require.Equal(t, cp, c.state.Finalized())
+	c.state.ProcessSlot(12, hashes[9])
+	require.Equal(t, cp, c.state.Finalized())
+	require.Equal(t, types.Checkpoint{Epoch: 3, Root: types.Root(hashes[9])}, c.state.Justified())
+
+	// known blocks are skipped
+	imported, err := c.importChain(path)
+	require.NoError(t, err)
+	require.Equal(t, 0, imported)
+
+	// the checkpoint block must be in the chain, and not be after the start of its epoch
+	c.CheckpointSync.Root = common.Hash{0x01}.Hex()
+	_, err = c.checkpointSync()
+	require.Error(t, err)
+	c.CheckpointSync = CheckpointSync{Chain: path, Epoch: 1, Root: hashes[7].Hex()}
+	_, err = c.checkpointSync()
+	require.Error(t, err)
+}
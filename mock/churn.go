@@ -0,0 +1,205 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mergemock/beaconstate"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pathProposerDuties  = "/eth/v1/validator/duties/proposer/{epoch}"
+	pathStateValidators = "/eth/v1/beacon/states/{state_id}/validators"
+)
+
+// ChurnConfig configures the churn of the validator set over a consensus run. Validators exit
+// with --freq.exit.
+type ChurnConfig struct {
+	Pending     uint64  `ask:"--pending" help:"Validators of --validators that start pending activation, to activate over the run"`
+	Activations uint64  `ask:"--activations" help:"Pending validators to activate at the start of every epoch"`
+	Slashing    float64 `ask:"--slashing" help:"How often an active validator is slashed at the start of an epoch, and exits"`
+}
+
+func (ch *ChurnConfig) Default() {
+	ch.Activations = 4
+}
+
+// pendingValidators returns the number of the emulated validators that start pending activation.
+func (c *ConsensusCmd) pendingValidators() int {
+	if len(c.validators) == 0 {
+		return 0
+	}
+	return int(c.Churn.Pending)
+}
+
+// registrationFeeRecipient is the fee recipient the validators register with, without a fee
+// recipient contract.
+var registrationFeeRecipient = common.Address{0x42}
+
+// signRegistration signs a registration of the validator with the builder, at the current time.
+func (c *ConsensusCmd) signRegistration(ctx context.Context, v validator) (types.SignedValidatorRegistration, error) {
+	msg := &types.RegisterValidatorRequestMessage{
+		FeeRecipient: types.Address(c.feeRecipient(registrationFeeRecipient)),
+		GasLimit:     30_000_000,
+		Timestamp:    uint64(time.Now().Unix()),
+		Pubkey:       v.pk,
+	}
+	root, err := types.ComputeSigningRoot(msg, types.DomainBuilder)
+	if err != nil {
+		return types.SignedValidatorRegistration{}, err
+	}
+	var sig types.Signature
+	if v.sk != nil {
+		sig.FromSlice(v.sk.Sign(root[:]).Marshal())
+	} else if sig, err = c.signer.sign(ctx, v.pk, &signRequest{Type: "VALIDATOR_REGISTRATION", SigningRoot: root, ValidatorRegistration: msg}); err != nil {
+		return types.SignedValidatorRegistration{}, err
+	}
+	return types.SignedValidatorRegistration{Message: msg, Signature: sig}, nil
+}
+
+// churnValidators changes the validator set at the start of an epoch: an active validator may
+// exit or be slashed, keeping one active to propose, and pending validators activate, which
+// register with the builder.
+func (c *ConsensusCmd) churnValidators(log logrus.Ext1FieldLogger) {
+	if len(c.validators) == 0 {
+		return
+	}
+	if c.RNG.Float64() < c.Freq.Exit {
+		if active := c.state.ActiveValidators(); len(active) > 1 {
+			index := active[c.RNG.Intn(len(active))]
+			c.state.Exit(index)
+			log.WithField("validator", index).WithField("balance", c.state.Balance(index)).Info("Validator exited")
+		}
+	}
+	if c.Churn.Slashing > 0 && c.RNG.Float64() < c.Churn.Slashing {
+		if active := c.state.ActiveValidators(); len(active) > 1 {
+			index := active[c.RNG.Intn(len(active))]
+			c.state.Slash(index)
+			log.WithField("validator", index).WithField("balance", c.state.Balance(index)).Info("Validator slashed")
+		}
+	}
+	var activated []validator
+	for _, index := range c.state.PendingValidators() {
+		if uint64(len(activated)) == c.Churn.Activations {
+			break
+		}
+		c.state.Activate(index)
+		activated = append(activated, c.validators[index])
+		log.WithField("validator", index).Info("Validator activated")
+	}
+	if len(activated) == 0 || c.builder == nil {
+		return
+	}
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		if err := c.registerValidators(activated); err != nil {
+			log.WithError(err).Error("Failed to register activated validators")
+		}
+	}()
+}
+
+// registerValidators registers the validators with the builder.
+func (c *ConsensusCmd) registerValidators(validators []validator) error {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*10)
+	defer cancel()
+	registrations := make([]types.SignedValidatorRegistration, 0, len(validators))
+	for _, v := range validators {
+		registration, err := c.signRegistration(ctx, v)
+		if err != nil {
+			return err
+		}
+		registrations = append(registrations, registration)
+	}
+	return c.builder.RegisterValidators(ctx, registrations)
+}
+
+// proposerDuty is a proposer duty of the beacon API.
+type proposerDuty struct {
+	Pubkey         types.PublicKey `json:"pubkey"`
+	ValidatorIndex uint64          `json:"validator_index,string"`
+	Slot           uint64          `json:"slot,string"`
+}
+
+// handleProposerDuties serves the proposers of the current or next epoch. The mock has no beacon
+// blocks, so the dependent root is the randao mix that seeds the proposers.
+func (c *ConsensusCmd) handleProposerDuties(w http.ResponseWriter, req *http.Request) {
+	epoch, err := strconv.ParseUint(mux.Vars(req)["epoch"], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid epoch", http.StatusBadRequest)
+		return
+	}
+	if epoch > c.state.Epoch()+1 {
+		http.Error(w, fmt.Sprintf("epoch %d is after the next epoch", epoch), http.StatusBadRequest)
+		return
+	}
+	duties := make([]proposerDuty, 0, c.SlotsPerEpoch)
+	for slot := epoch * c.SlotsPerEpoch; slot < (epoch+1)*c.SlotsPerEpoch; slot++ {
+		index := c.state.Proposer(slot)
+		duties = append(duties, proposerDuty{Pubkey: c.validators[index].pk, ValidatorIndex: uint64(index), Slot: slot})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		DependentRoot       types.Root     `json:"dependent_root"`
+		ExecutionOptimistic bool           `json:"execution_optimistic"`
+		Data                []proposerDuty `json:"data"`
+	}{c.state.ProposerSeed(epoch), false, duties})
+}
+
+// stateValidator is a validator of the beacon API, with the fields the mock tracks.
+type stateValidator struct {
+	Index     uint64 `json:"index,string"`
+	Balance   uint64 `json:"balance,string"`
+	Status    string `json:"status"`
+	Validator struct {
+		Pubkey                types.PublicKey `json:"pubkey"`
+		WithdrawalCredentials common.Hash     `json:"withdrawal_credentials"`
+		EffectiveBalance      uint64          `json:"effective_balance,string"`
+		Slashed               bool            `json:"slashed"`
+	} `json:"validator"`
+}
+
+// handleStateValidators serves the validators of the head state, with an optional status filter.
+func (c *ConsensusCmd) handleStateValidators(w http.ResponseWriter, req *http.Request) {
+	if id := mux.Vars(req)["state_id"]; id != "head" {
+		http.Error(w, fmt.Sprintf("state %s is not available, only head", id), http.StatusNotFound)
+		return
+	}
+	filter := make(map[string]bool)
+	for _, status := range req.URL.Query()["status"] {
+		filter[status] = true
+	}
+	validators := []stateValidator{}
+	for i, v := range c.validators {
+		var sv stateValidator
+		sv.Index = uint64(i)
+		sv.Balance = c.state.Balance(i)
+		sv.Status = c.state.Status(i)
+		if len(filter) > 0 && !filter[sv.Status] {
+			continue
+		}
+		sv.Validator.Pubkey = v.pk
+		sv.Validator.WithdrawalCredentials[0] = 0x01
+		copy(sv.Validator.WithdrawalCredentials[12:], beaconstate.WithdrawalAddress(uint64(i)).Bytes())
+		sv.Validator.EffectiveBalance = sv.Balance
+		if sv.Validator.EffectiveBalance > beaconstate.MaxEffectiveBalance {
+			sv.Validator.EffectiveBalance = beaconstate.MaxEffectiveBalance
+		}
+		sv.Validator.Slashed = c.state.Slashed(i)
+		validators = append(validators, sv)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ExecutionOptimistic bool             `json:"execution_optimistic"`
+		Data                []stateValidator `json:"data"`
+	}{false, validators})
+}
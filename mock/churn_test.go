@@ -0,0 +1,89 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mergemock/beaconstate"
+	"mergemock/builderclient"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorChurn(t *testing.T) {
+	c := newAttestingConsensus(t, 8, 1)
+	c.ctx = context.Background()
+	c.state = beaconstate.New(beaconstate.Config{SlotTime: time.Second, SlotsPerEpoch: 4, Validators: 8, Pending: 4}, common.Hash{})
+	c.Churn = ChurnConfig{Activations: 2, Slashing: 1}
+
+	// activated validators register with the builder
+	var (
+		mu         sync.Mutex
+		registered []types.PublicKey
+	)
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, pathRegisterValidator, req.URL.Path)
+		var registrations []types.SignedValidatorRegistration
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&registrations))
+		mu.Lock()
+		for _, r := range registrations {
+			registered = append(registered, r.Message.Pubkey)
+		}
+		mu.Unlock()
+	}))
+	defer relay.Close()
+	c.builder = builderclient.New(relay.URL, logrus.New())
+
+	c.churnValidators(logrus.New())
+	c.inflight.Wait()
+	require.Equal(t, []types.PublicKey{c.validators[4].pk, c.validators[5].pk}, registered)
+	require.Equal(t, []int{6, 7}, c.state.PendingValidators())
+	require.Len(t, c.state.ActiveValidators(), 5)
+
+	srv := httptest.NewServer(c.beaconRouter())
+	defer srv.Close()
+
+	// the proposers are active validators
+	res, err := http.Get(srv.URL + "/eth/v1/validator/duties/proposer/1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var duties struct {
+		Data []proposerDuty `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&duties))
+	require.Len(t, duties.Data, 4)
+	for i, duty := range duties.Data {
+		require.Equal(t, uint64(4+i), duty.Slot)
+		require.True(t, c.state.Active(int(duty.ValidatorIndex)))
+		require.Equal(t, c.validators[duty.ValidatorIndex].pk, duty.Pubkey)
+	}
+	res, err = http.Get(srv.URL + "/eth/v1/validator/duties/proposer/2")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, err = http.Get(srv.URL + "/eth/v1/beacon/states/head/validators?status=pending_queued&status=withdrawal_possible")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var validators struct {
+		Data []stateValidator `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&validators))
+	require.Len(t, validators.Data, 3)
+	slashed := validators.Data[0]
+	require.True(t, slashed.Validator.Slashed)
+	require.Equal(t, beaconstate.StatusWithdrawalPossible, slashed.Status)
+	require.Equal(t, []uint64{6, 7}, []uint64{validators.Data[1].Index, validators.Data[2].Index})
+	require.Equal(t, byte(0x01), validators.Data[1].Validator.WithdrawalCredentials[0])
+
+	res, err = http.Get(srv.URL + "/eth/v1/beacon/states/finalized/validators")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,213 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/protolambda/ask"
+)
+
+// shells are the shells completion scripts can be generated for.
+var shells = []string{"bash", "zsh", "fish"}
+
+type CompletionCmd struct {
+	Shell string `ask:"<shell>" help:"Shell to generate the completion script for: 'bash', 'zsh' or 'fish'"`
+
+	out io.Writer
+}
+
+func (c *CompletionCmd) Help() string {
+	return "Print a completion script of the subcommands and flags for bash, zsh or fish."
+}
+
+func (c *CompletionCmd) Examples() []string {
+	return []string{
+		"source <(mergemock completion bash)",
+		"mergemock completion zsh > \"${fpath[1]}/_mergemock\"",
+		"mergemock completion fish > ~/.config/fish/completions/mergemock.fish",
+	}
+}
+
+func (c *CompletionCmd) Run(ctx context.Context, args ...string) error {
+	if c.out == nil {
+		c.out = os.Stdout
+	}
+	cmds, err := commandTree(&MergeMockCmd{})
+	if err != nil {
+		return err
+	}
+	switch c.Shell {
+	case "bash":
+		return bashCompletion(c.out, cmds)
+	case "zsh":
+		return zshCompletion(c.out, cmds)
+	case "fish":
+		return fishCompletion(c.out, cmds)
+	default:
+		return fmt.Errorf("unknown shell %q, expected one of %s", c.Shell, strings.Join(shells, ", "))
+	}
+}
+
+// completionFlag is a flag to complete, with the first line of its help.
+type completionFlag struct {
+	name string
+	help string
+}
+
+// completionCmd is a subcommand to complete, with its flags, and the values of its argument.
+type completionCmd struct {
+	name   string
+	help   string
+	flags  []completionFlag
+	values []string
+}
+
+// commandTree lists the subcommands of the route, with the flags that are not hidden.
+func commandTree(route ask.CommandKnownRoutes) ([]completionCmd, error) {
+	var cmds []completionCmd
+	for _, name := range route.Routes() {
+		sub, err := route.(ask.CommandRoute).Cmd(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get command %q: %w", name, err)
+		}
+		descr, err := ask.Load(sub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load command %q: %w", name, err)
+		}
+		cmd := completionCmd{name: name}
+		if descr.Help != nil {
+			cmd.help = descr.Help.Help()
+		}
+		for _, fl := range descr.All("") {
+			if fl.IsArg || fl.Hidden || fl.Deprecated != "" {
+				continue
+			}
+			cmd.flags = append(cmd.flags, completionFlag{name: fl.Path, help: strings.SplitN(fl.Help, "\n", 2)[0]})
+		}
+		if _, ok := sub.(*CompletionCmd); ok {
+			cmd.values = shells
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+func names(cmds []completionCmd) string {
+	out := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		out = append(out, cmd.name)
+	}
+	return strings.Join(out, " ")
+}
+
+func (c *completionCmd) words() string {
+	out := append([]string{}, c.values...)
+	for _, fl := range c.flags {
+		out = append(out, "--"+fl.name)
+	}
+	return strings.Join(out, " ")
+}
+
+func bashCompletion(w io.Writer, cmds []completionCmd) error {
+	var b strings.Builder
+	b.WriteString("# bash completion for mergemock\n")
+	b.WriteString("_mergemock() {\n")
+	b.WriteString("\tlocal cur=${COMP_WORDS[COMP_CWORD]}\n")
+	b.WriteString("\tif [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", names(cmds))
+	b.WriteString("\t\treturn\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcase ${COMP_WORDS[1]} in\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "\t%s) COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", cmd.name, cmd.words())
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("}\n")
+	b.WriteString("complete -o default -F _mergemock mergemock\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func zshCompletion(w io.Writer, cmds []completionCmd) error {
+	var b strings.Builder
+	b.WriteString("#compdef mergemock\n")
+	b.WriteString("_mergemock() {\n")
+	b.WriteString("\tif (( CURRENT == 2 )); then\n")
+	b.WriteString("\t\tlocal -a commands\n")
+	b.WriteString("\t\tcommands=(\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "\t\t\t%s\n", zshQuote(cmd.name+":"+cmd.help))
+	}
+	b.WriteString("\t\t)\n")
+	b.WriteString("\t\t_describe command commands\n")
+	b.WriteString("\t\treturn\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("\tcase $words[2] in\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "\t%s) compadd -- %s ;;\n", cmd.name, cmd.words())
+	}
+	b.WriteString("\tesac\n")
+	b.WriteString("\t_files\n")
+	b.WriteString("}\n")
+	b.WriteString("compdef _mergemock mergemock\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "'\\''") + "'"
+}
+
+func fishCompletion(w io.Writer, cmds []completionCmd) error {
+	var b strings.Builder
+	b.WriteString("# fish completion for mergemock\n")
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "complete -c mergemock -f -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.help))
+	}
+	for _, cmd := range cmds {
+		cond := fishQuote("__fish_seen_subcommand_from " + cmd.name)
+		if len(cmd.values) > 0 {
+			fmt.Fprintf(&b, "complete -c mergemock -f -n %s -a %s\n", cond, fishQuote(strings.Join(cmd.values, " ")))
+		}
+		for _, fl := range cmd.flags {
+			fmt.Fprintf(&b, "complete -c mergemock -n %s -l %s -d %s\n", cond, fl.name, fishQuote(fl.help))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(s) + "'"
+}
+
+// examples is implemented by commands with example invocations for their --help.
+type examples interface {
+	Examples() []string
+}
+
+// Usage returns the usage of the command, followed by its examples.
+func Usage(descr *ask.CommandDescription) string {
+	out := descr.Usage(false)
+	var ex examples
+	if cmd, ok := descr.Command.(examples); ok {
+		ex = cmd
+	} else if route, ok := descr.CommandRoute.(examples); ok {
+		ex = route
+	}
+	if ex == nil {
+		return out
+	}
+	var b strings.Builder
+	b.WriteString(out)
+	b.WriteString("Examples:\n")
+	for _, example := range ex.Examples() {
+		b.WriteString("  $ ")
+		b.WriteString(example)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
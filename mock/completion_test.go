@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/protolambda/ask"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range shells {
+		var out bytes.Buffer
+		cmd := &CompletionCmd{Shell: shell, out: &out}
+		require.NoError(t, cmd.Run(context.Background()))
+		script := out.String()
+		for _, name := range (&MergeMockCmd{}).Routes() {
+			require.Contains(t, script, name, shell)
+		}
+		require.Contains(t, script, "slot-time", shell)
+		require.Contains(t, script, "log.level", shell)
+		require.Contains(t, script, "bash zsh fish", shell)
+
+		if shell == "bash" {
+			if _, err := exec.LookPath("bash"); err == nil {
+				check := exec.Command("bash", "-n")
+				check.Stdin = strings.NewReader(script)
+				output, err := check.CombinedOutput()
+				require.NoError(t, err, string(output))
+			}
+		}
+	}
+
+	err := (&CompletionCmd{Shell: "powershell", out: &bytes.Buffer{}}).Run(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown shell")
+}
+
+func TestExamples(t *testing.T) {
+	root := &MergeMockCmd{}
+	descr, err := ask.Load(root)
+	require.NoError(t, err)
+	require.Contains(t, Usage(descr), "Examples:\n  $ mergemock engine --help\n")
+
+	for _, name := range root.Routes() {
+		sub, err := root.Cmd(name)
+		require.NoError(t, err)
+		descr, err := ask.Load(sub)
+		require.NoError(t, err)
+		ex, ok := sub.(examples)
+		require.True(t, ok, "%s has no examples", name)
+		require.NotEmpty(t, ex.Examples(), name)
+		require.Contains(t, Usage(descr), "Examples:\n", name)
+
+		flags := make(map[string]bool)
+		for _, fl := range descr.All("") {
+			flags[fl.Path] = true
+		}
+		// every example runs the command, with flags it knows
+		for _, example := range ex.Examples() {
+			require.Contains(t, example, "mergemock "+name, example)
+			for _, arg := range strings.Fields(example) {
+				if strings.HasPrefix(arg, "--") {
+					require.True(t, flags[strings.SplitN(arg[2:], "=", 2)[0]], "unknown flag %s in example %q", arg, example)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package mock
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// Compression configures the compression of relay request and response bodies. Only gzip is
+// supported, brotli needs a dependency the module does not have.
+type Compression struct {
+	Disable       bool    `ask:"--disable" help:"Do not compress responses, even if the client accepts gzip"`
+	WrongEncoding float64 `ask:"--wrong-encoding" help:"How often a response has a Content-Encoding that does not match its body"`
+	RNG           RNG     `ask:"--rng" help:"seed the RNG of the wrong encodings with an integer number"`
+}
+
+func (c *Compression) Default() {
+	c.RNG = NewRNG(DefaultRNGSeed)
+}
+
+// acceptsGzip returns whether the Accept-Encoding header allows a gzip response.
+func acceptsGzip(header string) bool {
+	for _, token := range strings.Split(header, ",") {
+		parts := strings.Split(token, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		rejected := false
+		for _, param := range parts[1:] {
+			param = strings.ReplaceAll(param, " ", "")
+			if param == "q=0" || strings.HasPrefix(param, "q=0.") && strings.Trim(param[4:], "0") == "" {
+				rejected = true
+			}
+		}
+		if !rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter writes the response body compressed, and labels it with the encoding, which
+// is wrong in the wrong-encoding fault mode. Bodyless responses are left alone.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	compress    bool
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		w.compress = false
+	} else {
+		if w.encoding != "" {
+			w.Header().Set("Content-Encoding", w.encoding)
+		}
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz == nil {
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	return w.gz.Write(b)
+}
+
+func (w *compressWriter) Close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// compressionMiddleware decompresses gzip request bodies, refusing other encodings, and
+// compresses responses for clients that accept gzip.
+func (r *RelayBackend) compressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+		case "gzip":
+			body, err := gzip.NewReader(req.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer body.Close()
+			req.Body = body
+			req.Header.Del("Content-Encoding")
+		default:
+			http.Error(w, "unsupported content encoding: "+encoding, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		gzipped := !r.compression.Disable && acceptsGzip(req.Header.Get("Accept-Encoding"))
+		cw := &compressWriter{ResponseWriter: w, compress: gzipped}
+		if gzipped {
+			cw.encoding = "gzip"
+		}
+		if r.compression.RNG.Float64() < r.compression.WrongEncoding {
+			// label a plain body as gzip, or send a gzip body unlabeled
+			r.log.WithField("path", req.URL.EscapedPath()).Info("Mocking wrong content encoding")
+			cw.compress = !gzipped
+			cw.encoding = ""
+			if gzipped {
+				cw.encoding = "gzip"
+			}
+		}
+		defer cw.Close()
+		next.ServeHTTP(cw, req)
+	})
+}
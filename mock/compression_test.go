@@ -0,0 +1,91 @@
+package mock
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	require.True(t, acceptsGzip("gzip"))
+	require.True(t, acceptsGzip("br;q=1.0, gzip;q=0.8"))
+	require.True(t, acceptsGzip("*"))
+	require.False(t, acceptsGzip(""))
+	require.False(t, acceptsGzip("br"))
+	require.False(t, acceptsGzip("gzip;q=0, br"))
+	require.False(t, acceptsGzip("gzip; q=0.000"))
+}
+
+func TestCompression(t *testing.T) {
+	relay := newTestRelay(t)
+	request := func(body []byte, headers map[string]string) *httptest.ResponseRecorder {
+		method, path := "GET", pathStatus
+		if body != nil {
+			method, path = "POST", pathRegisterValidator
+		}
+		req, err := http.NewRequest(method, path, bytes.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		relay.getRouter().ServeHTTP(rr, req)
+		return rr
+	}
+	gunzip := func(b []byte) string {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		require.NoError(t, err)
+		dec, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(dec)
+	}
+
+	// responses are compressed for clients that accept it
+	rr := request(nil, nil)
+	require.Empty(t, rr.Header().Get("Content-Encoding"))
+	require.Equal(t, "{}", rr.Body.String())
+	rr = request(nil, map[string]string{"Accept-Encoding": "gzip"})
+	require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	require.Equal(t, "{}", gunzip(rr.Body.Bytes()))
+
+	// compressed requests
+	_, sk := newKeypair(t)
+	msg := &types.RegisterValidatorRequestMessage{GasLimit: 30_000_000, Timestamp: uint64(time.Now().Unix())}
+	msg.Pubkey.FromSlice(sk.PublicKey().Marshal())
+	root, err := types.ComputeSigningRoot(msg, types.DomainBuilder)
+	require.NoError(t, err)
+	reg := types.SignedValidatorRegistration{Message: msg}
+	reg.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+	enc, err := json.Marshal([]types.SignedValidatorRegistration{reg})
+	require.NoError(t, err)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(enc)
+	require.NoError(t, w.Close())
+	rr = request(gz.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.NotNil(t, relay.registration(msg.Pubkey))
+	rr = request(enc, map[string]string{"Content-Encoding": "gzip"})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = request(enc, map[string]string{"Content-Encoding": "br"})
+	require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+	// wrong encodings
+	relay.compression.WrongEncoding = 1
+	rr = request(nil, map[string]string{"Accept-Encoding": "gzip"})
+	require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	require.Equal(t, "{}", rr.Body.String())
+	rr = request(nil, nil)
+	require.Empty(t, rr.Header().Get("Content-Encoding"))
+	require.Equal(t, "{}", gunzip(rr.Body.Bytes()))
+}
@@ -0,0 +1,1737 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"mergemock/api"
+	"mergemock/beaconstate"
+	"mergemock/builderclient"
+	"mergemock/engineclient"
+	"mergemock/p2p"
+	"mergemock/rpc"
+	"mergemock/slashing"
+	"mergemock/types"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/crypto/bls/blst"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+)
+
+type validator struct {
+	pk types.PublicKey
+	sk bls.SecretKey
+
+	// public key of a validator of the remote signer, which has no secret key
+	remote bls.PublicKey
+}
+
+// pendingProposal is a payload the engine is building for the next slot.
+type pendingProposal struct {
+	id         types.PayloadID
+	attributes *types.PayloadAttributesV3
+}
+
+type ConsensusCmd struct {
+	BeaconGenesisTime uint64        `ask:"--beacon-genesis-time" help:"Beacon genesis time"`
+	GenesisTimeFrom   string        `ask:"--genesis-time-from" help:"Derive the beacon genesis time from the timestamp of a block of the engine instead: 'genesis' for its genesis block, or the number or hash of an anchor block (empty to use --beacon-genesis-time)"`
+	SlotTime          time.Duration `ask:"--slot-time" help:"Time per slot"`
+	SlotsPerEpoch     uint64        `ask:"--slots-per-epoch" help:"Slots per epoch"`
+	CatchUpSlotTime   time.Duration `ask:"--catch-up-slot-time" help:"Time per slot when catching up on slots that passed before the start (0 to skip them)"`
+	TimeScale         float64       `ask:"--time-scale" help:"Speed of the clock of the slots relative to the wall clock, with the same slot timestamps"`
+	Deterministic     bool          `ask:"--deterministic" help:"Start every slot interval once the engine calls of the previous one complete, instead of following the clock"`
+	// TODO ideas:
+	// - % random gap slots (= missing beacon blocks)
+	// - % random finality
+
+	EngineAddr     string `ask:"--engine" help:"Address of Engine JSON-RPC endpoint to use"`
+	ProposerEngine string `ask:"--proposer-engine" help:"Address of the Engine JSON-RPC endpoint of a second client, to validate the payloads the engine builds as the engine of the proposer (empty to disable)"`
+	BuilderAddr    string `ask:"--builder" help:"Address of builder relay REST API endpoint to use"`
+	BeaconAPIAddr  string `ask:"--beacon-api" help:"Address to serve the light client data of the mock chain on, over the beacon API (requires --validators, empty to disable)"`
+	DataDir        string `ask:"--datadir" help:"Directory to store execution chain data (empty for in-memory data)"`
+	EthashDir      string `ask:"--ethashdir" help:"Directory to store ethash data"`
+	GenesisPath    string `ask:"--genesis" help:"Genesis execution-config file"`
+	JwtSecretPath  string `ask:"--jwt-secret" help:"JWT secret key for authenticated communication"`
+	Enode          string `ask:"--node" help:"Enode of execution client, required to insert pre-merge blocks."`
+	TerminalCheck  bool   `ask:"--terminal-check" help:"After the pre-merge blocks, check the engine rejects a payload built on a PoW block before the terminal block"`
+	SlotBound      uint64 `ask:"--slot-bound" help:"Terminate after the specified number of slots."`
+	ValidatorCount uint64 `ask:"--validators" help:"Number of validators to emulate."`
+
+	// BuilderTimeout is the getHeader timeout of mev-boost and consensus clients, 950ms on mainnet.
+	BuilderTimeout time.Duration `ask:"--builder-timeout" help:"Timeout of getHeader requests to the builder, after which the proposer falls back to the payload of the engine (0 for none)"`
+
+	GenesisValidatorsRoot types.Root `ask:"--genesis-validators-root" help:"Root of genesis validators"`
+
+	DiscoveryAddr string   `ask:"--discv5" help:"UDP address to answer discv5 on, with a node record of the mock beacon node for consensus clients to discover (empty to disable)"`
+	Bootnodes     []string `ask:"--bootnodes" help:"Node records of discv5 nodes to join, like the consensus clients under test"`
+
+	Pprof bool `ask:"--pprof" help:"Serve the runtime profiles and goroutine dumps of net/http/pprof on the beacon API, under /debug/pprof/ (requires --beacon-api)"`
+
+	// Slots receives the slots as they start, when the consensus mock is embedded (nil to disable)
+	Slots chan<- SlotEvent
+	// BeaconListener serves the beacon API instead of BeaconAPIAddr, when the consensus mock is embedded
+	BeaconListener net.Listener
+
+	ProbeAddr  string `ask:"--probe-addr" help:"Address to serve the /healthz and /readyz probes on (empty to disable)"`
+	ReadySlots uint64 `ask:"--ready-slots" help:"Slots without a new slot, or the head may lag behind the latest slot, before /readyz fails (0 to only check the engine)"`
+
+	// DepositContract must match the engine, as it changes the genesis state.
+	DepositContract string `ask:"--deposit-contract" help:"Address to deploy the deposit contract at in genesis (empty to disable)"`
+
+	ExportPath string `ask:"--export" help:"File to append the payloads of the mock chain to, for checkpoint sync (empty to disable)"`
+
+	HeadCheckSlots uint64 `ask:"--head-check-slots" help:"Check the latest, safe and finalized blocks of the engine against the forkchoice every this many slots (0 to disable)"`
+
+	SafeLagSlots       int `ask:"--safe-lag-slots" help:"Slots the safe block of the forkchoice trails the head by, 0 to keep it at the head (-1 to keep it at the finalized checkpoint)"`
+	FinalizedLagEpochs int `ask:"--finalized-lag-epochs" help:"Epochs the finalized block of the forkchoice trails the epoch of the head by, more than the run lasts to never advance it past genesis (-1 to follow the finality of the beacon state)"`
+
+	ForkchoiceEdges bool `ask:"--forkchoice-edges" help:"Once before finality, check the engine answers forkchoice updates with a zero finalized hash, the genesis block as head, and a finalized block ahead of the head as the spec mandates"`
+
+	History HistoryCheck `ask:".history-check" help:"Audit the historical blocks of the engine against the canonical chain of the forkchoice"`
+
+	SmokeCheckSlots uint64 `ask:"--smoke-check-slots" help:"Check the transactions of the block of the slot with eth_call, eth_estimateGas and receipt queries to the engine every this many slots (0 to disable)"`
+
+	EngineTxs bool `ask:"--engine-txs" help:"Also submit the transactions of the tx profile to the engine over eth_sendRawTransaction, before asking it to build a payload"`
+
+	JournalPath string `ask:"--journal" help:"File to record the state of the run in every slot, to resume from after a restart (requires --datadir, empty to disable)"`
+
+	BuilderSchema string `ask:"--builder-schema" help:"Validate builder API responses against the builder-specs schemas: 'off', 'warn' to log violations, 'error' to fail the request"`
+
+	EngineSchema string `ask:"--engine-schema" help:"Validate engine responses against the execution-apis schemas: 'off', 'warn' to log violations, 'error' to fail the call"`
+
+	Report ReportConfig `ask:".report" help:"Report the scenarios of the run as test cases, for CI"`
+
+	Diffs DiffConfig `ask:".diffs" help:"Write the field differences of payloads that fail the checks of the run, for CI annotations"`
+
+	Push MetricsPush `ask:".metrics-push" help:"Push the metrics of the run when it ends, for CI runs too short-lived to scrape"`
+
+	Watchdog Watchdog `ask:".watchdog" help:"Detect stalled runs, and dump the diagnostics to debug them"`
+
+	Artifacts Artifacts `ask:".artifacts" help:"Write a bundle of every failed check, to reproduce it against the engine with"`
+
+	Epochs EpochConfig `ask:".epoch" help:"Torture the engine around the epoch boundaries, and align the forks with them"`
+
+	Churn ChurnConfig `ask:".churn" help:"Activate, exit and slash validators over the run, which changes the proposers, registrations and withdrawals"`
+
+	Witness WitnessConfig `ask:".witness" help:"Generate the execution witnesses of the payloads, to export them and execute them statelessly"`
+
+	DeepReorg DeepReorgConfig `ask:".deep-reorg" help:"Reorg the engine deeper than its in-memory caches, to a side chain hidden from it"`
+
+	FeeRecipient FeeRecipientConfig `ask:".fee-recipient" help:"Make the fee recipient of the proposers a contract, which receives the priority fees and builder payments"`
+
+	StateGrowth StateGrowth `ask:".state-growth" help:"Grow the state by fresh storage slots in every block with the state-growth tx profile, and report the growth"`
+
+	BidCompare BidCompare `ask:".bid-compare" help:"Compare the bids of the builder with the local payload of the engine, and report the bid deltas"`
+
+	TxFaults TxFaults `ask:".tx-faults" help:"Send faulty transactions to the engine in external blocks and over eth_sendRawTransaction, which it must reject"`
+
+	PayloadLimits PayloadLimitsConfig `ask:".payload-limits" help:"Send blocks at and just above the limits of the payload to the engine, which it must accept and reject"`
+
+	CheckpointSync `ask:".checkpoint-sync" help:"Start mid-chain from a chain export and a finalized checkpoint"`
+
+	EngineLimits `ask:".engine-limit" help:"Limit the concurrent engine calls"`
+
+	Burst BurstConfig `ask:".burst" help:"Hold back blocks from the engine, and deliver them in bursts"`
+
+	Delivery DeliveryConfig `ask:".delivery" help:"Send payloads to the engine more than once and out of order, and record its status transitions"`
+
+	Ancient AncientConfig `ask:".ancient" help:"Re-send blocks far behind the finalized block to the engine"`
+
+	PayloadID PayloadIDConfig `ask:".payload-id" help:"Probe how long the engine serves the payloads it built by their payload id"`
+
+	AccessLog AccessLogConfig `ask:".access-log" help:"Log a sample of the engine calls and beacon API requests with their bodies"`
+
+	Keys KeysConfig `ask:".keys" help:"Load the keys of the validators from keystores, or derive them from a mnemonic"`
+
+	RemoteSigner RemoteSignerConfig `ask:".remote-signer" help:"Sign for the first validators with a web3signer"`
+
+	SlashingProtection SlashingProtectionConfig `ask:".slashing-protection" help:"Refuse to sign slashable blinded blocks"`
+
+	AssertionsPath string `ask:"--assert" help:"File of rules the events of the run must hold to, like 'newPayload.latency < 2s', to end the run on the first violation (empty to disable)"`
+
+	Preset string `ask:"--preset" help:"Behavior preset to start from: 'missed-slots', 'late-bids', 'relay-outage', 'slow-el', 'mainnet-bad-day' (empty for none)"`
+
+	// embed consensus behaviors
+	ConsensusBehavior `ask:"."`
+
+	// embed logger options
+	LogCmd `ask:".log" help:"Change logger configuration"`
+
+	TraceLogConfig `ask:".trace" help:"Tracing options"`
+
+	close     chan struct{}
+	log       logrus.Ext1FieldLogger
+	ctx       context.Context
+	engine    *engineclient.Client
+	jwtSecret []byte
+	db        ethdb.Database
+
+	// validates the payloads the engine builds, nil without a proposer engine
+	proposerEngine *engineclient.Client
+
+	// client of the builder API of the builder, nil without a builder
+	builder *builderclient.Client
+
+	// signs for the validators without a secret key, nil without a remote signer
+	signer *remoteSigner
+
+	// blocks the validators signed, nil without validators
+	slashingDB *slashing.DB
+
+	// results of the scenarios of the run, nil if not reported
+	report *scenarioReport
+
+	// rules the events of the run must hold to, nil without an assertions file
+	assertions *assertions
+
+	// output of the field differences of failed checks, nil if only logged
+	diffs *diffReporter
+
+	// progress of the head, and the last errors of the log, for the watchdog
+	watchdog watchdogState
+
+	// last payloads and engine calls, for the bundles of failed checks
+	artifacts artifactState
+
+	// comparisons of the bids with the local payloads, nil if not reported
+	bidDeltas *bidDeltaReport
+
+	// canonical chain of the last forkchoice the engine accepted, for the history check
+	canonical canonicalHashes
+
+	// faulty transactions the engine took, to check they are not in the payloads of the proposals
+	txFaults txFaultState
+
+	genesisValidatorsRoot types.Root
+
+	ethashCfg ethash.Config
+
+	mockChain  *MockChain
+	validators []validator
+
+	// beacon state of the mock chain, with the clock of its slots, and the attestations to include
+	state        *beaconstate.State
+	clock        *virtualClock
+	attestations attestationPool
+
+	// engine interactions of the current slot interval, to wait for in deterministic mode
+	inflight sync.WaitGroup
+
+	// payloads the engine missed while offline
+	offline engineBuffer
+
+	// blocks held back for the next burst
+	burst burstBuffer
+
+	// responses of the engine to ancient blocks
+	ancient responseCounts
+
+	// last payload ids of the engine, and its responses to probes of them
+	payloadIDs         issuedPayloads
+	payloadIDResponses responseCounts
+
+	// status transitions of the payloads sent again, by scenario
+	transitions responseCounts
+
+	// forkchoice the engine accepted last, to check its block tags against
+	forkchoice advertisedForkchoice
+
+	// deposits of the deposit contract, to include in blinded blocks
+	deposits depositTracker
+
+	// contracts of the contracts tx profile
+	deployments contractDeployments
+
+	// price pool of the bundles tx profile
+	pool poolDeployment
+
+	// fee recipient contract of the proposers
+	recipient recipientDeployment
+
+	// storage churner of the state-growth tx profile
+	churner churnerDeployment
+
+	// side chain fork of the deep reorg
+	deep deepReorgState
+
+	// whether the forkchoice edge cases were checked
+	edgesChecked bool
+
+	// light client data of the mock chain, served over the beacon API, nil if not served
+	beaconSrv   *http.Server
+	lightClient lightClientStore
+
+	// discv5 of the mock beacon node, nil if not answered
+	discovery *discover.UDPv5
+
+	// health and readiness probes, nil if not served
+	probeSrv *http.Server
+	progress slotProgress
+
+	export *chainExport
+}
+
+func (c *ConsensusCmd) Default() {
+	c.BeaconGenesisTime = uint64(time.Now().Unix()) + 5
+	c.EngineAddr = "http://127.0.0.1:8551"
+	c.GenesisPath = "genesis.json"
+	c.JwtSecretPath = "jwt.hex"
+	c.Enode = ""
+	c.ValidatorCount = 1
+	c.SlotTime = time.Second * 12
+	c.CatchUpSlotTime = time.Millisecond * 500
+	c.TimeScale = 1
+	c.SlotsPerEpoch = 32
+	c.ReadySlots = 8
+	c.SafeLagSlots = -1
+	c.FinalizedLagEpochs = -1
+	c.EngineSchema = string(rpc.SchemaWarn)
+	c.BuilderSchema = string(rpc.SchemaWarn)
+	c.LogLvl = "info"
+}
+
+func (c *ConsensusCmd) Help() string {
+	return "Run a mock Consensus client."
+}
+
+func (c *ConsensusCmd) Examples() []string {
+	return []string{
+		"mergemock consensus --engine=http://127.0.0.1:8551 --jwt-secret=jwt.hex --slot-time=4s",
+		"mergemock consensus --builder=http://127.0.0.1:28545 --validators=64 --slot-time=4s",
+		"mergemock consensus --deterministic --slot-bound=32 --time-scale=10",
+		"mergemock consensus --builder=http://127.0.0.1:28545 --preset=mainnet-bad-day",
+	}
+}
+
+func (c *ConsensusCmd) Run(ctx context.Context, args ...string) error {
+	log, err := c.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	if c.JournalPath != "" && c.DataDir == "" {
+		return fmt.Errorf("journal requires a datadir")
+	}
+	preset, err := lookupPreset(c.Preset)
+	if err != nil {
+		return err
+	}
+	if preset != nil {
+		preset.applyConsensus(c)
+		log.WithField("preset", c.Preset).Info("Applied preset")
+	}
+	if c.TimeScale <= 0 {
+		return fmt.Errorf("time scale %v is not positive", c.TimeScale)
+	}
+	if err := c.Delivery.check(c.Burst); err != nil {
+		return err
+	}
+	if scaled := time.Duration(float64(c.SlotTime) / c.TimeScale); scaled < 50*time.Millisecond {
+		return fmt.Errorf("slot time %s is too small", scaled.String())
+	}
+	if c.Report.Path != "" {
+		if c.Report.Format != reportJUnit && c.Report.Format != reportHive {
+			return fmt.Errorf("unrecognized report format: %q", c.Report.Format)
+		}
+	}
+	if c.Push.URL != "" {
+		if c.Push.Format != pushPushgateway && c.Push.Format != pushOTLP {
+			return fmt.Errorf("unrecognized metrics push format: %q", c.Push.Format)
+		}
+	}
+	if c.Report.Path != "" || c.Push.URL != "" || c.Artifacts.Dir != "" {
+		// the pushed metrics include the results of the scenarios, and failed ones write bundles
+		c.report = newScenarioReport()
+		c.report.failed = c.writeArtifacts
+	}
+	if c.AssertionsPath != "" {
+		if c.assertions, err = loadAssertions(c.AssertionsPath); err != nil {
+			return err
+		}
+	}
+	if c.diffs, err = newDiffReporter(c.Diffs); err != nil {
+		return err
+	}
+	if c.bidDeltas, err = newBidDeltaReport(c.BidCompare.Path); err != nil {
+		return err
+	}
+	if err := c.TxFaults.check(c.ConsensusBehavior.TestAccounts.accounts); err != nil {
+		return err
+	}
+	if err := c.PayloadLimits.check(c.ConsensusBehavior.TestAccounts.accounts); err != nil {
+		return err
+	}
+	switch c.TxProfile {
+	case "transfer":
+	case "deposit":
+		if c.DepositContract == "" {
+			return fmt.Errorf("tx profile %q requires a deposit contract", c.TxProfile)
+		}
+	case "withdrawal-request", "consolidation-request", "contracts", "access-list", "bundles":
+	case "state-growth":
+		if c.DataDir == "" {
+			return fmt.Errorf("tx profile %q requires a datadir, the state it grows does not fit in memory", c.TxProfile)
+		}
+	default:
+		return fmt.Errorf("unrecognized tx profile: %q", c.TxProfile)
+	}
+	if _, err := c.FeeRecipient.code(); err != nil {
+		return err
+	}
+
+	jwt, err := loadJwtSecret(c.JwtSecretPath)
+	if err != nil {
+		log.WithField("err", err).Fatal("Unable to read JWT secret")
+	}
+	c.jwtSecret = jwt
+	log.WithField("val", common.Bytes2Hex(c.jwtSecret[:])).Info("Loaded JWT secret")
+
+	c.genesisValidatorsRoot = c.GenesisValidatorsRoot
+
+	// Connect to execution client engine api
+	client, err := rpc.DialContext(ctx, c.EngineAddr, c.jwtSecret)
+	if err != nil {
+		return err
+	}
+	if err := client.SetLimits(c.EngineLimits.Limits()); err != nil {
+		return err
+	}
+	if err := client.SetSchemaCheck(rpc.SchemaCheck(c.EngineSchema), log); err != nil {
+		return err
+	}
+	client.SetAccessLog(c.AccessLog.AccessLog(log))
+	c.artifacts.engine = c.Artifacts.transcript()
+	client.SetTranscript(c.artifacts.engine)
+	if c.ProposerEngine != "" {
+		proposerClient, err := rpc.DialContext(ctx, c.ProposerEngine, c.jwtSecret)
+		if err != nil {
+			return err
+		}
+		if err := proposerClient.SetLimits(c.EngineLimits.Limits()); err != nil {
+			return err
+		}
+		if err := proposerClient.SetSchemaCheck(rpc.SchemaCheck(c.EngineSchema), log); err != nil {
+			return err
+		}
+		proposerClient.SetAccessLog(c.AccessLog.AccessLog(log))
+		c.artifacts.proposer = c.Artifacts.transcript()
+		proposerClient.SetTranscript(c.artifacts.proposer)
+		c.proposerEngine = engineclient.New(proposerClient, log.WithField("engine", "proposer"))
+	}
+	builderSchemas, err := rpc.NewSchemaChecker(rpc.SchemaCheck(c.BuilderSchema), rpc.BuilderSchemas, log)
+	if err != nil {
+		return err
+	}
+	if c.BuilderAddr != "" {
+		c.builder = builderclient.New(c.BuilderAddr, log)
+		c.builder.SetSchemaCheck(builderSchemas)
+	}
+
+	if c.servesBeaconAPI() && c.ValidatorCount == 0 {
+		return fmt.Errorf("serving light client data requires validators")
+	}
+	if c.Pprof && !c.servesBeaconAPI() {
+		return fmt.Errorf("serving pprof requires the beacon API")
+	}
+
+	if c.Churn.Pending > c.ValidatorCount {
+		return fmt.Errorf("%d validators pending activation, more than the %d validators", c.Churn.Pending, c.ValidatorCount)
+	}
+
+	// Create a validator identities
+	if c.BuilderAddr != "" || c.servesBeaconAPI() {
+		if err := c.loadSlashingProtection(); err != nil {
+			return fmt.Errorf("failed to load slashing protection: %w", err)
+		}
+		keys, err := c.Keys.secretKeys(c.ValidatorCount)
+		if err != nil {
+			return fmt.Errorf("failed to load validator keys: %w", err)
+		}
+		remote, err := c.remoteValidators(ctx, log)
+		if err != nil {
+			return err
+		}
+		if uint64(len(remote)) > c.ValidatorCount {
+			return fmt.Errorf("remote signer has %d validators, more than the %d validators", len(remote), c.ValidatorCount)
+		}
+		var registrations []types.SignedValidatorRegistration
+		for i := 0; i < int(c.ValidatorCount); i++ {
+			v := validator{}
+			if i < len(remote) {
+				v = remote[i]
+			} else if keys != nil {
+				v.sk = keys[i]
+			} else if v.sk, err = blst.RandKey(); err != nil {
+				return errors.New("unable to generate bls key pair")
+			}
+			if v.sk != nil {
+				v.pk.FromSlice(v.sk.PublicKey().Marshal())
+			}
+			c.validators = append(c.validators, v)
+			// validators pending activation register once activated
+			if uint64(i) >= c.ValidatorCount-c.Churn.Pending {
+				continue
+			}
+			registration, err := c.signRegistration(ctx, v)
+			if err != nil {
+				return err
+			}
+			registrations = append(registrations, registration)
+		}
+		if c.BuilderAddr != "" && len(registrations) > 0 {
+			if err := c.builder.RegisterValidators(ctx, registrations); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.ethashCfg = ethash.Config{
+		PowMode:        ethash.ModeNormal,
+		DatasetDir:     c.EthashDir,
+		CacheDir:       c.EthashDir,
+		DatasetsInMem:  1,
+		DatasetsOnDisk: 2,
+		CachesInMem:    2,
+		CachesOnDisk:   3,
+	}
+
+	db, err := NewDB(c.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open new db: %v", err)
+	}
+
+	if c.ExportPath != "" {
+		if c.export, err = openChainExport(c.ExportPath); err != nil {
+			return err
+		}
+	}
+
+	c.log = log
+	if c.Watchdog.Slots > 0 {
+		log.AddHook(&c.watchdog)
+	}
+	c.engine = engineclient.New(client, log)
+	if err := c.engine.Negotiate(ctx); err != nil {
+		log.WithError(err).Warn("Failed to exchange capabilities with engine, assuming it supports all methods")
+	}
+	c.db = db
+	c.ctx = ctx
+	c.close = make(chan struct{})
+
+	if c.GenesisTimeFrom != "" {
+		genesisTime, err := c.engineBlockTimestamp(ctx, c.GenesisTimeFrom)
+		if err != nil {
+			return fmt.Errorf("unable to derive the beacon genesis time: %w", err)
+		}
+		c.BeaconGenesisTime = genesisTime
+		log.WithField("from", c.GenesisTimeFrom).WithField("genesisTime", genesisTime).Info("Derived the beacon genesis time from the engine")
+	}
+	if c.Epochs.AlignForks {
+		if err := c.alignForks(log); err != nil {
+			return fmt.Errorf("unable to align the forks with epochs: %w", err)
+		}
+	}
+
+	if c.servesBeaconAPI() {
+		c.startBeaconAPI(log)
+	}
+	if c.DiscoveryAddr != "" {
+		if err := c.startDiscovery(log); err != nil {
+			return fmt.Errorf("unable to start discv5: %w", err)
+		}
+	}
+	if c.ProbeAddr != "" {
+		c.startProbes(log)
+	}
+
+	go c.RunNode()
+
+	return nil
+}
+
+func (c *ConsensusCmd) SlotTimestamp(slot uint64) uint64 {
+	return c.state.SlotTimestamp(slot)
+}
+
+func (c *ConsensusCmd) ValidateTimestamp(timestamp uint64, slot uint64) error {
+	expectedTimestamp := c.state.SlotTimestamp(slot)
+	if timestamp != expectedTimestamp {
+		return fmt.Errorf("wrong timestamp: got %d, expected %d", timestamp, expectedTimestamp)
+	}
+	return nil
+}
+
+func (c *ConsensusCmd) proofOfWorkPrelogue(log logrus.Ext1FieldLogger) (transitionBlock uint64, err error) {
+	// Create a temporary chain around the db, with ethash consensus, to run through the POW part.
+	engine := ethash.New(c.ethashCfg, nil, false)
+
+	genesis, err := LoadGenesis(c.GenesisPath, c.DepositContract)
+	if err != nil {
+		return 0, err
+	}
+	mc, err := NewMockChain(log, engine, genesis, c.db, &c.TraceLogConfig)
+	if err != nil {
+		return 0, fmt.Errorf("unable to initialize mock chain: %v", err)
+	}
+	if mc.chain.Config().TerminalTotalDifficulty.Cmp(common.Big0) != 1 {
+		// Already transitioned
+		return 0, nil
+	}
+
+	// Dial the peer to feed the POW blocks to
+	n, err := enode.Parse(enode.ValidSchemes, c.Enode)
+	if err != nil {
+		return 0, fmt.Errorf("malformatted enode address (%q): %v", c.Enode, err)
+	}
+	peer, err := p2p.Dial(n)
+	if err != nil {
+		return 0, fmt.Errorf("unable to connect to client: %v", err)
+	}
+	if err := peer.Peer(mc.chain, nil); err != nil {
+		return 0, fmt.Errorf("unable to peer with client: %v", err)
+	}
+	ctx, cancelPeer := context.WithCancel(c.ctx)
+	defer cancelPeer()
+
+	// keep peer connection alive until after the transition
+	go peer.KeepAlive(ctx, log)
+
+	defer mc.Close()
+	defer engine.Close()
+
+	// Send pre-transition blocks
+	for {
+		parent := mc.CurrentHeader()
+
+		if c.RNG.Float64() < c.Freq.ReorgFreq {
+			parent = c.calcReorgTarget(mc.chain, parent.Number.Uint64(), 0)
+		}
+
+		// build a block, without using the engine, and insert it into the engine
+		block, err := mc.MineBlock(parent)
+		if err != nil {
+			return 0, fmt.Errorf("failed to mine block: %v", err)
+		}
+
+		// announce block
+		newBlock := eth.NewBlockPacket{Block: block, TD: mc.CurrentTd()}
+		if err := peer.Write66(&newBlock, 23); err != nil {
+			return 0, fmt.Errorf("failed to msg peer: %v", err)
+		}
+
+		// check if terminal total difficulty is reached
+		ttd := mc.chain.Config().TerminalTotalDifficulty
+		td := mc.CurrentTd()
+		log.WithField("td", td).WithField("ttd", ttd).Debug("Comparing TD to terminal TD")
+		if td.Cmp(ttd) >= 0 {
+			head := mc.CurrentHeader()
+			if !mc.IsTerminalBlock(head) {
+				return 0, fmt.Errorf("head %s passed the terminal total difficulty without a terminal block", head.Hash())
+			}
+			log.WithField("terminal", head.Hash()).WithField("td", td).Info("Terminal total difficulty reached, transitioning to POS")
+			return head.Number.Uint64(), nil
+		}
+	}
+}
+
+func (c *ConsensusCmd) RunNode() {
+	var (
+		ticks           = make(chan slotTick)
+		done            = make(chan struct{})
+		transitionBlock = uint64(0)
+		safeHash        = common.Hash{}
+		posEngine       = &ExecutionConsensusMock{
+			pow: ethash.New(c.ethashCfg, nil, false),
+			log: c.log,
+		}
+		// buffered, so the engine interactions of a slot complete without waiting for the next
+		proposals = make(chan pendingProposal, 1)
+	)
+	defer close(done)
+
+	var resumed *journal
+	if c.JournalPath != "" {
+		var err error
+		if resumed, err = readJournal(c.JournalPath); err != nil {
+			c.log.WithError(err).Error("Unable to resume")
+			os.Exit(1)
+		}
+	}
+
+	// Run PoW prelouge if peered with client, unless resuming or starting from a checkpoint
+	if resumed != nil {
+		c.log.WithField("slot", resumed.State.Slot).Info("Resuming from journal")
+		c.BeaconGenesisTime = resumed.GenesisTime
+		transitionBlock = resumed.TransitionBlock
+	} else if c.CheckpointSync.Chain != "" {
+		c.log.Info("Checkpoint sync, skipping pre-merge transition simulation")
+	} else if c.Enode != "" {
+		var err error
+		nr, err := c.proofOfWorkPrelogue(c.log.WithField("transitioned", false))
+		if err != nil {
+			c.log.WithField("err", err).Error("Failed to complete POW-prologue")
+			os.Exit(1)
+		}
+		transitionBlock = nr
+	} else {
+		c.log.Info("No peer, skipping pre-merge transition simulation, starting in POS mode")
+	}
+
+	// Initialize mock chain with existing db
+	genesis, err := LoadGenesis(c.GenesisPath, c.DepositContract)
+	if err != nil {
+		c.log.WithField("err", err).Error("Unable to load genesis")
+		os.Exit(1)
+	}
+	forks, err := LoadForkTimes(c.GenesisPath)
+	if err != nil {
+		c.log.WithField("err", err).Error("Unable to load fork times")
+		os.Exit(1)
+	}
+	mc, err := NewMockChain(c.log, posEngine, genesis, c.db, &c.TraceLogConfig)
+	if err != nil {
+		c.log.WithField("err", err).Error("Unable to initialize mock chain")
+		os.Exit(1)
+	}
+	mc.SetForkTimes(forks)
+	if c.DepositContract != "" {
+		mc.SetDepositContract(common.HexToAddress(c.DepositContract))
+	}
+	if c.Witness.enabled() {
+		if err := mc.EnableWitnesses(); err != nil {
+			c.log.WithField("err", err).Error("Unable to keep execution witnesses")
+			os.Exit(1)
+		}
+	}
+	c.mockChain = mc
+	c.state = beaconstate.New(beaconstate.Config{
+		GenesisTime:   c.BeaconGenesisTime,
+		SlotTime:      c.SlotTime,
+		SlotsPerEpoch: c.SlotsPerEpoch,
+		Validators:    len(c.validators),
+		Pending:       c.pendingValidators(),
+	}, mc.CurrentHeader().Hash())
+	safeHash = mc.CurrentHeader().Hash()
+	if resumed != nil {
+		if err := c.resume(resumed); err != nil {
+			c.log.WithError(err).Error("Unable to resume")
+			os.Exit(1)
+		}
+		safeHash = common.Hash(c.state.Finalized().Root)
+	} else if c.CheckpointSync.Chain != "" {
+		cp, err := c.checkpointSync()
+		if err != nil {
+			c.log.WithError(err).Error("Unable to checkpoint sync")
+			os.Exit(1)
+		}
+		c.state.Bootstrap(cp)
+		safeHash = common.Hash(cp.Root)
+		c.log.WithField("epoch", cp.Epoch).WithField("root", safeHash).Info("Starting from checkpoint")
+	}
+	if c.TerminalCheck && transitionBlock > 0 && resumed == nil {
+		err := c.checkInvalidTerminalBlock(transitionBlock)
+		c.report.record(scenarioInvalidTerminalBlock, 0, err)
+		if err != nil {
+			c.log.WithError(err).Error("Invalid terminal block check failed")
+			c.maybeExit()
+		}
+	}
+	c.clock = newVirtualClock(c.TimeScale)
+	go c.runSlotClock(c.state.Slot(), ticks, done)
+
+	for {
+		select {
+		case tick := <-ticks:
+			if c.Deterministic {
+				c.inflight.Wait()
+			}
+			signedSlot := tick.slot
+			switch tick.interval {
+			case attestationDeadline:
+				// attest to the head after the block of the slot, if the slot was processed
+				if signedSlot > 0 && uint64(signedSlot) == c.state.Slot() {
+					if err := c.attest(uint64(signedSlot), c.mockChain.CurrentHeader().Hash()); err != nil {
+						c.log.WithField("slot", signedSlot).WithError(err).Error("Failed to attest")
+					}
+				}
+				continue
+			case aggregationDeadline:
+				if signedSlot > 0 {
+					c.attestations.aggregate(uint64(signedSlot), c.SlotsPerEpoch)
+				}
+				headCheck := c.HeadCheckSlots > 0 && signedSlot > 0 && uint64(signedSlot)%c.HeadCheckSlots == 0
+				historyCheck := c.History.Slots > 0 && signedSlot > 0 && uint64(signedSlot)%c.History.Slots == 0
+				ancientCheck := c.Ancient.Depth > 0 && c.Ancient.Slots > 0 && signedSlot > 0 && uint64(signedSlot)%c.Ancient.Slots == 0
+				edgesCheck := signedSlot > 0 && !c.offline.isOffline() && c.forkchoiceEdgesDue(uint64(signedSlot))
+				payloadIDCheck := c.PayloadID.Slots > 0 && signedSlot > 0 && uint64(signedSlot)%c.PayloadID.Slots == 0
+				if (headCheck || historyCheck || ancientCheck || edgesCheck || payloadIDCheck) && !c.offline.isOffline() {
+					c.inflight.Add(1)
+					go func() {
+						defer c.inflight.Done()
+						if headCheck {
+							c.reportForkchoiceDrift(uint64(signedSlot))
+						}
+						if historyCheck {
+							c.reportHistory(uint64(signedSlot))
+						}
+						// after the head check, as it moves the head of the engine for a moment
+						if ancientCheck {
+							c.checkAncient(uint64(signedSlot))
+						}
+						if edgesCheck {
+							c.checkForkchoiceEdges(uint64(signedSlot))
+						}
+						if payloadIDCheck {
+							c.probePayloadIDs(uint64(signedSlot))
+						}
+					}()
+				}
+				continue
+			}
+			if signedSlot < 0 {
+				// before genesis...
+				if signedSlot >= -10.0 {
+					c.log.WithField("remaining_slots", -signedSlot).Info("Counting down to genesis...")
+				}
+				continue
+			}
+			if signedSlot == 0 {
+				c.log.WithField("slot", 0).Info("Genesis!")
+				safeHash = c.mockChain.CurrentHeader().Hash()
+				continue
+			}
+			if uint64(signedSlot) <= c.state.Slot() {
+				// not after the checkpoint the state started from
+				continue
+			}
+			slot := uint64(signedSlot)
+			if c.SlotBound > 0 && slot > c.SlotBound {
+				c.log.WithField("testRuns", c.SlotBound).Info("All test runs successfully completed")
+				c.writeReport()
+				os.Exit(0)
+			}
+			c.checkProgress(slot)
+			last := c.state.Finalized()
+			if c.state.ProcessSlot(slot, c.mockChain.CurrentHeader().Hash()) {
+				safeHash = common.Hash(c.state.Finalized().Root)
+				c.slotLog(slot).WithField("last", last.Root).WithField("new", safeHash).WithField("next", c.state.Justified().Root).Info("Finalized block updated")
+				c.logEngineMetrics()
+				c.churnValidators(c.slotLog(slot))
+				c.updateFeeRecipient(c.slotLog(slot))
+				c.reportStateGrowth(c.slotLog(slot))
+			}
+			finalizedHash := common.Hash(c.state.Finalized().Root)
+			safe, final := c.lagForkchoice(c.mockChain.CurrentHeader(), slot, safeHash, finalizedHash)
+			c.resubmit(safe, final)
+			c.journalSlot(transitionBlock)
+			// Gap slot
+			if c.RNG.Float64() < c.Freq.GapSlot {
+				c.slotLog(slot).Info("Mocking gap slot, no payload execution here")
+				// empty pending proposal
+				select {
+				case <-proposals:
+				default:
+				}
+				continue
+			}
+
+			// Send bad hash
+			if c.RNG.Float64() < c.Freq.InvalidHashFreq {
+				c.log.Info("Sending payload with invalid hash")
+				timestamp := c.mockChain.CurrentHeader().Time + 1
+				payload := &types.ExecutionPayloadV3{
+					ParentHash:    c.mockChain.CurrentHeader().Hash(),
+					FeeRecipient:  common.Address{},
+					Number:        c.mockChain.CurrentHeader().Number.Uint64(),
+					GasLimit:      c.mockChain.CurrentHeader().GasLimit,
+					GasUsed:       0,
+					Timestamp:     timestamp,
+					BaseFeePerGas: c.mockChain.CurrentHeader().BaseFee,
+					BlockHash:     common.HexToHash("0xdeadbeef"),
+					Withdrawals:   c.makeWithdrawals(timestamp),
+				}
+				beaconRoot := c.makeBeaconRoot(timestamp)
+				c.inflight.Add(1)
+				go func() {
+					defer c.inflight.Done()
+					c.newPayload(c.ctx, c.log, payload, beaconRoot, types.ExecutionRequests{})
+				}()
+				continue
+			}
+
+			// Fake some forking by building on an ancestor
+			c.startDeepReorg(c.slotLog(slot), slot)
+			parent := c.mockChain.CurrentHeader()
+			if c.RNG.Float64() < c.reorgFreq(slot) {
+				min := transitionBlock
+				if header := c.mockChain.chain.GetHeaderByHash(final); header != nil {
+					num := header.Number.Uint64()
+					if min < num {
+						min = num
+					}
+				}
+				// not below the fork of the side chain of a pending deep reorg
+				if fork := c.pendingDeepReorg(); fork != nil && min < fork.Number.Uint64() {
+					min = fork.Number.Uint64()
+				}
+				parent = c.reorgTarget(c.mockChain.chain, slot, parent, min)
+			}
+
+			slotLog := c.slotLog(slot)
+			slotLog.WithField("previous", parent.Hash()).Info("Slot trigger")
+			c.progress.record(slot, c.blockSlot(c.mockChain.CurrentHeader()), time.Now())
+			c.publishSlot(slot, parent.Hash(), time.Now())
+			if c.beaconSrv != nil {
+				if err := c.updateLightClient(slot, parent); err != nil {
+					slotLog.WithError(err).Error("Failed to update light client data")
+				}
+			}
+
+			// The block of the slot reveals the randao of its proposer, after the block itself
+			// used the mix of the previous block.
+			prevRandao := common.Hash(c.state.RandaoMix())
+			reveal, err := c.randaoReveal(slot)
+			if err != nil {
+				slotLog.WithError(err).Error("Failed to reveal randao")
+				continue
+			}
+
+			// Reorg the engine to the hidden side chain, instead of the block of the slot
+			if reorged, err := c.deepReorg(slotLog, slot, prevRandao, safe, final); reorged {
+				// the payload the engine built on the canonical chain is not proposed
+				select {
+				case <-proposals:
+				default:
+				}
+				if err != nil {
+					slotLog.WithError(err).Error("Failed to build side chain for deep reorg")
+					c.report.record(scenarioDeepReorg, slot, err)
+					c.maybeExit()
+					continue
+				}
+				c.state.ProcessRandao(reveal)
+				continue
+			}
+
+			// If we're proposing, get a block from the engine!
+			select {
+			case proposal := <-proposals:
+				slotLog.WithField("payloadId", proposal.id).Info("Update forkchoice to block built by engine")
+				c.state.ProcessRandao(reveal)
+				c.inflight.Add(1)
+				go func() {
+					defer c.inflight.Done()
+					c.mockProposal(slotLog.WithField("payloadId", proposal.id), proposal, slot, false)
+				}()
+				continue
+			default:
+				// Not proposing a block
+			}
+
+			// Build a block, without using the engine, and insert it into the engine
+			slotLog.Debug("Mocking external block")
+
+			// TODO: different proposers, gas limit (target in london) changes, etc.
+			coinbase := c.feeRecipient(common.Address{1})
+			timestamp := c.SlotTimestamp(slot)
+			gasLimit := parent.GasLimit
+			extraData := []byte("proto says hi")
+			uncleBlocks := []*ethTypes.Header{}
+			creator := TransactionsCreator{c.ConsensusBehavior.TestAccounts.accounts, c.txCreatorFn()}
+			withdrawals := c.makeWithdrawals(timestamp)
+			beaconRoot := c.makeBeaconRoot(timestamp)
+
+			block, err := c.mockChain.AddNewBlock(parent.Hash(), coinbase, timestamp, gasLimit, creator, prevRandao, extraData, uncleBlocks, withdrawals, beaconRoot, true)
+			if err != nil {
+				slotLog.WithError(err).Errorf("Failed to add block")
+				continue
+			}
+			c.state.ProcessRandao(reveal)
+			c.processWithdrawals(slotLog, withdrawals)
+			c.exportBlock(block, withdrawals, beaconRoot)
+
+			slotLog.WithField("blockhash", block.Hash()).Debug("Built external block")
+
+			var limits *limitProbe
+			if c.RNG.Float64() < c.PayloadLimits.Freq {
+				if limits, err = c.buildLimitProbe(block, withdrawals, beaconRoot); err != nil {
+					slotLog.WithError(err).Error("Failed to build blocks at the payload limits")
+				}
+			}
+
+			// the lag policies put the safe and finalized blocks relative to the new head
+			safe, final = c.lagForkchoice(block.Header(), slot, safeHash, finalizedHash)
+			if c.holdBlock(slotLog, slot, block, withdrawals, beaconRoot, safe, final) {
+				continue
+			}
+
+			c.inflight.Add(1)
+			go func(log logrus.Ext1FieldLogger, block *ethTypes.Block, safe, final common.Hash) {
+				defer c.inflight.Done()
+				if c.RNG.Float64() < c.Freq.WrongBaseFee {
+					c.mockWrongBaseFee(log, slot, block, withdrawals, beaconRoot)
+				}
+				if c.RNG.Float64() < c.TxFaults.Block {
+					c.mockTxFaultBlock(log, slot, block, withdrawals, beaconRoot)
+				}
+				if limits != nil {
+					c.sendLimitProbe(log, slot, limits)
+				}
+				c.mockExecution(log, block, withdrawals, beaconRoot)
+				latest := block.Hash()
+				// Note: head and safe hash are set to the same hash,
+				// until forkchoice updates are more attestation-weight aware.
+				var attributes *types.PayloadAttributesV3
+				if c.RNG.Float64() < c.Freq.ProposalFreq {
+					// proposing next slot!
+					attributes = c.makePayloadAttributes(slot + 1)
+					if c.EngineTxs {
+						c.submitEngineTxs(log, block, attributes)
+					}
+					if c.RNG.Float64() < c.TxFaults.Mempool {
+						c.submitTxFaults(log, slot+1, block)
+					}
+				}
+				id, err := c.sendForkchoiceUpdated(latest, safe, final, attributes)
+				if err != nil {
+					c.exitUnlessOffline(scenarioForkchoiceUpdated, slot, err)
+				} else {
+					c.report.record(scenarioForkchoiceUpdated, slot, nil)
+				}
+				c.proposerForkchoiceUpdated(log, latest, safe, final)
+				if id != nil {
+					proposals <- pendingProposal{*id, attributes}
+				}
+				if err == nil && c.SmokeCheckSlots > 0 && slot%c.SmokeCheckSlots == 0 {
+					c.reportSmokeCheck(log, slot, block)
+				}
+				if err == nil {
+					c.reportFeeRecipient(log, slot, block)
+				}
+			}(slotLog, block, safe, final)
+
+		case <-c.close:
+			c.log.Info("Closing consensus mock node")
+			c.engine.Close()
+			if c.beaconSrv != nil {
+				c.beaconSrv.Close()
+			}
+			if c.discovery != nil {
+				c.discovery.Close()
+			}
+			if c.probeSrv != nil {
+				c.probeSrv.Close()
+			}
+			if c.proposerEngine != nil {
+				c.proposerEngine.Close()
+			}
+			if c.export != nil {
+				if err := c.export.Close(); err != nil {
+					c.log.WithError(err).Error("Failed closing chain export")
+				}
+			}
+			if err := c.mockChain.Close(); err != nil {
+				c.log.WithError(err).Error("Failed closing mock chain")
+			}
+			if err := c.db.Close(); err != nil {
+				c.log.WithError(err).Error("Failed closing database")
+			}
+			return
+		}
+	}
+}
+
+func (c *ConsensusCmd) sendForkchoiceUpdated(latest, safe, final common.Hash, attributes *types.PayloadAttributesV3) (*types.PayloadID, error) {
+	if c.offline.isOffline() {
+		return nil, errEngineOffline
+	}
+	result, err := c.forkchoiceUpdated(c.engine, latest, safe, final, attributes)
+	if err != nil && c.engineOffline(err) {
+		return nil, err
+	}
+	if result.PayloadStatus.Status != types.ExecutionValid {
+		c.log.WithField("status", result.PayloadStatus).Error("Update not considered valid")
+		return nil, fmt.Errorf("update not considered valid")
+	}
+	c.forkchoice.set(types.ForkchoiceStateV1{HeadBlockHash: latest, SafeBlockHash: safe, FinalizedBlockHash: final})
+	c.canonical.update(c.mockChain.chain, latest)
+	if c.PayloadID.Slots > 0 && attributes != nil && result.PayloadID != nil {
+		c.payloadIDs.add(issuedPayload{*result.PayloadID, latest, attributes.Timestamp, time.Now()})
+	}
+	return result.PayloadID, nil
+}
+
+// engineFork returns the fork of the block at the timestamp, which decides the versions of the
+// engine calls about it.
+func (c *ConsensusCmd) engineFork(timestamp uint64) engineclient.Fork {
+	switch {
+	case c.mockChain.forks.IsPrague(timestamp):
+		return engineclient.Prague
+	case c.mockChain.forks.IsCancun(timestamp):
+		return engineclient.Cancun
+	case c.mockChain.forks.IsShanghai(timestamp):
+		return engineclient.Shanghai
+	default:
+		return engineclient.Paris
+	}
+}
+
+// forkchoiceUpdated sends the forkchoice update to the engine, with the method version of the fork
+// of the attributes, or of the head if not building a payload.
+func (c *ConsensusCmd) forkchoiceUpdated(engine *engineclient.Client, latest, safe, final common.Hash, attributes *types.PayloadAttributesV3) (types.ForkchoiceUpdatedResult, error) {
+	var timestamp uint64
+	if attributes != nil {
+		timestamp = attributes.Timestamp
+	} else if head := c.mockChain.chain.GetHeaderByHash(latest); head != nil {
+		timestamp = head.Time
+	}
+	start := time.Now()
+	result, err := engine.ForkchoiceUpdated(c.correlate(c.ctx, timestamp), c.engineFork(timestamp), latest, safe, final, attributes)
+	if engine == c.engine {
+		c.assert(eventForkchoiceUpdated, logrus.Fields{
+			"corr":     correlationID(c.timestampSlot(timestamp)),
+			"head":     latest.Hex(),
+			"status":   string(result.PayloadStatus.Status),
+			"error":    errorText(err),
+			"latency":  time.Since(start),
+			"building": attributes != nil,
+		})
+	}
+	return result, err
+}
+
+// newPayload sends the payload to the engine, with the method version of the fork it belongs to.
+func (c *ConsensusCmd) newPayload(ctx context.Context, log logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	hashes, err := types.BlobVersionedHashes(payload.Transactions)
+	if err != nil {
+		log.WithError(err).Error("Cannot get versioned hashes of payload")
+		return nil, err
+	}
+	return c.sendNewPayload(ctx, c.engine, payload, hashes, beaconRoot, requests)
+}
+
+// sendNewPayload sends the payload to the engine with the given versioned hashes, which are ignored
+// before Cancun.
+func (c *ConsensusCmd) sendNewPayload(ctx context.Context, engine *engineclient.Client, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot *common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	var root common.Hash
+	if beaconRoot != nil {
+		root = *beaconRoot
+	}
+	c.artifacts.remember(c.timestampSlot(payload.Timestamp), payload)
+	start := time.Now()
+	res, err := engine.NewPayload(c.correlate(ctx, payload.Timestamp), c.engineFork(payload.Timestamp), payload, versionedHashes, root, requests)
+	if engine == c.engine {
+		var status string
+		if res != nil {
+			status = string(res.Status)
+		}
+		c.assert(eventNewPayload, logrus.Fields{
+			"number":    new(big.Int).SetUint64(payload.Number),
+			"hash":      payload.BlockHash.Hex(),
+			"status":    status,
+			"error":     errorText(err),
+			"latency":   time.Since(start),
+			"canonical": !isFault(ctx),
+			"corr":      correlationID(c.timestampSlot(payload.Timestamp)),
+		})
+	}
+	return res, err
+}
+
+// processPayload processes the payload in the consensus mock world, like the engine would.
+func (c *ConsensusCmd) processPayload(payload *types.ExecutionPayloadV3, beaconRoot *common.Hash) (*ethTypes.Block, error) {
+	switch {
+	case c.mockChain.forks.IsCancun(payload.Timestamp):
+		if beaconRoot == nil {
+			return nil, fmt.Errorf("missing parent beacon block root")
+		}
+		return c.mockChain.ProcessPayloadV3(payload, *beaconRoot)
+	case c.mockChain.forks.IsShanghai(payload.Timestamp):
+		return c.mockChain.ProcessPayloadV2(payload.V2())
+	default:
+		return c.mockChain.ProcessPayload(payload.V1())
+	}
+}
+
+func (c *ConsensusCmd) getMockProposal(ctx context.Context, log logrus.Ext1FieldLogger, payloadId types.PayloadID, slot uint64) (*types.ExecutionPayloadV3, types.ExecutionRequests, error) {
+	// If the CL is connected to builder client, request the payload from there.
+	if c.BuilderAddr != "" {
+		idx := c.state.Proposer(slot)
+		headerCtx, cancel := c.ctx, context.CancelFunc(func() {})
+		if c.BuilderTimeout > 0 {
+			headerCtx, cancel = context.WithTimeout(c.ctx, c.BuilderTimeout)
+		}
+		start := time.Now()
+		bid, err := c.builder.GetHeader(headerCtx, slot, c.mockChain.CurrentHeader().Hash(), c.validators[idx].pk)
+		cancel()
+		if bid != nil {
+			c.assertBid(bid, slot, err == nil, time.Since(start))
+		}
+		if errors.Is(err, builderclient.ErrNoBid) || errors.Is(err, builderclient.ErrInvalidBid) {
+			// like a proposer without an acceptable bid, fall back to the payload of the engine
+			log.WithError(err).Warn("No acceptable bid from builder, falling back to local payload")
+			return c.getLocalProposal(payloadId, slot)
+		}
+		if errors.Is(err, context.DeadlineExceeded) && c.ctx.Err() == nil {
+			log.WithField("timeout", c.BuilderTimeout).Warn("Builder did not bid in time, falling back to local payload")
+			return c.getLocalProposal(payloadId, slot)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if c.BidCompare.Enable {
+			payload, requests, err := c.compareBid(log, bid, payloadId, slot)
+			if err != nil || payload != nil {
+				return payload, requests, err
+			}
+		}
+
+		parentHash, err := bid.ParentHash()
+		if err != nil {
+			return nil, nil, err
+		}
+		syncAggregate, err := c.makeSyncAggregate(slot, common.Hash(parentHash))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		reveal, err := c.randaoReveal(slot)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body := &types.BlindedBeaconBlockBody{
+			RandaoReveal:  reveal,
+			Eth1Data:      &types.Eth1Data{},
+			Deposits:      []*types.Deposit{},
+			Attestations:  c.attestations.take(slot, c.SlotsPerEpoch, maxAttestations),
+			SyncAggregate: syncAggregate,
+		}
+		if c.TxProfile == "deposit" {
+			body.Eth1Data, body.Deposits, err = c.deposits.include(c.mockChain, common.HexToAddress(c.DepositContract), common.Hash(parentHash))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if c.RNG.Float64() < c.Freq.ProposerSlashing {
+			slashing, err := c.makeProposerSlashing(slot)
+			if err != nil {
+				return nil, nil, err
+			}
+			log.WithField("proposer", slashing.A.Header.ProposerIndex).Info("Including proposer slashing")
+			body.ProposerSlashings = []*types.ProposerSlashing{slashing}
+		}
+		if c.RNG.Float64() < c.Freq.AttesterSlashing {
+			slashing, err := c.makeAttesterSlashing(slot)
+			if err != nil {
+				return nil, nil, err
+			}
+			log.WithField("attesters", slashing.A.AttestingIndices).Info("Including attester slashing")
+			body.AttesterSlashings = []*types.AttesterSlashing{slashing}
+		}
+
+		unsigned := &types.BlindedBeaconBlock{Slot: slot, ProposerIndex: uint64(idx), Body: body}
+		block, err := c.signBlindedBlock(bid, unsigned, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		revealed, err := c.builder.SubmitBlindedBlock(ctx, block)
+		if err != nil {
+			return nil, nil, err
+		}
+		payload, err := revealed.ExecutionPayload()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.log.WithField("hash", payload.BlockHash.Hex()).Info("received payload from builder")
+		if c.SlashingProtection.Violate > 0 && c.RNG.Float64() < c.SlashingProtection.Violate {
+			c.equivocate(ctx, log, bid, unsigned)
+		}
+		return payload, nil, nil
+	}
+
+	// Otherwise, get payload from EL.
+	return c.getLocalProposal(payloadId, slot)
+}
+
+// signBlindedBlock returns the block with the header of the bid, of the version of the bid,
+// signed by its proposer, if its slashing protection allows it or the block violates it on
+// purpose. Blocks of every version are signed with the bellatrix domain, as the relay mock
+// verifies them.
+func (c *ConsensusCmd) signBlindedBlock(bid *types.VersionedSignedBuilderBid, block *types.BlindedBeaconBlock, violate bool) (*types.VersionedSignedBlindedBeaconBlock, error) {
+	signed := &types.VersionedSignedBlindedBeaconBlock{Version: bid.Version}
+	switch bid.Version {
+	case types.VersionBellatrix:
+		block.Body.ExecutionPayloadHeader = bid.Bellatrix.Message.Header
+		signed.Bellatrix = &types.SignedBlindedBeaconBlock{Message: block}
+	case types.VersionCapella:
+		body := block.Body
+		signed.Capella = &types.SignedBlindedBeaconBlockCapella{Message: &types.BlindedBeaconBlockCapella{
+			Slot:          block.Slot,
+			ProposerIndex: block.ProposerIndex,
+			Body: &types.BlindedBeaconBlockBodyCapella{
+				RandaoReveal:           body.RandaoReveal,
+				Eth1Data:               body.Eth1Data,
+				ProposerSlashings:      body.ProposerSlashings,
+				AttesterSlashings:      body.AttesterSlashings,
+				Attestations:           body.Attestations,
+				Deposits:               body.Deposits,
+				SyncAggregate:          body.SyncAggregate,
+				ExecutionPayloadHeader: bid.Capella.Message.Header,
+			},
+		}}
+	default:
+		return nil, fmt.Errorf("%w: %q", types.ErrUnknownVersion, bid.Version)
+	}
+	msg, err := signed.Message()
+	if err != nil {
+		return nil, err
+	}
+	domain := types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &c.genesisValidatorsRoot)
+	root, err := types.ComputeSigningRoot(msg, domain)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.protectBlock(block.ProposerIndex, block.Slot, root, violate); err != nil {
+		return nil, err
+	}
+	// the remote signer signs blocks by their header
+	header := &signedBlockHeader{Version: strings.ToUpper(bid.Version)}
+	header.BlockHeader.Slot = strconv.FormatUint(block.Slot, 10)
+	header.BlockHeader.ProposerIndex = strconv.FormatUint(block.ProposerIndex, 10)
+	header.BlockHeader.ParentRoot = block.ParentRoot
+	header.BlockHeader.StateRoot = block.StateRoot
+	if signed.Bellatrix != nil {
+		header.BlockHeader.BodyRoot, err = block.Body.HashTreeRoot()
+	} else {
+		header.BlockHeader.BodyRoot, err = signed.Capella.Message.Body.HashTreeRoot()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sig, err := c.signAs(int(block.ProposerIndex), root, &signRequest{Type: "BLOCK_V2", ForkInfo: c.bellatrixForkInfo(), BeaconBlock: header})
+	if err != nil {
+		return nil, err
+	}
+	if signed.Bellatrix != nil {
+		signed.Bellatrix.Signature = sig
+	} else {
+		signed.Capella.Signature = sig
+	}
+	return signed, nil
+}
+
+// getLocalProposal gets the payload the engine built for the slot.
+func (c *ConsensusCmd) getLocalProposal(payloadId types.PayloadID, slot uint64) (*types.ExecutionPayloadV3, types.ExecutionRequests, error) {
+	payload, requests, _, err := c.getLocalProposalWithValue(payloadId, slot)
+	return payload, requests, err
+}
+
+// getLocalProposalWithValue is getLocalProposal, also returning the block value the engine
+// reports for the payload, nil before Shanghai.
+func (c *ConsensusCmd) getLocalProposalWithValue(payloadId types.PayloadID, slot uint64) (*types.ExecutionPayloadV3, types.ExecutionRequests, *big.Int, error) {
+	start := time.Now()
+	payload, requests, value, err := c.engine.GetPayloadWithValue(c.correlate(c.ctx, c.SlotTimestamp(slot)), c.engineFork(c.SlotTimestamp(slot)), payloadId)
+	fields := logrus.Fields{"slot": new(big.Int).SetUint64(slot), "corr": correlationID(slot), "error": errorText(err), "latency": time.Since(start)}
+	if payload != nil {
+		fields["number"] = new(big.Int).SetUint64(payload.Number)
+		fields["txs"] = big.NewInt(int64(len(payload.Transactions)))
+		fields["gasUsed"] = new(big.Int).SetUint64(payload.GasUsed)
+	}
+	if value != nil {
+		fields["value"] = value
+	}
+	c.assert(eventGetPayload, fields)
+	return payload, requests, value, err
+}
+
+func (c *ConsensusCmd) mockProposal(log logrus.Ext1FieldLogger, proposal pendingProposal, slot uint64, consensusFail bool) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	payload, requests, err := c.getMockProposal(ctx, log, proposal.id, slot)
+	if err != nil {
+		log.WithError(err).Error("Unable to retrieve proposal payload")
+		if c.BuilderAddr != "" {
+			c.report.record(scenarioProposal, slot, err)
+			c.maybeExit()
+		} else {
+			c.exitUnlessOffline(scenarioProposal, slot, err)
+		}
+		return
+	}
+	c.artifacts.remember(slot, payload)
+	if err := c.ValidateTimestamp(uint64(payload.Timestamp), slot); err != nil {
+		log.WithError(err).Error("Payload has bad timestamp")
+		c.report.record(scenarioProposal, slot, err)
+		c.maybeExit()
+		return
+	}
+	c.report.record(scenarioProposal, slot, nil)
+	c.checkTxFaults(log, slot, payload)
+	if consensusFail {
+		log.Debug("Mocking a failed proposal on consensus-side, ignoring produced payload of engine")
+		return
+	}
+	var beaconRoot *common.Hash
+	if c.mockChain.forks.IsCancun(payload.Timestamp) {
+		beaconRoot = &proposal.attributes.ParentBeaconBlockRoot
+	}
+	if beaconRoot != nil && c.RNG.Float64() < c.Freq.WrongBeaconRoot {
+		c.mockWrongBeaconRoot(ctx, log, slot, payload, *beaconRoot, requests)
+	}
+	if beaconRoot != nil && c.RNG.Float64() < c.Freq.WrongVersionedHashes {
+		c.mockWrongVersionedHashes(ctx, log, slot, payload, beaconRoot, requests)
+	}
+	block, err := c.processPayload(payload, beaconRoot)
+	c.report.record(scenarioPayloadProcessing, slot, err)
+	if err != nil {
+		log.WithError(err).Error("Failed to process execution payload from engine")
+		logReceiptsMismatch(log, err)
+		c.diffs.report(log, scenarioPayloadProcessing, slot, mismatchDiffs(err))
+		c.maybeExit()
+		return
+	} else {
+		log.WithField("blockhash", block.Hash()).Debug("Processed payload in consensus mock world")
+	}
+	c.processWithdrawals(log, payload.Withdrawals)
+	c.exportPayload(payload, beaconRoot)
+	if c.mockChain.forks.IsPrague(payload.Timestamp) {
+		err := c.validateRequests(block.Hash(), requests)
+		c.report.record(scenarioExecutionRequests, slot, err)
+		if err != nil {
+			log.WithError(err).Error("Engine returned invalid execution requests")
+			c.maybeExit()
+			return
+		}
+	}
+
+	// Check the engine of the proposer agrees with the one that built it, if split
+	c.proposerValidate(ctx, log, slot, payload, beaconRoot, requests)
+
+	// Send it back to execution layer for execution
+	res, err := c.submitPayload(ctx, log, payload, beaconRoot, requests)
+	if err == nil && res.Status == types.ExecutionValid {
+		log.WithField("blockhash", block.Hash()).Debug("Processed payload in engine")
+		c.report.record(scenarioNewPayload, slot, nil)
+		c.reportStateless(log, payload, beaconRoot, requests)
+		c.reportFeeRecipient(log, slot, block)
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to execute payload")
+	} else if res.Status == types.ExecutionInvalid {
+		log.WithField("blockhash", block.Hash()).Error("Engine just produced payload and failed to execute it after!")
+		err = fmt.Errorf("engine produced payload %s and failed to execute it", block.Hash())
+	} else {
+		log.WithField("status", res.Status).Error("Unrecognized execution status")
+		err = fmt.Errorf("unrecognized execution status %s", res.Status)
+	}
+	c.exitUnlessOffline(scenarioNewPayload, slot, err)
+}
+
+// mockWrongBeaconRoot sends the payload with a different parent beacon block root than it was built with,
+// which changes the beacon roots contract storage, and must not be accepted by the engine.
+func (c *ConsensusCmd) mockWrongBeaconRoot(ctx context.Context, log logrus.Ext1FieldLogger, slot uint64, payload *types.ExecutionPayloadV3, beaconRoot common.Hash, requests types.ExecutionRequests) {
+	wrong := beaconRoot
+	wrong[0] ^= 0xff
+	log = log.WithField("beacon_root", beaconRoot).WithField("wrong_beacon_root", wrong)
+	log.Info("Sending payload with wrong parent beacon block root")
+	res, err := c.newPayload(withFault(ctx), log, payload, &wrong, requests)
+	if err != nil {
+		log.WithError(err).Info("Engine rejected payload with wrong parent beacon block root")
+		c.report.record(scenarioWrongBeaconRoot, slot, nil)
+		return
+	}
+	if res.Status == types.ExecutionValid {
+		log.Error("Engine accepted payload with wrong parent beacon block root")
+		c.report.record(scenarioWrongBeaconRoot, slot, fmt.Errorf("engine accepted wrong parent beacon block root %s", wrong))
+		c.maybeExit()
+		return
+	}
+	log.WithField("status", res.Status).Info("Engine rejected payload with wrong parent beacon block root")
+	c.report.record(scenarioWrongBeaconRoot, slot, nil)
+}
+
+// mockWrongBaseFee sends the block with a base fee off by one wei from the EIP-1559 base fee,
+// and the block hash of the changed header, which the engine must consider invalid.
+func (c *ConsensusCmd) mockWrongBaseFee(log logrus.Ext1FieldLogger, slot uint64, block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash) {
+	if block.BaseFee() == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	header := ethTypes.CopyHeader(block.Header())
+	header.BaseFee = new(big.Int).Add(header.BaseFee, common.Big1)
+	wrong := ethTypes.NewBlockWithHeader(header).WithBody(block.Transactions(), nil)
+	payload, err := api.BlockToPayloadV3(wrong, withdrawals)
+	if err != nil {
+		log.WithError(err).Error("Failed to convert block with wrong base fee to execution payload")
+		return
+	}
+	log = log.WithField("base_fee", block.BaseFee()).WithField("wrong_base_fee", header.BaseFee)
+	log.Info("Sending payload with wrong base fee")
+	requests, _ := c.mockChain.ExecutionRequests(block.Hash())
+	res, err := c.newPayload(withFault(ctx), log, payload, beaconRoot, requests)
+	if err != nil {
+		log.WithError(err).Info("Engine rejected payload with wrong base fee")
+		c.report.record(scenarioWrongBaseFee, slot, nil)
+		return
+	}
+	switch res.Status {
+	case types.ExecutionInvalid, types.ExecutionInvalidBlockHash:
+		log.WithField("status", res.Status).Info("Engine rejected payload with wrong base fee")
+		c.report.record(scenarioWrongBaseFee, slot, nil)
+	case types.ExecutionValid:
+		log.Error("Engine accepted payload with wrong base fee")
+		c.report.record(scenarioWrongBaseFee, slot, fmt.Errorf("engine accepted wrong base fee %s", header.BaseFee))
+		c.maybeExit()
+	default:
+		log.WithField("status", res.Status).Warn("Unexpected status for payload with wrong base fee")
+	}
+}
+
+// mockWrongVersionedHashes sends the payload with missing, extra or reordered versioned hashes,
+// compared to the blob transactions in the payload, which the engine must consider invalid.
+func (c *ConsensusCmd) mockWrongVersionedHashes(ctx context.Context, log logrus.Ext1FieldLogger, slot uint64, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) {
+	hashes, err := types.BlobVersionedHashes(payload.Transactions)
+	if err != nil {
+		log.WithError(err).Error("Cannot get versioned hashes of payload")
+		return
+	}
+	faults := []string{"extra"}
+	if len(hashes) > 0 {
+		faults = append(faults, "missing")
+	}
+	if len(hashes) > 1 && hashes[0] != hashes[len(hashes)-1] {
+		faults = append(faults, "reordered")
+	}
+	fault := faults[c.RNG.Intn(len(faults))]
+	wrong := append([]common.Hash{}, hashes...)
+	switch fault {
+	case "extra":
+		var extra common.Hash
+		c.RNG.Read(extra[:])
+		extra[0] = 0x01 // KZG version
+		wrong = append(wrong, extra)
+	case "missing":
+		wrong = wrong[:len(wrong)-1]
+	case "reordered":
+		wrong[0], wrong[len(wrong)-1] = wrong[len(wrong)-1], wrong[0]
+	}
+	log = log.WithField("fault", fault).WithField("versioned_hashes", len(hashes))
+	log.Info("Sending payload with wrong versioned hashes")
+	res, err := c.sendNewPayload(withFault(ctx), c.engine, payload, wrong, beaconRoot, requests)
+	if err != nil {
+		log.WithError(err).Warn("Engine errored on payload with wrong versioned hashes, expected an invalid status")
+		return
+	}
+	switch res.Status {
+	case types.ExecutionInvalid:
+		log.Info("Engine rejected payload with wrong versioned hashes")
+		c.report.record(scenarioWrongVersionedHashes, slot, nil)
+	case types.ExecutionValid:
+		log.Error("Engine accepted payload with wrong versioned hashes")
+		c.report.record(scenarioWrongVersionedHashes, slot, fmt.Errorf("engine accepted %s versioned hashes", fault))
+		c.maybeExit()
+	default:
+		log.WithField("status", res.Status).Warn("Unexpected status for payload with wrong versioned hashes")
+	}
+}
+
+func (c *ConsensusCmd) mockExecution(log logrus.Ext1FieldLogger, block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	// derive the random 32 bytes from the block hash for mocking ease
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+
+	if err != nil {
+		log.WithError(err).Error("Failed to convert execution block to execution payload")
+		return
+	}
+
+	requests, _ := c.mockChain.ExecutionRequests(block.Hash())
+	res, err := c.submitPayload(ctx, log, payload, beaconRoot, requests)
+	if err == nil && res.Status == types.ExecutionValid {
+		c.reportStateless(log, payload, beaconRoot, requests)
+	}
+	if err == nil && c.Delivery.Duplicates > 0 {
+		c.sendDuplicates(ctx, log, payload, beaconRoot, requests, res.Status)
+	}
+	c.proposerFollow(ctx, log, payload, beaconRoot, requests)
+}
+
+// validateRequests checks the execution requests of a payload are well-formed, and that they match
+// the deposits and the withdrawal and consolidation request transactions of the block, as processed
+// by the consensus mock.
+func (c *ConsensusCmd) validateRequests(hash common.Hash, requests types.ExecutionRequests) error {
+	if requests == nil {
+		return fmt.Errorf("missing execution requests")
+	}
+	if err := requests.Validate(); err != nil {
+		return err
+	}
+	derived, _ := c.mockChain.ExecutionRequests(hash)
+	if !bytes.Equal(derived.Type(types.DepositRequestType), requests.Type(types.DepositRequestType)) {
+		return fmt.Errorf("deposit requests do not match the deposits of the block")
+	}
+	if !bytes.Equal(derived.Type(types.WithdrawalRequestType), requests.Type(types.WithdrawalRequestType)) {
+		return fmt.Errorf("withdrawal requests do not match the withdrawal request transactions of the block")
+	}
+	if !bytes.Equal(derived.Type(types.ConsolidationRequestType), requests.Type(types.ConsolidationRequestType)) {
+		return fmt.Errorf("consolidation requests do not match the consolidation request transactions of the block")
+	}
+	return nil
+}
+
+func dummyTxCreator(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+	// TODO create some more txs and use all accounts
+	if len(accounts) != 0 {
+		signer := ethTypes.NewLondonSigner(config.ChainID)
+		txdata := &ethTypes.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     statedb.GetNonce(accounts[0].addr),
+			To:        &accounts[0].addr,
+			Gas:       30000,
+			GasFeeCap: new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei)),
+			GasTipCap: big.NewInt(2),
+			Data:      []byte{},
+		}
+		tx := ethTypes.NewTx(txdata)
+		tx, _ = ethTypes.SignTx(tx, signer, accounts[0].pk)
+		return []*ethTypes.Transaction{tx}
+	} else {
+		return nil
+	}
+}
+
+// txCreatorFn returns the function creating transactions for mocked blocks, based on the tx profile,
+// after the deployment of the fee recipient contract.
+func (c *ConsensusCmd) txCreatorFn() func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	if code, _ := c.FeeRecipient.code(); code != nil {
+		return c.recipient.txCreator(code, c.profileTxCreator())
+	}
+	return c.profileTxCreator()
+}
+
+// profileTxCreator returns the function creating the transactions of the tx profile.
+func (c *ConsensusCmd) profileTxCreator() func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	switch c.TxProfile {
+	case "deposit":
+		return depositTxCreator(common.HexToAddress(c.DepositContract))
+	case "withdrawal-request":
+		return withdrawalRequestTxCreator(c.validatorPubkey)
+	case "consolidation-request":
+		return consolidationRequestTxCreator(c.validatorPubkey)
+	case "contracts":
+		return c.deployments.txCreator()
+	case "access-list":
+		return accessListTxCreator
+	case "bundles":
+		return c.pool.txCreator()
+	case "state-growth":
+		return c.churner.txCreator(c.StateGrowth.Slots)
+	default:
+		return dummyTxCreator
+	}
+}
+
+// validatorPubkey returns the key of a random emulated validator, or a fresh key if there are none.
+func (c *ConsensusCmd) validatorPubkey() (types.PublicKey, error) {
+	var pk types.PublicKey
+	if len(c.validators) > 0 {
+		return c.validators[c.RNG.Intn(len(c.validators))].pk, nil
+	}
+	sk, err := blst.RandKey()
+	if err != nil {
+		return pk, err
+	}
+	pk.FromSlice(sk.PublicKey().Marshal())
+	return pk, nil
+}
+
+func (c *ConsensusCmd) calcReorgTarget(chain *core.BlockChain, parent uint64, min uint64) *ethTypes.Header {
+	depth := c.RNG.Float64() * float64(c.ReorgMaxDepth)
+	target := uint64(math.Max(float64(parent)-depth, float64(min)))
+	return chain.GetHeaderByNumber(target)
+}
+
+func (c *ConsensusCmd) Close() error {
+	c.writeReport()
+	if c.close != nil {
+		c.close <- struct{}{}
+	}
+	return nil
+}
+
+func (c *ConsensusCmd) makePayloadAttributes(slot uint64) *types.PayloadAttributesV3 {
+	timestamp := c.SlotTimestamp(slot)
+	attributes := &types.PayloadAttributesV3{
+		Timestamp:             timestamp,
+		PrevRandao:            common.Hash(c.state.RandaoMix()),
+		SuggestedFeeRecipient: c.feeRecipient(common.Address{0x13, 0x37}),
+		Withdrawals:           c.makeWithdrawals(timestamp),
+	}
+	if root := c.makeBeaconRoot(timestamp); root != nil {
+		attributes.ParentBeaconBlockRoot = *root
+	}
+	return attributes
+}
+
+// makeWithdrawals returns the withdrawals of a block at the given time, those of the sweep over
+// the validators from Shanghai onwards, and nil before.
+func (c *ConsensusCmd) makeWithdrawals(timestamp uint64) types.Withdrawals {
+	if !c.mockChain.forks.IsShanghai(timestamp) {
+		return nil
+	}
+	return c.state.ExpectedWithdrawals()
+}
+
+// processWithdrawals moves the sweep on past the withdrawals of a canonical block. Withdrawals
+// the sweep already moved on from, like those of a payload built for a slot that was missed,
+// are skipped.
+func (c *ConsensusCmd) processWithdrawals(log logrus.Ext1FieldLogger, withdrawals types.Withdrawals) {
+	if withdrawals == nil {
+		return
+	}
+	if err := c.state.ProcessWithdrawals(withdrawals); err != nil {
+		log.WithError(err).Warn("Withdrawals do not follow the sweep")
+		return
+	}
+	if len(withdrawals) > 0 {
+		log.WithField("first", withdrawals[0].Index).WithField("count", len(withdrawals)).Debug("Processed withdrawals")
+	}
+}
+
+// makeBeaconRoot returns a random parent beacon block root from Cancun onwards, and nil before.
+func (c *ConsensusCmd) makeBeaconRoot(timestamp uint64) *common.Hash {
+	if !c.mockChain.forks.IsCancun(timestamp) {
+		return nil
+	}
+	var root common.Hash
+	c.RNG.Read(root[:])
+	return &root
+}
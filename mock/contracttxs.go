@@ -0,0 +1,119 @@
+package mock
+
+import (
+	"math/big"
+	"mergemock/contracts"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	contractDeployGas = 1_000_000
+	contractCallGas   = 500_000
+
+	// maxChurnSlots bounds the storage slots a churn call writes, within the call gas.
+	maxChurnSlots = 16
+)
+
+// contractDeployments are the token, churner and factory contracts of the contracts tx profile,
+// in the order of deployment, at the addresses of their last deployment.
+type contractDeployments struct {
+	mu        sync.Mutex
+	addresses [3]common.Address
+}
+
+// txCreator deploys the token, churner and factory contracts from the first test account,
+// one per block, and then interacts with them, rotating through calls that log, revert or make
+// internal calls. Contracts missing from the state of the parent, after a reorg, are deployed again.
+func (d *contractDeployments) txCreator() func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	codes := [3][]byte{contracts.TokenCode, contracts.ChurnerCode, contracts.FactoryCode}
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		if len(accounts) == 0 {
+			return nil
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		sender := accounts[0].addr
+		nonce := statedb.GetNonce(sender)
+		for i, code := range codes {
+			if d.addresses[i] != (common.Address{}) && statedb.GetCodeSize(d.addresses[i]) > 0 {
+				continue
+			}
+			d.addresses[i] = crypto.CreateAddress(sender, nonce)
+			return contractTx(config, statedb, accounts[0], nil, code, contractDeployGas)
+		}
+		token, churner, factory := d.addresses[0], d.addresses[1], d.addresses[2]
+
+		// the recipient of transfers, another test account if there is one
+		recipient := accounts[len(accounts)-1].addr
+		if recipient == sender {
+			recipient = common.Address{0x42}
+		}
+		number := header.Number.Uint64()
+		var (
+			to    common.Address
+			input []byte
+		)
+		switch number % 6 {
+		case 0:
+			to, input = token, contractInput(contracts.TransferSelector, common.BytesToHash(recipient[:]), common.BigToHash(big.NewInt(1)))
+		case 1:
+			// more than the supply, reverts
+			amount := new(big.Int).Add(contracts.TokenSupply, common.Big1)
+			to, input = token, contractInput(contracts.TransferSelector, common.BytesToHash(recipient[:]), common.BigToHash(amount))
+		case 2:
+			slots := 1 + int64(number/6)%maxChurnSlots
+			to, input = churner, contractInput(contracts.ChurnSelector, common.BigToHash(big.NewInt(slots)))
+		case 3:
+			// churning no slots reverts
+			to, input = churner, contractInput(contracts.ChurnSelector, common.Hash{})
+		case 4:
+			to, input = factory, contractInput(contracts.DeploySelector, common.BigToHash(header.Number))
+		case 5:
+			// the salt of the previous block, taken unless that block was reorged out, reverts
+			salt := new(big.Int).Sub(header.Number, common.Big1)
+			to, input = factory, contractInput(contracts.DeploySelector, common.BigToHash(salt))
+		}
+		return contractTx(config, statedb, accounts[0], &to, input, contractCallGas)
+	}
+}
+
+func contractInput(selector []byte, args ...common.Hash) []byte {
+	input := append([]byte{}, selector...)
+	for _, arg := range args {
+		input = append(input, arg[:]...)
+	}
+	return input
+}
+
+func contractTx(config *params.ChainConfig, statedb *state.StateDB, account TestAccount, to *common.Address, input []byte, gas uint64) []*ethTypes.Transaction {
+	feeCap := new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei))
+	cost := new(big.Int).Mul(feeCap, new(big.Int).SetUint64(gas))
+	if statedb.GetBalance(account.addr).Cmp(cost) < 0 {
+		// out of funds, the transaction would make the block invalid
+		return nil
+	}
+	signer := ethTypes.NewLondonSigner(config.ChainID)
+	txdata := &ethTypes.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     statedb.GetNonce(account.addr),
+		To:        to,
+		Gas:       gas,
+		GasFeeCap: feeCap,
+		GasTipCap: big.NewInt(2),
+		Data:      input,
+	}
+	tx, err := ethTypes.SignTx(ethTypes.NewTx(txdata), signer, account.pk)
+	if err != nil {
+		return nil
+	}
+	return []*ethTypes.Transaction{tx}
+}
@@ -0,0 +1,80 @@
+package mock
+
+import (
+	"math/big"
+	"testing"
+
+	"mergemock/contracts"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractTxs(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	engine := newTestEngine(t, newFundedGenesis(t, sender))
+	chain := engine.mockChain()
+
+	var deployments contractDeployments
+	creator := TransactionsCreator{[]TestAccount{{key, sender}}, deployments.txCreator()}
+	var receipts []*ethTypes.Receipt
+	for i := 0; i < 15; i++ {
+		parent := chain.CurrentHeader()
+		block, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		blockReceipts := chain.chain.GetReceiptsByHash(block.Hash())
+		require.Len(t, blockReceipts, 1)
+		receipts = append(receipts, blockReceipts...)
+	}
+	recipient := common.Address{0x42}
+	token, churner, factory := deployments.addresses[0], deployments.addresses[1], deployments.addresses[2]
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+
+	// deployments in the first three blocks
+	for i, addr := range deployments.addresses {
+		require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipts[i].Status, i)
+		require.Equal(t, addr, receipts[i].ContractAddress)
+		require.NotZero(t, statedb.GetCodeSize(addr))
+	}
+
+	// then a call per block, by block number
+	for number := uint64(4); number <= 15; number++ {
+		receipt := receipts[number-1]
+		switch number % 6 {
+		case 0:
+			require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipt.Status, number)
+			require.Len(t, receipt.Logs, 1)
+			require.Equal(t, token, receipt.Logs[0].Address)
+			require.Equal(t, []common.Hash{contracts.TransferEventTopic, common.BytesToHash(sender[:]), common.BytesToHash(recipient[:])}, receipt.Logs[0].Topics)
+		case 2:
+			require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipt.Status, number)
+			require.Len(t, receipt.Logs, 1)
+			require.Equal(t, churner, receipt.Logs[0].Address)
+			require.Equal(t, contracts.ChurnedEventTopic, receipt.Logs[0].Topics[0])
+		case 4:
+			require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipt.Status, number)
+			salt := common.BigToHash(new(big.Int).SetUint64(number))
+			child := crypto.CreateAddress2(factory, salt, crypto.Keccak256(common.FromHex("0x6e602a60005260206000a060206000f3600052600f6011f3")))
+			// the log of the child, on the internal call, and the one of the factory
+			require.Len(t, receipt.Logs, 2)
+			require.Equal(t, child, receipt.Logs[0].Address)
+			require.Equal(t, factory, receipt.Logs[1].Address)
+			require.Equal(t, []common.Hash{contracts.DeployedEventTopic, common.BytesToHash(child[:])}, receipt.Logs[1].Topics)
+		default:
+			require.Equal(t, ethTypes.ReceiptStatusFailed, receipt.Status, number)
+			require.Empty(t, receipt.Logs)
+		}
+	}
+
+	// balances after the two transfers, and slots written by the two churn calls, of two and three slots
+	remaining := new(big.Int).Sub(contracts.TokenSupply, big.NewInt(2))
+	require.Equal(t, common.BigToHash(remaining), statedb.GetState(token, common.BytesToHash(sender[:])))
+	require.Equal(t, common.BigToHash(big.NewInt(2)), statedb.GetState(token, common.BytesToHash(recipient[:])))
+	require.Equal(t, common.BigToHash(big.NewInt(5)), statedb.GetState(churner, common.Hash{}))
+	require.Equal(t, common.BigToHash(big.NewInt(8)), statedb.GetState(churner, common.BigToHash(big.NewInt(2))))
+	require.Equal(t, common.BigToHash(big.NewInt(14)), statedb.GetState(churner, common.BigToHash(big.NewInt(3))))
+}
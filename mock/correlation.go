@@ -0,0 +1,44 @@
+package mock
+
+import (
+	"context"
+	"mergemock/engineclient"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// correlationID identifies the logs and events of the proposal of a slot, across the goroutines
+// of the consensus loop, the engine client and the relay, which all know the slot.
+func correlationID(slot uint64) string {
+	return "slot-" + strconv.FormatUint(slot, 10)
+}
+
+// slotLog returns the logger of the slot, with its epoch and correlation ID.
+func (c *ConsensusCmd) slotLog(slot uint64) logrus.Ext1FieldLogger {
+	log := c.log.WithField("slot", slot).WithField("corr", correlationID(slot))
+	if c.SlotsPerEpoch > 0 {
+		log = log.WithField("epoch", slot/c.SlotsPerEpoch)
+	}
+	return log
+}
+
+// timestampSlot returns the slot of a block with the timestamp, 0 before genesis.
+func (c *ConsensusCmd) timestampSlot(timestamp uint64) uint64 {
+	if c.state == nil {
+		return 0
+	}
+	slot := c.state.SlotAt(time.Unix(int64(timestamp), 0))
+	if slot < 0 {
+		return 0
+	}
+	return uint64(slot)
+}
+
+// correlate returns the context of the engine calls for the block with the timestamp, which the
+// engine client logs with the slot of the block and its correlation ID.
+func (c *ConsensusCmd) correlate(ctx context.Context, timestamp uint64) context.Context {
+	slot := c.timestampSlot(timestamp)
+	return engineclient.WithLogFields(ctx, logrus.Fields{"slot": slot, "corr": correlationID(slot)})
+}
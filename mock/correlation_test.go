@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelation(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	log, hook := test.NewNullLogger()
+	c.log = log
+
+	c.slotLog(9).Info("Slot trigger")
+	entry := hook.LastEntry()
+	require.Equal(t, logrus.Fields{"slot": uint64(9), "epoch": uint64(2), "corr": "slot-9"}, entry.Data)
+
+	// blocks belong to the slot of their timestamp, and those before genesis to slot 0
+	require.Equal(t, uint64(9), c.timestampSlot(c.SlotTimestamp(9)))
+	require.Equal(t, uint64(0), c.timestampSlot(0))
+
+	slots := make(chan SlotEvent, 1)
+	c.Slots = slots
+	c.publishSlot(3, c.mockChain.CurrentHeader().Hash(), time.Now())
+	require.Equal(t, "slot-3", (<-slots).Correlation)
+}
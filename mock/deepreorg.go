@@ -0,0 +1,165 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// DeepReorgConfig configures a reorg of the engine deeper than its in-memory caches of recent
+// states, like the 128 blocks of go-ethereum, to a side chain it did not see before.
+type DeepReorgConfig struct {
+	Depth uint64 `ask:"--depth" help:"Reorg the engine this many blocks deep to a side chain hidden from it, with finality stalled until the canonical chain is that long past the fork (0 to disable)"`
+	Start uint64 `ask:"--start" help:"Slot to fork the hidden side chain at, from the head of the chain"`
+}
+
+// deepReorgState is the progress of the deep reorg: the block the side chain forks at, once the
+// canonical chain started growing past it, and whether the reorg is done.
+type deepReorgState struct {
+	fork *ethTypes.Header
+	done bool
+}
+
+// sideBlock is a block of the hidden side chain, with its withdrawals and parent beacon block root.
+type sideBlock struct {
+	block       *ethTypes.Block
+	withdrawals types.Withdrawals
+	beaconRoot  *common.Hash
+}
+
+// pendingDeepReorg returns the block the side chain of the deep reorg forks at, if the reorg is
+// pending, so random reorgs do not go below it.
+func (c *ConsensusCmd) pendingDeepReorg() *ethTypes.Header {
+	if c.deep.done {
+		return nil
+	}
+	return c.deep.fork
+}
+
+// startDeepReorg forks the side chain of the deep reorg at the head in the start slot, and stalls
+// finality, so the fork stays after the finalized block while the canonical chain grows.
+func (c *ConsensusCmd) startDeepReorg(log logrus.Ext1FieldLogger, slot uint64) {
+	if c.DeepReorg.Depth == 0 || c.deep.fork != nil || slot < c.DeepReorg.Start {
+		return
+	}
+	c.deep.fork = c.mockChain.CurrentHeader()
+	c.state.StallFinality(true)
+	log.WithField("fork", c.deep.fork.Hash()).WithField("number", c.deep.fork.Number).WithField("depth", c.DeepReorg.Depth).
+		Info("Stalling finality for deep reorg")
+}
+
+// deepReorg builds the side chain of the deep reorg once the canonical chain is the depth past the
+// fork, a block at every height of the canonical chain, at its timestamp and with its withdrawals,
+// and a last block in the slot, which makes it the head of the mock chain. The engine gets the
+// side chain, and the forkchoice update to its head. It returns false if the reorg is not due.
+func (c *ConsensusCmd) deepReorg(log logrus.Ext1FieldLogger, slot uint64, prevRandao common.Hash, safe, final common.Hash) (bool, error) {
+	fork := c.pendingDeepReorg()
+	if fork == nil {
+		return false, nil
+	}
+	head := c.mockChain.CurrentHeader()
+	if head.Number.Uint64() < fork.Number.Uint64()+c.DeepReorg.Depth {
+		return false, nil
+	}
+	c.deep.done = true
+	c.state.StallFinality(false)
+
+	var canonical []*ethTypes.Header
+	for n := fork.Number.Uint64() + 1; n <= head.Number.Uint64(); n++ {
+		header := c.mockChain.chain.GetHeaderByNumber(n)
+		if header == nil {
+			return true, fmt.Errorf("no canonical block %d", n)
+		}
+		canonical = append(canonical, header)
+	}
+	creator := TransactionsCreator{c.ConsensusBehavior.TestAccounts.accounts, c.txCreatorFn()}
+	extraData := []byte("deep reorg side chain")
+	var side []sideBlock
+	parent := fork
+	for i := 0; i <= len(canonical); i++ {
+		var timestamp uint64
+		var withdrawals types.Withdrawals
+		var beaconRoot *common.Hash
+		random := prevRandao
+		if i < len(canonical) {
+			timestamp, random = canonical[i].Time, canonical[i].MixDigest
+			withdrawals, beaconRoot = c.mockChain.Extras(canonical[i].Hash())
+		} else {
+			timestamp = c.SlotTimestamp(slot)
+			withdrawals, beaconRoot = c.makeWithdrawals(timestamp), c.makeBeaconRoot(timestamp)
+		}
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{2}, timestamp, parent.GasLimit, creator, random, extraData, nil, withdrawals, beaconRoot, true)
+		if err != nil {
+			return true, fmt.Errorf("side block %d: %w", i, err)
+		}
+		side = append(side, sideBlock{block, withdrawals, beaconRoot})
+		c.exportBlock(block, withdrawals, beaconRoot)
+		parent = block.Header()
+	}
+	c.processWithdrawals(log, side[len(side)-1].withdrawals)
+	log.WithField("fork", fork.Hash()).WithField("depth", len(canonical)).WithField("head", parent.Hash()).
+		Info("Reorging engine to hidden side chain")
+
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		err := c.deliverSideChain(log, fork, side, safe, final)
+		if err != nil {
+			log.WithError(err).Error("Deep reorg failed")
+			c.exitUnlessOffline(scenarioDeepReorg, slot, err)
+			return
+		}
+		log.WithField("depth", len(canonical)).Info("Engine reorged to hidden side chain")
+		c.report.record(scenarioDeepReorg, slot, nil)
+	}()
+	return true, nil
+}
+
+// deliverSideChain sends the blocks of the side chain to the engine, updates the forkchoice to its
+// head, and checks the engine rolled its canonical chain back to the side chain.
+func (c *ConsensusCmd) deliverSideChain(log logrus.Ext1FieldLogger, fork *ethTypes.Header, side []sideBlock, safe, final common.Hash) error {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Minute)
+	defer cancel()
+	for _, b := range side {
+		payload, err := api.BlockToPayloadV3(b.block, b.withdrawals)
+		if err != nil {
+			return err
+		}
+		requests, _ := c.mockChain.ExecutionRequests(b.block.Hash())
+		res, err := c.newPayload(ctx, log, payload, b.beaconRoot, requests)
+		if err != nil {
+			return fmt.Errorf("new payload %s: %w", payload.BlockHash, err)
+		}
+		if res.Status == types.ExecutionInvalid {
+			return fmt.Errorf("engine considers side chain payload %s invalid: %s", payload.BlockHash, res.ValidationError)
+		}
+	}
+	head := side[len(side)-1].block
+	if _, err := c.sendForkchoiceUpdated(head.Hash(), safe, final, nil); err != nil {
+		return fmt.Errorf("forkchoice update to head of side chain: %w", err)
+	}
+	latest, err := c.engineBlockHash(ctx, "latest")
+	if err != nil {
+		return err
+	}
+	if latest != head.Hash() {
+		return fmt.Errorf("latest block of engine is %s, expected head of side chain %s", latest, head.Hash())
+	}
+	first := side[0].block
+	rolled, err := c.engineBlockHash(ctx, hexutil.EncodeBig(first.Number()))
+	if err != nil {
+		return err
+	}
+	if rolled != first.Hash() {
+		return fmt.Errorf("block %d of engine is %s, expected first block of side chain %s after fork %s", first.NumberU64(), rolled, first.Hash(), fork.Hash())
+	}
+	return nil
+}
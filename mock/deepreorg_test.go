@@ -0,0 +1,131 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// rollbackEngine is an engine that accepts every call, and answers eth_getBlockByNumber with the
+// blocks of its canonical chain.
+type rollbackEngine struct {
+	fakeEngine
+	canonical func(tag string) common.Hash
+}
+
+func (e *rollbackEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Method != "eth_getBlockByNumber" {
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		e.fakeEngine.ServeHTTP(w, r)
+		return
+	}
+	e.mu.Lock()
+	e.methods = append(e.methods, req.Method)
+	e.mu.Unlock()
+	var tag string
+	json.Unmarshal(req.Params[0], &tag)
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": map[string]common.Hash{"hash": e.canonical(tag)}})
+}
+
+func TestDeepReorg(t *testing.T) {
+	for _, rollback := range []bool{true, false} {
+		c := newCheckpointConsensus(t)
+		c.ctx = context.Background()
+		c.RNG = NewRNG(1)
+		c.report = newScenarioReport()
+		c.DeepReorg = DeepReorgConfig{Depth: 6, Start: 2}
+		// the canonical chain of the engine before the reorg, which it keeps if it does not roll back
+		stale := make(map[string]common.Hash)
+		engine := &rollbackEngine{canonical: func(tag string) common.Hash {
+			head := c.mockChain.CurrentHeader()
+			if !rollback {
+				return stale[tag]
+			}
+			if tag == "latest" {
+				return head.Hash()
+			}
+			number, err := hexutil.DecodeUint64(tag)
+			require.NoError(t, err)
+			return c.mockChain.chain.GetHeaderByNumber(number).Hash()
+		}}
+		srv := httptest.NewServer(engine)
+		c.engine = dialEngine(t, srv.URL)
+
+		log := logrus.New()
+		var reorgSlot uint64
+		for slot := uint64(1); reorgSlot == 0; slot++ {
+			head := c.mockChain.CurrentHeader()
+			c.state.ProcessSlot(slot, head.Hash())
+			c.startDeepReorg(log, slot)
+			reorged, err := c.deepReorg(log, slot, common.Hash{}, common.Hash{}, common.Hash{})
+			require.NoError(t, err)
+			if reorged {
+				reorgSlot = slot
+				break
+			}
+			timestamp := c.SlotTimestamp(slot)
+			block, err := c.mockChain.AddNewBlock(head.Hash(), common.Address{1}, timestamp, head.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, c.makeBeaconRoot(timestamp), true)
+			require.NoError(t, err)
+			stale["latest"] = block.Hash()
+			stale[hexutil.EncodeBig(block.Number())] = block.Hash()
+		}
+		c.inflight.Wait()
+		srv.Close()
+
+		// the side chain forks at the head of the start slot, and finality stalls until the reorg
+		fork := c.deep.fork
+		require.Equal(t, uint64(1), fork.Number.Uint64())
+		require.Equal(t, uint64(8), reorgSlot)
+		require.Equal(t, types.Checkpoint{}, c.state.Finalized())
+		head := c.mockChain.CurrentHeader()
+		require.Equal(t, uint64(8), head.Number.Uint64())
+		require.NotEqual(t, stale[hexutil.EncodeUint64(2)], c.mockChain.chain.GetHeaderByNumber(2).Hash())
+		require.Equal(t, fork.Hash(), c.mockChain.chain.GetHeaderByNumber(2).ParentHash)
+		require.Nil(t, c.pendingDeepReorg())
+
+		// the side chain, the forkchoice update to its head, and the checks of the rollback, which
+		// stop at the latest block if the engine does not roll back
+		methods := engine.methods
+		if rollback {
+			require.Len(t, methods, 7+1+2)
+		} else {
+			require.Len(t, methods, 7+1+1)
+		}
+		for _, method := range methods[:7] {
+			require.Equal(t, "engine_newPayloadV3", method)
+		}
+		require.Equal(t, "engine_forkchoiceUpdatedV3", methods[7])
+		require.Equal(t, head.Hash(), c.forkchoice.get().HeadBlockHash)
+		require.Equal(t, 1, c.report.cases[scenarioDeepReorg].runs)
+		if rollback {
+			require.Empty(t, c.report.cases[scenarioDeepReorg].failures)
+		} else {
+			require.Len(t, c.report.cases[scenarioDeepReorg].failures, 1)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Orders to deliver the blocks held back for a burst in.
+const (
+	deliveryInOrder    = "in-order"
+	deliveryReverse    = "reverse"
+	deliveryInterleave = "interleave"
+)
+
+// DeliveryConfig configures resending and reordering the payloads sent to the engine.
+type DeliveryConfig struct {
+	Duplicates uint64 `ask:"--duplicates" help:"Send every external payload to the engine this many more times after the first, which it must answer as it did the first (0 to disable)"`
+	Order      string `ask:"--order" help:"Order to deliver the blocks held back for a burst in (requires --burst.size): 'in-order', 'reverse' for children before their parents, 'interleave' to alternate them with the blocks of a second branch on the same parent"`
+}
+
+func (d *DeliveryConfig) Default() {
+	d.Order = deliveryInOrder
+}
+
+// check returns an error if the delivery order is not recognized, or needs bursts.
+func (d *DeliveryConfig) check(burst BurstConfig) error {
+	switch d.Order {
+	case deliveryInOrder:
+		return nil
+	case deliveryReverse, deliveryInterleave:
+		if burst.Size == 0 {
+			return fmt.Errorf("delivery order %q requires bursts", d.Order)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unrecognized delivery order: %q", d.Order)
+	}
+}
+
+// outOfOrder returns whether the bursts are delivered out of order.
+func (d *DeliveryConfig) outOfOrder() bool {
+	return d.Order == deliveryReverse || d.Order == deliveryInterleave
+}
+
+// burstScenario returns the scenario to report the bursts as, by their delivery order.
+func (c *ConsensusCmd) burstScenario() string {
+	switch c.Delivery.Order {
+	case deliveryReverse:
+		return scenarioReverseDelivery
+	case deliveryInterleave:
+		return scenarioInterleavedDelivery
+	default:
+		return scenarioBurst
+	}
+}
+
+// statusTransition names the change of the status of a payload, when it is sent again.
+func statusTransition(from, to types.ExecutePayloadStatus) string {
+	return strings.ToLower(string(from)) + "->" + strings.ToLower(string(to))
+}
+
+// sendDuplicates sends the payload the engine answered with the status again, as configured. The
+// engine must answer a duplicate of a valid payload as valid, and not consider a duplicate of a
+// payload it accepted invalid.
+func (c *ConsensusCmd) sendDuplicates(ctx context.Context, log logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests, first types.ExecutePayloadStatus) {
+	slot := c.timestampSlot(payload.Timestamp)
+	var failure error
+	for i := uint64(1); i <= c.Delivery.Duplicates && failure == nil; i++ {
+		res, err := c.newPayload(ctx, log, payload, beaconRoot, requests)
+		if err != nil {
+			if c.engineOffline(err) {
+				return
+			}
+			failure = fmt.Errorf("duplicate %d of payload %s: %w", i, payload.BlockHash, err)
+			break
+		}
+		transition := statusTransition(first, res.Status)
+		log = log.WithField("transitions", c.transitions.add(scenarioDuplicatePayload, transition))
+		switch {
+		case first == types.ExecutionValid && res.Status != types.ExecutionValid:
+			failure = fmt.Errorf("engine returned %s for duplicate %d of valid payload %s", res.Status, i, payload.BlockHash)
+		case first != types.ExecutionInvalid && res.Status == types.ExecutionInvalid:
+			failure = fmt.Errorf("engine considers duplicate %d of payload %s it answered %s invalid: %s", i, payload.BlockHash, first, res.ValidationError)
+		}
+	}
+	c.report.record(scenarioDuplicatePayload, slot, failure)
+	if failure != nil {
+		log.WithError(failure).Error("Engine answered duplicate payload differently")
+		c.maybeExit()
+		return
+	}
+	log.WithField("duplicates", c.Delivery.Duplicates).Debug("Engine answered duplicate payloads")
+}
+
+// deliveryOrder returns the held payloads in the order to deliver them in: as they are, children
+// before their parents, or alternating with the payloads of the second branch.
+func (c *ConsensusCmd) deliveryOrder(payloads, side []bufferedPayload) []bufferedPayload {
+	switch c.Delivery.Order {
+	case deliveryReverse:
+		order := make([]bufferedPayload, 0, len(payloads))
+		for i := len(payloads) - 1; i >= 0; i-- {
+			order = append(order, payloads[i])
+		}
+		return order
+	case deliveryInterleave:
+		order := make([]bufferedPayload, 0, len(payloads)+len(side))
+		for i, p := range payloads {
+			order = append(order, p)
+			if i < len(side) {
+				order = append(order, side[i])
+			}
+		}
+		return order
+	default:
+		return payloads
+	}
+}
+
+// interleavedBranch builds a second branch on the parent of the held payloads, which stays out of
+// the mock chain: a block at the timestamp of every held one, with its randao, withdrawals and
+// beacon root, but another fee recipient.
+func (c *ConsensusCmd) interleavedBranch(payloads []bufferedPayload) ([]bufferedPayload, error) {
+	if c.Delivery.Order != deliveryInterleave || len(payloads) == 0 {
+		return nil, nil
+	}
+	creator := TransactionsCreator{c.ConsensusBehavior.TestAccounts.accounts, dummyTxCreator}
+	parent := payloads[0].payload.ParentHash
+	side := make([]bufferedPayload, 0, len(payloads))
+	for _, p := range payloads {
+		block, err := c.mockChain.AddNewBlock(parent, common.Address{3}, p.payload.Timestamp, p.payload.GasLimit, creator, p.payload.Random, []byte("interleaved branch"), nil, p.payload.Withdrawals, p.beaconRoot, false)
+		if err != nil {
+			return nil, fmt.Errorf("block %d of second branch: %w", len(side), err)
+		}
+		payload, err := api.BlockToPayloadV3(block, p.payload.Withdrawals)
+		if err != nil {
+			return nil, err
+		}
+		requests, _ := c.mockChain.ExecutionRequests(block.Hash())
+		side = append(side, bufferedPayload{payload, p.beaconRoot, requests})
+		parent = block.Hash()
+	}
+	return side, nil
+}
+
+// checkTransitions sends the held payloads, and those of the second branch, again after the
+// forkchoice update to the head, and records how their statuses changed since their delivery out
+// of order. The engine has the parents of all of them by then: it must answer the held payloads
+// as valid, and not consider those of the second branch invalid.
+func (c *ConsensusCmd) checkTransitions(log logrus.Ext1FieldLogger, payloads, side []bufferedPayload, first map[common.Hash]types.ExecutePayloadStatus) error {
+	scenario := c.burstScenario()
+	var counts map[string]int
+	for i, p := range append(append([]bufferedPayload{}, payloads...), side...) {
+		res, err := c.newPayload(c.ctx, log, p.payload, p.beaconRoot, p.requests)
+		if err != nil {
+			return fmt.Errorf("new payload %s again: %w", p.payload.BlockHash, err)
+		}
+		counts = c.transitions.add(scenario, statusTransition(first[p.payload.BlockHash], res.Status))
+		if i < len(payloads) && res.Status != types.ExecutionValid {
+			return fmt.Errorf("engine returned %s for payload %s after the forkchoice update to the head, expected VALID", res.Status, p.payload.BlockHash)
+		}
+		if res.Status == types.ExecutionInvalid {
+			return fmt.Errorf("engine considers payload %s of second branch invalid: %s", p.payload.BlockHash, res.ValidationError)
+		}
+	}
+	log.WithField("order", c.Delivery.Order).WithField("transitions", counts).Info("Recorded status transitions of out of order delivery")
+	return nil
+}
@@ -0,0 +1,89 @@
+package mock
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryOrder(t *testing.T) {
+	require.NoError(t, (&DeliveryConfig{Order: deliveryInOrder}).check(BurstConfig{}))
+	require.Error(t, (&DeliveryConfig{Order: deliveryReverse}).check(BurstConfig{}))
+	require.NoError(t, (&DeliveryConfig{Order: deliveryInterleave}).check(BurstConfig{Size: 4}))
+	require.Error(t, (&DeliveryConfig{Order: "shuffle"}).check(BurstConfig{Size: 4}))
+
+	c := newCheckpointConsensus(t)
+	c.Delivery.Order = deliveryInterleave
+	var payloads []bufferedPayload
+	parent := c.mockChain.CurrentHeader()
+	for slot := uint64(1); slot <= 3; slot++ {
+		beaconRoot := common.Hash{byte(slot)}
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+		require.NoError(t, err)
+		payload, err := api.BlockToPayloadV3(block, types.Withdrawals{})
+		require.NoError(t, err)
+		payloads = append(payloads, bufferedPayload{payload, &beaconRoot, nil})
+		parent = block.Header()
+	}
+
+	// the second branch builds on its own blocks, which stay out of the mock chain
+	side, err := c.interleavedBranch(payloads)
+	require.NoError(t, err)
+	require.Len(t, side, 3)
+	require.Equal(t, payloads[0].payload.ParentHash, side[0].payload.ParentHash)
+	for i := range side {
+		require.Equal(t, payloads[i].payload.Timestamp, side[i].payload.Timestamp)
+		require.NotEqual(t, payloads[i].payload.BlockHash, side[i].payload.BlockHash)
+		if i > 0 {
+			require.Equal(t, side[i-1].payload.BlockHash, side[i].payload.ParentHash)
+		}
+	}
+	require.Equal(t, parent.Hash(), c.mockChain.CurrentHeader().Hash())
+
+	hashes := func(order []bufferedPayload) (out []common.Hash) {
+		for _, p := range order {
+			out = append(out, p.payload.BlockHash)
+		}
+		return out
+	}
+	require.Equal(t, []common.Hash{payloads[0].payload.BlockHash, side[0].payload.BlockHash, payloads[1].payload.BlockHash, side[1].payload.BlockHash, payloads[2].payload.BlockHash, side[2].payload.BlockHash}, hashes(c.deliveryOrder(payloads, side)))
+	c.Delivery.Order = deliveryReverse
+	require.Equal(t, []common.Hash{payloads[2].payload.BlockHash, payloads[1].payload.BlockHash, payloads[0].payload.BlockHash}, hashes(c.deliveryOrder(payloads, nil)))
+}
+
+func TestSendDuplicates(t *testing.T) {
+	engine := &statusEngine{status: types.ExecutionValid}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	c.engine = dialEngine(t, srv.URL)
+	c.Delivery.Duplicates = 2
+
+	parent := c.mockChain.CurrentHeader()
+	beaconRoot := common.Hash{1}
+	block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.SlotTimestamp(1), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+	require.NoError(t, err)
+	payload, err := api.BlockToPayloadV3(block, types.Withdrawals{})
+	require.NoError(t, err)
+
+	c.sendDuplicates(c.ctx, logrus.New(), payload, &beaconRoot, nil, types.ExecutionValid)
+	require.Equal(t, map[string]int{"valid->valid": 2}, c.transitions.counts[scenarioDuplicatePayload])
+	require.Empty(t, c.report.cases[scenarioDuplicatePayload].failures)
+
+	// an accepted payload may become valid, but a valid one must stay valid
+	engine.status = types.ExecutionSyncing
+	c.sendDuplicates(c.ctx, logrus.New(), payload, &beaconRoot, nil, types.ExecutionAccepted)
+	require.Empty(t, c.report.cases[scenarioDuplicatePayload].failures)
+	c.sendDuplicates(c.ctx, logrus.New(), payload, &beaconRoot, nil, types.ExecutionValid)
+	require.Len(t, c.report.cases[scenarioDuplicatePayload].failures, 1)
+	require.Equal(t, map[string]int{"valid->valid": 2, "accepted->syncing": 2, "valid->syncing": 1}, c.transitions.counts[scenarioDuplicatePayload])
+}
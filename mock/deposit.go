@@ -0,0 +1,306 @@
+package mock
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"mergemock/beaconstate"
+	"mergemock/contracts"
+	"mergemock/types"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/prysmaticlabs/prysm/crypto/bls/blst"
+)
+
+const (
+	// MaxEffectiveBalance is the deposit amount of a full validator, in gwei.
+	MaxEffectiveBalance = beaconstate.MaxEffectiveBalance
+
+	depositTxGas = 200_000
+
+	depositContractTreeDepth = 32
+	maxDeposits              = 16
+)
+
+// depositZeroHashes are the roots of deposit subtrees without deposits, by depth.
+var depositZeroHashes = func() (zero [depositContractTreeDepth + 1]types.Root) {
+	for i := 1; i < len(zero); i++ {
+		zero[i] = hashPair(zero[i-1], zero[i-1])
+	}
+	return
+}()
+
+// NewDepositData creates a deposit for a fresh random validator key, signed with the
+// deposit domain, and returns it together with its deposit data root.
+func NewDepositData(amount uint64) (*types.DepositData, [32]byte, error) {
+	sk, err := blst.RandKey()
+	if err != nil {
+		return nil, [32]byte{}, fmt.Errorf("unable to generate bls key pair: %v", err)
+	}
+	var pk types.PublicKey
+	pk.FromSlice(sk.PublicKey().Marshal())
+
+	// BLS withdrawal credentials, withdrawing to the same key
+	creds := types.Hash(sha256.Sum256(pk[:]))
+	creds[0] = 0x00
+
+	msg := &types.DepositMessage{
+		Pubkey:                pk,
+		WithdrawalCredentials: creds,
+		Amount:                amount,
+	}
+	// Deposits are valid regardless of fork, and use the genesis fork version.
+	domain := types.ComputeDomain(types.DomainTypeDeposit, 0, nil)
+	root, err := types.ComputeSigningRoot(msg, domain)
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	deposit := &types.DepositData{
+		Pubkey:                pk,
+		WithdrawalCredentials: creds,
+		Amount:                amount,
+	}
+	deposit.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+	dataRoot, err := deposit.HashTreeRoot()
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	return deposit, dataRoot, nil
+}
+
+// depositTxCreator makes a full validator deposit into the deposit contract, from the first test account.
+func depositTxCreator(contract common.Address) func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		if len(accounts) == 0 {
+			return nil
+		}
+		deposit, root, err := NewDepositData(MaxEffectiveBalance)
+		if err != nil {
+			return nil
+		}
+		input, err := contracts.DepositContractABI.Pack("deposit", deposit.Pubkey[:], deposit.WithdrawalCredentials[:], deposit.Signature[:], root)
+		if err != nil {
+			return nil
+		}
+		value := new(big.Int).Mul(new(big.Int).SetUint64(deposit.Amount), big.NewInt(params.GWei))
+		feeCap := new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei))
+		cost := new(big.Int).Add(value, new(big.Int).Mul(feeCap, big.NewInt(depositTxGas)))
+		if statedb.GetBalance(accounts[0].addr).Cmp(cost) < 0 {
+			// out of funds, the transaction would make the block invalid
+			return nil
+		}
+		signer := ethTypes.NewLondonSigner(config.ChainID)
+		txdata := &ethTypes.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     statedb.GetNonce(accounts[0].addr),
+			To:        &contract,
+			Gas:       depositTxGas,
+			GasFeeCap: feeCap,
+			GasTipCap: big.NewInt(2),
+			Value:     value,
+			Data:      input,
+		}
+		tx, err := ethTypes.SignTx(ethTypes.NewTx(txdata), signer, accounts[0].pk)
+		if err != nil {
+			return nil
+		}
+		return []*ethTypes.Transaction{tx}
+	}
+}
+
+// depositRequests returns the EIP-6110 deposit requests, concatenated, of the deposit
+// events the deposit contract logged.
+func depositRequests(logs []*ethTypes.Log, contract common.Address) ([]byte, error) {
+	var out []byte
+	for _, l := range logs {
+		if l.Address != contract || len(l.Topics) == 0 || l.Topics[0] != contracts.DepositEventTopic {
+			continue
+		}
+		fields, err := contracts.DepositContractABI.Unpack("DepositEvent", l.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deposit event: %v", err)
+		}
+		// pubkey, withdrawal credentials, amount, signature and index, amount and index are already little-endian
+		sizes := []int{48, 32, 8, 96, 8}
+		for i, field := range fields {
+			b := field.([]byte)
+			if len(b) != sizes[i] {
+				return nil, fmt.Errorf("invalid deposit event field %d length: %d", i, len(b))
+			}
+			out = append(out, b...)
+		}
+	}
+	return out, nil
+}
+
+// depositEvents returns the deposit data of the deposit events the deposit contract logged.
+func depositEvents(logs []*ethTypes.Log, contract common.Address) ([]*types.DepositData, error) {
+	var out []*types.DepositData
+	for _, l := range logs {
+		if l.Address != contract || len(l.Topics) == 0 || l.Topics[0] != contracts.DepositEventTopic {
+			continue
+		}
+		fields, err := contracts.DepositContractABI.Unpack("DepositEvent", l.Data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deposit event: %v", err)
+		}
+		pubkey, creds, amount, sig := fields[0].([]byte), fields[1].([]byte), fields[2].([]byte), fields[3].([]byte)
+		if len(pubkey) != 48 || len(creds) != 32 || len(amount) != 8 || len(sig) != 96 {
+			return nil, fmt.Errorf("invalid deposit event field lengths")
+		}
+		data := &types.DepositData{Amount: binary.LittleEndian.Uint64(amount)}
+		data.Pubkey.FromSlice(pubkey)
+		copy(data.WithdrawalCredentials[:], creds)
+		data.Signature.FromSlice(sig)
+		out = append(out, data)
+	}
+	return out, nil
+}
+
+func hashPair(a, b types.Root) types.Root {
+	return sha256.Sum256(append(a[:], b[:]...))
+}
+
+// depositTree is the deposit tree of the deposit contract, as of some block.
+type depositTree struct {
+	deposits []*types.DepositData
+	leaves   []types.Root
+}
+
+// Root returns the deposit root, as get_deposit_root of the deposit contract: the root of the
+// tree mixed in with the deposit count.
+func (t *depositTree) Root() types.Root {
+	layer := t.leaves
+	for depth := 0; depth < depositContractTreeDepth; depth++ {
+		layer = nextDepositLayer(layer, depth)
+	}
+	root := depositZeroHashes[depositContractTreeDepth]
+	if len(layer) > 0 {
+		root = layer[0]
+	}
+	return hashPair(root, t.countChunk())
+}
+
+// Proof returns the proof of the deposit at the index against the deposit root: the siblings
+// up the tree, and the deposit count.
+func (t *depositTree) Proof(index int) []types.Root {
+	proof := make([]types.Root, 0, depositContractTreeDepth+1)
+	layer := t.leaves
+	for depth := 0; depth < depositContractTreeDepth; depth++ {
+		sibling := depositZeroHashes[depth]
+		if index^1 < len(layer) {
+			sibling = layer[index^1]
+		}
+		proof = append(proof, sibling)
+		layer = nextDepositLayer(layer, depth)
+		index /= 2
+	}
+	return append(proof, t.countChunk())
+}
+
+// nextDepositLayer hashes the nodes of a layer of the deposit tree in pairs, with empty
+// subtrees to the right.
+func nextDepositLayer(layer []types.Root, depth int) []types.Root {
+	next := make([]types.Root, (len(layer)+1)/2)
+	for i := range next {
+		right := depositZeroHashes[depth]
+		if 2*i+1 < len(layer) {
+			right = layer[2*i+1]
+		}
+		next[i] = hashPair(layer[2*i], right)
+	}
+	return next
+}
+
+func (t *depositTree) countChunk() (chunk types.Root) {
+	binary.LittleEndian.PutUint64(chunk[:], uint64(len(t.leaves)))
+	return
+}
+
+// depositTracker follows the deposit trees of the blocks of the mock chain, and the deposits
+// already included in blinded blocks.
+type depositTracker struct {
+	mu       sync.Mutex
+	trees    map[common.Hash]*depositTree
+	included uint64
+}
+
+// tree returns the deposit tree as of the block, from the deposit events of it and its ancestors.
+func (d *depositTracker) tree(chain *MockChain, contract common.Address, hash common.Hash) (*depositTree, error) {
+	if d.trees == nil {
+		d.trees = make(map[common.Hash]*depositTree)
+	}
+	// walk back to a block with a known tree, or the genesis, which has no deposits
+	var path []*ethTypes.Header
+	tree, ok := d.trees[hash]
+	for !ok {
+		header := chain.chain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, fmt.Errorf("unknown block %s", hash)
+		}
+		if header.Number.Sign() == 0 {
+			tree = &depositTree{}
+			d.trees[hash] = tree
+			break
+		}
+		path = append(path, header)
+		hash = header.ParentHash
+		tree, ok = d.trees[hash]
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		var logs []*ethTypes.Log
+		for _, receipt := range chain.chain.GetReceiptsByHash(path[i].Hash()) {
+			logs = append(logs, receipt.Logs...)
+		}
+		deposits, err := depositEvents(logs, contract)
+		if err != nil {
+			return nil, err
+		}
+		if len(deposits) > 0 {
+			next := &depositTree{
+				deposits: append(tree.deposits[:len(tree.deposits):len(tree.deposits)], deposits...),
+				leaves:   tree.leaves[:len(tree.leaves):len(tree.leaves)],
+			}
+			for _, deposit := range deposits {
+				root, err := deposit.HashTreeRoot()
+				if err != nil {
+					return nil, err
+				}
+				next.leaves = append(next.leaves, root)
+			}
+			tree = next
+		}
+		d.trees[path[i].Hash()] = tree
+	}
+	return tree, nil
+}
+
+// include returns the eth1 data of a block on the parent, and the next deposits it includes,
+// with proofs against the deposit root of the parent. The mock chain has no follow distance,
+// so the eth1 data is that of the parent block.
+func (d *depositTracker) include(chain *MockChain, contract common.Address, parent common.Hash) (*types.Eth1Data, []*types.Deposit, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	tree, err := d.tree(chain, contract, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	count := uint64(len(tree.leaves))
+	eth1Data := &types.Eth1Data{DepositRoot: tree.Root(), DepositCount: count, BlockHash: types.Hash(parent)}
+	if d.included > count {
+		// reorged to a chain with fewer deposits
+		d.included = count
+	}
+	deposits := []*types.Deposit{}
+	for ; d.included < count && len(deposits) < maxDeposits; d.included++ {
+		deposits = append(deposits, &types.Deposit{Proof: tree.Proof(int(d.included)), Data: tree.deposits[d.included]})
+	}
+	return eth1Data, deposits, nil
+}
@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"mergemock/contracts"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// verifyDepositProof is is_valid_merkle_branch of the spec, for a deposit proof.
+func verifyDepositProof(leaf types.Root, proof []types.Root, index uint64, root types.Root) bool {
+	value := leaf
+	for i, node := range proof {
+		if (index>>i)&1 == 1 {
+			value = sha256.Sum256(append(node[:], value[:]...))
+		} else {
+			value = sha256.Sum256(append(value[:], node[:]...))
+		}
+	}
+	return value == root
+}
+
+func TestDepositInclusion(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	contract := common.HexToAddress("0x4242424242424242424242424242424242424242")
+	engine := newTestEngine(t, newFundedGenesis(t, sender), func(e *EngineCmd) {
+		e.DepositContract = contract.Hex()
+	})
+	chain := engine.mockChain()
+
+	var tracker depositTracker
+	eth1Data, deposits, err := tracker.include(chain, contract, chain.CurrentHeader().Hash())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), eth1Data.DepositCount)
+	require.Empty(t, deposits)
+
+	// blocks with a deposit each, more than a block can include
+	creator := TransactionsCreator{[]TestAccount{{key, sender}}, depositTxCreator(contract)}
+	for i := 0; i < maxDeposits+2; i++ {
+		parent := chain.CurrentHeader()
+		_, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+	}
+	head := chain.CurrentHeader()
+
+	// the deposit root matches the one of the contract
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	evm := vm.NewEVM(core.NewEVMBlockContext(head, chain.chain, nil), vm.TxContext{}, statedb, chain.chain.Config(), vm.Config{})
+	input, err := contracts.DepositContractABI.Pack("get_deposit_root")
+	require.NoError(t, err)
+	out, _, err := evm.StaticCall(vm.AccountRef(sender), contract, input, 1_000_000)
+	require.NoError(t, err)
+
+	eth1Data, deposits, err = tracker.include(chain, contract, head.Hash())
+	require.NoError(t, err)
+	require.Equal(t, types.Root(common.BytesToHash(out)), eth1Data.DepositRoot)
+	require.Equal(t, uint64(maxDeposits+2), eth1Data.DepositCount)
+	require.Equal(t, types.Hash(head.Hash()), eth1Data.BlockHash)
+	require.Len(t, deposits, maxDeposits)
+
+	for i, deposit := range deposits {
+		leaf, err := deposit.Data.HashTreeRoot()
+		require.NoError(t, err)
+		require.Len(t, deposit.Proof, depositContractTreeDepth+1)
+		require.True(t, verifyDepositProof(leaf, deposit.Proof, uint64(i), eth1Data.DepositRoot), i)
+		require.Equal(t, eth1Data.DepositCount, binary.LittleEndian.Uint64(deposit.Proof[depositContractTreeDepth][:]))
+	}
+
+	// the next block includes the remaining deposits
+	_, deposits, err = tracker.include(chain, contract, head.Hash())
+	require.NoError(t, err)
+	require.Len(t, deposits, 2)
+	leaf, err := deposits[1].Data.HashTreeRoot()
+	require.NoError(t, err)
+	require.True(t, verifyDepositProof(leaf, deposits[1].Proof, maxDeposits+1, eth1Data.DepositRoot))
+}
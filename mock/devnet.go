@@ -0,0 +1,243 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// devnetClient is how to run an execution client image against a consensus mock, with the
+// devnet directory mounted at /devnet.
+type devnetClient struct {
+	image string
+	// entrypoint overrides the entrypoint of the image, if not empty
+	entrypoint []string
+	command    func(chainID uint64) []string
+}
+
+var devnetClients = map[string]devnetClient{
+	"geth": {
+		image:      "ethereum/client-go:latest",
+		entrypoint: []string{"/bin/sh", "-c"},
+		command: func(chainID uint64) []string {
+			return []string{"geth init --datadir /data /devnet/genesis.json && exec geth --datadir /data " +
+				fmt.Sprintf("--networkid %d ", chainID) +
+				"--syncmode full --authrpc.addr 0.0.0.0 --authrpc.port 8551 --authrpc.vhosts '*' " +
+				"--authrpc.jwtsecret /devnet/jwt.hex --http --http.addr 0.0.0.0 --http.port 8545"}
+		},
+	},
+	"besu": {
+		image: "hyperledger/besu:latest",
+		command: func(uint64) []string {
+			return []string{"--genesis-file=/devnet/genesis.json", "--data-path=/tmp/data", "--sync-mode=FULL",
+				"--engine-rpc-port=8551", "--engine-jwt-secret=/devnet/jwt.hex", "--engine-host-allowlist=*",
+				"--rpc-http-enabled", "--rpc-http-host=0.0.0.0", "--rpc-http-port=8545", "--host-allowlist=*"}
+		},
+	},
+	"reth": {
+		image: "ghcr.io/paradigmxyz/reth:latest",
+		command: func(uint64) []string {
+			return []string{"node", "--chain=/devnet/genesis.json", "--datadir=/data",
+				"--authrpc.addr=0.0.0.0", "--authrpc.port=8551", "--authrpc.jwtsecret=/devnet/jwt.hex",
+				"--http", "--http.addr=0.0.0.0", "--http.port=8545"}
+		},
+	},
+}
+
+type DevnetCmd struct {
+	Out             string        `ask:"--out" help:"Directory to write the devnet to: docker-compose.yml, genesis.json and jwt.hex"`
+	Clients         []string      `ask:"--clients" help:"Execution clients to run, each against its own consensus mock, as a type or type=image: 'geth', 'besu', 'reth'"`
+	Image           string        `ask:"--image" help:"Docker image of mergemock, as built from the Dockerfile"`
+	GenesisPath     string        `ask:"--genesis" help:"Genesis execution-config file of the devnet, merged at genesis"`
+	JwtSecretPath   string        `ask:"--jwt-secret" help:"JWT secret to share between the clients (empty to generate one)"`
+	DepositContract string        `ask:"--deposit-contract" help:"Address to deploy the deposit contract at in genesis (empty to disable)"`
+	SlotTime        time.Duration `ask:"--slot-time" help:"Slot time of the consensus mocks"`
+
+	LogCmd `ask:".log" help:"Change logger configuration"`
+}
+
+func (c *DevnetCmd) Default() {
+	c.Out = "devnet"
+	c.Clients = []string{"geth"}
+	c.Image = "mergemock:latest"
+	c.GenesisPath = "genesis.json"
+	c.SlotTime = 12 * time.Second
+}
+
+func (c *DevnetCmd) Help() string {
+	return "Write a docker-compose devnet of execution clients, each driven by a consensus mock, with a shared genesis and JWT secret."
+}
+
+func (c *DevnetCmd) Examples() []string {
+	return []string{
+		"mergemock devnet --out=devnet --genesis=genesis.json --clients=geth,reth --slot-time=4s",
+	}
+}
+
+type devnetService struct {
+	Name       string
+	Image      string
+	Entrypoint string
+	Command    string
+	Ports      []string
+	DependsOn  string
+}
+
+var devnetCompose = template.Must(template.New("compose").Parse(`# Written by mergemock devnet, start with: docker compose up
+services:
+{{- range .}}
+  {{.Name}}:
+    image: {{.Image}}
+{{- if .Entrypoint}}
+    entrypoint: {{.Entrypoint}}
+{{- end}}
+    command: {{.Command}}
+    volumes:
+      - ./:/devnet:ro
+{{- if .Ports}}
+    ports:
+{{- range .Ports}}
+      - "{{.}}"
+{{- end}}
+{{- end}}
+{{- if .DependsOn}}
+    depends_on:
+      - {{.DependsOn}}
+{{- end}}
+{{- end}}
+`))
+
+// flow returns the list as a YAML flow sequence, of JSON strings.
+func flow(list []string) string {
+	var out strings.Builder
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(list)
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// services returns the execution clients and their consensus mocks.
+func (c *DevnetCmd) services(chainID uint64) ([]devnetService, error) {
+	if len(c.Clients) == 0 {
+		return nil, fmt.Errorf("no clients")
+	}
+	var services []devnetService
+	for i, entry := range c.Clients {
+		kind, image, _ := strings.Cut(entry, "=")
+		client, ok := devnetClients[kind]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized client: %q", kind)
+		}
+		if image == "" {
+			image = client.image
+		}
+		el := devnetService{
+			Name:    fmt.Sprintf("%s-%d", kind, i+1),
+			Image:   image,
+			Command: flow(client.command(chainID)),
+			Ports:   []string{fmt.Sprintf("%d:8545", 8545+i)},
+		}
+		if len(client.entrypoint) > 0 {
+			el.Entrypoint = flow(client.entrypoint)
+		}
+		args := []string{
+			"consensus",
+			fmt.Sprintf("--engine=http://%s:8551", el.Name),
+			"--genesis=/devnet/genesis.json",
+			"--jwt-secret=/devnet/jwt.hex",
+			fmt.Sprintf("--slot-time=%s", c.SlotTime),
+		}
+		if c.DepositContract != "" {
+			args = append(args, fmt.Sprintf("--deposit-contract=%s", c.DepositContract))
+		}
+		services = append(services, el, devnetService{
+			Name:      fmt.Sprintf("consensus-%d", i+1),
+			Image:     c.Image,
+			Command:   flow(args),
+			DependsOn: el.Name,
+		})
+	}
+	return services, nil
+}
+
+// writeGenesis writes the genesis of the devnet, with the deposit contract if configured, so
+// the execution clients start from the same state as the consensus mocks.
+func (c *DevnetCmd) writeGenesis(path string) (uint64, error) {
+	genesis, err := LoadGenesis(c.GenesisPath, c.DepositContract)
+	if err != nil {
+		return 0, err
+	}
+	if genesis.Config == nil || genesis.Config.ChainID == nil {
+		return 0, fmt.Errorf("genesis has no chain id")
+	}
+	out, err := os.ReadFile(c.GenesisPath)
+	if err != nil {
+		return 0, err
+	}
+	if c.DepositContract != "" {
+		if out, err = json.MarshalIndent(genesis, "", "  "); err != nil {
+			return 0, err
+		}
+	}
+	return genesis.Config.ChainID.Uint64(), os.WriteFile(path, out, 0644)
+}
+
+// writeJwtSecret writes the configured JWT secret, or a new one.
+func (c *DevnetCmd) writeJwtSecret(path string) error {
+	var secret []byte
+	if c.JwtSecretPath != "" {
+		jwt, err := loadJwtSecret(c.JwtSecretPath)
+		if err != nil {
+			return fmt.Errorf("invalid JWT secret: %v", err)
+		}
+		secret = jwt
+	} else {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(common.Bytes2Hex(secret)), 0644)
+}
+
+func (c *DevnetCmd) Run(ctx context.Context, args ...string) error {
+	if c.SlotTime <= 0 {
+		return fmt.Errorf("slot time %s is not positive", c.SlotTime)
+	}
+	log, err := c.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Out, 0755); err != nil {
+		return err
+	}
+	chainID, err := c.writeGenesis(filepath.Join(c.Out, "genesis.json"))
+	if err != nil {
+		return fmt.Errorf("failed to write genesis: %w", err)
+	}
+	if err := c.writeJwtSecret(filepath.Join(c.Out, "jwt.hex")); err != nil {
+		return fmt.Errorf("failed to write JWT secret: %w", err)
+	}
+	services, err := c.services(chainID)
+	if err != nil {
+		return err
+	}
+	var compose bytes.Buffer
+	if err := devnetCompose.Execute(&compose, services); err != nil {
+		return fmt.Errorf("failed to write docker-compose.yml: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(c.Out, "docker-compose.yml"), compose.Bytes(), 0644); err != nil {
+		return err
+	}
+	log.WithField("clients", len(c.Clients)).WithField("out", c.Out).Info("Wrote devnet")
+	return nil
+}
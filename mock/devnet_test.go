@@ -0,0 +1,69 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevnet(t *testing.T) {
+	out := t.TempDir()
+	cmd := &DevnetCmd{}
+	cmd.Default()
+	cmd.LogCmd.Default()
+	cmd.Out = out
+	cmd.GenesisPath = newGenesis(t)
+	cmd.JwtSecretPath = newJwt(t)
+	cmd.Clients = []string{"geth", "reth=ghcr.io/paradigmxyz/reth:v1.0.0"}
+	cmd.DepositContract = "0x4242424242424242424242424242424242424242"
+	cmd.SlotTime = 4 * time.Second
+	require.NoError(t, cmd.Run(context.Background()))
+
+	jwt, err := os.ReadFile(filepath.Join(out, "jwt.hex"))
+	require.NoError(t, err)
+	want, err := os.ReadFile(cmd.JwtSecretPath)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(jwt))
+
+	// the execution clients start from the genesis the consensus mocks derive
+	genesis, err := LoadGenesisConfig(filepath.Join(out, "genesis.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, genesis.Alloc[common.HexToAddress(cmd.DepositContract)].Code)
+
+	compose, err := os.ReadFile(filepath.Join(out, "docker-compose.yml"))
+	require.NoError(t, err)
+	for _, line := range []string{
+		"  geth-1:\n    image: ethereum/client-go:latest\n",
+		"--networkid 1337 ",
+		"  consensus-1:\n    image: mergemock:latest\n",
+		`"--engine=http://geth-1:8551"`,
+		"  reth-2:\n    image: ghcr.io/paradigmxyz/reth:v1.0.0\n",
+		`"--engine=http://reth-2:8551"`,
+		`"--slot-time=4s"`,
+		`"--deposit-contract=0x4242424242424242424242424242424242424242"`,
+		"      - \"8546:8545\"\n",
+		"    depends_on:\n      - reth-2\n",
+	} {
+		require.Contains(t, string(compose), line)
+	}
+	require.Equal(t, 4, strings.Count(string(compose), "./:/devnet:ro"))
+
+	// without a secret, a new one is generated
+	cmd.JwtSecretPath = ""
+	require.NoError(t, cmd.Run(context.Background()))
+	jwt, err = os.ReadFile(filepath.Join(out, "jwt.hex"))
+	require.NoError(t, err)
+	require.Len(t, jwt, 64)
+	require.NotEqual(t, string(want), string(jwt))
+
+	cmd.Clients = []string{"nimbus"}
+	err = cmd.Run(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unrecognized client: "nimbus"`)
+}
@@ -0,0 +1,227 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formats of the differences output.
+const (
+	diffsJSON   = "json"
+	diffsGitHub = "github"
+)
+
+// fieldDiff is a field of two objects with different values, by its path in their JSON encoding,
+// like 'transactions[2]' or 'payloadStatus.status'. A value missing from one side is nil.
+type fieldDiff struct {
+	Path     string      `json:"path"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// diffJSON compares the JSON encodings of the expected and actual objects, and returns the fields
+// that differ, sorted by path.
+func diffJSON(expected, actual interface{}) ([]fieldDiff, error) {
+	e, err := toJSONValue(expected)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toJSONValue(actual)
+	if err != nil {
+		return nil, err
+	}
+	var diffs []fieldDiff
+	walkDiff("", e, a, &diffs)
+	return diffs, nil
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func walkDiff(path string, expected, actual interface{}, diffs *[]fieldDiff) {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		if a, ok := actual.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(e)+len(a))
+			for k := range e {
+				keys = append(keys, k)
+			}
+			for k := range a {
+				if _, ok := e[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				sub := k
+				if path != "" {
+					sub = path + "." + k
+				}
+				walkDiff(sub, e[k], a[k], diffs)
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := actual.([]interface{}); ok {
+			for i := 0; i < len(e) || i < len(a); i++ {
+				var ev, av interface{}
+				if i < len(e) {
+					ev = e[i]
+				}
+				if i < len(a) {
+					av = a[i]
+				}
+				walkDiff(fmt.Sprintf("%s[%d]", path, i), ev, av, diffs)
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		*diffs = append(*diffs, fieldDiff{Path: path, Expected: expected, Actual: actual})
+	}
+}
+
+// mismatchError is the error of an object that does not match the one it was checked against,
+// with the fields that differ.
+type mismatchError struct {
+	msg   string
+	diffs []fieldDiff
+}
+
+func (e *mismatchError) Error() string {
+	if len(e.diffs) == 0 {
+		return e.msg
+	}
+	paths := make([]string, len(e.diffs))
+	for i, diff := range e.diffs {
+		paths[i] = diff.Path
+	}
+	return fmt.Sprintf("%s (differences: %s)", e.msg, strings.Join(paths, ", "))
+}
+
+// newMismatch returns the error with the differences between the expected and actual objects.
+func newMismatch(msg string, expected, actual interface{}) *mismatchError {
+	// the objects of the checks always encode, and without differences the message still holds
+	diffs, _ := diffJSON(expected, actual)
+	return &mismatchError{msg: msg, diffs: diffs}
+}
+
+// mismatchDiffs returns the differences of a mismatch error, nil for any other error.
+func mismatchDiffs(err error) []fieldDiff {
+	var mismatch *mismatchError
+	if !errors.As(err, &mismatch) {
+		return nil
+	}
+	return mismatch.diffs
+}
+
+// logDiffs logs the differences of a check, one per line.
+func logDiffs(log logrus.Ext1FieldLogger, check string, diffs []fieldDiff) {
+	for _, diff := range diffs {
+		log.WithFields(logrus.Fields{
+			"check":    check,
+			"path":     diff.Path,
+			"expected": diff.Expected,
+			"actual":   diff.Actual,
+		}).Error("Field difference")
+	}
+}
+
+// DiffConfig configures the output of the differences found by the checks of a mock, for CI.
+type DiffConfig struct {
+	Path   string `ask:"--path" help:"File to write the field differences of failed checks to, '-' for stdout (empty to disable)"`
+	Format string `ask:"--format" help:"Format of the differences: 'json' for JSON lines, 'github' for GitHub Actions error annotations"`
+}
+
+func (d *DiffConfig) Default() {
+	d.Format = diffsJSON
+}
+
+// diffRecord is a line of the JSON differences output.
+type diffRecord struct {
+	Check string `json:"check"`
+	Slot  uint64 `json:"slot"`
+	fieldDiff
+}
+
+// diffReporter writes the differences of failed checks, as they are found. A nil reporter only
+// logs them.
+type diffReporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+}
+
+// newDiffReporter opens the output of the differences, and returns nil without one.
+func newDiffReporter(cfg DiffConfig) (*diffReporter, error) {
+	if cfg.Path == "" {
+		return nil, nil
+	}
+	if cfg.Format != diffsJSON && cfg.Format != diffsGitHub {
+		return nil, fmt.Errorf("unrecognized diffs format: %q", cfg.Format)
+	}
+	if cfg.Path == "-" {
+		return &diffReporter{w: os.Stdout, format: cfg.Format}, nil
+	}
+	f, err := os.Create(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &diffReporter{w: f, format: cfg.Format}, nil
+}
+
+// report logs the differences of the check of the slot, and writes them to the output.
+func (r *diffReporter) report(log logrus.Ext1FieldLogger, check string, slot uint64, diffs []fieldDiff) {
+	logDiffs(log, check, diffs)
+	if r == nil || len(diffs) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, diff := range diffs {
+		if err := r.write(check, slot, diff); err != nil {
+			log.WithError(err).Warn("Failed to write field difference")
+			return
+		}
+	}
+}
+
+func (r *diffReporter) write(check string, slot uint64, diff fieldDiff) error {
+	if r.format == diffsJSON {
+		data, err := json.Marshal(diffRecord{check, slot, diff})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(r.w, "%s\n", data)
+		return err
+	}
+	expected, _ := json.Marshal(diff.Expected)
+	actual, _ := json.Marshal(diff.Actual)
+	msg := fmt.Sprintf("slot %d: %s: expected %s, actual %s", slot, diff.Path, expected, actual)
+	_, err := fmt.Fprintf(r.w, "::error title=%s::%s\n", annotationProperty.Replace(check), annotationMessage.Replace(msg))
+	return err
+}
+
+// Escapes of the characters with a meaning in GitHub Actions workflow commands.
+var (
+	annotationMessage  = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	annotationProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
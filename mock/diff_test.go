@@ -0,0 +1,98 @@
+package mock
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	hash := common.Hash{1}
+	expected := &types.PayloadStatusV1{Status: types.ExecutionValid, LatestValidHash: &hash}
+	actual := &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: "bad block"}
+	diffs, err := diffJSON(expected, actual)
+	require.NoError(t, err)
+	require.Equal(t, []fieldDiff{
+		{Path: "latestValidHash", Expected: hash.Hex(), Actual: nil},
+		{Path: "status", Expected: "VALID", Actual: "INVALID"},
+		{Path: "validationError", Expected: "", Actual: "bad block"},
+	}, diffs)
+
+	// nested objects and lists, with elements missing from one side
+	diffs, err = diffJSON(
+		map[string]interface{}{"a": map[string]interface{}{"b": []int{1, 2}}, "c": 1},
+		map[string]interface{}{"a": map[string]interface{}{"b": []int{1, 3, 4}}, "c": 1, "d": true},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []fieldDiff{
+		{Path: "a.b[1]", Expected: 2.0, Actual: 3.0},
+		{Path: "a.b[2]", Expected: nil, Actual: 4.0},
+		{Path: "d", Expected: nil, Actual: true},
+	}, diffs)
+
+	diffs, err = diffJSON(expected, expected)
+	require.NoError(t, err)
+	require.Empty(t, diffs)
+}
+
+func TestSubmissionMismatch(t *testing.T) {
+	payload := &types.ExecutionPayloadV3{ParentHash: common.Hash{1}, BlockHash: common.Hash{2}, GasLimit: 30_000_000, GasUsed: 21000}
+	msg := &types.BidTrace{Slot: 1, ParentHash: types.Hash{1}, BlockHash: types.Hash{2}, GasLimit: 30_000_000, GasUsed: 21000}
+	require.Nil(t, submissionMismatch(msg, payload))
+
+	msg.BlockHash = types.Hash{3}
+	msg.GasUsed = 42000
+	mismatch := submissionMismatch(msg, payload)
+	require.NotNil(t, mismatch)
+	require.Equal(t, errSubmissionMismatch.Error()+" (differences: block_hash, gas_used)", mismatch.Error())
+	require.Equal(t, mismatch.diffs, mismatchDiffs(mismatch))
+	require.Equal(t, "21000", mismatch.diffs[1].Expected)
+	require.Equal(t, "42000", mismatch.diffs[1].Actual)
+}
+
+func TestDiffReporter(t *testing.T) {
+	reporter, err := newDiffReporter(DiffConfig{})
+	require.NoError(t, err)
+	require.Nil(t, reporter)
+	_, err = newDiffReporter(DiffConfig{Path: "diffs", Format: "xml"})
+	require.Error(t, err)
+
+	diffs := []fieldDiff{{Path: "stateRoot", Expected: "0x01", Actual: "0x02"}}
+	// a nil reporter only logs
+	reporter.report(logrus.New(), scenarioPayloadProcessing, 3, diffs)
+
+	path := filepath.Join(t.TempDir(), "diffs.jsonl")
+	reporter, err = newDiffReporter(DiffConfig{Path: path, Format: diffsJSON})
+	require.NoError(t, err)
+	reporter.report(logrus.New(), scenarioPayloadProcessing, 3, diffs)
+	reporter.report(logrus.New(), scenarioProposerValidation, 4, diffs)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, map[string]interface{}{
+		"check":    scenarioPayloadProcessing,
+		"slot":     3.0,
+		"path":     "stateRoot",
+		"expected": "0x01",
+		"actual":   "0x02",
+	}, record)
+
+	path = filepath.Join(t.TempDir(), "diffs.txt")
+	reporter, err = newDiffReporter(DiffConfig{Path: path, Format: diffsGitHub})
+	require.NoError(t, err)
+	reporter.report(logrus.New(), scenarioPayloadProcessing, 3, []fieldDiff{{Path: "extraData", Expected: "100%", Actual: "a\nb"}})
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "::error title=payload-processing::slot 3: extraData: expected \"100%25\", actual \"a\\nb\"\n", string(data))
+}
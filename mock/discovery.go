@@ -0,0 +1,141 @@
+package mock
+
+import (
+	"fmt"
+	"mergemock/types"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// bytes of the attestation subnet bitvector of 64 subnets, and the sync committee subnet
+	// bitvector of 4 subnets, of node records
+	attnetsLength  = 8
+	syncnetsLength = 1
+)
+
+// fork versions of the consensus forks that activate with the timestamp-activated forks of the
+// execution layer, which the runtime versions of this prysm version do not know about
+const (
+	forkVersionCapella uint32 = 3
+	forkVersionDeneb   uint32 = 4
+	forkVersionElectra uint32 = 5
+)
+
+// forkEpoch returns the first epoch that starts at or after the timestamp.
+func (c *ConsensusCmd) forkEpoch(timestamp uint64) uint64 {
+	epoch := c.SlotTime * time.Duration(c.SlotsPerEpoch)
+	if epoch <= 0 || timestamp <= c.BeaconGenesisTime {
+		return 0
+	}
+	since := time.Duration(timestamp-c.BeaconGenesisTime) * time.Second
+	return uint64((since + epoch - 1) / epoch)
+}
+
+// beaconForkID returns the eth2 entry of the fork of the fork times at the timestamp, and the
+// next fork scheduled after it, Bellatrix before the first fork.
+func (c *ConsensusCmd) beaconForkID(forks ForkTimes, timestamp uint64) types.ENRForkID {
+	scheduled := []struct {
+		version uint32
+		time    *uint64
+	}{{forkVersionCapella, forks.ShanghaiTime}, {forkVersionDeneb, forks.CancunTime}, {forkVersionElectra, forks.PragueTime}}
+	current := uint32(version.Bellatrix)
+	forkID := types.ENRForkID{NextForkEpoch: types.FarFutureEpoch}
+	for _, fork := range scheduled {
+		if fork.time == nil {
+			continue
+		}
+		if *fork.time <= timestamp {
+			current = fork.version
+			continue
+		}
+		forkID.NextForkVersion = fork.version
+		forkID.NextForkEpoch = c.forkEpoch(*fork.time)
+		break
+	}
+	if forkID.NextForkEpoch == types.FarFutureEpoch {
+		forkID.NextForkVersion = current
+	}
+	forkID.CurrentForkDigest = types.ComputeForkDigest(current, c.genesisValidatorsRoot)
+	return forkID
+}
+
+// setBeaconEntries sets the eth2, attnets and syncnets entries of the node record of the mock
+// beacon node, with the fork of the genesis file at the start, or at genesis if later. The
+// subnet bitfields are empty, as there is no libp2p host that could serve any subnet, even
+// with emulated validators.
+func (c *ConsensusCmd) setBeaconEntries(ln *enode.LocalNode) error {
+	forks, err := LoadForkTimes(c.GenesisPath)
+	if err != nil {
+		return err
+	}
+	now := uint64(time.Now().Unix())
+	if now < c.BeaconGenesisTime {
+		now = c.BeaconGenesisTime
+	}
+	forkID := c.beaconForkID(forks, now)
+	eth2, err := forkID.MarshalSSZ()
+	if err != nil {
+		return err
+	}
+	ln.Set(enr.WithEntry("eth2", eth2))
+	ln.Set(enr.WithEntry("attnets", make([]byte, attnetsLength)))
+	ln.Set(enr.WithEntry("syncnets", make([]byte, syncnetsLength)))
+	return nil
+}
+
+// startDiscovery answers discv5 at the discovery address, with a node record of the mock beacon
+// node under a new key, and joins the bootnodes.
+func (c *ConsensusCmd) startDiscovery(log logrus.Ext1FieldLogger) error {
+	bootnodes := make([]*enode.Node, 0, len(c.Bootnodes))
+	for _, record := range c.Bootnodes {
+		node, err := enode.Parse(enode.ValidSchemes, record)
+		if err != nil {
+			return fmt.Errorf("invalid bootnode %q: %w", record, err)
+		}
+		bootnodes = append(bootnodes, node)
+	}
+	addr, err := net.ResolveUDPAddr("udp", c.DiscoveryAddr)
+	if err != nil {
+		return fmt.Errorf("invalid discovery address: %w", err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	db, err := enode.OpenDB("")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	ln := enode.NewLocalNode(db, key)
+	ip := addr.IP
+	if ip == nil || ip.IsUnspecified() {
+		ip = net.IPv4(127, 0, 0, 1)
+	}
+	ln.SetFallbackIP(ip)
+	ln.SetFallbackUDP(conn.LocalAddr().(*net.UDPAddr).Port)
+	if err := c.setBeaconEntries(ln); err != nil {
+		conn.Close()
+		return err
+	}
+	c.discovery, err = discover.ListenV5(conn, ln, discover.Config{PrivateKey: key, Bootnodes: bootnodes})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	log.WithField("enr", ln.Node().String()).Info("Answering discv5")
+	return nil
+}
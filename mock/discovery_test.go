@@ -0,0 +1,80 @@
+package mock
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscovery(t *testing.T) {
+	// shanghai at genesis, and cancun a second into the third epoch
+	genesisTime := uint64(time.Now().Unix())
+	shanghai, cancun := genesisTime, genesisTime+2*32*12+1
+	c := &ConsensusCmd{
+		DiscoveryAddr:         "127.0.0.1:0",
+		ValidatorCount:        4,
+		GenesisPath:           writeGenesis(t, newMergedGenesis(common.Address{}), ForkTimes{ShanghaiTime: &shanghai, CancunTime: &cancun}),
+		BeaconGenesisTime:     genesisTime,
+		SlotTime:              12 * time.Second,
+		SlotsPerEpoch:         32,
+		genesisValidatorsRoot: types.Root{0x01},
+	}
+	require.NoError(t, c.startDiscovery(logrus.New()))
+	defer c.discovery.Close()
+
+	// a client that has the mock beacon node as bootnode
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	db, err := enode.OpenDB("")
+	require.NoError(t, err)
+	ln := enode.NewLocalNode(db, key)
+	ln.SetFallbackIP(net.IPv4(127, 0, 0, 1))
+	ln.SetFallbackUDP(conn.LocalAddr().(*net.UDPAddr).Port)
+	client, err := discover.ListenV5(conn, ln, discover.Config{PrivateKey: key, Bootnodes: []*enode.Node{c.discovery.Self()}})
+	require.NoError(t, err)
+	defer client.Close()
+
+	node, err := client.RequestENR(c.discovery.Self())
+	require.NoError(t, err)
+	require.Equal(t, c.discovery.Self().ID(), node.ID())
+	var eth2, attnets, syncnets []byte
+	require.NoError(t, node.Load(enr.WithEntry("eth2", &eth2)))
+	require.NoError(t, node.Load(enr.WithEntry("attnets", &attnets)))
+	require.NoError(t, node.Load(enr.WithEntry("syncnets", &syncnets)))
+	var forkID types.ENRForkID
+	require.NoError(t, forkID.UnmarshalSSZ(eth2))
+	require.Equal(t, types.ComputeForkDigest(forkVersionCapella, types.Root{0x01}), forkID.CurrentForkDigest)
+	require.Equal(t, forkVersionDeneb, forkID.NextForkVersion)
+	require.Equal(t, uint64(3), forkID.NextForkEpoch)
+	// no subnets without a libp2p host, even with validators
+	require.Equal(t, make([]byte, 8), attnets)
+	require.Equal(t, []byte{0}, syncnets)
+
+	// before the first fork, and after the last one
+	forkID = c.beaconForkID(ForkTimes{}, genesisTime)
+	require.Equal(t, types.ComputeForkDigest(version.Bellatrix, types.Root{0x01}), forkID.CurrentForkDigest)
+	require.Equal(t, uint32(version.Bellatrix), forkID.NextForkVersion)
+	require.Equal(t, types.FarFutureEpoch, forkID.NextForkEpoch)
+	forkID = c.beaconForkID(ForkTimes{ShanghaiTime: &shanghai, CancunTime: &cancun}, cancun)
+	require.Equal(t, types.ComputeForkDigest(forkVersionDeneb, types.Root{0x01}), forkID.CurrentForkDigest)
+	require.Equal(t, forkVersionDeneb, forkID.NextForkVersion)
+	require.Equal(t, types.FarFutureEpoch, forkID.NextForkEpoch)
+
+	bad := &ConsensusCmd{DiscoveryAddr: "127.0.0.1:0", Bootnodes: []string{"enr:-bad"}}
+	err = bad.startDiscovery(logrus.New())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid bootnode")
+}
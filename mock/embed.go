@@ -0,0 +1,73 @@
+package mock
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/protolambda/ask"
+)
+
+// withDefaults sets the defaults of the flags of the command and of its flag groups, as the CLI
+// does before it parses the arguments.
+func withDefaults(cmd interface{}) {
+	if _, err := ask.Load(cmd); err != nil {
+		panic(fmt.Errorf("invalid flags of %T: %w", cmd, err))
+	}
+}
+
+// serve serves the server on the listener of an embedded mock, or on its address without one.
+func serve(srv *http.Server, l net.Listener) error {
+	if l != nil {
+		return srv.Serve(l)
+	}
+	return srv.ListenAndServe()
+}
+
+// NewConsensusCmd returns a consensus mock with the defaults of the CLI, to embed in Go programs
+// and test suites: set its fields, Run it to start the mock, and Close it to stop it.
+func NewConsensusCmd() *ConsensusCmd {
+	c := &ConsensusCmd{}
+	withDefaults(c)
+	return c
+}
+
+// NewEngineCmd returns an engine mock with the defaults of the CLI. Run starts it, Close stops it.
+func NewEngineCmd() *EngineCmd {
+	c := &EngineCmd{}
+	withDefaults(c)
+	return c
+}
+
+// NewRelayCmd returns a relay mock with the defaults of the CLI, including a new secret key.
+// Run starts it, Close stops it.
+func NewRelayCmd() *RelayCmd {
+	r := &RelayCmd{}
+	withDefaults(r)
+	return r
+}
+
+// SlotEvent is sent on ConsensusCmd.Slots as a slot starts, before its payload is built.
+type SlotEvent struct {
+	Slot uint64
+	// Parent is the hash of the block the payload of the slot builds on.
+	Parent common.Hash
+	Time   time.Time
+	// Correlation is the ID the logs and events of the proposal of the slot carry.
+	Correlation string
+}
+
+// publishSlot sends the slot to the Slots channel, if any. Slots are dropped while the channel is
+// full, so a slow receiver never holds up the consensus mock.
+func (c *ConsensusCmd) publishSlot(slot uint64, parent common.Hash, at time.Time) {
+	if c.Slots == nil {
+		return
+	}
+	select {
+	case c.Slots <- SlotEvent{Slot: slot, Parent: parent, Time: at, Correlation: correlationID(slot)}:
+	default:
+		c.slotLog(slot).Debug("Dropped slot event, channel full")
+	}
+}
@@ -0,0 +1,41 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCmds(t *testing.T) {
+	// defaults of the command and of its flag groups
+	consensus := NewConsensusCmd()
+	require.Equal(t, 12*time.Second, consensus.SlotTime)
+	require.Equal(t, 1024, consensus.AccessLog.BodyLimit)
+
+	engine := NewEngineCmd()
+	require.Equal(t, "127.0.0.1:8551", engine.ListenAddr)
+
+	relay := NewRelayCmd()
+	require.NotEmpty(t, relay.SecretKey)
+	require.NotEqual(t, relay.SecretKey, NewRelayCmd().SecretKey)
+	require.Equal(t, 1024, relay.AccessLog.BodyLimit)
+}
+
+func TestPublishSlot(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	c := &ConsensusCmd{log: log}
+	// no channel
+	c.publishSlot(1, common.Hash{0x01}, time.Now())
+
+	slots := make(chan SlotEvent, 1)
+	c.Slots = slots
+	at := time.Now()
+	c.publishSlot(2, common.Hash{0x02}, at)
+	// dropped while the channel is full
+	c.publishSlot(3, common.Hash{0x03}, at)
+	require.Equal(t, SlotEvent{Slot: 2, Parent: common.Hash{0x02}, Time: at, Correlation: correlationID(2)}, <-slots)
+	require.Len(t, slots, 0)
+}
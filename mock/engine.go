@@ -0,0 +1,657 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"mergemock/api"
+	"mergemock/engineclient"
+	"mergemock/rpc"
+	"mergemock/types"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	gethRpc "github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/sirupsen/logrus"
+)
+
+type EngineCmd struct {
+	// chain options
+	SlotsPerEpoch uint64 `ask:"--slots-per-epoch" help:"Slots per epoch"`
+	DataDir       string `ask:"--datadir" help:"Directory to store execution chain data (empty for in-memory data)"`
+	GenesisPath   string `ask:"--genesis" help:"Genesis execution-config file"`
+	JwtSecretPath string `ask:"--jwt-secret" help:"JWT secret key for authenticated communication"`
+	// DepositContract must match the consensus mock, as it changes the genesis state.
+	DepositContract string `ask:"--deposit-contract" help:"Address to deploy the deposit contract at in genesis (empty to disable)"`
+
+	// connectivity options
+	ListenAddr    string      `ask:"--listen-addr" help:"Address to bind RPC HTTP server to"`
+	WebsocketAddr string      `ask:"--ws-addr" help:"Address to serve /ws endpoint on for websocket JSON-RPC (empty to disable)"`
+	Cors          []string    `ask:"--cors" help:"List of allowable origins (CORS http header)"`
+	Timeout       rpc.Timeout `ask:".timeout" help:"Configure timeouts of the HTTP servers"`
+	StrictJSON    bool        `ask:"--strict-json" help:"Reject engine API requests over HTTP with unknown fields, missing fields or hex values of the wrong length"`
+
+	Latency EngineLatency `ask:".latency" help:"Answer engine API calls late, like an execution client under load"`
+
+	Preset string `ask:"--preset" help:"Behavior preset to start from: 'missed-slots', 'late-bids', 'relay-outage', 'slow-el', 'mainnet-bad-day' (empty for none)"`
+
+	// Listener serves the RPC over HTTP instead of ListenAddr, when the engine mock is embedded
+	Listener net.Listener
+
+	// embed logger options
+	LogCmd         `ask:".log" help:"Change logger configuration"`
+	TraceLogConfig `ask:".trace" help:"Tracing options"`
+
+	close   chan struct{}
+	log     logrus.Ext1FieldLogger
+	ctx     context.Context
+	backend *EngineBackend
+	rpcSrv  *gethRpc.Server
+	srv     *http.Server
+	wsSrv   *http.Server // upgrades to websocket rpc
+
+	jwtSecret []byte
+}
+
+func (c *EngineCmd) Default() {
+	c.GenesisPath = "genesis.json"
+	c.JwtSecretPath = "jwt.hex"
+
+	c.ListenAddr = "127.0.0.1:8551"
+	c.WebsocketAddr = "127.0.0.1:8552"
+	c.Cors = []string{"*"}
+
+	c.Timeout.Read = 30 * time.Second
+	c.Timeout.ReadHeader = 10 * time.Second
+	c.Timeout.Write = 30 * time.Second
+	c.Timeout.Idle = 5 * time.Minute
+}
+
+func (c *EngineCmd) Help() string {
+	return "Run a mock Execution engine."
+}
+
+func (c *EngineCmd) Examples() []string {
+	return []string{
+		"mergemock engine --genesis=genesis.json --jwt-secret=jwt.hex",
+		"mergemock engine --datadir=chaindata --listen-addr=127.0.0.1:8551 --ws-addr=127.0.0.1:8552",
+	}
+}
+
+func (c *EngineCmd) Run(ctx context.Context, args ...string) error {
+	if err := c.initLogger(ctx); err != nil {
+		// Logger wasn't initialized so we can't log. Error out instead.
+		return err
+	}
+	jwt, err := loadJwtSecret(c.JwtSecretPath)
+	if err != nil {
+		c.log.WithField("err", err).Fatal("Unable to read JWT secret")
+	}
+	c.jwtSecret = jwt
+	c.log.WithField("val", common.Bytes2Hex(c.jwtSecret)).Info("Loaded JWT secret")
+	preset, err := lookupPreset(c.Preset)
+	if err != nil {
+		return err
+	}
+	if preset != nil {
+		preset.applyEngine(c)
+		c.log.WithField("preset", c.Preset).Info("Applied preset")
+	}
+	chain, err := c.makeMockChain()
+	if err != nil {
+		c.log.WithField("err", err).Fatal("Unable to initialize mock chain")
+	}
+	backend, err := NewEngineBackend(c.log, chain)
+	if err != nil {
+		c.log.WithField("err", err).Fatal("Unable to initialize backend")
+	}
+	backend.latency = c.Latency
+	c.backend = backend
+	c.startRPC(ctx)
+	go c.RunNode()
+	return nil
+}
+
+func (c *EngineCmd) RunNode() {
+	addr := c.ListenAddr
+	if c.Listener != nil {
+		addr = c.Listener.Addr().String()
+	}
+	c.log.WithField("listenAddr", addr).Info("Engine started")
+
+	go serve(c.srv, c.Listener)
+	if c.wsSrv != nil {
+		go c.wsSrv.ListenAndServe()
+	}
+
+	for range c.close {
+		c.rpcSrv.Stop()
+		c.srv.Close()
+		if c.wsSrv != nil {
+			c.wsSrv.Close()
+		}
+		return
+		// TODO: any other tasks to run in this loop? mock sync changes?
+	}
+}
+
+func (c *EngineCmd) Close() error {
+	if c.close != nil {
+		c.close <- struct{}{}
+	}
+	return nil
+}
+
+func (c *EngineCmd) initLogger(ctx context.Context) error {
+	logr, err := c.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	c.log = logr
+	c.ctx = ctx
+	c.close = make(chan struct{})
+	return nil
+}
+
+func loadJwtSecret(path string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	jwt := common.Hex2Bytes(string(raw))
+	if len(jwt) != 32 {
+		return nil, fmt.Errorf("invalid length, expected 32-byte value")
+	}
+	return jwt, nil
+}
+
+func (c *EngineCmd) makeMockChain() (*MockChain, error) {
+	posEngine := &ExecutionConsensusMock{
+		pow: nil, // TODO: do we even need this?
+		log: c.log,
+	}
+	db, err := NewDB(c.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open db")
+	}
+	genesis, err := LoadGenesis(c.GenesisPath, c.DepositContract)
+	if err != nil {
+		return nil, err
+	}
+	forks, err := LoadForkTimes(c.GenesisPath)
+	if err != nil {
+		return nil, err
+	}
+	mc, err := NewMockChain(c.log, posEngine, genesis, db, &c.TraceLogConfig)
+	if err != nil {
+		return nil, err
+	}
+	mc.SetForkTimes(forks)
+	if c.DepositContract != "" {
+		mc.SetDepositContract(common.HexToAddress(c.DepositContract))
+	}
+	return mc, nil
+}
+
+func (c *EngineCmd) mockChain() *MockChain {
+	return c.backend.mockChain
+}
+
+func (c *EngineCmd) startRPC(ctx context.Context) {
+	rpcSrv, err := rpc.NewServer("engine", c.backend, true)
+	if err != nil {
+		c.log.Fatal(err)
+	}
+
+	ethBackend := NewEthBackend(c.backend.mockChain.chain, c.backend.txPool)
+	ethBackend.Register(rpcSrv)
+
+	c.rpcSrv = rpcSrv
+	var strict rpc.StrictParams
+	if c.StrictJSON {
+		strict = rpc.MethodParams("engine", c.backend)
+	}
+	c.srv = rpc.NewHTTPServer(ctx, c.log, c.rpcSrv, c.ListenAddr, c.Timeout, c.Cors, strict)
+	if c.WebsocketAddr != "" {
+		if c.StrictJSON {
+			// the checks wrap the HTTP handler, websocket requests are frames after the upgrade
+			c.log.Warn("Strict JSON checks only apply to requests over HTTP, not over the websocket")
+		}
+		c.wsSrv = rpc.NewWSServer(ctx, c.log, c.rpcSrv, c.WebsocketAddr, c.jwtSecret, c.Timeout, c.Cors)
+	}
+}
+
+// defaultTxPoolLimit is the maximum number of transactions kept in the engine mock's pool.
+const defaultTxPoolLimit = 4096
+
+type EngineBackend struct {
+	log              logrus.Ext1FieldLogger
+	mockChain        *MockChain
+	payloadIdCounter uint64
+	recentPayloads   *lru.Cache
+	txPool           *TxPool
+
+	// parent beacon block roots of the recent payloads, by block hash, to rebuild them
+	beaconRoots *lru.Cache
+
+	latency EngineLatency
+}
+
+func NewEngineBackend(log logrus.Ext1FieldLogger, mock *MockChain) (*EngineBackend, error) {
+	cache, err := lru.New(10)
+	if err != nil {
+		return nil, err
+	}
+	beaconRoots, err := lru.New(10)
+	if err != nil {
+		return nil, err
+	}
+	pool := NewTxPool(log, mock.gspec.Config, defaultTxPoolLimit)
+	return &EngineBackend{log, mock, 0, cache, pool, beaconRoots, EngineLatency{}}, nil
+}
+
+// getPayload returns a payload built earlier, in its latest version.
+func (e *EngineBackend) getPayload(id types.PayloadID) (*types.ExecutionPayloadV3, error) {
+	plog := e.log.WithField("payload_id", id)
+	e.latency.wait(plog, "engine_getPayload")
+
+	payload, ok := e.recentPayloads.Get(id)
+	if !ok {
+		plog.Warn("Cannot get unknown payload")
+		return nil, &rpc.Error{Err: fmt.Errorf("unknown payload %d", id), Id: int(api.UnavailablePayload)}
+	}
+
+	plog.Info("Consensus client retrieved prepared payload")
+	return payload.(*types.ExecutionPayloadV3), nil
+}
+
+// ExchangeCapabilities returns the engine methods the mock serves, which are those the engine
+// client calls, whatever the capabilities of the consensus client.
+func (e *EngineBackend) ExchangeCapabilities(ctx context.Context, capabilities []string) ([]string, error) {
+	e.log.WithField("capabilities", capabilities).Debug("Exchanged capabilities")
+	return engineclient.Capabilities, nil
+}
+
+func (e *EngineBackend) GetPayloadV1(ctx context.Context, id types.PayloadID) (*types.ExecutionPayloadV1, error) {
+	payload, err := e.getPayload(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.mockChain.forks.IsShanghai(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("payload %d is a shanghai payload", id), Id: int(api.UnsupportedFork)}
+	}
+	return payload.V1(), nil
+}
+
+func (e *EngineBackend) GetPayloadV2(ctx context.Context, id types.PayloadID) (*types.GetPayloadV2Response, error) {
+	payload, err := e.getPayload(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.mockChain.forks.IsCancun(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("payload %d is a cancun payload", id), Id: int(api.UnsupportedFork)}
+	}
+	return &types.GetPayloadV2Response{ExecutionPayload: payload.V2(), BlockValue: (*hexutil.Big)(common.Big0)}, nil
+}
+
+func (e *EngineBackend) GetPayloadV3(ctx context.Context, id types.PayloadID) (*types.GetPayloadV3Response, error) {
+	payload, err := e.getPayload(id)
+	if err != nil {
+		return nil, err
+	}
+	if !e.mockChain.forks.IsCancun(payload.Timestamp) || e.mockChain.forks.IsPrague(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("payload %d is not a cancun payload", id), Id: int(api.UnsupportedFork)}
+	}
+	return &types.GetPayloadV3Response{
+		ExecutionPayload: payload,
+		BlockValue:       (*hexutil.Big)(common.Big0),
+		BlobsBundle:      &types.BlobsBundleV1{Commitments: []hexutil.Bytes{}, Proofs: []hexutil.Bytes{}, Blobs: []hexutil.Bytes{}},
+	}, nil
+}
+
+func (e *EngineBackend) GetPayloadV4(ctx context.Context, id types.PayloadID) (*types.GetPayloadV4Response, error) {
+	payload, err := e.getPayload(id)
+	if err != nil {
+		return nil, err
+	}
+	if !e.mockChain.forks.IsPrague(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("payload %d is not a prague payload", id), Id: int(api.UnsupportedFork)}
+	}
+	requests, ok := e.mockChain.ExecutionRequests(payload.BlockHash)
+	if !ok {
+		return nil, &rpc.Error{Err: fmt.Errorf("execution requests of payload %d are unavailable", id), Id: int(api.UnavailablePayload)}
+	}
+	return &types.GetPayloadV4Response{
+		ExecutionPayload:  payload,
+		BlockValue:        (*hexutil.Big)(common.Big0),
+		BlobsBundle:       &types.BlobsBundleV1{Commitments: []hexutil.Bytes{}, Proofs: []hexutil.Bytes{}, Blobs: []hexutil.Bytes{}},
+		ExecutionRequests: requests,
+	}, nil
+}
+
+// checkVersionedHashes returns an invalid status if the expected versioned hashes do not match
+// those of the blob transactions in the payload.
+func checkVersionedHashes(log logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, expected []common.Hash) *types.PayloadStatusV1 {
+	hashes, err := types.BlobVersionedHashes(payload.Transactions)
+	if err != nil {
+		log.WithError(err).Warn("Cannot get versioned hashes of payload")
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: err.Error()}
+	}
+	if len(hashes) != len(expected) {
+		log.WithField("expected", len(expected)).WithField("got", len(hashes)).Warn("Versioned hashes count mismatch")
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: fmt.Sprintf("expected %d versioned hashes, payload has %d", len(expected), len(hashes))}
+	}
+	for i := range hashes {
+		if hashes[i] != expected[i] {
+			log.WithField("index", i).Warn("Versioned hash mismatch")
+			return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: fmt.Sprintf("versioned hash %d mismatch: expected %s, got %s", i, expected[i], hashes[i])}
+		}
+	}
+	return nil
+}
+
+// checkPayloadParent returns a status if the payload cannot be executed on top of its parent.
+func (e *EngineBackend) checkPayloadParent(log logrus.Ext1FieldLogger, parentHash common.Hash) *types.PayloadStatusV1 {
+	parent := e.mockChain.chain.GetHeaderByHash(parentHash)
+	if parent == nil {
+		log.WithField("parent_hash", parentHash.String()).Warn("Cannot execute payload, parent is unknown")
+		return &types.PayloadStatusV1{Status: types.ExecutionSyncing}
+	} else if err := e.mockChain.CheckTransitionParent(parent); err != nil {
+		log.WithField("parent_hash", parentHash.String()).WithError(err).Warn("Parent block not a valid terminal block")
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, LatestValidHash: &common.Hash{}, ValidationError: err.Error()}
+	}
+	return nil
+}
+
+// checkExtraData returns an invalid status if the extra data of the payload exceeds the limit of
+// the specs, which the header checks of the mock chain do not enforce.
+func checkExtraData(log logrus.Ext1FieldLogger, extraData []byte) *types.PayloadStatusV1 {
+	if len(extraData) <= types.MaxExtraDataBytes {
+		return nil
+	}
+	err := fmt.Errorf("%w: %d bytes of extra data", types.ErrPayloadLimit, len(extraData))
+	log.WithError(err).Warn("Payload exceeds the extra data limit")
+	return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: err.Error()}
+}
+
+func (e *EngineBackend) NewPayloadV1(ctx context.Context, payload *types.ExecutionPayloadV1) (*types.PayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_newPayload")
+	if status := checkExtraData(log, payload.ExtraData); status != nil {
+		return status, nil
+	}
+	if !payload.ValidateHash() {
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalidBlockHash}, nil
+	}
+	if status := e.checkPayloadParent(log, payload.ParentHash); status != nil {
+		return status, nil
+	}
+
+	_, err := e.mockChain.ProcessPayload(payload)
+	if err != nil {
+		log.WithError(err).Error("Failed to execute payload")
+		logDiffs(log, "newPayload", mismatchDiffs(err))
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: err.Error()}, nil
+	}
+	log.Info("Executed payload")
+	return &types.PayloadStatusV1{Status: types.ExecutionValid}, nil
+}
+
+func (e *EngineBackend) NewPayloadV2(ctx context.Context, payload *types.ExecutionPayloadV2) (*types.PayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_newPayload")
+	if e.mockChain.forks.IsCancun(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("cancun payloads require engine_newPayloadV3"), Id: int(api.UnsupportedFork)}
+	}
+	if status := checkExtraData(log, payload.ExtraData); status != nil {
+		return status, nil
+	}
+	if !payload.V1().ValidateHash() {
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalidBlockHash}, nil
+	}
+	if status := e.checkPayloadParent(log, payload.ParentHash); status != nil {
+		return status, nil
+	}
+
+	_, err := e.mockChain.ProcessPayloadV2(payload)
+	if err != nil {
+		log.WithError(err).Error("Failed to execute payload")
+		logDiffs(log, "newPayload", mismatchDiffs(err))
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: err.Error()}, nil
+	}
+	log.Info("Executed payload")
+	return &types.PayloadStatusV1{Status: types.ExecutionValid}, nil
+}
+
+func (e *EngineBackend) NewPayloadV3(ctx context.Context, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot *common.Hash) (*types.PayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_newPayload")
+	if !e.mockChain.forks.IsCancun(payload.Timestamp) || e.mockChain.forks.IsPrague(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("engine_newPayloadV3 requires a cancun payload"), Id: int(api.UnsupportedFork)}
+	}
+	if versionedHashes == nil || beaconRoot == nil {
+		return nil, &rpc.Error{Err: fmt.Errorf("missing versioned hashes or parent beacon block root"), Id: int(api.InvalidParams)}
+	}
+	if status := checkVersionedHashes(log, payload, versionedHashes); status != nil {
+		return status, nil
+	}
+	if status := checkExtraData(log, payload.ExtraData); status != nil {
+		return status, nil
+	}
+	if !payload.V1().ValidateHash() {
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalidBlockHash}, nil
+	}
+	if status := e.checkPayloadParent(log, payload.ParentHash); status != nil {
+		return status, nil
+	}
+
+	_, err := e.mockChain.ProcessPayloadV3(payload, *beaconRoot)
+	if err != nil {
+		log.WithError(err).Error("Failed to execute payload")
+		logDiffs(log, "newPayload", mismatchDiffs(err))
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: err.Error()}, nil
+	}
+	log.WithField("beacon_root", beaconRoot).Info("Executed payload")
+	return &types.PayloadStatusV1{Status: types.ExecutionValid}, nil
+}
+
+func (e *EngineBackend) NewPayloadV4(ctx context.Context, payload *types.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot *common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_newPayload")
+	if !e.mockChain.forks.IsPrague(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("engine_newPayloadV4 requires a prague payload"), Id: int(api.UnsupportedFork)}
+	}
+	if versionedHashes == nil || beaconRoot == nil || requests == nil {
+		return nil, &rpc.Error{Err: fmt.Errorf("missing versioned hashes, parent beacon block root or execution requests"), Id: int(api.InvalidParams)}
+	}
+	if err := requests.Validate(); err != nil {
+		return nil, &rpc.Error{Err: fmt.Errorf("invalid execution requests: %v", err), Id: int(api.InvalidParams)}
+	}
+	if status := checkVersionedHashes(log, payload, versionedHashes); status != nil {
+		return status, nil
+	}
+	if status := checkExtraData(log, payload.ExtraData); status != nil {
+		return status, nil
+	}
+	if !payload.V1().ValidateHash() {
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalidBlockHash}, nil
+	}
+	if status := e.checkPayloadParent(log, payload.ParentHash); status != nil {
+		return status, nil
+	}
+
+	block, err := e.mockChain.ProcessPayloadV3(payload, *beaconRoot)
+	if err != nil {
+		log.WithError(err).Error("Failed to execute payload")
+		logDiffs(log, "newPayload", mismatchDiffs(err))
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: err.Error()}, nil
+	}
+	// the block hash does not commit to the requests, compare the requests we can derive instead
+	derived, _ := e.mockChain.ExecutionRequests(block.Hash())
+	if !bytes.Equal(derived.Type(types.DepositRequestType), requests.Type(types.DepositRequestType)) {
+		log.Error("Deposit requests differ from the deposits in the payload")
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: "deposit requests mismatch"}, nil
+	}
+	if !bytes.Equal(derived.Type(types.WithdrawalRequestType), requests.Type(types.WithdrawalRequestType)) {
+		log.Error("Withdrawal requests differ from the withdrawal request transactions in the payload")
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: "withdrawal requests mismatch"}, nil
+	}
+	if !bytes.Equal(derived.Type(types.ConsolidationRequestType), requests.Type(types.ConsolidationRequestType)) {
+		log.Error("Consolidation requests differ from the consolidation request transactions in the payload")
+		return &types.PayloadStatusV1{Status: types.ExecutionInvalid, ValidationError: "consolidation requests mismatch"}, nil
+	}
+	log.WithField("requests", len(requests)).Info("Executed payload")
+	return &types.PayloadStatusV1{Status: types.ExecutionValid}, nil
+}
+
+func (e *EngineBackend) ForkchoiceUpdatedV1(ctx context.Context, heads *types.ForkchoiceStateV1, attributes *types.PayloadAttributesV1) (*types.ForkchoiceUpdatedResult, error) {
+	var attrs *types.PayloadAttributesV3
+	if attributes != nil {
+		if e.mockChain.forks.IsShanghai(attributes.Timestamp) {
+			return nil, &rpc.Error{Err: fmt.Errorf("shanghai payload attributes require engine_forkchoiceUpdatedV2"), Id: int(api.UnsupportedFork)}
+		}
+		attrs = &types.PayloadAttributesV3{
+			Timestamp:             attributes.Timestamp,
+			PrevRandao:            attributes.PrevRandao,
+			SuggestedFeeRecipient: attributes.SuggestedFeeRecipient,
+		}
+	}
+	return e.forkchoiceUpdated(heads, attrs, nil)
+}
+
+func (e *EngineBackend) ForkchoiceUpdatedV2(ctx context.Context, heads *types.ForkchoiceStateV1, attributes *types.PayloadAttributesV2) (*types.ForkchoiceUpdatedResult, error) {
+	var attrs *types.PayloadAttributesV3
+	if attributes != nil {
+		if e.mockChain.forks.IsCancun(attributes.Timestamp) {
+			return nil, &rpc.Error{Err: fmt.Errorf("cancun payload attributes require engine_forkchoiceUpdatedV3"), Id: int(api.UnsupportedFork)}
+		}
+		if e.mockChain.forks.IsShanghai(attributes.Timestamp) != (attributes.Withdrawals != nil) {
+			return nil, &rpc.Error{Err: fmt.Errorf("withdrawals must be set if and only if shanghai is active"), Id: int(api.InvalidParams)}
+		}
+		attrs = &types.PayloadAttributesV3{
+			Timestamp:             attributes.Timestamp,
+			PrevRandao:            attributes.PrevRandao,
+			SuggestedFeeRecipient: attributes.SuggestedFeeRecipient,
+			Withdrawals:           attributes.Withdrawals,
+		}
+	}
+	return e.forkchoiceUpdated(heads, attrs, nil)
+}
+
+func (e *EngineBackend) ForkchoiceUpdatedV3(ctx context.Context, heads *types.ForkchoiceStateV1, attributes *types.PayloadAttributesV3) (*types.ForkchoiceUpdatedResult, error) {
+	if attributes != nil {
+		if !e.mockChain.forks.IsCancun(attributes.Timestamp) {
+			return nil, &rpc.Error{Err: fmt.Errorf("engine_forkchoiceUpdatedV3 requires cancun payload attributes"), Id: int(api.UnsupportedFork)}
+		}
+		if attributes.Withdrawals == nil {
+			return nil, &rpc.Error{Err: fmt.Errorf("missing withdrawals"), Id: int(api.InvalidParams)}
+		}
+		return e.forkchoiceUpdated(heads, attributes, &attributes.ParentBeaconBlockRoot)
+	}
+	return e.forkchoiceUpdated(heads, nil, nil)
+}
+
+func (e *EngineBackend) forkchoiceUpdated(heads *types.ForkchoiceStateV1, attributes *types.PayloadAttributesV3, beaconRoot *common.Hash) (*types.ForkchoiceUpdatedResult, error) {
+	e.latency.wait(e.log, "engine_forkchoiceUpdated")
+	e.log.WithFields(logrus.Fields{
+		"head":       heads.HeadBlockHash,
+		"safe":       heads.SafeBlockHash,
+		"finalized":  heads.FinalizedBlockHash,
+		"attributes": attributes,
+	}).Info("Forkchoice updated")
+
+	if attributes == nil {
+		return &types.ForkchoiceUpdatedResult{PayloadStatus: types.PayloadStatusV1{Status: types.ExecutionValid, LatestValidHash: &heads.HeadBlockHash}}, nil
+	}
+	idU64 := atomic.AddUint64(&e.payloadIdCounter, 1)
+	var id types.PayloadID
+	binary.BigEndian.PutUint64(id[:], idU64)
+
+	plog := e.log.WithField("payload_id", id)
+	plog.WithFields(logrus.Fields{
+		"timestamp":               attributes.Timestamp,
+		"prev_randao":             attributes.PrevRandao.String(),
+		"suggested_fee_recipient": attributes.SuggestedFeeRecipient.String(),
+		"withdrawals":             len(attributes.Withdrawals),
+		"beacon_root":             beaconRoot,
+	}).Info("Preparing new payload")
+
+	gasLimit := e.mockChain.gspec.GasLimit
+	txsCreator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext,
+		statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		// include whatever was submitted via eth_sendRawTransaction
+		return e.txPool.Select(config, bc, statedb, header, cfg)
+	}}
+	extraData := []byte{}
+
+	bl, err := e.mockChain.AddNewBlock(common.BytesToHash(heads.HeadBlockHash[:]), attributes.SuggestedFeeRecipient, uint64(attributes.Timestamp),
+		gasLimit, txsCreator, attributes.PrevRandao, extraData, nil, attributes.Withdrawals, beaconRoot, false)
+
+	if err != nil {
+		// TODO: proper error codes
+		plog.WithError(err).Error("Failed to create block, cannot build new payload")
+		return nil, err
+	}
+
+	payload, err := api.BlockToPayloadV3(bl, attributes.Withdrawals)
+	if err != nil {
+		plog.WithError(err).Error("Failed to convert block to payload")
+		// TODO: proper error codes
+		return nil, err
+	}
+
+	// store in cache for later retrieval
+	e.recentPayloads.Add(id, payload)
+	e.recentPayloads.Add(payload.ParentHash, payload)
+	if beaconRoot != nil {
+		e.beaconRoots.Add(payload.BlockHash, *beaconRoot)
+	}
+
+	return &types.ForkchoiceUpdatedResult{PayloadStatus: types.PayloadStatusV1{Status: types.ExecutionValid, LatestValidHash: &heads.HeadBlockHash}, PayloadID: &id}, nil
+}
+
+// rebuildPayload builds the payload again on its parent with only the transactions, with the
+// same fee recipient, timestamp, randao, extra data, withdrawals and beacon root. Payloads after
+// cancun are only rebuilt if the engine built them, as it knows their beacon root.
+func (e *EngineBackend) rebuildPayload(payload *types.ExecutionPayloadV3, txs [][]byte) (*types.ExecutionPayloadV3, error) {
+	decoded := make([]*ethTypes.Transaction, len(txs))
+	for i, raw := range txs {
+		decoded[i] = new(ethTypes.Transaction)
+		if err := decoded[i].UnmarshalBinary(raw); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %w", i, err)
+		}
+	}
+	var beaconRoot *common.Hash
+	if root, ok := e.beaconRoots.Get(payload.BlockHash); ok {
+		r := root.(common.Hash)
+		beaconRoot = &r
+	}
+	txsCreator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext,
+		statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		return decoded
+	}}
+	// payloads before shanghai have empty withdrawals, blocks none
+	var withdrawals types.Withdrawals
+	if e.mockChain.forks.IsShanghai(payload.Timestamp) {
+		withdrawals = payload.Withdrawals
+	}
+	bl, err := e.mockChain.AddNewBlock(payload.ParentHash, payload.FeeRecipient, payload.Timestamp, payload.GasLimit, txsCreator,
+		payload.Random, payload.ExtraData, nil, withdrawals, beaconRoot, false)
+	if err != nil {
+		return nil, err
+	}
+	return api.BlockToPayloadV3(bl, withdrawals)
+}
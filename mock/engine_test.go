@@ -0,0 +1,559 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/contracts"
+	"mergemock/engineclient"
+	"mergemock/rpc"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func newFundedGenesis(t *testing.T, faucet common.Address) string {
+	return writeGenesis(t, newMergedGenesis(faucet), ForkTimes{})
+}
+
+func newMergedGenesis(faucet common.Address) *core.Genesis {
+	genesis := core.DeveloperGenesisBlock(5, 30_000_000, faucet)
+	genesis.Config.MergeForkBlock = common.Big0
+	genesis.Config.TerminalTotalDifficulty = common.Big0
+	return genesis
+}
+
+// writeGenesis writes the genesis to a tmp file, with the fork times added to its config.
+func writeGenesis(t *testing.T, genesis *core.Genesis, forks ForkTimes) string {
+	path := fmt.Sprintf("%s/genesis.json", t.TempDir())
+	buf, err := genesis.MarshalJSON()
+	if err != nil {
+		t.Fatal("cannot marshal tmp genesis")
+	}
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf, &raw))
+	config := raw["config"].(map[string]interface{})
+	if forks.ShanghaiTime != nil {
+		config["shanghaiTime"] = *forks.ShanghaiTime
+	}
+	if forks.CancunTime != nil {
+		config["cancunTime"] = *forks.CancunTime
+	}
+	if forks.PragueTime != nil {
+		config["pragueTime"] = *forks.PragueTime
+	}
+	buf, err = json.Marshal(raw)
+	require.NoError(t, err)
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal("unable to write tmp genesis file")
+	}
+	return path
+}
+
+func newTestEngine(t *testing.T, genesisPath string, opts ...func(*EngineCmd)) *EngineCmd {
+	engine := &EngineCmd{}
+	engine.Default()
+	engine.LogCmd.Default()
+	engine.ListenAddr = "127.0.0.1:38651"
+	engine.WebsocketAddr = "127.0.0.1:38652"
+	engine.JwtSecretPath = newJwt(t)
+	engine.GenesisPath = genesisPath
+	for _, opt := range opts {
+		opt(engine)
+	}
+	require.NoError(t, engine.Run(context.Background()))
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+// dialEngine returns a client of the engine API at the URL, without a JWT secret.
+func dialEngine(t *testing.T, url string) *engineclient.Client {
+	client, err := rpc.DialContext(context.Background(), url, []byte{})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return engineclient.New(client, logrus.New())
+}
+
+func TestSendRawTransaction(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	engine := newTestEngine(t, newFundedGenesis(t, sender))
+	config := engine.mockChain().gspec.Config
+	eth := NewEthBackend(engine.mockChain().chain, engine.backend.txPool)
+
+	signer := ethTypes.LatestSigner(config)
+	recipient := common.Address{0x42}
+	var hashes []common.Hash
+	for nonce, tip := range []int64{1, 3} {
+		tx := ethTypes.MustSignNewTx(key, signer, &ethTypes.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     uint64(nonce),
+			To:        &recipient,
+			Gas:       params.TxGas,
+			GasFeeCap: big.NewInt(10 * params.GWei),
+			GasTipCap: big.NewInt(tip),
+			Value:     big.NewInt(1),
+		})
+		raw, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		hash, err := eth.SendRawTransaction(ctx, raw)
+		require.NoError(t, err)
+		require.Equal(t, tx.Hash(), hash)
+		hashes = append(hashes, hash)
+
+		// resubmission is rejected
+		_, err = eth.SendRawTransaction(ctx, raw)
+		require.ErrorIs(t, err, errTxKnown)
+	}
+
+	// wrong chain id is rejected
+	bad := ethTypes.MustSignNewTx(key, ethTypes.LatestSignerForChainID(big.NewInt(999)), &ethTypes.DynamicFeeTx{
+		ChainID:   big.NewInt(999),
+		Nonce:     2,
+		To:        &recipient,
+		Gas:       params.TxGas,
+		GasFeeCap: big.NewInt(10 * params.GWei),
+	})
+	raw, err := bad.MarshalBinary()
+	require.NoError(t, err)
+	_, err = eth.SendRawTransaction(ctx, raw)
+	require.ErrorIs(t, err, errTxWrongChainID)
+
+	// build a payload and check both pooled txs are in it, nonce order honoured
+	parent := engine.mockChain().CurrentHeader()
+	res, err := engine.backend.ForkchoiceUpdatedV1(ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, SuggestedFeeRecipient: common.Address{0x13}},
+	)
+	require.NoError(t, err)
+	payload, err := engine.backend.GetPayloadV1(ctx, *res.PayloadID)
+	require.NoError(t, err)
+	require.Len(t, payload.Transactions, 2)
+	for i, enc := range payload.Transactions {
+		var tx ethTypes.Transaction
+		require.NoError(t, tx.UnmarshalBinary(enc))
+		require.Equal(t, hashes[i], tx.Hash())
+	}
+
+	// once the payload is canonical, the included txs are pruned from the pool
+	status, err := engine.backend.NewPayloadV1(ctx, payload)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+	statedb, err := engine.mockChain().chain.State()
+	require.NoError(t, err)
+	require.Empty(t, engine.backend.txPool.Pending(statedb))
+	require.Equal(t, 0, engine.backend.txPool.Len())
+}
+
+func TestDepositContractLogs(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	depositContract := common.HexToAddress("0x4242424242424242424242424242424242424242")
+	engine := newTestEngine(t, newFundedGenesis(t, sender), func(e *EngineCmd) {
+		e.DepositContract = depositContract.Hex()
+	})
+	chain := engine.mockChain()
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	require.NotEmpty(t, statedb.GetCode(depositContract), "deposit contract not in genesis")
+
+	// the consensus mock builds a block with a deposit, and the engine executes it
+	parent := chain.CurrentHeader()
+	creator := TransactionsCreator{[]TestAccount{{key, sender}}, depositTxCreator(depositContract)}
+	block, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, false)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), 1)
+	payload, err := api.BlockToPayload(block)
+	require.NoError(t, err)
+	status, err := engine.backend.NewPayloadV1(ctx, payload)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+
+	eth := NewEthBackend(chain.chain, nil)
+	logs, err := eth.GetLogs(ctx, filters.FilterCriteria{
+		FromBlock: common.Big0,
+		Addresses: []common.Address{depositContract},
+		Topics:    [][]common.Hash{{contracts.DepositEventTopic}},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, block.Hash(), logs[0].BlockHash)
+
+	// unrelated topics don't match
+	logs, err = eth.GetLogs(ctx, filters.FilterCriteria{
+		FromBlock: common.Big0,
+		Topics:    [][]common.Hash{{common.Hash{0x01}}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, logs)
+}
+
+func TestWithdrawals(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	shanghai := uint64(0)
+	path := writeGenesis(t, newMergedGenesis(crypto.PubkeyToAddress(key.PublicKey)), ForkTimes{ShanghaiTime: &shanghai})
+
+	engine := newTestEngine(t, path)
+	chain := engine.mockChain()
+	require.True(t, chain.forks.IsShanghai(0))
+
+	// build the block on a separate chain, so the engine mock processes it from scratch
+	gspec, err := LoadGenesis(path, "")
+	require.NoError(t, err)
+	builder, err := NewMockChain(engine.log, chain.engine, gspec, rawdb.NewMemoryDatabase(), &engine.TraceLogConfig)
+	require.NoError(t, err)
+	builder.SetForkTimes(chain.forks)
+
+	recipient := common.Address{0x42}
+	withdrawals := types.Withdrawals{
+		{Index: 0, Validator: 1, Address: recipient, Amount: 10},
+		{Index: 1, Validator: 2, Address: recipient, Amount: 5},
+	}
+	parent := builder.CurrentHeader()
+	creator := TransactionsCreator{nil, func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+		return nil
+	}}
+	block, err := builder.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, withdrawals, nil, false)
+	require.NoError(t, err)
+
+	// a payload without withdrawals is rejected after shanghai
+	payloadV1, err := api.BlockToPayload(block)
+	require.NoError(t, err)
+	_, err = chain.ProcessPayload(payloadV1)
+	require.Error(t, err)
+
+	// with the withdrawals, both sides agree on the post-state
+	payload, err := api.BlockToPayloadV2(block, withdrawals)
+	require.NoError(t, err)
+	_, err = chain.ProcessPayloadV2(payload)
+	require.NoError(t, err)
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(15*params.GWei), statedb.GetBalance(recipient))
+
+	// a different withdrawal amount results in a different state root
+	payload.Withdrawals = types.Withdrawals{{Index: 0, Validator: 1, Address: recipient, Amount: 11}}
+	payload.ParentHash = block.Hash()
+	payload.Number++
+	payload.Timestamp++
+	payload.BaseFeePerGas = chain.ExpectedBaseFee(block.Header())
+	_, err = chain.ProcessPayloadV2(payload)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "state root difference")
+	require.Contains(t, err.Error(), "(differences: blockHash, stateRoot)")
+}
+
+func TestBeaconRoot(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	genesis := newMergedGenesis(crypto.PubkeyToAddress(key.PublicKey))
+	// any code will do, the mock writes the storage without calling the contract
+	genesis.Alloc[contracts.BeaconRootsAddress] = core.GenesisAccount{Code: []byte{0x00}, Balance: common.Big0}
+	zero := uint64(0)
+	engine := newTestEngine(t, writeGenesis(t, genesis, ForkTimes{ShanghaiTime: &zero, CancunTime: &zero}))
+	chain := engine.mockChain()
+
+	build := func(root common.Hash) *types.ExecutionPayloadV3 {
+		parent := chain.CurrentHeader()
+		res, err := engine.backend.ForkchoiceUpdatedV3(ctx,
+			&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+			&types.PayloadAttributesV3{Timestamp: parent.Time + 1, Withdrawals: types.Withdrawals{}, ParentBeaconBlockRoot: root},
+		)
+		require.NoError(t, err)
+		// cancun payloads are not available with older method versions
+		_, err = engine.backend.GetPayloadV2(ctx, *res.PayloadID)
+		require.Error(t, err)
+		envelope, err := engine.backend.GetPayloadV3(ctx, *res.PayloadID)
+		require.NoError(t, err)
+		return envelope.ExecutionPayload
+	}
+
+	// a wrong root results in a different post-state
+	root := common.Hash{0x01}
+	payload := build(root)
+	wrong := common.Hash{0x02}
+	status, err := engine.backend.NewPayloadV3(ctx, payload, []common.Hash{}, &wrong)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionInvalid, status.Status)
+
+	// the right root is written to the beacon roots contract
+	status, err = engine.backend.NewPayloadV3(ctx, payload, []common.Hash{}, &root)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	idx := payload.Timestamp % contracts.BeaconRootsHistoryBufferLength
+	require.Equal(t, common.BigToHash(new(big.Int).SetUint64(payload.Timestamp)), statedb.GetState(contracts.BeaconRootsAddress, common.BigToHash(new(big.Int).SetUint64(idx))))
+	require.Equal(t, root, statedb.GetState(contracts.BeaconRootsAddress, common.BigToHash(new(big.Int).SetUint64(idx+contracts.BeaconRootsHistoryBufferLength))))
+
+	// the root is required
+	_, err = engine.backend.NewPayloadV3(ctx, payload, []common.Hash{}, nil)
+	require.Error(t, err)
+}
+
+func TestBeaconRootBeforeTxs(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	genesis := newMergedGenesis(account.addr)
+	// the beacon roots contract copies the storage slot of the calldata to slot 0xffff
+	genesis.Alloc[contracts.BeaconRootsAddress] = core.GenesisAccount{Code: common.FromHex("0x6000355461ffff5500"), Balance: common.Big0}
+	zero := uint64(0)
+	forks := ForkTimes{ShanghaiTime: &zero, CancunTime: &zero}
+	builder, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	builder.SetForkTimes(forks)
+	chain, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	chain.SetForkTimes(forks)
+
+	// a transaction reads the root of its own block
+	parent := builder.CurrentHeader()
+	timestamp := parent.Time + 1
+	root := common.Hash{0x01}
+	slot := common.BigToHash(new(big.Int).SetUint64(timestamp%contracts.BeaconRootsHistoryBufferLength + contracts.BeaconRootsHistoryBufferLength))
+	readRoot := func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		tx, _ := ethTypes.SignTx(ethTypes.NewTx(&ethTypes.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     statedb.GetNonce(account.addr),
+			To:        &contracts.BeaconRootsAddress,
+			Gas:       100000,
+			GasFeeCap: new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei)),
+			GasTipCap: big.NewInt(2),
+			Data:      slot[:],
+		}), ethTypes.NewLondonSigner(config.ChainID), account.pk)
+		return []*ethTypes.Transaction{tx}
+	}
+	block, err := builder.AddNewBlock(parent.Hash(), common.Address{1}, timestamp, parent.GasLimit, TransactionsCreator{nil, readRoot}, common.Hash{}, nil, nil, types.Withdrawals{}, &root, true)
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), builder.CurrentHeader().Hash())
+	statedb, err := builder.chain.State()
+	require.NoError(t, err)
+	require.Equal(t, root, statedb.GetState(contracts.BeaconRootsAddress, common.BigToHash(big.NewInt(0xffff))))
+
+	// a chain importing the block computes the same state
+	payload, err := api.BlockToPayloadV3(block, types.Withdrawals{})
+	require.NoError(t, err)
+	_, err = chain.ProcessPayloadV3(payload, root)
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), chain.CurrentHeader().Hash())
+}
+
+func TestDepositRequests(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	depositContract := common.HexToAddress("0x4242424242424242424242424242424242424242")
+	zero := uint64(0)
+	path := writeGenesis(t, newMergedGenesis(sender), ForkTimes{ShanghaiTime: &zero, CancunTime: &zero, PragueTime: &zero})
+	engine := newTestEngine(t, path, func(e *EngineCmd) {
+		e.DepositContract = depositContract.Hex()
+	})
+	chain := engine.mockChain()
+
+	// pool a deposit, for the engine to include in the payload
+	parent := chain.CurrentHeader()
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	txs := depositTxCreator(depositContract)(chain.gspec.Config, chain.chain, statedb, parent, vm.Config{}, []TestAccount{{key, sender}})
+	require.Len(t, txs, 1)
+	require.NoError(t, engine.backend.txPool.Add(txs[0], statedb))
+
+	root := common.Hash{0x01}
+	res, err := engine.backend.ForkchoiceUpdatedV3(ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV3{Timestamp: parent.Time + 1, Withdrawals: types.Withdrawals{}, ParentBeaconBlockRoot: root},
+	)
+	require.NoError(t, err)
+	_, err = engine.backend.GetPayloadV3(ctx, *res.PayloadID)
+	require.Error(t, err)
+	envelope, err := engine.backend.GetPayloadV4(ctx, *res.PayloadID)
+	require.NoError(t, err)
+	require.NoError(t, envelope.ExecutionRequests.Validate())
+	require.Len(t, envelope.ExecutionRequests, 1)
+	require.Len(t, envelope.ExecutionRequests.Type(types.DepositRequestType), types.DepositRequestSize)
+
+	// requests are required, and must match the deposits of the payload
+	_, err = engine.backend.NewPayloadV4(ctx, envelope.ExecutionPayload, []common.Hash{}, &root, nil)
+	require.Error(t, err)
+	status, err := engine.backend.NewPayloadV4(ctx, envelope.ExecutionPayload, []common.Hash{}, &root, types.ExecutionRequests{})
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionInvalid, status.Status)
+	status, err = engine.backend.NewPayloadV4(ctx, envelope.ExecutionPayload, []common.Hash{}, &root, envelope.ExecutionRequests)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+}
+
+func TestQueuedRequests(t *testing.T) {
+	ctx := context.Background()
+	withdrawer, _ := crypto.GenerateKey()
+	consolidator, _ := crypto.GenerateKey()
+	accounts := []TestAccount{{withdrawer, crypto.PubkeyToAddress(withdrawer.PublicKey)}, {consolidator, crypto.PubkeyToAddress(consolidator.PublicKey)}}
+	genesis := newMergedGenesis(accounts[0].addr)
+	genesis.Alloc[accounts[1].addr] = genesis.Alloc[accounts[0].addr]
+	zero := uint64(0)
+	engine := newTestEngine(t, writeGenesis(t, genesis, ForkTimes{ShanghaiTime: &zero, CancunTime: &zero, PragueTime: &zero}))
+	chain := engine.mockChain()
+
+	// pool a withdrawal and a consolidation request, for the engine to include in the payload
+	parent := chain.CurrentHeader()
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	pk := types.PublicKey{0x42}
+	pubkey := func() (types.PublicKey, error) { return pk, nil }
+	withdrawal := withdrawalRequestTxCreator(pubkey)(chain.gspec.Config, chain.chain, statedb, parent, vm.Config{}, accounts[:1])
+	consolidation := consolidationRequestTxCreator(pubkey)(chain.gspec.Config, chain.chain, statedb, parent, vm.Config{}, accounts[1:])
+	require.Len(t, withdrawal, 1)
+	require.Len(t, consolidation, 1)
+	require.NoError(t, engine.backend.txPool.Add(withdrawal[0], statedb))
+	require.NoError(t, engine.backend.txPool.Add(consolidation[0], statedb))
+
+	root := common.Hash{0x01}
+	res, err := engine.backend.ForkchoiceUpdatedV3(ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV3{Timestamp: parent.Time + 1, Withdrawals: types.Withdrawals{}, ParentBeaconBlockRoot: root},
+	)
+	require.NoError(t, err)
+	envelope, err := engine.backend.GetPayloadV4(ctx, *res.PayloadID)
+	require.NoError(t, err)
+	require.NoError(t, envelope.ExecutionRequests.Validate())
+	var amount [8]byte
+	require.Equal(t, append(append(accounts[0].addr.Bytes(), pk[:]...), amount[:]...), []byte(envelope.ExecutionRequests.Type(types.WithdrawalRequestType)))
+	require.Equal(t, append(append(accounts[1].addr.Bytes(), pk[:]...), pk[:]...), []byte(envelope.ExecutionRequests.Type(types.ConsolidationRequestType)))
+
+	// requests must match the request transactions of the payload
+	status, err := engine.backend.NewPayloadV4(ctx, envelope.ExecutionPayload, []common.Hash{}, &root, envelope.ExecutionRequests[:1])
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionInvalid, status.Status)
+	status, err = engine.backend.NewPayloadV4(ctx, envelope.ExecutionPayload, []common.Hash{}, &root, envelope.ExecutionRequests)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+}
+
+func TestVersionedHashes(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	zero := uint64(0)
+	engine := newTestEngine(t, writeGenesis(t, newMergedGenesis(crypto.PubkeyToAddress(key.PublicKey)), ForkTimes{ShanghaiTime: &zero, CancunTime: &zero}))
+	chain := engine.mockChain()
+
+	parent := chain.CurrentHeader()
+	root := common.Hash{0x01}
+	res, err := engine.backend.ForkchoiceUpdatedV3(ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV3{Timestamp: parent.Time + 1, Withdrawals: types.Withdrawals{}, ParentBeaconBlockRoot: root},
+	)
+	require.NoError(t, err)
+	envelope, err := engine.backend.GetPayloadV3(ctx, *res.PayloadID)
+	require.NoError(t, err)
+
+	// the payload has no blob transactions, any hash is extra
+	status, err := engine.backend.NewPayloadV3(ctx, envelope.ExecutionPayload, []common.Hash{{0x01}}, &root)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionInvalid, status.Status)
+
+	status, err = engine.backend.NewPayloadV3(ctx, envelope.ExecutionPayload, []common.Hash{}, &root)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+}
+
+func TestStrictEngineParams(t *testing.T) {
+	engine := newTestEngine(t, newFundedGenesis(t, common.Address{}), func(c *EngineCmd) { c.StrictJSON = true })
+	head := engine.mockChain().CurrentHeader().Hash()
+
+	call := func(params string) map[string]json.RawMessage {
+		body := `{"jsonrpc":"2.0","id":1,"method":"engine_forkchoiceUpdatedV1","params":` + params + `}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Host = "127.0.0.1"
+		rr := httptest.NewRecorder()
+		engine.srv.Handler.ServeHTTP(rr, req)
+		var resp map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), rr.Body.String())
+		return resp
+	}
+	heads := fmt.Sprintf(`{"headBlockHash":"%s","safeBlockHash":"%s","finalizedBlockHash":"%s"`, head, head, head)
+
+	resp := call(`[` + heads + `}, null]`)
+	require.Contains(t, resp, "result")
+
+	for _, params := range []string{
+		`[` + heads + `,"extra":"0x"}, null]`,
+		`[` + heads + `}]`,
+		`[{"headBlockHash":"` + head.Hex() + `"}, null]`,
+		`[` + heads + `}, {"timestamp":"0x1","prevRandao":"0x01","suggestedFeeRecipient":"` + common.Address{}.Hex() + `"}]`,
+	} {
+		resp := call(params)
+		require.Contains(t, resp, "error", params)
+		var rpcErr struct{ Code int }
+		require.NoError(t, json.Unmarshal(resp["error"], &rpcErr))
+		require.Equal(t, int(api.InvalidParams), rpcErr.Code, params)
+	}
+}
+
+func TestWrongBaseFee(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	engine := newTestEngine(t, newFundedGenesis(t, sender))
+	chain := engine.mockChain()
+
+	parent := chain.CurrentHeader()
+	creator := TransactionsCreator{[]TestAccount{{key, sender}}, dummyTxCreator}
+	block, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, chain.ExpectedBaseFee(parent), block.BaseFee())
+
+	// a base fee off by one is invalid, even with a block hash to match
+	header := ethTypes.CopyHeader(block.Header())
+	header.BaseFee = new(big.Int).Add(header.BaseFee, common.Big1)
+	wrong, err := api.BlockToPayloadV3(ethTypes.NewBlockWithHeader(header).WithBody(block.Transactions(), nil), nil)
+	require.NoError(t, err)
+	status, err := engine.backend.NewPayloadV1(ctx, wrong.V1())
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionInvalid, status.Status)
+	require.Contains(t, status.ValidationError, "base fee difference")
+
+	payload, err := api.BlockToPayloadV3(block, nil)
+	require.NoError(t, err)
+	status, err = engine.backend.NewPayloadV1(ctx, payload.V1())
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+
+	// the consensus mock reports engines that accept the wrong base fee
+	c := newCheckpointConsensus(t)
+	c.ctx = ctx
+	c.report = newScenarioReport()
+	parent = c.mockChain.CurrentHeader()
+	block, err = c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &common.Hash{}, true)
+	require.NoError(t, err)
+	for i, status := range []types.ExecutePayloadStatus{types.ExecutionInvalid, types.ExecutionValid} {
+		srv := httptest.NewServer(&statusEngine{status: status})
+		c.engine = dialEngine(t, srv.URL)
+		c.mockWrongBaseFee(logrus.New(), uint64(i+1), block, types.Withdrawals{}, &common.Hash{})
+		srv.Close()
+	}
+	sc := c.report.cases[scenarioWrongBaseFee]
+	require.Equal(t, 2, sc.runs)
+	require.Len(t, sc.failures, 1)
+	require.Contains(t, sc.failures[0], "slot 2: engine accepted wrong base fee")
+}
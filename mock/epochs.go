@@ -0,0 +1,121 @@
+package mock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// EpochConfig configures what happens around the epoch boundaries of a consensus run. The
+// checkpoints move and the exits of validators take effect at the start of every epoch, which
+// clients historically get wrong when the chain reorgs at the same time.
+type EpochConfig struct {
+	Torture    bool    `ask:"--torture" help:"Cluster the reorgs around the epoch boundaries, and reorg the last blocks of an epoch away in the first slots of the next"`
+	Window     uint64  `ask:"--window" help:"Slots on either side of an epoch boundary that are around it, for the torture mode"`
+	Reorg      float64 `ask:"--reorg" help:"How often a slot around an epoch boundary reorgs in torture mode, instead of --freq.reorg"`
+	AlignForks bool    `ask:"--align-forks" help:"Delay the beacon genesis time so the next fork of the genesis activates at the first slot of an epoch, and warn about the forks that do not"`
+}
+
+func (e *EpochConfig) Default() {
+	e.Window = 2
+	e.Reorg = 0.5
+}
+
+// aroundBoundary returns whether the slot is within the window of an epoch boundary, and
+// whether it is after the boundary.
+func (c *ConsensusCmd) aroundBoundary(slot uint64) (around bool, after bool) {
+	pos := slot % c.SlotsPerEpoch
+	if pos < c.Epochs.Window {
+		return true, true
+	}
+	return pos+c.Epochs.Window >= c.SlotsPerEpoch, false
+}
+
+// reorgFreq returns how often the slot reorgs.
+func (c *ConsensusCmd) reorgFreq(slot uint64) float64 {
+	if around, _ := c.aroundBoundary(slot); c.Epochs.Torture && around {
+		return c.Epochs.Reorg
+	}
+	return c.Freq.ReorgFreq
+}
+
+// reorgTarget returns the block to build the block of the slot on instead of the head, no
+// lower than the block with number min. In torture mode, the first slots of an epoch build on a
+// block before the last slot of the previous epoch, so the blocks of the boundary are reorged
+// away with the justified checkpoint of the new epoch.
+func (c *ConsensusCmd) reorgTarget(chain *core.BlockChain, slot uint64, head *ethTypes.Header, min uint64) *ethTypes.Header {
+	around, after := c.aroundBoundary(slot)
+	if !c.Epochs.Torture || !around || !after {
+		return c.calcReorgTarget(chain, head.Number.Uint64(), min)
+	}
+	start := slot - slot%c.SlotsPerEpoch
+	depth := 2 + uint64(c.RNG.Intn(int(c.Epochs.Window)))
+	if start < depth {
+		return c.calcReorgTarget(chain, head.Number.Uint64(), min)
+	}
+	target := start - depth
+	parent := head
+	for parent.Number.Uint64() > min && c.blockSlot(parent) > target {
+		next := chain.GetHeader(parent.ParentHash, parent.Number.Uint64()-1)
+		if next == nil {
+			break
+		}
+		parent = next
+	}
+	// the checkpoint of the epoch moves to the parent if it is reorged away
+	if cp := chain.GetHeaderByHash(common.Hash(c.state.Justified().Root)); cp == nil || cp.Number.Uint64() > parent.Number.Uint64() {
+		c.state.MoveCheckpoint(parent.Hash())
+	}
+	return parent
+}
+
+// alignForks delays the beacon genesis time to the latest time before the next fork of the
+// genesis that starts an epoch, so the fork activates at the first slot of an epoch, if the
+// genesis time is not fixed otherwise. It warns about the forks that do not activate at an
+// epoch boundary.
+func (c *ConsensusCmd) alignForks(log logrus.Ext1FieldLogger) error {
+	if c.SlotTime%time.Second != 0 {
+		return fmt.Errorf("cannot align forks to epochs of %s slots", c.SlotTime)
+	}
+	epoch := c.SlotsPerEpoch * uint64(c.SlotTime/time.Second)
+	if epoch == 0 {
+		return fmt.Errorf("cannot align forks to empty epochs")
+	}
+	forks, err := LoadForkTimes(c.GenesisPath)
+	if err != nil {
+		return err
+	}
+	named := []struct {
+		name string
+		time *uint64
+	}{{"shanghai", forks.ShanghaiTime}, {"cancun", forks.CancunTime}, {"prague", forks.PragueTime}}
+	fixed := c.GenesisTimeFrom != "" || c.CheckpointSync.Chain != ""
+	for _, fork := range named {
+		if fork.time == nil || *fork.time <= c.BeaconGenesisTime {
+			continue
+		}
+		if fixed {
+			break
+		}
+		genesis := *fork.time - (*fork.time-c.BeaconGenesisTime)/epoch*epoch
+		if genesis != c.BeaconGenesisTime {
+			log.WithField("fork", fork.name).WithField("genesisTime", genesis).WithField("delay", genesis-c.BeaconGenesisTime).Info("Delayed the beacon genesis time to align the fork with an epoch")
+			c.BeaconGenesisTime = genesis
+		}
+		break
+	}
+	for _, fork := range named {
+		if fork.time == nil || *fork.time <= c.BeaconGenesisTime {
+			continue
+		}
+		if offset := (*fork.time - c.BeaconGenesisTime) % epoch; offset != 0 {
+			slot := (*fork.time - c.BeaconGenesisTime) / uint64(c.SlotTime/time.Second)
+			log.WithField("fork", fork.name).WithField("slot", slot).WithField("offset", offset).Warn("Fork does not activate at an epoch boundary")
+		}
+	}
+	return nil
+}
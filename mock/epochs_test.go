@@ -0,0 +1,81 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEpochTorture(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	c.RNG = NewRNG(1)
+	c.Freq.ReorgFreq = 0.05
+	c.ReorgMaxDepth = 2
+	c.Epochs = EpochConfig{Torture: true, Window: 1, Reorg: 1}
+
+	around, after := c.aroundBoundary(4)
+	require.True(t, around)
+	require.True(t, after)
+	around, after = c.aroundBoundary(3)
+	require.True(t, around)
+	require.False(t, after)
+	around, _ = c.aroundBoundary(5)
+	require.False(t, around)
+	require.Equal(t, 1.0, c.reorgFreq(8))
+	require.Equal(t, 1.0, c.reorgFreq(7))
+	require.Equal(t, 0.05, c.reorgFreq(6))
+
+	blocks := []*ethTypes.Header{c.mockChain.CurrentHeader()}
+	for slot := uint64(1); slot < 8; slot++ {
+		parent := c.mockChain.CurrentHeader()
+		c.state.ProcessSlot(slot, parent.Hash())
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.state.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &common.Hash{}, true)
+		require.NoError(t, err)
+		blocks = append(blocks, block.Header())
+	}
+	head := c.mockChain.CurrentHeader()
+	require.True(t, c.state.ProcessSlot(8, head.Hash()))
+	require.Equal(t, types.Root(blocks[7].Hash()), c.state.Justified().Root)
+
+	// the first slot of the epoch reorgs the last block of the previous epoch away, with the
+	// checkpoint of the epoch
+	parent := c.reorgTarget(c.mockChain.chain, 8, head, 0)
+	require.Equal(t, blocks[6].Hash(), parent.Hash())
+	require.Equal(t, types.Root(blocks[6].Hash()), c.state.Justified().Root)
+
+	// not below the finalized block
+	parent = c.reorgTarget(c.mockChain.chain, 8, head, 7)
+	require.Equal(t, head.Hash(), parent.Hash())
+
+	c.Epochs.Torture = false
+	require.Equal(t, 0.05, c.reorgFreq(8))
+}
+
+func TestAlignForks(t *testing.T) {
+	past, cancun, prague := uint64(500), uint64(1100), uint64(1150)
+	genesisPath := writeGenesis(t, newMergedGenesis(common.Address{}), ForkTimes{ShanghaiTime: &past, CancunTime: &cancun, PragueTime: &prague})
+	newCmd := func() *ConsensusCmd {
+		return &ConsensusCmd{GenesisPath: genesisPath, BeaconGenesisTime: 1000, SlotsPerEpoch: 4, SlotTime: 12 * time.Second}
+	}
+
+	// cancun activates at the start of epoch 2
+	c := newCmd()
+	require.NoError(t, c.alignForks(logrus.New()))
+	require.Equal(t, uint64(1004), c.BeaconGenesisTime)
+
+	// the genesis time of the engine is kept
+	c = newCmd()
+	c.GenesisTimeFrom = "0"
+	require.NoError(t, c.alignForks(logrus.New()))
+	require.Equal(t, uint64(1000), c.BeaconGenesisTime)
+
+	c = newCmd()
+	c.SlotTime = 1500 * time.Millisecond
+	require.Error(t, c.alignForks(logrus.New()))
+}
@@ -0,0 +1,257 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mergemock/rpc"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/node"
+	gethRpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxLogsBlockRange limits how many blocks a single eth_getLogs query may scan.
+const maxLogsBlockRange = 10_000
+
+type EthBackend struct {
+	chain  *core.BlockChain
+	txPool *TxPool
+}
+
+func NewEthBackend(chain *core.BlockChain, txPool *TxPool) *EthBackend {
+	return &EthBackend{
+		chain:  chain,
+		txPool: txPool,
+	}
+}
+func (b *EthBackend) Register(srv *rpc.Server) error {
+	srv.RegisterName("eth", b)
+	return node.RegisterApis([]rpc.API{
+		{
+			Namespace:     "eth",
+			Version:       "1.0",
+			Service:       b,
+			Public:        true,
+			Authenticated: false,
+		},
+	}, []string{"eth"}, srv, false)
+}
+
+// Based on https://github.com/ethereum/go-ethereum/blob/16701c51697e28986feebd122c6a491e4d9ac0e7/internal/ethapi/api.go#L1200
+func (b *EthBackend) rpcMarshalBlock(ctx context.Context, block *ethTypes.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	fields, err := types.RPCMarshalBlock(block, inclTx, fullTx, b.chain.Config())
+	if err != nil {
+		return nil, err
+	}
+	if inclTx {
+		fields["totalDifficulty"] = (*hexutil.Big)(b.chain.GetTd(block.Hash(), block.NumberU64()))
+	}
+	return fields, err
+}
+
+func (b *EthBackend) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	block := b.chain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, errors.New("unknown block")
+	}
+	return b.rpcMarshalBlock(ctx, block, true, fullTx)
+}
+
+func (b *EthBackend) GetBlockByNumber(ctx context.Context, number gethRpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	switch number {
+	case gethRpc.PendingBlockNumber:
+		return nil, errors.New("not implemented")
+	case gethRpc.LatestBlockNumber:
+		block := b.chain.CurrentBlock()
+		if block == nil {
+			block = b.chain.Genesis()
+		}
+		return b.rpcMarshalBlock(ctx, block, true, fullTx)
+	default:
+		block := b.chain.GetBlockByNumber(uint64(number))
+		if block == nil {
+			return nil, errors.New("unknown block")
+		}
+		return b.rpcMarshalBlock(ctx, block, true, fullTx)
+	}
+}
+
+// SendRawTransaction adds the signed transaction to the pool, to be included
+// in the next payload the engine builds.
+func (b *EthBackend) SendRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	if b.txPool == nil {
+		return common.Hash{}, errors.New("transaction pool not available")
+	}
+	tx := new(ethTypes.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	statedb, err := b.chain.State()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.txPool.Add(tx, statedb); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// SendBundleArgs are the arguments of eth_sendBundle, like those of the Flashbots RPC.
+type SendBundleArgs struct {
+	Txs         []hexutil.Bytes `json:"txs"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+}
+
+// SendBundleResult is the result of eth_sendBundle.
+type SendBundleResult struct {
+	BundleHash common.Hash `json:"bundleHash"`
+}
+
+// SendBundle adds the signed transactions to the pool as a bundle, to be included in order and
+// all together in the next payload the engine builds for the block number, or any block if zero.
+// The bundle hash is the hash of the hashes of the transactions.
+func (b *EthBackend) SendBundle(ctx context.Context, args SendBundleArgs) (*SendBundleResult, error) {
+	if b.txPool == nil {
+		return nil, errors.New("transaction pool not available")
+	}
+	txs := make([]*ethTypes.Transaction, len(args.Txs))
+	var hashes []byte
+	for i, input := range args.Txs {
+		txs[i] = new(ethTypes.Transaction)
+		if err := txs[i].UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("invalid bundle transaction %d: %w", i, err)
+		}
+		hashes = append(hashes, txs[i].Hash().Bytes()...)
+	}
+	statedb, err := b.chain.State()
+	if err != nil {
+		return nil, err
+	}
+	if err := b.txPool.AddBundle(txs, uint64(args.BlockNumber), statedb); err != nil {
+		return nil, err
+	}
+	return &SendBundleResult{BundleHash: crypto.Keccak256Hash(hashes)}, nil
+}
+
+// GetLogs returns the logs of canonical blocks matching the filter criteria.
+// The mock has no log index, every block in the range is scanned.
+func (b *EthBackend) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*ethTypes.Log, error) {
+	var blocks []*ethTypes.Block
+	if crit.BlockHash != nil {
+		block := b.chain.GetBlockByHash(*crit.BlockHash)
+		if block == nil {
+			return nil, errors.New("unknown block")
+		}
+		blocks = append(blocks, block)
+	} else {
+		head := b.chain.CurrentBlock().NumberU64()
+		from, to := head, head
+		if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+			from = crit.FromBlock.Uint64()
+		}
+		if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 {
+			to = crit.ToBlock.Uint64()
+		}
+		if to > head {
+			to = head
+		}
+		if from > to {
+			return []*ethTypes.Log{}, nil
+		}
+		if to-from >= maxLogsBlockRange {
+			return nil, fmt.Errorf("block range too large, max %d blocks", maxLogsBlockRange)
+		}
+		for n := from; n <= to; n++ {
+			block := b.chain.GetBlockByNumber(n)
+			if block == nil {
+				break
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	logs := []*ethTypes.Log{}
+	for _, block := range blocks {
+		if !bloomMatches(block.Bloom(), crit.Addresses, crit.Topics) {
+			continue
+		}
+		for _, receipt := range b.chain.GetReceiptsByHash(block.Hash()) {
+			for _, log := range receipt.Logs {
+				if logMatches(log, crit.Addresses, crit.Topics) {
+					logs = append(logs, log)
+				}
+			}
+		}
+	}
+	return logs, nil
+}
+
+// bloomMatches checks if the bloom may contain logs matching the addresses and topics.
+func bloomMatches(bloom ethTypes.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, addr := range addresses {
+			if ethTypes.BloomLookup(bloom, addr) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		found := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if ethTypes.BloomLookup(bloom, topic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatches checks the log against the addresses and the positional topic alternatives.
+func logMatches(log *ethTypes.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, addr := range addresses {
+			if log.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range sub {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
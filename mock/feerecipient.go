@@ -0,0 +1,170 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"mergemock/contracts"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+// Contracts the proposers can have as fee recipient.
+const (
+	recipientNone      = "none"
+	recipientAccepting = "accepting"
+	recipientReverting = "reverting"
+)
+
+// FeeRecipientConfig makes the fee recipient of the proposers a contract, like the multisigs and
+// fee splitters of staking pools, which the priority fees and builder payments go to.
+type FeeRecipientConfig struct {
+	Contract string `ask:"--contract" help:"Make the fee recipient of the proposers a contract the first test account deploys: 'none', 'accepting' for one that logs the value it receives, 'reverting' for one that reverts on receive, so last-tx builder payments to it fail"`
+}
+
+func (f *FeeRecipientConfig) Default() {
+	f.Contract = recipientNone
+}
+
+// code returns the creation code of the contract, nil if the fee recipient is no contract.
+func (f *FeeRecipientConfig) code() ([]byte, error) {
+	switch f.Contract {
+	case recipientNone:
+		return nil, nil
+	case recipientAccepting:
+		return contracts.RecipientCode, nil
+	case recipientReverting:
+		return contracts.RejecterCode, nil
+	default:
+		return nil, fmt.Errorf("unrecognized fee recipient contract: %q", f.Contract)
+	}
+}
+
+// recipientDeployment is the fee recipient contract, at the address of its last deployment, and
+// the fee recipient the validators registered with the builder last.
+type recipientDeployment struct {
+	mu         sync.Mutex
+	address    common.Address
+	registered common.Address
+}
+
+// txCreator deploys the fee recipient contract from the first test account before the
+// transactions of the tx profile, and again if it is missing from the state of the parent, after
+// a reorg.
+func (d *recipientDeployment) txCreator(code []byte, profile func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction) func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		if len(accounts) == 0 {
+			return nil
+		}
+		d.mu.Lock()
+		if d.address == (common.Address{}) || statedb.GetCodeSize(d.address) == 0 {
+			d.address = crypto.CreateAddress(accounts[0].addr, statedb.GetNonce(accounts[0].addr))
+			d.mu.Unlock()
+			return contractTx(config, statedb, accounts[0], nil, code, contractDeployGas)
+		}
+		d.mu.Unlock()
+		return profile(config, bc, statedb, header, cfg, accounts)
+	}
+}
+
+// feeRecipient returns the fee recipient contract if it is deployed in the state of the head of
+// the mock chain, and the fallback otherwise.
+func (c *ConsensusCmd) feeRecipient(fallback common.Address) common.Address {
+	if c.FeeRecipient.Contract == recipientNone || c.mockChain == nil {
+		return fallback
+	}
+	c.recipient.mu.Lock()
+	address := c.recipient.address
+	c.recipient.mu.Unlock()
+	if address == (common.Address{}) {
+		return fallback
+	}
+	statedb, err := c.mockChain.chain.StateAt(c.mockChain.CurrentHeader().Root)
+	if err != nil || statedb.GetCodeSize(address) == 0 {
+		return fallback
+	}
+	return address
+}
+
+// updateFeeRecipient registers the active validators with the builder again once their fee
+// recipient changes, to the contract after its deployment, or back after a reorg dropped it.
+func (c *ConsensusCmd) updateFeeRecipient(log logrus.Ext1FieldLogger) {
+	if c.FeeRecipient.Contract == recipientNone || c.builder == nil {
+		return
+	}
+	recipient := c.feeRecipient(registrationFeeRecipient)
+	c.recipient.mu.Lock()
+	registered := c.recipient.registered
+	if registered == (common.Address{}) {
+		registered = registrationFeeRecipient
+	}
+	c.recipient.registered = recipient
+	c.recipient.mu.Unlock()
+	if recipient == registered {
+		return
+	}
+	var validators []validator
+	for _, index := range c.state.ActiveValidators() {
+		validators = append(validators, c.validators[index])
+	}
+	log.WithField("feeRecipient", recipient).WithField("validators", len(validators)).Info("Registering validators with new fee recipient")
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		if err := c.registerValidators(validators); err != nil {
+			log.WithError(err).Error("Failed to register validators with new fee recipient")
+		}
+	}()
+}
+
+// checkFeeRecipient checks the engine credited the fee recipient contract of the block like the
+// mock chain did, the priority fees without calling it, and kept its code.
+func (c *ConsensusCmd) checkFeeRecipient(ctx context.Context, block *ethTypes.Block) error {
+	statedb, err := c.mockChain.chain.StateAt(block.Root())
+	if err != nil {
+		return err
+	}
+	number := hexutil.EncodeBig(block.Number())
+	var balance hexutil.Big
+	if err := c.engine.CallContext(ctx, &balance, "eth_getBalance", block.Coinbase(), number); err != nil {
+		return fmt.Errorf("balance of %s: %w", block.Coinbase(), err)
+	}
+	if balance.ToInt().Cmp(statedb.GetBalance(block.Coinbase())) != 0 {
+		return fmt.Errorf("balance of fee recipient contract %s is %s, expected %s", block.Coinbase(), balance.ToInt(), statedb.GetBalance(block.Coinbase()))
+	}
+	var code hexutil.Bytes
+	if err := c.engine.CallContext(ctx, &code, "eth_getCode", block.Coinbase(), number); err != nil {
+		return fmt.Errorf("code of %s: %w", block.Coinbase(), err)
+	}
+	if crypto.Keccak256Hash(code) != statedb.GetCodeHash(block.Coinbase()) {
+		return fmt.Errorf("code of fee recipient contract %s changed", block.Coinbase())
+	}
+	return nil
+}
+
+// reportFeeRecipient checks the block the engine accepted, if its fee recipient is the contract.
+func (c *ConsensusCmd) reportFeeRecipient(log logrus.Ext1FieldLogger, slot uint64, block *ethTypes.Block) {
+	if c.FeeRecipient.Contract == recipientNone || block.Coinbase() != c.feeRecipient(common.Address{}) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	err := c.checkFeeRecipient(ctx, block)
+	if err != nil && c.engineOffline(err) {
+		return
+	}
+	c.report.record(scenarioFeeRecipientContract, slot, err)
+	if err != nil {
+		log.WithError(err).Error("Engine did not credit fee recipient contract")
+		c.maybeExit()
+	}
+}
@@ -0,0 +1,51 @@
+package mock
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeRecipientContract(t *testing.T) {
+	for _, contract := range []string{recipientAccepting, recipientReverting} {
+		key, _ := crypto.GenerateKey()
+		sender := crypto.PubkeyToAddress(key.PublicKey)
+		engine := newTestEngine(t, newFundedGenesis(t, sender))
+		chain := engine.mockChain()
+		c := &ConsensusCmd{mockChain: chain}
+		c.FeeRecipient.Contract = contract
+		creator := TransactionsCreator{[]TestAccount{{key, sender}}, c.txCreatorFn()}
+
+		// the fee recipient is the contract once it is deployed in the head
+		parent := chain.CurrentHeader()
+		require.Equal(t, common.Address{1}, c.feeRecipient(common.Address{1}))
+		block, err := chain.AddNewBlock(parent.Hash(), c.feeRecipient(common.Address{1}), parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		receipts := chain.chain.GetReceiptsByHash(block.Hash())
+		require.Len(t, receipts, 1)
+		require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipts[0].Status)
+		address := receipts[0].ContractAddress
+		require.Equal(t, address, c.feeRecipient(common.Address{1}), contract)
+
+		// the contract gets the priority fees of the block without being called, even if it
+		// reverts on receive
+		before, err := chain.chain.StateAt(block.Root())
+		require.NoError(t, err)
+		block, err = chain.AddNewBlock(block.Hash(), c.feeRecipient(common.Address{1}), block.Time()+1, block.GasLimit(), creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		require.Equal(t, address, block.Coinbase())
+		receipts = chain.chain.GetReceiptsByHash(block.Hash())
+		require.Len(t, receipts, 1)
+		after, err := chain.chain.StateAt(block.Root())
+		require.NoError(t, err)
+		fees := new(big.Int).SetUint64(2 * receipts[0].GasUsed)
+		require.Equal(t, fees, new(big.Int).Sub(after.GetBalance(address), before.GetBalance(address)), contract)
+	}
+
+	_, err := (&FeeRecipientConfig{Contract: "multisig"}).code()
+	require.Error(t, err)
+}
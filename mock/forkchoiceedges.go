@@ -0,0 +1,120 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// forkchoiceEdgesDue returns whether the forkchoice edge cases are checked in the slot: once, in
+// the first slot with a block after genesis, as long as no epoch finalized yet.
+func (c *ConsensusCmd) forkchoiceEdgesDue(slot uint64) bool {
+	if !c.ForkchoiceEdges || c.edgesChecked || c.mockChain.CurrentHeader().Number.Sign() == 0 {
+		return false
+	}
+	c.edgesChecked = true
+	if final := c.state.Finalized(); final.Epoch > 0 {
+		c.slotLog(slot).WithField("epoch", final.Epoch).Warn("Finality already reached, skipping forkchoice edge cases")
+		return false
+	}
+	return true
+}
+
+// checkForkchoiceEdges sends the engine the forkchoice updates at the edges of the spec, before
+// finality: a zero finalized hash, the genesis block as head, which the engine must both accept
+// as valid, and a finalized block ahead of the head, which it must refuse with the invalid
+// forkchoice state error. The forkchoice of the engine is restored afterwards.
+func (c *ConsensusCmd) checkForkchoiceEdges(slot uint64) {
+	log := c.slotLog(slot)
+	head := c.mockChain.CurrentHeader()
+	genesis := c.mockChain.chain.Genesis()
+	defer func() {
+		if fc := c.forkchoice.get(); fc != nil {
+			if _, err := c.forkchoiceUpdated(c.engine, fc.HeadBlockHash, fc.SafeBlockHash, fc.FinalizedBlockHash, nil); err != nil {
+				log.WithError(err).Warn("Failed to restore forkchoice after edge cases")
+			}
+		}
+	}()
+
+	edges := []struct {
+		scenario string
+		check    func() error
+	}{
+		{scenarioForkchoiceZeroFinalized, func() error {
+			return expectValid(c.forkchoiceUpdated(c.engine, head.Hash(), common.Hash{}, common.Hash{}, nil))
+		}},
+		{scenarioForkchoiceGenesisHead, func() error {
+			return expectValid(c.forkchoiceUpdated(c.engine, genesis.Hash(), common.Hash{}, common.Hash{}, nil))
+		}},
+		{scenarioForkchoiceFinalizedAhead, func() error {
+			return c.checkFinalizedAhead(log, slot)
+		}},
+	}
+	for _, edge := range edges {
+		err := edge.check()
+		if err != nil && c.engineOffline(err) {
+			return
+		}
+		c.report.record(edge.scenario, slot, err)
+		if err != nil {
+			log.WithField("case", edge.scenario).WithError(err).Error("Engine failed forkchoice edge case")
+			c.maybeExit()
+			continue
+		}
+		log.WithField("case", edge.scenario).Info("Engine passed forkchoice edge case")
+	}
+}
+
+// checkFinalizedAhead sends the engine a child of the head, which stays out of the mock chain, and
+// a forkchoice update that keeps the head and finalizes the child.
+func (c *ConsensusCmd) checkFinalizedAhead(log logrus.Ext1FieldLogger, slot uint64) error {
+	head := c.mockChain.CurrentHeader()
+	timestamp := c.SlotTimestamp(slot + 1)
+	withdrawals := c.makeWithdrawals(timestamp)
+	beaconRoot := c.makeBeaconRoot(timestamp)
+	block, err := c.mockChain.AddNewBlock(head.Hash(), common.Address{1}, timestamp, head.GasLimit, TransactionsCreator{nil, dummyTxCreator},
+		common.Hash(c.state.RandaoMix()), []byte("finalized ahead of head"), nil, withdrawals, beaconRoot, false)
+	if err != nil {
+		return fmt.Errorf("failed to build child of head: %w", err)
+	}
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	res, err := c.newPayload(withFault(ctx), log, payload, beaconRoot, nil)
+	if err != nil {
+		return fmt.Errorf("new payload of child of head: %w", err)
+	}
+	if res.Status == types.ExecutionInvalid {
+		return fmt.Errorf("engine considers child of head invalid: %s", res.ValidationError)
+	}
+	result, err := c.forkchoiceUpdated(c.engine, head.Hash(), head.Hash(), block.Hash(), nil)
+	if err == nil {
+		return fmt.Errorf("engine returned %s for finalized block ahead of head, expected error %d", result.PayloadStatus.Status, api.InvalidForkchoiceState)
+	}
+	var rpcErr interface{ ErrorCode() int }
+	if !errors.As(err, &rpcErr) || api.ErrorCode(rpcErr.ErrorCode()) != api.InvalidForkchoiceState {
+		return fmt.Errorf("engine errored on finalized block ahead of head, expected error %d: %w", api.InvalidForkchoiceState, err)
+	}
+	return nil
+}
+
+// expectValid returns an error unless the engine answered the forkchoice update with VALID.
+func expectValid(result types.ForkchoiceUpdatedResult, err error) error {
+	if err != nil {
+		return fmt.Errorf("engine errored, expected VALID: %w", err)
+	}
+	if result.PayloadStatus.Status != types.ExecutionValid {
+		return fmt.Errorf("engine returned %s, expected VALID", result.PayloadStatus.Status)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// specEngine refuses forkchoice updates with a finalized block that is neither zero nor canonical
+// with the invalid forkchoice state error, and accepts every other call.
+type specEngine struct {
+	fakeEngine
+	canonical func(hash common.Hash) bool
+}
+
+func (e *specEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e.mu.Lock()
+	e.methods = append(e.methods, req.Method)
+	e.mu.Unlock()
+	status := types.PayloadStatusV1{Status: types.ExecutionValid}
+	var result interface{} = status
+	if strings.HasPrefix(req.Method, "engine_forkchoiceUpdated") {
+		var state types.ForkchoiceStateV1
+		if err := json.Unmarshal(req.Params[0], &state); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if final := state.FinalizedBlockHash; final != (common.Hash{}) && !e.canonical(final) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": api.InvalidForkchoiceState, "message": "Invalid forkchoice state"}})
+			return
+		}
+		result = types.ForkchoiceUpdatedResult{PayloadStatus: status}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestForkchoiceEdges(t *testing.T) {
+	for _, spec := range []bool{true, false} {
+		c := newCheckpointConsensus(t)
+		c.ctx = context.Background()
+		c.report = newScenarioReport()
+		c.RNG = NewRNG(1)
+		c.ForkchoiceEdges = true
+		var handler http.Handler = new(fakeEngine)
+		if spec {
+			handler = &specEngine{canonical: func(hash common.Hash) bool {
+				header := c.mockChain.chain.GetHeaderByHash(hash)
+				return header != nil && c.mockChain.chain.GetHeaderByNumber(header.Number.Uint64()).Hash() == hash
+			}}
+		}
+		srv := httptest.NewServer(handler)
+		c.engine = dialEngine(t, srv.URL)
+
+		// not before a block after genesis, and only once
+		require.False(t, c.forkchoiceEdgesDue(1))
+		parent := c.mockChain.CurrentHeader()
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.SlotTimestamp(1), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, c.makeBeaconRoot(c.SlotTimestamp(1)), true)
+		require.NoError(t, err)
+		require.True(t, c.forkchoiceEdgesDue(2))
+		require.False(t, c.forkchoiceEdgesDue(3))
+
+		c.forkchoice.set(types.ForkchoiceStateV1{HeadBlockHash: block.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()})
+		c.checkForkchoiceEdges(2)
+		srv.Close()
+
+		for _, scenario := range []string{scenarioForkchoiceZeroFinalized, scenarioForkchoiceGenesisHead, scenarioForkchoiceFinalizedAhead} {
+			require.Equal(t, 1, c.report.cases[scenario].runs)
+		}
+		require.Empty(t, c.report.cases[scenarioForkchoiceZeroFinalized].failures)
+		require.Empty(t, c.report.cases[scenarioForkchoiceGenesisHead].failures)
+		if spec {
+			require.Empty(t, c.report.cases[scenarioForkchoiceFinalizedAhead].failures)
+		} else {
+			require.Len(t, c.report.cases[scenarioForkchoiceFinalizedAhead].failures, 1)
+		}
+		// the child of the head stays out of the mock chain
+		require.Equal(t, block.Hash(), c.mockChain.CurrentHeader().Hash())
+	}
+}
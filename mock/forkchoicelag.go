@@ -0,0 +1,48 @@
+package mock
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// lagForkchoice returns the safe and finalized blocks of a forkchoice update to the head in the
+// slot, as --safe-lag-slots and --finalized-lag-epochs put them: the last ancestor of the head at
+// or before the slot that many slots back, and the first slot of the epoch that many epochs back.
+// Without a policy, or while a deep reorg is pending, they stay the safe and finalized blocks of
+// the beacon state.
+func (c *ConsensusCmd) lagForkchoice(head *ethTypes.Header, slot uint64, safe, final common.Hash) (common.Hash, common.Hash) {
+	if c.pendingDeepReorg() != nil {
+		return safe, final
+	}
+	if c.SafeLagSlots >= 0 {
+		var cutoff uint64
+		if lag := uint64(c.SafeLagSlots); lag < slot {
+			cutoff = slot - lag
+		}
+		safe = c.ancestorAt(head, c.SlotTimestamp(cutoff))
+	}
+	if c.FinalizedLagEpochs >= 0 {
+		var epoch uint64
+		if current, lag := c.state.EpochAt(slot), uint64(c.FinalizedLagEpochs); lag < current {
+			epoch = current - lag
+		}
+		final = c.ancestorAt(head, c.SlotTimestamp(epoch*c.SlotsPerEpoch))
+	}
+	return safe, final
+}
+
+// ancestorAt returns the last ancestor of the head, or the head itself, with a timestamp at or
+// before the timestamp, or the genesis block if there is none.
+func (c *ConsensusCmd) ancestorAt(head *ethTypes.Header, timestamp uint64) common.Hash {
+	if genesis := c.mockChain.chain.Genesis(); timestamp <= genesis.Time() {
+		return genesis.Hash()
+	}
+	for head.Time > timestamp && head.Number.Sign() > 0 {
+		parent := c.mockChain.chain.GetHeader(head.ParentHash, head.Number.Uint64()-1)
+		if parent == nil {
+			break
+		}
+		head = parent
+	}
+	return head.Hash()
+}
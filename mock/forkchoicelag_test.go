@@ -0,0 +1,61 @@
+package mock
+
+import (
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLagForkchoice(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	c.SafeLagSlots, c.FinalizedLagEpochs = -1, -1
+	genesis := c.mockChain.CurrentHeader()
+	// blocks in the slots of three epochs, but for the gap slot 9
+	blocks := map[uint64]*ethTypes.Header{0: genesis}
+	for slot := uint64(1); slot <= 12; slot++ {
+		if slot == 9 {
+			continue
+		}
+		parent := c.mockChain.CurrentHeader()
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &common.Hash{}, true)
+		require.NoError(t, err)
+		blocks[slot] = block.Header()
+	}
+	head := blocks[12]
+
+	// without a policy, the safe and finalized blocks of the beacon state
+	safe, final := c.lagForkchoice(head, 12, common.Hash{1}, common.Hash{2})
+	require.Equal(t, common.Hash{1}, safe)
+	require.Equal(t, common.Hash{2}, final)
+
+	// the safe block at the head, and finalized at the start of the epoch of the head
+	c.SafeLagSlots, c.FinalizedLagEpochs = 0, 0
+	safe, final = c.lagForkchoice(head, 12, common.Hash{1}, common.Hash{2})
+	require.Equal(t, head.Hash(), safe)
+	require.Equal(t, blocks[12].Hash(), final)
+
+	// the last block before a gap slot
+	c.SafeLagSlots, c.FinalizedLagEpochs = 3, 1
+	safe, final = c.lagForkchoice(head, 12, common.Hash{1}, common.Hash{2})
+	require.Equal(t, blocks[8].Hash(), safe)
+	require.Equal(t, blocks[8].Hash(), final)
+	safe, final = c.lagForkchoice(blocks[11], 11, common.Hash{1}, common.Hash{2})
+	require.Equal(t, blocks[8].Hash(), safe)
+	require.Equal(t, blocks[4].Hash(), final)
+
+	// lags longer than the chain never advance past genesis
+	c.SafeLagSlots, c.FinalizedLagEpochs = 100, 1_000_000
+	safe, final = c.lagForkchoice(head, 12, common.Hash{1}, common.Hash{2})
+	require.Equal(t, genesis.Hash(), safe)
+	require.Equal(t, genesis.Hash(), final)
+
+	// a pending deep reorg keeps the stalled finality of the beacon state
+	c.deep.fork = blocks[2]
+	safe, final = c.lagForkchoice(head, 12, common.Hash{1}, common.Hash{2})
+	require.Equal(t, common.Hash{1}, safe)
+	require.Equal(t, common.Hash{2}, final)
+}
@@ -0,0 +1,41 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// genesisTimeFromGenesis derives the beacon genesis time from the genesis block of the engine.
+const genesisTimeFromGenesis = "genesis"
+
+// engineBlockTimestamp returns the timestamp of the block of the engine with the number or
+// hash, or of its genesis block.
+func (c *ConsensusCmd) engineBlockTimestamp(ctx context.Context, block string) (uint64, error) {
+	var header *struct {
+		Timestamp hexutil.Uint64 `json:"timestamp"`
+	}
+	var err error
+	switch {
+	case block == genesisTimeFromGenesis:
+		err = c.engine.CallContext(ctx, &header, "eth_getBlockByNumber", "0x0", false)
+	case len(block) == 2+2*common.HashLength && block[:2] == "0x":
+		err = c.engine.CallContext(ctx, &header, "eth_getBlockByHash", common.HexToHash(block), false)
+	default:
+		number, perr := strconv.ParseUint(block, 10, 64)
+		if perr != nil {
+			return 0, fmt.Errorf("invalid anchor block %q: not %q, a block number or hash", block, genesisTimeFromGenesis)
+		}
+		err = c.engine.CallContext(ctx, &header, "eth_getBlockByNumber", hexutil.EncodeUint64(number), false)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("engine does not have block %s", block)
+	}
+	return uint64(header.Timestamp), nil
+}
@@ -0,0 +1,54 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+// timestampEngine is an engine that serves the timestamps of blocks by number or hash.
+type timestampEngine map[string]uint64
+
+func (e timestampEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Params []interface{}   `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var result interface{}
+	if timestamp, ok := e[req.Params[0].(string)]; ok {
+		result = map[string]interface{}{"timestamp": hexutil.Uint64(timestamp)}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestEngineBlockTimestamp(t *testing.T) {
+	anchor := common.Hash{7}
+	srv := httptest.NewServer(timestampEngine{"0x0": 1000, "0x10": 1192, anchor.Hex(): 1384})
+	defer srv.Close()
+	c := new(ConsensusCmd)
+	var err error
+	c.engine = dialEngine(t, srv.URL)
+
+	for block, want := range map[string]uint64{"genesis": 1000, "16": 1192, anchor.Hex(): 1384} {
+		timestamp, err := c.engineBlockTimestamp(context.Background(), block)
+		require.NoError(t, err, block)
+		require.Equal(t, want, timestamp, block)
+	}
+
+	_, err = c.engineBlockTimestamp(context.Background(), "17")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not have block 17")
+	_, err = c.engineBlockTimestamp(context.Background(), "latest")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid anchor block")
+}
@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// forkchoiceTags are the block tags of the engine that follow the forkchoice.
+var forkchoiceTags = []string{"latest", "safe", "finalized"}
+
+// forkchoiceDrift is a block tag of the engine that does not match the forkchoice it accepted.
+type forkchoiceDrift struct {
+	Tag        string
+	Advertised common.Hash
+	Engine     common.Hash
+}
+
+// advertisedForkchoice is the last forkchoice state the engine accepted, and whether the
+// engine diverged from it in the last check.
+type advertisedForkchoice struct {
+	mu       sync.Mutex
+	state    *types.ForkchoiceStateV1
+	diverged bool
+}
+
+func (f *advertisedForkchoice) set(state types.ForkchoiceStateV1) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state = &state
+}
+
+func (f *advertisedForkchoice) get() *types.ForkchoiceStateV1 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+// engineBlockHash returns the hash of the block of the engine with the tag, zero if none.
+func (c *ConsensusCmd) engineBlockHash(ctx context.Context, tag string) (common.Hash, error) {
+	var block *struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := c.engine.CallContext(ctx, &block, "eth_getBlockByNumber", tag, false); err != nil {
+		return common.Hash{}, err
+	}
+	if block == nil {
+		return common.Hash{}, nil
+	}
+	return block.Hash, nil
+}
+
+// checkForkchoice compares the latest, safe and finalized blocks of the engine with the last
+// forkchoice it accepted. Tags the forkchoice has not set yet are not compared.
+func (c *ConsensusCmd) checkForkchoice(ctx context.Context) ([]forkchoiceDrift, error) {
+	state := c.forkchoice.get()
+	if state == nil {
+		return nil, nil
+	}
+	advertised := []common.Hash{state.HeadBlockHash, state.SafeBlockHash, state.FinalizedBlockHash}
+	var drift []forkchoiceDrift
+	for i, tag := range forkchoiceTags {
+		if advertised[i] == (common.Hash{}) {
+			continue
+		}
+		hash, err := c.engineBlockHash(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		if hash != advertised[i] {
+			drift = append(drift, forkchoiceDrift{tag, advertised[i], hash})
+		}
+	}
+	return drift, nil
+}
+
+// reportForkchoiceDrift checks the forkchoice of the engine, and reports any drift. In runs
+// with a slot bound, it exits if the engine still diverges since the previous check, which
+// rules out forkchoice updates that were in flight.
+func (c *ConsensusCmd) reportForkchoiceDrift(slot uint64) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	log := c.slotLog(slot)
+	drift, err := c.checkForkchoice(ctx)
+	if err != nil {
+		log.WithError(err).Warn("Failed to check the forkchoice of the engine")
+		return
+	}
+	for _, d := range drift {
+		log.WithField("tag", d.Tag).WithField("advertised", d.Advertised).WithField("engine", d.Engine).Error("Engine diverged from the forkchoice")
+	}
+	c.forkchoice.mu.Lock()
+	persisted := c.forkchoice.diverged && len(drift) > 0
+	c.forkchoice.diverged = len(drift) > 0
+	c.forkchoice.mu.Unlock()
+	if persisted {
+		c.report.record(scenarioForkchoiceCheck, slot, fmt.Errorf("engine diverged from the forkchoice at %d tags since the previous check", len(drift)))
+		c.maybeExit()
+	} else {
+		c.report.record(scenarioForkchoiceCheck, slot, nil)
+	}
+}
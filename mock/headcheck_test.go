@@ -0,0 +1,62 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// tagEngine is an engine that serves eth_getBlockByNumber for block tags.
+type tagEngine map[string]common.Hash
+
+func (e tagEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Params []interface{}   `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var result interface{}
+	if hash, ok := e[req.Params[0].(string)]; ok {
+		result = map[string]interface{}{"hash": hash}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestCheckForkchoice(t *testing.T) {
+	engine := tagEngine{"latest": {3}, "safe": {2}}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c := new(ConsensusCmd)
+	var err error
+	c.engine = dialEngine(t, srv.URL)
+
+	// nothing advertised yet
+	drift, err := c.checkForkchoice(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, drift)
+
+	// the finalized block is not set yet
+	c.forkchoice.set(types.ForkchoiceStateV1{HeadBlockHash: common.Hash{3}, SafeBlockHash: common.Hash{2}})
+	drift, err = c.checkForkchoice(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, drift)
+
+	// the engine lags behind on the head, and misses the finalized block
+	c.forkchoice.set(types.ForkchoiceStateV1{HeadBlockHash: common.Hash{4}, SafeBlockHash: common.Hash{2}, FinalizedBlockHash: common.Hash{1}})
+	drift, err = c.checkForkchoice(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []forkchoiceDrift{
+		{Tag: "latest", Advertised: common.Hash{4}, Engine: common.Hash{3}},
+		{Tag: "finalized", Advertised: common.Hash{1}},
+	}, drift)
+}
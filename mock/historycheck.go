@@ -0,0 +1,151 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// HistoryCheck configures the audit of the historical blocks of the engine against the canonical
+// chain of the forkchoice updates it accepted, to detect silent rewrites of history.
+type HistoryCheck struct {
+	Slots  uint64 `ask:"--slots" help:"Compare the hashes of a sample of the canonical blocks of the engine with eth_getBlockByNumber every this many slots (0 to disable)"`
+	Sample uint64 `ask:"--sample" help:"Number of block heights to compare in each check, at random"`
+}
+
+func (h *HistoryCheck) Default() {
+	h.Sample = 8
+}
+
+// headerReader reads the headers of the mock chain.
+type headerReader interface {
+	GetHeaderByHash(hash common.Hash) *ethTypes.Header
+}
+
+// canonicalHashes is the canonical chain of the last forkchoice the engine accepted, by number.
+// It is kept apart from the database of the mock chain, which the audit must not trust.
+type canonicalHashes struct {
+	mu     sync.Mutex
+	hashes []common.Hash
+	head   common.Hash
+}
+
+// update sets the canonical chain to the ancestry of the head, from the head back to the first
+// block the chain already has. Blocks the mock chain does not know are left unset.
+func (h *canonicalHashes) update(chain headerReader, head common.Hash) {
+	header := chain.GetHeaderByHash(head)
+	if header == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.head = head
+	number := header.Number.Uint64()
+	if uint64(len(h.hashes)) > number+1 {
+		h.hashes = h.hashes[:number+1]
+	}
+	for uint64(len(h.hashes)) < number+1 {
+		h.hashes = append(h.hashes, common.Hash{})
+	}
+	for header != nil {
+		number := header.Number.Uint64()
+		hash := header.Hash()
+		if h.hashes[number] == hash {
+			break
+		}
+		h.hashes[number] = hash
+		if number == 0 {
+			break
+		}
+		header = chain.GetHeaderByHash(header.ParentHash)
+	}
+}
+
+// sample returns up to n known heights of the canonical chain at random, in order, with their
+// hashes, and the head they are the ancestry of.
+func (h *canonicalHashes) sample(rng RNG, n uint64) (map[uint64]common.Hash, common.Hash) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var known []uint64
+	for number, hash := range h.hashes {
+		if hash != (common.Hash{}) {
+			known = append(known, uint64(number))
+		}
+	}
+	rng.Shuffle(len(known), func(i, j int) { known[i], known[j] = known[j], known[i] })
+	if uint64(len(known)) > n {
+		known = known[:n]
+	}
+	heights := make(map[uint64]common.Hash, len(known))
+	for _, number := range known {
+		heights[number] = h.hashes[number]
+	}
+	return heights, h.head
+}
+
+func (h *canonicalHashes) currentHead() common.Hash {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.head
+}
+
+// historyMismatch is a height at which the engine has another block than the canonical chain,
+// zero if it has none.
+type historyMismatch struct {
+	Number   uint64
+	Expected common.Hash
+	Engine   common.Hash
+}
+
+// checkHistory compares the blocks of the engine at a sample of heights with the canonical chain.
+// Mismatches are dropped if the engine accepted another head during the check, which may have
+// reorged them legitimately.
+func (c *ConsensusCmd) checkHistory(ctx context.Context) ([]historyMismatch, error) {
+	heights, head := c.canonical.sample(c.RNG, c.History.Sample)
+	numbers := make([]uint64, 0, len(heights))
+	for number := range heights {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	var mismatches []historyMismatch
+	for _, number := range numbers {
+		hash, err := c.engineBlockHash(ctx, hexutil.EncodeUint64(number))
+		if err != nil {
+			return nil, err
+		}
+		if hash != heights[number] {
+			mismatches = append(mismatches, historyMismatch{number, heights[number], hash})
+		}
+	}
+	if len(mismatches) > 0 && c.canonical.currentHead() != head {
+		return nil, nil
+	}
+	return mismatches, nil
+}
+
+// reportHistory audits the history of the engine, and reports the heights it rewrote.
+func (c *ConsensusCmd) reportHistory(slot uint64) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	log := c.slotLog(slot)
+	mismatches, err := c.checkHistory(ctx)
+	if err != nil {
+		log.WithError(err).Warn("Failed to check the history of the engine")
+		return
+	}
+	for _, m := range mismatches {
+		log.WithField("number", m.Number).WithField("expected", m.Expected).WithField("engine", m.Engine).Error("Engine rewrote a canonical block")
+	}
+	if len(mismatches) > 0 {
+		c.report.record(scenarioHistoryCheck, slot, fmt.Errorf("engine has other blocks than the canonical chain at %d heights, first %d", len(mismatches), mismatches[0].Number))
+		c.maybeExit()
+		return
+	}
+	c.report.record(scenarioHistoryCheck, slot, nil)
+}
@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryCheck(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	c.RNG = NewRNG(1)
+	c.History.Default()
+	addBlocks := func(parent *ethTypes.Header, n int, coinbase common.Address) []*ethTypes.Header {
+		var headers []*ethTypes.Header
+		for i := 0; i < n; i++ {
+			block, err := c.mockChain.AddNewBlock(parent.Hash(), coinbase, parent.Time+1, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, nil, &common.Hash{}, true)
+			require.NoError(t, err)
+			parent = block.Header()
+			headers = append(headers, parent)
+		}
+		return headers
+	}
+	genesis := c.mockChain.CurrentHeader()
+	chain := addBlocks(genesis, 5, common.Address{1})
+	fork := addBlocks(chain[1], 2, common.Address{2})
+
+	// a reorg replaces the blocks after the common ancestor, and drops the ones above the head
+	c.canonical.update(c.mockChain.chain, chain[4].Hash())
+	require.Len(t, c.canonical.hashes, 6)
+	c.canonical.update(c.mockChain.chain, fork[1].Hash())
+	require.Equal(t, []common.Hash{genesis.Hash(), chain[0].Hash(), chain[1].Hash(), fork[0].Hash(), fork[1].Hash()}, c.canonical.hashes)
+	c.canonical.update(c.mockChain.chain, common.Hash{0x01})
+	require.Equal(t, fork[1].Hash(), c.canonical.currentHead())
+	heights, head := c.canonical.sample(c.RNG, 3)
+	require.Len(t, heights, 3)
+	require.Equal(t, fork[1].Hash(), head)
+
+	// the engine rewrote a block under the head, and misses another
+	engine := tagEngine{}
+	for number, hash := range c.canonical.hashes {
+		engine[hexutil.EncodeUint64(uint64(number))] = hash
+	}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c.engine = dialEngine(t, srv.URL)
+	c.History.Sample = 10
+	mismatches, err := c.checkHistory(c.ctx)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+	c.reportHistory(1)
+
+	engine["0x1"] = common.Hash{0x02}
+	delete(engine, "0x3")
+	mismatches, err = c.checkHistory(c.ctx)
+	require.NoError(t, err)
+	require.Equal(t, []historyMismatch{
+		{Number: 1, Expected: chain[0].Hash(), Engine: common.Hash{0x02}},
+		{Number: 3, Expected: fork[0].Hash()},
+	}, mismatches)
+	c.reportHistory(2)
+	sc := c.report.cases[scenarioHistoryCheck]
+	require.Equal(t, 2, sc.runs)
+	require.Len(t, sc.failures, 1)
+	require.Contains(t, sc.failures[0], "slot 2: engine has other blocks than the canonical chain at 2 heights, first 1")
+}
@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"mergemock/beaconstate"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// journal is the state of a run of the consensus mock, which a restarted mock resumes from,
+// with the same datadir and engine. The chain itself is in the datadir.
+type journal struct {
+	GenesisTime     uint64               `json:"genesisTime"`
+	TransitionBlock uint64               `json:"transitionBlock"`
+	Head            common.Hash          `json:"head"`
+	State           beaconstate.Snapshot `json:"state"`
+	Deposits        uint64               `json:"deposits"`
+	RNG             RNGState             `json:"rng"`
+}
+
+// readJournal reads the journal, which is nil if there is none yet.
+func readJournal(path string) (*journal, error) {
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+	var j journal
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return nil, fmt.Errorf("invalid journal: %v", err)
+	}
+	return &j, nil
+}
+
+// writeJournal replaces the journal, without leaving a partially written one on a crash.
+func writeJournal(path string, j *journal) error {
+	buf, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// journalSlot records the state of the run at the start of the slot, if journaling.
+func (c *ConsensusCmd) journalSlot(transitionBlock uint64) {
+	if c.JournalPath == "" {
+		return
+	}
+	c.deposits.mu.Lock()
+	deposits := c.deposits.included
+	c.deposits.mu.Unlock()
+	j := &journal{
+		GenesisTime:     c.BeaconGenesisTime,
+		TransitionBlock: transitionBlock,
+		Head:            c.mockChain.CurrentHeader().Hash(),
+		State:           c.state.Snapshot(),
+		Deposits:        deposits,
+		RNG:             c.RNG.State(),
+	}
+	if err := writeJournal(c.JournalPath, j); err != nil {
+		c.log.WithError(err).Error("Failed to journal slot")
+	}
+}
+
+// resume returns the run to the state of the journal. The head of the journal must be in the
+// mock chain of the datadir.
+func (c *ConsensusCmd) resume(j *journal) error {
+	if c.mockChain.chain.GetHeaderByHash(j.Head) == nil {
+		return fmt.Errorf("journal head %s is not in the datadir", j.Head)
+	}
+	c.state.Restore(j.State)
+	c.deposits.mu.Lock()
+	c.deposits.included = j.Deposits
+	c.deposits.mu.Unlock()
+	c.RNG.Restore(j.RNG)
+	return nil
+}
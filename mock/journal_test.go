@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"fmt"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRNGRestore(t *testing.T) {
+	rng := NewRNG(5)
+	rng.Float64()
+	rng.Intn(10)
+	var buf [35]byte
+	rng.Read(buf[:])
+	state := rng.State()
+	require.Equal(t, RNGState{Seed: 5, Draws: 7}, state)
+
+	restored := NewRNG(1)
+	restored.Restore(state)
+	require.Equal(t, rng.Float64(), restored.Float64())
+	var a, b [32]byte
+	rng.Read(a[:])
+	restored.Read(b[:])
+	require.Equal(t, a, b)
+	require.Equal(t, "5", restored.String())
+}
+
+func TestJournal(t *testing.T) {
+	path := fmt.Sprintf("%s/journal.json", t.TempDir())
+	j, err := readJournal(path)
+	require.NoError(t, err)
+	require.Nil(t, j)
+
+	c := newCheckpointConsensus(t)
+	c.JournalPath = path
+	c.RNG = NewRNG(3)
+	c.RNG.Float64()
+	head := c.mockChain.CurrentHeader().Hash()
+	c.state.ProcessSlot(5, head)
+	c.state.ProcessRandao(types.Signature{0x01})
+	c.deposits.included = 2
+	c.journalSlot(7)
+
+	// a restarted mock with the same datadir resumes from the journal
+	resumed := newCheckpointConsensus(t)
+	resumed.mockChain = c.mockChain
+	j, err = readJournal(path)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), j.TransitionBlock)
+	require.Equal(t, head, j.Head)
+	require.NoError(t, resumed.resume(j))
+	require.Equal(t, c.state.Snapshot(), resumed.state.Snapshot())
+	require.Equal(t, c.RNG.State(), resumed.RNG.State())
+	require.Equal(t, uint64(2), resumed.deposits.included)
+
+	// not with another chain
+	other := newCheckpointConsensus(t)
+	j.Head = common.Hash{0x01}
+	require.Error(t, other.resume(j))
+}
@@ -0,0 +1,158 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mergemock/keystore"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+)
+
+// KeysCmd writes EIP-2335 keystores of validator keys, for the consensus mock and standard
+// validator tooling to share.
+type KeysCmd struct {
+	Out          string `ask:"--out" help:"Directory to write the keystores to"`
+	Count        uint32 `ask:"--count" help:"Number of validator keys"`
+	Start        uint32 `ask:"--start" help:"Index of the first validator key derived from the mnemonic"`
+	MnemonicPath string `ask:"--mnemonic" help:"File with a BIP-39 mnemonic to derive the keys from, as EIP-2334 signing keys (empty for random keys)"`
+	PasswordPath string `ask:"--password-file" help:"File with the password to encrypt the keystores with"`
+	KDF          string `ask:"--kdf" help:"Key derivation function of the keystores: 'scrypt' or 'pbkdf2'"`
+
+	out io.Writer
+}
+
+func (c *KeysCmd) Default() {
+	c.Out = "validator_keys"
+	c.Count = 1
+	c.PasswordPath = "keystore-password.txt"
+	c.KDF = keystore.KDFScrypt
+}
+
+func (c *KeysCmd) Help() string {
+	return "Write EIP-2335 keystores of random validator keys, or of keys derived from a BIP-39 mnemonic, for the consensus mock to load with --keys.keystores."
+}
+
+func (c *KeysCmd) Examples() []string {
+	return []string{
+		"mergemock keys --count=64 --password-file=keystore-password.txt --out=validator_keys",
+		"mergemock keys --mnemonic=mnemonic.txt --start=0 --count=64 --kdf=pbkdf2",
+	}
+}
+
+func (c *KeysCmd) Run(ctx context.Context, args ...string) error {
+	out := c.out
+	if out == nil {
+		out = os.Stdout
+	}
+	password, err := readSecretFile(c.PasswordPath)
+	if err != nil {
+		return fmt.Errorf("failed to read keystore password: %w", err)
+	}
+	var seed []byte
+	if c.MnemonicPath != "" {
+		mnemonic, err := readSecretFile(c.MnemonicPath)
+		if err != nil {
+			return fmt.Errorf("failed to read mnemonic: %w", err)
+		}
+		seed = keystore.Seed(mnemonic, "")
+	}
+	if err := os.MkdirAll(c.Out, 0700); err != nil {
+		return err
+	}
+	for i := c.Start; i < c.Start+c.Count; i++ {
+		var (
+			sk   bls.SecretKey
+			path string
+			name string
+		)
+		if seed != nil {
+			path = keystore.SigningKeyPath(i)
+			if sk, err = keystore.DeriveKey(seed, path); err != nil {
+				return err
+			}
+			// the file names of the deposit CLI, without the timestamp
+			name = fmt.Sprintf("keystore-%s.json", strings.ReplaceAll(path, "/", "_"))
+		} else {
+			if sk, err = bls.RandKey(); err != nil {
+				return fmt.Errorf("unable to generate bls key pair: %w", err)
+			}
+			name = fmt.Sprintf("keystore-%06d.json", i)
+		}
+		ks, err := keystore.Encrypt(sk, password, path, c.KDF)
+		if err != nil {
+			return err
+		}
+		if err := ks.Save(filepath.Join(c.Out, name)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "0x%s %s\n", ks.Pubkey, name)
+	}
+	return nil
+}
+
+// readSecretFile reads a password or mnemonic, without the surrounding whitespace.
+func readSecretFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// KeysConfig configures the keys of the validators of the consensus mock.
+type KeysConfig struct {
+	Keystores    string `ask:"--keystores" help:"Directory of EIP-2335 keystores of the validators, like those of the keys command (empty for random keys)"`
+	PasswordPath string `ask:"--password-file" help:"File with the password of the keystores"`
+	MnemonicPath string `ask:"--mnemonic" help:"File with a BIP-39 mnemonic to derive the keys of the validators from, as EIP-2334 signing keys (empty for random keys)"`
+}
+
+func (k *KeysConfig) Default() {
+	k.PasswordPath = "keystore-password.txt"
+}
+
+// secretKeys returns the keys of the count validators, from the keystores or the mnemonic, or
+// nil for random keys.
+func (k *KeysConfig) secretKeys(count uint64) ([]bls.SecretKey, error) {
+	switch {
+	case k.Keystores != "" && k.MnemonicPath != "":
+		return nil, fmt.Errorf("validator keys are either loaded from keystores or derived from a mnemonic, not both")
+	case k.Keystores != "":
+		keystores, err := keystore.LoadDir(k.Keystores)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(keystores)) < count {
+			return nil, fmt.Errorf("%d validators need %d keystores, %s has %d", count, count, k.Keystores, len(keystores))
+		}
+		password, err := readSecretFile(k.PasswordPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore password: %w", err)
+		}
+		keys := make([]bls.SecretKey, count)
+		for i := range keys {
+			if keys[i], err = keystores[i].Decrypt(password); err != nil {
+				return nil, err
+			}
+		}
+		return keys, nil
+	case k.MnemonicPath != "":
+		mnemonic, err := readSecretFile(k.MnemonicPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mnemonic: %w", err)
+		}
+		seed := keystore.Seed(mnemonic, "")
+		keys := make([]bls.SecretKey, count)
+		for i := range keys {
+			if keys[i], err = keystore.DeriveKey(seed, keystore.SigningKeyPath(uint32(i))); err != nil {
+				return nil, err
+			}
+		}
+		return keys, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mergemock/keystore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestKeysCmd(t *testing.T) {
+	mnemonic := writeFile(t, "mnemonic.txt", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about\n")
+	password := writeFile(t, "password.txt", "password\n")
+	var out bytes.Buffer
+	cmd := &KeysCmd{out: &out}
+	cmd.Default()
+	cmd.Out = t.TempDir()
+	cmd.Count = 2
+	cmd.MnemonicPath = mnemonic
+	cmd.PasswordPath = password
+	cmd.KDF = keystore.KDFPBKDF2
+	require.NoError(t, cmd.Run(context.Background()))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasSuffix(lines[1], " keystore-m_12381_3600_1_0_0.json"))
+
+	// the keystores hold the keys derived from the mnemonic
+	fromKeystores, err := (&KeysConfig{Keystores: cmd.Out, PasswordPath: password}).secretKeys(2)
+	require.NoError(t, err)
+	fromMnemonic, err := (&KeysConfig{MnemonicPath: mnemonic}).secretKeys(2)
+	require.NoError(t, err)
+	for i := range fromKeystores {
+		require.Equal(t, fromMnemonic[i].Marshal(), fromKeystores[i].Marshal())
+		require.Equal(t, "0x"+hex.EncodeToString(fromMnemonic[i].PublicKey().Marshal()), strings.Fields(lines[i])[0])
+	}
+
+	_, err = (&KeysConfig{Keystores: cmd.Out, PasswordPath: password}).secretKeys(3)
+	require.Error(t, err)
+	_, err = (&KeysConfig{Keystores: cmd.Out, PasswordPath: password, MnemonicPath: mnemonic}).secretKeys(1)
+	require.Error(t, err)
+	keys, err := (&KeysConfig{}).secretKeys(1)
+	require.NoError(t, err)
+	require.Nil(t, keys)
+}
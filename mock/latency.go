@@ -0,0 +1,35 @@
+package mock
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EngineLatency configures how the engine mock delays its answers to the engine API calls of
+// the consensus client, like an execution client under load.
+type EngineLatency struct {
+	Freq float64       `ask:"--freq" help:"How often an engine_newPayload, engine_forkchoiceUpdated or engine_getPayload call is answered late"`
+	Min  time.Duration `ask:"--min" help:"Shortest delay of a late answer"`
+	Max  time.Duration `ask:"--max" help:"Longest delay of a late answer, the delays are uniform between --latency.min and this"`
+	RNG  RNG           `ask:"--rng" help:"seed the RNG of the latency with an integer number"`
+}
+
+func (l *EngineLatency) Default() {
+	l.Min = 500 * time.Millisecond
+	l.Max = 2 * time.Second
+	l.RNG = NewRNG(DefaultRNGSeed)
+}
+
+// wait delays the answer to the engine call, if it is one of the late ones.
+func (l *EngineLatency) wait(log logrus.Ext1FieldLogger, method string) {
+	if l.Freq == 0 || l.RNG.Float64() >= l.Freq {
+		return
+	}
+	delay := l.Min
+	if l.Max > l.Min {
+		delay += time.Duration(l.RNG.Int63n(int64(l.Max - l.Min)))
+	}
+	log.WithField("method", method).WithField("delay", delay).Debug("Delaying engine answer")
+	time.Sleep(delay)
+}
@@ -0,0 +1,256 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"mergemock/types"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/gorilla/mux"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// generalized indices of the finalized checkpoint root and the next sync committee in the
+	// altair beacon state, which the branches of light client updates prove
+	finalizedRootGindex     = 105
+	nextSyncCommitteeGindex = 55
+
+	// fork of the light client data, which has the altair light client header up to bellatrix
+	lightClientVersion = "bellatrix"
+
+	// maxLightClientUpdates is the most updates a request by sync committee period returns.
+	maxLightClientUpdates = 128
+
+	pathLightClientUpdates          = "/eth/v1/beacon/light_client/updates"
+	pathLightClientFinalityUpdate   = "/eth/v1/beacon/light_client/finality_update"
+	pathLightClientOptimisticUpdate = "/eth/v1/beacon/light_client/optimistic_update"
+)
+
+// lightClientStore keeps the latest light client data of the mock chain, to serve over the beacon API.
+type lightClientStore struct {
+	mu         sync.RWMutex
+	optimistic *types.LightClientOptimisticUpdate
+	finality   *types.LightClientFinalityUpdate
+	// latest update by sync committee period of the attested header
+	updates map[uint64]*types.LightClientUpdate
+}
+
+func (s *lightClientStore) store(period uint64, update *types.LightClientUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.updates == nil {
+		s.updates = make(map[uint64]*types.LightClientUpdate)
+	}
+	s.updates[period] = update
+	s.optimistic = &types.LightClientOptimisticUpdate{
+		AttestedHeader: update.AttestedHeader,
+		SyncAggregate:  update.SyncAggregate,
+		SignatureSlot:  update.SignatureSlot,
+	}
+	s.finality = &types.LightClientFinalityUpdate{
+		AttestedHeader:  update.AttestedHeader,
+		FinalizedHeader: update.FinalizedHeader,
+		FinalityBranch:  update.FinalityBranch,
+		SyncAggregate:   update.SyncAggregate,
+		SignatureSlot:   update.SignatureSlot,
+	}
+}
+
+// sparseTree is a merkle tree of which only some leaves are known, by generalized index. The
+// nodes without known leaves below them are zero.
+type sparseTree map[uint64]types.Root
+
+func (t sparseTree) node(gindex uint64) types.Root {
+	if leaf, ok := t[gindex]; ok {
+		return leaf
+	}
+	for leaf := range t {
+		for g := leaf; g >= gindex; g >>= 1 {
+			if g == gindex {
+				return hashPair(t.node(2*gindex), t.node(2*gindex+1))
+			}
+		}
+	}
+	return types.Root{}
+}
+
+// branch returns the proof of the node at the generalized index, from the bottom up.
+func (t sparseTree) branch(gindex uint64) []types.Root {
+	var branch []types.Root
+	for g := gindex; g > 1; g >>= 1 {
+		branch = append(branch, t.node(g^1))
+	}
+	return branch
+}
+
+// blockSlot returns the slot of an execution block, by its timestamp.
+func (c *ConsensusCmd) blockSlot(header *ethTypes.Header) uint64 {
+	return c.timestampSlot(header.Time)
+}
+
+// standInHeader returns the beacon block header standing in for an execution block, which
+// the mock chain has no beacon block for. The parent and body roots are the execution block
+// hashes of the parent and the block.
+func (c *ConsensusCmd) standInHeader(header *ethTypes.Header, stateRoot types.Root) *types.BeaconBlockHeader {
+	slot := c.blockSlot(header)
+	return &types.BeaconBlockHeader{
+		Slot:          slot,
+		ProposerIndex: uint64(c.state.Proposer(slot)),
+		ParentRoot:    types.Root(header.ParentHash),
+		StateRoot:     stateRoot,
+		BodyRoot:      types.Root(header.Hash()),
+	}
+}
+
+// nextSyncCommittee returns the sync committee of the period after the one of the slot.
+func (c *ConsensusCmd) nextSyncCommittee(slot uint64) *types.SyncCommittee {
+	committee := &types.SyncCommittee{Pubkeys: make([]types.PublicKey, types.SyncCommitteeSize)}
+	pubkeys := make([]bls.PublicKey, types.SyncCommitteeSize)
+	for i, idx := range c.syncCommittee(slot + c.SlotsPerEpoch*epochsPerSyncCommitteePeriod) {
+		committee.Pubkeys[i] = c.validators[idx].pk
+		pubkeys[i] = c.validators[idx].publicKey()
+	}
+	committee.AggregatePubkey.FromSlice(bls.AggregateMultiplePubkeys(pubkeys).Marshal())
+	return committee
+}
+
+// updateLightClient makes the light client update of the slot, of the sync committee of the slot
+// attesting to the block the slot builds on. The state root of the attested header is the root
+// of a tree of just the finalized header and the next sync committee, for the branches to prove.
+func (c *ConsensusCmd) updateLightClient(slot uint64, attested *ethTypes.Header) error {
+	if len(c.validators) == 0 {
+		return fmt.Errorf("light client data requires validators")
+	}
+	finalizedHeader := c.mockChain.chain.GetHeaderByHash(common.Hash(c.state.Finalized().Root))
+	if finalizedHeader == nil {
+		finalizedHeader = c.mockChain.chain.Genesis().Header()
+	}
+	finalized := c.standInHeader(finalizedHeader, types.Root(finalizedHeader.Root))
+	finalizedRoot, err := finalized.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	attestedSlot := c.blockSlot(attested)
+	committee := c.nextSyncCommittee(attestedSlot)
+	committeeRoot, err := committee.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	tree := sparseTree{finalizedRootGindex: finalizedRoot, nextSyncCommitteeGindex: committeeRoot}
+	header := c.standInHeader(attested, tree.node(1))
+	root, err := header.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	aggregate, err := c.signSyncAggregate(slot, root)
+	if err != nil {
+		return err
+	}
+	update := &types.LightClientUpdate{
+		AttestedHeader:          &types.LightClientHeader{Beacon: header},
+		NextSyncCommittee:       committee,
+		NextSyncCommitteeBranch: tree.branch(nextSyncCommitteeGindex),
+		FinalizedHeader:         &types.LightClientHeader{Beacon: finalized},
+		FinalityBranch:          tree.branch(finalizedRootGindex),
+		SyncAggregate:           aggregate,
+		SignatureSlot:           slot,
+	}
+	c.lightClient.store(attestedSlot/c.SlotsPerEpoch/epochsPerSyncCommitteePeriod, update)
+	return nil
+}
+
+// lightClientResponse is the versioned light client data of beacon API responses.
+type lightClientResponse struct {
+	Version string      `json:"version"`
+	Data    interface{} `json:"data"`
+}
+
+func (c *ConsensusCmd) beaconRouter() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc(pathLightClientUpdates, c.handleLightClientUpdates).Methods(http.MethodGet)
+	router.HandleFunc(pathLightClientFinalityUpdate, c.handleLightClientFinalityUpdate).Methods(http.MethodGet)
+	router.HandleFunc(pathLightClientOptimisticUpdate, c.handleLightClientOptimisticUpdate).Methods(http.MethodGet)
+	router.HandleFunc(pathProposerDuties, c.handleProposerDuties).Methods(http.MethodGet)
+	router.HandleFunc(pathStateValidators, c.handleStateValidators).Methods(http.MethodGet)
+	if c.Pprof {
+		registerPprof(router)
+	}
+	return router
+}
+
+// startBeaconAPI serves the light client data, proposer duties and validators of the mock chain.
+func (c *ConsensusCmd) startBeaconAPI(log logrus.Ext1FieldLogger) {
+	handler := AccessLogMiddleware(c.beaconRouter(), c.AccessLog.AccessLog(log))
+	c.beaconSrv = &http.Server{Addr: c.BeaconAPIAddr, Handler: handler}
+	go func() {
+		if err := serve(c.beaconSrv, c.BeaconListener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("Beacon API stopped")
+		}
+	}()
+	addr := c.BeaconAPIAddr
+	if c.BeaconListener != nil {
+		addr = c.BeaconListener.Addr().String()
+	}
+	log.WithField("addr", addr).Info("Serving light client data over the beacon API")
+}
+
+// servesBeaconAPI returns whether the beacon API is served, on its address or listener.
+func (c *ConsensusCmd) servesBeaconAPI() bool {
+	return c.BeaconAPIAddr != "" || c.BeaconListener != nil
+}
+
+func (c *ConsensusCmd) handleLightClientUpdates(w http.ResponseWriter, req *http.Request) {
+	start, err := strconv.ParseUint(req.URL.Query().Get("start_period"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid start_period", http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.ParseUint(req.URL.Query().Get("count"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid count", http.StatusBadRequest)
+		return
+	}
+	if count > maxLightClientUpdates {
+		count = maxLightClientUpdates
+	}
+	c.lightClient.mu.RLock()
+	responses := []lightClientResponse{}
+	for period := start; period < start+count; period++ {
+		if update, ok := c.lightClient.updates[period]; ok {
+			responses = append(responses, lightClientResponse{lightClientVersion, update})
+		}
+	}
+	c.lightClient.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responses)
+}
+
+func (c *ConsensusCmd) handleLightClientFinalityUpdate(w http.ResponseWriter, req *http.Request) {
+	c.lightClient.mu.RLock()
+	update := c.lightClient.finality
+	c.lightClient.mu.RUnlock()
+	if update == nil {
+		http.Error(w, "no finality update available", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lightClientResponse{lightClientVersion, update})
+}
+
+func (c *ConsensusCmd) handleLightClientOptimisticUpdate(w http.ResponseWriter, req *http.Request) {
+	c.lightClient.mu.RLock()
+	update := c.lightClient.optimistic
+	c.lightClient.mu.RUnlock()
+	if update == nil {
+		http.Error(w, "no optimistic update available", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lightClientResponse{lightClientVersion, update})
+}
@@ -0,0 +1,101 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLightClientUpdate(t *testing.T) {
+	c := newAttestingConsensus(t, 8, 1)
+	c.SyncParticipation = 1
+	mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, newMergedGenesis(common.Address{0x42}), rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	c.mockChain = mc
+	srv := httptest.NewServer(c.beaconRouter())
+	defer srv.Close()
+
+	// nothing to serve before the first update
+	res, err := http.Get(srv.URL + pathLightClientFinalityUpdate)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	genesis := mc.CurrentHeader()
+	attested, err := mc.AddNewBlock(genesis.Hash(), common.Address{1}, c.state.SlotTimestamp(1), genesis.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, nil, nil, true)
+	require.NoError(t, err)
+	require.NoError(t, c.updateLightClient(2, attested.Header()))
+
+	var versioned struct {
+		Version string                   `json:"version"`
+		Data    *types.LightClientUpdate `json:"data"`
+	}
+	var updates []json.RawMessage
+	res, err = http.Get(srv.URL + pathLightClientUpdates + "?start_period=0&count=2")
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&updates))
+	require.Len(t, updates, 1)
+	require.NoError(t, json.Unmarshal(updates[0], &versioned))
+	require.Equal(t, lightClientVersion, versioned.Version)
+	update := versioned.Data
+
+	header := update.AttestedHeader.Beacon
+	require.Equal(t, uint64(1), header.Slot)
+	require.Equal(t, types.Root(attested.Hash()), header.BodyRoot)
+	require.Equal(t, uint64(2), update.SignatureSlot)
+
+	// the branches prove the finalized header, genesis so far, and the next sync committee
+	finalized := update.FinalizedHeader.Beacon
+	require.Equal(t, types.Root(genesis.Hash()), finalized.BodyRoot)
+	finalizedRoot, err := finalized.HashTreeRoot()
+	require.NoError(t, err)
+	require.Len(t, update.FinalityBranch, 6)
+	require.True(t, verifyDepositProof(finalizedRoot, update.FinalityBranch, finalizedRootGindex-64, header.StateRoot))
+	committeeRoot, err := update.NextSyncCommittee.HashTreeRoot()
+	require.NoError(t, err)
+	require.Len(t, update.NextSyncCommitteeBranch, 5)
+	require.True(t, verifyDepositProof(committeeRoot, update.NextSyncCommitteeBranch, nextSyncCommitteeGindex-32, header.StateRoot))
+
+	// the sync committee of the signature slot signs the attested header
+	var pubkeys []bls.PublicKey
+	for _, idx := range c.syncCommittee(update.SignatureSlot) {
+		pubkeys = append(pubkeys, c.validators[idx].sk.PublicKey())
+	}
+	root, err := header.HashTreeRoot()
+	require.NoError(t, err)
+	domain := types.ComputeDomain(types.DomainTypeSyncCommittee, version.Bellatrix, &c.genesisValidatorsRoot)
+	signingRoot, err := types.ComputeSigningRoot(&types.SigningBlockRoot{Root: root}, domain)
+	require.NoError(t, err)
+	sig, err := bls.SignatureFromBytes(update.SyncAggregate.CommitteeSignature[:])
+	require.NoError(t, err)
+	require.True(t, sig.FastAggregateVerify(pubkeys, signingRoot))
+
+	// the finality and optimistic updates are those of the latest update
+	var finality struct {
+		Data *types.LightClientFinalityUpdate `json:"data"`
+	}
+	res, err = http.Get(srv.URL + pathLightClientFinalityUpdate)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&finality))
+	require.Equal(t, update.FinalityBranch, finality.Data.FinalityBranch)
+	var optimistic struct {
+		Data *types.LightClientOptimisticUpdate `json:"data"`
+	}
+	res, err = http.Get(srv.URL + pathLightClientOptimisticUpdate)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&optimistic))
+	require.Equal(t, header, optimistic.Data.AttestedHeader.Beacon)
+
+	res, err = http.Get(srv.URL + pathLightClientUpdates + "?start_period=0")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mergemock/rpc"
+)
+
+// MethodLimits are the concurrency limits of specific engine methods.
+type MethodLimits map[string]int
+
+func (m *MethodLimits) String() string {
+	pairs := make([]string, 0, len(*m))
+	for method, limit := range *m {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", method, limit))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m *MethodLimits) Set(s string) error {
+	limits := make(MethodLimits)
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected method=limit, got %q", pair)
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid limit of %s: %v", method, err)
+		}
+		limits[method] = limit
+	}
+	*m = limits
+	return nil
+}
+
+func (m *MethodLimits) Type() string {
+	return "MethodLimits"
+}
+
+type EngineLimits struct {
+	Concurrency int          `ask:"--concurrency" help:"Max concurrent calls per engine method (0 for no limit)"`
+	Methods     MethodLimits `ask:"--methods" help:"Max concurrent calls of specific engine methods, as comma-separated method=limit pairs"`
+	Queue       int          `ask:"--queue" help:"Max calls per engine method waiting for their turn, before the overflow policy applies (0 for no limit)"`
+	Overflow    string       `ask:"--overflow" help:"What to do with calls over the queue limit: 'block' to wait regardless, 'drop-oldest' to fail the call that waited the longest"`
+}
+
+func (l *EngineLimits) Default() {
+	l.Overflow = string(rpc.OverflowBlock)
+}
+
+func (l *EngineLimits) Limits() rpc.Limits {
+	return rpc.Limits{
+		Concurrency: l.Concurrency,
+		Methods:     l.Methods,
+		Queue:       l.Queue,
+		Overflow:    rpc.OverflowPolicy(l.Overflow),
+	}
+}
+
+// logEngineMetrics logs the metrics of the engine calls by method.
+func (c *ConsensusCmd) logEngineMetrics() {
+	for method, m := range c.engine.Metrics() {
+		c.log.WithField("method", method).WithField("calls", m.Calls).WithField("dropped", m.Dropped).
+			WithField("active", m.Active).WithField("queued", m.Queued).WithField("max_queued", m.MaxQueued).
+			Debug("Engine call metrics")
+	}
+}
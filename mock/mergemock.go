@@ -0,0 +1,48 @@
+package mock
+
+import "github.com/protolambda/ask"
+
+// MergeMockCmd routes to the subcommands of the mergemock CLI.
+type MergeMockCmd struct {
+}
+
+func (c *MergeMockCmd) Help() string {
+	return "Run MergeMock. Either mock a consensus node or execution engine."
+}
+
+func (c *MergeMockCmd) Cmd(route string) (cmd interface{}, err error) {
+	switch route {
+	case "consensus":
+		cmd = &ConsensusCmd{}
+	case "engine":
+		cmd = &EngineCmd{}
+	case "relay":
+		cmd = &RelayCmd{}
+	case "vectors":
+		cmd = &VectorsCmd{}
+	case "devnet":
+		cmd = &DevnetCmd{}
+	case "keys":
+		cmd = &KeysCmd{}
+	case "replay-chain":
+		cmd = &ReplayCmd{}
+	case "shadow-fork":
+		cmd = &ShadowForkCmd{}
+	case "completion":
+		cmd = &CompletionCmd{}
+	default:
+		return nil, ask.UnrecognizedErr
+	}
+	return
+}
+
+func (c *MergeMockCmd) Routes() []string {
+	return []string{"consensus", "engine", "relay", "vectors", "devnet", "keys", "replay-chain", "shadow-fork", "completion"}
+}
+
+func (c *MergeMockCmd) Examples() []string {
+	return []string{
+		"mergemock engine --help",
+		"mergemock completion bash",
+	}
+}
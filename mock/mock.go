@@ -0,0 +1,960 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"mergemock/api"
+	"mergemock/contracts"
+	mmTypes "mergemock/types"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/ethdb"
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/sha3"
+)
+
+// type testAccount struct {
+//         pk   *ecdsa.PrivateKey
+//         addr common.Address
+// }
+
+// This implements the execution-block-header verification interface, but omits many of the details:
+// the mock doesn't fully verify, and sealing work of headers is very limited.
+type ExecutionConsensusMock struct {
+	// TODO: set terminal total difficulty, and switch from ethash to pos
+	pow *ethash.Ethash
+	log logrus.Ext1FieldLogger
+}
+
+// maxBlockExtras is the number of recent blocks the extras are kept for, far more than reorgs
+// and re-sent blocks go back, without growing over long runs.
+const maxBlockExtras = 1024
+
+// blockExtras are the post-merge block contents that the header of this geth version has no room for.
+type blockExtras struct {
+	withdrawals mmTypes.Withdrawals
+	beaconRoot  *common.Hash
+}
+
+func (e *ExecutionConsensusMock) Author(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+func (e *ExecutionConsensusMock) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	// Short circuit if the header is known, or its parent not
+	number := header.Number.Uint64()
+	if chain.GetHeader(header.Hash(), number) != nil {
+		return nil
+	}
+	// check we have the parent
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	// TODO: not verifying time, difficulty, gas limit, gas usage vs limit, base fee, extra-data, etc.
+	return nil
+}
+
+func (e *ExecutionConsensusMock) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	// nothing fancy, not optimized, this is mostly a mock anyway
+	abort := make(chan struct{})
+	errorsOut := make(chan error, len(headers))
+	go func() {
+		for i, h := range headers {
+			err := e.VerifyHeader(chain, h, seals[i])
+			select {
+			case <-abort:
+				return
+			case errorsOut <- err:
+				continue
+			}
+		}
+	}()
+	return abort, errorsOut
+}
+
+func (e *ExecutionConsensusMock) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	// TODO sanity check maybe?
+	return nil
+}
+
+func (e *ExecutionConsensusMock) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Difficulty = big.NewInt(0)
+	return nil
+}
+
+func (e *ExecutionConsensusMock) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	// no block rewards, consensus layer does that instead.
+	// The beacon root and withdrawals are applied by the mock chain, which executes and writes
+	// post-merge blocks itself, as geth would apply them here, after the transactions.
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+}
+
+func (e *ExecutionConsensusMock) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// Finalize block
+	e.Finalize(chain, header, state, txs, uncles)
+
+	// Header seems complete, assemble into a block and return
+	return types.NewBlock(header, txs, uncles, receipts, trie.NewStackTrie(nil)), nil
+}
+
+func (e *ExecutionConsensusMock) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+	header.Nonce, header.MixDigest = types.BlockNonce{}, common.Hash{}
+	select {
+	case results <- block.WithSeal(header):
+	default:
+		e.log.Warn("Sealing result is not read by miner", "mode", "fake", "sealhash", e.SealHash(block.Header()))
+	}
+	return nil
+}
+
+func (e *ExecutionConsensusMock) SealHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewLegacyKeccak256()
+
+	enc := []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra,
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	rlp.Encode(hasher, enc)
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+func (e *ExecutionConsensusMock) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return nil
+}
+
+func (e *ExecutionConsensusMock) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return nil
+}
+
+func (e *ExecutionConsensusMock) Close() error {
+	return nil
+}
+
+var _ consensus.Engine = (*ExecutionConsensusMock)(nil)
+
+type TraceLogConfig struct {
+	EnableTrace      bool `ask:"--enable" help:"enable tracing"`
+	EnableMemory     bool `ask:"--enable-memory" help:"enable memory capture"`
+	DisableStack     bool `ask:"--disable-stack" help:"disable stack capture"`
+	DisableStorage   bool `ask:"--disable-storage" help:"disable storage capture"`
+	EnableReturnData bool `ask:"--enable-return-data" help:"enable return data capture"`
+	Debug            bool `ask:"--debug" help:"print output during capture end"`
+	Limit            int  `ask:"--limit" help:"maximum length of output, but zero means unlimited"`
+}
+
+type TransactionsCreator struct {
+	accounts []TestAccount
+	fn       func(*params.ChainConfig, core.ChainContext, *state.StateDB, *types.Header, vm.Config, []TestAccount) []*types.Transaction
+}
+
+func (t *TransactionsCreator) Create(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *types.Header, cfg vm.Config) []*types.Transaction {
+	return t.fn(config, bc, statedb, header, cfg, t.accounts)
+}
+
+type MockChain struct {
+	chain     *core.BlockChain
+	database  ethdb.Database
+	engine    consensus.Engine
+	gspec     *core.Genesis
+	log       logrus.Ext1FieldLogger
+	traceOpts *TraceLogConfig
+	forks     ForkTimes
+
+	depositContract *common.Address
+	// blockExtras of recent blocks, by block hash
+	extras *lru.Cache
+	// execution requests of recent Prague blocks, by block hash
+	requests *lru.Cache
+	// execution witnesses of recent blocks, by block hash, nil unless enabled
+	witnesses *lru.Cache
+	// headers of recent blocks built without storing them, by block hash, to build on
+	detached *lru.Cache
+}
+
+// ForkTimes are the timestamp-activated forks, which the genesis config of this
+// geth version does not know about, and are read from the genesis file separately.
+type ForkTimes struct {
+	ShanghaiTime *uint64 `json:"shanghaiTime,omitempty"`
+	CancunTime   *uint64 `json:"cancunTime,omitempty"`
+	PragueTime   *uint64 `json:"pragueTime,omitempty"`
+}
+
+func (f *ForkTimes) IsShanghai(time uint64) bool {
+	return f.ShanghaiTime != nil && *f.ShanghaiTime <= time
+}
+
+func (f *ForkTimes) IsCancun(time uint64) bool {
+	return f.CancunTime != nil && *f.CancunTime <= time
+}
+
+func (f *ForkTimes) IsPrague(time uint64) bool {
+	return f.PragueTime != nil && *f.PragueTime <= time
+}
+
+// LoadForkTimes reads the fork timestamps from the config of a genesis file.
+func LoadForkTimes(path string) (ForkTimes, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ForkTimes{}, fmt.Errorf("failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	var genesis struct {
+		Config ForkTimes `json:"config"`
+	}
+	if err := json.NewDecoder(file).Decode(&genesis); err != nil {
+		return ForkTimes{}, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return genesis.Config, nil
+}
+
+func NewDB(dataDir string) (ethdb.Database, error) {
+	if dataDir == "" {
+		return rawdb.NewMemoryDatabase(), nil
+	} else {
+		return rawdb.NewLevelDBDatabaseWithFreezer(dataDir, 128, 128, dataDir, "", false)
+	}
+}
+
+func NewMockChain(log logrus.Ext1FieldLogger, engine consensus.Engine, genesis *core.Genesis, db ethdb.Database, traceOpts *TraceLogConfig) (*MockChain, error) {
+	// Geth logs some things globally unfortunately.
+	// If we were using multiple mocks, we wouldn't know which one is logging what :(
+	gethlog.Root().SetHandler(&GethLogger{FieldLogger: log, Adjust: 0})
+
+	stored := rawdb.ReadCanonicalHash(db, 0)
+	if (stored == common.Hash{}) {
+		_, err := genesis.Commit(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bc, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	extras, err := lru.New(maxBlockExtras)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := lru.New(128)
+	if err != nil {
+		return nil, err
+	}
+	detached, err := lru.New(128)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MockChain{
+		chain:     bc,
+		database:  db,
+		engine:    engine,
+		gspec:     genesis,
+		log:       log,
+		traceOpts: traceOpts,
+		extras:    extras,
+		requests:  requests,
+		detached:  detached,
+	}, nil
+}
+
+// SetForkTimes configures the timestamp-activated forks of the chain.
+func (c *MockChain) SetForkTimes(forks ForkTimes) {
+	c.forks = forks
+}
+
+// SetDepositContract configures the deposit contract to derive deposit requests from, after Prague.
+func (c *MockChain) SetDepositContract(addr common.Address) {
+	c.depositContract = &addr
+}
+
+// ExecutionRequests returns the execution requests of a recently built or processed Prague block.
+func (c *MockChain) ExecutionRequests(hash common.Hash) (mmTypes.ExecutionRequests, bool) {
+	requests, ok := c.requests.Get(hash)
+	if !ok {
+		return nil, false
+	}
+	return requests.(mmTypes.ExecutionRequests), true
+}
+
+// Extras returns the withdrawals and the parent beacon block root of a recent block built or
+// processed since the start, nil if it has none or is not known.
+func (c *MockChain) Extras(hash common.Hash) (mmTypes.Withdrawals, *common.Hash) {
+	v, ok := c.extras.Get(hash)
+	if !ok {
+		return nil, nil
+	}
+	extras := v.(*blockExtras)
+	return extras.withdrawals, extras.beaconRoot
+}
+
+func (c *MockChain) Head() common.Hash {
+	return c.chain.CurrentBlock().Hash()
+}
+
+func (c *MockChain) CurrentHeader() *types.Header {
+	return c.chain.CurrentHeader()
+}
+
+func (c *MockChain) CurrentTd() *big.Int {
+	return c.chain.GetTd(c.Head(), c.CurrentHeader().Number.Uint64())
+}
+
+// Call executes a message on the state after the block, like eth_call, without a gas price.
+func (c *MockChain) Call(header *types.Header, from common.Address, to *common.Address, value *big.Int, gas uint64, data []byte) (*core.ExecutionResult, error) {
+	statedb, err := c.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	msg := types.NewMessage(from, to, statedb.GetNonce(from), value, gas, new(big.Int), new(big.Int), new(big.Int), data, nil, true)
+	evm := vm.NewEVM(core.NewEVMBlockContext(header, c.chain, nil), core.NewEVMTxContext(msg), statedb, c.gspec.Config, vm.Config{NoBaseFee: true})
+	return core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(gas))
+}
+
+// ExpectedBaseFee returns the EIP-1559 base fee of a block on the parent, from the gas the parent
+// used against its gas target, or the initial base fee at the London transition.
+func (c *MockChain) ExpectedBaseFee(parent *types.Header) *big.Int {
+	return misc.CalcBaseFee(c.gspec.Config, parent)
+}
+
+// IsTerminalBlock returns true if the block is a terminal PoW block: it reaches the terminal total
+// difficulty, and its parent does not.
+func (c *MockChain) IsTerminalBlock(header *types.Header) bool {
+	ttd := c.gspec.Config.TerminalTotalDifficulty
+	if ttd == nil {
+		return false
+	}
+	td := c.chain.GetTd(header.Hash(), header.Number.Uint64())
+	if td == nil || td.Cmp(ttd) < 0 {
+		return false
+	}
+	if header.Number.Sign() == 0 {
+		return true
+	}
+	parentTd := c.chain.GetTd(header.ParentHash, header.Number.Uint64()-1)
+	return parentTd != nil && parentTd.Cmp(ttd) < 0
+}
+
+// CheckTransitionParent returns an error if a PoS block cannot extend the parent, which must be a
+// PoS block itself, or a terminal PoW block.
+func (c *MockChain) CheckTransitionParent(parent *types.Header) error {
+	ttd := c.gspec.Config.TerminalTotalDifficulty
+	if ttd == nil || parent.Difficulty.Sign() == 0 || c.IsTerminalBlock(parent) {
+		return nil
+	}
+	return fmt.Errorf("parent %s is not a terminal PoW block: total difficulty %s, terminal total difficulty %s",
+		parent.Hash(), c.chain.GetTd(parent.Hash(), parent.Number.Uint64()), ttd)
+}
+
+// Custom block builder, to change more things, fake time more easily, deal with difficulty etc.
+// Blocks that are not stored can be built on, while they are recent.
+func (c *MockChain) AddNewBlock(parentHash common.Hash, coinbase common.Address, timestamp uint64, gasLimit uint64, txsCreator TransactionsCreator, prevRandao common.Hash, extraData []byte, uncles []*types.Header, withdrawals mmTypes.Withdrawals, beaconRoot *common.Hash, storeBlock bool) (*types.Block, error) {
+	parent := c.chain.GetHeaderByHash(parentHash)
+	if parent == nil {
+		if header, ok := c.detached.Get(parentHash); ok {
+			parent = header.(*types.Header)
+		}
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("unknown parent %s", parentHash)
+	}
+	config := c.gspec.Config
+	if err := c.checkWithdrawals(timestamp, withdrawals, false); err != nil {
+		return nil, err
+	}
+	if err := c.checkBeaconRoot(timestamp, beaconRoot); err != nil {
+		return nil, err
+	}
+	recorder := c.newWitnessRecorder()
+	statedb, err := state.New(parent.Root, state.NewDatabase(recorder.database(c.database)), nil)
+	if err != nil {
+		return nil, err
+	}
+	header := &types.Header{
+		ParentHash: parentHash,
+		Coinbase:   coinbase,
+		Difficulty: common.Big0,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   gasLimit,
+		Time:       timestamp,
+		Extra:      extraData,
+		MixDigest:  common.BytesToHash(prevRandao[:]),
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = c.ExpectedBaseFee(parent)
+		// At the transition, double the gas limit so the gas target is equal to the old gas limit.
+		if !config.IsLondon(parent.Number) {
+			header.GasLimit = parent.GasLimit * params.ElasticityMultiplier
+		}
+	}
+
+	receipts := make([]*types.Receipt, 0)
+	gasPool := new(core.GasPool).AddGas(header.GasLimit)
+	stl := logger.NewStructLogger(&logger.Config{
+		EnableMemory:     c.traceOpts.EnableMemory,
+		DisableStack:     c.traceOpts.DisableStack,
+		DisableStorage:   c.traceOpts.DisableStorage,
+		EnableReturnData: c.traceOpts.EnableReturnData,
+		Debug:            c.traceOpts.Debug,
+		Limit:            c.traceOpts.Limit,
+		Overrides:        nil,
+	})
+	vmconf := vm.Config{}
+	if c.traceOpts.EnableTrace {
+		vmconf.Tracer = stl
+	}
+
+	applyBeaconRoot(statedb, header.Time, beaconRoot)
+	txs := txsCreator.Create(config, c.chain, statedb, header, vmconf)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), i)
+		receipt, err := core.ApplyTransaction(config, recorder.chainContext(c.chain), &header.Coinbase, gasPool, statedb, header, tx, &header.GasUsed, vmconf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transaction %d: %v", i, err)
+		}
+		rec, _ := json.MarshalIndent(receipt, "  ", "  ")
+		c.log.WithField("receipt_index", i).Debug("receipt:\n" + string(rec))
+		receipts = append(receipts, receipt)
+	}
+	if c.traceOpts.EnableTrace {
+		var buf bytes.Buffer
+		logger.WriteTrace(&buf, stl.StructLogs())
+		c.log.Info("trace:\n" + buf.String())
+	}
+	applyWithdrawals(statedb, withdrawals)
+
+	header.GasUsed = header.GasLimit - uint64(*gasPool)
+	header.Root = statedb.IntermediateRoot(config.IsEIP158(header.Number))
+	block := types.NewBlock(header, txs, uncles, receipts, trie.NewStackTrie(nil))
+	if err := c.storeRequests(block, receipts); err != nil {
+		return nil, err
+	}
+
+	// Write state changes to db
+	root, err := statedb.Commit(config.IsEIP158(header.Number))
+	if err != nil {
+		return nil, fmt.Errorf("state write error: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false, nil); err != nil {
+		return nil, fmt.Errorf("trie write error: %v", err)
+	}
+
+	if storeBlock {
+		c.registerExtras(block.Hash(), withdrawals, beaconRoot)
+		c.storeWitness(block.Hash(), parent, recorder)
+		if err := c.writeBlock(block, receipts, statedb); err != nil {
+			return nil, err
+		}
+	} else {
+		c.detached.Add(block.Hash(), block.Header())
+	}
+
+	return block, nil
+}
+
+// Custom block builder, to change more things, fake time more easily, deal with difficulty etc.
+func (c *MockChain) MineBlock(parent *types.Header) (*types.Block, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+	}
+
+	config := c.gspec.Config
+	if config.IsLondon(header.Number) {
+		header.BaseFee = c.ExpectedBaseFee(parent)
+		// At the transition, double the gas limit so the gas target is equal to the old gas limit.
+		if !config.IsLondon(parent.Number) {
+			header.GasLimit = parent.GasLimit * params.ElasticityMultiplier
+		}
+	}
+
+	// Calculate difficulty
+	if err := c.engine.Prepare(c.chain, header); err != nil {
+		return nil, fmt.Errorf("failed to prepare header for mining: %v", err)
+	}
+
+	// Finalize block
+	statedb, err := state.New(parent.Root, state.NewDatabase(c.database), nil)
+	if err != nil {
+		panic(err)
+	}
+	block, err := c.engine.FinalizeAndAssemble(c.chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize and assemble block: %v", err)
+	}
+
+	// Seal block, buffered as the fake PoW of tests delivers the result before Seal returns
+	results := make(chan *types.Block, 1)
+	if err := c.engine.Seal(c.chain, block, results, nil); err != nil {
+		panic(fmt.Sprintf("failed to seal block: %v", err))
+	}
+	select {
+	case found := <-results:
+		block = found
+	case <-time.NewTimer(10000 * time.Second).C:
+		return nil, fmt.Errorf("sealing result timeout")
+	}
+
+	// Write state changes to db
+	root, err := statedb.Commit(config.IsEIP158(header.Number))
+	if err != nil {
+		return nil, fmt.Errorf("state write error: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false, nil); err != nil {
+		return nil, fmt.Errorf("trie write error: %v", err)
+	}
+
+	// Insert block into chain
+	_, err = c.chain.InsertChain(types.Blocks{block})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert block into chain: %v", err)
+	}
+
+	return block, nil
+}
+
+// payloadMismatch returns the error of a payload that differs from the block the mock computed
+// from it, with the fields of the payload of the block that differ.
+func (c *MockChain) payloadMismatch(msg string, block *types.Block, payload *mmTypes.ExecutionPayloadV1) error {
+	computed, err := api.BlockToPayload(block)
+	if err != nil {
+		return errors.New(msg)
+	}
+	return newMismatch(msg, computed, payload)
+}
+
+func (c *MockChain) ProcessPayload(payload *mmTypes.ExecutionPayloadV1) (*types.Block, error) {
+	return c.processPayload(payload, nil, nil)
+}
+
+// ProcessPayloadV2 processes a Shanghai payload, crediting its withdrawals after the transactions.
+//
+// The block header of this geth version has no withdrawals root, so the block hash of the
+// payload cannot commit to the withdrawals. The post-state does include them, and is checked
+// against the state root of the payload.
+func (c *MockChain) ProcessPayloadV2(payload *mmTypes.ExecutionPayloadV2) (*types.Block, error) {
+	withdrawals := payload.Withdrawals
+	if withdrawals == nil {
+		withdrawals = mmTypes.Withdrawals{}
+	}
+	return c.processPayload(payload.V1(), withdrawals, nil)
+}
+
+// ProcessPayloadV3 processes a Cancun payload, writing the parent beacon block root into the
+// EIP-4788 beacon roots contract before the transactions. Like the withdrawals, the root is not
+// committed to by the block hash, but a mismatch shows in the state root.
+//
+// Blob transactions are not supported by this geth version, so blob gas must be zero.
+func (c *MockChain) ProcessPayloadV3(payload *mmTypes.ExecutionPayloadV3, beaconRoot common.Hash) (*types.Block, error) {
+	if payload.BlobGasUsed != 0 || payload.ExcessBlobGas != 0 {
+		return nil, fmt.Errorf("blob gas is not supported: used %d, excess %d", payload.BlobGasUsed, payload.ExcessBlobGas)
+	}
+	withdrawals := payload.Withdrawals
+	if withdrawals == nil {
+		withdrawals = mmTypes.Withdrawals{}
+	}
+	return c.processPayload(payload.V1(), withdrawals, &beaconRoot)
+}
+
+func (c *MockChain) processPayload(payload *mmTypes.ExecutionPayloadV1, withdrawals mmTypes.Withdrawals, beaconRoot *common.Hash) (*types.Block, error) {
+	if err := c.checkWithdrawals(payload.Timestamp, withdrawals, true); err != nil {
+		return nil, err
+	}
+	if err := c.checkBeaconRoot(payload.Timestamp, beaconRoot); err != nil {
+		return nil, err
+	}
+	parent := c.chain.GetHeaderByHash(payload.ParentHash)
+	if parent == nil {
+		return nil, fmt.Errorf("unknown parent %s", payload.ParentHash)
+	}
+	if err := c.CheckTransitionParent(parent); err != nil {
+		return nil, err
+	}
+	config := c.gspec.Config
+	recorder := c.newWitnessRecorder()
+	statedb, err := state.New(parent.Root, state.NewDatabase(recorder.database(c.database)), nil)
+	if err != nil {
+		panic(err)
+	}
+	block, receipts, err := c.executePayload(statedb, recorder.chainContext(c.chain), parent, payload, withdrawals, beaconRoot)
+	if err != nil {
+		return nil, err
+	}
+	if used := block.GasUsed(); used != uint64(payload.GasUsed) {
+		return nil, c.payloadMismatch(fmt.Sprintf("gas usage difference: %d <> %d", payload.GasUsed, used), block, payload)
+	}
+	if receiptHash := block.ReceiptHash(); receiptHash != common.Hash(payload.ReceiptsRoot) {
+		return nil, newReceiptsMismatch("receipt root", payload.ReceiptsRoot, receiptHash, receipts, payload.LogsBloom)
+	}
+	if bloom := block.Bloom(); bloom != payload.LogsBloom {
+		return nil, newReceiptsMismatch("logs bloom", hexutil.Encode(payload.LogsBloom[:]), hexutil.Encode(bloom[:]), receipts, payload.LogsBloom)
+	}
+	if block.Root() != common.Hash(payload.StateRoot) {
+		return nil, c.payloadMismatch(fmt.Sprintf("state root difference: %s <> %s", block.Root(), payload.StateRoot), block, payload)
+	}
+	if hash := block.Hash(); hash != payload.BlockHash {
+		return nil, c.payloadMismatch(fmt.Sprintf("block hash difference: %s <> %s", hash, payload.BlockHash), block, payload)
+	}
+	if err := c.storeRequests(block, receipts); err != nil {
+		return nil, err
+	}
+	// Write state changes to db
+	root, err := statedb.Commit(config.IsEIP158(block.Number()))
+	if err != nil {
+		return nil, fmt.Errorf("state write error: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false, nil); err != nil {
+		return nil, fmt.Errorf("trie write error: %v", err)
+	}
+	c.registerExtras(block.Hash(), withdrawals, beaconRoot)
+	c.storeWitness(block.Hash(), parent, recorder)
+	if err := c.writeBlock(block, receipts, statedb); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// executePayload executes the payload on the state of its parent, with the chain for the hashes
+// of the blocks the EVM reads, and returns the block it computes, without checking it against the
+// payload.
+func (c *MockChain) executePayload(statedb *state.StateDB, chain core.ChainContext, parent *types.Header, payload *mmTypes.ExecutionPayloadV1, withdrawals mmTypes.Withdrawals, beaconRoot *common.Hash) (*types.Block, []*types.Receipt, error) {
+	config := c.gspec.Config
+	header := &types.Header{
+		ParentHash:  parent.Hash(),
+		UncleHash:   common.Hash{}, // updated by sealing, if necessary
+		Coinbase:    payload.FeeRecipient,
+		Root:        common.Hash{}, // state root verified after processing
+		TxHash:      common.Hash{}, // part of assembling
+		ReceiptHash: common.Hash{}, // receipt root verified after processing
+		Bloom:       types.Bloom{}, // bloom verified after processing
+		Difficulty:  common.Big0,
+		Number:      new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:    uint64(payload.GasLimit),
+		GasUsed:     0,                         // updated by processing
+		Time:        uint64(payload.Timestamp), // verified against slot
+		Extra:       payload.ExtraData,
+		MixDigest:   payload.Random,
+		Nonce:       types.BlockNonce{},    // updated by sealing, if necessary
+		BaseFee:     payload.BaseFeePerGas, // verified against the parent after London
+	}
+	if config.IsLondon(header.Number) {
+		expected := c.ExpectedBaseFee(parent)
+		if payload.BaseFeePerGas == nil || payload.BaseFeePerGas.Cmp(expected) != 0 {
+			return nil, nil, fmt.Errorf("base fee difference: %s <> %s", expected, payload.BaseFeePerGas)
+		}
+		header.BaseFee = expected
+		// At the transition, double the gas limit so the gas target is equal to the old gas limit.
+		if !config.IsLondon(parent.Number) {
+			header.GasLimit = parent.GasLimit * params.ElasticityMultiplier
+		}
+	}
+	receipts := make([]*types.Receipt, 0)
+	gasPool := new(core.GasPool).AddGas(header.GasLimit)
+	stl := logger.NewStructLogger(&logger.Config{
+		EnableMemory:     c.traceOpts.EnableMemory,
+		DisableStack:     c.traceOpts.DisableStack,
+		DisableStorage:   c.traceOpts.DisableStorage,
+		EnableReturnData: c.traceOpts.EnableReturnData,
+		Debug:            c.traceOpts.Debug,
+		Limit:            c.traceOpts.Limit,
+		Overrides:        nil,
+	})
+	vmconf := vm.Config{}
+	if c.traceOpts.EnableTrace {
+		vmconf.Tracer = stl
+	}
+	applyBeaconRoot(statedb, header.Time, beaconRoot)
+	txs := make([]*types.Transaction, 0, len(payload.Transactions))
+	for i, otx := range payload.Transactions {
+		var tx types.Transaction
+		if err := tx.UnmarshalBinary(otx); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode tx %d: %v", i, err)
+		}
+		txs = append(txs, &tx)
+		statedb.Prepare(tx.Hash(), i)
+		receipt, err := core.ApplyTransaction(config, chain, &header.Coinbase, gasPool, statedb, header, &tx, &header.GasUsed, vmconf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to apply transaction %d: %v", i, err)
+		}
+		rec, _ := json.MarshalIndent(receipt, "  ", "  ")
+		c.log.WithField("receipt_index", i).Debug("receipt:\n" + string(rec))
+		receipts = append(receipts, receipt)
+	}
+	if c.traceOpts.EnableTrace {
+		var buf bytes.Buffer
+		logger.WriteTrace(&buf, stl.StructLogs())
+		c.log.Info("trace:\n" + buf.String())
+	}
+	applyWithdrawals(statedb, withdrawals)
+
+	// compute the state root, verified by the caller, and build the block
+	header.Root = statedb.IntermediateRoot(config.IsEIP158(header.Number))
+	block := types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
+
+	c.log.WithFields(map[string]interface{}{
+		"blockHash": block.Hash(),
+		"number":    block.Number(),
+		"gasUsed":   block.GasUsed(),
+		"stateRoot": block.Root(),
+	}).Debug("computed block from payload")
+
+	return block, receipts, nil
+}
+
+// checkWithdrawals checks the withdrawals are only present from Shanghai onwards.
+// Blocks built by the mock may leave them out, payloads after Shanghai must include them.
+func (c *MockChain) checkWithdrawals(timestamp uint64, withdrawals mmTypes.Withdrawals, strict bool) error {
+	if !c.forks.IsShanghai(timestamp) {
+		if withdrawals != nil {
+			return fmt.Errorf("withdrawals before shanghai")
+		}
+		return nil
+	}
+	if strict && withdrawals == nil {
+		return fmt.Errorf("missing withdrawals after shanghai")
+	}
+	return nil
+}
+
+// storeRequests derives the execution requests of a Prague block from its transactions and receipts.
+//
+// This geth version cannot execute the EIP-7002 and EIP-7251 system contracts, withdrawal and
+// consolidation requests are derived from the transactions calling them instead.
+func (c *MockChain) storeRequests(block *types.Block, receipts []*types.Receipt) error {
+	if !c.forks.IsPrague(block.Time()) {
+		return nil
+	}
+	requests := mmTypes.ExecutionRequests{}
+	if c.depositContract != nil {
+		var logs []*types.Log
+		for _, r := range receipts {
+			logs = append(logs, r.Logs...)
+		}
+		deposits, err := depositRequests(logs, *c.depositContract)
+		if err != nil {
+			return err
+		}
+		if len(deposits) > 0 {
+			requests = append(requests, append([]byte{mmTypes.DepositRequestType}, deposits...))
+		}
+	}
+	queues := []struct {
+		typ       byte
+		contract  common.Address
+		inputSize int
+	}{
+		{mmTypes.WithdrawalRequestType, contracts.WithdrawalRequestAddress, mmTypes.WithdrawalRequestSize - common.AddressLength},
+		{mmTypes.ConsolidationRequestType, contracts.ConsolidationRequestAddress, mmTypes.ConsolidationRequestSize - common.AddressLength},
+	}
+	for _, q := range queues {
+		queued, err := queuedRequests(c.chain.Config(), block, receipts, q.contract, q.inputSize)
+		if err != nil {
+			return err
+		}
+		if len(queued) > 0 {
+			requests = append(requests, append([]byte{q.typ}, queued...))
+		}
+	}
+	c.requests.Add(block.Hash(), requests)
+	return nil
+}
+
+// checkBeaconRoot checks the parent beacon block root is present if and only if Cancun is active.
+func (c *MockChain) checkBeaconRoot(timestamp uint64, beaconRoot *common.Hash) error {
+	if c.forks.IsCancun(timestamp) {
+		if beaconRoot == nil {
+			return fmt.Errorf("missing parent beacon block root after cancun")
+		}
+	} else if beaconRoot != nil {
+		return fmt.Errorf("parent beacon block root before cancun")
+	}
+	return nil
+}
+
+// applyBeaconRoot stores the timestamp and parent beacon block root in the ring buffer of the
+// EIP-4788 beacon roots contract, with the same effect as the system call into the contract.
+// Like in the EIP, nothing happens if the contract is not deployed.
+func applyBeaconRoot(statedb *state.StateDB, timestamp uint64, beaconRoot *common.Hash) {
+	if beaconRoot == nil || statedb.GetCodeSize(contracts.BeaconRootsAddress) == 0 {
+		return
+	}
+	timestampIdx := timestamp % contracts.BeaconRootsHistoryBufferLength
+	rootIdx := timestampIdx + contracts.BeaconRootsHistoryBufferLength
+	statedb.SetState(contracts.BeaconRootsAddress, common.BigToHash(new(big.Int).SetUint64(timestampIdx)), common.BigToHash(new(big.Int).SetUint64(timestamp)))
+	statedb.SetState(contracts.BeaconRootsAddress, common.BigToHash(new(big.Int).SetUint64(rootIdx)), *beaconRoot)
+}
+
+// applyWithdrawals credits the withdrawn amounts, in gwei, to the withdrawal addresses.
+func applyWithdrawals(statedb *state.StateDB, withdrawals mmTypes.Withdrawals) {
+	for _, w := range withdrawals {
+		amount := new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), big.NewInt(params.GWei))
+		statedb.AddBalance(w.Address, amount)
+	}
+}
+
+// registerExtras keeps the withdrawals and beacon root of the block, which its header has no room for.
+func (c *MockChain) registerExtras(hash common.Hash, withdrawals mmTypes.Withdrawals, beaconRoot *common.Hash) {
+	if len(withdrawals) == 0 && beaconRoot == nil {
+		return
+	}
+	c.extras.Add(hash, &blockExtras{withdrawals: withdrawals, beaconRoot: beaconRoot})
+}
+
+// writeBlock stores the block with the receipts and state the mock computed, as the new head.
+// Unlike InsertChain, geth does not execute the block again, which would apply the beacon root
+// after the transactions instead of before them. Known blocks are left alone, like InsertChain does.
+func (c *MockChain) writeBlock(block *types.Block, receipts []*types.Receipt, statedb *state.StateDB) error {
+	if c.chain.HasBlockAndState(block.Hash(), block.NumberU64()) {
+		return nil
+	}
+	// the receipts and logs were made before the block hash was known
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		receipt.BlockHash = block.Hash()
+		for _, l := range receipt.Logs {
+			l.BlockHash = block.Hash()
+		}
+		logs = append(logs, receipt.Logs...)
+	}
+	if _, err := c.chain.WriteBlockAndSetHead(block, receipts, logs, statedb, true); err != nil {
+		return fmt.Errorf("failed to insert block into chain: %v", err)
+	}
+	return nil
+}
+
+func (c *MockChain) Close() error {
+	err := c.engine.Close()
+	if err != nil {
+		c.log.WithError(err).Error("Failed closing consensus engine")
+	}
+	// mock-chain doesn't own the db, it's not closed here.
+	return nil
+}
+
+func LoadGenesisConfig(path string) (*core.Genesis, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	var genesis core.Genesis
+	if err := json.NewDecoder(file).Decode(&genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return &genesis, nil
+}
+
+// LoadGenesis loads the genesis config, and deploys the deposit contract in it
+// if a deposit contract address is configured. An empty address is ignored.
+func LoadGenesis(path string, depositContract string) (*core.Genesis, error) {
+	genesis, err := LoadGenesisConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if depositContract == "" {
+		return genesis, nil
+	}
+	if !common.IsHexAddress(depositContract) {
+		return nil, fmt.Errorf("invalid deposit contract address: %q", depositContract)
+	}
+	if err := AddDepositContract(genesis, common.HexToAddress(depositContract)); err != nil {
+		return nil, fmt.Errorf("failed to add deposit contract to genesis: %v", err)
+	}
+	return genesis, nil
+}
+
+// AddDepositContract adds the deposit contract to the genesis allocation at the given address.
+// The constructor is run in an ephemeral EVM to obtain the runtime code and the initialized
+// zero-hashes storage. Existing code at the address is left untouched.
+func AddDepositContract(genesis *core.Genesis, addr common.Address) error {
+	if acc, ok := genesis.Alloc[addr]; ok && len(acc.Code) > 0 {
+		return nil
+	}
+	// ForEachStorage needs the preimages of the storage keys, which are only written on commit
+	statedb, err := state.New(common.Hash{}, state.NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &trie.Config{Preimages: true}), nil)
+	if err != nil {
+		return err
+	}
+	cfg := &runtime.Config{ChainConfig: genesis.Config, BlockNumber: common.Big0, State: statedb}
+	code, deployed, _, err := runtime.Create(contracts.DepositContractCode, cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := cfg.State.Commit(true); err != nil {
+		return err
+	}
+	storage := make(map[common.Hash]common.Hash)
+	err = cfg.State.ForEachStorage(deployed, func(key, value common.Hash) bool {
+		storage[key] = value
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if genesis.Alloc == nil {
+		genesis.Alloc = make(core.GenesisAlloc)
+	}
+	acc := genesis.Alloc[addr]
+	if acc.Balance == nil {
+		acc.Balance = new(big.Int)
+	}
+	acc.Code = code
+	acc.Storage = storage
+	genesis.Alloc[addr] = acc
+	return nil
+}
+
+// func mockRandomValue(seed [32]byte) [32]byte {
+//         h := sha256.New()
+//         h.Write(seed[:])
+//         var random common.Hash
+//         copy(random[:], h.Sum(nil))
+//         return random
+// }
@@ -0,0 +1,155 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// PayloadIDConfig configures probing how long the engine serves the payloads it builds by their
+// payload id.
+type PayloadIDConfig struct {
+	Slots  uint64        `ask:"--slots" help:"Get payloads by ids after another forkchoice update, of another parent than the head, and past the expiry every this many slots, to classify the payload id lifecycle of the engine (0 to disable)"`
+	Expiry time.Duration `ask:"--expiry" help:"Age past which a payload id is probed as expired, longer than the engine keeps the payloads it built"`
+}
+
+func (p *PayloadIDConfig) Default() {
+	p.Expiry = 2 * time.Minute
+}
+
+// maxIssuedPayloads is how many of the last payload ids of the engine are kept to probe.
+const maxIssuedPayloads = 64
+
+// payloadIDProbeRecipient is the fee recipient of the payloads built for the probes only, so
+// their ids differ from those of the proposals.
+var payloadIDProbeRecipient = common.Address{0xfe}
+
+// issuedPayload is a payload id the engine returned, with the parent and timestamp of the payload
+// it builds.
+type issuedPayload struct {
+	id        types.PayloadID
+	parent    common.Hash
+	timestamp uint64
+	issued    time.Time
+}
+
+// issuedPayloads are the last payload ids the engine returned for the forkchoice updates of the
+// slots.
+type issuedPayloads struct {
+	mu  sync.Mutex
+	ids []issuedPayload
+}
+
+func (p *issuedPayloads) add(id issuedPayload) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids = append(p.ids, id)
+	if len(p.ids) > maxIssuedPayloads {
+		p.ids = p.ids[len(p.ids)-maxIssuedPayloads:]
+	}
+}
+
+// last returns the last payload id that matches.
+func (p *issuedPayloads) last(match func(issuedPayload) bool) (issuedPayload, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := len(p.ids) - 1; i >= 0; i-- {
+		if match(p.ids[i]) {
+			return p.ids[i], true
+		}
+	}
+	return issuedPayload{}, false
+}
+
+// probePayloadIDs gets payloads from the engine by ids it should no longer serve, or serve as
+// they were: an id of the head after another forkchoice update to the head, the last id of
+// another parent than the head, and the last id past the expiry. Unknown payload errors and
+// served payloads are both only classified, as the spec leaves how long the engine keeps payloads
+// open, but the engine must not serve a payload of another parent or timestamp than the id.
+func (c *ConsensusCmd) probePayloadIDs(slot uint64) {
+	log := c.slotLog(slot)
+	head := c.mockChain.CurrentHeader()
+	now := time.Now()
+
+	fresh, err := c.payloadIDAfterForkchoice(slot, head.Hash())
+	if err != nil {
+		if !c.engineOffline(err) {
+			log.WithError(err).Warn("Failed to get payload id to probe")
+		}
+		return
+	}
+	if !c.probePayloadID(log, slot, scenarioPayloadIDForkchoice, fresh) {
+		return
+	}
+	if p, ok := c.payloadIDs.last(func(p issuedPayload) bool {
+		return p.parent != head.Hash() && now.Sub(p.issued) < c.PayloadID.Expiry
+	}); ok {
+		if !c.probePayloadID(log, slot, scenarioPayloadIDOtherParent, p) {
+			return
+		}
+	}
+	if p, ok := c.payloadIDs.last(func(p issuedPayload) bool {
+		return now.Sub(p.issued) >= c.PayloadID.Expiry
+	}); ok {
+		c.probePayloadID(log, slot, scenarioPayloadIDExpired, p)
+	}
+}
+
+// payloadIDAfterForkchoice gets the engine to build a payload on the head, for the next slot, and
+// then updates its forkchoice to the head again without building.
+func (c *ConsensusCmd) payloadIDAfterForkchoice(slot uint64, head common.Hash) (issuedPayload, error) {
+	var safe, final common.Hash
+	if fc := c.forkchoice.get(); fc != nil {
+		safe, final = fc.SafeBlockHash, fc.FinalizedBlockHash
+	}
+	attributes := c.makePayloadAttributes(slot + 1)
+	attributes.SuggestedFeeRecipient = payloadIDProbeRecipient
+	result, err := c.forkchoiceUpdated(c.engine, head, safe, final, attributes)
+	if err != nil {
+		return issuedPayload{}, err
+	}
+	if result.PayloadID == nil {
+		return issuedPayload{}, fmt.Errorf("engine returned %s and no payload id", result.PayloadStatus.Status)
+	}
+	issued := issuedPayload{*result.PayloadID, head, attributes.Timestamp, time.Now()}
+	if _, err := c.forkchoiceUpdated(c.engine, head, safe, final, nil); err != nil {
+		return issuedPayload{}, err
+	}
+	return issued, nil
+}
+
+// probePayloadID gets the payload of the id from the engine, classifies the response, and reports
+// the scenario as failed if the engine served a payload other than the one of the id. It returns
+// false if the engine is offline.
+func (c *ConsensusCmd) probePayloadID(log logrus.Ext1FieldLogger, slot uint64, scenario string, p issuedPayload) bool {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	payload, _, _, err := c.engine.GetPayloadWithValue(ctx, c.engineFork(p.timestamp), p.id)
+	if err != nil && c.engineOffline(err) {
+		return false
+	}
+	class := "payload"
+	if err != nil {
+		class = classifyResponse("", err)
+	}
+	log = log.WithField("call", scenario).WithField("payloadId", p.id).WithField("age", time.Since(p.issued).Round(time.Millisecond)).
+		WithField("response", class).WithField("responses", c.payloadIDResponses.add(scenario, class))
+	var mismatch error
+	if payload != nil && (payload.ParentHash != p.parent || payload.Timestamp != p.timestamp) {
+		mismatch = fmt.Errorf("engine served payload on parent %s at %d for payload id %s of parent %s at %d", payload.ParentHash, payload.Timestamp, p.id, p.parent, p.timestamp)
+		log.WithError(mismatch).Error("Engine served another payload for payload id")
+	} else {
+		log.Info("Classified response to payload id")
+	}
+	c.report.record(scenario, slot, mismatch)
+	if mismatch != nil {
+		c.maybeExit()
+	}
+	return true
+}
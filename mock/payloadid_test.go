@@ -0,0 +1,130 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+// buildEngine issues a payload id for every forkchoice update with attributes, and serves the
+// payloads by id: all of them, none after a forkchoice update without attributes if it forgets,
+// or the last one for every id if it mixes them up.
+type buildEngine struct {
+	mu       sync.Mutex
+	template *types.ExecutionPayloadV3
+	payloads map[types.PayloadID]*types.ExecutionPayloadV3
+	last     *types.ExecutionPayloadV3
+	forget   bool
+	mixUp    bool
+}
+
+func (e *buildEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	reply := func(result interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}
+	switch {
+	case strings.HasPrefix(req.Method, "engine_forkchoiceUpdated"):
+		var state types.ForkchoiceStateV1
+		var attributes *types.PayloadAttributesV3
+		json.Unmarshal(req.Params[0], &state)
+		json.Unmarshal(req.Params[1], &attributes)
+		result := types.ForkchoiceUpdatedResult{PayloadStatus: types.PayloadStatusV1{Status: types.ExecutionValid}}
+		if attributes == nil {
+			if e.forget {
+				e.payloads = make(map[types.PayloadID]*types.ExecutionPayloadV3)
+			}
+		} else {
+			payload := *e.template
+			payload.ParentHash, payload.Timestamp = state.HeadBlockHash, attributes.Timestamp
+			id := types.PayloadID{byte(len(e.payloads) + 1)}
+			e.payloads[id], e.last = &payload, &payload
+			result.PayloadID = &id
+		}
+		reply(result)
+	case strings.HasPrefix(req.Method, "engine_getPayload"):
+		var id types.PayloadID
+		json.Unmarshal(req.Params[0], &id)
+		payload, ok := e.payloads[id]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": api.UnknownPayload, "message": "Unknown payload"}})
+			return
+		}
+		if e.mixUp {
+			payload = e.last
+		}
+		reply(types.GetPayloadV3Response{ExecutionPayload: payload, BlockValue: (*hexutil.Big)(common.Big0), BlobsBundle: &types.BlobsBundleV1{}})
+	default:
+		http.Error(w, "unexpected method", http.StatusBadRequest)
+	}
+}
+
+func TestProbePayloadIDs(t *testing.T) {
+	for _, mode := range []string{"serve", "forget", "mix-up"} {
+		c := newCheckpointConsensus(t)
+		c.ctx = context.Background()
+		c.report = newScenarioReport()
+		c.RNG = NewRNG(1)
+		c.PayloadID = PayloadIDConfig{Slots: 1, Expiry: time.Minute}
+
+		genesis := c.mockChain.CurrentHeader()
+		block, err := c.mockChain.AddNewBlock(genesis.Hash(), common.Address{1}, c.SlotTimestamp(1), genesis.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, c.makeBeaconRoot(c.SlotTimestamp(1)), false)
+		require.NoError(t, err)
+		template, err := api.BlockToPayloadV3(block, types.Withdrawals{})
+		require.NoError(t, err)
+		engine := &buildEngine{template: template, payloads: make(map[types.PayloadID]*types.ExecutionPayloadV3), forget: mode == "forget", mixUp: mode == "mix-up"}
+		srv := httptest.NewServer(engine)
+		c.engine = dialEngine(t, srv.URL)
+
+		// ids of the genesis block, the first one past the expiry
+		for i := 0; i < 2; i++ {
+			id, err := c.sendForkchoiceUpdated(genesis.Hash(), common.Hash{}, common.Hash{}, c.makePayloadAttributes(1))
+			require.NoError(t, err)
+			require.NotNil(t, id)
+		}
+		c.payloadIDs.ids[0].issued = time.Now().Add(-time.Hour)
+		_, err = c.mockChain.AddNewBlock(genesis.Hash(), common.Address{1}, c.SlotTimestamp(1), genesis.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, c.makeBeaconRoot(c.SlotTimestamp(1)), true)
+		require.NoError(t, err)
+
+		c.probePayloadIDs(1)
+		srv.Close()
+
+		class := map[string]string{"serve": "payload", "forget": "error -38001", "mix-up": "payload"}[mode]
+		for _, scenario := range []string{scenarioPayloadIDForkchoice, scenarioPayloadIDOtherParent, scenarioPayloadIDExpired} {
+			require.Equal(t, 1, c.report.cases[scenario].runs, scenario)
+			if scenario == scenarioPayloadIDForkchoice && mode == "mix-up" {
+				// the last payload is the one of the id
+				require.Empty(t, c.report.cases[scenario].failures)
+				continue
+			}
+			require.Equal(t, map[string]int{class: 1}, c.payloadIDResponses.counts[scenario], scenario)
+			if mode == "mix-up" {
+				require.Len(t, c.report.cases[scenario].failures, 1, scenario)
+			} else {
+				require.Empty(t, c.report.cases[scenario].failures, scenario)
+			}
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"mergemock/api"
+	"mergemock/contracts"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/sirupsen/logrus"
+)
+
+// Limits of the payload the payload limits probe sends blocks at and above.
+const (
+	limitExtraData = "extra-data"
+	limitGas       = "gas"
+	limitLogsBloom = "logs-bloom"
+)
+
+var limitScenarios = map[string]string{
+	limitExtraData: scenarioLimitExtraData,
+	limitGas:       scenarioLimitGas,
+	limitLogsBloom: scenarioLimitLogsBloom,
+}
+
+// limitProbeRecipient is the fee recipient of the blocks at the payload limits, to tell them
+// apart from the external blocks.
+var limitProbeRecipient = common.Address{0xfd}
+
+// fullBloom is the logs bloom with all bits set.
+var fullBloom = ethTypes.BytesToBloom(bytes.Repeat([]byte{0xff}, ethTypes.BloomByteLength))
+
+// PayloadLimitsConfig configures blocks at and just above the limits of the payload, sent to the
+// engine next to external blocks, to check that it enforces the limits like the relay and the
+// execution clients.
+type PayloadLimitsConfig struct {
+	Freq  float64  `ask:"--freq" help:"How often an external block is followed by a sibling block at a limit of the payload, which the engine must accept, and one above it, which the engine must reject"`
+	Kinds []string `ask:"--kinds" help:"Limits of the payload: 'extra-data' for 32 and 33 bytes of extra data, 'gas' for transfers filling the gas limit and one more, 'logs-bloom' for logs filling the logs bloom and a full logs bloom without logs (not the transaction count and size limits, which blocks cannot reach within the gas limit)"`
+}
+
+func (l *PayloadLimitsConfig) Default() {
+	l.Kinds = []string{limitExtraData, limitGas, limitLogsBloom}
+}
+
+// check returns an error for unknown limits, or limits without a test account to send the
+// transactions of their blocks.
+func (l *PayloadLimitsConfig) check(accounts []TestAccount) error {
+	if l.Freq == 0 {
+		return nil
+	}
+	if len(l.Kinds) == 0 {
+		return fmt.Errorf("no payload limit kinds")
+	}
+	for _, kind := range l.Kinds {
+		switch kind {
+		case limitExtraData:
+		case limitGas, limitLogsBloom:
+			if len(accounts) == 0 {
+				return fmt.Errorf("payload limit %q needs a test account", kind)
+			}
+		default:
+			return fmt.Errorf("unrecognized payload limit: %q", kind)
+		}
+	}
+	return nil
+}
+
+// limitProbe is a payload at a limit, which the engine must accept, and one above the limit, or
+// with a logs bloom its logs do not fill, which the engine must reject.
+type limitProbe struct {
+	kind      string
+	at, above bufferedPayload
+}
+
+// buildLimitProbe builds the blocks of a limit as siblings of the block, from the first test
+// account.
+func (c *ConsensusCmd) buildLimitProbe(block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash) (*limitProbe, error) {
+	kind := c.PayloadLimits.Kinds[c.RNG.Intn(len(c.PayloadLimits.Kinds))]
+	var account TestAccount
+	if accounts := c.ConsensusBehavior.TestAccounts.accounts; len(accounts) > 0 {
+		account = accounts[0]
+	}
+	return buildLimitProbe(c.mockChain, account, kind, block.Header(), withdrawals, beaconRoot)
+}
+
+// buildLimitProbe builds the blocks of the limit, with the parent, timestamp and gas limit of
+// the sibling. The blocks are not stored in the chain.
+func buildLimitProbe(mc *MockChain, account TestAccount, kind string, sibling *ethTypes.Header, withdrawals types.Withdrawals, beaconRoot *common.Hash) (*limitProbe, error) {
+	build := func(creator TransactionsCreator, extra []byte) (bufferedPayload, *ethTypes.Block, error) {
+		block, err := mc.AddNewBlock(sibling.ParentHash, limitProbeRecipient, sibling.Time, sibling.GasLimit, creator, sibling.MixDigest, extra, nil, withdrawals, beaconRoot, false)
+		if err != nil {
+			return bufferedPayload{}, nil, err
+		}
+		payload, err := api.BlockToPayloadV3(block, withdrawals)
+		if err != nil {
+			return bufferedPayload{}, nil, err
+		}
+		requests, _ := mc.ExecutionRequests(block.Hash())
+		return bufferedPayload{payload, beaconRoot, requests}, block, nil
+	}
+	// changed returns the payload of the block with the header and transactions
+	changed := func(header *ethTypes.Header, txs []*ethTypes.Transaction, requests types.ExecutionRequests) (bufferedPayload, error) {
+		payload, err := api.BlockToPayloadV3(ethTypes.NewBlockWithHeader(header).WithBody(txs, nil), withdrawals)
+		if err != nil {
+			return bufferedPayload{}, err
+		}
+		return bufferedPayload{payload, beaconRoot, requests}, nil
+	}
+	noTxs := TransactionsCreator{nil, dummyTxCreator}
+	probe := &limitProbe{kind: kind}
+	switch kind {
+	case limitExtraData:
+		at, block, err := build(noTxs, bytes.Repeat([]byte{0xee}, types.MaxExtraDataBytes))
+		if err != nil {
+			return nil, fmt.Errorf("block with %d bytes of extra data: %w", types.MaxExtraDataBytes, err)
+		}
+		// the payload conversion refuses the extra data, so the payload is changed after it
+		header := ethTypes.CopyHeader(block.Header())
+		header.Extra = append(header.Extra, 0xee)
+		above := *at.payload
+		above.ExtraData = header.Extra
+		above.BlockHash = header.Hash()
+		probe.at, probe.above = at, bufferedPayload{&above, beaconRoot, at.requests}
+	case limitGas:
+		creator := TransactionsCreator{[]TestAccount{account}, fillGasTxCreator}
+		at, block, err := build(creator, []byte("gas limit"))
+		if err != nil {
+			return nil, fmt.Errorf("block filling the gas limit: %w", err)
+		}
+		txs := block.Transactions()
+		if len(txs) == 0 {
+			return nil, fmt.Errorf("gas limit %d too low for a transfer", block.GasLimit())
+		}
+		extra := transferTxs(mc.chain.Config(), account, txs[len(txs)-1].Nonce()+1, block.BaseFee(), 1)
+		txs = append(txs[:len(txs):len(txs)], extra...)
+		header := ethTypes.CopyHeader(block.Header())
+		header.TxHash = ethTypes.DeriveSha(ethTypes.Transactions(txs), trie.NewStackTrie(nil))
+		header.GasUsed += params.TxGas
+		above, err := changed(header, txs, at.requests)
+		if err != nil {
+			return nil, err
+		}
+		probe.at, probe.above = at, above
+	case limitLogsBloom:
+		creator := TransactionsCreator{[]TestAccount{account}, bloomTxCreator}
+		at, block, err := build(creator, []byte("logs bloom"))
+		if err != nil {
+			return nil, fmt.Errorf("block filling the logs bloom: %w", err)
+		}
+		if block.Bloom() != fullBloom {
+			return nil, fmt.Errorf("logs of block %s do not fill the logs bloom, for lack of gas or funds", block.Hash())
+		}
+		empty, emptyBlock, err := build(noTxs, []byte("full logs bloom"))
+		if err != nil {
+			return nil, fmt.Errorf("block without logs: %w", err)
+		}
+		header := ethTypes.CopyHeader(emptyBlock.Header())
+		header.Bloom = fullBloom
+		above, err := changed(header, nil, empty.requests)
+		if err != nil {
+			return nil, err
+		}
+		probe.at, probe.above = at, above
+	default:
+		return nil, fmt.Errorf("unrecognized payload limit: %q", kind)
+	}
+	return probe, nil
+}
+
+// transferTxs returns n transfers of the account to itself from the nonce on, for a block with
+// the base fee.
+func transferTxs(config *params.ChainConfig, account TestAccount, nonce uint64, baseFee *big.Int, n uint64) []*ethTypes.Transaction {
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	}
+	tip := big.NewInt(2)
+	feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, common.Big2), tip)
+	signer := ethTypes.LatestSignerForChainID(config.ChainID)
+	to := account.addr
+	txs := make([]*ethTypes.Transaction, 0, n)
+	for i := uint64(0); i < n; i++ {
+		tx, err := ethTypes.SignNewTx(account.pk, signer, &ethTypes.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     nonce + i,
+			GasTipCap: tip,
+			GasFeeCap: feeCap,
+			Gas:       params.TxGas,
+			To:        &to,
+			Value:     common.Big1,
+		})
+		if err != nil {
+			return txs
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// fillGasTxCreator creates as many transfers as fit in the gas limit of the block, the most
+// transactions a block can have.
+func fillGasTxCreator(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+	return transferTxs(config, accounts[0], statedb.GetNonce(accounts[0].addr), header.BaseFee, header.GasLimit/params.TxGas)
+}
+
+// bloomTopics returns the number of topics, counting up from 1, which fill the logs bloom
+// along with the address of their logs.
+func bloomTopics(addr common.Address) uint64 {
+	var bloom ethTypes.Bloom
+	bloom.Add(addr.Bytes())
+	for n := uint64(1); ; n++ {
+		bloom.Add(common.BigToHash(new(big.Int).SetUint64(n)).Bytes())
+		if bloom == fullBloom {
+			return n
+		}
+	}
+}
+
+// bloomTxCreator creates the contract whose constructor emits the logs that fill the logs bloom
+// of the block, if they fit in the gas limit.
+func bloomTxCreator(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+	account := accounts[0]
+	n := bloomTopics(crypto.CreateAddress(account.addr, statedb.GetNonce(account.addr)))
+	// a log and the loop around it take less than 1000 gas
+	gas := params.TxGasContractCreation + 100_000 + 1000*n
+	if gas > header.GasLimit {
+		return nil
+	}
+	input := append(append([]byte{}, contracts.BloomerCode...), common.BigToHash(new(big.Int).SetUint64(n)).Bytes()...)
+	return contractTx(config, statedb, account, nil, input, gas)
+}
+
+// sendLimitProbe sends the payload at the limit, which the engine must not consider invalid,
+// then the payload above the limit, which the engine must consider invalid.
+func (c *ConsensusCmd) sendLimitProbe(log logrus.Ext1FieldLogger, slot uint64, probe *limitProbe) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	scenario := limitScenarios[probe.kind]
+	log = log.WithField("limit", probe.kind)
+	at := probe.at
+	log.WithField("blockhash", at.payload.BlockHash).Info("Sending payload at the limit")
+	res, err := c.newPayload(ctx, log, at.payload, at.beaconRoot, at.requests)
+	if err == nil && (res.Status == types.ExecutionInvalid || res.Status == types.ExecutionInvalidBlockHash) {
+		err = fmt.Errorf("status %s: %s", res.Status, res.ValidationError)
+	}
+	if err != nil {
+		log.WithError(err).Error("Engine rejected payload at the limit")
+		c.report.record(scenario, slot, fmt.Errorf("engine rejected block %s at the %s limit: %w", at.payload.BlockHash, probe.kind, err))
+		c.maybeExit()
+		return
+	}
+	log.WithField("status", res.Status).Info("Engine took payload at the limit")
+
+	above := probe.above
+	log = log.WithField("blockhash", above.payload.BlockHash)
+	log.Info("Sending payload above the limit")
+	res, err = c.newPayload(withFault(ctx), log, above.payload, above.beaconRoot, above.requests)
+	if err != nil {
+		log.WithError(err).Info("Engine rejected payload above the limit")
+		c.report.record(scenario, slot, nil)
+		return
+	}
+	switch res.Status {
+	case types.ExecutionInvalid, types.ExecutionInvalidBlockHash:
+		log.WithField("status", res.Status).Info("Engine rejected payload above the limit")
+		c.report.record(scenario, slot, nil)
+	case types.ExecutionValid:
+		log.Error("Engine accepted payload above the limit")
+		c.report.record(scenario, slot, fmt.Errorf("engine accepted block %s above the %s limit", above.payload.BlockHash, probe.kind))
+		c.maybeExit()
+	default:
+		log.WithField("status", res.Status).Warn("Unexpected status for payload above the limit")
+	}
+}
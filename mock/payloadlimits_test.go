@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadLimits(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	engine := newTestEngine(t, newFundedGenesis(t, account.addr))
+	chain := engine.mockChain()
+	parent := chain.CurrentHeader()
+	sibling := &ethTypes.Header{ParentHash: parent.Hash(), Time: parent.Time + 1, GasLimit: parent.GasLimit}
+
+	// the mock engine takes the blocks at the limits, and rejects those above them
+	for _, kind := range []string{limitExtraData, limitGas, limitLogsBloom} {
+		probe, err := buildLimitProbe(chain, account, kind, sibling, nil, nil)
+		require.NoError(t, err, kind)
+		status, err := engine.backend.NewPayloadV1(ctx, probe.at.payload.V1())
+		require.NoError(t, err, kind)
+		require.Equal(t, types.ExecutionValid, status.Status, kind)
+		status, err = engine.backend.NewPayloadV1(ctx, probe.above.payload.V1())
+		require.NoError(t, err, kind)
+		require.Equal(t, types.ExecutionInvalid, status.Status, kind)
+
+		switch kind {
+		case limitExtraData:
+			require.Len(t, probe.at.payload.ExtraData, types.MaxExtraDataBytes)
+			require.Len(t, probe.above.payload.ExtraData, types.MaxExtraDataBytes+1)
+		case limitGas:
+			require.Len(t, probe.at.payload.Transactions, int(parent.GasLimit/params.TxGas))
+			require.Len(t, probe.above.payload.Transactions, int(parent.GasLimit/params.TxGas)+1)
+			require.Greater(t, probe.above.payload.GasUsed, probe.above.payload.GasLimit)
+		case limitLogsBloom:
+			require.Equal(t, fullBloom, probe.at.payload.LogsBloom)
+			require.Equal(t, fullBloom, probe.above.payload.LogsBloom)
+			require.Empty(t, probe.above.payload.Transactions)
+		}
+	}
+
+	// without the gas for the logs, the logs bloom cannot be filled
+	sibling.GasLimit = 1_000_000
+	_, err := buildLimitProbe(chain, account, limitLogsBloom, sibling, nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "do not fill the logs bloom")
+}
+
+func TestPayloadLimitReports(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	c.RNG = NewRNG(1)
+	c.PayloadLimits.Default()
+	require.NoError(t, c.PayloadLimits.check(nil))
+	c.PayloadLimits.Freq = 1
+	require.Error(t, c.PayloadLimits.check(nil))
+	c.PayloadLimits.Kinds = []string{"block-size"}
+	require.Error(t, c.PayloadLimits.check(nil))
+	c.PayloadLimits.Kinds = []string{limitExtraData}
+	require.NoError(t, c.PayloadLimits.check(nil))
+
+	parent := c.mockChain.CurrentHeader()
+	block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &common.Hash{}, true)
+	require.NoError(t, err)
+	probe, err := c.buildLimitProbe(block, types.Withdrawals{}, &common.Hash{})
+	require.NoError(t, err)
+	require.Equal(t, block.ParentHash(), probe.at.payload.ParentHash)
+	require.Equal(t, limitProbeRecipient, probe.at.payload.FeeRecipient)
+
+	// engines rejecting the block at the limit, or accepting the one above it, fail the scenario
+	for i, status := range []types.ExecutePayloadStatus{types.ExecutionInvalid, types.ExecutionValid, types.ExecutionAccepted} {
+		srv := httptest.NewServer(&statusEngine{status: status})
+		c.engine = dialEngine(t, srv.URL)
+		c.sendLimitProbe(logrus.New(), uint64(i+1), probe)
+		srv.Close()
+	}
+	sc := c.report.cases[scenarioLimitExtraData]
+	require.Equal(t, 2, sc.runs)
+	require.Len(t, sc.failures, 2)
+	require.Contains(t, sc.failures[0], "slot 1: engine rejected block")
+	require.Contains(t, sc.failures[1], "slot 2: engine accepted block")
+}
@@ -0,0 +1,20 @@
+package mock
+
+import (
+	"net/http/pprof"
+
+	"github.com/gorilla/mux"
+)
+
+// pathPprof is the prefix of the net/http/pprof endpoints.
+const pathPprof = "/debug/pprof/"
+
+// registerPprof serves the runtime profiles and goroutine dumps of net/http/pprof on the router:
+// the index and named profiles like heap and goroutine, the CPU profile and the execution trace.
+func registerPprof(router *mux.Router) {
+	router.HandleFunc(pathPprof+"cmdline", pprof.Cmdline)
+	router.HandleFunc(pathPprof+"profile", pprof.Profile)
+	router.HandleFunc(pathPprof+"symbol", pprof.Symbol)
+	router.HandleFunc(pathPprof+"trace", pprof.Trace)
+	router.PathPrefix(pathPprof).HandlerFunc(pprof.Index)
+}
@@ -0,0 +1,31 @@
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPprof(t *testing.T) {
+	relay := newTestRelay(t)
+	require.Equal(t, http.StatusNotFound, relay.testRequest(t, "GET", pathPprof, nil).Code)
+
+	relay.pprof = true
+	rr := relay.testRequest(t, "GET", pathPprof, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "goroutine")
+	// the admin API stays up while the relay is down
+	relay.health.set(healthDown)
+	rr = relay.testRequest(t, "GET", pathPprof+"goroutine?debug=1", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "goroutine profile")
+	require.Equal(t, http.StatusServiceUnavailable, relay.testRequest(t, "GET", pathStatus, nil).Code)
+
+	c := &ConsensusCmd{Pprof: true}
+	rr = httptest.NewRecorder()
+	c.beaconRouter().ServeHTTP(rr, httptest.NewRequest("GET", pathPprof+"heap?debug=1", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), "heap profile")
+}
@@ -0,0 +1,128 @@
+package mock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Preset is a named combination of behaviors, calibrated to conditions seen on mainnet. Each
+// mock applies its share of a preset: the consensus mock its gap slots, reorgs, finality,
+// participation and getHeader timeout, the relay mock its late bids, and the engine mock its
+// latency. Zero values leave the behavior as it is.
+type Preset struct {
+	GapSlot        float64
+	ReorgFreq      float64
+	ReorgMaxDepth  uint64
+	Finality       float64
+	Participation  float64
+	BuilderTimeout time.Duration
+
+	LateBid      float64
+	LateBidDelay time.Duration
+
+	LatencyFreq float64
+	LatencyMin  time.Duration
+	LatencyMax  time.Duration
+}
+
+// mevBoostTimeout is the default getHeader timeout of mev-boost.
+const mevBoostTimeout = 950 * time.Millisecond
+
+var presets = map[string]Preset{
+	// many proposers offline, like the loss of finality in May 2023: blocks go missing,
+	// participation drops below two thirds, and epochs do not finalize
+	"missed-slots": {
+		GapSlot:       0.25,
+		ReorgFreq:     0.1,
+		ReorgMaxDepth: 2,
+		Finality:      0.01,
+		Participation: 0.6,
+	},
+	// builders bidding late in the slot: bids miss the getHeader timeout, and the late blocks of
+	// the proposers that wait for them are reorged out by the next proposer
+	"late-bids": {
+		ReorgFreq:      0.1,
+		ReorgMaxDepth:  1,
+		BuilderTimeout: mevBoostTimeout,
+		LateBid:        0.25,
+		LateBidDelay:   1500 * time.Millisecond,
+	},
+	// a relay that stops answering in time, and every proposer falls back to local payloads
+	"relay-outage": {
+		BuilderTimeout: mevBoostTimeout,
+		LateBid:        1,
+		LateBidDelay:   3 * time.Second,
+	},
+	// execution clients struggling with their load, answering the engine API late, and
+	// proposers missing their slots
+	"slow-el": {
+		GapSlot:     0.1,
+		LatencyFreq: 0.25,
+		LatencyMin:  time.Second,
+		LatencyMax:  4 * time.Second,
+	},
+	// a bit of everything at once
+	"mainnet-bad-day": {
+		GapSlot:        0.1,
+		ReorgFreq:      0.1,
+		ReorgMaxDepth:  2,
+		Finality:       0.05,
+		Participation:  0.85,
+		BuilderTimeout: mevBoostTimeout,
+		LateBid:        0.2,
+		LateBidDelay:   1500 * time.Millisecond,
+		LatencyFreq:    0.1,
+		LatencyMin:     500 * time.Millisecond,
+		LatencyMax:     3 * time.Second,
+	},
+}
+
+// lookupPreset returns the preset of the name, or nil for an empty name.
+func lookupPreset(name string) (*Preset, error) {
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q", name)
+	}
+	return &p, nil
+}
+
+// presetValue sets the value to that of the preset, unless the preset leaves it as it is, or
+// its flag changed it from the default.
+func presetValue[T comparable](v *T, def, preset T) {
+	var zero T
+	if preset != zero && *v == def {
+		*v = preset
+	}
+}
+
+// applyConsensus applies the share of the preset of the consensus mock.
+func (p *Preset) applyConsensus(c *ConsensusCmd) {
+	var def ConsensusBehavior
+	def.Default()
+	presetValue(&c.Freq.GapSlot, def.Freq.GapSlot, p.GapSlot)
+	presetValue(&c.Freq.ReorgFreq, def.Freq.ReorgFreq, p.ReorgFreq)
+	presetValue(&c.ReorgMaxDepth, def.ReorgMaxDepth, p.ReorgMaxDepth)
+	presetValue(&c.Freq.Finality, def.Freq.Finality, p.Finality)
+	presetValue(&c.Participation, def.Participation, p.Participation)
+	presetValue(&c.BuilderTimeout, 0, p.BuilderTimeout)
+}
+
+// applyRelay applies the share of the preset of the relay mock.
+func (p *Preset) applyRelay(r *RelayCmd) {
+	var def BidFaults
+	def.Default()
+	presetValue(&r.Bids.Late, def.Late, p.LateBid)
+	presetValue(&r.Bids.LateDelay, def.LateDelay, p.LateBidDelay)
+}
+
+// applyEngine applies the share of the preset of the engine mock.
+func (p *Preset) applyEngine(c *EngineCmd) {
+	var def EngineLatency
+	def.Default()
+	presetValue(&c.Latency.Freq, def.Freq, p.LatencyFreq)
+	presetValue(&c.Latency.Min, def.Min, p.LatencyMin)
+	presetValue(&c.Latency.Max, def.Max, p.LatencyMax)
+}
@@ -0,0 +1,74 @@
+package mock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresets(t *testing.T) {
+	preset, err := lookupPreset("")
+	require.NoError(t, err)
+	require.Nil(t, preset)
+	_, err = lookupPreset("good-day")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown preset")
+
+	// every preset is listed in the help of the flags
+	for _, cmd := range []interface{}{ConsensusCmd{}, RelayCmd{}, EngineCmd{}} {
+		field, ok := reflect.TypeOf(cmd).FieldByName("Preset")
+		require.True(t, ok)
+		for name := range presets {
+			require.Contains(t, field.Tag.Get("help"), "'"+name+"'")
+		}
+	}
+
+	// the preset changes the defaults, but not the flags set to other values
+	c := &ConsensusCmd{}
+	c.Default()
+	c.ConsensusBehavior.Default()
+	c.Freq.GapSlot = 0.5
+	preset, err = lookupPreset("mainnet-bad-day")
+	require.NoError(t, err)
+	preset.applyConsensus(c)
+	require.Equal(t, 0.5, c.Freq.GapSlot)
+	require.Equal(t, 0.1, c.Freq.ReorgFreq)
+	require.Equal(t, uint64(2), c.ReorgMaxDepth)
+	require.Equal(t, 0.85, c.Participation)
+	require.Equal(t, mevBoostTimeout, c.BuilderTimeout)
+	require.Equal(t, 0.95, c.SyncParticipation)
+
+	r := &RelayCmd{}
+	r.Bids.Default()
+	preset, err = lookupPreset("relay-outage")
+	require.NoError(t, err)
+	preset.applyRelay(r)
+	require.Equal(t, 1.0, r.Bids.Late)
+	require.Equal(t, 3*time.Second, r.Bids.LateDelay)
+
+	e := &EngineCmd{}
+	e.Latency.Default()
+	e.Latency.Max = 5 * time.Second
+	preset, err = lookupPreset("slow-el")
+	require.NoError(t, err)
+	preset.applyEngine(e)
+	require.Equal(t, 0.25, e.Latency.Freq)
+	require.Equal(t, time.Second, e.Latency.Min)
+	require.Equal(t, 5*time.Second, e.Latency.Max)
+}
+
+func TestEngineLatency(t *testing.T) {
+	var latency EngineLatency
+	latency.Default()
+	start := time.Now()
+	latency.wait(logrus.New(), "engine_newPayload")
+	require.Less(t, time.Since(start), latency.Min)
+
+	latency.Freq, latency.Min, latency.Max = 1, 20*time.Millisecond, 40*time.Millisecond
+	start = time.Now()
+	latency.wait(logrus.New(), "engine_newPayload")
+	require.GreaterOrEqual(t, time.Since(start), latency.Min)
+}
@@ -0,0 +1,134 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pathHealthz = "/healthz"
+	pathReadyz  = "/readyz"
+
+	// engineProbeTimeout is how long the readiness probe waits for the engine.
+	engineProbeTimeout = 2 * time.Second
+)
+
+// probeResponse is the answer to a probe, with the outcome of every check by name.
+type probeResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// serveProbe answers 200 OK if every check passed, and 503 Service Unavailable otherwise.
+func serveProbe(w http.ResponseWriter, checks map[string]error) {
+	response := probeResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checks[name]; err != nil {
+			response.Status = "unavailable"
+			response.Checks[name] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			response.Checks[name] = "ok"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleHealthz answers the liveness probe, which passes as long as the process serves it.
+func handleHealthz(w http.ResponseWriter, req *http.Request) {
+	serveProbe(w, nil)
+}
+
+// slotProgress is the latest slot of the consensus mock, with the slot of the head it built on,
+// shared with the readiness probe.
+type slotProgress struct {
+	slot uint64
+	head uint64
+	// wall clock time of the slot, in unix nanoseconds, 0 before the first slot
+	at int64
+}
+
+func (p *slotProgress) record(slot uint64, head uint64, at time.Time) {
+	atomic.StoreUint64(&p.slot, slot)
+	atomic.StoreUint64(&p.head, head)
+	atomic.StoreInt64(&p.at, at.UnixNano())
+}
+
+// check returns an error if the last slot started longer than the number of slots ago, or
+// the head it built on was more than the number of slots behind it.
+func (p *slotProgress) check(now time.Time, slots uint64, slotTime time.Duration) error {
+	at := atomic.LoadInt64(&p.at)
+	if at == 0 {
+		return fmt.Errorf("no slot started yet")
+	}
+	if since := now.Sub(time.Unix(0, at)); since > time.Duration(slots)*slotTime {
+		return fmt.Errorf("no slot started in %s", since.Round(time.Millisecond))
+	}
+	slot, head := atomic.LoadUint64(&p.slot), atomic.LoadUint64(&p.head)
+	if slot > head+slots {
+		return fmt.Errorf("head is %d slots behind slot %d", slot-head, slot)
+	}
+	return nil
+}
+
+// readinessChecks returns the checks of the consensus mock: the engine answers, and the chain
+// progresses within --ready-slots.
+func (c *ConsensusCmd) readinessChecks(ctx context.Context) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, engineProbeTimeout)
+	defer cancel()
+	var head map[string]interface{}
+	checks := map[string]error{"engine": c.engine.CallContext(ctx, &head, "eth_getBlockByNumber", "latest", false)}
+	if c.ReadySlots > 0 {
+		checks["chain"] = c.progress.check(time.Now(), c.ReadySlots, time.Duration(float64(c.SlotTime)/c.TimeScale))
+	}
+	return checks
+}
+
+// startProbes serves the liveness and readiness probes of the consensus mock.
+func (c *ConsensusCmd) startProbes(log logrus.Ext1FieldLogger) {
+	router := mux.NewRouter()
+	router.HandleFunc(pathHealthz, handleHealthz).Methods(http.MethodGet)
+	router.HandleFunc(pathReadyz, func(w http.ResponseWriter, req *http.Request) {
+		serveProbe(w, c.readinessChecks(req.Context()))
+	}).Methods(http.MethodGet)
+	c.probeSrv = &http.Server{Addr: c.ProbeAddr, Handler: router}
+	go func() {
+		if err := c.probeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("Probes stopped")
+		}
+	}()
+	log.WithField("addr", c.ProbeAddr).Info("Serving health and readiness probes")
+}
+
+// handleReadyz answers the readiness probe of the relay: it is not down, and its engine runs
+// with a head block in the chain database.
+func (r *RelayBackend) handleReadyz(w http.ResponseWriter, req *http.Request) {
+	checks := map[string]error{"health": nil, "storage": nil}
+	if health := r.health.get(); health == healthDown {
+		checks["health"] = fmt.Errorf("relay is %s", health)
+	}
+	if r.engine.backend == nil {
+		checks["storage"] = fmt.Errorf("engine not started")
+	} else if rawdb.ReadHeadBlockHash(r.engine.mockChain().database) == (common.Hash{}) {
+		checks["storage"] = fmt.Errorf("no head block in the chain database")
+	}
+	serveProbe(w, checks)
+}
@@ -0,0 +1,69 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotProgress(t *testing.T) {
+	var p slotProgress
+	now := time.Now()
+	require.EqualError(t, p.check(now, 4, time.Second), "no slot started yet")
+	p.record(10, 8, now)
+	require.NoError(t, p.check(now.Add(time.Second), 4, time.Second))
+	require.EqualError(t, p.check(now.Add(5*time.Second), 4, time.Second), "no slot started in 5s")
+	p.record(20, 8, now)
+	require.EqualError(t, p.check(now, 4, time.Second), "head is 12 slots behind slot 20")
+}
+
+func TestConsensusProbes(t *testing.T) {
+	engine := new(fakeEngine)
+	srv, addr := serveFakeEngine(t, "127.0.0.1:0", engine)
+	c := &ConsensusCmd{SlotTime: time.Second, TimeScale: 1}
+	c.engine = dialEngine(t, "http://"+addr)
+
+	checks := c.readinessChecks(context.Background())
+	require.NoError(t, checks["engine"])
+	require.NotContains(t, checks, "chain")
+	require.Equal(t, []string{"eth_getBlockByNumber"}, engine.methods)
+
+	c.ReadySlots = 2
+	c.progress.record(3, 3, time.Now())
+	require.NoError(t, c.readinessChecks(context.Background())["chain"])
+
+	srv.Close()
+	require.Error(t, c.readinessChecks(context.Background())["engine"])
+}
+
+func TestRelayProbes(t *testing.T) {
+	relay := newTestRelay(t)
+	probe := func(path string) (int, probeResponse) {
+		rr := relay.testRequest(t, "GET", path, nil)
+		var response probeResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr.Code, response
+	}
+	code, response := probe(pathReadyz)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "engine not started", response.Checks["storage"])
+
+	require.NoError(t, relay.engine.Run(context.Background()))
+	defer relay.engine.Close()
+	code, response = probe(pathReadyz)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, probeResponse{Status: "ok", Checks: map[string]string{"health": "ok", "storage": "ok"}}, response)
+
+	// a down relay is alive, but not ready
+	relay.health.set(healthDown)
+	code, response = probe(pathReadyz)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "relay is down", response.Checks["health"])
+	code, response = probe(pathHealthz)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "ok", response.Status)
+}
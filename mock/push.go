@@ -0,0 +1,278 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the pushed metrics.
+const (
+	pushPushgateway = "pushgateway"
+	pushOTLP        = "otlp"
+)
+
+// MetricsPush configures the push of the metrics of a consensus run when it ends, for CI runs
+// too short-lived to be scraped.
+type MetricsPush struct {
+	URL     string        `ask:"--url" help:"Base URL of the Prometheus Pushgateway or OTLP/HTTP collector to push the metrics of the run to when it ends (empty to disable)"`
+	Format  string        `ask:"--format" help:"Protocol of the push: 'pushgateway' for the text format to /metrics/job/<job>, 'otlp' for OTLP/HTTP JSON to /v1/metrics"`
+	Job     string        `ask:"--job" help:"Job label of the Pushgateway group, and service name of the OTLP resource"`
+	Timeout time.Duration `ask:"--timeout" help:"Timeout of the push"`
+}
+
+func (p *MetricsPush) Default() {
+	p.Format = pushPushgateway
+	p.Job = "mergemock"
+	p.Timeout = 5 * time.Second
+}
+
+// metricFamily is a metric of the run, with a sample by labels.
+type metricFamily struct {
+	name    string
+	help    string
+	counter bool
+	samples []metricSample
+}
+
+type metricSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// runMetrics returns the metrics of the run at its end: the slot, the results of the scenarios,
+// the engine calls by method, and the state growth of the state-growth tx profile.
+func (c *ConsensusCmd) runMetrics(start, end time.Time) []metricFamily {
+	families := []metricFamily{{
+		name:    "mergemock_run_duration_seconds",
+		help:    "Duration of the consensus run.",
+		samples: []metricSample{{value: end.Sub(start).Seconds()}},
+	}}
+	if c.state != nil {
+		families = append(families, metricFamily{
+			name:    "mergemock_slot",
+			help:    "Last slot of the consensus run.",
+			samples: []metricSample{{value: float64(c.state.Slot())}},
+		})
+	}
+	if c.report != nil {
+		runs := metricFamily{name: "mergemock_scenario_runs_total", help: "Runs of the scenario.", counter: true}
+		failures := metricFamily{name: "mergemock_scenario_failures_total", help: "Failed runs of the scenario.", counter: true}
+		c.report.mu.Lock()
+		for _, name := range c.report.names() {
+			sc := c.report.cases[name]
+			labels := map[string]string{"scenario": name}
+			runs.samples = append(runs.samples, metricSample{labels, float64(sc.runs)})
+			failures.samples = append(failures.samples, metricSample{labels, float64(len(sc.failures))})
+		}
+		c.report.mu.Unlock()
+		families = append(families, runs, failures)
+	}
+	if c.engine != nil {
+		metrics := c.engine.Metrics()
+		methods := make([]string, 0, len(metrics))
+		for method := range metrics {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		calls := metricFamily{name: "mergemock_engine_calls_total", help: "Engine calls by method.", counter: true}
+		dropped := metricFamily{name: "mergemock_engine_calls_dropped_total", help: "Engine calls dropped by the limits, by method.", counter: true}
+		queued := metricFamily{name: "mergemock_engine_calls_max_queued", help: "Most engine calls waiting at once for the limits, by method."}
+		for _, method := range methods {
+			m, labels := metrics[method], map[string]string{"method": method}
+			calls.samples = append(calls.samples, metricSample{labels, float64(m.Calls)})
+			dropped.samples = append(dropped.samples, metricSample{labels, float64(m.Dropped)})
+			queued.samples = append(queued.samples, metricSample{labels, float64(m.MaxQueued)})
+		}
+		families = append(families, calls, dropped, queued)
+	}
+	if c.TxProfile == "state-growth" {
+		families = append(families, c.stateGrowthMetrics()...)
+	}
+	return families
+}
+
+// sortedLabels returns the names of the labels, in order.
+func sortedLabels(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// prometheusText encodes the metrics in the text exposition format of Prometheus.
+func prometheusText(families []metricFamily) []byte {
+	var buf bytes.Buffer
+	escape := strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	for _, f := range families {
+		kind := "gauge"
+		if f.counter {
+			kind = "counter"
+		}
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, kind)
+		for _, s := range f.samples {
+			buf.WriteString(f.name)
+			if len(s.labels) > 0 {
+				pairs := make([]string, 0, len(s.labels))
+				for _, name := range sortedLabels(s.labels) {
+					pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escape.Replace(s.labels[name])))
+				}
+				buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+			}
+			buf.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// OTLP/HTTP JSON encoding of the metrics, after opentelemetry-proto.
+type otlpMetrics struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Gauge       *otlpGauge `json:"gauge,omitempty"`
+	Sum         *otlpSum   `json:"sum,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+	// AggregationTemporality 2 is cumulative
+	AggregationTemporality int  `json:"aggregationTemporality"`
+	IsMonotonic            bool `json:"isMonotonic"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          float64         `json:"asDouble"`
+}
+
+type otlpAttribute struct {
+	Key   string          `json:"key"`
+	Value otlpStringValue `json:"value"`
+}
+
+type otlpStringValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// otlpJSON encodes the metrics as an OTLP export request of the service, with counters as
+// cumulative sums since the start of the run.
+func otlpJSON(families []metricFamily, service string, start, end time.Time) ([]byte, error) {
+	scope := otlpScopeMetrics{Scope: otlpScope{Name: "mergemock"}}
+	for _, f := range families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		metric := otlpMetric{Name: f.name, Description: f.help}
+		var points []otlpDataPoint
+		for _, s := range f.samples {
+			point := otlpDataPoint{TimeUnixNano: strconv.FormatInt(end.UnixNano(), 10), AsDouble: s.value}
+			for _, name := range sortedLabels(s.labels) {
+				point.Attributes = append(point.Attributes, otlpAttribute{name, otlpStringValue{s.labels[name]}})
+			}
+			if f.counter {
+				point.StartTimeUnixNano = strconv.FormatInt(start.UnixNano(), 10)
+			}
+			points = append(points, point)
+		}
+		if f.counter {
+			metric.Sum = &otlpSum{DataPoints: points, AggregationTemporality: 2, IsMonotonic: true}
+		} else {
+			metric.Gauge = &otlpGauge{DataPoints: points}
+		}
+		scope.Metrics = append(scope.Metrics, metric)
+	}
+	return json.Marshal(otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: []otlpAttribute{{"service.name", otlpStringValue{service}}}},
+		ScopeMetrics: []otlpScopeMetrics{scope},
+	}}})
+}
+
+// push sends the metrics to the Pushgateway or OTLP collector.
+func (p *MetricsPush) push(families []metricFamily, start, end time.Time) error {
+	base := strings.TrimSuffix(p.URL, "/")
+	var method, target, contentType string
+	var body []byte
+	switch p.Format {
+	case pushPushgateway:
+		// PUT replaces the metrics of an earlier run of the job
+		method, target = http.MethodPut, base+"/metrics/job/"+url.PathEscape(p.Job)
+		contentType = "text/plain; version=0.0.4"
+		body = prometheusText(families)
+	case pushOTLP:
+		method, target, contentType = http.MethodPost, base+"/v1/metrics", "application/json"
+		var err error
+		if body, err = otlpJSON(families, p.Job, start, end); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unrecognized metrics push format: %q", p.Format)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, target, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// pushMetrics pushes the metrics of the run, if enabled.
+func (c *ConsensusCmd) pushMetrics() {
+	if c.Push.URL == "" || c.report == nil {
+		return
+	}
+	start, end := c.report.start, time.Now()
+	if err := c.Push.push(c.runMetrics(start, end), start, end); err != nil {
+		c.log.WithError(err).Error("Failed to push the metrics of the run")
+		return
+	}
+	c.log.WithField("url", c.Push.URL).Info("Pushed the metrics of the run")
+}
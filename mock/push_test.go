@@ -0,0 +1,79 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsPush(t *testing.T) {
+	type pushed struct {
+		method, path, contentType, body string
+	}
+	var requests []pushed
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, pushed{r.Method, r.URL.Path, r.Header.Get("Content-Type"), string(body)})
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := &ConsensusCmd{log: logrus.New(), report: newScenarioReport()}
+	c.Push.Default()
+	c.report.record(scenarioNewPayload, 1, nil)
+	c.report.record(scenarioNewPayload, 2, errors.New("unrecognized execution status SYNCING"))
+	c.report.record(scenarioProposal, 1, nil)
+
+	// disabled without a URL
+	c.pushMetrics()
+	require.Empty(t, requests)
+
+	c.Push.URL = srv.URL + "/"
+	c.pushMetrics()
+	require.Len(t, requests, 1)
+	require.Equal(t, http.MethodPut, requests[0].method)
+	require.Equal(t, "/metrics/job/mergemock", requests[0].path)
+	require.True(t, strings.HasPrefix(requests[0].contentType, "text/plain"))
+	body := requests[0].body
+	require.Contains(t, body, "# TYPE mergemock_scenario_runs_total counter\n")
+	require.Contains(t, body, "mergemock_scenario_runs_total{scenario=\"new-payload\"} 2\n")
+	require.Contains(t, body, "mergemock_scenario_failures_total{scenario=\"new-payload\"} 1\n")
+	require.Contains(t, body, "mergemock_scenario_failures_total{scenario=\"proposal\"} 0\n")
+	require.Contains(t, body, "# TYPE mergemock_run_duration_seconds gauge\n")
+
+	c.Push.Format = pushOTLP
+	c.pushMetrics()
+	require.Len(t, requests, 2)
+	require.Equal(t, http.MethodPost, requests[1].method)
+	require.Equal(t, "/v1/metrics", requests[1].path)
+	require.Equal(t, "application/json", requests[1].contentType)
+	var export otlpMetrics
+	require.NoError(t, json.Unmarshal([]byte(requests[1].body), &export))
+	require.Len(t, export.ResourceMetrics, 1)
+	require.Equal(t, "mergemock", export.ResourceMetrics[0].Resource.Attributes[0].Value.StringValue)
+	metrics := export.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	require.Len(t, metrics, 3)
+	require.NotNil(t, metrics[0].Gauge)
+	require.Equal(t, "mergemock_scenario_failures_total", metrics[2].Name)
+	require.True(t, metrics[2].Sum.IsMonotonic)
+	point := metrics[2].Sum.DataPoints[0]
+	require.Equal(t, []otlpAttribute{{"scenario", otlpStringValue{scenarioNewPayload}}}, point.Attributes)
+	require.Equal(t, 1.0, point.AsDouble)
+	require.NotEmpty(t, point.StartTimeUnixNano)
+
+	// the collector refusing the metrics fails the push
+	status = http.StatusBadRequest
+	start := time.Now()
+	err := c.Push.push(c.runMetrics(start, start), start, start)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "400 Bad Request")
+}
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"mergemock/types"
+	"strconv"
+
+	"github.com/prysmaticlabs/prysm/runtime/version"
+)
+
+// randaoReveal returns the randao reveal of the proposer of the slot: its signature of the
+// epoch. Without validators to sign, the reveal is random.
+func (c *ConsensusCmd) randaoReveal(slot uint64) (types.Signature, error) {
+	var reveal types.Signature
+	if len(c.validators) == 0 {
+		c.RNG.Read(reveal[:])
+		return reveal, nil
+	}
+	domain := types.ComputeDomain(types.DomainTypeRandao, version.Bellatrix, &c.genesisValidatorsRoot)
+	epoch := c.state.EpochAt(slot)
+	root, err := types.ComputeSigningRoot(&types.SigningEpoch{Epoch: epoch}, domain)
+	if err != nil {
+		return reveal, err
+	}
+	return c.signAs(int(c.state.Proposer(slot)), root, &signRequest{
+		Type:         "RANDAO_REVEAL",
+		ForkInfo:     c.bellatrixForkInfo(),
+		RandaoReveal: &signingEpoch{Epoch: strconv.FormatUint(epoch, 10)},
+	})
+}
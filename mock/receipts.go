@@ -0,0 +1,80 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// receiptsMismatchError is the error of a payload with a receipts root or logs bloom other than
+// those of the receipts the mock derived from its transactions, with the differences by transaction.
+type receiptsMismatchError struct {
+	field string
+	have  interface{}
+	want  interface{}
+	diffs []string
+}
+
+func (e *receiptsMismatchError) Error() string {
+	return fmt.Sprintf("%s difference: %s <> %s (%s)", e.field, e.want, e.have, strings.Join(e.diffs, "; "))
+}
+
+// newReceiptsMismatch describes the receipts of the mock against the logs bloom of the payload. Every
+// transaction is described for a receipts root mismatch, as the root hides which receipt differs.
+// For a logs bloom mismatch, only the logs missing from the payload bloom, and the bits of the
+// payload bloom that no log sets, are.
+func newReceiptsMismatch(field string, have, want interface{}, receipts []*types.Receipt, bloom types.Bloom) *receiptsMismatchError {
+	err := &receiptsMismatchError{field: field, have: have, want: want}
+	var covered types.Bloom
+	for i, receipt := range receipts {
+		if field == "receipt root" {
+			err.diffs = append(err.diffs, fmt.Sprintf("tx %d %s: status %d, cumulative gas %d, %d logs",
+				i, receipt.TxHash, receipt.Status, receipt.CumulativeGasUsed, len(receipt.Logs)))
+		}
+		for j, log := range receipt.Logs {
+			logBloom := types.BytesToBloom(types.LogsBloom([]*types.Log{log}))
+			if !bloomContains(bloom, logBloom) {
+				err.diffs = append(err.diffs, fmt.Sprintf("tx %d %s: log %d of %s not in logs bloom", i, receipt.TxHash, j, log.Address))
+			}
+			for k := range covered {
+				covered[k] |= logBloom[k]
+			}
+		}
+	}
+	if extra := bloomBitsNotIn(bloom, covered); extra > 0 {
+		err.diffs = append(err.diffs, fmt.Sprintf("logs bloom sets %d bits of no log", extra))
+	}
+	if len(err.diffs) == 0 {
+		err.diffs = append(err.diffs, "no transactions")
+	}
+	return err
+}
+
+// logReceiptsMismatch logs the differences by transaction of a receipts mismatch, one per line.
+func logReceiptsMismatch(log logrus.Ext1FieldLogger, err error) {
+	var mismatch *receiptsMismatchError
+	if !errors.As(err, &mismatch) {
+		return
+	}
+	for _, diff := range mismatch.diffs {
+		log.WithField("field", mismatch.field).Error(diff)
+	}
+}
+
+// bloomContains returns true if all bits of sub are set in bloom.
+func bloomContains(bloom, sub types.Bloom) bool {
+	return bloomBitsNotIn(sub, bloom) == 0
+}
+
+// bloomBitsNotIn counts the bits set in bloom, but not in other.
+func bloomBitsNotIn(bloom, other types.Bloom) (n int) {
+	for i := range bloom {
+		for b := bloom[i] &^ other[i]; b != 0; b &= b - 1 {
+			n++
+		}
+	}
+	return n
+}
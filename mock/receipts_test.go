@@ -0,0 +1,71 @@
+package mock
+
+import (
+	"testing"
+
+	"mergemock/api"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiptsMismatch(t *testing.T) {
+	transfer := &types.Log{Address: common.Address{1}, Topics: []common.Hash{{2}}}
+	churn := &types.Log{Address: common.Address{3}, Topics: []common.Hash{{4}}}
+	receipts := []*types.Receipt{
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 50_000, TxHash: common.Hash{0xa}, Logs: []*types.Log{transfer}},
+		{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 80_000, TxHash: common.Hash{0xb}},
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 120_000, TxHash: common.Hash{0xc}, Logs: []*types.Log{churn}},
+	}
+
+	// a bloom of the first log, and another address
+	bloom := types.BytesToBloom(types.LogsBloom([]*types.Log{transfer, {Address: common.Address{5}}}))
+	err := newReceiptsMismatch("logs bloom", "have", "want", receipts, bloom)
+	require.Equal(t, []string{
+		"tx 2 " + common.Hash{0xc}.Hex() + ": log 0 of " + common.Address{3}.Hex() + " not in logs bloom",
+		"logs bloom sets 3 bits of no log",
+	}, err.diffs)
+
+	// every receipt is described for a receipts root mismatch
+	bloom = types.BytesToBloom(types.LogsBloom([]*types.Log{transfer, churn}))
+	err = newReceiptsMismatch("receipt root", common.Hash{1}, common.Hash{2}, receipts, bloom)
+	require.Len(t, err.diffs, 3)
+	require.Equal(t, "tx 1 "+common.Hash{0xb}.Hex()+": status 0, cumulative gas 80000, 0 logs", err.diffs[1])
+	require.Contains(t, err.Error(), "receipt root difference: "+common.Hash{2}.Hex()+" <> "+common.Hash{1}.Hex())
+}
+
+func TestProcessPayloadReceipts(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	genesis := newMergedGenesis(account.addr)
+	builder, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	chain, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+
+	parent := builder.CurrentHeader()
+	block, err := builder.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, TransactionsCreator{[]TestAccount{account}, dummyTxCreator}, common.Hash{}, nil, nil, nil, nil, true)
+	require.NoError(t, err)
+
+	payload, err := api.BlockToPayload(block)
+	require.NoError(t, err)
+	payload.LogsBloom[0] = 0x81
+	_, err = chain.ProcessPayload(payload)
+	var mismatch *receiptsMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, []string{"logs bloom sets 2 bits of no log"}, mismatch.diffs)
+
+	payload.ReceiptsRoot = common.Hash{1}
+	_, err = chain.ProcessPayload(payload)
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, "receipt root", mismatch.field)
+	require.Equal(t, []string{
+		"tx 0 " + block.Transactions()[0].Hash().Hex() + ": status 1, cumulative gas 21000, 0 logs",
+		"logs bloom sets 2 bits of no log",
+	}, mismatch.diffs)
+}
@@ -0,0 +1,131 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+var errEngineOffline = errors.New("engine is offline")
+
+// bufferedPayload is a canonical payload the engine missed while offline.
+type bufferedPayload struct {
+	payload    *types.ExecutionPayloadV3
+	beaconRoot *common.Hash
+	requests   types.ExecutionRequests
+}
+
+// engineBuffer tracks whether the engine can be reached, and buffers the payloads it misses
+// meanwhile, to resubmit once it is back.
+type engineBuffer struct {
+	mu       sync.Mutex
+	offline  bool
+	payloads []bufferedPayload
+}
+
+func (b *engineBuffer) isOffline() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.offline
+}
+
+// isConnectionError returns true if the error is a failure to reach the engine, rather than an
+// error response of the engine.
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// engineOffline returns true if the engine call failed because the engine is offline, and
+// marks it offline if it was not yet.
+func (c *ConsensusCmd) engineOffline(err error) bool {
+	if errors.Is(err, errEngineOffline) {
+		return true
+	}
+	if !isConnectionError(err) {
+		return false
+	}
+	c.offline.mu.Lock()
+	defer c.offline.mu.Unlock()
+	if !c.offline.offline {
+		c.log.WithError(err).Warn("Lost connection to engine, buffering payloads until it is back")
+		c.offline.offline = true
+	}
+	return true
+}
+
+// exitUnlessOffline reports the scenario of the slot as failed, and exits after a failed engine
+// call in runs with a slot bound, unless the engine is offline: then the run continues, and
+// catches the engine up once it is back.
+func (c *ConsensusCmd) exitUnlessOffline(scenario string, slot uint64, err error) {
+	if err != nil && c.engineOffline(err) {
+		return
+	}
+	c.report.record(scenario, slot, err)
+	c.maybeExit()
+}
+
+// submitPayload sends a canonical payload to the engine, or buffers it if the engine is offline.
+func (c *ConsensusCmd) submitPayload(ctx context.Context, log logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	buffered := bufferedPayload{payload, beaconRoot, requests}
+	c.offline.mu.Lock()
+	if c.offline.offline {
+		c.offline.payloads = append(c.offline.payloads, buffered)
+		c.offline.mu.Unlock()
+		return nil, errEngineOffline
+	}
+	c.offline.mu.Unlock()
+	res, err := c.newPayload(ctx, log, payload, beaconRoot, requests)
+	if err != nil && c.engineOffline(err) {
+		c.offline.mu.Lock()
+		c.offline.payloads = append(c.offline.payloads, buffered)
+		c.offline.mu.Unlock()
+		return nil, errEngineOffline
+	}
+	return res, err
+}
+
+// resubmit tries to catch the engine up if it is offline: it replays the buffered payloads that
+// are still canonical, and then updates the forkchoice to the head. It returns true if the
+// engine is online.
+func (c *ConsensusCmd) resubmit(safe, final common.Hash) bool {
+	c.offline.mu.Lock()
+	if !c.offline.offline {
+		c.offline.mu.Unlock()
+		return true
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	for len(c.offline.payloads) > 0 {
+		p := c.offline.payloads[0]
+		if c.mockChain.chain.GetCanonicalHash(p.payload.Number) == p.payload.BlockHash {
+			log := c.log.WithField("blockhash", p.payload.BlockHash)
+			if _, err := c.newPayload(ctx, log, p.payload, p.beaconRoot, p.requests); err != nil {
+				if isConnectionError(err) {
+					c.offline.mu.Unlock()
+					return false
+				}
+				log.WithError(err).Error("Failed to resubmit payload")
+			}
+		}
+		c.offline.payloads = c.offline.payloads[1:]
+	}
+	c.offline.offline = false
+	c.offline.mu.Unlock()
+
+	head := c.mockChain.CurrentHeader().Hash()
+	c.log.WithField("head", head).Info("Engine is back, resubmitted buffered payloads")
+	if _, err := c.sendForkchoiceUpdated(head, safe, final, nil); err != nil {
+		c.log.WithError(err).Error("Failed to update forkchoice after resubmitting payloads")
+		return !c.engineOffline(err)
+	}
+	return true
+}
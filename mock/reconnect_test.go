@@ -0,0 +1,89 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine is an engine that accepts every call, and records the called methods.
+type fakeEngine struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e.mu.Lock()
+	e.methods = append(e.methods, req.Method)
+	e.mu.Unlock()
+	status := types.PayloadStatusV1{Status: types.ExecutionValid}
+	var result interface{} = status
+	if strings.HasPrefix(req.Method, "engine_forkchoiceUpdated") {
+		result = types.ForkchoiceUpdatedResult{PayloadStatus: status}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+// serveFakeEngine serves the engine at the address, and returns the server and its address.
+func serveFakeEngine(t *testing.T, addr string, engine *fakeEngine) (*http.Server, string) {
+	l, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	srv := &http.Server{Handler: engine}
+	go srv.Serve(l)
+	return srv, l.Addr().String()
+}
+
+func TestEngineRestart(t *testing.T) {
+	engine := new(fakeEngine)
+	srv, addr := serveFakeEngine(t, "127.0.0.1:0", engine)
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.engine = dialEngine(t, "http://"+addr)
+
+	addBlock := func(slot uint64) common.Hash {
+		parent := c.mockChain.CurrentHeader()
+		beaconRoot := common.Hash{byte(slot)}
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.state.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+		require.NoError(t, err)
+		c.mockExecution(logrus.New(), block, types.Withdrawals{}, &beaconRoot)
+		return block.Hash()
+	}
+	addBlock(1)
+	require.False(t, c.offline.isOffline())
+	require.Equal(t, []string{"engine_newPayloadV3"}, engine.methods)
+
+	// the engine goes down, and misses the blocks meanwhile
+	require.NoError(t, srv.Close())
+	addBlock(2)
+	require.True(t, c.offline.isOffline())
+	addBlock(3)
+	require.Len(t, c.offline.payloads, 2)
+	require.False(t, c.resubmit(common.Hash{}, common.Hash{}))
+
+	// once back, it gets the missed blocks, and the forkchoice
+	engine.methods = nil
+	srv, _ = serveFakeEngine(t, addr, engine)
+	defer srv.Close()
+	require.True(t, c.resubmit(common.Hash{}, common.Hash{}))
+	require.False(t, c.offline.isOffline())
+	require.Empty(t, c.offline.payloads)
+	require.Equal(t, []string{"engine_newPayloadV3", "engine_newPayloadV3", "engine_forkchoiceUpdatedV3"}, engine.methods)
+}
@@ -0,0 +1,872 @@
+package mock
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mergemock/rpc"
+	"mergemock/types"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"github.com/holiman/uint256"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	UnknownHash         = -32001
+	UnknownValidator    = -32002
+	UnknownFeeRecipient = -32003
+	InvalidSignature    = -32005
+)
+
+var (
+	errInvalidSlot       = errors.New("invalid slot")
+	errInvalidHash       = errors.New("invalid hash")
+	errInvalidPubkey     = errors.New("invalid pubkey")
+	errInvalidSignature  = errors.New("invalid signature")
+	errInvalidTimestamp  = errors.New("invalid timestamp")
+	errNotRegistered     = errors.New("proposer not registered")
+	errStaleRegistration = errors.New("proposer registration too old")
+	errAlreadyDelivered  = errors.New("payload already delivered for a different block of the slot")
+
+	pathStatus            = "/eth/v1/builder/status"
+	pathRegisterValidator = "/eth/v1/builder/validators"
+	pathGetHeader         = "/eth/v1/builder/header/{slot:[0-9]+}/{parent_hash:0x[a-fA-F0-9]+}/{pubkey:0x[a-fA-F0-9]+}"
+	pathGetPayload        = "/eth/v1/builder/blinded_blocks"
+	pathSubmitBlock       = "/relay/v1/builder/blocks"
+	pathDataDelivered     = "/relay/v1/data/bidtraces/proposer_payload_delivered"
+	pathDataReceived      = "/relay/v1/data/bidtraces/builder_blocks_received"
+	pathDataPayload       = "/relay/v1/data/payload"
+	pathDataPayments      = "/relay/v1/data/proposer_payments"
+	pathDataBidLimits     = "/relay/v1/data/bid_limits"
+	pathDataBuilderKeys   = "/relay/v1/data/builder_keys"
+)
+
+// maxRegistrationDrift is how far in the future the timestamp of a registration may be.
+const maxRegistrationDrift = 10 * time.Second
+
+// RegistrationGating configures which proposers the relay serves getHeader to.
+type RegistrationGating struct {
+	Disable bool          `ask:"--disable" help:"Serve getHeader to every proposer, registered or not"`
+	Grace   time.Duration `ask:"--grace" help:"How long a registration stays recent enough to serve getHeader to its proposer. No expiry if 0."`
+	Status  int           `ask:"--status" help:"Status to answer getHeader of unregistered proposers with: 204 for no bid, or 400"`
+}
+
+func (g *RegistrationGating) Default() {
+	g.Status = http.StatusNoContent
+}
+
+// check returns an error if getHeader is not served to a proposer with the registration, which
+// is nil for unregistered proposers.
+func (g *RegistrationGating) check(reg *types.RegisterValidatorRequestMessage, now time.Time) error {
+	if g.Disable {
+		return nil
+	}
+	if reg == nil {
+		return errNotRegistered
+	}
+	if g.Grace > 0 && now.Sub(time.Unix(int64(reg.Timestamp), 0)) > g.Grace {
+		return errStaleRegistration
+	}
+	return nil
+}
+
+type RelayCmd struct {
+	// connectivity options
+	ListenAddr         string `ask:"--listen-addr" help:"Address to bind relay HTTP server to"`
+	EngineListenAddr   string `ask:"--engine-listen-addr" help:"Address to bind engine JSON-RPC server to"`
+	EngineListenAddrWs string `ask:"--engine-listen-addr-ws" help:"Address to bind engine JSON-RPC WebSocket server to (empty to disable)"`
+
+	// Listener and EngineListener serve the relay and its engine instead of ListenAddr and
+	// EngineListenAddr, when the relay mock is embedded
+	Listener       net.Listener
+	EngineListener net.Listener
+
+	GenesisPath   string `ask:"--genesis" help:"Genesis execution-config file of the engine of the relay"`
+	JwtSecretPath string `ask:"--jwt-secret" help:"JWT secret key of the engine of the relay"`
+
+	// embed timeout and logger options
+	Timeout rpc.Timeout `ask:".timeout" help:"Configure timeouts of the HTTP servers"`
+	LogCmd  `ask:".log" help:"Change logger configuration"`
+
+	GenesisValidatorsRoot types.Root `ask:"--genesis-validators-root" help:"Root of genesis validators"`
+
+	SecretKey string `ask:"--secret-key" help:"The relay's secret key used to sign payloads"`
+
+	StrictJSON bool `ask:"--strict-json" help:"Reject builder and engine API requests with unknown fields, missing fields or hex values of the wrong length"`
+	StrictBLS  bool `ask:"--strict-bls" help:"Reject public keys and signatures in builder API requests that are at infinity or outside the BLS12-381 subgroup, before verifying signatures"`
+
+	BuilderSchema string `ask:"--builder-schema" help:"Validate builder API responses against the builder-specs schemas: 'off', 'warn' to log violations, 'error' to answer with a 500 error instead"`
+
+	Gating RegistrationGating `ask:".gating" help:"Serve getHeader only to proposers with recent validator registrations"`
+
+	Bids BidFaults `ask:".bids" help:"Answer getHeader without a bid, or with bids a proposer must not accept"`
+
+	KeyRotation BuilderKeyRotation `ask:".builder-key" help:"Rotate the builder key of the relay during the run, and sign bids with the previous key"`
+
+	Adjust BidAdjustment `ask:".adjust" help:"Bid on payloads rebuilt without some of their transactions, like bid adjusting relays"`
+
+	Payment BuilderPayment `ask:".payment" help:"Pay the proposer in the blocks the relay builds with its engine, by a last transaction or as fee recipient"`
+
+	Compression Compression `ask:".compression" help:"Configure gzip compression of request and response bodies"`
+
+	Status RelayStatus `ask:".status" help:"Configure the health of the relay, as observed through the builder API"`
+
+	AccessLog AccessLogConfig `ask:".access-log" help:"Log a sample of the requests to the relay with their bodies"`
+
+	Diffs DiffConfig `ask:".diffs" help:"Write the field differences of block submissions that do not match their bid trace, for CI annotations"`
+
+	Pprof bool `ask:"--pprof" help:"Serve the runtime profiles and goroutine dumps of net/http/pprof on the admin API, under /debug/pprof/"`
+
+	BidValue  types.U256Str `ask:"--bid-value" help:"Value of the bids of the relay, in wei, up to 2**256-1"`
+	MinBid    types.U256Str `ask:"--min-bid" help:"Smallest value of the block submissions the relay accepts, in wei: lower ones get a 400 error"`
+	BidFloor  types.U256Str `ask:"--bid-floor" help:"Smallest value of the bids the relay serves, in wei: getHeader answers 204 without a bid below it"`
+	Relays    []string      `ask:"--relays" help:"Behavior profiles of relays to run on consecutive ports from --listen-addr, sharing the engine: 'honest', 'slow', 'withholding', 'low-bid' (empty for a single relay)"`
+	SlowDelay time.Duration `ask:"--slow-delay" help:"How long slow relays take to answer getHeader"`
+
+	Preset string `ask:"--preset" help:"Behavior preset to start from: 'missed-slots', 'late-bids', 'relay-outage', 'slow-el', 'mainnet-bad-day' (empty for none)"`
+
+	close chan struct{}
+	log   *logrus.Logger
+	ctx   context.Context
+	srvs  []*http.Server
+}
+
+func (r *RelayCmd) Default() {
+	r.ListenAddr = "127.0.0.1:28545"
+	r.EngineListenAddr = "127.0.0.1:8551"
+	r.EngineListenAddrWs = "127.0.0.1:8552"
+	r.GenesisPath = "genesis.json"
+	r.JwtSecretPath = "jwt.hex"
+
+	r.Timeout.Read = 30 * time.Second
+	r.Timeout.ReadHeader = 10 * time.Second
+	r.Timeout.Write = 30 * time.Second
+	r.Timeout.Idle = 5 * time.Minute
+
+	sk, _ := bls.RandKey()
+	r.SecretKey = hex.EncodeToString(sk.Marshal())
+
+	r.BidValue = types.IntToU256(10_000_000_000_000_000)
+	r.SlowDelay = 1500 * time.Millisecond
+	r.BuilderSchema = string(rpc.SchemaWarn)
+}
+
+func (r *RelayCmd) Help() string {
+	return "Run a mock builder relay."
+}
+
+func (r *RelayCmd) Examples() []string {
+	return []string{
+		"mergemock relay --listen-addr=127.0.0.1:28545 --bid-value=1000000000000000000",
+		"mergemock relay --relays=honest,slow,low-bid --slow-delay=2s",
+	}
+}
+
+func (r *RelayCmd) Run(ctx context.Context, args ...string) error {
+	r.ctx = ctx
+	r.close = make(chan struct{})
+	if err := r.initLogger(ctx); err != nil {
+		// Logger wasn't initialized so we can't log. Error out instead.
+		return err
+	}
+	preset, err := lookupPreset(r.Preset)
+	if err != nil {
+		return err
+	}
+	if preset != nil {
+		preset.applyRelay(r)
+		r.log.WithField("preset", r.Preset).Info("Applied preset")
+	}
+	profiles := r.Relays
+	if len(profiles) == 0 {
+		profiles = []string{relayHonest}
+	}
+	if r.Listener != nil && len(profiles) > 1 {
+		return fmt.Errorf("a listener serves a single relay, not %d", len(profiles))
+	}
+	host, port, err := net.SplitHostPort(r.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address: %v", err)
+	}
+	basePort, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("invalid listen port: %v", err)
+	}
+	if err := checkHealth(r.Status.Initial); err != nil {
+		return err
+	}
+	schedule, err := parseSchedule(r.Status.Schedule)
+	if err != nil {
+		return err
+	}
+	types.SetStrictBLS(r.StrictBLS)
+	schemas, err := rpc.NewSchemaChecker(rpc.SchemaCheck(r.BuilderSchema), rpc.BuilderSchemas, r.log)
+	if err != nil {
+		return err
+	}
+	if err := r.Payment.load(); err != nil {
+		return err
+	}
+	access := r.AccessLog.AccessLog(r.log)
+	diffs, err := newDiffReporter(r.Diffs)
+	if err != nil {
+		return err
+	}
+
+	backend, err := NewRelayBackend(r.log, r.EngineListenAddr, r.EngineListenAddrWs, r.GenesisValidatorsRoot, r.SecretKey)
+	if err != nil {
+		r.log.WithField("err", err).Fatal("Unable to initialize backend")
+	}
+	backends := []*RelayBackend{backend}
+	for i := 1; i < len(profiles); i++ {
+		// every relay signs its bids with its own key
+		sk, err := bls.RandKey()
+		if err != nil {
+			return err
+		}
+		b, err := newRelayBackend(r.log, backend.engine, r.GenesisValidatorsRoot, sk)
+		if err != nil {
+			return err
+		}
+		backends = append(backends, b)
+	}
+	for i, b := range backends {
+		b.strictJSON = r.StrictJSON
+		b.schemas = schemas
+		b.access = access
+		b.diffs = diffs
+		b.pprof = r.Pprof
+		b.gating = r.Gating
+		b.bids = r.Bids
+		b.keyRotation = r.KeyRotation
+		b.adjust = r.Adjust
+		b.payment = r.Payment
+		b.compression = r.Compression
+		b.value = r.BidValue
+		b.limits.minBid, b.limits.floor = r.MinBid, r.BidFloor
+		b.status = r.Status
+		b.health.set(r.Status.Initial)
+		if err := b.applyProfile(profiles[i], r.SlowDelay); err != nil {
+			return err
+		}
+	}
+	backend.engine.StrictJSON = r.StrictJSON
+	backend.engine.GenesisPath = r.GenesisPath
+	backend.engine.JwtSecretPath = r.JwtSecretPath
+	backend.engine.Listener = r.EngineListener
+	backend.engine.LogCmd = r.LogCmd
+	if err := backend.engine.Run(ctx); err != nil {
+		r.log.WithField("err", err).Fatal("Unable to initialize engine")
+	}
+	for i, b := range backends {
+		addr := net.JoinHostPort(host, strconv.Itoa(basePort+i))
+		if r.Listener != nil {
+			addr = r.Listener.Addr().String()
+		}
+		pk, _ := b.keys.current()
+		r.log.WithField("listenAddr", addr).WithField("profile", profiles[i]).WithField("pubkey", pk.String()).Info("Relay started")
+		r.startRESTApi(b, addr)
+		if len(schedule) > 0 {
+			go b.followSchedule(schedule, ctx.Done())
+		}
+		if r.KeyRotation.Every > 0 {
+			go b.rotateKeys(r.KeyRotation, ctx.Done())
+		}
+	}
+	go func() {
+		for range r.close {
+			for _, srv := range r.srvs {
+				srv.Close()
+			}
+			backend.engine.Close()
+			return
+		}
+	}()
+	return nil
+}
+
+func (r *RelayCmd) Close() error {
+	if r.close != nil {
+		r.close <- struct{}{}
+	}
+	return nil
+}
+
+func (r *RelayCmd) initLogger(ctx context.Context) error {
+	logr, err := r.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	r.log = logr
+	return nil
+}
+
+func (r *RelayCmd) startRESTApi(backend *RelayBackend, addr string) {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: backend.getRouter(),
+
+		ReadTimeout:       r.Timeout.Read,
+		ReadHeaderTimeout: r.Timeout.ReadHeader,
+		WriteTimeout:      r.Timeout.Write,
+		IdleTimeout:       r.Timeout.Idle,
+	}
+	r.srvs = append(r.srvs, srv)
+	go serve(srv, r.Listener)
+}
+
+// Relay behavior profiles.
+const (
+	relayHonest      = "honest"
+	relaySlow        = "slow"
+	relayWithholding = "withholding"
+	relayLowBid      = "low-bid"
+)
+
+// applyProfile changes the behavior of the relay to the profile: slow relays answer getHeader
+// after the delay, withholding relays never answer submitBlindedBlock, and low-bid relays bid a
+// tenth of the value.
+func (r *RelayBackend) applyProfile(profile string, slowDelay time.Duration) error {
+	switch profile {
+	case relayHonest:
+	case relaySlow:
+		r.delay = slowDelay
+	case relayWithholding:
+		r.withhold = true
+	case relayLowBid:
+		r.value = types.Uint256ToU256(new(uint256.Int).Div(r.value.Uint256(), uint256.NewInt(10)))
+	default:
+		return fmt.Errorf("unknown relay profile %q", profile)
+	}
+	return nil
+}
+
+// BidFaults configures how often getHeader answers with each of the response shapes a proposer
+// has to fall back to a local payload for.
+type BidFaults struct {
+	NoBid       float64       `ask:"--no-bid" help:"How often getHeader answers 204 No Content, without a bid"`
+	ZeroValue   float64       `ask:"--zero-value" help:"How often a bid has a zero value"`
+	WrongParent float64       `ask:"--wrong-parent" help:"How often a bid builds on a different parent hash than requested"`
+	Stiff       float64       `ask:"--stiff" help:"How often a bid on a submitted block promises the proposer twice the value the builder pays"`
+	Late        float64       `ask:"--late" help:"How often getHeader answers only after --bids.late-delay, like a bid that arrives after the getHeader timeout of the proposer"`
+	LateDelay   time.Duration `ask:"--late-delay" help:"How long getHeader takes to answer with a late bid"`
+	RNG         RNG           `ask:"--rng" help:"seed the RNG of the bid faults with an integer number"`
+}
+
+func (b *BidFaults) Default() {
+	b.LateDelay = 2 * time.Second
+	b.RNG = NewRNG(DefaultRNGSeed)
+}
+
+type RelayBackend struct {
+	log    *logrus.Logger
+	engine *EngineCmd
+	keys   relayKeys
+
+	genesisValidatorsRoot types.Root
+	registrationsMu       sync.RWMutex
+	registrations         map[types.PublicKey]*types.RegisterValidatorRequestMessage
+	gating                RegistrationGating
+	bids                  BidFaults
+	keyRotation           BuilderKeyRotation
+	adjust                BidAdjustment
+	payment               BuilderPayment
+	value                 types.U256Str
+	limits                bidLimits
+	delay                 time.Duration
+	withhold              bool
+	cache                 relayCache
+	compression           Compression
+	status                RelayStatus
+	health                relayHealth
+
+	latestPubkey types.PublicKey // cache for pubkey from latest getHeader call
+
+	strictJSON bool
+	schemas    *rpc.SchemaChecker
+	access     *rpc.AccessLog
+	diffs      *diffReporter
+	pprof      bool
+}
+
+func NewRelayBackend(log *logrus.Logger, engineListenAddr, engineListenAddrWs string, genesisValidatorsRoot types.Root, secretKey string) (*RelayBackend, error) {
+	engine := &EngineCmd{}
+	engine.Default()
+	engine.LogCmd.Default()
+	engine.ListenAddr = engineListenAddr
+	engine.WebsocketAddr = engineListenAddrWs
+
+	skBytes, err := hex.DecodeString(secretKey)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := bls.SecretKeyFromBytes(skBytes)
+	if err != nil {
+		return nil, err
+	}
+	return newRelayBackend(log, engine, genesisValidatorsRoot, sk)
+}
+
+// newRelayBackend returns a relay that gets its payloads from the engine, and signs bids with
+// the key.
+func newRelayBackend(log *logrus.Logger, engine *EngineCmd, genesisValidatorsRoot types.Root, sk bls.SecretKey) (*RelayBackend, error) {
+	registrations := make(map[types.PublicKey]*types.RegisterValidatorRequestMessage)
+
+	backend := &RelayBackend{
+		log:                   log,
+		engine:                engine,
+		keys:                  newRelayKeys(sk),
+		genesisValidatorsRoot: genesisValidatorsRoot,
+		registrations:         registrations,
+	}
+	backend.gating.Default()
+	backend.value = types.IntToU256(1)
+	backend.bids.Default()
+	backend.adjust.Default()
+	backend.compression.Default()
+	backend.status.Default()
+	return backend, nil
+}
+
+// registration returns the latest registration of the proposer, nil if unregistered.
+func (r *RelayBackend) registration(pubkey types.PublicKey) *types.RegisterValidatorRequestMessage {
+	r.registrationsMu.RLock()
+	defer r.registrationsMu.RUnlock()
+	return r.registrations[pubkey]
+}
+
+func (r *RelayBackend) getRouter() http.Handler {
+	router := mux.NewRouter()
+
+	// Add routes
+	router.HandleFunc(pathStatus, r.handleStatus).Methods(http.MethodGet).Name("status")
+	router.HandleFunc(pathRegisterValidator, r.handleRegisterValidator).Methods(http.MethodPost).Name("registerValidator")
+	router.HandleFunc(pathGetHeader, r.handleGetHeader).Methods(http.MethodGet).Name("getHeader")
+	router.HandleFunc(pathGetPayload, r.handleGetPayload).Methods(http.MethodPost).Name("submitBlindedBlock")
+	router.HandleFunc(pathSubmitBlock, r.handleSubmitBlock).Methods(http.MethodPost)
+	router.HandleFunc(pathDataDelivered, r.handleDataDelivered).Methods(http.MethodGet)
+	router.HandleFunc(pathDataReceived, r.handleDataReceived).Methods(http.MethodGet)
+	router.HandleFunc(pathDataPayload, r.handleDataPayload).Methods(http.MethodGet)
+	router.HandleFunc(pathDataPayments, r.handleDataPayments).Methods(http.MethodGet)
+	router.HandleFunc(pathDataBidLimits, r.handleDataBidLimits).Methods(http.MethodGet)
+	router.HandleFunc(pathDataBuilderKeys, r.handleDataBuilderKeys).Methods(http.MethodGet)
+	router.HandleFunc(pathAdminStatus, r.handleAdminStatus).Methods(http.MethodGet, http.MethodPost)
+	router.HandleFunc(pathHealthz, handleHealthz).Methods(http.MethodGet)
+	router.HandleFunc(pathReadyz, r.handleReadyz).Methods(http.MethodGet)
+	if r.pprof {
+		registerPprof(router)
+	}
+	if r.schemas != nil {
+		router.Use(r.schemaMiddleware)
+	}
+
+	// Add logging and return router
+	loggedRouter := LoggingMiddleware(r.compressionMiddleware(AccessLogMiddleware(r.statusMiddleware(router), r.access)), r.log)
+	return loggedRouter
+}
+
+// decodeJSON decodes the request body into v, with types.DecodeStrict in strict mode.
+func (r *RelayBackend) decodeJSON(req *http.Request, v interface{}) error {
+	if !r.strictJSON {
+		return json.NewDecoder(req.Body).Decode(v)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	return types.DecodeStrict(body, v)
+}
+
+// isSSZ returns whether the request body is SSZ encoded, rather than JSON.
+func isSSZ(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), "application/octet-stream")
+}
+
+// decodeBlindedBlock decodes a signed blinded block of the fork in the Eth-Consensus-Version
+// header. JSON bodies without the header are bellatrix blocks, SSZ bodies are decoded as
+// whichever fork they fit.
+func (r *RelayBackend) decodeBlindedBlock(req *http.Request) (*types.VersionedSignedBlindedBeaconBlock, error) {
+	version := strings.ToLower(req.Header.Get("Eth-Consensus-Version"))
+	if !isSSZ(req) {
+		if version == "" {
+			version = types.VersionBellatrix
+		}
+		block := &types.VersionedSignedBlindedBeaconBlock{Version: version}
+		switch version {
+		case types.VersionBellatrix:
+			block.Bellatrix = new(types.SignedBlindedBeaconBlock)
+			return block, r.decodeJSON(req, block.Bellatrix)
+		case types.VersionCapella:
+			block.Capella = new(types.SignedBlindedBeaconBlockCapella)
+			return block, r.decodeJSON(req, block.Capella)
+		default:
+			return nil, fmt.Errorf("%w: %q", types.ErrUnknownVersion, version)
+		}
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{version}
+	if version == "" {
+		versions = []string{types.VersionCapella, types.VersionBellatrix}
+	}
+	for _, v := range versions {
+		block := &types.VersionedSignedBlindedBeaconBlock{Version: v}
+		if err = block.UnmarshalSSZ(body); err == nil {
+			return block, nil
+		}
+	}
+	return nil, err
+}
+
+// version returns the builder API version of the fork of the payload.
+func (r *RelayBackend) version(payload *types.ExecutionPayloadV3) string {
+	if r.engine.mockChain().forks.IsShanghai(payload.Timestamp) {
+		return types.VersionCapella
+	}
+	return types.VersionBellatrix
+}
+
+func (r *RelayBackend) handleStatus(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `{}`)
+}
+
+func (r *RelayBackend) handleRegisterValidator(w http.ResponseWriter, req *http.Request) {
+	payload := make([]types.SignedValidatorRegistration, 0)
+	if isSSZ(req) {
+		body, err := io.ReadAll(req.Body)
+		if err == nil {
+			payload, err = types.UnmarshalSignedValidatorRegistrations(body)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if err := r.decodeJSON(req, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, reg := range payload {
+		if len(reg.Message.Pubkey) != 48 {
+			http.Error(w, errInvalidPubkey.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(reg.Signature) != 96 {
+			http.Error(w, errInvalidSignature.Error(), http.StatusBadRequest)
+			return
+		}
+		ok, err := types.VerifySignature(reg.Message, types.DomainBuilder, reg.Message.Pubkey[:], reg.Signature[:])
+		if !ok || err != nil {
+			r.log.WithError(err).Error("error verifying signature")
+			http.Error(w, errInvalidSignature.Error(), http.StatusBadRequest)
+			return
+		}
+		if time.Unix(int64(reg.Message.Timestamp), 0).After(time.Now().Add(maxRegistrationDrift)) {
+			http.Error(w, errInvalidTimestamp.Error(), http.StatusBadRequest)
+			return
+		}
+		// A registration only replaces an older one.
+		r.registrationsMu.Lock()
+		if prefs, ok := r.registrations[reg.Message.Pubkey]; ok {
+			if reg.Message.Timestamp <= prefs.Timestamp {
+				r.registrationsMu.Unlock()
+				http.Error(w, errInvalidTimestamp.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		// Note, successful registrations are not reverted if an error
+		// is encountered on a later validator.
+		r.registrations[reg.Message.Pubkey] = reg.Message
+		r.registrationsMu.Unlock()
+	}
+	r.log.Info(fmt.Sprintf("registered %d validator(s) successfully\n", len(payload)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `{}`)
+}
+
+func (r *RelayBackend) handleGetHeader(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	slot := vars["slot"]
+	parentHashHex := vars["parent_hash"]
+	pubkey := vars["pubkey"]
+	slotNum, err := strconv.ParseUint(slot, 10, 64)
+	if err != nil {
+		http.Error(w, errInvalidSlot.Error(), http.StatusBadRequest)
+		return
+	}
+	plog := r.log.WithFields(logrus.Fields{
+		"slot":       slot,
+		"corr":       correlationID(slotNum),
+		"parentHash": parentHashHex,
+		"pubkey":     pubkey,
+	})
+	plog.Info("getHeader")
+
+	delay := r.delay
+	if r.bids.Late > 0 && r.bids.RNG.Float64() < r.bids.Late {
+		plog.WithField("delay", r.bids.LateDelay).Info("Answering getHeader late")
+		delay += r.bids.LateDelay
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	if len(pubkey) != 98 {
+		http.Error(w, errInvalidPubkey.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(parentHashHex) != 66 {
+		http.Error(w, errInvalidHash.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var proposer types.PublicKey
+	if err := proposer.UnmarshalText([]byte(pubkey)); err != nil {
+		http.Error(w, errInvalidPubkey.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := r.gating.check(r.registration(proposer), time.Now()); err != nil {
+		plog.WithError(err).Warn("Not serving getHeader")
+		if r.gating.Status == http.StatusNoContent {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		http.Error(w, err.Error(), r.gating.Status)
+		return
+	}
+
+	// a repeated request gets the same answer
+	key := bidKey{slotNum, common.HexToHash(parentHashHex), proposer}
+	response, cached := r.cache.bid(key)
+	if cached {
+		plog.Debug("Serving cached bid")
+	} else {
+		var served *servedPayload
+		response, served, err = r.makeBid(plog, key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.cache.addBid(key, response, served)
+	}
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	r.latestPubkey = proposer
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// makeBid returns the signed bid on the payload the engine built on the parent, or on the
+// block builders submitted for the request if it is worth more, with the payload and its
+// trace, or no bid at the no-bid rate.
+func (r *RelayBackend) makeBid(plog logrus.Ext1FieldLogger, key bidKey) (*types.VersionedSignedBuilderBid, *servedPayload, error) {
+	if r.bids.RNG.Float64() < r.bids.NoBid {
+		plog.Info("Mocking no bid")
+		return nil, nil, nil
+	}
+
+	var payload *types.ExecutionPayloadV3
+	var value types.U256Str
+	pk, sk := r.keys.current()
+	builder := pk
+	if _payload, ok := r.engine.backend.recentPayloads.Get(key.parent); ok {
+		payload = _payload.(*types.ExecutionPayloadV3)
+		value = r.value
+	}
+	if sub, ok := r.cache.submission(key); ok && (payload == nil || sub.trace.Value.Cmp(value) > 0) {
+		plog.WithField("builder", sub.trace.BuilderPubkey.String()).Info("Bidding on submitted block")
+		payload = sub.payload
+		value = sub.trace.Value
+		builder = sub.trace.BuilderPubkey
+	}
+	if payload == nil {
+		plog.Warn("Cannot get unknown payload")
+		return nil, nil, errors.New("cannot get unknown payload")
+	}
+	feeRecipient := types.Address(payload.FeeRecipient)
+	if reg := r.registration(key.pubkey); reg != nil {
+		feeRecipient = reg.FeeRecipient
+	}
+	if builder == pk && r.payment.enabled() {
+		paid, paidValue, err := r.payProposer(plog, payload, feeRecipient, value)
+		if err != nil {
+			plog.WithError(err).Warn("Cannot build payload paying the proposer, bidding on it unpaid")
+		} else {
+			payload, value = paid, paidValue
+		}
+	}
+	if !r.limits.serve(value) {
+		plog.WithField("value", value.String()).Info("Best bid below the bid floor, not bidding")
+		return nil, nil, nil
+	}
+	if r.adjust.Freq > 0 && r.bids.RNG.Float64() < r.adjust.Freq {
+		// the bid keeps its builder and value, so only the delivered payload tells it apart
+		adjusted, err := r.adjustPayload(plog, payload, feeRecipient)
+		if err != nil {
+			plog.WithError(err).Warn("Cannot adjust payload, bidding on it unadjusted")
+		} else if adjusted != nil {
+			payload = adjusted
+		}
+	}
+	blinded := payload
+	if builder != pk && r.bids.RNG.Float64() < r.bids.Stiff {
+		plog.Info("Mocking bid stiffing the proposer")
+		value = types.Uint256ToU256(new(uint256.Int).Lsh(value.Uint256(), 1))
+	}
+	if r.bids.RNG.Float64() < r.bids.ZeroValue {
+		plog.Info("Mocking zero-value bid")
+		value = types.U256Str{}
+	}
+	if r.bids.RNG.Float64() < r.bids.WrongParent {
+		plog.Info("Mocking bid for wrong parent hash")
+		wrong := *payload
+		wrong.ParentHash[0] ^= 0xff
+		blinded = &wrong
+	}
+
+	response, err := types.NewVersionedBuilderBid(r.version(blinded), blinded, value, pk)
+	if err != nil {
+		plog.Warn("Cannot convert payload to header")
+		return nil, nil, errors.New("cannot convert payload to header")
+	}
+
+	plog.WithField("version", response.Version).Info("Consensus client retrieved prepared payload header")
+
+	bid, err := response.Message()
+	if err != nil {
+		plog.Warn("cannot get bid")
+		return nil, nil, errors.New("cannot get bid")
+	}
+	msg, err := types.ComputeSigningRoot(bid, types.DomainBuilder)
+	if err != nil {
+		plog.Warn("cannot compute signing root")
+		return nil, nil, errors.New("cannot compute signing root")
+	}
+	signer, stale := r.signingKey(sk)
+	if stale {
+		plog.Info("Mocking bid signed with the previous builder key")
+	}
+	var sig types.Signature
+	tmp := signer.Sign(msg[:])
+	copy(sig[:], tmp.Marshal())
+	if err := response.SetSignature(sig); err != nil {
+		plog.Warn("cannot set signature")
+		return nil, nil, errors.New("cannot set signature")
+	}
+
+	trace := types.NewBidTrace(key.slot, blinded, builder, key.pubkey, feeRecipient, value)
+	if builder == pk {
+		// the relay builds its own blocks
+		r.cache.addReceived(withTimestamp(trace, time.Now()))
+	}
+	return response, &servedPayload{key.slot, payload, trace}, nil
+}
+
+func (r *RelayBackend) handleGetPayload(w http.ResponseWriter, req *http.Request) {
+	plog := r.log.WithField("method", "getPayload")
+
+	payload, err := r.decodeBlindedBlock(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	msg, err := payload.Message()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sig, _ := payload.Signature()
+	parentHash, _ := payload.ParentHash()
+	blockHash, _ := payload.BlockHash()
+	slot, _ := payload.Slot()
+	plog = plog.WithField("slot", slot).WithField("corr", correlationID(slot))
+
+	domain := types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &r.genesisValidatorsRoot)
+	ok, err := types.VerifySignature(msg, domain, r.latestPubkey[:], sig[:])
+	if !ok || err != nil {
+		plog.WithError(err).Error("error verifying signature")
+		http.Error(w, errInvalidSignature.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.withhold {
+		plog.Info("Withholding payload")
+		<-req.Context().Done()
+		return
+	}
+
+	// a retry gets the same answer, another block for the same slot is refused
+	root, err := msg.HashTreeRoot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	delivered, ok := r.cache.deliveredAt(slot)
+	if ok && delivered.root != root {
+		plog.Warn("Refusing a second blinded block for the slot")
+		http.Error(w, errAlreadyDelivered.Error(), http.StatusBadRequest)
+		return
+	}
+	response := delivered.response
+	if ok {
+		plog.Debug("Serving delivered payload again")
+	} else {
+		served, ok := r.cache.payload(slot, common.Hash(blockHash))
+		execPayloadEL := served.payload
+		if !ok || execPayloadEL.ParentHash != common.Hash(parentHash) {
+			plog.Warn("Cannot get unknown payload")
+			http.Error(w, "Cannot get unknown payload", http.StatusBadRequest)
+			return
+		}
+		plog.Info(execPayloadEL)
+
+		response, err = types.NewVersionedExecutionPayload(r.version(execPayloadEL), execPayloadEL)
+		if err != nil {
+			plog.Warn("Cannot convert payload to payloadREST")
+			http.Error(w, "cannot convert payload to payloadREST", http.StatusBadRequest)
+			return
+		}
+		r.cache.addDelivered(slot, root, response, served.trace)
+		if r.paysProposer(served.trace.BuilderPubkey) {
+			if payment := r.checkPayment(served.trace, execPayloadEL); payment.Discrepancy != "" {
+				plog.WithField("payment", payment.Method).WithField("discrepancy", payment.Discrepancy).Warn("Delivered payload does not pay the proposer the bid value")
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
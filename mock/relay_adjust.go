@@ -0,0 +1,44 @@
+package mock
+
+import (
+	"math"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// BidAdjustment configures how the relay adjusts bids like relays that bid on a subset of the
+// transactions of a block: the engine rebuilds the payload without its last transactions.
+type BidAdjustment struct {
+	Freq     float64 `ask:"--freq" help:"How often the relay bids on the payload rebuilt by its engine without some of its transactions"`
+	Fraction float64 `ask:"--fraction" help:"Fraction of the transactions of an adjusted payload to remove, from the end, keeping a last transaction that pays the proposer"`
+}
+
+func (a *BidAdjustment) Default() {
+	a.Fraction = 0.5
+}
+
+// adjustPayload returns the payload rebuilt without the last of its transactions, before a
+// last transaction that pays the fee recipient, or nil if it has no transactions to remove.
+func (r *RelayBackend) adjustPayload(plog logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, feeRecipient types.Address) (*types.ExecutionPayloadV3, error) {
+	txs := payload.Transactions
+	var payment [][]byte
+	if _, ok := lastTxPayment(payload, common.Address(feeRecipient)); ok {
+		txs, payment = txs[:len(txs)-1], txs[len(txs)-1:]
+	}
+	remove := int(math.Ceil(r.adjust.Fraction * float64(len(txs))))
+	if remove > len(txs) {
+		remove = len(txs)
+	}
+	if remove == 0 {
+		return nil, nil
+	}
+	kept := append(append([][]byte{}, txs[:len(txs)-remove]...), payment...)
+	adjusted, err := r.engine.backend.rebuildPayload(payload, kept)
+	if err != nil {
+		return nil, err
+	}
+	plog.WithField("removed", remove).WithField("txs", len(kept)).WithField("blockHash", adjusted.BlockHash).Info("Adjusted payload of bid")
+	return adjusted, nil
+}
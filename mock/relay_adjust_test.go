@@ -0,0 +1,104 @@
+package mock
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustPayload(t *testing.T) {
+	faucet, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	builder, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	relay := newTestRelay(t)
+	relay.engine.GenesisPath = newFundedGenesis(t, crypto.PubkeyToAddress(faucet.PublicKey))
+	require.NoError(t, relay.engine.Run(context.Background()))
+	t.Cleanup(func() { relay.engine.Close() })
+	chain := relay.engine.mockChain()
+
+	type transfer struct {
+		key   *ecdsa.PrivateKey
+		to    common.Address
+		value int64
+	}
+	// block creates a block with the transfers, in order
+	block := func(store bool, transfers ...transfer) *types.ExecutionPayloadV3 {
+		creator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+			var txs []*ethTypes.Transaction
+			nonces := make(map[common.Address]uint64)
+			for _, tr := range transfers {
+				to, from := tr.to, crypto.PubkeyToAddress(tr.key.PublicKey)
+				tx, err := ethTypes.SignNewTx(tr.key, ethTypes.LatestSigner(config), &ethTypes.DynamicFeeTx{
+					ChainID:   config.ChainID,
+					Nonce:     statedb.GetNonce(from) + nonces[from],
+					GasTipCap: big.NewInt(params.GWei),
+					GasFeeCap: new(big.Int).Add(header.BaseFee, big.NewInt(params.GWei)),
+					Gas:       params.TxGas,
+					To:        &to,
+					Value:     big.NewInt(tr.value),
+				})
+				require.NoError(t, err)
+				nonces[from]++
+				txs = append(txs, tx)
+			}
+			return txs
+		}}
+		parent := chain.CurrentHeader()
+		b, err := chain.AddNewBlock(parent.Hash(), common.Address{0x01}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, store)
+		require.NoError(t, err)
+		payload, err := api.BlockToPayloadV3(b, nil)
+		require.NoError(t, err)
+		return payload
+	}
+	block(true, transfer{faucet, crypto.PubkeyToAddress(builder.PublicKey), params.Ether})
+
+	// four transfers, and the payment of the builder to the proposer
+	proposer := common.Address{0x42}
+	payload := block(false,
+		transfer{faucet, common.Address{0x43}, 1}, transfer{faucet, common.Address{0x43}, 2},
+		transfer{faucet, common.Address{0x43}, 3}, transfer{faucet, common.Address{0x43}, 4},
+		transfer{builder, proposer, 1000})
+	adjusted, err := relay.adjustPayload(logrus.New(), payload, types.Address(proposer))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{payload.Transactions[0], payload.Transactions[1], payload.Transactions[4]}, adjusted.Transactions)
+	require.Equal(t, payload.ParentHash, adjusted.ParentHash)
+	require.Equal(t, 3*params.TxGas, adjusted.GasUsed)
+	require.NotEqual(t, payload.BlockHash, adjusted.BlockHash)
+	paid, ok := lastTxPayment(adjusted, proposer)
+	require.True(t, ok)
+	require.Equal(t, types.IntToU256(1000), paid)
+
+	// the relay bids on the adjusted payload, and delivers it
+	relay.adjust.Freq = 1
+	relay.engine.backend.recentPayloads.Add(payload.ParentHash, payload)
+	key := bidKey{1, payload.ParentHash, types.PublicKey{0x01}}
+	relay.registrations[key.pubkey] = &types.RegisterValidatorRequestMessage{FeeRecipient: types.Address(proposer), Pubkey: key.pubkey}
+	bid, served, err := relay.makeBid(logrus.New(), key)
+	require.NoError(t, err)
+	blockHash, err := bid.BlockHash()
+	require.NoError(t, err)
+	require.Equal(t, adjusted.BlockHash, common.Hash(blockHash))
+	require.Equal(t, adjusted.BlockHash, served.payload.BlockHash)
+	require.Equal(t, uint64(3), served.trace.NumTx)
+
+	// payloads without transactions to remove are not adjusted
+	empty := block(false, transfer{builder, proposer, 1000})
+	adjusted, err = relay.adjustPayload(logrus.New(), empty, types.Address(proposer))
+	require.NoError(t, err)
+	require.Nil(t, adjusted)
+}
@@ -0,0 +1,200 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	errMissingSubmission   = errors.New("missing bid trace or execution payload")
+	errGasAboveLimit       = errors.New("gas used above the gas limit")
+	errSubmissionMismatch  = errors.New("bid trace does not match the execution payload")
+	errWrongFeeRecipient   = errors.New("proposer fee recipient does not match the registration")
+	errInvalidSlotArgument = errors.New("invalid slot argument")
+	errUnknownPayload      = errors.New("no payload delivered with the block hash")
+)
+
+// withTimestamp returns the trace of a block received at the time.
+func withTimestamp(trace *types.BidTraceV2, received time.Time) types.BidTraceV2WithTimestamp {
+	return types.BidTraceV2WithTimestamp{
+		BidTraceV2:  *trace,
+		Timestamp:   received.Unix(),
+		TimestampMs: received.UnixMilli(),
+	}
+}
+
+// decodeSubmission decodes a block submission of the fork in the Eth-Consensus-Version header,
+// bellatrix without the header, into the signed bid trace and the payload.
+func (r *RelayBackend) decodeSubmission(req *http.Request) (*types.SignedBidTrace, *types.ExecutionPayloadV3, error) {
+	version := strings.ToLower(req.Header.Get("Eth-Consensus-Version"))
+	if version == "" {
+		version = types.VersionBellatrix
+	}
+	signed := new(types.SignedBidTrace)
+	payload := &types.VersionedExecutionPayload{Version: version}
+	switch version {
+	case types.VersionBellatrix:
+		var sub types.BuilderSubmitBlockRequest
+		if err := r.decodeJSON(req, &sub); err != nil {
+			return nil, nil, err
+		}
+		signed.Message, signed.Signature, payload.Bellatrix = sub.Message, sub.Signature, sub.ExecutionPayload
+	case types.VersionCapella:
+		var sub types.BuilderSubmitBlockRequestCapella
+		if err := r.decodeJSON(req, &sub); err != nil {
+			return nil, nil, err
+		}
+		signed.Message, signed.Signature, payload.Capella = sub.Message, sub.Signature, sub.ExecutionPayload
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", types.ErrUnknownVersion, version)
+	}
+	if signed.Message == nil {
+		return nil, nil, errMissingSubmission
+	}
+	p, err := payload.ExecutionPayload()
+	if errors.Is(err, types.ErrPayloadLimit) {
+		// refused like engines refuse the payload
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, errMissingSubmission
+	}
+	if p.GasUsed > p.GasLimit {
+		return nil, nil, fmt.Errorf("%w: %d of %d", errGasAboveLimit, p.GasUsed, p.GasLimit)
+	}
+	return signed, p, nil
+}
+
+// handleSubmitBlock accepts a block from a builder, to bid on in getHeader if it is worth more
+// than the block of the engine.
+func (r *RelayBackend) handleSubmitBlock(w http.ResponseWriter, req *http.Request) {
+	plog := r.log.WithField("method", "submitBlock")
+
+	signed, payload, err := r.decodeSubmission(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	msg := signed.Message
+	plog = plog.WithField("slot", msg.Slot).WithField("corr", correlationID(msg.Slot)).WithField("builder", msg.BuilderPubkey.String()).WithField("value", msg.Value.String())
+
+	ok, err := types.VerifySignature(msg, types.DomainBuilder, msg.BuilderPubkey[:], signed.Signature[:])
+	if !ok || err != nil {
+		plog.WithError(err).Warn("Invalid bid trace signature")
+		http.Error(w, errInvalidSignature.Error(), http.StatusBadRequest)
+		return
+	}
+	if mismatch := submissionMismatch(msg, payload); mismatch != nil {
+		plog.Warn("Bid trace does not match the payload")
+		r.diffs.report(plog, "submitBlock", msg.Slot, mismatch.diffs)
+		http.Error(w, mismatch.Error(), http.StatusBadRequest)
+		return
+	}
+	if !r.limits.admit(msg.Value) {
+		plog.Info("Rejected block submission below the min bid")
+		http.Error(w, errBelowMinBid.Error(), http.StatusBadRequest)
+		return
+	}
+	if reg := r.registration(msg.ProposerPubkey); reg != nil && reg.FeeRecipient != msg.ProposerFeeRecipient {
+		plog.Warn("Bid trace pays the wrong fee recipient")
+		http.Error(w, errWrongFeeRecipient.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trace := &types.BidTraceV2{
+		BidTrace:    *msg,
+		BlockNumber: payload.Number,
+		NumTx:       uint64(len(payload.Transactions)),
+	}
+	key := bidKey{msg.Slot, common.Hash(msg.ParentHash), msg.ProposerPubkey}
+	r.cache.addSubmission(key, servedPayload{msg.Slot, payload, trace})
+	r.cache.addReceived(withTimestamp(trace, time.Now()))
+	plog.Info("Received block submission")
+	w.WriteHeader(http.StatusOK)
+}
+
+// submissionMismatch returns the fields of the bid trace that do not describe the payload, or nil.
+func submissionMismatch(msg *types.BidTrace, payload *types.ExecutionPayloadV3) *mismatchError {
+	expected := *msg
+	expected.ParentHash = types.Hash(payload.ParentHash)
+	expected.BlockHash = types.Hash(payload.BlockHash)
+	expected.GasLimit = payload.GasLimit
+	expected.GasUsed = payload.GasUsed
+	if expected == *msg {
+		return nil
+	}
+	return newMismatch(errSubmissionMismatch.Error(), &expected, msg)
+}
+
+// slotArgument returns the slot query argument of the data API, nil if there is none.
+func slotArgument(req *http.Request) (*uint64, error) {
+	arg := req.URL.Query().Get("slot")
+	if arg == "" {
+		return nil, nil
+	}
+	slot, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return nil, errInvalidSlotArgument
+	}
+	return &slot, nil
+}
+
+// handleDataDelivered returns the traces of the payloads the relay delivered.
+func (r *RelayBackend) handleDataDelivered(w http.ResponseWriter, req *http.Request) {
+	slot, err := slotArgument(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.cache.deliveredTraces(slot))
+}
+
+// handleDataReceived returns the traces of the blocks the relay received from builders, and
+// built with its engine.
+func (r *RelayBackend) handleDataReceived(w http.ResponseWriter, req *http.Request) {
+	slot, err := slotArgument(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.cache.receivedTraces(slot))
+}
+
+// handleDataPayload returns a delivered payload by block hash, as the getPayload response, or
+// SSZ encoded for clients that accept it.
+func (r *RelayBackend) handleDataPayload(w http.ResponseWriter, req *http.Request) {
+	arg := req.URL.Query().Get("block_hash")
+	if len(arg) != 66 {
+		http.Error(w, errInvalidHash.Error(), http.StatusBadRequest)
+		return
+	}
+	payload, ok := r.cache.archived(common.HexToHash(arg))
+	if !ok {
+		http.Error(w, errUnknownPayload.Error(), http.StatusNotFound)
+		return
+	}
+	if strings.Contains(req.Header.Get("Accept"), "application/octet-stream") {
+		enc, err := payload.MarshalSSZ()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Eth-Consensus-Version", payload.Version)
+		w.Write(enc)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
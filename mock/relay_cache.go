@@ -0,0 +1,220 @@
+package mock
+
+import (
+	"sort"
+	"sync"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxCachedSlots is the number of slots the relay keeps served bids and delivered payloads for.
+const maxCachedSlots = 64
+
+// bidKey identifies a getHeader request.
+type bidKey struct {
+	slot   uint64
+	parent common.Hash
+	pubkey types.PublicKey
+}
+
+// payloadKey identifies a served payload, by the slot of the bid and its block hash.
+type payloadKey struct {
+	slot uint64
+	hash common.Hash
+}
+
+type servedPayload struct {
+	slot    uint64
+	payload *types.ExecutionPayloadV3
+	trace   *types.BidTraceV2
+}
+
+type deliveredPayload struct {
+	root     [32]byte
+	response *types.VersionedExecutionPayload
+	trace    *types.BidTraceV2
+}
+
+// relayCache keeps the bids the relay served, to serve the same bid again on a repeated
+// getHeader, and the payloads it delivered, to answer a retried submitBlindedBlock the same.
+// It also keeps the best block builders submitted for each getHeader request, and the traces
+// of all blocks the relay received, for the data API. The delivered payloads stay in the
+// archive after they are pruned, for the whole run.
+type relayCache struct {
+	mu          sync.Mutex
+	bids        map[bidKey]*types.VersionedSignedBuilderBid
+	payloads    map[payloadKey]servedPayload
+	delivered   map[uint64]deliveredPayload
+	submissions map[bidKey]servedPayload
+	received    []types.BidTraceV2WithTimestamp
+	archive     map[common.Hash]*types.VersionedExecutionPayload
+}
+
+// bid returns the bid served for the request before, nil if there was no bid, and whether
+// the request was served before.
+func (c *relayCache) bid(key bidKey) (*types.VersionedSignedBuilderBid, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bid, ok := c.bids[key]
+	return bid, ok
+}
+
+// addBid records the bid served for the request, nil for no bid, with the payload it blinds.
+func (c *relayCache) addBid(key bidKey, bid *types.VersionedSignedBuilderBid, served *servedPayload) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bids == nil {
+		c.bids = make(map[bidKey]*types.VersionedSignedBuilderBid)
+		c.payloads = make(map[payloadKey]servedPayload)
+	}
+	c.bids[key] = bid
+	if served != nil {
+		c.payloads[payloadKey{key.slot, served.payload.BlockHash}] = *served
+	}
+	c.prune(key.slot)
+}
+
+// payload returns the payload of a served bid by block hash, with the trace of the bid for the
+// slot, or of the latest bid on the payload for other slots.
+func (c *relayCache) payload(slot uint64, hash common.Hash) (servedPayload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if served, ok := c.payloads[payloadKey{slot, hash}]; ok {
+		return served, true
+	}
+	var latest servedPayload
+	found := false
+	for key, served := range c.payloads {
+		if key.hash == hash && (!found || key.slot > latest.slot) {
+			latest, found = served, true
+		}
+	}
+	return latest, found
+}
+
+// submission returns the most valuable block submitted for the request.
+func (c *relayCache) submission(key bidKey) (servedPayload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub, ok := c.submissions[key]
+	return sub, ok
+}
+
+// addSubmission records a submitted block for the request, if it is worth more than the
+// blocks submitted before.
+func (c *relayCache) addSubmission(key bidKey, sub servedPayload) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.submissions == nil {
+		c.submissions = make(map[bidKey]servedPayload)
+	}
+	if best, ok := c.submissions[key]; ok && best.trace.Value.Cmp(sub.trace.Value) >= 0 {
+		return
+	}
+	c.submissions[key] = sub
+	c.prune(key.slot)
+}
+
+// addReceived records the trace of a block the relay received.
+func (c *relayCache) addReceived(trace types.BidTraceV2WithTimestamp) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.received = append(c.received, trace)
+	c.prune(trace.Slot)
+}
+
+// receivedTraces returns the traces of the received blocks, of the slot if not nil, in the
+// order they were received.
+func (c *relayCache) receivedTraces(slot *uint64) []types.BidTraceV2WithTimestamp {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	traces := []types.BidTraceV2WithTimestamp{}
+	for _, trace := range c.received {
+		if slot == nil || trace.Slot == *slot {
+			traces = append(traces, trace)
+		}
+	}
+	return traces
+}
+
+// deliveredTraces returns the traces of the delivered payloads, of the slot if not nil, latest
+// slot first.
+func (c *relayCache) deliveredTraces(slot *uint64) []types.BidTraceV2 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	traces := []types.BidTraceV2{}
+	for s, d := range c.delivered {
+		if d.trace != nil && (slot == nil || s == *slot) {
+			traces = append(traces, *d.trace)
+		}
+	}
+	sort.Slice(traces, func(i, j int) bool { return traces[i].Slot > traces[j].Slot })
+	return traces
+}
+
+// deliveredAt returns the blinded block root and response of the payload delivered for the slot.
+func (c *relayCache) deliveredAt(slot uint64) (deliveredPayload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.delivered[slot]
+	return d, ok
+}
+
+func (c *relayCache) addDelivered(slot uint64, root [32]byte, response *types.VersionedExecutionPayload, trace *types.BidTraceV2) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.delivered == nil {
+		c.delivered = make(map[uint64]deliveredPayload)
+	}
+	c.delivered[slot] = deliveredPayload{root, response, trace}
+	if c.archive == nil {
+		c.archive = make(map[common.Hash]*types.VersionedExecutionPayload)
+	}
+	c.archive[common.Hash(response.BlockHash())] = response
+	c.prune(slot)
+}
+
+// archived returns the delivered payload with the block hash.
+func (c *relayCache) archived(hash common.Hash) (*types.VersionedExecutionPayload, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	payload, ok := c.archive[hash]
+	return payload, ok
+}
+
+// prune drops the entries of slots too far before the slot.
+func (c *relayCache) prune(slot uint64) {
+	if slot < maxCachedSlots {
+		return
+	}
+	min := slot - maxCachedSlots
+	for key := range c.bids {
+		if key.slot < min {
+			delete(c.bids, key)
+		}
+	}
+	for key := range c.payloads {
+		if key.slot < min {
+			delete(c.payloads, key)
+		}
+	}
+	for s := range c.delivered {
+		if s < min {
+			delete(c.delivered, s)
+		}
+	}
+	for key := range c.submissions {
+		if key.slot < min {
+			delete(c.submissions, key)
+		}
+	}
+	received := c.received[:0]
+	for _, trace := range c.received {
+		if trace.Slot >= min {
+			received = append(received, trace)
+		}
+	}
+	c.received = received
+}
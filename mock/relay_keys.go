@@ -0,0 +1,170 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+)
+
+// BuilderKeyRotation configures the rotation of the builder key the relay signs its bids with,
+// for the consumers of getHeader to verify bids across a key rollover.
+type BuilderKeyRotation struct {
+	Every    time.Duration `ask:"--every" help:"Rotate the builder key the relay signs its bids with this often (0 to disable)"`
+	Announce time.Duration `ask:"--announce" help:"How long before a rotation the next builder key is announced on the data API"`
+	Stale    float64       `ask:"--stale" help:"How often a bid after a rotation is signed with the previous builder key, for the pubkey of the current one"`
+}
+
+func (k *BuilderKeyRotation) Default() {
+	k.Announce = 12 * time.Second
+}
+
+// relayKeys are the builder keys of a relay: the current one it signs bids with, the next one
+// it announced, and the ones it signed with before.
+type relayKeys struct {
+	mu       sync.RWMutex
+	pk       types.PublicKey
+	sk       bls.SecretKey
+	next     *announcedKey
+	previous []types.PublicKey
+	stale    bls.SecretKey
+}
+
+// announcedKey is the next builder key of a relay, with the time it signs bids from.
+type announcedKey struct {
+	pk   types.PublicKey
+	sk   bls.SecretKey
+	from time.Time
+}
+
+func newRelayKeys(sk bls.SecretKey) relayKeys {
+	return relayKeys{pk: builderPubkey(sk), sk: sk}
+}
+
+func builderPubkey(sk bls.SecretKey) types.PublicKey {
+	var pk types.PublicKey
+	copy(pk[:], sk.PublicKey().Marshal())
+	return pk
+}
+
+// current returns the key the relay signs bids with.
+func (k *relayKeys) current() (types.PublicKey, bls.SecretKey) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.pk, k.sk
+}
+
+// staleKey returns the key the relay signed bids with before the current one, nil before the
+// first rotation.
+func (k *relayKeys) staleKey() bls.SecretKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.stale
+}
+
+// own returns whether the relay signs or signed bids with the key, so it built their blocks.
+func (k *relayKeys) own(pk types.PublicKey) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if pk == k.pk {
+		return true
+	}
+	for _, prev := range k.previous {
+		if pk == prev {
+			return true
+		}
+	}
+	return false
+}
+
+// announce makes the key the next one, which the relay signs bids with from the time on.
+func (k *relayKeys) announce(sk bls.SecretKey, from time.Time) types.PublicKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.next = &announcedKey{builderPubkey(sk), sk, from}
+	return k.next.pk
+}
+
+// rotate replaces the current key with the announced one.
+func (k *relayKeys) rotate() (types.PublicKey, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.next == nil {
+		return k.pk, false
+	}
+	k.previous = append(k.previous, k.pk)
+	k.stale = k.sk
+	k.pk, k.sk = k.next.pk, k.next.sk
+	k.next = nil
+	return k.pk, true
+}
+
+// rotateKeys announces a new builder key, and rotates to it, every period of the rotation, until
+// done is closed.
+func (r *RelayBackend) rotateKeys(rotation BuilderKeyRotation, done <-chan struct{}) {
+	for {
+		rotateAt := time.Now().Add(rotation.Every)
+		select {
+		case <-time.After(time.Until(rotateAt.Add(-rotation.Announce))):
+		case <-done:
+			return
+		}
+		sk, err := bls.RandKey()
+		if err != nil {
+			r.log.WithError(err).Error("Unable to generate the next builder key")
+			return
+		}
+		pk := r.keys.announce(sk, rotateAt)
+		r.log.WithField("pubkey", pk.String()).WithField("from", rotateAt).Info("Announced next builder key")
+		select {
+		case <-time.After(time.Until(rotateAt)):
+		case <-done:
+			return
+		}
+		if pk, ok := r.keys.rotate(); ok {
+			r.log.WithField("pubkey", pk.String()).Info("Rotated builder key")
+		}
+	}
+}
+
+// signingKey returns the key to sign a bid for the builder key with: the current key, or the
+// previous one at the stale key rate after a rotation.
+func (r *RelayBackend) signingKey(sk bls.SecretKey) (bls.SecretKey, bool) {
+	if r.keyRotation.Stale <= 0 {
+		return sk, false
+	}
+	stale := r.keys.staleKey()
+	if stale == nil || r.bids.RNG.Float64() >= r.keyRotation.Stale {
+		return sk, false
+	}
+	return stale, true
+}
+
+// builderKeysData is the builder keys of the relay in the data API.
+type builderKeysData struct {
+	Current  types.PublicKey   `json:"current"`
+	Next     *nextKeyData      `json:"next,omitempty"`
+	Previous []types.PublicKey `json:"previous"`
+}
+
+type nextKeyData struct {
+	Pubkey    types.PublicKey `json:"pubkey"`
+	Timestamp uint64          `json:"timestamp,string"`
+}
+
+// handleDataBuilderKeys returns the current builder key of the relay, the announced next one with
+// the time it signs bids from, and the previous ones.
+func (r *RelayBackend) handleDataBuilderKeys(w http.ResponseWriter, req *http.Request) {
+	r.keys.mu.RLock()
+	data := builderKeysData{Current: r.keys.pk, Previous: append([]types.PublicKey{}, r.keys.previous...)}
+	if next := r.keys.next; next != nil {
+		data.Next = &nextKeyData{next.pk, uint64(next.from.Unix())}
+	}
+	r.keys.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	getHeader := func(slot uint64) *types.GetHeaderResponse {
+		rr := relay.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", slot, parent.Hash().Hex(), pk), nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		bid := new(types.GetHeaderResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		return bid
+	}
+	verify := func(bid *types.GetHeaderResponse) bool {
+		ok, err := types.VerifySignature(bid.Data.Message, types.DomainBuilder, bid.Data.Message.Pubkey[:], bid.Data.Signature[:])
+		require.NoError(t, err)
+		return ok
+	}
+	keys := func() builderKeysData {
+		rr := relay.testRequest(t, "GET", pathDataBuilderKeys, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var data builderKeysData
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &data))
+		return data
+	}
+
+	// the next key is announced before it signs bids
+	first, _ := relay.keys.current()
+	nextSk, err := bls.RandKey()
+	require.NoError(t, err)
+	from := time.Now().Add(time.Minute)
+	next := relay.keys.announce(nextSk, from)
+	require.Equal(t, builderKeysData{Current: first, Next: &nextKeyData{next, uint64(from.Unix())}, Previous: []types.PublicKey{}}, keys())
+	bid := getHeader(1)
+	require.Equal(t, first, bid.Data.Message.Pubkey)
+	require.True(t, verify(bid))
+
+	_, ok := relay.keys.rotate()
+	require.True(t, ok)
+	require.Equal(t, builderKeysData{Current: next, Previous: []types.PublicKey{first}}, keys())
+	bid = getHeader(2)
+	require.Equal(t, next, bid.Data.Message.Pubkey)
+	require.True(t, verify(bid))
+	require.False(t, relay.paysProposer(first), "blocks of the previous key are the relay's own")
+
+	// bids signed with the stale key carry the current pubkey, and fail verification
+	relay.keyRotation.Stale = 1
+	bid = getHeader(3)
+	require.Equal(t, next, bid.Data.Message.Pubkey)
+	require.False(t, verify(bid))
+	ok, err = types.VerifySignature(bid.Data.Message, types.DomainBuilder, first[:], bid.Data.Signature[:])
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,63 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"mergemock/types"
+)
+
+var errBelowMinBid = errors.New("bid value below the min bid of the relay")
+
+// bidLimits are the smallest values of the block submissions the relay accepts, and of the bids
+// it serves, with the submissions and bids they turned away.
+type bidLimits struct {
+	minBid types.U256Str
+	floor  types.U256Str
+
+	mu       sync.Mutex
+	rejected uint64
+	floored  uint64
+}
+
+// admit returns whether a submission of the value is accepted, and counts it if not.
+func (l *bidLimits) admit(value types.U256Str) bool {
+	if value.Cmp(l.minBid) >= 0 {
+		return true
+	}
+	l.mu.Lock()
+	l.rejected++
+	l.mu.Unlock()
+	return false
+}
+
+// serve returns whether a bid of the value is served, and counts it if not.
+func (l *bidLimits) serve(value types.U256Str) bool {
+	if value.Cmp(l.floor) >= 0 {
+		return true
+	}
+	l.mu.Lock()
+	l.floored++
+	l.mu.Unlock()
+	return false
+}
+
+// bidLimitsData is the bid limits of the relay in the data API.
+type bidLimitsData struct {
+	MinBid              types.U256Str `json:"min_bid"`
+	BidFloor            types.U256Str `json:"bid_floor"`
+	RejectedSubmissions uint64        `json:"rejected_submissions,string"`
+	FlooredBids         uint64        `json:"floored_bids,string"`
+}
+
+// handleDataBidLimits returns the bid limits of the relay, and how many submissions and bids they
+// turned away.
+func (r *RelayBackend) handleDataBidLimits(w http.ResponseWriter, req *http.Request) {
+	r.limits.mu.Lock()
+	data := bidLimitsData{r.limits.minBid, r.limits.floor, r.limits.rejected, r.limits.floored}
+	r.limits.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
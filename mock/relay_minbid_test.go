@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBidLimits(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	relay.value = types.IntToU256(1000)
+	relay.limits.minBid = types.IntToU256(2000)
+	relay.limits.floor = types.IntToU256(3000)
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	var proposer types.PublicKey
+	copy(proposer[:], pk)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	_payload, ok := relay.engine.backend.recentPayloads.Get(parent.Hash())
+	require.True(t, ok)
+	payload := _payload.(*types.ExecutionPayloadV3)
+	rest, err := types.ELPayloadToRESTPayload(payload.V1())
+	require.NoError(t, err)
+
+	builderPk, builderSk := newKeypair(t)
+	submit := func(slot uint64, value uint64) *httptest.ResponseRecorder {
+		msg := types.NewBidTrace(slot, payload, types.PublicKey{}, proposer, types.Address{0x42}, types.IntToU256(value)).BidTrace
+		copy(msg.BuilderPubkey[:], builderPk)
+		root, err := types.ComputeSigningRoot(&msg, types.DomainBuilder)
+		require.NoError(t, err)
+		sub := types.BuilderSubmitBlockRequest{Message: &msg, ExecutionPayload: rest}
+		sub.Signature.FromSlice(builderSk.Sign(root[:]).Marshal())
+		return relay.testRequest(t, "POST", pathSubmitBlock, sub)
+	}
+	getHeader := func(slot uint64) *httptest.ResponseRecorder {
+		return relay.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", slot, parent.Hash().Hex(), pk), nil)
+	}
+
+	// submissions below the min bid are rejected, and bids below the floor are not served
+	rr := submit(1, 1500)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), errBelowMinBid.Error())
+	require.Equal(t, http.StatusOK, submit(1, 2500).Code)
+	require.Equal(t, http.StatusNoContent, getHeader(1).Code)
+
+	require.Equal(t, http.StatusOK, submit(2, 5000).Code)
+	rr = getHeader(2)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	bid := new(types.GetHeaderResponse)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+	require.Equal(t, types.IntToU256(5000), bid.Data.Message.Value)
+
+	rr = relay.testRequest(t, "GET", pathDataBidLimits, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var limits bidLimitsData
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &limits))
+	require.Equal(t, bidLimitsData{types.IntToU256(2000), types.IntToU256(3000), 1, 1}, limits)
+	require.Contains(t, rr.Body.String(), `"rejected_submissions":"1"`)
+}
@@ -0,0 +1,176 @@
+package mock
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+// paymentCallGas is the gas of a last-tx payment to a contract fee recipient, enough for the
+// receive function of a multisig or fee splitter, where a plain transfer would run out of gas.
+const paymentCallGas = 50_000
+
+// How the blocks the relay builds with its engine pay the proposer.
+const (
+	payNone     = "none"
+	payLastTx   = "last-tx"
+	payCoinbase = "coinbase"
+)
+
+// BuilderPayment configures how the blocks the relay builds with its engine pay the proposer,
+// like the two patterns of real builders: a last transaction transferring the bid value, or the
+// proposer fee recipient as fee recipient of the block, with the priority fees as bid value.
+type BuilderPayment struct {
+	Method string  `ask:"--method" help:"How the blocks of the engine of the relay pay the proposer: 'none', 'last-tx' for a transfer of the bid value from the builder account in a last transaction, 'coinbase' for the proposer fee recipient as fee recipient of the block"`
+	Key    string  `ask:"--key" help:"Hex private key of the builder account, the fee recipient of last-tx blocks that pays the proposer, funded in the genesis of the engine"`
+	Forget float64 `ask:"--forget" help:"How often a block does not pay the proposer, though its bid promises the value"`
+
+	key     *ecdsa.PrivateKey
+	account common.Address
+}
+
+func (p *BuilderPayment) Default() {
+	p.Method = payNone
+}
+
+// load checks the method, and decodes the key of the builder account.
+func (p *BuilderPayment) load() error {
+	switch p.Method {
+	case payNone, payCoinbase:
+	case payLastTx:
+		if p.Key == "" {
+			return fmt.Errorf("last-tx payments need the key of the builder account")
+		}
+	default:
+		return fmt.Errorf("unrecognized builder payment method: %q", p.Method)
+	}
+	if p.Key == "" {
+		return nil
+	}
+	key, err := crypto.HexToECDSA(p.Key)
+	if err != nil {
+		return fmt.Errorf("invalid builder account key: %w", err)
+	}
+	p.key, p.account = key, crypto.PubkeyToAddress(key.PublicKey)
+	return nil
+}
+
+// enabled returns whether the blocks of the engine pay the proposer.
+func (p *BuilderPayment) enabled() bool {
+	return p.Method == payLastTx || p.Method == payCoinbase
+}
+
+// paysProposer returns whether the blocks of the builder are expected to pay the proposer.
+func (r *RelayBackend) paysProposer(builder types.PublicKey) bool {
+	return !r.keys.own(builder) || r.payment.enabled()
+}
+
+// payProposer returns the payload of the engine rebuilt to pay the proposer fee recipient, with
+// the value of its bid. At the forget rate, the block pays nothing, for the same bid value. A
+// payment transaction to a fee recipient contract that reverts on receive is an error.
+func (r *RelayBackend) payProposer(plog logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, feeRecipient types.Address, value types.U256Str) (*types.ExecutionPayloadV3, types.U256Str, error) {
+	forget := r.bids.RNG.Float64() < r.payment.Forget
+	rebuilt := *payload
+	var paid *types.ExecutionPayloadV3
+	var err error
+	switch r.payment.Method {
+	case payLastTx:
+		rebuilt.FeeRecipient = r.payment.account
+		txs := payload.Transactions
+		if !forget {
+			tx, err := r.paymentTx(payload, common.Address(feeRecipient), value)
+			if err != nil {
+				return nil, value, err
+			}
+			txs = append(txs[:len(txs):len(txs)], tx)
+		}
+		if paid, err = r.engine.backend.rebuildPayload(&rebuilt, txs); err != nil {
+			return nil, value, err
+		}
+		if !forget && r.lastTxReverted(paid, common.Address(feeRecipient), value) {
+			return nil, value, fmt.Errorf("payment to fee recipient %s reverted", feeRecipient.String())
+		}
+	case payCoinbase:
+		// the bid is worth the priority fees the proposer gets as fee recipient
+		rebuilt.FeeRecipient = common.Address(feeRecipient)
+		if paid, err = r.engine.backend.rebuildPayload(&rebuilt, payload.Transactions); err != nil {
+			return nil, value, err
+		}
+		parent := r.engine.mockChain().chain.GetHeaderByHash(paid.ParentHash)
+		if parent == nil {
+			return nil, value, fmt.Errorf("unknown parent %s", paid.ParentHash)
+		}
+		fees, ok := r.rootBalanceDelta(parent.Root, paid.StateRoot, paid.FeeRecipient)
+		if !ok {
+			return nil, value, fmt.Errorf("no state of the block %s", paid.BlockHash)
+		}
+		value = fees
+		if forget {
+			rebuilt.FeeRecipient = r.payment.account
+			if paid, err = r.engine.backend.rebuildPayload(&rebuilt, payload.Transactions); err != nil {
+				return nil, value, err
+			}
+		}
+	default:
+		return payload, value, nil
+	}
+	plog.WithFields(logrus.Fields{
+		"method":    r.payment.Method,
+		"forgot":    forget,
+		"value":     value.String(),
+		"blockHash": paid.BlockHash,
+	}).Info("Built payload paying the proposer")
+	return paid, value, nil
+}
+
+// paymentTx returns the transaction transferring the value from the builder account to the
+// recipient, after the transactions of the payload, with the gas of a call if the recipient is a
+// contract.
+func (r *RelayBackend) paymentTx(payload *types.ExecutionPayloadV3, recipient common.Address, value types.U256Str) ([]byte, error) {
+	chain := r.engine.mockChain().chain
+	config := chain.Config()
+	parent := chain.GetHeaderByHash(payload.ParentHash)
+	if parent == nil {
+		return nil, fmt.Errorf("unknown parent %s", payload.ParentHash)
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	nonce := statedb.GetNonce(r.payment.account)
+	gas := params.TxGas
+	if statedb.GetCodeSize(recipient) > 0 {
+		gas = paymentCallGas
+	}
+	signer := ethTypes.LatestSigner(config)
+	for _, raw := range payload.Transactions {
+		var tx ethTypes.Transaction
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, err
+		}
+		if sender, err := ethTypes.Sender(signer, &tx); err == nil && sender == r.payment.account {
+			nonce++
+		}
+	}
+	tx, err := ethTypes.SignNewTx(r.payment.key, signer, &ethTypes.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     nonce,
+		GasTipCap: new(big.Int),
+		GasFeeCap: payload.BaseFeePerGas,
+		Gas:       gas,
+		To:        &recipient,
+		Value:     value.BigInt(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tx.MarshalBinary()
+}
@@ -0,0 +1,163 @@
+package mock
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/contracts"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayProposer(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	builder := crypto.PubkeyToAddress(key.PublicKey)
+	relay := newTestRelay(t)
+	relay.engine.GenesisPath = newFundedGenesis(t, builder)
+	require.NoError(t, relay.engine.Run(context.Background()))
+	t.Cleanup(func() { relay.engine.Close() })
+	chain := relay.engine.mockChain()
+
+	// a block of the engine, with a transaction of the builder account tipping 1 gwei
+	creator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		tx, err := ethTypes.SignNewTx(key, ethTypes.LatestSigner(config), &ethTypes.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     statedb.GetNonce(builder),
+			GasTipCap: big.NewInt(params.GWei),
+			GasFeeCap: new(big.Int).Add(header.BaseFee, big.NewInt(params.GWei)),
+			Gas:       params.TxGas,
+			To:        &common.Address{0x43},
+			Value:     big.NewInt(1),
+		})
+		require.NoError(t, err)
+		return []*ethTypes.Transaction{tx}
+	}}
+	parent := chain.CurrentHeader()
+	block, err := chain.AddNewBlock(parent.Hash(), common.Address{0x01}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, false)
+	require.NoError(t, err)
+	payload, err := api.BlockToPayloadV3(block, nil)
+	require.NoError(t, err)
+
+	proposer := types.Address{0x42}
+	pay := func(method string, forget float64) (*types.ExecutionPayloadV3, types.U256Str) {
+		relay.payment = BuilderPayment{Method: method, Key: hex.EncodeToString(crypto.FromECDSA(key)), Forget: forget}
+		require.NoError(t, relay.payment.load())
+		paid, value, err := relay.payProposer(relay.log, payload, proposer, types.IntToU256(1000))
+		require.NoError(t, err)
+		return paid, value
+	}
+	check := func(paid *types.ExecutionPayloadV3, value types.U256Str) proposerPayment {
+		return relay.checkPayment(types.NewBidTrace(1, paid, relay.keys.pk, types.PublicKey{}, proposer, value), paid)
+	}
+
+	// a last transaction of the builder account, after its other transaction
+	paid, value := pay(payLastTx, 0)
+	require.Len(t, paid.Transactions, 2)
+	require.Equal(t, builder, paid.FeeRecipient)
+	require.Equal(t, types.IntToU256(1000), value)
+	payment := check(paid, value)
+	require.Equal(t, paymentLastTx, payment.Method)
+	require.Empty(t, payment.Discrepancy)
+
+	// the proposer fee recipient gets the priority fees, the bid value
+	paid, value = pay(payCoinbase, 0)
+	require.Len(t, paid.Transactions, 1)
+	require.Equal(t, common.Address(proposer), paid.FeeRecipient)
+	require.Equal(t, types.IntToU256(params.TxGas*params.GWei), value)
+
+	// forgetting to pay
+	paid, value = pay(payLastTx, 1)
+	require.Len(t, paid.Transactions, 1)
+	payment = check(paid, value)
+	require.Equal(t, paymentNone, payment.Method)
+	require.Equal(t, "proposer paid 0 of the 1000 wei bid", payment.Discrepancy)
+	paid, value = pay(payCoinbase, 1)
+	require.Equal(t, builder, paid.FeeRecipient)
+	require.Equal(t, types.IntToU256(params.TxGas*params.GWei), value)
+	require.Equal(t, paymentNone, check(paid, value).Method)
+
+	require.True(t, relay.paysProposer(relay.keys.pk))
+	relay.payment = BuilderPayment{Method: payNone}
+	require.False(t, relay.paysProposer(relay.keys.pk))
+	require.True(t, relay.paysProposer(types.PublicKey{0x01}))
+
+	require.Error(t, (&BuilderPayment{Method: payLastTx}).load())
+	require.Error(t, (&BuilderPayment{Method: "tip"}).load())
+	require.NoError(t, (&BuilderPayment{Method: payCoinbase}).load())
+}
+
+func TestPayContractRecipient(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	builder := crypto.PubkeyToAddress(key.PublicKey)
+	relay := newTestRelay(t)
+	relay.engine.GenesisPath = newFundedGenesis(t, builder)
+	require.NoError(t, relay.engine.Run(context.Background()))
+	t.Cleanup(func() { relay.engine.Close() })
+	chain := relay.engine.mockChain()
+	relay.payment = BuilderPayment{Method: payLastTx, Key: hex.EncodeToString(crypto.FromECDSA(key))}
+	require.NoError(t, relay.payment.load())
+
+	// the builder account deploys an accepting and a reverting fee recipient contract
+	accepting, reverting := crypto.CreateAddress(builder, 0), crypto.CreateAddress(builder, 1)
+	deployer := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		var txs []*ethTypes.Transaction
+		for nonce, code := range [][]byte{contracts.RecipientCode, contracts.RejecterCode} {
+			tx, err := ethTypes.SignNewTx(key, ethTypes.LatestSigner(config), &ethTypes.DynamicFeeTx{
+				ChainID:   config.ChainID,
+				Nonce:     uint64(nonce),
+				GasTipCap: new(big.Int),
+				GasFeeCap: header.BaseFee,
+				Gas:       contractDeployGas,
+				Data:      code,
+			})
+			require.NoError(t, err)
+			txs = append(txs, tx)
+		}
+		return txs
+	}}
+	parent := chain.CurrentHeader()
+	block, err := chain.AddNewBlock(parent.Hash(), common.Address{0x01}, parent.Time+1, parent.GasLimit, deployer, common.Hash{}, nil, nil, nil, nil, true)
+	require.NoError(t, err)
+	block, err = chain.AddNewBlock(block.Hash(), common.Address{0x01}, block.Time()+1, block.GasLimit(), TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, nil, nil, false)
+	require.NoError(t, err)
+	payload, err := api.BlockToPayloadV3(block, nil)
+	require.NoError(t, err)
+	value := types.IntToU256(1000)
+
+	// a payment to the accepting contract calls its receive function, with more than transfer gas
+	paid, _, err := relay.payProposer(relay.log, payload, types.Address(accepting), value)
+	require.NoError(t, err)
+	var tx ethTypes.Transaction
+	require.NoError(t, tx.UnmarshalBinary(paid.Transactions[len(paid.Transactions)-1]))
+	require.Equal(t, uint64(paymentCallGas), tx.Gas())
+	payment := relay.checkPayment(types.NewBidTrace(1, paid, relay.keys.pk, types.PublicKey{}, types.Address(accepting), value), paid)
+	require.Equal(t, paymentLastTx, payment.Method)
+	require.Empty(t, payment.Discrepancy)
+
+	// a payment to the reverting contract fails
+	_, _, err = relay.payProposer(relay.log, payload, types.Address(reverting), value)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "reverted")
+	raw, err := relay.paymentTx(payload, reverting, value)
+	require.NoError(t, err)
+	rebuilt := *payload
+	rebuilt.FeeRecipient = builder
+	unpaid, err := relay.engine.backend.rebuildPayload(&rebuilt, append(payload.Transactions[:len(payload.Transactions):len(payload.Transactions)], raw))
+	require.NoError(t, err)
+	payment = relay.checkPayment(types.NewBidTrace(1, unpaid, relay.keys.pk, types.PublicKey{}, types.Address(reverting), value), unpaid)
+	require.Equal(t, paymentLastTxReverted, payment.Method)
+	require.Equal(t, "proposer paid 0 of the 1000 wei bid", payment.Discrepancy)
+}
@@ -0,0 +1,174 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"mergemock/types"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+// How the payment of the proposer in a delivered payload was determined.
+const (
+	// the last transaction of the payload transfers the value to the proposer fee recipient
+	paymentLastTx = "last-tx"
+	// the last transaction of the payload transfers the value to the proposer fee recipient, a
+	// contract that reverted the transfer
+	paymentLastTxReverted = "last-tx-reverted"
+	// the proposer fee recipient is the fee recipient of the block, and its balance grew by the value
+	paymentBalanceDelta = "balance-delta"
+	// the proposer fee recipient is not the fee recipient of the block, and no transaction pays it
+	paymentNone = "none"
+	// the proposer fee recipient is the fee recipient of the block, which the engine did not import yet
+	paymentPending = "pending"
+)
+
+// proposerPayment is the payment of the proposer in a payload delivered for a submitted block,
+// against the value of the bid the proposer accepted.
+type proposerPayment struct {
+	Slot                 uint64          `json:"slot,string"`
+	BlockHash            types.Hash      `json:"block_hash"`
+	BuilderPubkey        types.PublicKey `json:"builder_pubkey"`
+	ProposerFeeRecipient types.Address   `json:"proposer_fee_recipient"`
+	Value                types.U256Str   `json:"value"`
+	Paid                 types.U256Str   `json:"paid"`
+	Method               string          `json:"method"`
+	Discrepancy          string          `json:"discrepancy,omitempty"`
+}
+
+// checkPayment returns the payment of the proposer in the payload, by the last transaction unless
+// it reverted, or else by the balance delta of the fee recipient of the block if the engine
+// imported it.
+func (r *RelayBackend) checkPayment(trace *types.BidTraceV2, payload *types.ExecutionPayloadV3) proposerPayment {
+	p := proposerPayment{
+		Slot:                 trace.Slot,
+		BlockHash:            trace.BlockHash,
+		BuilderPubkey:        trace.BuilderPubkey,
+		ProposerFeeRecipient: trace.ProposerFeeRecipient,
+		Value:                trace.Value,
+		Method:               paymentNone,
+	}
+	recipient := common.Address(trace.ProposerFeeRecipient)
+	if paid, ok := lastTxPayment(payload, recipient); ok {
+		p.Paid, p.Method = paid, paymentLastTx
+		if r.lastTxReverted(payload, recipient, paid) {
+			p.Paid, p.Method = types.U256Str{}, paymentLastTxReverted
+		}
+	}
+	if p.Paid.Cmp(p.Value) < 0 && payload.FeeRecipient == recipient {
+		if delta, ok := r.balanceDelta(payload, recipient); ok {
+			p.Paid, p.Method = delta, paymentBalanceDelta
+		} else {
+			p.Method = paymentPending
+		}
+	}
+	if p.Method != paymentPending && p.Paid.Cmp(p.Value) < 0 {
+		p.Discrepancy = fmt.Sprintf("proposer paid %s of the %s wei bid", p.Paid.String(), p.Value.String())
+	}
+	return p
+}
+
+// lastTxPayment returns the value the last transaction of the payload transfers to the recipient.
+func lastTxPayment(payload *types.ExecutionPayloadV3, recipient common.Address) (types.U256Str, bool) {
+	if len(payload.Transactions) == 0 {
+		return types.U256Str{}, false
+	}
+	var tx ethTypes.Transaction
+	if err := tx.UnmarshalBinary(payload.Transactions[len(payload.Transactions)-1]); err != nil {
+		return types.U256Str{}, false
+	}
+	if tx.To() == nil || *tx.To() != recipient {
+		return types.U256Str{}, false
+	}
+	return bigToU256(tx.Value()), true
+}
+
+// lastTxReverted returns whether the transfer of the last transaction of the payload to the
+// recipient reverted, like on receive of a contract, by the balance delta of the recipient if the
+// engine has the state of the payload.
+func (r *RelayBackend) lastTxReverted(payload *types.ExecutionPayloadV3, recipient common.Address, value types.U256Str) bool {
+	parent := r.engine.mockChain().chain.GetHeaderByHash(payload.ParentHash)
+	if parent == nil {
+		return false
+	}
+	delta, ok := r.rootBalanceDelta(parent.Root, payload.StateRoot, recipient)
+	return ok && delta.Cmp(value) < 0
+}
+
+// balanceDelta returns how much the balance of the account grew in the block of the payload, if
+// the engine imported the block.
+func (r *RelayBackend) balanceDelta(payload *types.ExecutionPayloadV3, account common.Address) (types.U256Str, bool) {
+	chain := r.engine.mockChain().chain
+	header := chain.GetHeaderByHash(payload.BlockHash)
+	parent := chain.GetHeaderByHash(payload.ParentHash)
+	if header == nil || parent == nil {
+		return types.U256Str{}, false
+	}
+	return r.rootBalanceDelta(parent.Root, header.Root, account)
+}
+
+// rootBalanceDelta returns how much the balance of the account grew between the state roots, if
+// the engine has both states.
+func (r *RelayBackend) rootBalanceDelta(parentRoot, root common.Hash, account common.Address) (types.U256Str, bool) {
+	chain := r.engine.mockChain().chain
+	after, err := chain.StateAt(root)
+	if err != nil {
+		return types.U256Str{}, false
+	}
+	before, err := chain.StateAt(parentRoot)
+	if err != nil {
+		return types.U256Str{}, false
+	}
+	delta := new(big.Int).Sub(after.GetBalance(account), before.GetBalance(account))
+	if delta.Sign() < 0 {
+		return types.U256Str{}, true
+	}
+	return bigToU256(delta), true
+}
+
+// bigToU256 returns the non-negative integer as a U256Str, capped at 2**256-1.
+func bigToU256(x *big.Int) types.U256Str {
+	v, overflow := uint256.FromBig(x)
+	if overflow {
+		v = new(uint256.Int).SetAllOne()
+	}
+	return types.Uint256ToU256(v)
+}
+
+// payments returns the payments of the proposers in the payloads delivered for submitted blocks.
+// The blocks of the engine of the relay pay the proposer the bid value only with a payment method.
+func (r *RelayBackend) payments(slot *uint64) []proposerPayment {
+	payments := []proposerPayment{}
+	for _, trace := range r.cache.deliveredTraces(slot) {
+		if !r.paysProposer(trace.BuilderPubkey) {
+			continue
+		}
+		delivered, ok := r.cache.archived(common.Hash(trace.BlockHash))
+		if !ok {
+			continue
+		}
+		payload, err := delivered.ExecutionPayload()
+		if err != nil {
+			continue
+		}
+		trace := trace
+		payments = append(payments, r.checkPayment(&trace, payload))
+	}
+	return payments
+}
+
+// handleDataPayments returns the payments of the proposers in the delivered payloads, with the
+// discrepancies to the values of the bids.
+func (r *RelayBackend) handleDataPayments(w http.ResponseWriter, req *http.Request) {
+	slot, err := slotArgument(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.payments(slot))
+}
@@ -0,0 +1,103 @@
+package mock
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPayment(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	relay := newTestRelay(t)
+	relay.engine.GenesisPath = newFundedGenesis(t, sender)
+	require.NoError(t, relay.engine.Run(context.Background()))
+	t.Cleanup(func() { relay.engine.Close() })
+	chain := relay.engine.mockChain()
+
+	proposer := common.Address{0x42}
+	// transfers creates a block with a transfer of each value to its recipient, in order
+	transfers := func(coinbase common.Address, store bool, recipients []common.Address, values []int64) *types.ExecutionPayloadV3 {
+		creator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+			var txs []*ethTypes.Transaction
+			for i, to := range recipients {
+				to := to
+				tx, err := ethTypes.SignNewTx(key, ethTypes.LatestSigner(config), &ethTypes.DynamicFeeTx{
+					ChainID:   config.ChainID,
+					Nonce:     statedb.GetNonce(sender) + uint64(i),
+					GasTipCap: big.NewInt(params.GWei),
+					GasFeeCap: new(big.Int).Add(header.BaseFee, big.NewInt(params.GWei)),
+					Gas:       params.TxGas,
+					To:        &to,
+					Value:     big.NewInt(values[i]),
+				})
+				require.NoError(t, err)
+				txs = append(txs, tx)
+			}
+			return txs
+		}}
+		parent := chain.CurrentHeader()
+		block, err := chain.AddNewBlock(parent.Hash(), coinbase, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, store)
+		require.NoError(t, err)
+		payload, err := api.BlockToPayloadV3(block, nil)
+		require.NoError(t, err)
+		return payload
+	}
+	check := func(payload *types.ExecutionPayloadV3, value uint64) proposerPayment {
+		trace := types.NewBidTrace(1, payload, types.PublicKey{0x01}, types.PublicKey{}, types.Address(proposer), types.IntToU256(value))
+		return relay.checkPayment(trace, payload)
+	}
+
+	// a payment in the last transaction
+	payload := transfers(common.Address{0x01}, true, []common.Address{proposer}, []int64{1000})
+	payment := check(payload, 1000)
+	require.Equal(t, paymentLastTx, payment.Method)
+	require.Equal(t, types.IntToU256(1000), payment.Paid)
+	require.Empty(t, payment.Discrepancy)
+	payment = check(payload, 2000)
+	require.Equal(t, paymentLastTx, payment.Method)
+	require.Equal(t, "proposer paid 1000 of the 2000 wei bid", payment.Discrepancy)
+
+	// a payment to the fee recipient of the block, with the priority fees
+	payload = transfers(proposer, true, []common.Address{proposer, {0x43}}, []int64{1000, 1})
+	payment = check(payload, 1000)
+	require.Equal(t, paymentBalanceDelta, payment.Method)
+	require.Equal(t, types.IntToU256(1000+2*params.TxGas*params.GWei), payment.Paid)
+	require.Empty(t, payment.Discrepancy)
+
+	// no balance delta before the engine imports the block
+	payload = transfers(proposer, false, []common.Address{{0x43}}, []int64{1})
+	payment = check(payload, 1000)
+	require.Equal(t, paymentPending, payment.Method)
+	require.Empty(t, payment.Discrepancy)
+
+	// no payment at all
+	payload = transfers(common.Address{0x01}, true, nil, nil)
+	payment = check(payload, 1000)
+	require.Equal(t, paymentNone, payment.Method)
+	require.Equal(t, "proposer paid 0 of the 1000 wei bid", payment.Discrepancy)
+
+	// a stiffing relay bids twice the value of the submission
+	relay.bids.Stiff = 1
+	key2 := bidKey{2, payload.ParentHash, types.PublicKey{0x02}}
+	relay.cache.addSubmission(key2, servedPayload{2, payload, types.NewBidTrace(2, payload, types.PublicKey{0x01}, key2.pubkey, types.Address(proposer), types.IntToU256(1000))})
+	bid, served, err := relay.makeBid(relay.log, key2)
+	require.NoError(t, err)
+	value, err := bid.Value()
+	require.NoError(t, err)
+	require.Equal(t, types.IntToU256(2000), value)
+	require.Equal(t, "proposer paid 0 of the 2000 wei bid", relay.checkPayment(served.trace, payload).Discrepancy)
+}
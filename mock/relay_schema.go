@@ -0,0 +1,50 @@
+package mock
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// schemaWriter holds back the response, to check it against its schema before it is sent.
+type schemaWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *schemaWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *schemaWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// schemaMiddleware checks the successful responses of the builder API routes against the
+// builder-specs schema of their operation. In error mode, a response that does not match is
+// replaced with a 500 error naming the violations.
+func (r *RelayBackend) schemaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sw := &schemaWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, req)
+		if sw.status == 0 {
+			// nothing was answered, like a withheld payload
+			return
+		}
+		if route := mux.CurrentRoute(req); sw.status == http.StatusOK && route != nil {
+			if err := r.schemas.Check(route.GetName(), sw.body.Bytes()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.WriteHeader(sw.status)
+		w.Write(sw.body.Bytes())
+	})
+}
@@ -0,0 +1,149 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Relay health states, as observed through the builder API.
+const (
+	healthHealthy  = "healthy"
+	healthDegraded = "degraded"
+	healthDown     = "down"
+)
+
+var pathAdminStatus = "/mergemock/v1/relay/status"
+
+// RelayStatus configures the health of the relay: degraded relays answer every builder API
+// request slowly, and down relays answer them with 503 Service Unavailable.
+type RelayStatus struct {
+	Initial  string        `ask:"--initial" help:"Health of the relay at the start: 'healthy', 'degraded', 'down'"`
+	Schedule []string      `ask:"--schedule" help:"Health changes after the start, as time:health, e.g. 30s:degraded,1m:down,2m:healthy"`
+	Delay    time.Duration `ask:"--delay" help:"How long a degraded relay takes to answer builder API requests"`
+}
+
+func (s *RelayStatus) Default() {
+	s.Initial = healthHealthy
+	s.Delay = 2 * time.Second
+}
+
+// statusChange is a scheduled change of the health of the relay.
+type statusChange struct {
+	after  time.Duration
+	health string
+}
+
+// parseSchedule parses the time:health entries of the schedule.
+func parseSchedule(entries []string) ([]statusChange, error) {
+	var changes []statusChange
+	for _, entry := range entries {
+		after, health, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule entry %q, expected time:health", entry)
+		}
+		d, err := time.ParseDuration(after)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule entry %q: %v", entry, err)
+		}
+		if err := checkHealth(health); err != nil {
+			return nil, err
+		}
+		changes = append(changes, statusChange{d, health})
+	}
+	return changes, nil
+}
+
+func checkHealth(health string) error {
+	switch health {
+	case healthHealthy, healthDegraded, healthDown:
+		return nil
+	default:
+		return fmt.Errorf("unknown relay health %q", health)
+	}
+}
+
+// relayHealth is the current health of a relay, changed by the admin API and the schedule.
+type relayHealth struct {
+	mu     sync.RWMutex
+	health string
+}
+
+func (h *relayHealth) get() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.health == "" {
+		return healthHealthy
+	}
+	return h.health
+}
+
+func (h *relayHealth) set(health string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.health = health
+}
+
+// followSchedule changes the health of the relay at the scheduled times after now, until done
+// is closed.
+func (r *RelayBackend) followSchedule(changes []statusChange, done <-chan struct{}) {
+	start := time.Now()
+	for _, change := range changes {
+		select {
+		case <-time.After(time.Until(start.Add(change.after))):
+		case <-done:
+			return
+		}
+		r.log.WithField("health", change.health).Info("Scheduled relay health change")
+		r.health.set(change.health)
+	}
+}
+
+// statusMiddleware answers builder API requests according to the health of the relay. The
+// admin API and the probes stay available, to bring the relay back and to debug it.
+func (r *RelayBackend) statusMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == pathAdminStatus || req.URL.Path == pathHealthz || req.URL.Path == pathReadyz || strings.HasPrefix(req.URL.Path, pathPprof) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		switch r.health.get() {
+		case healthDown:
+			http.Error(w, "relay is down", http.StatusServiceUnavailable)
+			return
+		case healthDegraded:
+			select {
+			case <-time.After(r.status.Delay):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+type adminStatus struct {
+	Health string `json:"health"`
+}
+
+// handleAdminStatus returns the health of the relay, or changes it to the posted one.
+func (r *RelayBackend) handleAdminStatus(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		var status adminStatus
+		if err := json.NewDecoder(req.Body).Decode(&status); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := checkHealth(status.Health); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.log.WithField("health", status.Health).Info("Relay health changed")
+		r.health.set(status.Health)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(adminStatus{Health: r.health.get()})
+}
@@ -0,0 +1,883 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mergemock/api"
+	"mergemock/builderclient"
+	"mergemock/rpc"
+	"mergemock/types"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type testRelayBackend struct {
+	*RelayBackend
+}
+
+func newTestRelay(t *testing.T) *testRelayBackend {
+	sk, err := bls.RandKey()
+	require.NoError(t, err)
+
+	relay, err := NewRelayBackend(logrus.New(), "127.0.0.1:38551", "127.0.0.1:38552", types.Root{0x12, 0x34}, hex.EncodeToString(sk.Marshal()))
+	if err != nil {
+		t.Fatal("unable to create relay")
+	}
+
+	testRelay := testRelayBackend{relay}
+	testRelay.engine.JwtSecretPath = newJwt(t)
+	testRelay.engine.GenesisPath = newGenesis(t)
+	return &testRelay
+}
+
+func (mr *testRelayBackend) testRequest(t *testing.T, method string, path string, payload any) *httptest.ResponseRecorder {
+	var req *http.Request
+	var err error
+
+	if payload == nil {
+		req, err = http.NewRequest(method, path, nil)
+	} else {
+		payloadBytes, err2 := json.Marshal(payload)
+		require.NoError(t, err2)
+		req, err = http.NewRequest(method, path, bytes.NewReader(payloadBytes))
+	}
+
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	mr.getRouter().ServeHTTP(rr, req)
+	return rr
+}
+
+func newKeypair(t *testing.T) (pubkey []byte, privkey bls.SecretKey) {
+	sk, err := bls.RandKey()
+	if err != nil {
+		t.Fatal("unable to generate bls key pair", err)
+	}
+	return sk.PublicKey().Marshal(), sk
+}
+
+// register registers the validator with the relay, at the timestamp.
+func (mr *testRelayBackend) register(t *testing.T, sk bls.SecretKey, timestamp uint64) *httptest.ResponseRecorder {
+	msg := &types.RegisterValidatorRequestMessage{
+		FeeRecipient: types.Address{0x42},
+		GasLimit:     30_000_000,
+		Timestamp:    timestamp,
+	}
+	msg.Pubkey.FromSlice(sk.PublicKey().Marshal())
+	root, err := types.ComputeSigningRoot(msg, types.DomainBuilder)
+	require.NoError(t, err)
+	var sig types.Signature
+	sig.FromSlice(sk.Sign(root[:]).Marshal())
+	return mr.testRequest(t, "POST", "/eth/v1/builder/validators", []types.SignedValidatorRegistration{{Message: msg, Signature: sig}})
+}
+
+func newJwt(t *testing.T) string {
+	path := fmt.Sprintf("%s/jwt.hex", t.TempDir())
+	jwt := []byte("ed6588309287e7dbbb0ca2ba8c8be6e6063a72dc0f2235999ee6a751e8459cbc")
+	if err := os.WriteFile(path, jwt, 0644); err != nil {
+		t.Fatal("unable to write tmp jwt file")
+	}
+	return path
+}
+
+func newGenesis(t *testing.T) string {
+	path := fmt.Sprintf("%s/genesis.json", t.TempDir())
+	genesis := core.DeveloperGenesisBlock(5, 30_000_000, common.Address{})
+	genesis.Config.MergeForkBlock = common.Big0
+	genesis.Config.TerminalTotalDifficulty = common.Big0
+	buf, err := genesis.MarshalJSON()
+	if err != nil {
+		t.Fatal("cannot marshal tmp genesis")
+	}
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatal("unable to write tmp genesis file")
+	}
+	return path
+}
+
+func TestStatusEndpoint(t *testing.T) {
+	relay := newTestRelay(t)
+	rr := relay.testRequest(t, "GET", "/eth/v1/builder/status", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRelaySchemas(t *testing.T) {
+	relay := newTestRelay(t)
+	doc := `{"paths":{"/eth/v1/builder/status":{"get":{"operationId":"status","responses":{"200":{"content":{"application/json":{"schema":{"type":"object","required":["health"]}}}}}}}}}`
+	load := func() (*rpc.Schemas, error) { return rpc.LoadOpenAPISchemas([]byte(doc)) }
+
+	schemas, err := rpc.NewSchemaChecker(rpc.SchemaWarn, load, relay.log)
+	require.NoError(t, err)
+	relay.schemas = schemas
+	rr := relay.testRequest(t, "GET", pathStatus, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "{}", rr.Body.String())
+
+	schemas, err = rpc.NewSchemaChecker(rpc.SchemaError, load, relay.log)
+	require.NoError(t, err)
+	relay.schemas = schemas
+	rr = relay.testRequest(t, "GET", pathStatus, nil)
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Contains(t, rr.Body.String(), `status: $: missing field "health"`)
+}
+
+func TestRelayHealth(t *testing.T) {
+	relay := newTestRelay(t)
+	relay.status.Delay = 100 * time.Millisecond
+	setHealth := func(health string) *httptest.ResponseRecorder {
+		return relay.testRequest(t, "POST", pathAdminStatus, adminStatus{Health: health})
+	}
+
+	require.Equal(t, http.StatusOK, setHealth(healthDegraded).Code)
+	start := time.Now()
+	rr := relay.testRequest(t, "GET", pathStatus, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	require.Equal(t, http.StatusOK, setHealth(healthDown).Code)
+	require.Equal(t, http.StatusServiceUnavailable, relay.testRequest(t, "GET", pathStatus, nil).Code)
+	require.Equal(t, http.StatusServiceUnavailable, relay.testRequest(t, "POST", pathRegisterValidator, []types.SignedValidatorRegistration{}).Code)
+
+	// the admin API stays up
+	rr = relay.testRequest(t, "GET", pathAdminStatus, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"health":"down"}`, rr.Body.String())
+	require.Equal(t, http.StatusBadRequest, setHealth("sleepy").Code)
+	require.Equal(t, http.StatusOK, setHealth(healthHealthy).Code)
+	require.Equal(t, http.StatusOK, relay.testRequest(t, "GET", pathStatus, nil).Code)
+
+	changes, err := parseSchedule([]string{"10ms:down", "20ms:healthy"})
+	require.NoError(t, err)
+	require.Equal(t, []statusChange{{10 * time.Millisecond, healthDown}, {20 * time.Millisecond, healthHealthy}}, changes)
+	done := make(chan struct{})
+	go relay.followSchedule(changes[:1], done)
+	require.Eventually(t, func() bool { return relay.health.get() == healthDown }, time.Second, 5*time.Millisecond)
+	close(done)
+	_, err = parseSchedule([]string{"10ms"})
+	require.Error(t, err)
+	_, err = parseSchedule([]string{"10ms:sleepy"})
+	require.Error(t, err)
+}
+
+func TestValidatorRegistration(t *testing.T) {
+	relay := newTestRelay(t)
+	pk1, sk1 := newKeypair(t)
+	pk2, sk2 := newKeypair(t)
+
+	var pubkey1 types.PublicKey
+	var pubkey2 types.PublicKey
+	pubkey1.FromSlice(pk1)
+	pubkey2.FromSlice(pk2)
+
+	msg1 := &types.RegisterValidatorRequestMessage{
+		FeeRecipient: types.Address{0x42},
+		GasLimit:     15_000_000,
+		Timestamp:    uint64(time.Now().Unix()),
+		Pubkey:       pubkey1,
+	}
+	msg2 := &types.RegisterValidatorRequestMessage{
+		FeeRecipient: types.Address{0x42},
+		GasLimit:     15_000_000,
+		Timestamp:    uint64(time.Now().Unix()),
+		Pubkey:       pubkey2,
+	}
+	root1, err := types.ComputeSigningRoot(msg1, types.DomainBuilder)
+	require.NoError(t, err)
+	root2, err := types.ComputeSigningRoot(msg2, types.DomainBuilder)
+	require.NoError(t, err)
+
+	// Success
+	var sig1 types.Signature
+	sig1.FromSlice(sk1.Sign(root1[:]).Marshal())
+	var sig2 types.Signature
+	sig2.FromSlice(sk2.Sign(root2[:]).Marshal())
+
+	rr := relay.testRequest(t, "POST", "/eth/v1/builder/validators", []types.SignedValidatorRegistration{
+		{
+			Message:   msg1,
+			Signature: sig1,
+		},
+		{
+			Message:   msg2,
+			Signature: sig2,
+		},
+	})
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// Invalid signature
+	sig1[len(sig1)-1] = 0x00
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/validators", []types.SignedValidatorRegistration{
+		{
+			Message:   msg1,
+			Signature: sig1,
+		},
+	})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, errInvalidSignature.Error()+"\n", rr.Body.String())
+
+	// Old registration
+	msg := &types.RegisterValidatorRequestMessage{
+		FeeRecipient: types.Address{0x42},
+		GasLimit:     15_000_000,
+		Timestamp:    msg1.Timestamp,
+		Pubkey:       pubkey1,
+	}
+	root, err := types.ComputeSigningRoot(msg, types.DomainBuilder)
+	var sig types.Signature
+	sig.FromSlice(sk1.Sign(root[:]).Marshal())
+	require.NoError(t, err)
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/validators", []types.SignedValidatorRegistration{
+		{
+			Message:   msg,
+			Signature: sig,
+		},
+	})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, errInvalidTimestamp.Error()+"\n", rr.Body.String())
+}
+
+func TestGetHeader(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	parent := relay.engine.mockChain().CurrentHeader()
+	parentHash := parent.Hash()
+
+	// Initialize engine
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{
+			HeadBlockHash:      parentHash,
+			SafeBlockHash:      parentHash,
+			FinalizedBlockHash: parentHash,
+		},
+		&types.PayloadAttributesV1{
+			Timestamp:             parent.Time + 1,
+			PrevRandao:            common.Hash{0x01},
+			SuggestedFeeRecipient: common.Address{0x02},
+		},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+
+	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", 0, parentHash.Hex(), pk)
+	rr := relay.testRequest(t, "GET", path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	bid := new(types.GetHeaderResponse)
+	err = json.Unmarshal(rr.Body.Bytes(), bid)
+	require.NoError(t, err)
+
+	require.Equal(t, parentHash[:], bid.Data.Message.Header.ParentHash[:], "didn't build on expected parent")
+	ok, err := types.VerifySignature(bid.Data.Message, types.DomainBuilder, relay.keys.pk[:], bid.Data.Signature[:])
+	require.NoError(t, err, "error verifying signature")
+	require.True(t, ok, "bid signature not valid")
+
+	require.Equal(t, pk, relay.latestPubkey[:])
+}
+
+func TestGetHeaderGating(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", 0, parent.Hash().Hex(), pk)
+
+	// unregistered
+	rr := relay.testRequest(t, "GET", path, nil)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Empty(t, rr.Body.String())
+	relay.gating.Status = http.StatusBadRequest
+	rr = relay.testRequest(t, "GET", path, nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, errNotRegistered.Error()+"\n", rr.Body.String())
+
+	// registered too long ago
+	relay.gating.Grace = time.Minute
+	past := uint64(time.Now().Add(-time.Hour).Unix())
+	require.Equal(t, http.StatusOK, relay.register(t, sk, past).Code)
+	rr = relay.testRequest(t, "GET", path, nil)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, errStaleRegistration.Error()+"\n", rr.Body.String())
+
+	// a new registration replaces the old one, but not the other way around
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	require.Equal(t, http.StatusBadRequest, relay.register(t, sk, past).Code)
+	rr = relay.testRequest(t, "GET", path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// registrations from the future are invalid
+	require.Equal(t, http.StatusBadRequest, relay.register(t, sk, uint64(time.Now().Add(time.Hour).Unix())).Code)
+
+	// without gating, anyone gets a bid
+	other, _ := newKeypair(t)
+	relay.gating.Disable = true
+	rr = relay.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", 0, parent.Hash().Hex(), other), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestBidFaults(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	srv := httptest.NewServer(relay.getRouter())
+	defer srv.Close()
+	client := builderclient.New(srv.URL, logrus.New())
+	var pubkey types.PublicKey
+	pubkey.FromSlice(pk)
+	// bids are cached per slot, so every request is for the next slot
+	slot := uint64(0)
+	getHeader := func() error {
+		slot++
+		_, err := client.GetHeader(ctx, slot, parent.Hash(), pubkey)
+		return err
+	}
+
+	require.NoError(t, getHeader())
+	relay.bids.NoBid = 1
+	require.ErrorIs(t, getHeader(), builderclient.ErrNoBid)
+	relay.bids.NoBid = 0
+	relay.bids.ZeroValue = 1
+	require.ErrorIs(t, getHeader(), builderclient.ErrInvalidBid)
+	relay.bids.ZeroValue = 0
+	relay.bids.WrongParent = 1
+	require.ErrorIs(t, getHeader(), builderclient.ErrInvalidBid)
+}
+
+func TestGetPayload(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	// responses that do not match the builder-specs fail with a 500 error
+	schemas, err := rpc.NewSchemaChecker(rpc.SchemaError, rpc.BuilderSchemas, relay.log)
+	require.NoError(t, err)
+	relay.schemas = schemas
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	parent := relay.engine.mockChain().CurrentHeader()
+	parentHash := parent.Hash()
+
+	// Initialize engine
+	_, err = relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{
+			HeadBlockHash:      parentHash,
+			SafeBlockHash:      parentHash,
+			FinalizedBlockHash: parentHash,
+		},
+		&types.PayloadAttributesV1{
+			Timestamp:             parent.Time + 1,
+			PrevRandao:            common.Hash{0x01},
+			SuggestedFeeRecipient: common.Address{0x02},
+		},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+
+	// Call getHeader to prepare payload
+	path := fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", 0, parentHash.Hex(), pk)
+	rr := relay.testRequest(t, "GET", path, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	bid := new(types.GetHeaderResponse)
+	err = json.Unmarshal(rr.Body.Bytes(), bid)
+	require.NoError(t, err)
+
+	// Create request payload
+	msg := &types.BlindedBeaconBlock{
+		Slot:          1,
+		ProposerIndex: 2,
+		ParentRoot:    types.Root{0x03},
+		StateRoot:     types.Root{0x04},
+		Body: &types.BlindedBeaconBlockBody{
+			Eth1Data: &types.Eth1Data{
+				DepositRoot:  types.Root{0x05},
+				DepositCount: 5,
+				BlockHash:    types.Hash{0x06},
+			},
+			SyncAggregate: &types.SyncAggregate{
+				CommitteeBits:      types.CommitteeBits{0x07},
+				CommitteeSignature: types.Signature{0x08},
+			},
+			ExecutionPayloadHeader: bid.Data.Message.Header,
+		},
+	}
+
+	// Sign payload
+	root, err := types.ComputeSigningRoot(msg, types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &relay.genesisValidatorsRoot))
+	require.NoError(t, err)
+	sig := sk.Sign(root[:]).Marshal()
+	var signature types.Signature
+	signature.FromSlice(sig)
+	require.Equal(t, sig[:], signature[:])
+
+	// Call getPayload with invalid signature
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/blinded_blocks", types.SignedBlindedBeaconBlock{
+		Message:   msg,
+		Signature: types.Signature{0x09},
+	})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Call getPayload with correct signature
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/blinded_blocks", types.SignedBlindedBeaconBlock{
+		Message:   msg,
+		Signature: signature,
+	})
+
+	// Verify getPayload response
+	require.Equal(t, http.StatusOK, rr.Code)
+	getPayloadResponse := new(types.GetPayloadResponse)
+	err = json.Unmarshal(rr.Body.Bytes(), getPayloadResponse)
+	require.NoError(t, err)
+	require.Equal(t, bid.Data.Message.Header.BlockHash, getPayloadResponse.Data.BlockHash)
+}
+
+func TestExecutionPayloadTransformations(t *testing.T) {
+	// Test: block -> EL payload -> CL payload -> EL payload -> block -> compare blockhash
+	relay := newTestRelay(t)
+	relay.engine.Run(context.Background())
+	parent := relay.engine.mockChain().CurrentHeader()
+
+	txsCreator := TransactionsCreator{nil, func(config *params.ChainConfig, bc core.ChainContext,
+		statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		return nil // TODO: create some transactions
+	}}
+
+	// Create a block
+	block1, err := relay.engine.mockChain().AddNewBlock(parent.Hash(), common.Address{0x02}, 12345, 23456, txsCreator, common.Hash{0x04}, []byte("hello"), nil, nil, nil, false)
+	require.NoError(t, err)
+
+	// Transform to EL payload
+	payloadEl, err := api.BlockToPayload(block1)
+	require.NoError(t, err)
+
+	// Transform EL payload to CL payload
+	payloadCl, err := types.ELPayloadToRESTPayload(payloadEl)
+	require.NoError(t, err)
+
+	// Transform CL payload back to EL payload
+	payloadEl2, err := types.RESTPayloadToELPayload(payloadCl)
+	require.NoError(t, err)
+
+	// Create a block from the 'new' EL payload and ensure correctness
+	block2, err := relay.engine.mockChain().ProcessPayload(payloadEl2)
+	require.NoError(t, err)
+	require.Equal(t, block1.Hash(), block2.Hash())
+}
+
+func TestStrictRegistration(t *testing.T) {
+	relay := newTestRelay(t)
+	pk, sk := newKeypair(t)
+	var pubkey types.PublicKey
+	pubkey.FromSlice(pk)
+	msg := &types.RegisterValidatorRequestMessage{
+		FeeRecipient: types.Address{0x42},
+		GasLimit:     15_000_000,
+		Timestamp:    uint64(time.Now().Unix()),
+		Pubkey:       pubkey,
+	}
+	root, err := types.ComputeSigningRoot(msg, types.DomainBuilder)
+	require.NoError(t, err)
+	var sig types.Signature
+	sig.FromSlice(sk.Sign(root[:]).Marshal())
+	enc, err := json.Marshal([]types.SignedValidatorRegistration{{Message: msg, Signature: sig}})
+	require.NoError(t, err)
+	withUnknown := json.RawMessage(bytes.Replace(enc, []byte(`"signature"`), []byte(`"extra":"0x","signature"`), 1))
+
+	// The unknown field is ignored by default, and rejected in strict mode
+	relay.strictJSON = true
+	rr := relay.testRequest(t, "POST", "/eth/v1/builder/validators", withUnknown)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), `unknown field "extra"`)
+
+	relay.strictJSON = false
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/validators", withUnknown)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// A public key at infinity fails signature verification, or decoding with strict BLS checks
+	atInfinity := json.RawMessage(bytes.Replace(enc, []byte(pubkey.String()), []byte("0xc0"+strings.Repeat("00", 47)), 1))
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/validators", atInfinity)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.NotContains(t, rr.Body.String(), "infinity")
+	types.SetStrictBLS(true)
+	defer types.SetStrictBLS(false)
+	rr = relay.testRequest(t, "POST", "/eth/v1/builder/validators", atInfinity)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "point at infinity")
+}
+
+func TestSSZRequests(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	sszRequest := func(path string, body []byte, version string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/octet-stream")
+		if version != "" {
+			req.Header.Set("Eth-Consensus-Version", version)
+		}
+		rr := httptest.NewRecorder()
+		relay.getRouter().ServeHTTP(rr, req)
+		return rr
+	}
+
+	// register
+	msg := &types.RegisterValidatorRequestMessage{FeeRecipient: types.Address{0x42}, GasLimit: 30_000_000, Timestamp: uint64(time.Now().Unix())}
+	msg.Pubkey.FromSlice(pk)
+	root, err := types.ComputeSigningRoot(msg, types.DomainBuilder)
+	require.NoError(t, err)
+	reg := types.SignedValidatorRegistration{Message: msg}
+	reg.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+	body, err := types.MarshalSignedValidatorRegistrations([]types.SignedValidatorRegistration{reg})
+	require.NoError(t, err)
+	rr := sszRequest(pathRegisterValidator, body[:100], "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = sszRequest(pathRegisterValidator, body, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, msg, relay.registration(msg.Pubkey))
+
+	// get a header to blind
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err = relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	rr = relay.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", 0, parent.Hash().Hex(), pk), nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	bid := new(types.GetHeaderResponse)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+
+	// submit the blinded block
+	block := &types.BlindedBeaconBlock{
+		Slot: 1,
+		Body: &types.BlindedBeaconBlockBody{
+			Eth1Data:               &types.Eth1Data{},
+			SyncAggregate:          &types.SyncAggregate{},
+			ExecutionPayloadHeader: bid.Data.Message.Header,
+		},
+	}
+	root, err = types.ComputeSigningRoot(block, types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &relay.genesisValidatorsRoot))
+	require.NoError(t, err)
+	signed := &types.SignedBlindedBeaconBlock{Message: block}
+	signed.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+	body, err = signed.MarshalSSZ()
+	require.NoError(t, err)
+	rr = sszRequest(pathGetPayload, body, types.VersionCapella)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	for _, version := range []string{types.VersionBellatrix, ""} {
+		rr = sszRequest(pathGetPayload, body, version)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		res := new(types.GetPayloadResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), res))
+		require.Equal(t, bid.Data.Message.Header.BlockHash, res.Data.BlockHash)
+	}
+}
+
+func TestRelayRetries(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	getHeader := func(slot uint64) *types.GetHeaderResponse {
+		rr := relay.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", slot, parent.Hash().Hex(), pk), nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		bid := new(types.GetHeaderResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		return bid
+	}
+
+	// the same request gets the same bid, even if the relay would bid differently now
+	bid := getHeader(1)
+	relay.bids.ZeroValue = 1
+	require.Equal(t, bid, getHeader(1))
+	require.Equal(t, types.U256Str{}, getHeader(2).Data.Message.Value)
+	relay.bids.ZeroValue = 0
+
+	getPayload := func(slot uint64, graffiti types.Hash) *httptest.ResponseRecorder {
+		block := &types.BlindedBeaconBlock{
+			Slot: slot,
+			Body: &types.BlindedBeaconBlockBody{
+				Eth1Data:               &types.Eth1Data{},
+				Graffiti:               graffiti,
+				SyncAggregate:          &types.SyncAggregate{},
+				ExecutionPayloadHeader: bid.Data.Message.Header,
+			},
+		}
+		root, err := types.ComputeSigningRoot(block, types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &relay.genesisValidatorsRoot))
+		require.NoError(t, err)
+		signed := types.SignedBlindedBeaconBlock{Message: block}
+		signed.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+		return relay.testRequest(t, "POST", pathGetPayload, signed)
+	}
+
+	// a retried blinded block gets the same payload, another one for the slot is refused
+	rr := getPayload(1, types.Hash{})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	first := rr.Body.String()
+	rr = getPayload(1, types.Hash{})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, first, rr.Body.String())
+	rr = getPayload(1, types.Hash{0x01})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, errAlreadyDelivered.Error()+"\n", rr.Body.String())
+}
+
+func TestRelayProfiles(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	pk, sk := newKeypair(t)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+
+	// relays with other profiles share the engine
+	newProfile := func(profile string) *testRelayBackend {
+		relaySk, err := bls.RandKey()
+		require.NoError(t, err)
+		b, err := newRelayBackend(logrus.New(), relay.engine, types.Root{0x12, 0x34}, relaySk)
+		require.NoError(t, err)
+		b.value = types.IntToU256(1000)
+		require.NoError(t, b.applyProfile(profile, 100*time.Millisecond))
+		mr := &testRelayBackend{b}
+		require.Equal(t, http.StatusOK, mr.register(t, sk, uint64(time.Now().Unix())).Code)
+		return mr
+	}
+	getHeader := func(mr *testRelayBackend) (*types.GetHeaderResponse, time.Duration) {
+		start := time.Now()
+		rr := mr.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", 1, parent.Hash().Hex(), pk), nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		bid := new(types.GetHeaderResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		return bid, time.Since(start)
+	}
+
+	honest, took := getHeader(newProfile(relayHonest))
+	require.Less(t, took, 100*time.Millisecond)
+	require.Equal(t, types.U256Str{0xe8, 0x03}, honest.Data.Message.Value)
+	lowBid, _ := getHeader(newProfile(relayLowBid))
+	require.Equal(t, types.U256Str{100}, lowBid.Data.Message.Value)
+	require.NotEqual(t, honest.Data.Message.Pubkey, lowBid.Data.Message.Pubkey)
+
+	// bids can be worth up to 2**256-1 wei
+	var max types.U256Str
+	require.NoError(t, max.Set("115792089237316195423570985008687907853269984665640564039457584007913129639935"))
+	rich := newProfile(relayHonest)
+	rich.value = max
+	bid, _ := getHeader(rich)
+	require.Equal(t, max, bid.Data.Message.Value)
+	rich = newProfile(relayHonest)
+	rich.value = max
+	require.NoError(t, rich.applyProfile(relayLowBid, 0))
+	bid, _ = getHeader(rich)
+	require.Equal(t, "11579208923731619542357098500868790785326998466564056403945758400791312963993", bid.Data.Message.Value.String())
+	_, took = getHeader(newProfile(relaySlow))
+	require.GreaterOrEqual(t, took, 100*time.Millisecond)
+
+	// a withholding relay serves bids, but no payloads
+	withholding := newProfile(relayWithholding)
+	bid, _ = getHeader(withholding)
+	block := &types.BlindedBeaconBlock{
+		Slot: 1,
+		Body: &types.BlindedBeaconBlockBody{
+			Eth1Data:               &types.Eth1Data{},
+			SyncAggregate:          &types.SyncAggregate{},
+			ExecutionPayloadHeader: bid.Data.Message.Header,
+		},
+	}
+	root, err := types.ComputeSigningRoot(block, types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &withholding.genesisValidatorsRoot))
+	require.NoError(t, err)
+	signed := types.SignedBlindedBeaconBlock{Message: block}
+	signed.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+	enc, err := json.Marshal(signed)
+	require.NoError(t, err)
+	reqCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "POST", pathGetPayload, bytes.NewReader(enc))
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	withholding.getRouter().ServeHTTP(rr, req)
+	require.Empty(t, rr.Body.String())
+
+	require.Error(t, relay.applyProfile("generous", 0))
+}
+
+func TestSubmitBlock(t *testing.T) {
+	ctx := context.Background()
+	relay := newTestRelay(t)
+	relay.engine.Run(ctx)
+	relay.value = types.IntToU256(1000)
+	pk, sk := newKeypair(t)
+	require.Equal(t, http.StatusOK, relay.register(t, sk, uint64(time.Now().Unix())).Code)
+	var proposer types.PublicKey
+	copy(proposer[:], pk)
+	parent := relay.engine.mockChain().CurrentHeader()
+	_, err := relay.engine.backend.ForkchoiceUpdatedV1(
+		ctx,
+		&types.ForkchoiceStateV1{HeadBlockHash: parent.Hash(), SafeBlockHash: parent.Hash(), FinalizedBlockHash: parent.Hash()},
+		&types.PayloadAttributesV1{Timestamp: parent.Time + 1, PrevRandao: common.Hash{0x01}, SuggestedFeeRecipient: common.Address{0x02}},
+	)
+	require.NoError(t, err, "unable to initialize engine")
+	_payload, ok := relay.engine.backend.recentPayloads.Get(parent.Hash())
+	require.True(t, ok)
+	payload := _payload.(*types.ExecutionPayloadV3)
+	rest, err := types.ELPayloadToRESTPayload(payload.V1())
+	require.NoError(t, err)
+
+	builderPk, builderSk := newKeypair(t)
+	submit := func(slot uint64, value uint64, feeRecipient types.Address) *httptest.ResponseRecorder {
+		msg := types.NewBidTrace(slot, payload, types.PublicKey{}, proposer, feeRecipient, types.IntToU256(value)).BidTrace
+		copy(msg.BuilderPubkey[:], builderPk)
+		root, err := types.ComputeSigningRoot(&msg, types.DomainBuilder)
+		require.NoError(t, err)
+		sub := types.BuilderSubmitBlockRequest{Message: &msg, ExecutionPayload: rest}
+		sub.Signature.FromSlice(builderSk.Sign(root[:]).Marshal())
+		return relay.testRequest(t, "POST", pathSubmitBlock, sub)
+	}
+	require.Equal(t, http.StatusBadRequest, submit(1, 5000, types.Address{0x01}).Code)
+	require.Equal(t, http.StatusOK, submit(1, 5000, types.Address{0x42}).Code)
+	require.Equal(t, http.StatusOK, submit(1, 2000, types.Address{0x42}).Code)
+	require.Equal(t, http.StatusOK, submit(2, 500, types.Address{0x42}).Code)
+
+	// the relay takes extra data up to the limit, and refuses payloads above the limits like engines
+	extra := rest.ExtraData
+	rest.ExtraData = bytes.Repeat([]byte{0xee}, types.MaxExtraDataBytes)
+	require.Equal(t, http.StatusOK, submit(3, 100, types.Address{0x42}).Code)
+	rest.ExtraData = append(rest.ExtraData, 0xee)
+	rr := submit(3, 100, types.Address{0x42})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), types.ErrPayloadLimit.Error())
+	rest.ExtraData = nil
+	rest.GasUsed = rest.GasLimit + 1
+	rr = submit(3, 100, types.Address{0x42})
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), errGasAboveLimit.Error())
+	rest.GasUsed = payload.GasUsed
+	rest.ExtraData = extra
+
+	// the best submission outbids the engine, the relay outbids worse ones
+	getHeader := func(slot uint64) *types.GetHeaderResponse {
+		rr := relay.testRequest(t, "GET", fmt.Sprintf("/eth/v1/builder/header/%d/%s/0x%x", slot, parent.Hash().Hex(), pk), nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		bid := new(types.GetHeaderResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		return bid
+	}
+	bid := getHeader(1)
+	require.Equal(t, types.IntToU256(5000), bid.Data.Message.Value)
+	require.Equal(t, relay.keys.pk, bid.Data.Message.Pubkey)
+	require.Equal(t, types.IntToU256(1000), getHeader(2).Data.Message.Value)
+
+	block := &types.BlindedBeaconBlock{
+		Slot: 1,
+		Body: &types.BlindedBeaconBlockBody{
+			Eth1Data:               &types.Eth1Data{},
+			SyncAggregate:          &types.SyncAggregate{},
+			ExecutionPayloadHeader: bid.Data.Message.Header,
+		},
+	}
+	root, err := types.ComputeSigningRoot(block, types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &relay.genesisValidatorsRoot))
+	require.NoError(t, err)
+	signed := types.SignedBlindedBeaconBlock{Message: block}
+	signed.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+	rr = relay.testRequest(t, "POST", pathGetPayload, signed)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	delivered := rr.Body.String()
+
+	// the archive serves the delivered payload, as JSON or SSZ
+	rr = relay.testRequest(t, "GET", pathDataPayload+"?block_hash="+payload.BlockHash.Hex(), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, delivered, rr.Body.String())
+	req, err := http.NewRequest("GET", pathDataPayload+"?block_hash="+payload.BlockHash.Hex(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/octet-stream")
+	rr = httptest.NewRecorder()
+	relay.getRouter().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	archived := &types.VersionedExecutionPayload{Version: rr.Header().Get("Eth-Consensus-Version")}
+	require.NoError(t, archived.UnmarshalSSZ(rr.Body.Bytes()))
+	require.Equal(t, types.Hash(payload.BlockHash), archived.BlockHash())
+	rr = relay.testRequest(t, "GET", pathDataPayload+"?block_hash="+common.Hash{0x01}.Hex(), nil)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	// the data API shows the traces of the received and delivered blocks
+	rr = relay.testRequest(t, "GET", pathDataDelivered, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var traces []types.BidTraceV2
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &traces))
+	require.Len(t, traces, 1)
+	require.Equal(t, types.IntToU256(5000), traces[0].Value)
+	require.Equal(t, types.Address{0x42}, traces[0].ProposerFeeRecipient)
+	require.Equal(t, payload.Number, traces[0].BlockNumber)
+	require.Equal(t, payload.BlockHash, common.Hash(traces[0].BlockHash))
+	require.Equal(t, bid.Data.Message.Header.BlockHash, traces[0].BlockHash)
+
+	rr = relay.testRequest(t, "GET", pathDataReceived+"?slot=1", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var received []types.BidTraceV2WithTimestamp
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &received))
+	require.Len(t, received, 2)
+	require.Equal(t, types.IntToU256(2000), received[1].Value)
+	rr = relay.testRequest(t, "GET", pathDataReceived+"?slot=2", nil)
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &received))
+	require.Len(t, received, 2, "the submission and the block of the relay")
+	require.Equal(t, http.StatusBadRequest, relay.testRequest(t, "GET", pathDataReceived+"?slot=x", nil).Code)
+
+	// the submitted block does not pay the proposer
+	rr = relay.testRequest(t, "GET", pathDataPayments+"?slot=1", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var payments []proposerPayment
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payments))
+	require.Len(t, payments, 1)
+	require.Equal(t, paymentNone, payments[0].Method)
+	require.Equal(t, "proposer paid 0 of the 5000 wei bid", payments[0].Discrepancy)
+}
@@ -0,0 +1,206 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mergemock/types"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+)
+
+// RemoteSignerConfig configures the web3signer that signs for the first validators.
+type RemoteSignerConfig struct {
+	URL     string        `ask:"--url" help:"URL of a web3signer to sign the randao reveals, registrations and blocks of its validators with (empty to disable)"`
+	Pubkeys []string      `ask:"--pubkeys" help:"Public keys of the signer to use, as the first validators (empty for all keys of the signer)"`
+	Timeout time.Duration `ask:"--timeout" help:"Timeout of a signing request"`
+}
+
+func (r *RemoteSignerConfig) Default() {
+	r.Timeout = 5 * time.Second
+}
+
+// remoteSigner signs with the keys of a web3signer, over its eth2 signing API.
+type remoteSigner struct {
+	url  string
+	http *http.Client
+	log  logrus.Ext1FieldLogger
+}
+
+func newRemoteSigner(cfg *RemoteSignerConfig, log logrus.Ext1FieldLogger) *remoteSigner {
+	return &remoteSigner{
+		url:  strings.TrimSuffix(cfg.URL, "/"),
+		http: &http.Client{Timeout: cfg.Timeout},
+		log:  log.WithField("signer", cfg.URL),
+	}
+}
+
+// publicKeys returns the public keys the signer holds.
+func (s *remoteSigner) publicKeys(ctx context.Context) ([]types.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/v1/eth2/publicKeys", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote signer returned status code %d listing its public keys", resp.StatusCode)
+	}
+	var pubkeys []types.PublicKey
+	return pubkeys, json.NewDecoder(resp.Body).Decode(&pubkeys)
+}
+
+// forkInfo is the fork the signer computes the domain of a signing root with.
+type forkInfo struct {
+	Fork struct {
+		PreviousVersion hexutil.Bytes `json:"previous_version"`
+		CurrentVersion  hexutil.Bytes `json:"current_version"`
+		Epoch           string        `json:"epoch"`
+	} `json:"fork"`
+	GenesisValidatorsRoot types.Root `json:"genesis_validators_root"`
+}
+
+// signedBlockHeader is the beacon block of a BLOCK_V2 signing request, by its header.
+type signedBlockHeader struct {
+	Version     string `json:"version"`
+	BlockHeader struct {
+		Slot          string     `json:"slot"`
+		ProposerIndex string     `json:"proposer_index"`
+		ParentRoot    types.Root `json:"parent_root"`
+		StateRoot     types.Root `json:"state_root"`
+		BodyRoot      types.Root `json:"body_root"`
+	} `json:"block_header"`
+}
+
+// signingEpoch is the epoch of a RANDAO_REVEAL signing request.
+type signingEpoch struct {
+	Epoch string `json:"epoch"`
+}
+
+// signRequest is a request to the eth2 signing API of web3signer, which checks the signing root
+// against the object of the type.
+type signRequest struct {
+	Type                  string                                 `json:"type"`
+	ForkInfo              *forkInfo                              `json:"fork_info,omitempty"`
+	SigningRoot           types.Root                             `json:"signingRoot"`
+	RandaoReveal          *signingEpoch                          `json:"randao_reveal,omitempty"`
+	BeaconBlock           *signedBlockHeader                     `json:"beacon_block,omitempty"`
+	ValidatorRegistration *types.RegisterValidatorRequestMessage `json:"validator_registration,omitempty"`
+}
+
+// sign asks the signer for the signature of the request with the key.
+func (s *remoteSigner) sign(ctx context.Context, pubkey types.PublicKey, sr *signRequest) (types.Signature, error) {
+	var sig types.Signature
+	body, err := json.Marshal(sr)
+	if err != nil {
+		return sig, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/api/v1/eth2/sign/"+pubkey.String(), bytes.NewReader(body))
+	if err != nil {
+		return sig, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	start := time.Now()
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return sig, fmt.Errorf("remote signer failed to sign %s: %w", sr.Type, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return sig, err
+	}
+	s.log.WithField("type", sr.Type).WithField("pubkey", pubkey).WithField("latency", time.Since(start)).Debug("Remote signer signed")
+	if resp.StatusCode != http.StatusOK {
+		return sig, fmt.Errorf("remote signer returned status code %d signing %s: %s", resp.StatusCode, sr.Type, strings.TrimSpace(string(raw)))
+	}
+	// the signature is JSON, or plain text for signers that ignore the accept header
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var res struct {
+			Signature types.Signature `json:"signature"`
+		}
+		if err := json.Unmarshal(raw, &res); err != nil {
+			return sig, err
+		}
+		return res.Signature, nil
+	}
+	return sig, sig.UnmarshalText(bytes.TrimSpace(raw))
+}
+
+// bellatrixForkInfo is the fork info of the bellatrix domain the consensus mock signs with.
+func (c *ConsensusCmd) bellatrixForkInfo() *forkInfo {
+	info := &forkInfo{GenesisValidatorsRoot: c.genesisValidatorsRoot}
+	// fork versions are little endian uint32 in the domain computation
+	v := make([]byte, 4)
+	binary.LittleEndian.PutUint32(v, uint32(version.Bellatrix))
+	info.Fork.PreviousVersion = v
+	info.Fork.CurrentVersion = v
+	info.Fork.Epoch = "0"
+	return info
+}
+
+// remoteValidators returns the validators of the remote signer, nil without one.
+func (c *ConsensusCmd) remoteValidators(ctx context.Context, log logrus.Ext1FieldLogger) ([]validator, error) {
+	if c.RemoteSigner.URL == "" {
+		return nil, nil
+	}
+	c.signer = newRemoteSigner(&c.RemoteSigner, log)
+	var pubkeys []types.PublicKey
+	if len(c.RemoteSigner.Pubkeys) > 0 {
+		pubkeys = make([]types.PublicKey, len(c.RemoteSigner.Pubkeys))
+		for i, s := range c.RemoteSigner.Pubkeys {
+			if err := pubkeys[i].UnmarshalText([]byte(s)); err != nil {
+				return nil, fmt.Errorf("invalid remote signer public key %q: %w", s, err)
+			}
+		}
+	} else {
+		var err error
+		if pubkeys, err = c.signer.publicKeys(ctx); err != nil {
+			return nil, fmt.Errorf("failed to list the public keys of the remote signer: %w", err)
+		}
+	}
+	validators := make([]validator, len(pubkeys))
+	for i, pk := range pubkeys {
+		remote, err := bls.PublicKeyFromBytes(pk[:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote signer public key %s: %w", pk, err)
+		}
+		validators[i] = validator{pk: pk, remote: remote}
+	}
+	log.WithField("validators", len(validators)).Info("Signing with remote signer")
+	return validators, nil
+}
+
+// signAs signs the signing root as the validator, with its key or with the remote signer, which
+// gets the request with the signing root.
+func (c *ConsensusCmd) signAs(idx int, root types.Root, sr *signRequest) (types.Signature, error) {
+	v := c.validators[idx]
+	if v.sk != nil {
+		var sig types.Signature
+		sig.FromSlice(v.sk.Sign(root[:]).Marshal())
+		return sig, nil
+	}
+	sr.SigningRoot = root
+	return c.signer.sign(c.ctx, v.pk, sr)
+}
+
+// publicKey returns the BLS public key of the validator.
+func (v validator) publicKey() bls.PublicKey {
+	if v.sk != nil {
+		return v.sk.PublicKey()
+	}
+	return v.remote
+}
@@ -0,0 +1,126 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/crypto/bls/blst"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSigner serves the eth2 signing API of web3signer with the key, and records the requests.
+func newTestSigner(t *testing.T, sk bls.SecretKey) (*httptest.Server, *[]signRequest) {
+	var pk types.PublicKey
+	pk.FromSlice(sk.PublicKey().Marshal())
+	var requests []signRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v1/eth2/publicKeys":
+			json.NewEncoder(w).Encode([]types.PublicKey{pk})
+		case r.URL.Path == "/api/v1/eth2/sign/"+pk.String():
+			var sr signRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sr))
+			requests = append(requests, sr)
+			var sig types.Signature
+			sig.FromSlice(sk.Sign(sr.SigningRoot[:]).Marshal())
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]types.Signature{"signature": sig})
+		case strings.HasPrefix(r.URL.Path, "/api/v1/eth2/sign/"):
+			http.Error(w, "public key not found", http.StatusNotFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestRemoteSigner(t *testing.T) {
+	sk, err := blst.RandKey()
+	require.NoError(t, err)
+	srv, requests := newTestSigner(t, sk)
+
+	c := newAttestingConsensus(t, 4, 1)
+	c.ctx = context.Background()
+	c.RemoteSigner.Default()
+	c.RemoteSigner.URL = srv.URL
+	remote, err := c.remoteValidators(c.ctx, logrus.New())
+	require.NoError(t, err)
+	require.Len(t, remote, 1)
+	require.Nil(t, remote[0].sk)
+	require.Equal(t, sk.PublicKey().Marshal(), remote[0].publicKey().Marshal())
+	c.validators[0] = remote[0]
+
+	// the remote validator reveals its randao with the signer
+	slot := uint64(0)
+	for c.state.Proposer(slot) != 0 {
+		slot++
+	}
+	reveal, err := c.randaoReveal(slot)
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	sr := (*requests)[0]
+	require.Equal(t, "RANDAO_REVEAL", sr.Type)
+	require.NotNil(t, sr.ForkInfo)
+	require.Equal(t, c.genesisValidatorsRoot, sr.ForkInfo.GenesisValidatorsRoot)
+
+	domain := types.ComputeDomain(types.DomainTypeRandao, version.Bellatrix, &c.genesisValidatorsRoot)
+	root, err := types.ComputeSigningRoot(&types.SigningEpoch{Epoch: c.state.EpochAt(slot)}, domain)
+	require.NoError(t, err)
+	require.Equal(t, types.Root(root), sr.SigningRoot)
+	sig, err := bls.SignatureFromBytes(reveal[:])
+	require.NoError(t, err)
+	require.True(t, sig.Verify(sk.PublicKey(), root[:]))
+
+	// local validators do not use the signer
+	slot++
+	for c.state.Proposer(slot) == 0 {
+		slot++
+	}
+	_, err = c.randaoReveal(slot)
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+
+	// the remote validator does not attest
+	for s := uint64(0); s < c.SlotsPerEpoch; s++ {
+		require.NoError(t, c.attest(s, common.Hash{0x01}))
+	}
+	require.Len(t, *requests, 1)
+}
+
+func TestRemoteSignerUnknownKey(t *testing.T) {
+	sk, err := blst.RandKey()
+	require.NoError(t, err)
+	srv, _ := newTestSigner(t, sk)
+
+	other, err := blst.RandKey()
+	require.NoError(t, err)
+	var pk types.PublicKey
+	pk.FromSlice(other.PublicKey().Marshal())
+	c := &ConsensusCmd{}
+	c.RemoteSigner.Default()
+	c.RemoteSigner.URL = srv.URL
+	c.RemoteSigner.Pubkeys = []string{pk.String()}
+	remote, err := c.remoteValidators(context.Background(), logrus.New())
+	require.NoError(t, err)
+	require.Len(t, remote, 1)
+	require.Equal(t, pk, remote[0].pk)
+
+	_, err = c.signer.sign(context.Background(), pk, &signRequest{Type: "RANDAO_REVEAL"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "status code 404")
+
+	c.RemoteSigner.Pubkeys = []string{"0x1234"}
+	_, err = c.remoteValidators(context.Background(), logrus.New())
+	require.Error(t, err)
+}
@@ -0,0 +1,370 @@
+package mock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"mergemock/engineclient"
+	"mergemock/rpc"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	gethRpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// emptyRequestsHash is the requests hash of a block without execution requests, the sha256 of
+// no request hashes.
+var emptyRequestsHash = common.Hash(sha256.Sum256(nil))
+
+// ReplayCmd replays a range of blocks of a live network, or of a chain export, through the
+// engine, so real-world blocks become test inputs.
+type ReplayCmd struct {
+	SourceAddr string `ask:"--source" help:"JSON-RPC endpoint of an execution client of the network to pull the blocks from, like a public RPC"`
+	Chain      string `ask:"--chain" help:"Chain export to replay instead of pulling the blocks from --source, as written with --out or the --export of the consensus mock"`
+	From       uint64 `ask:"--from" help:"Number of the first block to replay"`
+	To         uint64 `ask:"--to" help:"Number of the last block to replay (0 for the whole chain export)"`
+
+	EngineAddr    string `ask:"--engine" help:"Address of the Engine JSON-RPC endpoint to replay the blocks through, which must have the parent of the first block"`
+	JwtSecretPath string `ask:"--jwt-secret" help:"JWT secret key for authenticated communication"`
+
+	ForkchoiceEvery uint64 `ask:"--forkchoice-every" help:"Update the forkchoice of the engine to the last replayed block every this many blocks, and after the last one"`
+	KeepGoing       bool   `ask:"--keep-going" help:"Replay the rest of the range after a block the engine does not accept as valid, instead of stopping"`
+	Out             string `ask:"--out" help:"File to append the replayed payloads to as a chain export, to replay them again without the source (empty to disable)"`
+
+	LogCmd `ask:".log" help:"Change logger configuration"`
+}
+
+func (c *ReplayCmd) Default() {
+	c.EngineAddr = "http://127.0.0.1:8551"
+	c.JwtSecretPath = "jwt.hex"
+	c.ForkchoiceEvery = 1
+}
+
+func (c *ReplayCmd) Help() string {
+	return "Replay a range of blocks of a live network, or of a chain export, through the engine with newPayload and forkchoiceUpdated."
+}
+
+func (c *ReplayCmd) Examples() []string {
+	return []string{
+		"mergemock replay-chain --source=https://rpc.sepolia.org --from=5000000 --to=5000100 --out=sepolia.jsonl",
+		"mergemock replay-chain --chain=sepolia.jsonl --engine=http://127.0.0.1:8551",
+	}
+}
+
+// replayBlock is a block to replay, with the fork of its engine calls.
+type replayBlock struct {
+	exportedPayload
+	fork engineclient.Fork
+}
+
+// replaySource returns the blocks to replay in order, and io.EOF after the last one.
+type replaySource interface {
+	next(ctx context.Context) (*replayBlock, error)
+	Close() error
+}
+
+func (c *ReplayCmd) Run(ctx context.Context, args ...string) error {
+	if (c.SourceAddr == "") == (c.Chain == "") {
+		return fmt.Errorf("replay pulls the blocks from either --source or --chain")
+	}
+	if c.SourceAddr != "" && c.To < c.From {
+		return fmt.Errorf("last block %d before the first block %d", c.To, c.From)
+	}
+	if c.ForkchoiceEvery == 0 {
+		return fmt.Errorf("forkchoice updates every 0 blocks")
+	}
+	log, err := c.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	jwt, err := loadJwtSecret(c.JwtSecretPath)
+	if err != nil {
+		return fmt.Errorf("unable to read JWT secret: %w", err)
+	}
+	client, err := rpc.DialContext(ctx, c.EngineAddr, jwt)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	engine := engineclient.New(client, log)
+	if err := engine.Negotiate(ctx); err != nil {
+		log.WithError(err).Warn("Failed to exchange capabilities with engine, assuming it supports all methods")
+	}
+
+	var source replaySource
+	if c.SourceAddr != "" {
+		source, err = dialReplayRPC(ctx, c.SourceAddr, c.From, c.To)
+	} else {
+		source, err = openReplayChain(c.Chain, c.From, c.To)
+	}
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	var export *chainExport
+	if c.Out != "" {
+		if export, err = openChainExport(c.Out); err != nil {
+			return err
+		}
+		defer export.Close()
+	}
+	return c.replay(ctx, log, engine, source, export)
+}
+
+// replay sends the blocks of the source to the engine, and updates its forkchoice to them.
+func (c *ReplayCmd) replay(ctx context.Context, log logrus.Ext1FieldLogger, engine *engineclient.Client, source replaySource, export *chainExport) error {
+	var (
+		replayed, failed int
+		gas              uint64
+		last             *replayBlock
+		// the forkchoice is safe and finalized up to the parent of the first block
+		anchor common.Hash
+		start  = time.Now()
+	)
+	for {
+		block, err := source.next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		payload := block.Payload
+		if anchor == (common.Hash{}) {
+			anchor = payload.ParentHash
+		}
+		blog := log.WithField("number", payload.Number).WithField("hash", payload.BlockHash).WithField("fork", block.fork)
+		if export != nil {
+			if err := export.write(payload, block.BeaconRoot, nil); err != nil {
+				return err
+			}
+		}
+		if err := c.replayBlock(ctx, blog, engine, block); err != nil {
+			failed++
+			blog.WithError(err).Error("Engine did not accept the replayed block")
+			if !c.KeepGoing {
+				return fmt.Errorf("block %d (%s): %w", payload.Number, payload.BlockHash, err)
+			}
+			continue
+		}
+		replayed++
+		gas += payload.GasUsed
+		last = block
+		if uint64(replayed)%c.ForkchoiceEvery == 0 {
+			if err := c.replayForkchoice(ctx, engine, last, anchor); err != nil {
+				return err
+			}
+		}
+	}
+	if last != nil && uint64(replayed)%c.ForkchoiceEvery != 0 {
+		if err := c.replayForkchoice(ctx, engine, last, anchor); err != nil {
+			return err
+		}
+	}
+	elapsed := time.Since(start)
+	log.WithField("replayed", replayed).WithField("failed", failed).WithField("gas", gas).WithField("elapsed", elapsed).
+		WithField("mgasps", float64(gas)/1e6/elapsed.Seconds()).Info("Replayed blocks")
+	if failed > 0 {
+		return fmt.Errorf("engine did not accept %d of %d replayed blocks", failed, failed+replayed)
+	}
+	return nil
+}
+
+// replayBlock sends the block to the engine, which must consider it valid.
+func (c *ReplayCmd) replayBlock(ctx context.Context, log logrus.Ext1FieldLogger, engine *engineclient.Client, block *replayBlock) error {
+	hashes, err := types.BlobVersionedHashes(block.Payload.Transactions)
+	if err != nil {
+		return err
+	}
+	var root common.Hash
+	if block.BeaconRoot != nil {
+		root = *block.BeaconRoot
+	}
+	start := time.Now()
+	res, err := engine.NewPayload(ctx, block.fork, block.Payload, hashes, root, types.ExecutionRequests{})
+	if err != nil {
+		return err
+	}
+	if res.Status != types.ExecutionValid {
+		return fmt.Errorf("status %s: %s", res.Status, res.ValidationError)
+	}
+	log.WithField("txs", len(block.Payload.Transactions)).WithField("gasUsed", block.Payload.GasUsed).WithField("latency", time.Since(start)).Debug("Replayed block")
+	return nil
+}
+
+// replayForkchoice updates the forkchoice of the engine to the block.
+func (c *ReplayCmd) replayForkchoice(ctx context.Context, engine *engineclient.Client, block *replayBlock, anchor common.Hash) error {
+	head := block.Payload.BlockHash
+	res, err := engine.ForkchoiceUpdated(ctx, block.fork, head, anchor, anchor, nil)
+	if err != nil {
+		return fmt.Errorf("forkchoice update to block %d (%s): %w", block.Payload.Number, head, err)
+	}
+	if res.PayloadStatus.Status != types.ExecutionValid {
+		return fmt.Errorf("forkchoice update to block %d (%s): status %s", block.Payload.Number, head, res.PayloadStatus.Status)
+	}
+	return nil
+}
+
+// payloadFork returns the fork of the engine calls of a payload, by the fields of its fork.
+func payloadFork(payload *types.ExecutionPayloadV3, beaconRoot *common.Hash) engineclient.Fork {
+	switch {
+	case beaconRoot != nil:
+		return engineclient.Cancun
+	case payload.Withdrawals != nil:
+		return engineclient.Shanghai
+	default:
+		return engineclient.Paris
+	}
+}
+
+// replayChain reads the blocks to replay from a chain export.
+type replayChain struct {
+	f        *os.File
+	dec      *json.Decoder
+	from, to uint64
+}
+
+func openReplayChain(path string, from, to uint64) (*replayChain, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chain export: %v", err)
+	}
+	return &replayChain{f: f, dec: json.NewDecoder(f), from: from, to: to}, nil
+}
+
+func (r *replayChain) next(ctx context.Context) (*replayBlock, error) {
+	for {
+		var entry exportedPayload
+		if err := r.dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("invalid chain export entry: %v", err)
+		}
+		if entry.Payload == nil {
+			return nil, fmt.Errorf("chain export entry without payload")
+		}
+		number := entry.Payload.Number
+		if number < r.from || (r.to != 0 && number > r.to) {
+			continue
+		}
+		return &replayBlock{entry, payloadFork(entry.Payload, entry.BeaconRoot)}, nil
+	}
+}
+
+func (r *replayChain) Close() error {
+	return r.f.Close()
+}
+
+// rpcBlock is a block of eth_getBlockByNumber with the transactions, with the fields of a
+// payload.
+type rpcBlock struct {
+	ParentHash            common.Hash             `json:"parentHash"`
+	Miner                 common.Address          `json:"miner"`
+	StateRoot             common.Hash             `json:"stateRoot"`
+	ReceiptsRoot          common.Hash             `json:"receiptsRoot"`
+	LogsBloom             ethTypes.Bloom          `json:"logsBloom"`
+	MixHash               common.Hash             `json:"mixHash"`
+	Number                hexutil.Uint64          `json:"number"`
+	GasLimit              hexutil.Uint64          `json:"gasLimit"`
+	GasUsed               hexutil.Uint64          `json:"gasUsed"`
+	Timestamp             hexutil.Uint64          `json:"timestamp"`
+	ExtraData             hexutil.Bytes           `json:"extraData"`
+	BaseFee               *hexutil.Big            `json:"baseFeePerGas"`
+	Hash                  common.Hash             `json:"hash"`
+	Difficulty            *hexutil.Big            `json:"difficulty"`
+	Transactions          []*ethTypes.Transaction `json:"transactions"`
+	Withdrawals           types.Withdrawals       `json:"withdrawals"`
+	BlobGasUsed           *hexutil.Uint64         `json:"blobGasUsed"`
+	ExcessBlobGas         *hexutil.Uint64         `json:"excessBlobGas"`
+	ParentBeaconBlockRoot *common.Hash            `json:"parentBeaconBlockRoot"`
+	RequestsHash          *common.Hash            `json:"requestsHash"`
+}
+
+// replayBlock converts the block to the payload of the engine API. Blocks with execution requests
+// cannot be replayed, as the eth API only has the hash of their requests.
+func (b *rpcBlock) replayBlock() (*replayBlock, error) {
+	if b.BaseFee == nil || (b.Difficulty != nil && b.Difficulty.ToInt().Sign() != 0) {
+		return nil, fmt.Errorf("block %d is not a proof-of-stake block", b.Number)
+	}
+	payload := &types.ExecutionPayloadV3{
+		ParentHash:    b.ParentHash,
+		FeeRecipient:  b.Miner,
+		StateRoot:     b.StateRoot,
+		ReceiptsRoot:  b.ReceiptsRoot,
+		LogsBloom:     b.LogsBloom,
+		Random:        b.MixHash,
+		Number:        uint64(b.Number),
+		GasLimit:      uint64(b.GasLimit),
+		GasUsed:       uint64(b.GasUsed),
+		Timestamp:     uint64(b.Timestamp),
+		ExtraData:     b.ExtraData,
+		BaseFeePerGas: b.BaseFee.ToInt(),
+		BlockHash:     b.Hash,
+		Transactions:  make([][]byte, len(b.Transactions)),
+		Withdrawals:   b.Withdrawals,
+	}
+	for i, tx := range b.Transactions {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d of block %d: %v", i, b.Number, err)
+		}
+		payload.Transactions[i] = raw
+	}
+	if b.BlobGasUsed != nil {
+		payload.BlobGasUsed = uint64(*b.BlobGasUsed)
+	}
+	if b.ExcessBlobGas != nil {
+		payload.ExcessBlobGas = uint64(*b.ExcessBlobGas)
+	}
+	fork := payloadFork(payload, b.ParentBeaconBlockRoot)
+	if b.RequestsHash != nil {
+		if *b.RequestsHash != emptyRequestsHash {
+			return nil, fmt.Errorf("block %d has execution requests, which the eth API does not serve", b.Number)
+		}
+		fork = engineclient.Prague
+	}
+	return &replayBlock{exportedPayload{Payload: payload, BeaconRoot: b.ParentBeaconBlockRoot}, fork}, nil
+}
+
+// replayRPC pulls the blocks to replay from the eth API of a client of the network.
+type replayRPC struct {
+	client     *gethRpc.Client
+	number, to uint64
+}
+
+func dialReplayRPC(ctx context.Context, addr string, from, to uint64) (*replayRPC, error) {
+	client, err := gethRpc.DialContext(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial source: %w", err)
+	}
+	return &replayRPC{client: client, number: from, to: to}, nil
+}
+
+func (r *replayRPC) next(ctx context.Context) (*replayBlock, error) {
+	if r.number > r.to {
+		return nil, io.EOF
+	}
+	var block *rpcBlock
+	if err := r.client.CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeUint64(r.number), true); err != nil {
+		return nil, fmt.Errorf("failed to pull block %d: %w", r.number, err)
+	}
+	if block == nil {
+		return nil, fmt.Errorf("source does not have block %d", r.number)
+	}
+	r.number++
+	return block.replayBlock()
+}
+
+func (r *replayRPC) Close() error {
+	r.client.Close()
+	return nil
+}
@@ -0,0 +1,139 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/engineclient"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceNode serves the blocks by number over eth_getBlockByNumber, like a node of a network.
+type sourceNode map[string]map[string]interface{}
+
+func (n sourceNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Params []interface{}   `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": n[req.Params[0].(string)]})
+}
+
+// rpcBlockFields returns the block as the eth API serves it, with the transactions.
+func rpcBlockFields(block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash) map[string]interface{} {
+	fields := types.RPCMarshalHeader(block.Header())
+	fields["transactions"] = block.Transactions()
+	fields["withdrawals"] = withdrawals
+	fields["blobGasUsed"] = hexutil.Uint64(0)
+	fields["excessBlobGas"] = hexutil.Uint64(0)
+	fields["parentBeaconBlockRoot"] = beaconRoot
+	return fields
+}
+
+func TestReplayBlock(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := ethTypes.SignNewTx(key, ethTypes.NewLondonSigner(big.NewInt(1)), &ethTypes.DynamicFeeTx{
+		ChainID: big.NewInt(1), Nonce: 3, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: 21000, To: &common.Address{1}, Value: big.NewInt(5),
+	})
+	require.NoError(t, err)
+	header := &ethTypes.Header{Number: big.NewInt(7), Difficulty: common.Big0, BaseFee: big.NewInt(7), Time: 42, Extra: []byte("hi")}
+	block := ethTypes.NewBlockWithHeader(header).WithBody([]*ethTypes.Transaction{tx}, nil)
+	withdrawals := types.Withdrawals{{Index: 1, Validator: 2, Address: common.Address{3}, Amount: 4}}
+	beaconRoot := common.Hash{9}
+	data, err := json.Marshal(rpcBlockFields(block, withdrawals, &beaconRoot))
+	require.NoError(t, err)
+
+	var rpcBlock rpcBlock
+	require.NoError(t, json.Unmarshal(data, &rpcBlock))
+	replay, err := rpcBlock.replayBlock()
+	require.NoError(t, err)
+	expected, err := api.BlockToPayloadV3(block, withdrawals)
+	require.NoError(t, err)
+	require.Equal(t, expected, replay.Payload)
+	require.Equal(t, &beaconRoot, replay.BeaconRoot)
+	require.Equal(t, engineclient.Cancun, replay.fork)
+
+	// the eth API has the hash of the execution requests only
+	requestsHash := common.Hash{1}
+	rpcBlock.RequestsHash = &requestsHash
+	_, err = rpcBlock.replayBlock()
+	require.Error(t, err)
+	rpcBlock.RequestsHash = &emptyRequestsHash
+	replay, err = rpcBlock.replayBlock()
+	require.NoError(t, err)
+	require.Equal(t, engineclient.Prague, replay.fork)
+
+	// proof-of-work blocks are not payloads
+	rpcBlock.Difficulty = (*hexutil.Big)(big.NewInt(1))
+	_, err = rpcBlock.replayBlock()
+	require.Error(t, err)
+}
+
+func TestReplayChain(t *testing.T) {
+	c := newCheckpointConsensus(t)
+	node := sourceNode{}
+	var hashes []common.Hash
+	for slot := uint64(1); slot <= 5; slot++ {
+		parent := c.mockChain.CurrentHeader()
+		beaconRoot := common.Hash{byte(slot)}
+		block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.SlotTimestamp(slot), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+		require.NoError(t, err)
+		node[hexutil.EncodeUint64(block.NumberU64())] = rpcBlockFields(block, types.Withdrawals{}, &beaconRoot)
+		hashes = append(hashes, block.Hash())
+	}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+	engine := new(fakeEngine)
+	engineSrv, addr := serveFakeEngine(t, "127.0.0.1:0", engine)
+	defer engineSrv.Close()
+
+	cmd := &ReplayCmd{}
+	cmd.Default()
+	cmd.ForkchoiceEvery = 2
+	export := fmt.Sprintf("%s/replay.jsonl", t.TempDir())
+	out, err := openChainExport(export)
+	require.NoError(t, err)
+	source, err := dialReplayRPC(context.Background(), srv.URL, 2, 4)
+	require.NoError(t, err)
+	defer source.Close()
+	require.NoError(t, cmd.replay(context.Background(), logrus.New(), dialEngine(t, "http://"+addr), source, out))
+	require.NoError(t, out.Close())
+	require.Equal(t, []string{"engine_newPayloadV3", "engine_newPayloadV3", "engine_forkchoiceUpdatedV3", "engine_newPayloadV3", "engine_forkchoiceUpdatedV3"}, engine.methods)
+
+	// the replayed blocks are a chain export, to replay without the source
+	chain, err := openReplayChain(export, 3, 0)
+	require.NoError(t, err)
+	defer chain.Close()
+	for _, hash := range hashes[2:4] {
+		block, err := chain.next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, hash, block.Payload.BlockHash)
+		require.Equal(t, engineclient.Cancun, block.fork)
+	}
+	_, err = chain.next(context.Background())
+	require.ErrorIs(t, err, io.EOF)
+
+	// a missing block ends the replay
+	source, err = dialReplayRPC(context.Background(), srv.URL, 5, 6)
+	require.NoError(t, err)
+	defer source.Close()
+	require.Error(t, cmd.replay(context.Background(), logrus.New(), dialEngine(t, "http://"+addr), source, nil))
+}
@@ -0,0 +1,308 @@
+package mock
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report formats.
+const (
+	reportJUnit = "junit"
+	reportHive  = "hive"
+)
+
+// Scenarios of a consensus run, the checks of the engine that are reported as test cases.
+const (
+	scenarioProposal                 = "proposal"
+	scenarioPayloadProcessing        = "payload-processing"
+	scenarioExecutionRequests        = "execution-requests"
+	scenarioNewPayload               = "new-payload"
+	scenarioForkchoiceUpdated        = "forkchoice-updated"
+	scenarioWrongBeaconRoot          = "wrong-beacon-root"
+	scenarioWrongVersionedHashes     = "wrong-versioned-hashes"
+	scenarioWrongBaseFee             = "wrong-base-fee"
+	scenarioTxFaultBlock             = "tx-fault-block"
+	scenarioTxFaultMempool           = "tx-fault-mempool"
+	scenarioForkchoiceCheck          = "forkchoice-check"
+	scenarioHistoryCheck             = "history-check"
+	scenarioProposerValidation       = "proposer-validation"
+	scenarioBurst                    = "burst"
+	scenarioAncientPayload           = "ancient-payload"
+	scenarioAncientForkchoice        = "ancient-forkchoice"
+	scenarioInvalidTerminalBlock     = "invalid-terminal-block"
+	scenarioSmokeCheck               = "smoke-check"
+	scenarioAssertions               = "assertions"
+	scenarioStatelessPayload         = "stateless-payload"
+	scenarioFeeRecipientContract     = "fee-recipient-contract"
+	scenarioDeepReorg                = "deep-reorg"
+	scenarioForkchoiceZeroFinalized  = "forkchoice-zero-finalized"
+	scenarioForkchoiceGenesisHead    = "forkchoice-genesis-head"
+	scenarioForkchoiceFinalizedAhead = "forkchoice-finalized-ahead"
+	scenarioPayloadIDForkchoice      = "payload-id-after-forkchoice"
+	scenarioPayloadIDOtherParent     = "payload-id-other-parent"
+	scenarioPayloadIDExpired         = "payload-id-expired"
+	scenarioDuplicatePayload         = "duplicate-payload"
+	scenarioReverseDelivery          = "reverse-delivery"
+	scenarioInterleavedDelivery      = "interleaved-delivery"
+	scenarioLimitExtraData           = "limit-extra-data"
+	scenarioLimitGas                 = "limit-gas"
+	scenarioLimitLogsBloom           = "limit-logs-bloom"
+)
+
+var scenarioDescriptions = map[string]string{
+	scenarioProposal:                 "The engine builds a payload for the proposal of the slot, with the timestamp of the slot.",
+	scenarioPayloadProcessing:        "The payload the engine built is valid in the chain of the consensus mock.",
+	scenarioExecutionRequests:        "The execution requests of a Prague payload are well-formed, and match the deposits of the block.",
+	scenarioNewPayload:               "The engine accepts the payload of the slot as valid.",
+	scenarioForkchoiceUpdated:        "The engine accepts the forkchoice update to the block of the slot.",
+	scenarioWrongBeaconRoot:          "The engine does not accept a payload with the wrong parent beacon block root.",
+	scenarioWrongVersionedHashes:     "The engine rejects a payload with missing, extra or reordered versioned hashes as invalid.",
+	scenarioWrongBaseFee:             "The engine rejects a block with a base fee other than the EIP-1559 base fee of its parent as invalid.",
+	scenarioTxFaultBlock:             "The engine rejects a block with a transaction of the wrong chain id, after a nonce gap, below the base fee, or included twice as invalid.",
+	scenarioTxFaultMempool:           "The engine refuses transactions of the wrong chain id and duplicates over eth_sendRawTransaction, and builds no payload with a faulty transaction.",
+	scenarioForkchoiceCheck:          "The latest, safe and finalized blocks of the engine do not diverge from the forkchoice over two checks.",
+	scenarioHistoryCheck:             "The blocks of the engine at a sample of heights are those of the canonical chain of the forkchoice.",
+	scenarioProposerValidation:       "The proposer engine accepts the payload the engine built as valid.",
+	scenarioBurst:                    "The engine takes a burst of held back payloads, and the forkchoice update to the last one.",
+	scenarioAncientPayload:           "The engine does not consider the payload of a block far behind the finalized block invalid.",
+	scenarioAncientForkchoice:        "The engine does not consider a forkchoice update to a block far behind the finalized block invalid.",
+	scenarioInvalidTerminalBlock:     "The engine considers a payload built on a PoW block before the terminal block invalid.",
+	scenarioSmokeCheck:               "The receipts, eth_call results, gas estimates, nonces and balances of the engine match the transactions of the block.",
+	scenarioAssertions:               "The events of the run hold to the rules of the assertions file.",
+	scenarioStatelessPayload:         "The engine executes the payload with its execution witness only, to the state and receipts roots of the payload.",
+	scenarioFeeRecipientContract:     "The engine credits a fee recipient contract the priority fees of the block without calling it, and keeps its code.",
+	scenarioDeepReorg:                "The engine rolls its canonical chain back to a side chain forking deeper than its in-memory caches, on the forkchoice update to the side chain head.",
+	scenarioForkchoiceZeroFinalized:  "The engine accepts a forkchoice update to the head with a zero finalized hash before finality as valid.",
+	scenarioForkchoiceGenesisHead:    "The engine accepts a forkchoice update with the genesis block as head as valid.",
+	scenarioForkchoiceFinalizedAhead: "The engine refuses a forkchoice update with a finalized block ahead of the head with the invalid forkchoice state error.",
+	scenarioPayloadIDForkchoice:      "The engine serves no other payload than the one of a payload id after another forkchoice update to the head.",
+	scenarioPayloadIDOtherParent:     "The engine serves no other payload than the one of a payload id built on another parent than the head.",
+	scenarioPayloadIDExpired:         "The engine serves no other payload than the one of a payload id past the expiry.",
+	scenarioDuplicatePayload:         "The engine answers duplicates of a payload as it did the first: a valid payload as valid, and an accepted one not as invalid.",
+	scenarioReverseDelivery:          "The engine takes a burst of held back payloads delivered children first, and answers them as valid after the forkchoice update to the last one.",
+	scenarioInterleavedDelivery:      "The engine takes a burst of held back payloads interleaved with those of a second branch, and answers the held ones as valid after the forkchoice update to the last one.",
+	scenarioLimitExtraData:           "The engine takes a block with 32 bytes of extra data, and rejects one with 33 bytes.",
+	scenarioLimitGas:                 "The engine takes a block with as many transfers as fit in the gas limit, and rejects one with a transfer more.",
+	scenarioLimitLogsBloom:           "The engine takes a block whose logs fill the logs bloom, and rejects a full logs bloom without logs.",
+}
+
+// ReportConfig configures the report of a consensus run, for CI.
+type ReportConfig struct {
+	Path   string `ask:"--path" help:"File to write the scenarios of the run to as test cases with pass or fail results, when the run ends (empty to disable)"`
+	Format string `ask:"--format" help:"Format of the report: 'junit' for JUnit XML, 'hive' for the test suite JSON of hive"`
+}
+
+func (r *ReportConfig) Default() {
+	r.Format = reportJUnit
+}
+
+// scenarioCase is a scenario that ran at least once, with the failed runs.
+type scenarioCase struct {
+	runs       int
+	failures   []string
+	start, end time.Time
+}
+
+// scenarioReport collects the results of the scenarios of a run. A nil report records nothing.
+type scenarioReport struct {
+	mu    sync.Mutex
+	start time.Time
+	cases map[string]*scenarioCase
+
+	// failed is called on every failed run, nil if not set
+	failed func(scenario string, slot uint64, err error)
+}
+
+func newScenarioReport() *scenarioReport {
+	return &scenarioReport{start: time.Now(), cases: make(map[string]*scenarioCase)}
+}
+
+// record adds a run of the scenario in the slot, which failed with err if it is not nil.
+func (r *scenarioReport) record(scenario string, slot uint64, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	now := time.Now()
+	sc, ok := r.cases[scenario]
+	if !ok {
+		sc = &scenarioCase{start: now}
+		r.cases[scenario] = sc
+	}
+	sc.runs++
+	sc.end = now
+	if err != nil {
+		sc.failures = append(sc.failures, fmt.Sprintf("slot %d: %v", slot, err))
+	}
+	r.mu.Unlock()
+	if err != nil && r.failed != nil {
+		r.failed(scenario, slot, err)
+	}
+}
+
+// names returns the scenarios that ran, in order.
+func (r *scenarioReport) names() []string {
+	names := make([]string, 0, len(r.cases))
+	for name := range r.cases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// summary returns the result of the scenario as one line.
+func (sc *scenarioCase) summary() string {
+	if len(sc.failures) == 0 {
+		return fmt.Sprintf("%d of %d runs passed", sc.runs, sc.runs)
+	}
+	return fmt.Sprintf("%d of %d runs failed", len(sc.failures), sc.runs)
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+// junit returns the report as JUnit XML, with a test case per scenario.
+func (r *scenarioReport) junit(end time.Time) ([]byte, error) {
+	suite := junitSuite{
+		Name:      "mergemock",
+		Time:      end.Sub(r.start).Seconds(),
+		Timestamp: r.start.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, name := range r.names() {
+		sc := r.cases[name]
+		tc := junitCase{
+			Name:      name,
+			ClassName: "mergemock.consensus",
+			Time:      sc.end.Sub(sc.start).Seconds(),
+			SystemOut: scenarioDescriptions[name] + " " + sc.summary(),
+		}
+		if len(sc.failures) > 0 {
+			tc.Failure = &junitFailure{Message: sc.summary(), Details: strings.Join(sc.failures, "\n")}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+	out, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+type hiveSuite struct {
+	ID             int                  `json:"id"`
+	Name           string               `json:"name"`
+	Description    string               `json:"description"`
+	ClientVersions map[string]string    `json:"clientVersions"`
+	TestCases      map[string]*hiveCase `json:"testCases"`
+}
+
+type hiveCase struct {
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	Start         time.Time         `json:"start"`
+	End           time.Time         `json:"end"`
+	SummaryResult hiveResult        `json:"summaryResult"`
+	ClientInfo    map[string]string `json:"clientInfo"`
+}
+
+type hiveResult struct {
+	Pass    bool   `json:"pass"`
+	Details string `json:"details"`
+}
+
+// hive returns the report as the test suite JSON hive writes for its simulators, with a test
+// case per scenario.
+func (r *scenarioReport) hive() ([]byte, error) {
+	suite := hiveSuite{
+		Name:           "mergemock",
+		Description:    "Scenarios of a mergemock consensus run against the engine.",
+		ClientVersions: map[string]string{},
+		TestCases:      make(map[string]*hiveCase),
+	}
+	for i, name := range r.names() {
+		sc := r.cases[name]
+		details := append([]string{sc.summary()}, sc.failures...)
+		suite.TestCases[fmt.Sprint(i+1)] = &hiveCase{
+			Name:          name,
+			Description:   scenarioDescriptions[name],
+			Start:         sc.start.UTC(),
+			End:           sc.end.UTC(),
+			SummaryResult: hiveResult{Pass: len(sc.failures) == 0, Details: strings.Join(details, "\n")},
+			ClientInfo:    map[string]string{},
+		}
+	}
+	return json.MarshalIndent(suite, "", "  ")
+}
+
+// write writes the report to the path in the format.
+func (r *scenarioReport) write(path, format string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []byte
+	var err error
+	switch format {
+	case reportJUnit:
+		out, err = r.junit(time.Now())
+	case reportHive:
+		out, err = r.hive()
+	default:
+		err = fmt.Errorf("unrecognized report format: %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// writeReport writes the report of the run, and pushes its metrics, if enabled.
+func (c *ConsensusCmd) writeReport() {
+	c.pushMetrics()
+	if c.report == nil || c.Report.Path == "" {
+		return
+	}
+	if err := c.report.write(c.Report.Path, c.Report.Format); err != nil {
+		c.log.WithError(err).Error("Failed to write the report")
+		return
+	}
+	c.log.WithField("path", c.Report.Path).Info("Wrote the report")
+}
+
+// maybeExit ends runs with a slot bound after a failed check, with the report.
+func (c *ConsensusCmd) maybeExit() {
+	if c.SlotBound != 0 {
+		c.writeReport()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,56 @@
+package mock
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioReport(t *testing.T) {
+	var disabled *scenarioReport
+	disabled.record(scenarioProposal, 1, nil)
+
+	report := newScenarioReport()
+	report.record(scenarioProposal, 1, nil)
+	report.record(scenarioProposal, 2, nil)
+	report.record(scenarioNewPayload, 1, nil)
+	report.record(scenarioNewPayload, 2, errors.New("unrecognized execution status SYNCING"))
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "report.xml")
+	require.NoError(t, report.write(path, reportJUnit))
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var suites junitSuites
+	require.NoError(t, xml.Unmarshal(out, &suites))
+	require.Len(t, suites.Suites, 1)
+	suite := suites.Suites[0]
+	require.Equal(t, 2, suite.Tests)
+	require.Equal(t, 1, suite.Failures)
+	require.Equal(t, scenarioNewPayload, suite.Cases[0].Name)
+	require.Equal(t, "1 of 2 runs failed", suite.Cases[0].Failure.Message)
+	require.Equal(t, "slot 2: unrecognized execution status SYNCING", suite.Cases[0].Failure.Details)
+	require.Equal(t, scenarioProposal, suite.Cases[1].Name)
+	require.Nil(t, suite.Cases[1].Failure)
+
+	path = filepath.Join(dir, "report.json")
+	require.NoError(t, report.write(path, reportHive))
+	out, err = os.ReadFile(path)
+	require.NoError(t, err)
+	var hive hiveSuite
+	require.NoError(t, json.Unmarshal(out, &hive))
+	require.Len(t, hive.TestCases, 2)
+	require.Equal(t, scenarioNewPayload, hive.TestCases["1"].Name)
+	require.False(t, hive.TestCases["1"].SummaryResult.Pass)
+	require.Contains(t, hive.TestCases["1"].SummaryResult.Details, "slot 2: unrecognized execution status SYNCING")
+	require.Equal(t, scenarioProposal, hive.TestCases["2"].Name)
+	require.True(t, hive.TestCases["2"].SummaryResult.Pass)
+	require.Equal(t, scenarioDescriptions[scenarioProposal], hive.TestCases["2"].Description)
+
+	require.Error(t, report.write(path, "tap"))
+}
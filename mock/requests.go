@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"fmt"
+	"math/big"
+	"mergemock/contracts"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	requestTxGas = 200_000
+
+	// requestFee is paid to the request contracts, the fee starts at 1 wei and only grows
+	// with many requests per block, the excess is not refunded.
+	requestFee = params.GWei
+)
+
+// withdrawalRequestTxCreator makes an EIP-7002 full exit request for a validator, from the first test account.
+// The input is the validator pubkey followed by the big-endian amount, zero for a full exit.
+func withdrawalRequestTxCreator(pubkey func() (types.PublicKey, error)) func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		pk, err := pubkey()
+		if err != nil {
+			return nil
+		}
+		var amount [8]byte // zero for a full exit
+		input := append(pk[:], amount[:]...)
+		return requestTx(config, statedb, accounts, contracts.WithdrawalRequestAddress, input)
+	}
+}
+
+// consolidationRequestTxCreator makes an EIP-7251 request for a validator, from the first test account.
+// The source and target are the same validator, which requests a switch to compounding credentials.
+func consolidationRequestTxCreator(pubkey func() (types.PublicKey, error)) func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		pk, err := pubkey()
+		if err != nil {
+			return nil
+		}
+		input := append(pk[:], pk[:]...)
+		return requestTx(config, statedb, accounts, contracts.ConsolidationRequestAddress, input)
+	}
+}
+
+func requestTx(config *params.ChainConfig, statedb *state.StateDB, accounts []TestAccount, contract common.Address, input []byte) []*ethTypes.Transaction {
+	if len(accounts) == 0 {
+		return nil
+	}
+	feeCap := new(big.Int).Mul(big.NewInt(5), big.NewInt(params.GWei))
+	cost := new(big.Int).Add(big.NewInt(requestFee), new(big.Int).Mul(feeCap, big.NewInt(requestTxGas)))
+	if statedb.GetBalance(accounts[0].addr).Cmp(cost) < 0 {
+		// out of funds, the transaction would make the block invalid
+		return nil
+	}
+	signer := ethTypes.NewLondonSigner(config.ChainID)
+	txdata := &ethTypes.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     statedb.GetNonce(accounts[0].addr),
+		To:        &contract,
+		Gas:       requestTxGas,
+		GasFeeCap: feeCap,
+		GasTipCap: big.NewInt(2),
+		Value:     big.NewInt(requestFee),
+		Data:      input,
+	}
+	tx, err := ethTypes.SignTx(ethTypes.NewTx(txdata), signer, accounts[0].pk)
+	if err != nil {
+		return nil
+	}
+	return []*ethTypes.Transaction{tx}
+}
+
+// queuedRequests returns the requests, concatenated, that the transactions of a block queued in an
+// EIP-7002 or EIP-7251 system contract, as the contract dequeues them at the end of the block.
+// Each request is the sender followed by the input, with the amount of a withdrawal request
+// turned little-endian. This assumes the queue never backs up, as the tx profiles send at most
+// one request per block, far below the limits of the contracts.
+func queuedRequests(config *params.ChainConfig, block *ethTypes.Block, receipts []*ethTypes.Receipt, contract common.Address, inputSize int) ([]byte, error) {
+	signer := ethTypes.MakeSigner(config, block.Number())
+	var out []byte
+	for i, tx := range block.Transactions() {
+		if tx.To() == nil || *tx.To() != contract || len(tx.Data()) != inputSize || receipts[i].Status != ethTypes.ReceiptStatusSuccessful {
+			continue
+		}
+		sender, err := ethTypes.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request transaction %d: %v", i, err)
+		}
+		out = append(out, sender[:]...)
+		input := common.CopyBytes(tx.Data())
+		if contract == contracts.WithdrawalRequestAddress {
+			amount := input[len(types.PublicKey{}):]
+			for l, r := 0, len(amount)-1; l < r; l, r = l+1, r-1 {
+				amount[l], amount[r] = amount[r], amount[l]
+			}
+		}
+		out = append(out, input...)
+	}
+	return out, nil
+}
@@ -0,0 +1,286 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethRpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ShadowForkCmd writes the genesis of a shadow fork of a live network: the state of a block of
+// the network, which the engine and the consensus mock start from, to build mock blocks on top.
+type ShadowForkCmd struct {
+	GenesisPath string `ask:"--genesis" help:"Genesis file of the network, for the chain config and the fork times of the shadow fork"`
+	DataDir     string `ask:"--datadir" help:"Data directory of a geth node of the network to take the state of the fork block from, which must have recorded the preimages"`
+	StateDump   string `ask:"--state-dump" help:"State of the fork block as written by 'geth dump', as one JSON object or in lines with --iterative, instead of --datadir"`
+	SourceAddr  string `ask:"--source" help:"JSON-RPC endpoint of a client of the network to take the header of the fork block from, with --state-dump (empty to keep the header fields of --genesis)"`
+	Block       uint64 `ask:"--block" help:"Number of the fork block, whose state the shadow fork starts from (0 for the head of --datadir or --source)"`
+	Out         string `ask:"--out" help:"File to write the genesis of the shadow fork to"`
+
+	LogCmd `ask:".log" help:"Change logger configuration"`
+}
+
+func (c *ShadowForkCmd) Default() {
+	c.GenesisPath = "genesis.json"
+	c.Out = "shadow-genesis.json"
+}
+
+func (c *ShadowForkCmd) Help() string {
+	return "Write the genesis of a shadow fork from the state of a block of a live network, taken from a geth datadir or a state dump."
+}
+
+func (c *ShadowForkCmd) Examples() []string {
+	return []string{
+		"mergemock shadow-fork --genesis=sepolia.json --datadir=geth-sepolia --out=shadow-genesis.json",
+		"mergemock shadow-fork --genesis=sepolia.json --state-dump=dump.jsonl --source=http://127.0.0.1:8545 --block=5000000",
+	}
+}
+
+func (c *ShadowForkCmd) Run(ctx context.Context, args ...string) error {
+	if (c.DataDir == "") == (c.StateDump == "") {
+		return fmt.Errorf("shadow fork takes the state from either --datadir or --state-dump")
+	}
+	log, err := c.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	base, err := LoadGenesisConfig(c.GenesisPath)
+	if err != nil {
+		return err
+	}
+	if base.Config == nil {
+		return fmt.Errorf("genesis has no chain config")
+	}
+	forks, err := LoadForkTimes(c.GenesisPath)
+	if err != nil {
+		return err
+	}
+	var (
+		header *ethTypes.Header
+		st     *shadowState
+	)
+	if c.DataDir != "" {
+		header, st, err = readDatadirState(c.DataDir, c.Block)
+	} else if st, err = readStateDump(c.StateDump); err == nil && c.SourceAddr != "" {
+		header, err = fetchShadowHeader(ctx, c.SourceAddr, c.Block)
+	}
+	if err != nil {
+		return err
+	}
+	if st.missing > 0 {
+		return fmt.Errorf("state has %d accounts without the preimage of their address", st.missing)
+	}
+	genesis := shadowGenesis(base, header, st.alloc)
+	expected := st.root
+	if header != nil {
+		expected = header.Root
+	}
+	root := genesis.ToBlock(nil).Root()
+	if root != expected {
+		return fmt.Errorf("state root %s of the %d accounts does not match the state root %s of the fork block, the state is incomplete", root, len(st.alloc), expected)
+	}
+	if err := writeShadowGenesis(c.Out, genesis, forks); err != nil {
+		return fmt.Errorf("failed to write shadow genesis: %w", err)
+	}
+	flog := log.WithField("accounts", len(st.alloc)).WithField("root", root).WithField("out", c.Out)
+	if header != nil {
+		flog = flog.WithField("number", header.Number).WithField("timestamp", header.Time)
+	}
+	flog.Info("Wrote shadow fork genesis")
+	return nil
+}
+
+// shadowGenesis returns the genesis of the network with the state of the fork block, and the
+// header fields of the fork block if known. The genesis is block 0, as the chain starts at it,
+// and is past the merge.
+func shadowGenesis(base *core.Genesis, header *ethTypes.Header, alloc core.GenesisAlloc) *core.Genesis {
+	genesis := *base
+	config := *base.Config
+	config.TerminalTotalDifficulty = common.Big0
+	genesis.Config = &config
+	genesis.Alloc = alloc
+	genesis.Number = 0
+	genesis.GasUsed = 0
+	genesis.ParentHash = common.Hash{}
+	genesis.Difficulty = common.Big0
+	if header != nil {
+		genesis.Nonce = 0
+		genesis.Timestamp = header.Time
+		genesis.ExtraData = header.Extra
+		genesis.GasLimit = header.GasLimit
+		genesis.Mixhash = header.MixDigest
+		genesis.Coinbase = header.Coinbase
+		genesis.BaseFee = header.BaseFee
+	}
+	return &genesis
+}
+
+// writeShadowGenesis writes the genesis with the fork times in its config, which the chain config
+// of this geth version does not know about.
+func writeShadowGenesis(path string, genesis *core.Genesis, forks ForkTimes) error {
+	data, err := genesis.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(fields["config"], &config); err != nil {
+		return err
+	}
+	for name, time := range map[string]*uint64{"shanghaiTime": forks.ShanghaiTime, "cancunTime": forks.CancunTime, "pragueTime": forks.PragueTime} {
+		if time != nil {
+			config[name], _ = json.Marshal(*time)
+		}
+	}
+	if fields["config"], err = json.Marshal(config); err != nil {
+		return err
+	}
+	if data, err = json.MarshalIndent(fields, "", "  "); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// shadowState collects the accounts of a state dump as a genesis allocation.
+type shadowState struct {
+	root  common.Hash
+	alloc core.GenesisAlloc
+	// accounts without the preimage of their address, which cannot be allocated
+	missing int
+	err     error
+}
+
+func newShadowState() *shadowState {
+	return &shadowState{alloc: make(core.GenesisAlloc)}
+}
+
+func (s *shadowState) OnRoot(root common.Hash) {
+	s.root = root
+}
+
+func (s *shadowState) OnAccount(addr common.Address, account state.DumpAccount) {
+	if addr == (common.Address{}) && len(account.SecureKey) > 0 && common.BytesToHash(account.SecureKey) != crypto.Keccak256Hash(addr[:]) {
+		s.missing++
+		return
+	}
+	balance, ok := new(big.Int).SetString(account.Balance, 10)
+	if !ok {
+		if s.err == nil {
+			s.err = fmt.Errorf("invalid balance %q of account %s", account.Balance, addr)
+		}
+		return
+	}
+	acc := core.GenesisAccount{Balance: balance, Nonce: account.Nonce, Code: account.Code}
+	if len(account.Storage) > 0 {
+		acc.Storage = make(map[common.Hash]common.Hash, len(account.Storage))
+		for key, value := range account.Storage {
+			acc.Storage[key] = common.HexToHash(value)
+		}
+	}
+	s.alloc[addr] = acc
+}
+
+// readStateDump reads a state dump of geth, either one object with all accounts, or the state
+// root and then an account per line, as written with --iterative.
+func readStateDump(path string) (*shadowState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state dump: %v", err)
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	var dump struct {
+		Root     string                               `json:"root"`
+		Accounts map[common.Address]state.DumpAccount `json:"accounts"`
+	}
+	if err := dec.Decode(&dump); err != nil {
+		return nil, fmt.Errorf("invalid state dump: %v", err)
+	}
+	st := newShadowState()
+	st.OnRoot(common.HexToHash(dump.Root))
+	for addr, account := range dump.Accounts {
+		st.OnAccount(addr, account)
+	}
+	for dump.Accounts == nil {
+		var account state.DumpAccount
+		if err := dec.Decode(&account); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid state dump account: %v", err)
+		}
+		var addr common.Address
+		if account.Address != nil {
+			addr = *account.Address
+		}
+		st.OnAccount(addr, account)
+	}
+	return st, st.err
+}
+
+// readDatadirState reads the header and the state of the block from the chain database of a
+// geth datadir, the head block if the number is 0.
+func readDatadirState(datadir string, number uint64) (*ethTypes.Header, *shadowState, error) {
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+	db, err := rawdb.NewLevelDBDatabaseWithFreezer(chaindata, 128, 128, filepath.Join(chaindata, "ancient"), "", true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open chain database: %v", err)
+	}
+	defer db.Close()
+	hash := rawdb.ReadHeadBlockHash(db)
+	if number != 0 {
+		hash = rawdb.ReadCanonicalHash(db, number)
+	}
+	var header *ethTypes.Header
+	if n := rawdb.ReadHeaderNumber(db, hash); n != nil {
+		header = rawdb.ReadHeader(db, hash, *n)
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("chain database has no block %d", number)
+	}
+	statedb, err := state.New(header.Root, state.NewDatabaseWithConfig(db, &trie.Config{Preimages: true}), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("chain database has no state of block %d, which may be pruned: %v", header.Number, err)
+	}
+	st := newShadowState()
+	statedb.DumpToCollector(st, nil)
+	return header, st, st.err
+}
+
+// fetchShadowHeader returns the header of the block from the eth API of a client of the network,
+// the latest block if the number is 0.
+func fetchShadowHeader(ctx context.Context, addr string, number uint64) (*ethTypes.Header, error) {
+	client, err := gethRpc.DialContext(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial source: %w", err)
+	}
+	defer client.Close()
+	tag := "latest"
+	if number != 0 {
+		tag = hexutil.EncodeUint64(number)
+	}
+	var header *ethTypes.Header
+	if err := client.CallContext(ctx, &header, "eth_getBlockByNumber", tag, false); err != nil {
+		return nil, fmt.Errorf("failed to pull block %s: %w", tag, err)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("source does not have block %s", tag)
+	}
+	return header, nil
+}
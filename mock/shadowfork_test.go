@@ -0,0 +1,141 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// liveState returns a state like that of a live network, with an account, a contract with storage,
+// and the preimages of their addresses and keys.
+func liveState(t *testing.T) *state.StateDB {
+	statedb, err := state.New(common.Hash{}, state.NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &trie.Config{Preimages: true}), nil)
+	require.NoError(t, err)
+	statedb.SetBalance(common.Address{1}, big.NewInt(1e18))
+	statedb.SetNonce(common.Address{1}, 7)
+	statedb.SetCode(common.Address{2}, []byte{0x60, 0x00})
+	statedb.SetState(common.Address{2}, common.Hash{3}, common.Hash{4})
+	root, err := statedb.Commit(true)
+	require.NoError(t, err)
+	statedb, err = state.New(root, statedb.Database(), nil)
+	require.NoError(t, err)
+	return statedb
+}
+
+func TestShadowFork(t *testing.T) {
+	statedb := liveState(t)
+	root := statedb.IntermediateRoot(true)
+	shanghai := uint64(0)
+	genesisPath := writeGenesis(t, newMergedGenesis(common.Address{}), ForkTimes{ShanghaiTime: &shanghai})
+	dir := t.TempDir()
+	dump := filepath.Join(dir, "dump.json")
+	data, err := json.Marshal(statedb.RawDump(nil))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dump, data, 0644))
+	iterative := filepath.Join(dir, "dump.jsonl")
+	f, err := os.Create(iterative)
+	require.NoError(t, err)
+	statedb.IterativeDump(nil, json.NewEncoder(f))
+	require.NoError(t, f.Close())
+
+	header := &ethTypes.Header{Number: big.NewInt(5), Difficulty: common.Big0, BaseFee: big.NewInt(7), Time: 100, GasLimit: 30_000_000, Extra: []byte("live"), Root: root}
+	node := sourceNode{"0x5": types.RPCMarshalHeader(header)}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+
+	for _, path := range []string{dump, iterative} {
+		cmd := &ShadowForkCmd{}
+		cmd.Default()
+		cmd.LogCmd.Default()
+		cmd.GenesisPath = genesisPath
+		cmd.StateDump = path
+		cmd.SourceAddr = srv.URL
+		cmd.Block = 5
+		cmd.Out = filepath.Join(dir, "shadow-genesis.json")
+		require.NoError(t, cmd.Run(context.Background()))
+
+		genesis, err := LoadGenesis(cmd.Out, "")
+		require.NoError(t, err)
+		forks, err := LoadForkTimes(cmd.Out)
+		require.NoError(t, err)
+		require.Equal(t, &shanghai, forks.ShanghaiTime)
+		require.Equal(t, uint64(100), genesis.Timestamp)
+		require.Equal(t, []byte("live"), genesis.ExtraData)
+		require.Equal(t, uint64(7), genesis.Alloc[common.Address{1}].Nonce)
+		require.Equal(t, common.Hash{4}, genesis.Alloc[common.Address{2}].Storage[common.Hash{3}])
+
+		// the mock chain builds blocks of its own on the state of the fork block
+		mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+		require.NoError(t, err)
+		mc.SetForkTimes(forks)
+		parent := mc.CurrentHeader()
+		require.Equal(t, root, parent.Root)
+		block, err := mc.AddNewBlock(parent.Hash(), common.Address{9}, parent.Time+12, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, nil, true)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), block.NumberU64())
+	}
+
+	// the state must be that of the fork block
+	cmd := &ShadowForkCmd{}
+	cmd.Default()
+	cmd.LogCmd.Default()
+	cmd.GenesisPath = genesisPath
+	cmd.StateDump = dump
+	cmd.SourceAddr = srv.URL
+	cmd.Block = 6
+	require.Error(t, cmd.Run(context.Background()))
+	header.Root = common.Hash{1}
+	node["0x5"] = types.RPCMarshalHeader(header)
+	cmd.Block = 5
+	err = cmd.Run(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "state is incomplete")
+}
+
+func TestShadowForkDatadir(t *testing.T) {
+	statedb := liveState(t)
+	alloc := newShadowState()
+	statedb.DumpToCollector(alloc, nil)
+	require.NoError(t, alloc.err)
+
+	// a geth datadir with the state as its head, and the preimages of the addresses and keys
+	datadir := t.TempDir()
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+	db, err := rawdb.NewLevelDBDatabaseWithFreezer(chaindata, 16, 16, filepath.Join(chaindata, "ancient"), "", false)
+	require.NoError(t, err)
+	live := newMergedGenesis(common.Address{})
+	live.Alloc = alloc.alloc
+	block, err := live.Commit(db)
+	require.NoError(t, err)
+	rawdb.WritePreimages(db, map[common.Hash][]byte{
+		crypto.Keccak256Hash(common.Address{1}.Bytes()): common.Address{1}.Bytes(),
+		crypto.Keccak256Hash(common.Address{2}.Bytes()): common.Address{2}.Bytes(),
+		crypto.Keccak256Hash(common.Hash{3}.Bytes()):    common.Hash{3}.Bytes(),
+	})
+	require.NoError(t, db.Close())
+
+	header, st, err := readDatadirState(datadir, 0)
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), header.Hash())
+	require.Zero(t, st.missing)
+	require.Equal(t, alloc.alloc, st.alloc)
+
+	_, _, err = readDatadirState(datadir, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no block 1")
+}
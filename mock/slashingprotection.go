@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"context"
+	"mergemock/slashing"
+	"mergemock/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlashingProtectionConfig configures the slashing protection of the blinded blocks the
+// consensus mock signs.
+type SlashingProtectionConfig struct {
+	DB      string  `ask:"--db" help:"EIP-3076 interchange file to import the slashing protection of the validators from, and to export it to after each signed block (empty to only protect in memory)"`
+	Violate float64 `ask:"--violate" help:"How often a proposer also signs a conflicting blinded block for its slot, against its slashing protection, and submits it to the builder"`
+}
+
+// protectBlock records the block of the proposer in the slashing protection database, and
+// refuses slashable blocks, unless the block violates the protection on purpose.
+func (c *ConsensusCmd) protectBlock(idx uint64, slot uint64, root types.Root, violate bool) error {
+	pk := c.validators[idx].pk
+	if err := c.slashingDB.SignBlock(pk, slot, root); err != nil {
+		if !violate {
+			return err
+		}
+		c.slotLog(slot).WithField("proposer", idx).WithError(err).Warn("Signing slashable block against slashing protection")
+		return nil
+	}
+	if c.SlashingProtection.DB != "" {
+		if err := c.slashingDB.Save(c.SlashingProtection.DB); err != nil {
+			c.log.WithError(err).Error("Failed to export slashing protection")
+		}
+	}
+	return nil
+}
+
+// equivocate signs a second blinded block for the slot of the block, with another state root,
+// and submits it to the builder, which should refuse it.
+func (c *ConsensusCmd) equivocate(ctx context.Context, log logrus.Ext1FieldLogger, bid *types.VersionedSignedBuilderBid, block *types.BlindedBeaconBlock) {
+	conflicting := *block
+	c.RNG.Read(conflicting.StateRoot[:])
+	signed, err := c.signBlindedBlock(bid, &conflicting, true)
+	if err != nil {
+		log.WithError(err).Error("Failed to sign conflicting blinded block")
+		return
+	}
+	if _, err := c.builder.SubmitBlindedBlock(ctx, signed); err != nil {
+		log.WithError(err).Info("Builder refused conflicting blinded block")
+		return
+	}
+	log.WithField("slot", block.Slot).Warn("Builder revealed the payload of a conflicting blinded block")
+}
+
+// loadSlashingProtection loads the slashing protection database of the network.
+func (c *ConsensusCmd) loadSlashingProtection() error {
+	if c.SlashingProtection.DB == "" {
+		c.slashingDB = slashing.New(c.genesisValidatorsRoot)
+		return nil
+	}
+	db, err := slashing.Load(c.SlashingProtection.DB, c.genesisValidatorsRoot)
+	if err != nil {
+		return err
+	}
+	c.slashingDB = db
+	return nil
+}
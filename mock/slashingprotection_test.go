@@ -0,0 +1,50 @@
+package mock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mergemock/slashing"
+	"mergemock/types"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlashingProtection(t *testing.T) {
+	c := newAttestingConsensus(t, 2, 1)
+	c.log = logrus.New()
+	c.SlashingProtection.DB = filepath.Join(t.TempDir(), "slashing-protection.json")
+	require.NoError(t, c.loadSlashingProtection())
+
+	bid := &types.VersionedSignedBuilderBid{Version: types.VersionBellatrix, Bellatrix: &types.SignedBuilderBid{
+		Message: &types.BuilderBid{Header: &types.ExecutionPayloadHeader{BlockNumber: 1}},
+	}}
+	newBlock := func(stateRoot types.Root) *types.BlindedBeaconBlock {
+		return &types.BlindedBeaconBlock{Slot: 3, ProposerIndex: 1, StateRoot: stateRoot, Body: &types.BlindedBeaconBlockBody{
+			Eth1Data:      &types.Eth1Data{},
+			SyncAggregate: &types.SyncAggregate{},
+		}}
+	}
+	_, err := c.signBlindedBlock(bid, newBlock(types.Root{0x01}), false)
+	require.NoError(t, err)
+	// the same block again, as a retry
+	_, err = c.signBlindedBlock(bid, newBlock(types.Root{0x01}), false)
+	require.NoError(t, err)
+
+	// another block for the slot is refused, unless violating on purpose
+	_, err = c.signBlindedBlock(bid, newBlock(types.Root{0x02}), false)
+	require.True(t, errors.Is(err, slashing.ErrSlashable))
+	signed, err := c.signBlindedBlock(bid, newBlock(types.Root{0x02}), true)
+	require.NoError(t, err)
+	require.Equal(t, types.Root{0x02}, signed.Bellatrix.Message.StateRoot)
+
+	// the protection is exported, and a restart refuses the block too
+	_, err = os.Stat(c.SlashingProtection.DB)
+	require.NoError(t, err)
+	require.NoError(t, c.loadSlashingProtection())
+	_, err = c.signBlindedBlock(bid, newBlock(types.Root{0x03}), false)
+	require.True(t, errors.Is(err, slashing.ErrSlashable))
+}
@@ -0,0 +1,86 @@
+package mock
+
+import (
+	"sort"
+
+	"mergemock/types"
+
+	"github.com/prysmaticlabs/prysm/runtime/version"
+)
+
+// maxSlashedAttesters bounds the validators slashed together by an attester slashing.
+const maxSlashedAttesters = 4
+
+// makeProposerSlashing returns a slashing of a random emulated validator, for signing two
+// different block headers for the slot before the given one.
+func (c *ConsensusCmd) makeProposerSlashing(slot uint64) (*types.ProposerSlashing, error) {
+	idx := c.RNG.Intn(len(c.validators))
+	domain := types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &c.genesisValidatorsRoot)
+	header := func() (*types.SignedBeaconBlockHeader, error) {
+		h := &types.BeaconBlockHeader{Slot: slot - 1, ProposerIndex: uint64(idx)}
+		c.RNG.Read(h.ParentRoot[:])
+		c.RNG.Read(h.StateRoot[:])
+		c.RNG.Read(h.BodyRoot[:])
+		sig, err := c.signAggregate([]int{idx}, h, domain)
+		if err != nil {
+			return nil, err
+		}
+		return &types.SignedBeaconBlockHeader{Header: h, Signature: sig}, nil
+	}
+	a, err := header()
+	if err != nil {
+		return nil, err
+	}
+	b, err := header()
+	if err != nil {
+		return nil, err
+	}
+	return &types.ProposerSlashing{A: a, B: b}, nil
+}
+
+// makeAttesterSlashing returns a slashing of a few random emulated validators, for a double vote:
+// attesting to two different blocks with the same target epoch, that of the slot before the
+// given one.
+func (c *ConsensusCmd) makeAttesterSlashing(slot uint64) (*types.AttesterSlashing, error) {
+	n := 1 + c.RNG.Intn(maxSlashedAttesters)
+	if n > len(c.validators) {
+		n = len(c.validators)
+	}
+	indices := c.RNG.Perm(len(c.validators))[:n]
+	sort.Ints(indices)
+	attesting := make([]uint64, n)
+	for i, idx := range indices {
+		attesting[i] = uint64(idx)
+	}
+
+	domain := types.ComputeDomain(types.DomainTypeBeaconAttester, version.Bellatrix, &c.genesisValidatorsRoot)
+	target := (slot - 1) / c.SlotsPerEpoch
+	source := target
+	if source > 0 {
+		source--
+	}
+	attestation := func() (*types.IndexedAttestation, error) {
+		data := &types.AttestationData{
+			Slot:   slot - 1,
+			Source: &types.Checkpoint{Epoch: source},
+			Target: &types.Checkpoint{Epoch: target},
+		}
+		c.RNG.Read(data.BlockRoot[:])
+		c.RNG.Read(data.Source.Root[:])
+		c.RNG.Read(data.Target.Root[:])
+		sig, err := c.signAggregate(indices, data, domain)
+		if err != nil {
+			return nil, err
+		}
+		return &types.IndexedAttestation{AttestingIndices: attesting, Data: data, Signature: sig}, nil
+	}
+	a, err := attestation()
+	if err != nil {
+		return nil, err
+	}
+	b, err := attestation()
+	if err != nil {
+		return nil, err
+	}
+	return &types.AttesterSlashing{A: a, B: b}, nil
+}
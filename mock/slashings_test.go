@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposerSlashing(t *testing.T) {
+	c := newAttestingConsensus(t, 3, 1)
+	slashing, err := c.makeProposerSlashing(5)
+	require.NoError(t, err)
+
+	a, b := slashing.A.Header, slashing.B.Header
+	require.Equal(t, uint64(4), a.Slot)
+	require.Equal(t, a.Slot, b.Slot)
+	require.Equal(t, a.ProposerIndex, b.ProposerIndex)
+	require.NotEqual(t, a, b)
+
+	domain := types.ComputeDomain(types.DomainTypeBeaconProposer, version.Bellatrix, &c.genesisValidatorsRoot)
+	pk := c.validators[a.ProposerIndex].pk
+	for _, signed := range []*types.SignedBeaconBlockHeader{slashing.A, slashing.B} {
+		ok, err := types.VerifySignature(signed.Header, domain, pk[:], signed.Signature[:])
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	_, err = (&types.BlindedBeaconBlockBody{
+		Eth1Data:               &types.Eth1Data{},
+		ProposerSlashings:      []*types.ProposerSlashing{slashing},
+		SyncAggregate:          &types.SyncAggregate{},
+		ExecutionPayloadHeader: &types.ExecutionPayloadHeader{},
+	}).HashTreeRoot()
+	require.NoError(t, err)
+}
+
+func TestAttesterSlashing(t *testing.T) {
+	c := newAttestingConsensus(t, 8, 1)
+	slashing, err := c.makeAttesterSlashing(9)
+	require.NoError(t, err)
+
+	a, b := slashing.A, slashing.B
+	require.Equal(t, a.AttestingIndices, b.AttestingIndices)
+	require.IsIncreasing(t, a.AttestingIndices)
+	require.Equal(t, uint64(2), a.Data.Target.Epoch)
+	require.Equal(t, a.Data.Target.Epoch, b.Data.Target.Epoch)
+	require.NotEqual(t, a.Data, b.Data)
+
+	domain := types.ComputeDomain(types.DomainTypeBeaconAttester, version.Bellatrix, &c.genesisValidatorsRoot)
+	var pubkeys []bls.PublicKey
+	for _, idx := range a.AttestingIndices {
+		pubkeys = append(pubkeys, c.validators[idx].sk.PublicKey())
+	}
+	for _, att := range []*types.IndexedAttestation{a, b} {
+		root, err := types.ComputeSigningRoot(att.Data, domain)
+		require.NoError(t, err)
+		sig, err := bls.SignatureFromBytes(att.Signature[:])
+		require.NoError(t, err)
+		require.True(t, sig.FastAggregateVerify(pubkeys, root))
+	}
+
+	// with a single validator, in the first epoch
+	c = newAttestingConsensus(t, 1, 1)
+	slashing, err = c.makeAttesterSlashing(1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0}, slashing.A.AttestingIndices)
+	require.Equal(t, uint64(0), slashing.A.Data.Source.Epoch)
+}
@@ -0,0 +1,126 @@
+package mock
+
+import (
+	"math"
+	"time"
+)
+
+// slotInterval is a point in a slot at which the consensus mock acts: the slot is divided in
+// intervals like in the spec, and each starts at a fixed offset from genesis.
+type slotInterval int
+
+const (
+	// slotStart is the start of the slot, when the proposer proposes.
+	slotStart slotInterval = iota
+	// attestationDeadline is a third into the slot, when the committee attests to the head.
+	attestationDeadline
+	// aggregationDeadline is two thirds into the slot, when the attestations are aggregated.
+	aggregationDeadline
+	intervalsPerSlot
+)
+
+// slotTick is the start of an interval of a slot, which is negative before genesis.
+type slotTick struct {
+	slot     int64
+	interval slotInterval
+}
+
+// virtualClock runs faster than the wall clock by its scale, from the time it started. Slots
+// follow the virtual clock, so they take less wall clock time, with the same timestamps.
+type virtualClock struct {
+	start time.Time
+	scale float64
+}
+
+func newVirtualClock(scale float64) *virtualClock {
+	return &virtualClock{start: time.Now(), scale: scale}
+}
+
+func (v *virtualClock) Now() time.Time {
+	return v.start.Add(time.Duration(float64(time.Since(v.start)) * v.scale))
+}
+
+// Until returns the wall clock time until the virtual time.
+func (v *virtualClock) Until(t time.Time) time.Duration {
+	return time.Duration(float64(t.Sub(v.Now())) / v.scale)
+}
+
+// tickAt returns the tick of the index-th interval since genesis.
+func tickAt(index int64) slotTick {
+	slot := index / int64(intervalsPerSlot)
+	if index%int64(intervalsPerSlot) < 0 {
+		slot--
+	}
+	return slotTick{slot, slotInterval(index - slot*int64(intervalsPerSlot))}
+}
+
+// runSlotClock emits the intervals of the slots after the given one: first those of the slots
+// that passed already, a slot per catch-up slot time, then at the start of every interval of
+// the virtual clock. In deterministic mode, it emits the intervals one after the other without
+// a clock, as the node processes them. It stops when done is closed.
+func (c *ConsensusCmd) runSlotClock(from uint64, ticks chan<- slotTick, done <-chan struct{}) {
+	genesis := time.Unix(int64(c.state.Config().GenesisTime), 0)
+	interval := c.SlotTime / time.Duration(intervalsPerSlot)
+	emit := func(index int64) bool {
+		select {
+		case ticks <- tickAt(index):
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	if c.Deterministic {
+		for index := int64(from+1) * int64(intervalsPerSlot); emit(index); index++ {
+		}
+		return
+	}
+
+	// index of the next interval to emit, if any was emitted yet
+	next := int64(math.MinInt64)
+	if now := c.state.SlotAt(c.clock.Now()); c.CatchUpSlotTime > 0 && now > int64(from) {
+		next = int64(from+1) * int64(intervalsPerSlot)
+		c.log.WithField("from", from+1).WithField("to", now).Info("Catching up on passed slots")
+		catchUp := time.NewTicker(c.CatchUpSlotTime)
+		defer catchUp.Stop()
+		// time keeps passing while catching up
+		for next/int64(intervalsPerSlot) <= c.state.SlotAt(c.clock.Now()) {
+			select {
+			case <-catchUp.C:
+			case <-done:
+				return
+			}
+			for i := 0; i < int(intervalsPerSlot); i++ {
+				if !emit(next) {
+					return
+				}
+				next++
+			}
+		}
+		c.log.Info("Caught up, following the wall clock")
+	}
+
+	for {
+		// the next interval to start, without repeating any: the division rounds towards zero,
+		// so up before genesis
+		since := c.clock.Now().Sub(genesis)
+		if since > 0 {
+			since += interval - 1
+		}
+		index := int64(since / interval)
+		if index < next {
+			index = next
+		}
+		timer := time.NewTimer(c.clock.Until(genesis.Add(time.Duration(index) * interval)))
+		select {
+		case <-timer.C:
+		case <-done:
+			timer.Stop()
+			return
+		}
+		if !emit(index) {
+			return
+		}
+		next = index + 1
+	}
+}
@@ -0,0 +1,104 @@
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"mergemock/beaconstate"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTickAt(t *testing.T) {
+	require.Equal(t, slotTick{0, slotStart}, tickAt(0))
+	require.Equal(t, slotTick{1, aggregationDeadline}, tickAt(5))
+	require.Equal(t, slotTick{-1, aggregationDeadline}, tickAt(-1))
+	require.Equal(t, slotTick{-1, slotStart}, tickAt(-3))
+	require.Equal(t, slotTick{-2, aggregationDeadline}, tickAt(-4))
+}
+
+func newClockConsensus(genesis time.Time, slotTime time.Duration, scale float64) *ConsensusCmd {
+	c := &ConsensusCmd{SlotTime: slotTime, CatchUpSlotTime: time.Millisecond}
+	c.log = logrus.New()
+	c.clock = newVirtualClock(scale)
+	c.state = beaconstate.New(beaconstate.Config{GenesisTime: uint64(genesis.Unix()), SlotTime: slotTime, SlotsPerEpoch: 4}, common.Hash{})
+	return c
+}
+
+func TestSlotClockCatchUp(t *testing.T) {
+	c := newClockConsensus(time.Now().Add(-10*time.Second), time.Second, 1)
+	ticks := make(chan slotTick)
+	done := make(chan struct{})
+	defer close(done)
+	go c.runSlotClock(3, ticks, done)
+
+	// the intervals of the passed slots after the start are emitted in order, much faster than
+	// the slot time
+	start := time.Now()
+	for slot := int64(4); slot <= 9; slot++ {
+		for interval := slotStart; interval < intervalsPerSlot; interval++ {
+			require.Equal(t, slotTick{slot, interval}, <-ticks)
+		}
+	}
+	require.Less(t, time.Since(start), time.Second)
+
+	// then the intervals follow the wall clock, without repeating any
+	last := slotTick{9, aggregationDeadline}
+	for i := 0; i < 4; i++ {
+		tick := <-ticks
+		require.True(t, tick.slot > last.slot || (tick.slot == last.slot && tick.interval > last.interval), tick)
+		last = tick
+	}
+}
+
+func TestSlotClockAligned(t *testing.T) {
+	genesis := time.Now().Truncate(time.Second).Add(2 * time.Second)
+	c := newClockConsensus(genesis, 300*time.Millisecond, 1)
+	ticks := make(chan slotTick)
+	done := make(chan struct{})
+	defer close(done)
+	go c.runSlotClock(0, ticks, done)
+
+	// every interval starts at its offset from genesis, also before genesis
+	for i := 0; i < 6; i++ {
+		tick := <-ticks
+		offset := time.Duration(tick.slot)*c.SlotTime + time.Duration(tick.interval)*c.SlotTime/time.Duration(intervalsPerSlot)
+		require.InDelta(t, 0, time.Since(genesis.Add(offset)), float64(50*time.Millisecond), tick)
+	}
+}
+
+func TestSlotClockTimeScale(t *testing.T) {
+	// 12s slots at 100x speed, starting right at genesis
+	c := newClockConsensus(time.Now(), 12*time.Second, 100)
+	c.state = beaconstate.New(beaconstate.Config{GenesisTime: uint64(c.clock.start.Unix()), SlotTime: 12 * time.Second, SlotsPerEpoch: 4}, common.Hash{})
+	ticks := make(chan slotTick)
+	done := make(chan struct{})
+	defer close(done)
+	go c.runSlotClock(0, ticks, done)
+
+	start := time.Now()
+	var tick slotTick
+	for tick.slot < 5 {
+		tick = <-ticks
+	}
+	// 5 slots of 120ms
+	require.InDelta(t, 600*time.Millisecond, time.Since(start), float64(150*time.Millisecond))
+}
+
+func TestSlotClockDeterministic(t *testing.T) {
+	// genesis is an hour away, but the slots do not wait for the clock
+	c := newClockConsensus(time.Now().Add(time.Hour), 12*time.Second, 1)
+	c.Deterministic = true
+	ticks := make(chan slotTick)
+	done := make(chan struct{})
+	defer close(done)
+	go c.runSlotClock(0, ticks, done)
+
+	for slot := int64(1); slot <= 100; slot++ {
+		for interval := slotStart; interval < intervalsPerSlot; interval++ {
+			require.Equal(t, slotTick{slot, interval}, <-ticks)
+		}
+	}
+}
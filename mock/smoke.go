@@ -0,0 +1,139 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+// callArgs are the arguments of eth_call and eth_estimateGas for a transaction.
+type callArgs struct {
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to,omitempty"`
+	Gas   *hexutil.Uint64 `json:"gas,omitempty"`
+	Value *hexutil.Big    `json:"value"`
+	Data  hexutil.Bytes   `json:"data"`
+}
+
+// smokeReceipt is the part of a receipt of the engine the smoke checks compare.
+type smokeReceipt struct {
+	BlockHash common.Hash       `json:"blockHash"`
+	Status    hexutil.Uint64    `json:"status"`
+	GasUsed   hexutil.Uint64    `json:"gasUsed"`
+	Logs      []json.RawMessage `json:"logs"`
+}
+
+// smokeCheck checks the transactions the mock included in the block with the JSON-RPC methods of
+// the engine, against the mock chain. The receipts must match, the transactions called with
+// eth_call on the state before the block must return the same, the senders and recipients must
+// have the same nonces and balances after the block, and eth_estimateGas must estimate the first
+// transaction between the gas it used and its gas limit.
+func (c *ConsensusCmd) smokeCheck(ctx context.Context, block *ethTypes.Block) error {
+	parent := c.mockChain.chain.GetHeaderByHash(block.ParentHash())
+	if parent == nil {
+		return fmt.Errorf("unknown parent %s", block.ParentHash())
+	}
+	statedb, err := c.mockChain.chain.StateAt(block.Root())
+	if err != nil {
+		return err
+	}
+	receipts := c.mockChain.chain.GetReceiptsByHash(block.Hash())
+	signer := ethTypes.MakeSigner(c.mockChain.chain.Config(), block.Number())
+	number, parentNumber := hexutil.EncodeBig(block.Number()), hexutil.EncodeBig(parent.Number)
+	for i, tx := range block.Transactions() {
+		from, err := ethTypes.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		var receipt *smokeReceipt
+		if err := c.engine.CallContext(ctx, &receipt, "eth_getTransactionReceipt", tx.Hash()); err != nil {
+			return fmt.Errorf("receipt of tx %s: %w", tx.Hash(), err)
+		}
+		if receipt == nil {
+			return fmt.Errorf("engine has no receipt for tx %s", tx.Hash())
+		}
+		want := receipts[i]
+		if receipt.BlockHash != block.Hash() || uint64(receipt.Status) != want.Status || uint64(receipt.GasUsed) != want.GasUsed || len(receipt.Logs) != len(want.Logs) {
+			return fmt.Errorf("receipt of tx %s: block %s, status %d, gas used %d, %d logs, expected block %s, status %d, gas used %d, %d logs",
+				tx.Hash(), receipt.BlockHash, receipt.Status, receipt.GasUsed, len(receipt.Logs), block.Hash(), want.Status, want.GasUsed, len(want.Logs))
+		}
+
+		args := callArgs{From: from, To: tx.To(), Value: (*hexutil.Big)(tx.Value()), Data: tx.Data()}
+		gas := hexutil.Uint64(tx.Gas())
+		args.Gas = &gas
+		var result hexutil.Bytes
+		callErr := c.engine.CallContext(ctx, &result, "eth_call", args, parentNumber)
+		local, err := c.mockChain.Call(parent, from, tx.To(), tx.Value(), tx.Gas(), tx.Data())
+		if err != nil {
+			return fmt.Errorf("call of tx %s in mock chain: %w", tx.Hash(), err)
+		}
+		switch {
+		case local.Failed() && callErr == nil:
+			return fmt.Errorf("eth_call of tx %s succeeded, expected %v", tx.Hash(), local.Err)
+		case !local.Failed() && callErr != nil:
+			return fmt.Errorf("eth_call of tx %s: %w", tx.Hash(), callErr)
+		case !local.Failed() && !bytes.Equal(result, local.ReturnData):
+			return fmt.Errorf("eth_call of tx %s returned %s, expected %s", tx.Hash(), result, hexutil.Bytes(local.ReturnData))
+		}
+
+		if i == 0 && want.Status == ethTypes.ReceiptStatusSuccessful {
+			args.Gas = nil
+			var estimate hexutil.Uint64
+			if err := c.engine.CallContext(ctx, &estimate, "eth_estimateGas", args, parentNumber); err != nil {
+				return fmt.Errorf("eth_estimateGas of tx %s: %w", tx.Hash(), err)
+			}
+			if uint64(estimate) < want.GasUsed || uint64(estimate) > tx.Gas() {
+				return fmt.Errorf("eth_estimateGas of tx %s is %d, expected between gas used %d and gas limit %d", tx.Hash(), estimate, want.GasUsed, tx.Gas())
+			}
+		}
+
+		var nonce hexutil.Uint64
+		if err := c.engine.CallContext(ctx, &nonce, "eth_getTransactionCount", from, number); err != nil {
+			return fmt.Errorf("nonce of %s: %w", from, err)
+		}
+		if uint64(nonce) != statedb.GetNonce(from) {
+			return fmt.Errorf("nonce of %s is %d, expected %d", from, nonce, statedb.GetNonce(from))
+		}
+		accounts := []common.Address{from}
+		if tx.To() != nil {
+			accounts = append(accounts, *tx.To())
+		}
+		for _, addr := range accounts {
+			var balance hexutil.Big
+			if err := c.engine.CallContext(ctx, &balance, "eth_getBalance", addr, number); err != nil {
+				return fmt.Errorf("balance of %s: %w", addr, err)
+			}
+			if balance.ToInt().Cmp(statedb.GetBalance(addr)) != 0 {
+				return fmt.Errorf("balance of %s is %s, expected %s", addr, balance.ToInt(), statedb.GetBalance(addr))
+			}
+		}
+	}
+	return nil
+}
+
+// reportSmokeCheck runs the smoke checks of the block, if it has transactions.
+func (c *ConsensusCmd) reportSmokeCheck(log logrus.Ext1FieldLogger, slot uint64, block *ethTypes.Block) {
+	if len(block.Transactions()) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	err := c.smokeCheck(ctx, block)
+	if err != nil && c.engineOffline(err) {
+		return
+	}
+	c.report.record(scenarioSmokeCheck, slot, err)
+	if err != nil {
+		log.WithError(err).Error("Smoke check of block failed")
+		c.maybeExit()
+		return
+	}
+	log.WithField("txs", len(block.Transactions())).Debug("Smoke check of block passed")
+}
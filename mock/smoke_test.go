@@ -0,0 +1,96 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mergemock/beaconstate"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// smokeEngine is an engine that answers the smoke check methods with fixed results, and the
+// balances of accounts.
+type smokeEngine struct {
+	results  map[string]interface{}
+	balances map[common.Address]*hexutil.Big
+}
+
+func (e *smokeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := e.results[req.Method]
+	if req.Method == "eth_getBalance" {
+		var addr common.Address
+		json.Unmarshal(req.Params[0], &addr)
+		result = e.balances[addr]
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestSmokeCheck(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	genesis := newMergedGenesis(account.addr)
+	mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	c := &ConsensusCmd{SlotsPerEpoch: 4, SlotTime: time.Second}
+	c.log = logrus.New()
+	c.mockChain = mc
+	c.state = beaconstate.New(beaconstate.Config{GenesisTime: genesis.Timestamp, SlotTime: time.Second, SlotsPerEpoch: 4}, mc.CurrentHeader().Hash())
+
+	parent := mc.CurrentHeader()
+	block, err := mc.AddNewBlock(parent.Hash(), common.Address{1}, c.state.SlotTimestamp(1), parent.GasLimit, TransactionsCreator{[]TestAccount{account}, dummyTxCreator}, common.Hash{}, nil, nil, nil, nil, true)
+	require.NoError(t, err)
+	require.Len(t, block.Transactions(), 1)
+	statedb, err := mc.chain.StateAt(block.Root())
+	require.NoError(t, err)
+
+	engine := &smokeEngine{
+		results: map[string]interface{}{
+			"eth_getTransactionReceipt": map[string]interface{}{"blockHash": block.Hash(), "status": "0x1", "gasUsed": "0x5208", "logs": []interface{}{}},
+			"eth_call":                  "0x",
+			"eth_estimateGas":           "0x5208",
+			"eth_getTransactionCount":   "0x1",
+		},
+		balances: map[common.Address]*hexutil.Big{account.addr: (*hexutil.Big)(statedb.GetBalance(account.addr))},
+	}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c.engine = dialEngine(t, srv.URL)
+	require.NoError(t, c.smokeCheck(context.Background(), block))
+
+	engine.results["eth_estimateGas"] = "0x5000"
+	err = c.smokeCheck(context.Background(), block)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expected between gas used 21000 and gas limit 30000")
+
+	engine.results["eth_estimateGas"] = "0x5208"
+	engine.results["eth_call"] = "0x01"
+	err = c.smokeCheck(context.Background(), block)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "returned 0x01, expected 0x")
+
+	engine.results["eth_call"] = "0x"
+	engine.balances[account.addr] = (*hexutil.Big)(common.Big1)
+	err = c.smokeCheck(context.Background(), block)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "balance of "+account.addr.String()+" is 1")
+}
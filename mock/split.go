@@ -0,0 +1,81 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// proposerNewPayload sends the payload to the proposer engine, which follows the chain like the
+// engine, without building payloads.
+func (c *ConsensusCmd) proposerNewPayload(ctx context.Context, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) (*types.PayloadStatusV1, error) {
+	hashes, err := types.BlobVersionedHashes(payload.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	return c.sendNewPayload(ctx, c.proposerEngine, payload, hashes, beaconRoot, requests)
+}
+
+// proposerFollow sends a block of the mock chain to the proposer engine, if there is one.
+func (c *ConsensusCmd) proposerFollow(ctx context.Context, log logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) {
+	if c.proposerEngine == nil {
+		return
+	}
+	res, err := c.proposerNewPayload(ctx, payload, beaconRoot, requests)
+	if err != nil {
+		log.WithError(err).Warn("Proposer engine failed to execute payload")
+	} else if res.Status != types.ExecutionValid {
+		log.WithField("status", res.Status).Warn("Proposer engine did not validate payload")
+	}
+}
+
+// proposerForkchoiceUpdated updates the forkchoice of the proposer engine, if there is one.
+func (c *ConsensusCmd) proposerForkchoiceUpdated(log logrus.Ext1FieldLogger, latest, safe, final common.Hash) {
+	if c.proposerEngine == nil {
+		return
+	}
+	result, err := c.forkchoiceUpdated(c.proposerEngine, latest, safe, final, nil)
+	if err != nil {
+		log.WithError(err).Warn("Proposer engine failed to update forkchoice")
+	} else if result.PayloadStatus.Status != types.ExecutionValid {
+		log.WithField("status", result.PayloadStatus.Status).Warn("Proposer engine did not validate forkchoice update")
+	}
+}
+
+// proposerValidate checks the proposer engine agrees that the payload the engine built for the
+// slot is valid, if there is a proposer engine. An engine that builds payloads another client
+// considers invalid splits the chain, which a run against a single engine cannot see.
+func (c *ConsensusCmd) proposerValidate(ctx context.Context, log logrus.Ext1FieldLogger, slot uint64, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) {
+	if c.proposerEngine == nil {
+		return
+	}
+	res, err := c.proposerNewPayload(ctx, payload, beaconRoot, requests)
+	if err == nil {
+		switch res.Status {
+		case types.ExecutionValid:
+			log.WithField("blockhash", payload.BlockHash).Debug("Proposer engine validated payload")
+			c.report.record(scenarioProposerValidation, slot, nil)
+			return
+		case types.ExecutionInvalid:
+			log.WithField("blockhash", payload.BlockHash).WithField("validationError", res.ValidationError).Error("Proposer engine considers payload built by engine invalid")
+			// the engine that built the payload considers it valid, up to the block itself
+			hash := payload.BlockHash
+			mismatch := newMismatch(fmt.Sprintf("proposer engine considers payload %s built by engine invalid: %s", payload.BlockHash, res.ValidationError),
+				&types.PayloadStatusV1{Status: types.ExecutionValid, LatestValidHash: &hash}, res)
+			c.diffs.report(log, scenarioProposerValidation, slot, mismatch.diffs)
+			err = mismatch
+		default:
+			// e.g. syncing, after missing a block: no verdict on the payload
+			log.WithField("status", res.Status).Warn("Proposer engine did not validate payload")
+			return
+		}
+	} else {
+		log.WithError(err).Error("Proposer engine failed to execute payload")
+	}
+	c.report.record(scenarioProposerValidation, slot, err)
+	c.maybeExit()
+}
@@ -0,0 +1,84 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// statusEngine is an engine that answers every new payload and forkchoice update with its status.
+type statusEngine struct {
+	status types.ExecutePayloadStatus
+}
+
+func (e *statusEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := types.PayloadStatusV1{Status: e.status, ValidationError: "bad state root"}
+	var result interface{} = status
+	if strings.HasPrefix(req.Method, "engine_forkchoiceUpdated") {
+		result = types.ForkchoiceUpdatedResult{PayloadStatus: status}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestProposerValidate(t *testing.T) {
+	engine := new(fakeEngine)
+	srv, addr := serveFakeEngine(t, "127.0.0.1:0", engine)
+	defer srv.Close()
+	proposer := &statusEngine{status: types.ExecutionValid}
+	proposerSrv := httptest.NewServer(proposer)
+	defer proposerSrv.Close()
+
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	var err error
+	c.engine = dialEngine(t, "http://"+addr)
+	c.proposerEngine = dialEngine(t, proposerSrv.URL)
+
+	parent := c.mockChain.CurrentHeader()
+	beaconRoot := common.Hash{1}
+	block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, c.state.SlotTimestamp(1), parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &beaconRoot, true)
+	require.NoError(t, err)
+	payload, err := api.BlockToPayloadV3(block, types.Withdrawals{})
+	require.NoError(t, err)
+
+	c.proposerValidate(c.ctx, logrus.New(), 1, payload, &beaconRoot, nil)
+	require.Empty(t, c.report.cases[scenarioProposerValidation].failures)
+
+	// no verdict while the proposer engine syncs
+	proposer.status = types.ExecutionSyncing
+	c.proposerValidate(c.ctx, logrus.New(), 2, payload, &beaconRoot, nil)
+	require.Equal(t, 1, c.report.cases[scenarioProposerValidation].runs)
+
+	proposer.status = types.ExecutionInvalid
+	c.proposerValidate(c.ctx, logrus.New(), 3, payload, &beaconRoot, nil)
+	sc := c.report.cases[scenarioProposerValidation]
+	require.Equal(t, 2, sc.runs)
+	require.Len(t, sc.failures, 1)
+	require.Contains(t, sc.failures[0], "slot 3: proposer engine considers payload")
+	require.Contains(t, sc.failures[0], "bad state root")
+
+	// without a proposer engine, there is nothing to validate
+	c.proposerEngine = nil
+	c.proposerValidate(c.ctx, logrus.New(), 4, payload, &beaconRoot, nil)
+	require.Equal(t, 2, c.report.cases[scenarioProposerValidation].runs)
+	require.Empty(t, engine.methods)
+}
@@ -0,0 +1,173 @@
+package mock
+
+import (
+	"io/fs"
+	"math/big"
+	"mergemock/contracts"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// stateGrowthCallGas is the gas of a churn call besides its storage writes.
+	stateGrowthCallGas = 60_000
+	// stateGrowthSlotGas is the gas of writing a fresh storage slot in the churn loop, a cold
+	// zero to non-zero SSTORE and the loop around it.
+	stateGrowthSlotGas = 22_300
+)
+
+// StateGrowth configures the state-growth tx profile, which grows the state of the engine by fresh
+// storage slots in every block, to put its snapshots and pruning under pressure.
+type StateGrowth struct {
+	Slots         uint64 `ask:"--slots" help:"Fresh storage slots the state-growth tx profile writes per block, fewer if they do not fit in the gas limit"`
+	EngineDatadir string `ask:"--engine-datadir" help:"Datadir of the engine, to report its size on disk as the state grows (empty to not report it)"`
+}
+
+func (g *StateGrowth) Default() {
+	g.Slots = 1000
+}
+
+// churnerDeployment is the storage churner of the state-growth tx profile, at the address of its
+// last deployment, and the growth of the state it reported last.
+type churnerDeployment struct {
+	mu      sync.Mutex
+	address common.Address
+
+	progress stateGrowthProgress
+}
+
+// stateGrowthProgress is the state growth at the last report: the storage slots the churner wrote
+// in the head state, and the sizes of the datadirs on disk, -1 if not known.
+type stateGrowthProgress struct {
+	slots         uint64
+	datadir       int64
+	engineDatadir int64
+}
+
+// txCreator deploys the churner from the first test account, and then calls it once per block to
+// write as many fresh storage slots as configured, or as fit in the gas limit of the block. The
+// churner is deployed again if it is missing from the state of the parent, after a reorg.
+func (d *churnerDeployment) txCreator(slots uint64) func(*params.ChainConfig, core.ChainContext, *state.StateDB, *ethTypes.Header, vm.Config, []TestAccount) []*ethTypes.Transaction {
+	return func(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *ethTypes.Header, cfg vm.Config, accounts []TestAccount) []*ethTypes.Transaction {
+		if len(accounts) == 0 {
+			return nil
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		sender := accounts[0]
+		if d.address == (common.Address{}) || statedb.GetCodeSize(d.address) == 0 {
+			d.address = crypto.CreateAddress(sender.addr, statedb.GetNonce(sender.addr))
+			return contractTx(config, statedb, sender, nil, contracts.ChurnerCode, contractDeployGas)
+		}
+		if header.GasLimit <= stateGrowthCallGas+stateGrowthSlotGas {
+			return nil
+		}
+		if fit := (header.GasLimit - stateGrowthCallGas) / stateGrowthSlotGas; slots > fit {
+			slots = fit
+		}
+		input := contractInput(contracts.ChurnSelector, common.BigToHash(new(big.Int).SetUint64(slots)))
+		return contractTx(config, statedb, sender, &d.address, input, stateGrowthCallGas+slots*stateGrowthSlotGas)
+	}
+}
+
+// written returns the storage slots the churner wrote in the state.
+func (d *churnerDeployment) written(statedb *state.StateDB) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.address == (common.Address{}) {
+		return 0
+	}
+	return statedb.GetState(d.address, common.Hash{}).Big().Uint64()
+}
+
+// reportStateGrowth logs the storage slots the state-growth tx profile wrote in the head state, and
+// the sizes of the datadirs of the mock chain and the engine on disk, and how they grew since the
+// last report.
+func (c *ConsensusCmd) reportStateGrowth(log logrus.Ext1FieldLogger) {
+	if c.TxProfile != "state-growth" {
+		return
+	}
+	statedb, err := c.mockChain.chain.StateAt(c.mockChain.CurrentHeader().Root)
+	if err != nil {
+		log.WithError(err).Warn("Cannot read state growth of the head")
+		return
+	}
+	progress := stateGrowthProgress{
+		slots:         c.churner.written(statedb),
+		datadir:       dirSize(c.DataDir),
+		engineDatadir: dirSize(c.StateGrowth.EngineDatadir),
+	}
+	c.churner.mu.Lock()
+	last := c.churner.progress
+	c.churner.progress = progress
+	c.churner.mu.Unlock()
+	fields := logrus.Fields{
+		"slots":    progress.slots,
+		"newSlots": int64(progress.slots) - int64(last.slots),
+		"datadir":  progress.datadir,
+	}
+	if progress.engineDatadir >= 0 {
+		fields["engineDatadir"] = progress.engineDatadir
+		fields["engineDatadirGrowth"] = progress.engineDatadir - last.engineDatadir
+	}
+	log.WithFields(fields).Info("State growth")
+}
+
+// stateGrowthMetrics returns the metrics of the state growth at the last report.
+func (c *ConsensusCmd) stateGrowthMetrics() []metricFamily {
+	c.churner.mu.Lock()
+	progress := c.churner.progress
+	c.churner.mu.Unlock()
+	families := []metricFamily{{
+		name:    "mergemock_state_growth_slots",
+		help:    "Storage slots the state-growth tx profile wrote in the head state.",
+		samples: []metricSample{{value: float64(progress.slots)}},
+	}}
+	sizes := metricFamily{name: "mergemock_datadir_bytes", help: "Size of the datadir on disk, by client."}
+	if progress.datadir >= 0 {
+		sizes.samples = append(sizes.samples, metricSample{map[string]string{"client": "mock"}, float64(progress.datadir)})
+	}
+	if progress.engineDatadir >= 0 {
+		sizes.samples = append(sizes.samples, metricSample{map[string]string{"client": "engine"}, float64(progress.engineDatadir)})
+	}
+	if len(sizes.samples) > 0 {
+		families = append(families, sizes)
+	}
+	return families
+}
+
+// dirSize returns the size of the files in the directory, -1 if it cannot be read.
+func dirSize(dir string) int64 {
+	if dir == "" {
+		return -1
+	}
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				// removed since the walk listed it, like a compacted table
+				return nil
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return -1
+	}
+	return size
+}
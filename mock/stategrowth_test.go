@@ -0,0 +1,54 @@
+package mock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateGrowth(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	engine := newTestEngine(t, newFundedGenesis(t, sender))
+	chain := engine.mockChain()
+
+	var churner churnerDeployment
+	creator := TransactionsCreator{[]TestAccount{{key, sender}}, churner.txCreator(500)}
+	for i := 0; i < 3; i++ {
+		parent := chain.CurrentHeader()
+		block, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		receipts := chain.chain.GetReceiptsByHash(block.Hash())
+		require.Len(t, receipts, 1)
+		require.Equal(t, ethTypes.ReceiptStatusSuccessful, receipts[0].Status)
+	}
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+
+	// a deployment, and two calls of 500 slots
+	require.Equal(t, uint64(1000), churner.written(statedb))
+	require.NotEqual(t, common.Hash{}, statedb.GetState(churner.address, common.BigToHash(common.Big1)))
+
+	// a call writes the slots that fit in the gas limit of the block
+	header := ethTypes.CopyHeader(chain.CurrentHeader())
+	header.GasLimit = 5_000_000
+	txs := churner.txCreator(500)(chain.chain.Config(), chain.chain, statedb, header, vm.Config{}, creator.accounts)
+	require.Len(t, txs, 1)
+	fit := (header.GasLimit - stateGrowthCallGas) / stateGrowthSlotGas
+	require.Less(t, fit, uint64(500))
+	require.Equal(t, stateGrowthCallGas+fit*stateGrowthSlotGas, txs[0].Gas())
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "chaindata"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), make([]byte, 10), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "chaindata", "b"), make([]byte, 32), 0644))
+	require.Equal(t, int64(42), dirSize(dir))
+	require.Equal(t, int64(-1), dirSize(""))
+	require.Equal(t, int64(-1), dirSize(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// WitnessConfig configures the execution witnesses of the payloads of a consensus run.
+type WitnessConfig struct {
+	Export    bool `ask:"--export" help:"Add the execution witness of every payload to the chain export"`
+	Stateless bool `ask:"--stateless" help:"Send every payload with its execution witness to engine_executeStatelessPayload, which must compute the same state and receipts roots"`
+}
+
+// enabled returns whether the mock chain must keep the witnesses of its blocks.
+func (w *WitnessConfig) enabled() bool {
+	return w.Export || w.Stateless
+}
+
+// checkStateless has the engine execute the payload with its witness only, and checks it computes
+// the roots of the payload.
+func (c *ConsensusCmd) checkStateless(ctx context.Context, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) error {
+	witness, ok := c.mockChain.Witness(payload.BlockHash)
+	if !ok {
+		return fmt.Errorf("no execution witness of block %s", payload.BlockHash)
+	}
+	opaque, err := witness.Encode()
+	if err != nil {
+		return err
+	}
+	hashes, err := types.BlobVersionedHashes(payload.Transactions)
+	if err != nil {
+		return err
+	}
+	var root common.Hash
+	if beaconRoot != nil {
+		root = *beaconRoot
+	}
+	res, err := c.engine.ExecuteStatelessPayload(c.correlate(ctx, payload.Timestamp), c.engineFork(payload.Timestamp), payload, hashes, root, requests, opaque)
+	if err != nil {
+		return err
+	}
+	if res.Status != types.ExecutionValid {
+		return fmt.Errorf("stateless payload status %s: %s", res.Status, res.ValidationError)
+	}
+	if res.StateRoot != payload.StateRoot {
+		return fmt.Errorf("stateless state root %s, expected %s", res.StateRoot, payload.StateRoot)
+	}
+	if res.ReceiptsRoot != payload.ReceiptsRoot {
+		return fmt.Errorf("stateless receipts root %s, expected %s", res.ReceiptsRoot, payload.ReceiptsRoot)
+	}
+	return nil
+}
+
+// reportStateless runs the stateless check of the payload, if enabled.
+func (c *ConsensusCmd) reportStateless(log logrus.Ext1FieldLogger, payload *types.ExecutionPayloadV3, beaconRoot *common.Hash, requests types.ExecutionRequests) {
+	if !c.Witness.Stateless {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	err := c.checkStateless(ctx, payload, beaconRoot, requests)
+	if err != nil && c.engineOffline(err) {
+		return
+	}
+	c.report.record(scenarioStatelessPayload, c.timestampSlot(payload.Timestamp), err)
+	if err != nil {
+		log.WithError(err).Error("Engine failed to execute stateless payload")
+		c.maybeExit()
+		return
+	}
+	log.Debug("Engine executed stateless payload")
+}
@@ -0,0 +1,74 @@
+package mock
+
+import (
+	"math/rand"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+)
+
+const (
+	syncCommitteeSize            = 512
+	epochsPerSyncCommitteePeriod = 256
+)
+
+// infiniteSignature is the signature of an empty sync aggregate, the G2 point at infinity.
+var infiniteSignature = types.Signature{0xc0}
+
+// syncCommittee returns the indices of the validators in the sync committee of the slot. The
+// members are sampled from the emulated validators, with repetition like with few validators on
+// a real network, and change every sync committee period.
+func (c *ConsensusCmd) syncCommittee(slot uint64) []int {
+	period := slot / c.SlotsPerEpoch / epochsPerSyncCommitteePeriod
+	rng := rand.New(rand.NewSource(int64(period)))
+	members := make([]int, syncCommitteeSize)
+	for i := range members {
+		members[i] = rng.Intn(len(c.validators))
+	}
+	return members
+}
+
+// makeSyncAggregate returns the sync aggregate of a block at the slot, with the signatures of the
+// members of the sync committee that take part, with a chance of the sync participation, on the
+// root of the parent block. The mock chain has no beacon blocks, so the parent execution block
+// hash stands in for it.
+func (c *ConsensusCmd) makeSyncAggregate(slot uint64, parent common.Hash) (*types.SyncAggregate, error) {
+	return c.signSyncAggregate(slot, types.Root(parent))
+}
+
+// signSyncAggregate returns the sync aggregate of the members of the sync committee of the slot
+// that take part, signing the block root.
+func (c *ConsensusCmd) signSyncAggregate(slot uint64, blockRoot types.Root) (*types.SyncAggregate, error) {
+	aggregate := &types.SyncAggregate{CommitteeSignature: infiniteSignature}
+	if len(c.validators) == 0 {
+		return aggregate, nil
+	}
+	domain := types.ComputeDomain(types.DomainTypeSyncCommittee, version.Bellatrix, &c.genesisValidatorsRoot)
+	root, err := types.ComputeSigningRoot(&types.SigningBlockRoot{Root: blockRoot}, domain)
+	if err != nil {
+		return nil, err
+	}
+	// members that are the same validator sign once
+	signatures := make(map[int]bls.Signature)
+	var sigs []bls.Signature
+	for i, idx := range c.syncCommittee(slot) {
+		// validators of the remote signer only sign proposals
+		if c.RNG.Float64() >= c.SyncParticipation || c.validators[idx].sk == nil {
+			continue
+		}
+		aggregate.CommitteeBits[i/8] |= 1 << (i % 8)
+		sig, ok := signatures[idx]
+		if !ok {
+			sig = c.validators[idx].sk.Sign(root[:])
+			signatures[idx] = sig
+		}
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) > 0 {
+		aggregate.CommitteeSignature.FromSlice(bls.AggregateSignatures(sigs).Marshal())
+	}
+	return aggregate, nil
+}
@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prysmaticlabs/prysm/crypto/bls"
+	"github.com/prysmaticlabs/prysm/runtime/version"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncAggregate(t *testing.T) {
+	c := newAttestingConsensus(t, 3, 0.5)
+	c.SyncParticipation = 0.5
+	parent := common.Hash{0x01}
+	aggregate, err := c.makeSyncAggregate(1, parent)
+	require.NoError(t, err)
+
+	members := c.syncCommittee(1)
+	require.Equal(t, members, c.syncCommittee(c.SlotsPerEpoch*epochsPerSyncCommitteePeriod-1))
+	require.NotEqual(t, members, c.syncCommittee(c.SlotsPerEpoch*epochsPerSyncCommitteePeriod))
+
+	var pubkeys []bls.PublicKey
+	for i, idx := range members {
+		if aggregate.CommitteeBits[i/8]&(1<<(i%8)) != 0 {
+			pubkeys = append(pubkeys, c.validators[idx].sk.PublicKey())
+		}
+	}
+	require.Greater(t, len(pubkeys), syncCommitteeSize/4)
+	require.Less(t, len(pubkeys), syncCommitteeSize*3/4)
+
+	domain := types.ComputeDomain(types.DomainTypeSyncCommittee, version.Bellatrix, &c.genesisValidatorsRoot)
+	root, err := types.ComputeSigningRoot(&types.SigningBlockRoot{Root: types.Root(parent)}, domain)
+	require.NoError(t, err)
+	sig, err := bls.SignatureFromBytes(aggregate.CommitteeSignature[:])
+	require.NoError(t, err)
+	require.True(t, sig.FastAggregateVerify(pubkeys, root))
+
+	// without participants, the signature is the point at infinity
+	c.SyncParticipation = 0
+	aggregate, err = c.makeSyncAggregate(1, parent)
+	require.NoError(t, err)
+	require.Equal(t, types.CommitteeBits{}, aggregate.CommitteeBits)
+	require.Equal(t, infiniteSignature, aggregate.CommitteeSignature)
+}
@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// checkInvalidTerminalBlock sends the engine a payload that extends the parent of the terminal PoW
+// block, which does not reach the terminal total difficulty. The engine must consider it invalid,
+// with a zero latest valid hash, or the legacy INVALID_TERMINAL_BLOCK status.
+func (c *ConsensusCmd) checkInvalidTerminalBlock(transitionBlock uint64) error {
+	terminal := c.mockChain.chain.GetHeaderByNumber(transitionBlock)
+	if terminal == nil || !c.mockChain.IsTerminalBlock(terminal) {
+		return fmt.Errorf("block %d is not a terminal PoW block", transitionBlock)
+	}
+	if terminal.Number.Sign() == 0 {
+		return fmt.Errorf("terminal PoW block is the genesis block, no block before it to extend")
+	}
+	parent := c.mockChain.chain.GetHeaderByHash(terminal.ParentHash)
+	if parent == nil {
+		return fmt.Errorf("unknown parent %s of terminal PoW block", terminal.ParentHash)
+	}
+	log := c.log.WithField("terminal", terminal.Hash()).WithField("parent", parent.Hash())
+
+	timestamp := c.SlotTimestamp(1)
+	withdrawals := c.makeWithdrawals(timestamp)
+	beaconRoot := c.makeBeaconRoot(timestamp)
+	block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, timestamp, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator},
+		common.Hash(c.state.RandaoMix()), []byte("invalid terminal block"), nil, withdrawals, beaconRoot, false)
+	if err != nil {
+		return fmt.Errorf("failed to build payload on parent of terminal PoW block: %w", err)
+	}
+	payload, err := api.BlockToPayloadV3(block, withdrawals)
+	if err != nil {
+		return err
+	}
+	log = log.WithField("blockhash", block.Hash())
+	log.Info("Sending payload built on a PoW block before the terminal block")
+
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+	res, err := c.newPayload(withFault(ctx), log, payload, beaconRoot, nil)
+	if err != nil {
+		return fmt.Errorf("engine errored on payload with invalid terminal block: %w", err)
+	}
+	switch res.Status {
+	case types.ExecutionInvalidTerminalBlock:
+		log.Info("Engine rejected payload with invalid terminal block, with legacy status")
+	case types.ExecutionInvalid:
+		if res.LatestValidHash != nil && *res.LatestValidHash != (common.Hash{}) {
+			return fmt.Errorf("engine rejected payload with invalid terminal block with latest valid hash %s, expected zero", res.LatestValidHash)
+		}
+		log.WithField("validationError", res.ValidationError).Info("Engine rejected payload with invalid terminal block")
+	default:
+		return fmt.Errorf("engine returned %s for payload with invalid terminal block, expected INVALID", res.Status)
+	}
+	return nil
+}
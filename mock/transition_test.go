@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mergemock/beaconstate"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// newPoWChain returns a PoW chain of three blocks over the genesis, the last of which reaches the
+// terminal total difficulty.
+func newPoWChain(t *testing.T) *MockChain {
+	config := *params.AllEthashProtocolChanges
+	config.TerminalTotalDifficulty = big.NewInt(3 * 131072)
+	genesis := &core.Genesis{Config: &config, Difficulty: big.NewInt(131072), GasLimit: 30_000_000}
+	mc, err := NewMockChain(logrus.New(), ethash.NewFaker(), genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := mc.MineBlock(mc.CurrentHeader())
+		require.NoError(t, err)
+	}
+	return mc
+}
+
+func TestTerminalBlock(t *testing.T) {
+	mc := newPoWChain(t)
+	var terminal []uint64
+	for n := uint64(0); n <= 3; n++ {
+		header := mc.chain.GetHeaderByNumber(n)
+		if mc.IsTerminalBlock(header) {
+			terminal = append(terminal, n)
+		}
+	}
+	require.Equal(t, []uint64{2}, terminal)
+	require.NoError(t, mc.CheckTransitionParent(mc.chain.GetHeaderByNumber(2)))
+	err := mc.CheckTransitionParent(mc.chain.GetHeaderByNumber(1))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not a terminal PoW block")
+	require.Error(t, mc.CheckTransitionParent(mc.chain.GetHeaderByNumber(3)))
+}
+
+func TestCheckInvalidTerminalBlock(t *testing.T) {
+	engine := &statusEngine{status: types.ExecutionInvalid}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	c := &ConsensusCmd{SlotsPerEpoch: 4, SlotTime: time.Second}
+	c.log = logrus.New()
+	c.ctx = context.Background()
+	c.mockChain = newPoWChain(t)
+	c.state = beaconstate.New(beaconstate.Config{GenesisTime: c.mockChain.CurrentHeader().Time, SlotTime: time.Second, SlotsPerEpoch: 4}, c.mockChain.CurrentHeader().Hash())
+	var err error
+	c.engine = dialEngine(t, srv.URL)
+
+	require.NoError(t, c.checkInvalidTerminalBlock(2))
+
+	engine.status = types.ExecutionValid
+	err = c.checkInvalidTerminalBlock(2)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "engine returned VALID for payload with invalid terminal block")
+
+	err = c.checkInvalidTerminalBlock(3)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not a terminal PoW block")
+}
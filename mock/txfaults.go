@@ -0,0 +1,281 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/sirupsen/logrus"
+)
+
+// Faults of the transactions of the tx faults.
+const (
+	txFaultChainID     = "chain-id"
+	txFaultNonceGap    = "nonce-gap"
+	txFaultUnderpriced = "underpriced"
+	txFaultDuplicate   = "duplicate"
+)
+
+// TxFaults configures faulty transactions, in external blocks and in submissions to the
+// transaction pool of the engine, to check that the engine rejects them like an execution
+// client.
+type TxFaults struct {
+	Block   float64  `ask:"--block" help:"How often an external block is sent to the engine with a faulty transaction of the first test account, which the engine must consider invalid"`
+	Mempool float64  `ask:"--mempool" help:"How often faulty transactions of the first test account are submitted to the engine over eth_sendRawTransaction before a proposal, which the engine must refuse or leave out of the payload"`
+	Kinds   []string `ask:"--kinds" help:"Faults of the transactions: 'chain-id' for a wrong chain id, 'nonce-gap' for a nonce after a gap, 'underpriced' for a fee cap below the base fee, 'duplicate' for a transaction sent twice"`
+}
+
+func (f *TxFaults) Default() {
+	f.Kinds = []string{txFaultChainID, txFaultNonceGap, txFaultUnderpriced, txFaultDuplicate}
+}
+
+// check returns an error for unknown faults, or faults without a test account to send them.
+func (f *TxFaults) check(accounts []TestAccount) error {
+	if f.Block == 0 && f.Mempool == 0 {
+		return nil
+	}
+	if len(f.Kinds) == 0 {
+		return fmt.Errorf("no tx fault kinds")
+	}
+	for _, kind := range f.Kinds {
+		switch kind {
+		case txFaultChainID, txFaultNonceGap, txFaultUnderpriced, txFaultDuplicate:
+		default:
+			return fmt.Errorf("unrecognized tx fault: %q", kind)
+		}
+	}
+	if len(accounts) == 0 {
+		return fmt.Errorf("tx faults need a test account")
+	}
+	return nil
+}
+
+// faultyTx returns a transfer of the account to itself with the fault, for a block with the base
+// fee, in which the next nonce of the account is the nonce. A duplicate is a valid transaction,
+// to send twice.
+func faultyTx(config *params.ChainConfig, account TestAccount, nonce uint64, baseFee *big.Int, fault string) (*ethTypes.Transaction, error) {
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	}
+	chainID := config.ChainID
+	tip := big.NewInt(2)
+	feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, common.Big2), tip)
+	switch fault {
+	case txFaultChainID:
+		chainID = new(big.Int).Add(chainID, common.Big1)
+	case txFaultNonceGap:
+		nonce++
+	case txFaultUnderpriced:
+		tip = new(big.Int)
+		feeCap = new(big.Int)
+		if baseFee.Sign() > 0 {
+			feeCap.Sub(baseFee, common.Big1)
+		}
+	case txFaultDuplicate:
+	default:
+		return nil, fmt.Errorf("unrecognized tx fault: %q", fault)
+	}
+	to := account.addr
+	return ethTypes.SignNewTx(account.pk, ethTypes.LatestSignerForChainID(chainID), &ethTypes.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+		Gas:       params.TxGas,
+		To:        &to,
+		Value:     common.Big1,
+	})
+}
+
+// withFaultyTx returns the block with the faulty transaction after its transactions, twice for a
+// duplicate, and the transactions root to match.
+func withFaultyTx(block *ethTypes.Block, tx *ethTypes.Transaction, fault string) *ethTypes.Block {
+	txs := append(block.Transactions()[:len(block.Transactions()):len(block.Transactions())], tx)
+	if fault == txFaultDuplicate {
+		txs = append(txs, tx)
+	}
+	header := ethTypes.CopyHeader(block.Header())
+	header.TxHash = ethTypes.DeriveSha(txs, trie.NewStackTrie(nil))
+	return ethTypes.NewBlockWithHeader(header).WithBody(txs, nil)
+}
+
+// mockTxFaultBlock sends the block with a faulty transaction after its transactions, and the
+// block hash of the changed header, which the engine must consider invalid.
+func (c *ConsensusCmd) mockTxFaultBlock(log logrus.Ext1FieldLogger, slot uint64, block *ethTypes.Block, withdrawals types.Withdrawals, beaconRoot *common.Hash) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	fault := c.TxFaults.Kinds[c.RNG.Intn(len(c.TxFaults.Kinds))]
+	account := c.ConsensusBehavior.TestAccounts.accounts[0]
+	statedb, err := c.mockChain.chain.StateAt(block.Root())
+	if err != nil {
+		log.WithError(err).Error("Failed to get the state of the block for a faulty transaction")
+		return
+	}
+	tx, err := faultyTx(c.mockChain.chain.Config(), account, statedb.GetNonce(account.addr), block.BaseFee(), fault)
+	if err != nil {
+		log.WithError(err).Error("Failed to create faulty transaction")
+		return
+	}
+	payload, err := api.BlockToPayloadV3(withFaultyTx(block, tx, fault), withdrawals)
+	if err != nil {
+		log.WithError(err).Error("Failed to convert block with faulty transaction to execution payload")
+		return
+	}
+	log = log.WithField("fault", fault).WithField("tx", tx.Hash())
+	log.Info("Sending payload with faulty transaction")
+	requests, _ := c.mockChain.ExecutionRequests(block.Hash())
+	res, err := c.newPayload(withFault(ctx), log, payload, beaconRoot, requests)
+	if err != nil {
+		log.WithError(err).Info("Engine rejected payload with faulty transaction")
+		c.report.record(scenarioTxFaultBlock, slot, nil)
+		return
+	}
+	switch res.Status {
+	case types.ExecutionInvalid:
+		log.Info("Engine rejected payload with faulty transaction")
+		c.report.record(scenarioTxFaultBlock, slot, nil)
+	case types.ExecutionValid:
+		log.Error("Engine accepted payload with faulty transaction")
+		c.report.record(scenarioTxFaultBlock, slot, fmt.Errorf("engine accepted block with %s transaction %s", fault, tx.Hash()))
+		c.maybeExit()
+	default:
+		log.WithField("status", res.Status).Warn("Unexpected status for payload with faulty transaction")
+	}
+}
+
+// txFaultState keeps the faulty transactions submitted to the engine for a proposal, by slot,
+// to check they are not in its payload.
+type txFaultState struct {
+	mu      sync.Mutex
+	pending map[uint64][]txFaultSubmission
+}
+
+type txFaultSubmission struct {
+	fault string
+	hash  common.Hash
+}
+
+func (s *txFaultState) add(slot uint64, sub txFaultSubmission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		s.pending = make(map[uint64][]txFaultSubmission)
+	}
+	s.pending[slot] = append(s.pending[slot], sub)
+}
+
+// take returns the faulty transactions submitted for the slot, and forgets them along with the
+// ones of earlier slots.
+func (s *txFaultState) take(slot uint64) []txFaultSubmission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := s.pending[slot]
+	for pending := range s.pending {
+		if pending <= slot {
+			delete(s.pending, pending)
+		}
+	}
+	return subs
+}
+
+// submitTxFaults submits a faulty transaction to the engine, for the payload it builds on the
+// parent for the slot. Transactions with a wrong chain id, and the second submission of a
+// duplicate, must be refused. The others must not be in the payload.
+func (c *ConsensusCmd) submitTxFaults(log logrus.Ext1FieldLogger, slot uint64, parent *ethTypes.Block) {
+	ctx, cancel := context.WithTimeout(c.ctx, time.Second*20)
+	defer cancel()
+
+	fault := c.TxFaults.Kinds[c.RNG.Intn(len(c.TxFaults.Kinds))]
+	account := c.ConsensusBehavior.TestAccounts.accounts[0]
+	statedb, err := c.mockChain.chain.StateAt(parent.Root())
+	if err != nil {
+		log.WithError(err).Warn("Unable to create faulty transaction for the engine")
+		return
+	}
+	config := c.mockChain.chain.Config()
+	tx, err := faultyTx(config, account, statedb.GetNonce(account.addr), misc.CalcBaseFee(config, parent.Header()), fault)
+	if err != nil {
+		log.WithError(err).Error("Failed to create faulty transaction")
+		return
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		log.WithError(err).Error("Unable to encode faulty transaction for the engine")
+		return
+	}
+	log = log.WithField("fault", fault).WithField("tx", tx.Hash())
+	send := func() error {
+		var hash common.Hash
+		return c.engine.CallContext(ctx, &hash, "eth_sendRawTransaction", hexutil.Bytes(raw))
+	}
+	switch fault {
+	case txFaultDuplicate:
+		if err := send(); err != nil {
+			log.WithError(err).Warn("Engine refused transaction to duplicate")
+			return
+		}
+		if err := send(); err == nil {
+			log.Error("Engine accepted duplicate transaction")
+			c.report.record(scenarioTxFaultMempool, slot, fmt.Errorf("engine accepted duplicate transaction %s", tx.Hash()))
+			c.maybeExit()
+			return
+		}
+		log.Info("Engine refused duplicate transaction")
+		c.report.record(scenarioTxFaultMempool, slot, nil)
+	case txFaultChainID:
+		if err := send(); err == nil {
+			log.Error("Engine accepted transaction with wrong chain id")
+			c.report.record(scenarioTxFaultMempool, slot, fmt.Errorf("engine accepted transaction %s with wrong chain id", tx.Hash()))
+			c.maybeExit()
+			return
+		}
+		log.Info("Engine refused transaction with wrong chain id")
+		c.report.record(scenarioTxFaultMempool, slot, nil)
+	default:
+		// execution clients may keep these transactions, but must not include them
+		if err := send(); err != nil {
+			log.WithError(err).Info("Engine refused faulty transaction")
+			c.report.record(scenarioTxFaultMempool, slot, nil)
+			return
+		}
+		log.Info("Engine took faulty transaction, checking it is not in the payload")
+		c.txFaults.add(slot, txFaultSubmission{fault, tx.Hash()})
+	}
+}
+
+// checkTxFaults checks the payload of the slot has none of the faulty transactions the engine
+// took for it.
+func (c *ConsensusCmd) checkTxFaults(log logrus.Ext1FieldLogger, slot uint64, payload *types.ExecutionPayloadV3) {
+	subs := c.txFaults.take(slot)
+	if len(subs) == 0 {
+		return
+	}
+	included := make(map[common.Hash]bool, len(payload.Transactions))
+	for _, raw := range payload.Transactions {
+		var tx ethTypes.Transaction
+		if err := tx.UnmarshalBinary(raw); err == nil {
+			included[tx.Hash()] = true
+		}
+	}
+	for _, sub := range subs {
+		if included[sub.hash] {
+			log.WithField("fault", sub.fault).WithField("tx", sub.hash).Error("Engine included faulty transaction in payload")
+			c.report.record(scenarioTxFaultMempool, slot, fmt.Errorf("engine included %s transaction %s in payload", sub.fault, sub.hash))
+			c.maybeExit()
+			continue
+		}
+		c.report.record(scenarioTxFaultMempool, slot, nil)
+	}
+}
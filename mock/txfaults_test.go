@@ -0,0 +1,123 @@
+package mock
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxFaults(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	engine := newTestEngine(t, newFundedGenesis(t, account.addr))
+	chain := engine.mockChain()
+	config := chain.chain.Config()
+
+	// the engine rejects blocks with faulty transactions
+	parent := chain.CurrentHeader()
+	creator := TransactionsCreator{[]TestAccount{account}, dummyTxCreator}
+	block, err := chain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, creator, common.Hash{}, nil, nil, nil, nil, false)
+	require.NoError(t, err)
+	for _, fault := range []string{txFaultChainID, txFaultNonceGap, txFaultUnderpriced, txFaultDuplicate} {
+		tx, err := faultyTx(config, account, 1, block.BaseFee(), fault)
+		require.NoError(t, err)
+		payload, err := api.BlockToPayloadV3(withFaultyTx(block, tx, fault), nil)
+		require.NoError(t, err)
+		status, err := engine.backend.NewPayloadV1(ctx, payload.V1())
+		require.NoError(t, err, fault)
+		require.Equal(t, types.ExecutionInvalid, status.Status, fault)
+	}
+	payload, err := api.BlockToPayloadV3(block, nil)
+	require.NoError(t, err)
+	status, err := engine.backend.NewPayloadV1(ctx, payload.V1())
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, status.Status)
+
+	// the pool refuses the wrong chain id and duplicates, and leaves the others out of payloads
+	statedb, err := chain.chain.State()
+	require.NoError(t, err)
+	header := chain.CurrentHeader()
+	baseFee := chain.ExpectedBaseFee(header)
+	pool := NewTxPool(logrus.New(), config, 0)
+	nonce := statedb.GetNonce(account.addr)
+	tx, err := faultyTx(config, account, nonce, baseFee, txFaultChainID)
+	require.NoError(t, err)
+	require.ErrorIs(t, pool.Add(tx, statedb), errTxWrongChainID)
+	tx, err = faultyTx(config, account, nonce, baseFee, txFaultDuplicate)
+	require.NoError(t, err)
+	require.NoError(t, pool.Add(tx, statedb))
+	require.ErrorIs(t, pool.Add(tx, statedb), errTxKnown)
+	// a gap after the pooled transaction
+	gap, err := faultyTx(config, account, nonce+1, baseFee, txFaultNonceGap)
+	require.NoError(t, err)
+	require.NoError(t, pool.Add(gap, statedb))
+	next := &ethTypes.Header{Number: new(big.Int).Add(header.Number, common.Big1), GasLimit: header.GasLimit, BaseFee: baseFee}
+	selected := pool.Select(config, chain.chain, statedb, next, vm.Config{})
+	require.Len(t, selected, 1)
+	require.Equal(t, tx.Hash(), selected[0].Hash())
+
+	underpriced, err := faultyTx(config, account, nonce, baseFee, txFaultUnderpriced)
+	require.NoError(t, err)
+	require.Equal(t, -1, underpriced.GasFeeCap().Cmp(baseFee))
+	pool = NewTxPool(logrus.New(), config, 0)
+	require.NoError(t, pool.Add(underpriced, statedb))
+	require.Empty(t, pool.Select(config, chain.chain, statedb, next, vm.Config{}))
+}
+
+func TestTxFaultReports(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	c := newCheckpointConsensus(t)
+	c.ctx = context.Background()
+	c.report = newScenarioReport()
+	c.RNG = NewRNG(1)
+	c.ConsensusBehavior.TestAccounts.accounts = []TestAccount{{key, crypto.PubkeyToAddress(key.PublicKey)}}
+	c.TxFaults.Default()
+	require.NoError(t, c.TxFaults.check(c.ConsensusBehavior.TestAccounts.accounts))
+	c.TxFaults.Block = 1
+	require.Error(t, c.TxFaults.check(nil))
+	c.TxFaults.Kinds = []string{"replay"}
+	require.Error(t, c.TxFaults.check(c.ConsensusBehavior.TestAccounts.accounts))
+	c.TxFaults.Kinds = []string{txFaultNonceGap}
+
+	// engines accepting blocks with faulty transactions fail the scenario
+	parent := c.mockChain.CurrentHeader()
+	block, err := c.mockChain.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+1, parent.GasLimit, TransactionsCreator{nil, dummyTxCreator}, common.Hash{}, nil, nil, types.Withdrawals{}, &common.Hash{}, true)
+	require.NoError(t, err)
+	for i, status := range []types.ExecutePayloadStatus{types.ExecutionInvalid, types.ExecutionValid} {
+		srv := httptest.NewServer(&statusEngine{status: status})
+		c.engine = dialEngine(t, srv.URL)
+		c.mockTxFaultBlock(logrus.New(), uint64(i+1), block, types.Withdrawals{}, &common.Hash{})
+		srv.Close()
+	}
+	sc := c.report.cases[scenarioTxFaultBlock]
+	require.Equal(t, 2, sc.runs)
+	require.Len(t, sc.failures, 1)
+	require.Contains(t, sc.failures[0], "slot 2: engine accepted block with nonce-gap transaction")
+
+	// payloads must leave out the faulty transactions the engine took for their slot
+	tx, err := faultyTx(c.mockChain.chain.Config(), c.ConsensusBehavior.TestAccounts.accounts[0], 1, nil, txFaultNonceGap)
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	c.txFaults.add(3, txFaultSubmission{txFaultNonceGap, tx.Hash()})
+	c.txFaults.add(4, txFaultSubmission{txFaultNonceGap, tx.Hash()})
+	c.checkTxFaults(logrus.New(), 3, &types.ExecutionPayloadV3{})
+	c.checkTxFaults(logrus.New(), 4, &types.ExecutionPayloadV3{Transactions: [][]byte{raw}})
+	c.checkTxFaults(logrus.New(), 4, &types.ExecutionPayloadV3{Transactions: [][]byte{raw}})
+	sc = c.report.cases[scenarioTxFaultMempool]
+	require.Equal(t, 2, sc.runs)
+	require.Len(t, sc.failures, 1)
+	require.Contains(t, sc.failures[0], "slot 4: engine included nonce-gap transaction")
+}
@@ -0,0 +1,271 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errTxPoolFull     = errors.New("txpool is full")
+	errTxKnown        = errors.New("already known")
+	errTxNonceTooLow  = errors.New("nonce too low")
+	errTxWrongChainID = errors.New("invalid chain id")
+	errEmptyBundle    = errors.New("empty bundle")
+)
+
+// TxPool is a minimal transaction pool for the engine mock. It does not try to
+// be a real mempool: there is no replacement, no eviction by price and no
+// gossip. Transactions are kept until a payload is built on a state in which
+// their nonce is already used. Bundles are kept apart, and included before the other
+// transactions, in order and all together.
+type TxPool struct {
+	mu      sync.Mutex
+	log     logrus.Ext1FieldLogger
+	config  *params.ChainConfig
+	signer  types.Signer
+	txs     map[common.Hash]*types.Transaction
+	senders map[common.Hash]common.Address
+	bundles []*txBundle
+	limit   int
+}
+
+// txBundle is an ordered group of transactions, included all together or not at all.
+type txBundle struct {
+	txs     []*types.Transaction
+	senders []common.Address
+	// number of the block the bundle is for, any block if zero
+	block uint64
+}
+
+func NewTxPool(log logrus.Ext1FieldLogger, config *params.ChainConfig, limit int) *TxPool {
+	return &TxPool{
+		log:     log,
+		config:  config,
+		signer:  types.LatestSigner(config),
+		txs:     make(map[common.Hash]*types.Transaction),
+		senders: make(map[common.Hash]common.Address),
+		limit:   limit,
+	}
+}
+
+// validate returns the sender of the transaction, after checking it against the given state.
+func (p *TxPool) validate(tx *types.Transaction, statedb *state.StateDB) (common.Address, error) {
+	if chainID := tx.ChainId(); chainID.Sign() != 0 && chainID.Cmp(p.config.ChainID) != 0 {
+		return common.Address{}, fmt.Errorf("%w: have %d, want %d", errTxWrongChainID, chainID, p.config.ChainID)
+	}
+	from, err := types.Sender(p.signer, tx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid sender: %v", err)
+	}
+	if nonce := statedb.GetNonce(from); tx.Nonce() < nonce {
+		return common.Address{}, fmt.Errorf("%w: address %s, tx: %d state: %d", errTxNonceTooLow, from, tx.Nonce(), nonce)
+	}
+	return from, nil
+}
+
+// Add validates the transaction against the given state and adds it to the pool.
+func (p *TxPool) Add(tx *types.Transaction, statedb *state.StateDB) error {
+	from, err := p.validate(tx, statedb)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hash := tx.Hash()
+	if _, ok := p.txs[hash]; ok {
+		return errTxKnown
+	}
+	if p.limit > 0 && len(p.txs) >= p.limit {
+		return errTxPoolFull
+	}
+	p.txs[hash] = tx
+	p.senders[hash] = from
+	p.log.WithFields(logrus.Fields{
+		"hash":  hash,
+		"from":  from,
+		"nonce": tx.Nonce(),
+	}).Info("Added transaction to pool")
+	return nil
+}
+
+// AddBundle validates the transactions of the bundle against the given state, and adds the
+// bundle to the pool, for the block of the number, or any block if zero.
+func (p *TxPool) AddBundle(txs []*types.Transaction, block uint64, statedb *state.StateDB) error {
+	if len(txs) == 0 {
+		return errEmptyBundle
+	}
+	b := &txBundle{txs: txs, senders: make([]common.Address, len(txs)), block: block}
+	for i, tx := range txs {
+		from, err := p.validate(tx, statedb)
+		if err != nil {
+			return fmt.Errorf("bundle transaction %d: %w", i, err)
+		}
+		b.senders[i] = from
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.limit > 0 && len(p.txs)+len(txs) > p.limit {
+		return errTxPoolFull
+	}
+	p.bundles = append(p.bundles, b)
+	p.log.WithFields(logrus.Fields{
+		"txs":   len(txs),
+		"block": block,
+	}).Info("Added bundle to pool")
+	return nil
+}
+
+// pendingBundles returns the bundles for the block of the number, in the order they were added.
+// Bundles for earlier blocks, and bundles with a nonce that is already used in the state, are
+// dropped from the pool.
+func (p *TxPool) pendingBundles(number uint64, statedb *state.StateDB) []*txBundle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var kept, pending []*txBundle
+	for _, b := range p.bundles {
+		if b.block != 0 && b.block < number {
+			continue
+		}
+		used := false
+		for i, tx := range b.txs {
+			if tx.Nonce() < statedb.GetNonce(b.senders[i]) {
+				used = true
+				break
+			}
+		}
+		if used {
+			continue
+		}
+		kept = append(kept, b)
+		if b.block == 0 || b.block == number {
+			pending = append(pending, b)
+		}
+	}
+	p.bundles = kept
+	return pending
+}
+
+// Len returns the number of transactions currently in the pool.
+func (p *TxPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.txs)
+}
+
+// Pending returns the executable transactions per sender, sorted by nonce,
+// starting from the nonce each sender has in the given state. Transactions
+// with a nonce that is already used in the state are dropped from the pool.
+func (p *TxPool) Pending(statedb *state.StateDB) map[common.Address]types.Transactions {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bySender := make(map[common.Address]types.Transactions)
+	for hash, tx := range p.txs {
+		from := p.senders[hash]
+		if tx.Nonce() < statedb.GetNonce(from) {
+			delete(p.txs, hash)
+			delete(p.senders, hash)
+			continue
+		}
+		bySender[from] = append(bySender[from], tx)
+	}
+	pending := make(map[common.Address]types.Transactions)
+	for from, txs := range bySender {
+		sort.Sort(types.TxByNonce(txs))
+		// only keep the gapless sequence starting at the state nonce
+		next := statedb.GetNonce(from)
+		for _, tx := range txs {
+			if tx.Nonce() != next {
+				break
+			}
+			pending[from] = append(pending[from], tx)
+			next++
+		}
+	}
+	return pending
+}
+
+// Select picks the bundles of the pool, each in order and all together, and then the
+// transactions of the pool ordered by effective tip, and verifies that each of them applies on
+// top of the given state. Bundles with a transaction that reverts are left out. The state is
+// not modified, and no tracing is done during the selection.
+func (p *TxPool) Select(config *params.ChainConfig, bc core.ChainContext, statedb *state.StateDB, header *types.Header, _ vm.Config) []*types.Transaction {
+	bundles := p.pendingBundles(header.Number.Uint64(), statedb)
+	pending := p.Pending(statedb)
+	if len(pending) == 0 && len(bundles) == 0 {
+		return nil
+	}
+	baseFee := header.BaseFee
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	}
+	var (
+		txs      = types.NewTransactionsByPriceAndNonce(p.signer, pending, baseFee)
+		selected []*types.Transaction
+		sim      = statedb.Copy()
+		gasPool  = new(core.GasPool).AddGas(header.GasLimit)
+		gasUsed  = uint64(0)
+		simHead  = types.CopyHeader(header)
+	)
+	for _, b := range bundles {
+		// applied transactions are finalised, so a bundle is simulated on a copy of the state
+		bundleSim, gas, used, start := sim.Copy(), gasPool.Gas(), gasUsed, len(selected)
+		ok := true
+		for _, tx := range b.txs {
+			bundleSim.Prepare(tx.Hash(), len(selected))
+			receipt, err := core.ApplyTransaction(config, bc, &simHead.Coinbase, gasPool, bundleSim, simHead, tx, &gasUsed, vm.Config{})
+			if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+				p.log.WithError(err).WithField("hash", tx.Hash()).Debug("Skipping bundle")
+				ok = false
+				break
+			}
+			selected = append(selected, tx)
+		}
+		if !ok {
+			gasPool, gasUsed = new(core.GasPool).AddGas(gas), used
+			selected = selected[:start]
+			continue
+		}
+		sim = bundleSim
+	}
+	for {
+		tx := txs.Peek()
+		if tx == nil {
+			break
+		}
+		if gasPool.Gas() < params.TxGas {
+			break
+		}
+		if tx.Gas() > gasPool.Gas() {
+			// not enough gas left for this sender's next tx
+			txs.Pop()
+			continue
+		}
+		sim.Prepare(tx.Hash(), len(selected))
+		snap, gas := sim.Snapshot(), gasPool.Gas()
+		if _, err := core.ApplyTransaction(config, bc, &simHead.Coinbase, gasPool, sim, simHead, tx, &gasUsed, vm.Config{}); err != nil {
+			p.log.WithError(err).WithField("hash", tx.Hash()).Debug("Skipping pool transaction")
+			sim.RevertToSnapshot(snap)
+			gasPool = new(core.GasPool).AddGas(gas)
+			txs.Pop()
+			continue
+		}
+		selected = append(selected, tx)
+		txs.Shift()
+	}
+	return selected
+}
@@ -0,0 +1,44 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"mergemock/types"
+)
+
+type VectorsCmd struct {
+	Out   string `ask:"--out" help:"Directory to write the test vectors to"`
+	Seed  int64  `ask:"--seed" help:"Seed for the random test vectors"`
+	Cases int    `ask:"--cases" help:"Number of random test vectors per type"`
+
+	LogCmd `ask:".log" help:"Change logger configuration"`
+}
+
+func (c *VectorsCmd) Default() {
+	c.Out = "vectors"
+	c.Seed = 1234
+	c.Cases = 5
+}
+
+func (c *VectorsCmd) Help() string {
+	return "Generate SSZ static test vectors (SSZ, JSON and hash tree roots) for the builder and beacon types."
+}
+
+func (c *VectorsCmd) Examples() []string {
+	return []string{
+		"mergemock vectors --out=vectors --seed=1234 --cases=5",
+	}
+}
+
+func (c *VectorsCmd) Run(ctx context.Context, args ...string) error {
+	log, err := c.LogCmd.Create()
+	if err != nil {
+		return err
+	}
+	if err := types.WriteTestVectors(c.Out, c.Seed, c.Cases); err != nil {
+		return fmt.Errorf("failed to write test vectors: %w", err)
+	}
+	log.WithField("types", len(types.VectorTypes)).WithField("out", c.Out).Info("Wrote test vectors")
+	return nil
+}
@@ -0,0 +1,192 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mergemock/engineclient"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Watchdog configures the detection of stalled runs, where the head of the mock chain stops
+// advancing, with a dump of the diagnostics to debug them.
+type Watchdog struct {
+	Slots   uint64 `ask:"--slots" help:"Dump diagnostics every this many slots the head of the mock chain does not advance (0 to disable)"`
+	DumpDir string `ask:"--dump-dir" help:"Directory to write the diagnostics dumps to, with the goroutine stacks (empty to log them)"`
+	Recover bool   `ask:"--recover" help:"After a dump, redial the engines and update the forkchoice of the engine to the head again"`
+}
+
+// watchdogErrors are the last errors and warnings of the run.
+const watchdogErrors = 16
+
+// watchdogState tracks the progress of the head, and keeps the last errors and warnings of the
+// log, as a hook of the logger.
+type watchdogState struct {
+	mu     sync.Mutex
+	head   uint64
+	since  uint64
+	errors []*logrus.Entry
+}
+
+func (w *watchdogState) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+}
+
+func (w *watchdogState) Fire(entry *logrus.Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.errors) == watchdogErrors {
+		w.errors = w.errors[1:]
+	}
+	// the first line only, as the dumps themselves are logged
+	dup := entry.Dup()
+	dup.Level = entry.Level
+	dup.Message, _, _ = strings.Cut(entry.Message, "\n")
+	w.errors = append(w.errors, dup)
+	return nil
+}
+
+// progress records the head in the slot, and returns the slot the head last advanced in if it
+// did not advance for the slots since, or since the last stall was reported.
+func (w *watchdogState) progress(slot, head, slots uint64) (since uint64, stalled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if head != w.head || w.since == 0 {
+		w.head, w.since = head, slot
+		return 0, false
+	}
+	if slot-w.since < slots {
+		return 0, false
+	}
+	since, w.since = w.since, slot
+	return since, true
+}
+
+// checkProgress dumps the diagnostics of the run if the head stalled, and tries to recover.
+func (c *ConsensusCmd) checkProgress(slot uint64) {
+	if c.Watchdog.Slots == 0 {
+		return
+	}
+	head := c.mockChain.CurrentHeader()
+	since, stalled := c.watchdog.progress(slot, head.Number.Uint64(), c.Watchdog.Slots)
+	if !stalled {
+		return
+	}
+	log := c.slotLog(slot).WithField("head", head.Number).WithField("since", since)
+	log.Error("Head of the run stalled")
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+		defer cancel()
+		dump := c.diagnostics(ctx, slot, since)
+		if c.Watchdog.DumpDir == "" {
+			log.Error("Diagnostics of the stall:\n" + dump)
+		} else {
+			path := filepath.Join(c.Watchdog.DumpDir, fmt.Sprintf("stall-slot-%d.txt", slot))
+			if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+				log.WithError(err).Error("Failed to write the diagnostics of the stall")
+			} else {
+				log.WithField("path", path).Error("Wrote the diagnostics of the stall")
+			}
+		}
+		if c.Watchdog.Recover {
+			c.recoverStall(log)
+		}
+	}()
+}
+
+// diagnostics describes the state of the run: the engines and their pending calls, the last
+// errors and warnings, and the goroutine stacks.
+func (c *ConsensusCmd) diagnostics(ctx context.Context, slot, since uint64) string {
+	var buf bytes.Buffer
+	head := c.mockChain.CurrentHeader()
+	fmt.Fprintf(&buf, "head %d %s did not advance since slot %d, at slot %d\n\n", head.Number, head.Hash(), since, slot)
+
+	c.offline.mu.Lock()
+	offline, buffered := c.offline.offline, len(c.offline.payloads)
+	c.offline.mu.Unlock()
+	fmt.Fprintf(&buf, "engine %s: offline %t, %d buffered payloads\n", c.EngineAddr, offline, buffered)
+	if latest, err := c.engineBlockHash(ctx, "latest"); err != nil {
+		fmt.Fprintf(&buf, "  latest block: %v\n", err)
+	} else {
+		fmt.Fprintf(&buf, "  latest block: %s\n", latest)
+	}
+	if fc := c.forkchoice.get(); fc != nil {
+		fmt.Fprintf(&buf, "  forkchoice: head %s, safe %s, finalized %s\n", fc.HeadBlockHash, fc.SafeBlockHash, fc.FinalizedBlockHash)
+	}
+	writeEngineCalls(&buf, c.engine)
+	if c.proposerEngine != nil {
+		fmt.Fprintf(&buf, "proposer engine %s:\n", c.ProposerEngine)
+		writeEngineCalls(&buf, c.proposerEngine)
+	}
+
+	c.watchdog.mu.Lock()
+	fmt.Fprintf(&buf, "\nlast %d errors and warnings:\n", len(c.watchdog.errors))
+	for _, entry := range c.watchdog.errors {
+		fields := make([]string, 0, len(entry.Data))
+		for k, v := range entry.Data {
+			fields = append(fields, fmt.Sprintf("%s=%v", k, v))
+		}
+		sort.Strings(fields)
+		fmt.Fprintf(&buf, "  %s %s %s %s\n", entry.Time.Format(time.RFC3339), entry.Level, entry.Message, strings.Join(fields, " "))
+	}
+	c.watchdog.mu.Unlock()
+
+	buf.WriteString("\ngoroutines:\n")
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		fmt.Fprintf(&buf, "  %v\n", err)
+	}
+	return buf.String()
+}
+
+// writeEngineCalls describes the calls of the engine in progress or waiting, by method.
+func writeEngineCalls(buf *bytes.Buffer, engine *engineclient.Client) {
+	metrics := engine.Metrics()
+	methods := make([]string, 0, len(metrics))
+	for method, m := range metrics {
+		if m.Active > 0 || m.Queued > 0 {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	fmt.Fprintf(buf, "  pending calls: %d methods\n", len(methods))
+	for _, method := range methods {
+		m := metrics[method]
+		fmt.Fprintf(buf, "    %s: %d active, %d queued, %d dropped of %d\n", method, m.Active, m.Queued, m.Dropped, m.Calls)
+	}
+}
+
+// recoverStall redials the engines, and updates the forkchoice of the engine to the head of the
+// mock chain again, with the safe and finalized blocks it last accepted.
+func (c *ConsensusCmd) recoverStall(log logrus.Ext1FieldLogger) {
+	for _, engine := range []*engineclient.Client{c.engine, c.proposerEngine} {
+		if engine == nil {
+			continue
+		}
+		if err := engine.Redial(); err != nil {
+			log.WithError(err).Error("Failed to redial the engine")
+			return
+		}
+	}
+	fc := c.forkchoice.get()
+	if fc == nil {
+		log.Info("Redialed the engine")
+		return
+	}
+	head := c.mockChain.CurrentHeader().Hash()
+	if _, err := c.sendForkchoiceUpdated(head, fc.SafeBlockHash, fc.FinalizedBlockHash, nil); err != nil {
+		log.WithError(err).Error("Failed to update the forkchoice after redialing the engine")
+		return
+	}
+	log.WithField("head", head).Info("Redialed the engine, and updated its forkchoice to the head")
+}
@@ -0,0 +1,79 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogProgress(t *testing.T) {
+	var w watchdogState
+	_, stalled := w.progress(1, 1, 3)
+	require.False(t, stalled)
+	_, stalled = w.progress(2, 2, 3)
+	require.False(t, stalled)
+	for slot := uint64(3); slot < 5; slot++ {
+		_, stalled = w.progress(slot, 2, 3)
+		require.False(t, stalled)
+	}
+	since, stalled := w.progress(5, 2, 3)
+	require.True(t, stalled)
+	require.Equal(t, uint64(2), since)
+	// reported again after as many slots, until the head advances
+	_, stalled = w.progress(6, 2, 3)
+	require.False(t, stalled)
+	since, stalled = w.progress(8, 2, 3)
+	require.True(t, stalled)
+	require.Equal(t, uint64(5), since)
+	_, stalled = w.progress(9, 3, 3)
+	require.False(t, stalled)
+}
+
+func TestWatchdogDiagnostics(t *testing.T) {
+	srv := httptest.NewServer(tagEngine{"latest": {3}})
+	defer srv.Close()
+	engine := newTestEngine(t, writeGenesis(t, newMergedGenesis(common.Address{1}), ForkTimes{}))
+
+	log := logrus.New()
+	log.SetOutput(&strings.Builder{})
+	c := &ConsensusCmd{log: log, mockChain: engine.mockChain(), EngineAddr: srv.URL, ctx: context.Background()}
+	c.engine = dialEngine(t, srv.URL)
+	log.AddHook(&c.watchdog)
+	for i := 0; i < watchdogErrors+1; i++ {
+		log.WithField("slot", i).WithError(errors.New("unrecognized execution status SYNCING")).Error("Failed to execute payload")
+	}
+	log.Info("Not an error")
+	log.Warn("Head of the run\nstalled")
+	require.Len(t, c.watchdog.errors, watchdogErrors)
+	require.Equal(t, "Head of the run", c.watchdog.errors[watchdogErrors-1].Message)
+
+	dump := c.diagnostics(context.Background(), 10, 4)
+	require.Contains(t, dump, "did not advance since slot 4, at slot 10")
+	require.Contains(t, dump, "offline false, 0 buffered payloads")
+	require.Contains(t, dump, "latest block: "+common.Hash{3}.Hex())
+	require.Contains(t, dump, "pending calls: 0 methods")
+	require.Contains(t, dump, "error Failed to execute payload error=unrecognized execution status SYNCING slot=15")
+	require.Contains(t, dump, "warning Head of the run \n")
+	require.Contains(t, dump, "TestWatchdogDiagnostics")
+
+	// stalls are dumped to the directory, and the engine is redialed, without a forkchoice to
+	// update it to yet
+	c.Watchdog = Watchdog{Slots: 2, DumpDir: t.TempDir(), Recover: true}
+	c.checkProgress(1)
+	c.checkProgress(3)
+	c.inflight.Wait()
+	out, err := os.ReadFile(filepath.Join(c.Watchdog.DumpDir, "stall-slot-3.txt"))
+	require.NoError(t, err)
+	require.Contains(t, string(out), "did not advance since slot 1, at slot 3")
+	latest, err := c.engineBlockHash(context.Background(), "latest")
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{3}, latest)
+}
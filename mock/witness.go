@@ -0,0 +1,298 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mergemock/api"
+	"mergemock/rpc"
+	mmTypes "mergemock/types"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+)
+
+// witnessRecorder records the execution witness of a block: the trie nodes and code the state
+// reads from the database, and the headers the EVM reads the hashes of from the chain.
+type witnessRecorder struct {
+	ethdb.Database
+	chain core.ChainContext
+
+	mu      sync.Mutex
+	nodes   map[common.Hash][]byte
+	codes   map[common.Hash][]byte
+	headers map[common.Hash]*types.Header
+}
+
+// newWitnessRecorder returns a recorder of the witness of the next block, nil if the chain does
+// not keep witnesses.
+func (c *MockChain) newWitnessRecorder() *witnessRecorder {
+	if c.witnesses == nil {
+		return nil
+	}
+	return &witnessRecorder{
+		Database: c.database,
+		chain:    c.chain,
+		nodes:    make(map[common.Hash][]byte),
+		codes:    make(map[common.Hash][]byte),
+		headers:  make(map[common.Hash]*types.Header),
+	}
+}
+
+// database returns the database to read the state from, the recorder if not nil.
+func (r *witnessRecorder) database(db ethdb.Database) ethdb.Database {
+	if r == nil {
+		return db
+	}
+	return r
+}
+
+// chainContext returns the chain to execute the block with, the recorder if not nil.
+func (r *witnessRecorder) chainContext(chain core.ChainContext) core.ChainContext {
+	if r == nil {
+		return chain
+	}
+	return r
+}
+
+func (r *witnessRecorder) Get(key []byte) ([]byte, error) {
+	value, err := r.Database.Get(key)
+	if err != nil {
+		return value, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ok, hash := rawdb.IsCodeKey(key); ok {
+		r.codes[common.BytesToHash(hash)] = common.CopyBytes(value)
+	} else if len(key) == common.HashLength && bytes.Equal(crypto.Keccak256(value), key) {
+		r.nodes[common.BytesToHash(key)] = common.CopyBytes(value)
+	}
+	return value, nil
+}
+
+func (r *witnessRecorder) Engine() consensus.Engine {
+	return r.chain.Engine()
+}
+
+func (r *witnessRecorder) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := r.chain.GetHeader(hash, number)
+	if header != nil {
+		r.mu.Lock()
+		r.headers[hash] = header
+		r.mu.Unlock()
+	}
+	return header
+}
+
+// witness returns the witness of the block on top of the parent, with the parent header first,
+// then the other headers from the newest, and the code and trie nodes in the order of their hash.
+func (r *witnessRecorder) witness(parent *types.Header) *mmTypes.ExecutionWitness {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := &mmTypes.ExecutionWitness{Headers: []*types.Header{parent}, Codes: sortedBlobs(r.codes), State: sortedBlobs(r.nodes)}
+	var headers []*types.Header
+	for hash, header := range r.headers {
+		if hash != parent.Hash() {
+			headers = append(headers, header)
+		}
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Number.Cmp(headers[j].Number) > 0 })
+	w.Headers = append(w.Headers, headers...)
+	return w
+}
+
+func sortedBlobs(blobs map[common.Hash][]byte) []hexutil.Bytes {
+	hashes := make([]common.Hash, 0, len(blobs))
+	for hash := range blobs {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	out := make([]hexutil.Bytes, len(hashes))
+	for i, hash := range hashes {
+		out[i] = blobs[hash]
+	}
+	return out
+}
+
+// EnableWitnesses makes the chain keep the execution witnesses of the recent blocks it builds
+// and processes.
+func (c *MockChain) EnableWitnesses() error {
+	witnesses, err := lru.New(128)
+	if err != nil {
+		return err
+	}
+	c.witnesses = witnesses
+	return nil
+}
+
+// Witness returns the execution witness of a recently built or processed block.
+func (c *MockChain) Witness(hash common.Hash) (*mmTypes.ExecutionWitness, bool) {
+	if c.witnesses == nil {
+		return nil, false
+	}
+	w, ok := c.witnesses.Get(hash)
+	if !ok {
+		return nil, false
+	}
+	return w.(*mmTypes.ExecutionWitness), true
+}
+
+func (c *MockChain) storeWitness(hash common.Hash, parent *types.Header, r *witnessRecorder) {
+	if r != nil {
+		c.witnesses.Add(hash, r.witness(parent))
+	}
+}
+
+// witnessChain serves the headers of a witness, for the hashes of the blocks the EVM reads.
+type witnessChain struct {
+	engine  consensus.Engine
+	headers []*types.Header
+}
+
+func (w *witnessChain) Engine() consensus.Engine {
+	return w.engine
+}
+
+func (w *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	for _, h := range w.headers {
+		if h.Number.Uint64() == number && h.Hash() == hash {
+			return h
+		}
+	}
+	return nil
+}
+
+// ExecuteStateless executes the payload with the state of its witness only, without the state of
+// the chain or adding the block to it, and returns the block it computed, whose state and
+// receipts roots are those of the state after the payload.
+func (c *MockChain) ExecuteStateless(payload *mmTypes.ExecutionPayloadV1, withdrawals mmTypes.Withdrawals, beaconRoot *common.Hash, witness *mmTypes.ExecutionWitness) (*types.Block, error) {
+	if err := c.checkWithdrawals(payload.Timestamp, withdrawals, true); err != nil {
+		return nil, err
+	}
+	if err := c.checkBeaconRoot(payload.Timestamp, beaconRoot); err != nil {
+		return nil, err
+	}
+	parent := witness.Header(payload.ParentHash)
+	if parent == nil {
+		return nil, fmt.Errorf("witness has no parent header %s", payload.ParentHash)
+	}
+	db := rawdb.NewMemoryDatabase()
+	for hash, node := range witness.Nodes() {
+		if err := db.Put(hash[:], node); err != nil {
+			return nil, err
+		}
+	}
+	for _, code := range witness.Codes {
+		rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+	}
+	statedb, err := state.New(parent.Root, state.NewDatabase(db), nil)
+	if err != nil {
+		return nil, fmt.Errorf("witness has no state root of the parent: %v", err)
+	}
+	block, _, err := c.executePayload(statedb, &witnessChain{c.engine, witness.Headers}, parent, payload, withdrawals, beaconRoot)
+	if err != nil {
+		return nil, err
+	}
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("witness is incomplete: %v", err)
+	}
+	return block, nil
+}
+
+// executeStateless executes the payload with the opaque witness only, and returns the state and
+// receipts roots the mock computed, valid if the block is that of the payload.
+func (e *EngineBackend) executeStateless(log logrus.Ext1FieldLogger, payload *mmTypes.ExecutionPayloadV3, withdrawals mmTypes.Withdrawals, beaconRoot *common.Hash, opaque hexutil.Bytes) (*mmTypes.StatelessPayloadStatusV1, error) {
+	if !payload.V1().ValidateHash() {
+		return &mmTypes.StatelessPayloadStatusV1{Status: mmTypes.ExecutionInvalidBlockHash}, nil
+	}
+	witness, err := mmTypes.DecodeExecutionWitness(opaque)
+	if err != nil {
+		return nil, &rpc.Error{Err: err, Id: int(api.InvalidParams)}
+	}
+	block, err := e.mockChain.ExecuteStateless(payload.V1(), withdrawals, beaconRoot, witness)
+	if err != nil {
+		log.WithError(err).Warn("Failed to execute stateless payload")
+		return &mmTypes.StatelessPayloadStatusV1{Status: mmTypes.ExecutionInvalid, ValidationError: err.Error()}, nil
+	}
+	res := &mmTypes.StatelessPayloadStatusV1{Status: mmTypes.ExecutionValid, StateRoot: block.Root(), ReceiptsRoot: block.ReceiptHash()}
+	if hash := block.Hash(); hash != payload.BlockHash {
+		res.Status = mmTypes.ExecutionInvalid
+		res.ValidationError = fmt.Sprintf("block hash difference: %s <> %s", hash, payload.BlockHash)
+	}
+	log.WithField("status", res.Status).WithField("nodes", len(witness.State)).Info("Executed stateless payload")
+	return res, nil
+}
+
+// statelessStatus returns the status of a payload with a wrong parameter as the result of
+// engine_executeStatelessPayload.
+func statelessStatus(status *mmTypes.PayloadStatusV1) *mmTypes.StatelessPayloadStatusV1 {
+	return &mmTypes.StatelessPayloadStatusV1{Status: status.Status, ValidationError: status.ValidationError}
+}
+
+func (e *EngineBackend) ExecuteStatelessPayloadV1(ctx context.Context, payload *mmTypes.ExecutionPayloadV1, witness hexutil.Bytes) (*mmTypes.StatelessPayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_executeStatelessPayload")
+	if e.mockChain.forks.IsShanghai(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("shanghai payloads require engine_executeStatelessPayloadV2"), Id: int(api.UnsupportedFork)}
+	}
+	return e.executeStateless(log, payload.V3(), nil, nil, witness)
+}
+
+func (e *EngineBackend) ExecuteStatelessPayloadV2(ctx context.Context, payload *mmTypes.ExecutionPayloadV2, witness hexutil.Bytes) (*mmTypes.StatelessPayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_executeStatelessPayload")
+	if e.mockChain.forks.IsCancun(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("cancun payloads require engine_executeStatelessPayloadV3"), Id: int(api.UnsupportedFork)}
+	}
+	withdrawals := payload.Withdrawals
+	if withdrawals == nil && e.mockChain.forks.IsShanghai(payload.Timestamp) {
+		withdrawals = mmTypes.Withdrawals{}
+	}
+	return e.executeStateless(log, payload.V3(), withdrawals, nil, witness)
+}
+
+func (e *EngineBackend) ExecuteStatelessPayloadV3(ctx context.Context, payload *mmTypes.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot *common.Hash, witness hexutil.Bytes) (*mmTypes.StatelessPayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_executeStatelessPayload")
+	if !e.mockChain.forks.IsCancun(payload.Timestamp) || e.mockChain.forks.IsPrague(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("engine_executeStatelessPayloadV3 requires a cancun payload"), Id: int(api.UnsupportedFork)}
+	}
+	if versionedHashes == nil || beaconRoot == nil {
+		return nil, &rpc.Error{Err: fmt.Errorf("missing versioned hashes or parent beacon block root"), Id: int(api.InvalidParams)}
+	}
+	if status := checkVersionedHashes(log, payload, versionedHashes); status != nil {
+		return statelessStatus(status), nil
+	}
+	return e.executeStateless(log, payload, payload.Withdrawals, beaconRoot, witness)
+}
+
+// ExecuteStatelessPayloadV4 executes a Prague payload with the witness. The execution requests are
+// only checked to be well-formed: the deposits are derived from the receipts with the state.
+func (e *EngineBackend) ExecuteStatelessPayloadV4(ctx context.Context, payload *mmTypes.ExecutionPayloadV3, versionedHashes []common.Hash, beaconRoot *common.Hash, requests mmTypes.ExecutionRequests, witness hexutil.Bytes) (*mmTypes.StatelessPayloadStatusV1, error) {
+	log := e.log.WithField("block_hash", payload.BlockHash)
+	e.latency.wait(log, "engine_executeStatelessPayload")
+	if !e.mockChain.forks.IsPrague(payload.Timestamp) {
+		return nil, &rpc.Error{Err: fmt.Errorf("engine_executeStatelessPayloadV4 requires a prague payload"), Id: int(api.UnsupportedFork)}
+	}
+	if versionedHashes == nil || beaconRoot == nil || requests == nil {
+		return nil, &rpc.Error{Err: fmt.Errorf("missing versioned hashes, parent beacon block root or execution requests"), Id: int(api.InvalidParams)}
+	}
+	if err := requests.Validate(); err != nil {
+		return nil, &rpc.Error{Err: fmt.Errorf("invalid execution requests: %v", err), Id: int(api.InvalidParams)}
+	}
+	if status := checkVersionedHashes(log, payload, versionedHashes); status != nil {
+		return statelessStatus(status), nil
+	}
+	return e.executeStateless(log, payload, payload.Withdrawals, beaconRoot, witness)
+}
@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"mergemock/api"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteStateless(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	account := TestAccount{key, crypto.PubkeyToAddress(key.PublicKey)}
+	genesisPath := newFundedGenesis(t, account.addr)
+	genesis, err := LoadGenesis(genesisPath, "")
+	require.NoError(t, err)
+	mc, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	require.NoError(t, mc.EnableWitnesses())
+
+	var block *ethTypes.Block
+	for i := 0; i < 2; i++ {
+		parent := mc.CurrentHeader()
+		block, err = mc.AddNewBlock(parent.Hash(), common.Address{1}, parent.Time+12, parent.GasLimit, TransactionsCreator{[]TestAccount{account}, dummyTxCreator}, common.Hash{}, nil, nil, nil, nil, true)
+		require.NoError(t, err)
+		require.Len(t, block.Transactions(), 1)
+	}
+	witness, ok := mc.Witness(block.Hash())
+	require.True(t, ok)
+	require.Equal(t, block.ParentHash(), witness.Headers[0].Hash())
+	require.NotEmpty(t, witness.State)
+
+	// the witness round-trips through its opaque encoding
+	opaque, err := witness.Encode()
+	require.NoError(t, err)
+	decoded, err := types.DecodeExecutionWitness(opaque)
+	require.NoError(t, err)
+	require.Equal(t, witness.Nodes(), decoded.Nodes())
+
+	// a chain without the state of the parent computes the block from the witness
+	payload, err := api.BlockToPayloadV3(block, nil)
+	require.NoError(t, err)
+	stateless, err := NewMockChain(logrus.New(), &ExecutionConsensusMock{log: logrus.New()}, genesis, rawdb.NewMemoryDatabase(), &TraceLogConfig{})
+	require.NoError(t, err)
+	computed, err := stateless.ExecuteStateless(payload.V1(), nil, nil, decoded)
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), computed.Hash())
+	require.Equal(t, block.Root(), computed.Root())
+
+	// missing trie nodes are not taken from the chain
+	incomplete := *decoded
+	incomplete.State = decoded.State[:len(decoded.State)-1]
+	_, err = stateless.ExecuteStateless(payload.V1(), nil, nil, &incomplete)
+	require.Error(t, err)
+	incomplete.Headers = decoded.Headers[1:]
+	_, err = stateless.ExecuteStateless(payload.V1(), nil, nil, &incomplete)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no parent header")
+
+	// the engine executes the payload of a block it does not have
+	engine := newTestEngine(t, genesisPath)
+	ctx := context.Background()
+	res, err := engine.backend.ExecuteStatelessPayloadV1(ctx, payload.V1(), opaque)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionValid, res.Status)
+	require.Equal(t, block.Root(), res.StateRoot)
+	require.Equal(t, block.ReceiptHash(), res.ReceiptsRoot)
+
+	// a payload with another state root, and a block hash to match, is invalid
+	header := ethTypes.CopyHeader(block.Header())
+	header.Root = common.Hash{1}
+	wrong, err := api.BlockToPayloadV3(ethTypes.NewBlockWithHeader(header).WithBody(block.Transactions(), nil), nil)
+	require.NoError(t, err)
+	res, err = engine.backend.ExecuteStatelessPayloadV1(ctx, wrong.V1(), opaque)
+	require.NoError(t, err)
+	require.Equal(t, types.ExecutionInvalid, res.Status)
+	require.Equal(t, block.Root(), res.StateRoot)
+
+	_, err = engine.backend.ExecuteStatelessPayloadV1(ctx, payload.V1(), []byte{0x01})
+	require.Error(t, err)
+	_, err = engine.backend.ExecuteStatelessPayloadV2(ctx, payload.V2(), opaque)
+	require.NoError(t, err)
+}
@@ -0,0 +1,221 @@
+// Package mocktest runs the mocks of mergemock in-process on local listeners, like httptest
+// servers, for go test suites of client libraries to test against without external processes.
+// Every mock is stopped, and its files removed, when the test ends.
+package mocktest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mergemock/builderclient"
+	"mergemock/engineclient"
+	"mergemock/mock"
+	"mergemock/rpc"
+	"mergemock/types"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+// logLevel is the log level of the mocks, to keep the output of go test readable.
+const logLevel = "warn"
+
+// Genesis returns a genesis merged at genesis, with the faucet funded.
+func Genesis(faucet common.Address) *core.Genesis {
+	genesis := core.DeveloperGenesisBlock(5, 30_000_000, faucet)
+	genesis.Config.MergeForkBlock = common.Big0
+	genesis.Config.TerminalTotalDifficulty = common.Big0
+	return genesis
+}
+
+// WriteGenesis writes the genesis to a file of the test, and returns its path.
+func WriteGenesis(t testing.TB, genesis *core.Genesis) string {
+	t.Helper()
+	data, err := genesis.MarshalJSON()
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "genesis.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write genesis: %v", err)
+	}
+	return path
+}
+
+// WriteJwtSecret writes a new JWT secret to a file of the test, and returns its path.
+func WriteJwtSecret(t testing.TB) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwt.hex")
+	if err := os.WriteFile(path, []byte(common.Bytes2Hex(crypto.Keccak256([]byte(path)))), 0644); err != nil {
+		t.Fatalf("failed to write JWT secret: %v", err)
+	}
+	return path
+}
+
+// listen returns a listener on a free local port, with its URL. The mock that serves it closes it.
+func listen(t testing.TB) (net.Listener, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	return l, "http://" + l.Addr().String()
+}
+
+// dial returns a client of the engine API at the URL with the JWT secret at the path, closed when
+// the test ends.
+func dial(t testing.TB, url string, secretPath string) *engineclient.Client {
+	t.Helper()
+	raw, err := ioutil.ReadFile(secretPath)
+	if err != nil {
+		t.Fatalf("failed to read JWT secret: %v", err)
+	}
+	client, err := rpc.DialContext(context.Background(), url, common.FromHex(string(raw)))
+	if err != nil {
+		t.Fatalf("failed to dial engine: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return engineclient.New(client, logrus.StandardLogger())
+}
+
+// Engine is an engine mock, with a client of its engine API.
+type Engine struct {
+	*mock.EngineCmd
+	URL    string
+	Client *engineclient.Client
+}
+
+// NewEngine runs an engine mock of a new genesis, merged at genesis, without websocket. The
+// options change the mock before it runs.
+func NewEngine(t testing.TB, opts ...func(*mock.EngineCmd)) *Engine {
+	t.Helper()
+	cmd := mock.NewEngineCmd()
+	cmd.GenesisPath = WriteGenesis(t, Genesis(common.Address{}))
+	cmd.JwtSecretPath = WriteJwtSecret(t)
+	cmd.WebsocketAddr = ""
+	cmd.LogLvl = logLevel
+	for _, opt := range opts {
+		opt(cmd)
+	}
+	l, url := listen(t)
+	cmd.Listener = l
+	if err := cmd.Run(context.Background()); err != nil {
+		l.Close()
+		t.Fatalf("failed to run engine: %v", err)
+	}
+	t.Cleanup(func() { cmd.Close() })
+	return &Engine{EngineCmd: cmd, URL: url, Client: dial(t, url, cmd.JwtSecretPath)}
+}
+
+// Relay is a relay mock, with a client of its builder API, and of the engine API of its engine.
+type Relay struct {
+	*mock.RelayCmd
+	URL       string
+	Builder   *builderclient.Client
+	EngineURL string
+	Engine    *engineclient.Client
+}
+
+// NewRelay runs a relay mock with an engine of a new genesis, merged at genesis, without
+// websocket. The options change the mock before it runs.
+func NewRelay(t testing.TB, opts ...func(*mock.RelayCmd)) *Relay {
+	t.Helper()
+	cmd := mock.NewRelayCmd()
+	cmd.GenesisPath = WriteGenesis(t, Genesis(common.Address{}))
+	cmd.JwtSecretPath = WriteJwtSecret(t)
+	cmd.EngineListenAddrWs = ""
+	cmd.LogLvl = logLevel
+	for _, opt := range opts {
+		opt(cmd)
+	}
+	l, url := listen(t)
+	engineListener, engineURL := listen(t)
+	cmd.Listener, cmd.EngineListener = l, engineListener
+	if err := cmd.Run(context.Background()); err != nil {
+		l.Close()
+		engineListener.Close()
+		t.Fatalf("failed to run relay: %v", err)
+	}
+	t.Cleanup(func() { cmd.Close() })
+	return &Relay{
+		RelayCmd:  cmd,
+		URL:       url,
+		Builder:   builderclient.New(url, logrus.StandardLogger()),
+		EngineURL: engineURL,
+		Engine:    dial(t, engineURL, cmd.JwtSecretPath),
+	}
+}
+
+// Consensus is a consensus mock driving an engine mock, with the slots as they start, and a
+// client of its beacon API.
+type Consensus struct {
+	*mock.ConsensusCmd
+	BeaconURL string
+	Slots     <-chan mock.SlotEvent
+}
+
+// NewConsensus runs a consensus mock of the genesis of the engine, with the beacon genesis now,
+// and the beacon API. The options change the mock before it runs.
+func NewConsensus(t testing.TB, engine *Engine, opts ...func(*mock.ConsensusCmd)) *Consensus {
+	t.Helper()
+	cmd := mock.NewConsensusCmd()
+	cmd.EngineAddr = engine.URL
+	cmd.GenesisPath = engine.GenesisPath
+	cmd.JwtSecretPath = engine.JwtSecretPath
+	cmd.DepositContract = engine.DepositContract
+	cmd.BeaconGenesisTime = uint64(time.Now().Unix())
+	cmd.LogLvl = logLevel
+	slots := make(chan mock.SlotEvent, 64)
+	cmd.Slots = slots
+	for _, opt := range opts {
+		opt(cmd)
+	}
+	l, url := listen(t)
+	cmd.BeaconListener = l
+	if err := cmd.Run(context.Background()); err != nil {
+		l.Close()
+		t.Fatalf("failed to run consensus: %v", err)
+	}
+	t.Cleanup(func() { cmd.Close() })
+	return &Consensus{ConsensusCmd: cmd, BeaconURL: url, Slots: slots}
+}
+
+// OptimisticUpdate returns the latest light client optimistic update of the beacon API.
+func (c *Consensus) OptimisticUpdate(ctx context.Context) (*types.LightClientOptimisticUpdate, error) {
+	update := new(types.LightClientOptimisticUpdate)
+	return update, c.getBeacon(ctx, "/eth/v1/beacon/light_client/optimistic_update", update)
+}
+
+// FinalityUpdate returns the latest light client finality update of the beacon API.
+func (c *Consensus) FinalityUpdate(ctx context.Context) (*types.LightClientFinalityUpdate, error) {
+	update := new(types.LightClientFinalityUpdate)
+	return update, c.getBeacon(ctx, "/eth/v1/beacon/light_client/finality_update", update)
+}
+
+// getBeacon decodes the data of the response of the beacon API to the request of the path.
+func (c *Consensus) getBeacon(ctx context.Context, path string, data interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BeaconURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("beacon API returned status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(&struct {
+		Data interface{} `json:"data"`
+	}{data})
+}
@@ -0,0 +1,58 @@
+package mocktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mergemock/mock"
+	"mergemock/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine(t *testing.T) {
+	engine := NewEngine(t)
+	var head map[string]interface{}
+	require.NoError(t, engine.Client.CallContext(context.Background(), &head, "eth_getBlockByNumber", "latest", false))
+	require.Equal(t, "0x0", head["number"])
+}
+
+func TestRelay(t *testing.T) {
+	relay := NewRelay(t, func(r *mock.RelayCmd) { r.Gating.Disable = true })
+	var head map[string]interface{}
+	require.NoError(t, relay.Engine.CallContext(context.Background(), &head, "eth_getBlockByNumber", "latest", false))
+	parent := common.HexToHash(head["hash"].(string))
+	timestamp, err := hexutil.DecodeUint64(head["timestamp"].(string))
+	require.NoError(t, err)
+
+	// the relay bids on the payloads its engine builds
+	_, err = relay.Engine.ForkchoiceUpdatedV1(context.Background(), parent, parent, parent, &types.PayloadAttributesV1{
+		Timestamp:             timestamp + 1,
+		PrevRandao:            common.Hash{0x01},
+		SuggestedFeeRecipient: common.Address{0x02},
+	})
+	require.NoError(t, err)
+	bid, err := relay.Builder.GetHeader(context.Background(), 1, parent, types.PublicKey{0x01})
+	require.NoError(t, err)
+	parentHash, err := bid.ParentHash()
+	require.NoError(t, err)
+	require.Equal(t, parent, common.Hash(parentHash))
+}
+
+func TestConsensus(t *testing.T) {
+	engine := NewEngine(t)
+	consensus := NewConsensus(t, engine, func(c *mock.ConsensusCmd) { c.SlotTime = 200 * time.Millisecond })
+	select {
+	case slot := <-consensus.Slots:
+		require.NotZero(t, slot.Slot)
+	case <-time.After(10 * time.Second):
+		t.Fatal("no slot started")
+	}
+	require.Eventually(t, func() bool {
+		update, err := consensus.OptimisticUpdate(context.Background())
+		return err == nil && update.SignatureSlot > 0
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the values of redacted fields in logged bodies.
+const redacted = "[redacted]"
+
+// AccessLog logs a sample of requests with their sizes, latency and bodies, with the values of
+// sensitive fields redacted and long bodies truncated.
+type AccessLog struct {
+	sample    float64
+	bodyLimit int
+	redact    map[string]bool
+	log       logrus.Ext1FieldLogger
+}
+
+// NewAccessLog returns an access log of the fraction of requests, with bodies up to the limit
+// and the named JSON fields redacted, or nil if the fraction is 0.
+func NewAccessLog(sample float64, bodyLimit int, redact []string, log logrus.Ext1FieldLogger) *AccessLog {
+	if sample <= 0 {
+		return nil
+	}
+	fields := make(map[string]bool, len(redact))
+	for _, name := range redact {
+		fields[name] = true
+	}
+	return &AccessLog{sample: sample, bodyLimit: bodyLimit, redact: fields, log: log}
+}
+
+// Sampled returns whether to log the next request. A nil access log logs none.
+func (a *AccessLog) Sampled() bool {
+	return a != nil && (a.sample >= 1 || rand.Float64() < a.sample)
+}
+
+// Body returns the body to log: JSON with the redacted fields replaced, truncated to the limit,
+// or the size of other bodies.
+func (a *AccessLog) Body(body []byte) string {
+	if len(body) == 0 || a.bodyLimit <= 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+	out, err := json.Marshal(a.redactValue(v))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(body))
+	}
+	if len(out) > a.bodyLimit {
+		return string(out[:a.bodyLimit]) + "..."
+	}
+	return string(out)
+}
+
+// redactValue replaces the values of the redacted fields of the objects in the JSON value.
+func (a *AccessLog) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if a.redact[k] {
+				v[k] = redacted
+			} else {
+				v[k] = a.redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = a.redactValue(item)
+		}
+	}
+	return v
+}
+
+// Log logs a request with the fields.
+func (a *AccessLog) Log(msg string, fields logrus.Fields) {
+	a.log.WithFields(fields).Info(msg)
+}
+
+// logCall logs an engine call, with the params as request body and the result as response body.
+func (a *AccessLog) logCall(method string, args []interface{}, result json.RawMessage, err error, duration time.Duration) {
+	params, _ := json.Marshal(args)
+	fields := logrus.Fields{
+		"method":        method,
+		"durationMs":    duration.Milliseconds(),
+		"request_size":  len(params),
+		"response_size": len(result),
+		"request":       a.Body(params),
+		"response":      a.Body(result),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	a.Log("rpc: "+method, fields)
+}
+
+// SetAccessLog logs a sample of the engine calls of the client. It must be called before any calls.
+func (c *Client) SetAccessLog(access *AccessLog) {
+	c.access = access
+}
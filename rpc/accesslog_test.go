@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogBody(t *testing.T) {
+	require.Nil(t, NewAccessLog(0, 1024, nil, logrus.New()))
+	require.False(t, (*AccessLog)(nil).Sampled())
+
+	access := NewAccessLog(1, 1024, []string{"secret_key"}, logrus.New())
+	require.True(t, access.Sampled())
+	require.Equal(t, `[{"a":1,"secret_key":"[redacted]"},{"b":{"secret_key":"[redacted]"}}]`,
+		access.Body([]byte(`[{"secret_key":"0x01","a":1},{"b":{"secret_key":2}}]`)))
+	require.Equal(t, "<3 bytes>", access.Body([]byte{0x01, 0x02, 0x03}))
+	require.Equal(t, "", access.Body(nil))
+
+	access = NewAccessLog(1, 8, nil, logrus.New())
+	require.Equal(t, `{"a":"12...`, access.Body([]byte(`{"a":"1234567890"}`)))
+	access = NewAccessLog(1, 0, nil, logrus.New())
+	require.Equal(t, "", access.Body([]byte(`{"a":1}`)))
+}
+
+func TestAccessLogCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"status":"VALID"}}`)
+	}))
+	defer srv.Close()
+	client, err := DialContext(context.Background(), srv.URL, []byte{})
+	require.NoError(t, err)
+	log, hook := test.NewNullLogger()
+	client.SetAccessLog(NewAccessLog(1, 1024, nil, log))
+
+	var result struct {
+		Status string `json:"status"`
+	}
+	require.NoError(t, client.CallContext(context.Background(), &result, "engine_newPayloadV1", map[string]string{"blockHash": "0x01"}))
+	require.Equal(t, "VALID", result.Status)
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	require.Equal(t, "rpc: engine_newPayloadV1", entry.Message)
+	require.Equal(t, `[{"blockHash":"0x01"}]`, entry.Data["request"])
+	require.Equal(t, `{"status":"VALID"}`, entry.Data["response"])
+	require.Equal(t, 18, entry.Data["response_size"])
+}
@@ -2,8 +2,11 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/rpc"
@@ -12,7 +15,16 @@ import (
 
 type Client struct {
 	inner  *rpc.Client
+	url    string
 	secret []byte
+
+	limits   *Limits
+	mu       sync.Mutex
+	limiters map[string]*methodLimiter
+
+	schemas    *SchemaChecker
+	access     *AccessLog
+	transcript *Transcript
 }
 
 func DialContext(ctx context.Context, rawurl string, secret []byte) (*Client, error) {
@@ -32,20 +44,74 @@ func DialContext(ctx context.Context, rawurl string, secret []byte) (*Client, er
 	if err != nil {
 		return nil, err
 	}
-	return &Client{client, secret}, nil
+	return &Client{inner: client, url: rawurl, secret: secret}, nil
 }
 
+// Redial replaces the connection of the client with a new one to the same URL, over a new
+// transport, so calls after it do not reuse the connections of calls stuck on the server.
+func (c *Client) Redial() error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	inner, err := rpc.DialHTTPWithClient(c.url, &http.Client{Transport: transport})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	old := c.inner
+	c.inner = inner
+	c.mu.Unlock()
+	old.Close()
+	return nil
+}
+
+// rpcClient returns the connection of the client.
+func (c *Client) rpcClient() *rpc.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inner
+}
+
+// CallContext calls the method, once its limit of concurrent calls allows.
 func (c *Client) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	limits := c.limits
+	if limits == nil {
+		limits = &Limits{}
+	}
+	release, err := c.limiter(method).acquire(ctx, limits, limits.concurrency(method))
+	if err != nil {
+		return err
+	}
+	defer release()
 	token, err := IssueJwtToken().SignedString(c.secret)
 	if err != nil {
 		return err
 	}
-	c.inner.SetHeader("Authorization", EncodeJwtAuthorization(token))
-	return c.inner.CallContext(ctx, result, method, args...)
+	inner := c.rpcClient()
+	inner.SetHeader("Authorization", EncodeJwtAuthorization(token))
+	sampled := c.access.Sampled()
+	if c.schemas == nil && !sampled && c.transcript == nil {
+		return inner.CallContext(ctx, result, method, args...)
+	}
+	var raw json.RawMessage
+	start := time.Now()
+	err = inner.CallContext(ctx, &raw, method, args...)
+	if sampled {
+		c.access.logCall(method, args, raw, err, time.Since(start))
+	}
+	c.transcript.record(method, args, raw, err, start)
+	if err != nil {
+		return err
+	}
+	if err := c.schemas.Check(method, raw); err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
 }
 
 func (c *Client) Close() {
-	c.inner.Close()
+	c.rpcClient().Close()
 }
 
 // IssueJwtToken creates a new token with IssuedAt set to time.Now().
@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// OverflowPolicy decides what happens to a call when the queue of its method is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock has the call wait in the queue regardless.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest fails the call that waited the longest, to make room.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+)
+
+var ErrDropped = errors.New("engine call dropped from full queue")
+
+// Limits limits the concurrent calls per method. Calls over the limit wait in a queue.
+type Limits struct {
+	// Concurrency is the max concurrent calls of a method, 0 for no limit.
+	Concurrency int
+	// Methods overrides the concurrency of specific methods.
+	Methods map[string]int
+	// Queue is the max calls of a method waiting, before the overflow policy applies.
+	Queue    int
+	Overflow OverflowPolicy
+}
+
+func (l *Limits) concurrency(method string) int {
+	if n, ok := l.Methods[method]; ok {
+		return n
+	}
+	return l.Concurrency
+}
+
+// MethodMetrics are the metrics of the calls of a method.
+type MethodMetrics struct {
+	Calls   uint64 `json:"calls"`
+	Dropped uint64 `json:"dropped"`
+	// Active is the number of calls in progress, and Queued the number waiting.
+	Active    int `json:"active"`
+	Queued    int `json:"queued"`
+	MaxQueued int `json:"maxQueued"`
+}
+
+// methodLimiter limits the concurrent calls of a method, with a queue of waiting calls which
+// are granted a turn, or dropped, through their channel.
+type methodLimiter struct {
+	mu      sync.Mutex
+	waiting []chan error
+	metrics MethodMetrics
+}
+
+// acquire waits for the turn of a call, and returns the function to end it with.
+func (l *methodLimiter) acquire(ctx context.Context, limits *Limits, max int) (func(), error) {
+	l.mu.Lock()
+	l.metrics.Calls++
+	if max <= 0 || (l.metrics.Active < max && len(l.waiting) == 0) {
+		l.metrics.Active++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+	if limits.Queue > 0 && len(l.waiting) >= limits.Queue && limits.Overflow == OverflowDropOldest {
+		l.waiting[0] <- ErrDropped
+		l.waiting = l.waiting[1:]
+		l.metrics.Dropped++
+	}
+	turn := make(chan error, 1)
+	l.waiting = append(l.waiting, turn)
+	l.updateQueued()
+	l.mu.Unlock()
+
+	select {
+	case err := <-turn:
+		if err != nil {
+			return nil, err
+		}
+		return l.release, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, w := range l.waiting {
+			if w == turn {
+				l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+				l.updateQueued()
+				return nil, ctx.Err()
+			}
+		}
+		// granted or dropped meanwhile
+		if err := <-turn; err == nil {
+			l.releaseLocked()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (l *methodLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+// releaseLocked hands the turn of an ending call to the next waiting one.
+func (l *methodLimiter) releaseLocked() {
+	if len(l.waiting) == 0 {
+		l.metrics.Active--
+		return
+	}
+	l.waiting[0] <- nil
+	l.waiting = l.waiting[1:]
+	l.updateQueued()
+}
+
+func (l *methodLimiter) updateQueued() {
+	l.metrics.Queued = len(l.waiting)
+	if l.metrics.Queued > l.metrics.MaxQueued {
+		l.metrics.MaxQueued = l.metrics.Queued
+	}
+}
+
+// SetLimits limits the concurrent calls of the client. It must be called before any calls.
+func (c *Client) SetLimits(limits Limits) error {
+	switch limits.Overflow {
+	case OverflowBlock, OverflowDropOldest:
+	default:
+		return fmt.Errorf("unrecognized overflow policy: %q", limits.Overflow)
+	}
+	c.limits = &limits
+	return nil
+}
+
+func (c *Client) limiter(method string) *methodLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limiters == nil {
+		c.limiters = make(map[string]*methodLimiter)
+	}
+	l, ok := c.limiters[method]
+	if !ok {
+		l = new(methodLimiter)
+		c.limiters[method] = l
+	}
+	return l
+}
+
+// Metrics returns the metrics of the calls by method.
+func (c *Client) Metrics() map[string]MethodMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metrics := make(map[string]MethodMetrics, len(c.limiters))
+	for method, l := range c.limiters {
+		l.mu.Lock()
+		metrics[method] = l.metrics
+		l.mu.Unlock()
+	}
+	return metrics
+}
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterQueue(t *testing.T) {
+	var l methodLimiter
+	limits := &Limits{Overflow: OverflowBlock}
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, limits, 1)
+	require.NoError(t, err)
+	done := make(chan struct{})
+	go func() {
+		r, err := l.acquire(ctx, limits, 1)
+		require.NoError(t, err)
+		r()
+		close(done)
+	}()
+	require.Eventually(t, func() bool { l.mu.Lock(); defer l.mu.Unlock(); return l.metrics.Queued == 1 }, time.Second, time.Millisecond)
+	release()
+	<-done
+	require.Equal(t, MethodMetrics{Calls: 2, MaxQueued: 1}, l.metrics)
+}
+
+func TestLimiterDropOldest(t *testing.T) {
+	var l methodLimiter
+	limits := &Limits{Queue: 1, Overflow: OverflowDropOldest}
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, limits, 1)
+	require.NoError(t, err)
+	oldest := make(chan error)
+	go func() {
+		_, err := l.acquire(ctx, limits, 1)
+		oldest <- err
+	}()
+	require.Eventually(t, func() bool { l.mu.Lock(); defer l.mu.Unlock(); return l.metrics.Queued == 1 }, time.Second, time.Millisecond)
+	newest := make(chan error)
+	go func() {
+		r, err := l.acquire(ctx, limits, 1)
+		if err == nil {
+			r()
+		}
+		newest <- err
+	}()
+	require.ErrorIs(t, <-oldest, ErrDropped)
+	release()
+	require.NoError(t, <-newest)
+	require.Equal(t, uint64(1), l.metrics.Dropped)
+	require.Equal(t, 0, l.metrics.Active)
+
+	// a call that gives up leaves the queue
+	release, err = l.acquire(ctx, limits, 1)
+	require.NoError(t, err)
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(timeout, limits, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 0, l.metrics.Queued)
+	release()
+	require.Equal(t, 0, l.metrics.Active)
+}
@@ -0,0 +1,361 @@
+package rpc
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// engineSchemas is the part of the execution-apis OpenRPC document with the results of the
+// engine methods the consensus mock calls, and the schemas they refer to.
+//
+//go:embed schemas/engine.json
+var engineSchemas []byte
+
+// builderSchemas is the part of the builder-specs OpenAPI document with the responses of the
+// builder API that have a body, and the schemas they refer to.
+//
+//go:embed schemas/builder.json
+var builderSchemas []byte
+
+// SchemaCheck decides what happens to responses that do not match their schema.
+type SchemaCheck string
+
+const (
+	// SchemaOff does not check responses.
+	SchemaOff SchemaCheck = "off"
+	// SchemaWarn logs the violations, and uses the response anyway.
+	SchemaWarn SchemaCheck = "warn"
+	// SchemaError fails the call.
+	SchemaError SchemaCheck = "error"
+)
+
+var ErrSchema = errors.New("response does not match the schema of the spec")
+
+// SchemaChecker checks responses against their schemas.
+type SchemaChecker struct {
+	schemas *Schemas
+	check   SchemaCheck
+	log     logrus.Ext1FieldLogger
+}
+
+// NewSchemaChecker returns a checker of responses against the loaded schemas, which logs
+// violations to log in warn mode, or nil if the check is off.
+func NewSchemaChecker(check SchemaCheck, load func() (*Schemas, error), log logrus.Ext1FieldLogger) (*SchemaChecker, error) {
+	switch check {
+	case SchemaOff:
+		return nil, nil
+	case SchemaWarn, SchemaError:
+	default:
+		return nil, fmt.Errorf("unrecognized schema check: %q", check)
+	}
+	schemas, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaChecker{schemas: schemas, check: check, log: log}, nil
+}
+
+// Check returns the violations of the schema of the named method or operation by the
+// response in error mode, and logs them in warn mode. A nil checker accepts any response.
+func (c *SchemaChecker) Check(name string, response []byte) error {
+	if c == nil {
+		return nil
+	}
+	err := c.schemas.Validate(name, response)
+	if err == nil || c.check == SchemaError {
+		return err
+	}
+	c.log.WithError(err).Warn("Response does not conform to the spec")
+	return nil
+}
+
+// SetSchemaCheck validates the engine responses of the client against the execution-apis
+// schemas. It must be called before any calls.
+func (c *Client) SetSchemaCheck(check SchemaCheck, log logrus.Ext1FieldLogger) error {
+	checker, err := NewSchemaChecker(check, EngineSchemas, log)
+	if err != nil {
+		return err
+	}
+	c.schemas = checker
+	return nil
+}
+
+// schema is the subset of JSON schema the execution-apis use.
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Required   []string           `json:"required"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	Pattern    string             `json:"pattern"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	Enum       []string           `json:"enum"`
+	OneOf      []*schema          `json:"oneOf"`
+	AnyOf      []*schema          `json:"anyOf"`
+
+	pattern *regexp.Regexp
+}
+
+type openRPC struct {
+	Methods []struct {
+		Name   string `json:"name"`
+		Result struct {
+			Schema *schema `json:"schema"`
+		} `json:"result"`
+	} `json:"methods"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPI struct {
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+		Responses   map[string]struct {
+			Content map[string]struct {
+				Schema *schema `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Schemas are the schemas of the responses of methods or operations, to validate them against.
+type Schemas struct {
+	results    map[string]*schema
+	components map[string]*schema
+}
+
+// EngineSchemas returns the embedded result schemas of the engine methods.
+func EngineSchemas() (*Schemas, error) {
+	return LoadOpenRPCSchemas(engineSchemas)
+}
+
+// BuilderSchemas returns the embedded schemas of the builder API responses, by operation id.
+func BuilderSchemas() (*Schemas, error) {
+	return LoadOpenAPISchemas(builderSchemas)
+}
+
+// LoadOpenRPCSchemas loads the result schemas of the methods of an OpenRPC document.
+func LoadOpenRPCSchemas(doc []byte) (*Schemas, error) {
+	var o openRPC
+	if err := json.Unmarshal(doc, &o); err != nil {
+		return nil, fmt.Errorf("invalid OpenRPC document: %w", err)
+	}
+	results := make(map[string]*schema)
+	for _, m := range o.Methods {
+		results[m.Name] = m.Result.Schema
+	}
+	return newSchemas(results, o.Components.Schemas)
+}
+
+// LoadOpenAPISchemas loads the schemas of the successful JSON responses of the operations of
+// an OpenAPI document, by operation id.
+func LoadOpenAPISchemas(doc []byte) (*Schemas, error) {
+	var o openAPI
+	if err := json.Unmarshal(doc, &o); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	results := make(map[string]*schema)
+	for _, methods := range o.Paths {
+		for _, op := range methods {
+			if content, ok := op.Responses["200"].Content["application/json"]; ok {
+				results[op.OperationID] = content.Schema
+			}
+		}
+	}
+	return newSchemas(results, o.Components.Schemas)
+}
+
+func newSchemas(results, components map[string]*schema) (*Schemas, error) {
+	s := &Schemas{results: results, components: components}
+	for _, sch := range s.results {
+		if err := s.compile(sch); err != nil {
+			return nil, err
+		}
+	}
+	for name, sch := range s.components {
+		if err := s.compile(sch); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+// compile compiles the patterns of the schema, and checks its references resolve.
+func (s *Schemas) compile(sch *schema) error {
+	if sch == nil {
+		return nil
+	}
+	if sch.Ref != "" {
+		if _, err := s.resolve(sch.Ref); err != nil {
+			return err
+		}
+	}
+	if sch.Pattern != "" && sch.pattern == nil {
+		re, err := regexp.Compile(sch.Pattern)
+		if err != nil {
+			return err
+		}
+		sch.pattern = re
+	}
+	subs := append(append([]*schema{sch.Items}, sch.OneOf...), sch.AnyOf...)
+	for _, p := range sch.Properties {
+		subs = append(subs, p)
+	}
+	for _, sub := range subs {
+		if err := s.compile(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schemas) resolve(ref string) (*schema, error) {
+	name := strings.TrimPrefix(ref, "#/components/schemas/")
+	sch, ok := s.components[name]
+	if !ok || name == ref {
+		return nil, fmt.Errorf("unknown schema reference %q", ref)
+	}
+	return sch, nil
+}
+
+// Validate returns an error listing where the response to the method or operation does not
+// match its schema, nil if it does or there is no schema for it.
+func (s *Schemas) Validate(method string, result json.RawMessage) error {
+	sch, ok := s.results[method]
+	if !ok {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(result))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrSchema, method, err)
+	}
+	violations := s.validate(sch, v, "$")
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s: %s", ErrSchema, method, strings.Join(violations, "; "))
+}
+
+// validate returns the violations of the schema by the value at the path.
+func (s *Schemas) validate(sch *schema, v interface{}, path string) []string {
+	if sch.Ref != "" {
+		ref, _ := s.resolve(sch.Ref)
+		return s.validate(ref, v, path)
+	}
+	if len(sch.OneOf) > 0 {
+		matches := 0
+		for _, sub := range sch.OneOf {
+			if len(s.validate(sub, v, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of the oneOf schemas, not exactly one", path, matches)}
+		}
+	}
+	if len(sch.AnyOf) > 0 {
+		var all []string
+		for _, sub := range sch.AnyOf {
+			violations := s.validate(sub, v, path)
+			if len(violations) == 0 {
+				all = nil
+				break
+			}
+			all = append(all, violations...)
+		}
+		if len(all) > 0 {
+			return []string{fmt.Sprintf("%s: matches none of the anyOf schemas (%s)", path, strings.Join(all, ", "))}
+		}
+	}
+	if sch.Type != "" && jsonType(v) != sch.Type {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, sch.Type, jsonType(v))}
+	}
+	var violations []string
+	switch v := v.(type) {
+	case string:
+		if sch.pattern != nil && !sch.pattern.MatchString(v) {
+			violations = append(violations, fmt.Sprintf("%s: %s does not match %s", path, abbreviate(v), sch.Pattern))
+		}
+		if sch.MinLength != nil && len(v) < *sch.MinLength {
+			violations = append(violations, fmt.Sprintf("%s: %d characters, under %d", path, len(v), *sch.MinLength))
+		}
+		if sch.MaxLength != nil && len(v) > *sch.MaxLength {
+			violations = append(violations, fmt.Sprintf("%s: %d characters, over %d", path, len(v), *sch.MaxLength))
+		}
+		if len(sch.Enum) > 0 && !contains(sch.Enum, v) {
+			violations = append(violations, fmt.Sprintf("%s: %q is not one of %s", path, v, strings.Join(sch.Enum, ", ")))
+		}
+	case map[string]interface{}:
+		for _, name := range sch.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing field %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := sch.Properties[name]; ok {
+				violations = append(violations, s.validate(p, v[name], path+"."+name)...)
+			}
+		}
+	case []interface{}:
+		if sch.Items != nil {
+			for i, item := range v {
+				violations = append(violations, s.validate(sch.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return violations
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// abbreviate shortens long values, like blobs, in violations.
+func abbreviate(s string) string {
+	if len(s) > 80 {
+		return fmt.Sprintf("%q...", s[:80])
+	}
+	return fmt.Sprintf("%q", s)
+}
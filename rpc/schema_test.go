@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"mergemock/types"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngineSchemas(t *testing.T) {
+	schemas, err := EngineSchemas()
+	require.NoError(t, err)
+
+	// the responses of the engine mock conform
+	hash := common.Hash{0x01}
+	status, err := json.Marshal(&types.PayloadStatusV1{Status: types.ExecutionValid, LatestValidHash: &hash})
+	require.NoError(t, err)
+	require.NoError(t, schemas.Validate("engine_newPayloadV3", status))
+	payload, err := json.Marshal(&types.GetPayloadV3Response{
+		ExecutionPayload: &types.ExecutionPayloadV3{
+			BaseFeePerGas: big.NewInt(7),
+			Transactions:  [][]byte{{0x02, 0x01}},
+			Withdrawals:   types.Withdrawals{{Index: 1, Validator: 2, Amount: 3}},
+		},
+		BlockValue:  (*hexutil.Big)(big.NewInt(0)),
+		BlobsBundle: &types.BlobsBundleV1{Commitments: []hexutil.Bytes{}, Proofs: []hexutil.Bytes{}, Blobs: []hexutil.Bytes{}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, schemas.Validate("engine_getPayloadV3", payload))
+
+	// methods without a schema are not checked
+	require.NoError(t, schemas.Validate("eth_getBlockByNumber", json.RawMessage(`{"number":"0x01"}`)))
+
+	cases := []struct {
+		method, result, violation string
+	}{
+		{"engine_newPayloadV1", `{"latestValidHash":null}`, `$: missing field "status"`},
+		{"engine_newPayloadV1", `{"status":"valid"}`, `$.status: "valid" is not one of`},
+		{"engine_forkchoiceUpdatedV1", `{"payloadStatus":{"status":"ACCEPTED"}}`, `$.payloadStatus.status: "ACCEPTED" is not one of`},
+		{"engine_forkchoiceUpdatedV1", `{"payloadStatus":{"status":"VALID","latestValidHash":"0x01"}}`, `$.payloadStatus.latestValidHash: matches 0 of the oneOf schemas`},
+		{"engine_getPayloadV1", `{"blockNumber":"0x01"}`, `$.blockNumber: "0x01" does not match`},
+		{"engine_getPayloadV1", `{"gasLimit":"0xAB"}`, `$.gasLimit: "0xAB" does not match`},
+		{"engine_getPayloadV1", `{"transactions":"0x"}`, `$.transactions: expected array, got string`},
+		{"engine_exchangeCapabilities", `["engine_newPayloadV1",1]`, `$[1]: expected string, got number`},
+	}
+	for _, c := range cases {
+		err := schemas.Validate(c.method, json.RawMessage(c.result))
+		require.ErrorIs(t, err, ErrSchema, c.result)
+		require.Contains(t, err.Error(), c.method)
+		require.Contains(t, err.Error(), c.violation)
+	}
+
+	_, err = LoadOpenRPCSchemas([]byte(`{"methods":[{"name":"m","result":{"schema":{"$ref":"#/components/schemas/none"}}}]}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown schema reference")
+}
+
+func TestBuilderSchemas(t *testing.T) {
+	schemas, err := BuilderSchemas()
+	require.NoError(t, err)
+
+	header := &types.ExecutionPayloadHeader{BlockNumber: 1, BaseFeePerGas: types.IntToU256(7)}
+	bid, err := json.Marshal(&types.GetHeaderResponse{
+		Version: types.VersionBellatrix,
+		Data:    &types.SignedBuilderBid{Message: &types.BuilderBid{Header: header, Value: types.IntToU256(1)}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, schemas.Validate("getHeader", bid))
+
+	cases := []struct {
+		operation, response, violation string
+	}{
+		{"getHeader", `{"data":{}}`, `$: missing field "version"`},
+		{"getHeader", `{"version":"altair","data":{}}`, `$.version: "altair" is not one of`},
+		{"getHeader", `{"version":"capella","data":{"message":{"value":"0x01"}}}`, `$.data: matches none of the anyOf schemas`},
+		{"submitBlindedBlock", `{"version":"bellatrix","data":{"block_number":1}}`, `$.data.block_number: expected string, got number`},
+	}
+	for _, c := range cases {
+		err := schemas.Validate(c.operation, json.RawMessage(c.response))
+		require.ErrorIs(t, err, ErrSchema, c.response)
+		require.Contains(t, err.Error(), c.violation)
+	}
+
+	// a nil checker accepts anything, a warning checker logs and accepts
+	var checker *SchemaChecker
+	require.NoError(t, checker.Check("getHeader", []byte(`{}`)))
+	checker, err = NewSchemaChecker(SchemaWarn, BuilderSchemas, logrus.New())
+	require.NoError(t, err)
+	require.NoError(t, checker.Check("getHeader", []byte(`{}`)))
+	checker, err = NewSchemaChecker(SchemaError, BuilderSchemas, logrus.New())
+	require.NoError(t, err)
+	require.ErrorIs(t, checker.Check("getHeader", []byte(`{}`)), ErrSchema)
+	_, err = NewSchemaChecker("fail", BuilderSchemas, logrus.New())
+	require.Error(t, err)
+}
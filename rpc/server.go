@@ -49,8 +49,13 @@ func NewServer(namespace string, backend interface{}, authenticated bool) (*Serv
 	return srv, nil
 }
 
-func NewHTTPServer(ctx context.Context, log logrus.Ext1FieldLogger, rpcSrv *Server, addr string, timeout Timeout, cors []string) *http.Server {
+// NewHTTPServer serves the RPC server over HTTP. With strict params, requests to those methods
+// are checked with StrictHandler first.
+func NewHTTPServer(ctx context.Context, log logrus.Ext1FieldLogger, rpcSrv *Server, addr string, timeout Timeout, cors []string, strict StrictParams) *http.Server {
 	httpRpcHandler := node.NewHTTPHandlerStack(rpcSrv, cors, nil, nil)
+	if strict != nil {
+		httpRpcHandler = StrictHandler(httpRpcHandler, strict)
+	}
 	mux := http.NewServeMux()
 	mux.Handle("/", httpRpcHandler)
 	logHttp := log.WithField("type", "http")
@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"mergemock/types"
+)
+
+const invalidParamsCode = -32602
+
+// StrictParams maps JSON-RPC method names to the types of their params.
+type StrictParams map[string][]reflect.Type
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// MethodParams returns the param types of the exported methods of backend, under the names
+// the geth RPC server registers them with: <namespace>_<method with a lowercase first letter>.
+func MethodParams(namespace string, backend interface{}) StrictParams {
+	params := make(StrictParams)
+	typ := reflect.TypeOf(backend)
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		var args []reflect.Type
+		for j := 1; j < m.Type.NumIn(); j++ {
+			if j == 1 && m.Type.In(j) == contextType {
+				continue
+			}
+			args = append(args, m.Type.In(j))
+		}
+		name := []rune(m.Name)
+		name[0] = unicode.ToLower(name[0])
+		params[namespace+"_"+string(name)] = args
+	}
+	return params
+}
+
+type jsonRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type jsonError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonErrResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   jsonError       `json:"error"`
+}
+
+// StrictHandler checks the params of requests to the methods in params with types.CheckStrict,
+// and responds with an invalid params error instead of passing on the request if they do not
+// fit. Pointer params may be null.
+func StrictHandler(next http.Handler, params StrictParams) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			next.ServeHTTP(w, req)
+			return
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		var msgs []jsonRequest
+		batch := strings.HasPrefix(strings.TrimSpace(string(body)), "[")
+		if batch {
+			err = json.Unmarshal(body, &msgs)
+		} else {
+			msgs = make([]jsonRequest, 1)
+			err = json.Unmarshal(body, &msgs[0])
+		}
+		if err != nil {
+			// leave parse errors to the server
+			next.ServeHTTP(w, req)
+			return
+		}
+		for _, msg := range msgs {
+			args, ok := params[msg.Method]
+			if !ok {
+				continue
+			}
+			if err := checkParams(msg.Params, args); err != nil {
+				resp := jsonErrResponse{Version: "2.0", ID: msg.ID, Error: jsonError{Code: invalidParamsCode, Message: fmt.Sprintf("%s: %v", msg.Method, err)}}
+				w.Header().Set("Content-Type", "application/json")
+				if batch {
+					// the batch is rejected as a whole
+					resps := make([]jsonErrResponse, len(msgs))
+					for i := range msgs {
+						resps[i] = resp
+						resps[i].ID = msgs[i].ID
+					}
+					json.NewEncoder(w).Encode(resps)
+				} else {
+					json.NewEncoder(w).Encode(resp)
+				}
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func checkParams(params []json.RawMessage, args []reflect.Type) error {
+	if len(params) != len(args) {
+		return fmt.Errorf("expected %d params, got %d", len(args), len(params))
+	}
+	for i, p := range params {
+		if args[i].Kind() == reflect.Ptr && bytes.Equal(bytes.TrimSpace(p), []byte("null")) {
+			continue
+		}
+		if err := types.CheckStrict(p, args[i]); err != nil {
+			return fmt.Errorf("param %d: %w", i, err)
+		}
+	}
+	return nil
+}
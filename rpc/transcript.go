@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// TranscriptCall is a call of a transcript, with its params and result as sent and received.
+type TranscriptCall struct {
+	Time       time.Time       `json:"time"`
+	Method     string          `json:"method"`
+	Params     json.RawMessage `json:"params"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"durationMs"`
+}
+
+// Transcript keeps the last calls of a client in full, to reproduce failures with.
+type Transcript struct {
+	mu    sync.Mutex
+	size  int
+	calls []TranscriptCall
+}
+
+// NewTranscript returns a transcript of the last calls up to the size, or nil if the size is 0.
+func NewTranscript(size int) *Transcript {
+	if size <= 0 {
+		return nil
+	}
+	return &Transcript{size: size}
+}
+
+func (t *Transcript) record(method string, args []interface{}, result json.RawMessage, err error, start time.Time) {
+	if t == nil {
+		return
+	}
+	params, _ := json.Marshal(args)
+	call := TranscriptCall{
+		Time:       start,
+		Method:     method,
+		Params:     params,
+		Result:     append(json.RawMessage(nil), result...),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.calls) == t.size {
+		t.calls = t.calls[1:]
+	}
+	t.calls = append(t.calls, call)
+}
+
+// Calls returns the calls of the transcript, oldest first. A nil transcript has none.
+func (t *Transcript) Calls() []TranscriptCall {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TranscriptCall(nil), t.calls...)
+}
+
+// SetTranscript records the calls of the client in the transcript. It must be called before any
+// calls.
+func (c *Client) SetTranscript(transcript *Transcript) {
+	c.transcript = transcript
+}
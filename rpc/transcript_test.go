@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript(t *testing.T) {
+	require.Nil(t, NewTranscript(0))
+	require.Nil(t, (*Transcript)(nil).Calls())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"status":"VALID"}}`)
+	}))
+	defer srv.Close()
+	client, err := DialContext(context.Background(), srv.URL, []byte{})
+	require.NoError(t, err)
+	transcript := NewTranscript(2)
+	client.SetTranscript(transcript)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, client.CallContext(context.Background(), nil, "engine_newPayloadV1", map[string]int{"n": i}))
+	}
+	// the transcript keeps the last calls
+	calls := transcript.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, "engine_newPayloadV1", calls[0].Method)
+	require.JSONEq(t, `[{"n":2}]`, string(calls[0].Params))
+	require.JSONEq(t, `[{"n":3}]`, string(calls[1].Params))
+	require.JSONEq(t, `{"status":"VALID"}`, string(calls[1].Result))
+	require.Empty(t, calls[1].Error)
+}
@@ -0,0 +1,208 @@
+// Package slashing keeps the blocks validators signed in a slashing protection database, and
+// imports and exports it in the EIP-3076 interchange format, so the consensus mock refuses to
+// sign slashable blocks as validator clients do.
+package slashing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mergemock/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// InterchangeFormatVersion is the version of the EIP-3076 interchange format.
+const InterchangeFormatVersion = "5"
+
+// ErrSlashable is returned for blocks the database refuses to sign.
+var ErrSlashable = errors.New("slashable block")
+
+// SignedBlock is a block of the interchange format.
+type SignedBlock struct {
+	Slot        string      `json:"slot"`
+	SigningRoot *types.Root `json:"signing_root,omitempty"`
+}
+
+// SignedAttestation is an attestation of the interchange format.
+type SignedAttestation struct {
+	SourceEpoch string      `json:"source_epoch"`
+	TargetEpoch string      `json:"target_epoch"`
+	SigningRoot *types.Root `json:"signing_root,omitempty"`
+}
+
+// Record is the signing history of a validator in the interchange format.
+type Record struct {
+	Pubkey             types.PublicKey     `json:"pubkey"`
+	SignedBlocks       []SignedBlock       `json:"signed_blocks"`
+	SignedAttestations []SignedAttestation `json:"signed_attestations"`
+}
+
+// Interchange is an EIP-3076 slashing protection interchange file.
+type Interchange struct {
+	Metadata struct {
+		InterchangeFormatVersion string     `json:"interchange_format_version"`
+		GenesisValidatorsRoot    types.Root `json:"genesis_validators_root"`
+	} `json:"metadata"`
+	Data []Record `json:"data"`
+}
+
+// history is the signing history of a validator.
+type history struct {
+	// signing roots of the signed blocks by slot, zero if unknown
+	blocks map[uint64]types.Root
+	// attestations are kept to export them again, not checked
+	attestations []SignedAttestation
+}
+
+// DB is a slashing protection database of the blocks of validators, of one network.
+type DB struct {
+	mu                    sync.Mutex
+	genesisValidatorsRoot types.Root
+	validators            map[types.PublicKey]*history
+}
+
+// New returns an empty database of the network of the genesis validators root.
+func New(genesisValidatorsRoot types.Root) *DB {
+	return &DB{genesisValidatorsRoot: genesisValidatorsRoot, validators: make(map[types.PublicKey]*history)}
+}
+
+func (db *DB) history(pubkey types.PublicKey) *history {
+	h, ok := db.validators[pubkey]
+	if !ok {
+		h = &history{blocks: make(map[uint64]types.Root)}
+		db.validators[pubkey] = h
+	}
+	return h
+}
+
+// SignBlock records the block of the validator at the slot with the signing root, or returns
+// ErrSlashable if the validator signed another block at the slot, or a block at a later slot.
+// Signing the same block again is not slashable.
+func (db *DB) SignBlock(pubkey types.PublicKey, slot uint64, signingRoot types.Root) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	h := db.history(pubkey)
+	if root, ok := h.blocks[slot]; ok {
+		if root == signingRoot && root != (types.Root{}) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s already signed another block at slot %d", ErrSlashable, pubkey, slot)
+	}
+	for s := range h.blocks {
+		if s > slot {
+			return fmt.Errorf("%w: %s already signed a block at slot %d, after slot %d", ErrSlashable, pubkey, s, slot)
+		}
+	}
+	h.blocks[slot] = signingRoot
+	return nil
+}
+
+// Import merges the interchange file into the database, after checking it is of the network.
+func (db *DB) Import(r io.Reader) error {
+	var ic Interchange
+	if err := json.NewDecoder(r).Decode(&ic); err != nil {
+		return fmt.Errorf("invalid interchange file: %w", err)
+	}
+	if v := ic.Metadata.InterchangeFormatVersion; v != InterchangeFormatVersion {
+		return fmt.Errorf("unsupported interchange format version %q", v)
+	}
+	if ic.Metadata.GenesisValidatorsRoot != db.genesisValidatorsRoot {
+		return fmt.Errorf("interchange file of genesis validators root %s, not %s", ic.Metadata.GenesisValidatorsRoot, db.genesisValidatorsRoot)
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, rec := range ic.Data {
+		h := db.history(rec.Pubkey)
+		for _, b := range rec.SignedBlocks {
+			slot, err := strconv.ParseUint(b.Slot, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid slot %q of %s: %w", b.Slot, rec.Pubkey, err)
+			}
+			var root types.Root
+			if b.SigningRoot != nil {
+				root = *b.SigningRoot
+			}
+			// blocks of the same slot with different roots are kept as a block of unknown root
+			if prev, ok := h.blocks[slot]; ok && prev != root {
+				root = types.Root{}
+			}
+			h.blocks[slot] = root
+		}
+		h.attestations = append(h.attestations, rec.SignedAttestations...)
+	}
+	return nil
+}
+
+// Export writes the database as an interchange file, with the validators and blocks ordered.
+func (db *DB) Export(w io.Writer) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var ic Interchange
+	ic.Metadata.InterchangeFormatVersion = InterchangeFormatVersion
+	ic.Metadata.GenesisValidatorsRoot = db.genesisValidatorsRoot
+	ic.Data = make([]Record, 0, len(db.validators))
+	for pubkey, h := range db.validators {
+		rec := Record{Pubkey: pubkey, SignedBlocks: make([]SignedBlock, 0, len(h.blocks)), SignedAttestations: h.attestations}
+		if rec.SignedAttestations == nil {
+			rec.SignedAttestations = []SignedAttestation{}
+		}
+		slots := make([]uint64, 0, len(h.blocks))
+		for slot := range h.blocks {
+			slots = append(slots, slot)
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+		for _, slot := range slots {
+			b := SignedBlock{Slot: strconv.FormatUint(slot, 10)}
+			if root := h.blocks[slot]; root != (types.Root{}) {
+				b.SigningRoot = &root
+			}
+			rec.SignedBlocks = append(rec.SignedBlocks, b)
+		}
+		ic.Data = append(ic.Data, rec)
+	}
+	sort.Slice(ic.Data, func(i, j int) bool { return ic.Data[i].Pubkey.String() < ic.Data[j].Pubkey.String() })
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&ic)
+}
+
+// Load returns the database of the interchange file at the path, or an empty database if there
+// is no file yet.
+func Load(path string, genesisValidatorsRoot types.Root) (*DB, error) {
+	db := New(genesisValidatorsRoot)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := db.Import(f); err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Save exports the database to the path, replacing the file at once so a crash leaves the
+// previous export.
+func (db *DB) Save(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := db.Export(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
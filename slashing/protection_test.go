@@ -0,0 +1,93 @@
+package slashing
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mergemock/types"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the example of EIP-3076
+const testInterchange = `{
+	"metadata": {
+		"interchange_format_version": "5",
+		"genesis_validators_root": "0x04700007fabc8282644aed6d1c7c9e21d38a03a0c4ba193f3afe428824b3a673"
+	},
+	"data": [
+		{
+			"pubkey": "0xb845089a1457f811bfc000588fbb4e713669be8ce060ea6be3c6ece09afc3794106c91ca73acda5e5457122d58723bed",
+			"signed_blocks": [
+				{"slot": "81952", "signing_root": "0x4ff6f743a43f3b4f95350831aeaf0a122a1a392922c45d804280284a69eb850b"},
+				{"slot": "81951"}
+			],
+			"signed_attestations": [
+				{"source_epoch": "2290", "target_epoch": "3007", "signing_root": "0x587d6a4f59a58fe24f406e0502413e77fe1babddee641fda30034ed37ecc884d"},
+				{"source_epoch": "2290", "target_epoch": "3008"}
+			]
+		}
+	]
+}`
+
+func testDB(t *testing.T) (*DB, types.PublicKey) {
+	var gvr types.Root
+	require.NoError(t, gvr.UnmarshalText([]byte("0x04700007fabc8282644aed6d1c7c9e21d38a03a0c4ba193f3afe428824b3a673")))
+	var pk types.PublicKey
+	require.NoError(t, pk.UnmarshalText([]byte("0xb845089a1457f811bfc000588fbb4e713669be8ce060ea6be3c6ece09afc3794106c91ca73acda5e5457122d58723bed")))
+	db := New(gvr)
+	require.NoError(t, db.Import(strings.NewReader(testInterchange)))
+	return db, pk
+}
+
+func TestSignBlock(t *testing.T) {
+	db, pk := testDB(t)
+	var root types.Root
+	require.NoError(t, root.UnmarshalText([]byte("0x4ff6f743a43f3b4f95350831aeaf0a122a1a392922c45d804280284a69eb850b")))
+
+	// the same block again
+	require.NoError(t, db.SignBlock(pk, 81952, root))
+	// another block at the slot, and blocks at slots before the last block
+	require.True(t, errors.Is(db.SignBlock(pk, 81952, types.Root{0x01}), ErrSlashable))
+	require.True(t, errors.Is(db.SignBlock(pk, 81951, types.Root{0x01}), ErrSlashable))
+	require.True(t, errors.Is(db.SignBlock(pk, 100, types.Root{0x01}), ErrSlashable))
+	// blocks of unknown roots are never signed again
+	require.NoError(t, db.SignBlock(types.PublicKey{0x01}, 5, types.Root{}))
+	require.True(t, errors.Is(db.SignBlock(types.PublicKey{0x01}, 5, types.Root{}), ErrSlashable))
+
+	require.NoError(t, db.SignBlock(pk, 81953, types.Root{0x02}))
+	require.True(t, errors.Is(db.SignBlock(pk, 81953, types.Root{0x03}), ErrSlashable))
+	// other validators are independent
+	require.NoError(t, db.SignBlock(types.PublicKey{0x02}, 81952, types.Root{0x03}))
+}
+
+func TestExport(t *testing.T) {
+	db, pk := testDB(t)
+	require.NoError(t, db.SignBlock(pk, 81960, types.Root{0x02}))
+	path := filepath.Join(t.TempDir(), "slashing-protection.json")
+	require.NoError(t, db.Save(path))
+
+	loaded, err := Load(path, db.genesisValidatorsRoot)
+	require.NoError(t, err)
+	var a, b bytes.Buffer
+	require.NoError(t, db.Export(&a))
+	require.NoError(t, loaded.Export(&b))
+	require.Equal(t, a.String(), b.String())
+	require.True(t, errors.Is(loaded.SignBlock(pk, 81960, types.Root{0x03}), ErrSlashable))
+
+	// the blocks are ordered, and the attestations kept
+	require.Contains(t, a.String(), `"slot": "81951"`)
+	require.Less(t, strings.Index(a.String(), `"81951"`), strings.Index(a.String(), `"81952"`))
+	require.Contains(t, a.String(), `"target_epoch": "3008"`)
+
+	// a missing file is an empty database, another network is refused
+	empty, err := Load(filepath.Join(t.TempDir(), "missing.json"), db.genesisValidatorsRoot)
+	require.NoError(t, err)
+	require.NoError(t, empty.SignBlock(pk, 1, types.Root{0x01}))
+	_, err = Load(path, types.Root{0x01})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "genesis validators root")
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+var (
+	ErrBLSEncoding = errors.New("not a compressed BLS12-381 point")
+	ErrBLSInfinity = errors.New("point at infinity")
+	ErrBLSSubgroup = errors.New("point outside the BLS12-381 subgroup")
+)
+
+var strictBLS int32
+
+// SetStrictBLS sets whether unmarshalling a PublicKey or Signature checks that it is a valid
+// BLS12-381 point. The default is lenient: only the length is checked, like the hex of any
+// other fixed-size type, and invalid points are left to fail signature verification.
+func SetStrictBLS(strict bool) {
+	var v int32
+	if strict {
+		v = 1
+	}
+	atomic.StoreInt32(&strictBLS, v)
+}
+
+// StrictBLS returns whether unmarshalling checks public keys and signatures as BLS12-381 points.
+func StrictBLS() bool {
+	return atomic.LoadInt32(&strictBLS) == 1
+}
+
+// isInfinity returns whether b is the compressed encoding of the point at infinity: the
+// compression and infinity flags, and zeros.
+func isInfinity(b []byte) bool {
+	if b[0] != 0xc0 {
+		return false
+	}
+	for _, x := range b[1:] {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check returns an error unless the public key is a compressed G1 point of the subgroup, other
+// than the point at infinity, as relays require of validator keys.
+func (p PublicKey) Check() error {
+	if isInfinity(p[:]) {
+		return ErrBLSInfinity
+	}
+	point := new(blst.P1Affine).Uncompress(p[:])
+	if point == nil {
+		return ErrBLSEncoding
+	}
+	if !point.KeyValidate() {
+		return ErrBLSSubgroup
+	}
+	return nil
+}
+
+// Check returns an error unless the signature is a compressed G2 point of the subgroup, other
+// than the point at infinity, which is only valid as the aggregate of no signatures.
+func (s Signature) Check() error {
+	if isInfinity(s[:]) {
+		return ErrBLSInfinity
+	}
+	point := new(blst.P2Affine).Uncompress(s[:])
+	if point == nil {
+		return ErrBLSEncoding
+	}
+	if !point.SigValidate(false) {
+		return ErrBLSSubgroup
+	}
+	return nil
+}
+
+// setChecked sets p to the decoded key, after checking it in strict mode.
+func (p *PublicKey) setChecked(key PublicKey) error {
+	if StrictBLS() {
+		if err := key.Check(); err != nil {
+			return fmt.Errorf("invalid public key %s: %w", key, err)
+		}
+	}
+	*p = key
+	return nil
+}
+
+// setChecked sets s to the decoded signature, after checking it in strict mode.
+func (s *Signature) setChecked(sig Signature) error {
+	if StrictBLS() {
+		if err := sig.Check(); err != nil {
+			return fmt.Errorf("invalid signature %s: %w", sig, err)
+		}
+	}
+	*s = sig
+	return nil
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+// offSubgroup returns a compressed point on the curve, but outside the subgroup, by trying
+// small x coordinates.
+func offSubgroup(t *testing.T, size int, onCurve func([]byte) bool) []byte {
+	b := make([]byte, size)
+	for x := 1; x < 256; x++ {
+		b[0], b[size-1] = 0x80, byte(x)
+		if onCurve(b) {
+			return b
+		}
+	}
+	t.Fatal("no point outside the subgroup")
+	return nil
+}
+
+func TestBLSChecks(t *testing.T) {
+	pk, sk := newKeypair(t)
+	var pubkey PublicKey
+	pubkey.FromSlice(pk)
+	var sig Signature
+	sig.FromSlice(sk.Sign([]byte{0x01}).Marshal())
+	require.NoError(t, pubkey.Check())
+	require.NoError(t, sig.Check())
+
+	var badKey PublicKey
+	var badSig Signature
+	cases := []struct {
+		key, sig []byte
+		err      error
+	}{
+		{append([]byte{0xc0}, make([]byte, 47)...), append([]byte{0xc0}, make([]byte, 95)...), ErrBLSInfinity},
+		{make([]byte, 48), make([]byte, 96), ErrBLSEncoding},
+		{
+			offSubgroup(t, 48, func(b []byte) bool {
+				p := new(blst.P1Affine).Uncompress(b)
+				return p != nil && !p.InG1()
+			}),
+			offSubgroup(t, 96, func(b []byte) bool {
+				p := new(blst.P2Affine).Uncompress(b)
+				return p != nil && !p.InG2()
+			}),
+			ErrBLSSubgroup,
+		},
+	}
+	for _, c := range cases {
+		badKey.FromSlice(c.key)
+		badSig.FromSlice(c.sig)
+		require.ErrorIs(t, badKey.Check(), c.err)
+		require.ErrorIs(t, badSig.Check(), c.err)
+
+		// unmarshalling only checks the points in strict mode
+		msg := &SignedValidatorRegistration{
+			Message:   &RegisterValidatorRequestMessage{Pubkey: badKey},
+			Signature: sig,
+		}
+		enc, err := json.Marshal(msg)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(enc, new(SignedValidatorRegistration)))
+		require.NoError(t, badSig.Set(badSig.String()))
+
+		SetStrictBLS(true)
+		err = json.Unmarshal(enc, new(SignedValidatorRegistration))
+		require.ErrorIs(t, err, c.err)
+		require.Contains(t, err.Error(), "invalid public key")
+		err = badSig.Set(badSig.String())
+		require.ErrorIs(t, err, c.err)
+		require.Contains(t, err.Error(), "invalid signature")
+		require.NoError(t, pubkey.Set(pubkey.String()))
+		require.NoError(t, sig.Set(sig.String()))
+		SetStrictBLS(false)
+	}
+}
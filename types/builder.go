@@ -1,11 +1,10 @@
 package types
 
 import (
-	"math/big"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // Generate SSZ encoding: make generate-ssz
@@ -83,12 +82,25 @@ type Attestation struct {
 
 // Deposit https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/beacon-chain.md#deposit
 type Deposit struct {
+	Proof []Root       `json:"proof" ssz-size:"33"` // DEPOSIT_CONTRACT_TREE_DEPTH + 1
+	Data  *DepositData `json:"data"`
+}
+
+// DepositData https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/beacon-chain.md#depositdata
+type DepositData struct {
 	Pubkey                PublicKey `json:"pubkey" ssz-size:"48"`
 	WithdrawalCredentials Hash      `json:"withdrawal_credentials" ssz-size:"32"`
 	Amount                uint64    `json:"amount,string"`
 	Signature             Signature `json:"signature" ssz-size:"96"`
 }
 
+// DepositMessage https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/beacon-chain.md#depositmessage
+type DepositMessage struct {
+	Pubkey                PublicKey `json:"pubkey" ssz-size:"48"`
+	WithdrawalCredentials Hash      `json:"withdrawal_credentials" ssz-size:"32"`
+	Amount                uint64    `json:"amount,string"`
+}
+
 // VoluntaryExit https://github.com/ethereum/consensus-specs/blob/dev/specs/phase0/beacon-chain.md#voluntaryexit
 type VoluntaryExit struct {
 	Epoch          uint64 `json:"epoch,string"`
@@ -171,7 +183,7 @@ type RegisterValidatorRequestMessage struct {
 // SignedValidatorRegistration https://github.com/ethereum/beacon-APIs/blob/master/types/registration.yaml#L18
 type SignedValidatorRegistration struct {
 	Message   *RegisterValidatorRequestMessage `json:"message"`
-	Signature Signature                        `json:"signature"`
+	Signature Signature                        `json:"signature" ssz-size:"96"`
 }
 
 // BuilderBid https://github.com/ethereum/builder-specs/pull/2/files#diff-b37cbf48e8754483e30e7caaadc5defc8c3c6e1aaf3273ee188d787b7c75d993
@@ -196,7 +208,7 @@ type GetHeaderResponse struct {
 // SignedBlindedBeaconBlock https://github.com/ethereum/beacon-APIs/blob/master/types/bellatrix/block.yaml#L83
 type SignedBlindedBeaconBlock struct {
 	Message   *BlindedBeaconBlock `json:"message"`
-	Signature Signature           `json:"signature"`
+	Signature Signature           `json:"signature" ssz-size:"96"`
 }
 
 // GetPayloadResponse is the response payload from the getPayload request: https://github.com/ethereum/builder-specs/pull/2/files#diff-8446716b376f3ffe88737f9773ce2ff21adc2bc0f2c9a140dcc2e9d632091ba4
@@ -205,81 +217,335 @@ type GetPayloadResponse struct {
 	Data    *ExecutionPayloadREST `json:"data"`
 }
 
+// WithdrawalREST https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/beacon-chain.md#withdrawal
+type WithdrawalREST struct {
+	Index          uint64  `json:"index,string"`
+	ValidatorIndex uint64  `json:"validator_index,string"`
+	Address        Address `json:"address" ssz-size:"20"`
+	Amount         uint64  `json:"amount,string"`
+}
+
+// ExecutionPayloadHeaderCapella https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/beacon-chain.md#executionpayloadheader
+type ExecutionPayloadHeaderCapella struct {
+	ParentHash       Hash      `json:"parent_hash" ssz-size:"32"`
+	FeeRecipient     Address   `json:"fee_recipient" ssz-size:"20"`
+	StateRoot        Root      `json:"state_root" ssz-size:"32"`
+	ReceiptsRoot     Root      `json:"receipts_root" ssz-size:"32"`
+	LogsBloom        Bloom     `json:"logs_bloom" ssz-size:"256"`
+	Random           Hash      `json:"prev_randao" ssz-size:"32"`
+	BlockNumber      uint64    `json:"block_number,string"`
+	GasLimit         uint64    `json:"gas_limit,string"`
+	GasUsed          uint64    `json:"gas_used,string"`
+	Timestamp        uint64    `json:"timestamp,string"`
+	ExtraData        ExtraData `json:"extra_data" ssz-max:"32"`
+	BaseFeePerGas    U256Str   `json:"base_fee_per_gas" ssz-size:"32"`
+	BlockHash        Hash      `json:"block_hash" ssz-size:"32"`
+	TransactionsRoot Root      `json:"transactions_root" ssz-size:"32"`
+	WithdrawalsRoot  Root      `json:"withdrawals_root" ssz-size:"32"`
+}
+
+// ExecutionPayloadCapella https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/beacon-chain.md#executionpayload
+type ExecutionPayloadCapella struct {
+	ParentHash    Hash              `json:"parent_hash" ssz-size:"32"`
+	FeeRecipient  Address           `json:"fee_recipient" ssz-size:"20"`
+	StateRoot     Root              `json:"state_root" ssz-size:"32"`
+	ReceiptsRoot  Root              `json:"receipts_root" ssz-size:"32"`
+	LogsBloom     Bloom             `json:"logs_bloom" ssz-size:"256"`
+	Random        Hash              `json:"prev_randao" ssz-size:"32"`
+	BlockNumber   uint64            `json:"block_number,string"`
+	GasLimit      uint64            `json:"gas_limit,string"`
+	GasUsed       uint64            `json:"gas_used,string"`
+	Timestamp     uint64            `json:"timestamp,string"`
+	ExtraData     ExtraData         `json:"extra_data" ssz-max:"32"`
+	BaseFeePerGas U256Str           `json:"base_fee_per_gas" ssz-size:"32"`
+	BlockHash     Hash              `json:"block_hash" ssz-size:"32"`
+	Transactions  []hexutil.Bytes   `json:"transactions" ssz-max:"1048576,1073741824" ssz-size:"?,?"`
+	Withdrawals   []*WithdrawalREST `json:"withdrawals" ssz-max:"16"`
+}
+
+// ExecutionPayloadHeaderDeneb https://github.com/ethereum/consensus-specs/blob/dev/specs/deneb/beacon-chain.md#executionpayloadheader
+type ExecutionPayloadHeaderDeneb struct {
+	ParentHash       Hash      `json:"parent_hash" ssz-size:"32"`
+	FeeRecipient     Address   `json:"fee_recipient" ssz-size:"20"`
+	StateRoot        Root      `json:"state_root" ssz-size:"32"`
+	ReceiptsRoot     Root      `json:"receipts_root" ssz-size:"32"`
+	LogsBloom        Bloom     `json:"logs_bloom" ssz-size:"256"`
+	Random           Hash      `json:"prev_randao" ssz-size:"32"`
+	BlockNumber      uint64    `json:"block_number,string"`
+	GasLimit         uint64    `json:"gas_limit,string"`
+	GasUsed          uint64    `json:"gas_used,string"`
+	Timestamp        uint64    `json:"timestamp,string"`
+	ExtraData        ExtraData `json:"extra_data" ssz-max:"32"`
+	BaseFeePerGas    U256Str   `json:"base_fee_per_gas" ssz-size:"32"`
+	BlockHash        Hash      `json:"block_hash" ssz-size:"32"`
+	TransactionsRoot Root      `json:"transactions_root" ssz-size:"32"`
+	WithdrawalsRoot  Root      `json:"withdrawals_root" ssz-size:"32"`
+	BlobGasUsed      uint64    `json:"blob_gas_used,string"`
+	ExcessBlobGas    uint64    `json:"excess_blob_gas,string"`
+}
+
+// ExecutionPayloadDeneb https://github.com/ethereum/consensus-specs/blob/dev/specs/deneb/beacon-chain.md#executionpayload
+type ExecutionPayloadDeneb struct {
+	ParentHash    Hash              `json:"parent_hash" ssz-size:"32"`
+	FeeRecipient  Address           `json:"fee_recipient" ssz-size:"20"`
+	StateRoot     Root              `json:"state_root" ssz-size:"32"`
+	ReceiptsRoot  Root              `json:"receipts_root" ssz-size:"32"`
+	LogsBloom     Bloom             `json:"logs_bloom" ssz-size:"256"`
+	Random        Hash              `json:"prev_randao" ssz-size:"32"`
+	BlockNumber   uint64            `json:"block_number,string"`
+	GasLimit      uint64            `json:"gas_limit,string"`
+	GasUsed       uint64            `json:"gas_used,string"`
+	Timestamp     uint64            `json:"timestamp,string"`
+	ExtraData     ExtraData         `json:"extra_data" ssz-max:"32"`
+	BaseFeePerGas U256Str           `json:"base_fee_per_gas" ssz-size:"32"`
+	BlockHash     Hash              `json:"block_hash" ssz-size:"32"`
+	Transactions  []hexutil.Bytes   `json:"transactions" ssz-max:"1048576,1073741824" ssz-size:"?,?"`
+	Withdrawals   []*WithdrawalREST `json:"withdrawals" ssz-max:"16"`
+	BlobGasUsed   uint64            `json:"blob_gas_used,string"`
+	ExcessBlobGas uint64            `json:"excess_blob_gas,string"`
+}
+
+// BuilderBidCapella https://github.com/ethereum/builder-specs/blob/main/specs/capella/builder.md#builderbid
+type BuilderBidCapella struct {
+	Header *ExecutionPayloadHeaderCapella `json:"header"`
+	Value  U256Str                        `json:"value" ssz-size:"32"`
+	Pubkey PublicKey                      `json:"pubkey" ssz-size:"48"`
+}
+
+// SignedBuilderBidCapella https://github.com/ethereum/builder-specs/blob/main/specs/capella/builder.md#signedbuilderbid
+type SignedBuilderBidCapella struct {
+	Message   *BuilderBidCapella `json:"message"`
+	Signature Signature          `json:"signature" ssz-size:"96"`
+}
+
+// BidTrace https://flashbots.github.io/relay-specs/#/Data/getDeliveredPayloads
+type BidTrace struct {
+	Slot                 uint64    `json:"slot,string"`
+	ParentHash           Hash      `json:"parent_hash" ssz-size:"32"`
+	BlockHash            Hash      `json:"block_hash" ssz-size:"32"`
+	BuilderPubkey        PublicKey `json:"builder_pubkey" ssz-size:"48"`
+	ProposerPubkey       PublicKey `json:"proposer_pubkey" ssz-size:"48"`
+	ProposerFeeRecipient Address   `json:"proposer_fee_recipient" ssz-size:"20"`
+	GasLimit             uint64    `json:"gas_limit,string"`
+	GasUsed              uint64    `json:"gas_used,string"`
+	Value                U256Str   `json:"value" ssz-size:"32"`
+}
+
+// SignedBidTrace is a bid trace signed by the builder, in the builder domain.
+type SignedBidTrace struct {
+	Message   *BidTrace `json:"message"`
+	Signature Signature `json:"signature" ssz-size:"96"`
+}
+
+// BidTraceV2 is the bid trace of the data API, with block fields that are not signed.
+type BidTraceV2 struct {
+	BidTrace
+	BlockNumber uint64 `json:"block_number,string"`
+	NumTx       uint64 `json:"num_tx,string"`
+}
+
+// BidTraceV2WithTimestamp is the bid trace of a block the relay received, with the time it was
+// received.
+type BidTraceV2WithTimestamp struct {
+	BidTraceV2
+	Timestamp   int64 `json:"timestamp,string"`
+	TimestampMs int64 `json:"timestamp_ms,string"`
+}
+
+// BuilderSubmitBlockRequest https://flashbots.github.io/relay-specs/#/Builder/submitBlock
+type BuilderSubmitBlockRequest struct {
+	Message          *BidTrace             `json:"message"`
+	ExecutionPayload *ExecutionPayloadREST `json:"execution_payload"`
+	Signature        Signature             `json:"signature"`
+}
+
+// BuilderSubmitBlockRequestCapella is the block submission of a capella block.
+type BuilderSubmitBlockRequestCapella struct {
+	Message          *BidTrace                `json:"message"`
+	ExecutionPayload *ExecutionPayloadCapella `json:"execution_payload"`
+	Signature        Signature                `json:"signature"`
+}
+
+// BLSToExecutionChange https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/beacon-chain.md#blstoexecutionchange
+type BLSToExecutionChange struct {
+	ValidatorIndex     uint64    `json:"validator_index,string"`
+	FromBLSPubkey      PublicKey `json:"from_bls_pubkey" ssz-size:"48"`
+	ToExecutionAddress Address   `json:"to_execution_address" ssz-size:"20"`
+}
+
+// SignedBLSToExecutionChange https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/beacon-chain.md#signedblstoexecutionchange
+type SignedBLSToExecutionChange struct {
+	Message   *BLSToExecutionChange `json:"message"`
+	Signature Signature             `json:"signature" ssz-size:"96"`
+}
+
+// BlindedBeaconBlockBodyCapella https://github.com/ethereum/beacon-APIs/blob/master/types/capella/block.yaml
+type BlindedBeaconBlockBodyCapella struct {
+	RandaoReveal           Signature                      `json:"randao_reveal" ssz-size:"96"`
+	Eth1Data               *Eth1Data                      `json:"eth1_data"`
+	Graffiti               Hash                           `json:"graffiti" ssz-size:"32"`
+	ProposerSlashings      []*ProposerSlashing            `json:"proposer_slashings" ssz-max:"16"`
+	AttesterSlashings      []*AttesterSlashing            `json:"attester_slashings" ssz-max:"2"`
+	Attestations           []*Attestation                 `json:"attestations" ssz-max:"128"`
+	Deposits               []*Deposit                     `json:"deposits" ssz-max:"16"`
+	VoluntaryExits         []*VoluntaryExit               `json:"voluntary_exits" ssz-max:"16"`
+	SyncAggregate          *SyncAggregate                 `json:"sync_aggregate"`
+	ExecutionPayloadHeader *ExecutionPayloadHeaderCapella `json:"execution_payload_header"`
+	BLSToExecutionChanges  []*SignedBLSToExecutionChange  `json:"bls_to_execution_changes" ssz-max:"16"`
+}
+
+// BlindedBeaconBlockCapella https://github.com/ethereum/beacon-APIs/blob/master/types/capella/block.yaml
+type BlindedBeaconBlockCapella struct {
+	Slot          uint64                         `json:"slot,string"`
+	ProposerIndex uint64                         `json:"proposer_index,string"`
+	ParentRoot    Root                           `json:"parent_root" ssz-size:"32"`
+	StateRoot     Root                           `json:"state_root" ssz-size:"32"`
+	Body          *BlindedBeaconBlockBodyCapella `json:"body"`
+}
+
+// SignedBlindedBeaconBlockCapella https://github.com/ethereum/beacon-APIs/blob/master/types/capella/block.yaml
+type SignedBlindedBeaconBlockCapella struct {
+	Message   *BlindedBeaconBlockCapella `json:"message"`
+	Signature Signature                  `json:"signature" ssz-size:"96"`
+}
+
 type transactions struct {
 	Transactions [][]byte `ssz-max:"1048576,1073741824" ssz-size:"?,?"`
 }
 
-func PayloadToPayloadHeader(p *ExecutionPayloadV1) (*ExecutionPayloadHeader, error) {
-	txs := transactions{Transactions: p.Transactions}
-	txroot, err := txs.HashTreeRoot()
-	if err != nil {
-		return nil, err
+type withdrawals struct {
+	Withdrawals []*WithdrawalREST `ssz-max:"16"`
+}
+
+// executionPayload is the SSZ container of ExecutionPayloadREST, whose hexutil transactions
+// sszgen does not handle.
+type executionPayload struct {
+	ParentHash    Hash    `ssz-size:"32"`
+	FeeRecipient  Address `ssz-size:"20"`
+	StateRoot     Root    `ssz-size:"32"`
+	ReceiptsRoot  Root    `ssz-size:"32"`
+	LogsBloom     Bloom   `ssz-size:"256"`
+	Random        Hash    `ssz-size:"32"`
+	BlockNumber   uint64
+	GasLimit      uint64
+	GasUsed       uint64
+	Timestamp     uint64
+	ExtraData     ExtraData `ssz-max:"32"`
+	BaseFeePerGas U256Str   `ssz-size:"32"`
+	BlockHash     Hash      `ssz-size:"32"`
+	Transactions  [][]byte  `ssz-max:"1048576,1073741824" ssz-size:"?,?"`
+}
+
+// executionPayloadCapella is the SSZ container of ExecutionPayloadCapella.
+type executionPayloadCapella struct {
+	ParentHash    Hash    `ssz-size:"32"`
+	FeeRecipient  Address `ssz-size:"20"`
+	StateRoot     Root    `ssz-size:"32"`
+	ReceiptsRoot  Root    `ssz-size:"32"`
+	LogsBloom     Bloom   `ssz-size:"256"`
+	Random        Hash    `ssz-size:"32"`
+	BlockNumber   uint64
+	GasLimit      uint64
+	GasUsed       uint64
+	Timestamp     uint64
+	ExtraData     ExtraData         `ssz-max:"32"`
+	BaseFeePerGas U256Str           `ssz-size:"32"`
+	BlockHash     Hash              `ssz-size:"32"`
+	Transactions  [][]byte          `ssz-max:"1048576,1073741824" ssz-size:"?,?"`
+	Withdrawals   []*WithdrawalREST `ssz-max:"16"`
+}
+
+// executionPayloadDeneb is the SSZ container of ExecutionPayloadDeneb.
+type executionPayloadDeneb struct {
+	ParentHash    Hash    `ssz-size:"32"`
+	FeeRecipient  Address `ssz-size:"20"`
+	StateRoot     Root    `ssz-size:"32"`
+	ReceiptsRoot  Root    `ssz-size:"32"`
+	LogsBloom     Bloom   `ssz-size:"256"`
+	Random        Hash    `ssz-size:"32"`
+	BlockNumber   uint64
+	GasLimit      uint64
+	GasUsed       uint64
+	Timestamp     uint64
+	ExtraData     ExtraData         `ssz-max:"32"`
+	BaseFeePerGas U256Str           `ssz-size:"32"`
+	BlockHash     Hash              `ssz-size:"32"`
+	Transactions  [][]byte          `ssz-max:"1048576,1073741824" ssz-size:"?,?"`
+	Withdrawals   []*WithdrawalREST `ssz-max:"16"`
+	BlobGasUsed   uint64
+	ExcessBlobGas uint64
+}
+
+// MarshalSignedValidatorRegistrations encodes registrations as the SSZ list of a
+// registerValidator request body.
+func MarshalSignedValidatorRegistrations(regs []SignedValidatorRegistration) ([]byte, error) {
+	var out []byte
+	for i := range regs {
+		var err error
+		if out, err = regs[i].MarshalSSZTo(out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// UnmarshalSignedValidatorRegistrations decodes the SSZ list of a registerValidator request
+// body. The registrations have a fixed size, so the list is their concatenation.
+func UnmarshalSignedValidatorRegistrations(buf []byte) ([]SignedValidatorRegistration, error) {
+	size := new(SignedValidatorRegistration).SizeSSZ()
+	if len(buf)%size != 0 {
+		return nil, fmt.Errorf("registrations length %d is not a multiple of %d", len(buf), size)
 	}
-	return &ExecutionPayloadHeader{
-		ParentHash:       [32]byte(p.ParentHash),
-		FeeRecipient:     [20]byte(p.FeeRecipient),
-		StateRoot:        [32]byte(p.StateRoot),
-		ReceiptsRoot:     [32]byte(p.ReceiptsRoot),
-		LogsBloom:        [256]byte(p.LogsBloom),
-		Random:           [32]byte(p.Random),
-		BlockNumber:      p.Number,
-		GasLimit:         p.GasLimit,
-		GasUsed:          p.GasUsed,
-		Timestamp:        p.Timestamp,
-		ExtraData:        ExtraData(p.ExtraData),
-		BaseFeePerGas:    [32]byte(common.BytesToHash(p.BaseFeePerGas.Bytes())),
-		BlockHash:        [32]byte(p.BlockHash),
-		TransactionsRoot: [32]byte(txroot),
-	}, nil
-}
-
-func ELPayloadToRESTPayload(p *ExecutionPayloadV1) (*ExecutionPayloadREST, error) {
-	txs := make([]hexutil.Bytes, len(p.Transactions))
-	for i, tx := range p.Transactions {
-		txs[i] = hexutil.Bytes(tx)
+	regs := make([]SignedValidatorRegistration, len(buf)/size)
+	for i := range regs {
+		if err := regs[i].UnmarshalSSZ(buf[i*size : (i+1)*size]); err != nil {
+			return nil, err
+		}
 	}
+	return regs, nil
+}
 
-	return &ExecutionPayloadREST{
-		ParentHash:    [32]byte(p.ParentHash),
-		FeeRecipient:  [20]byte(p.FeeRecipient),
-		StateRoot:     [32]byte(p.StateRoot),
-		ReceiptsRoot:  [32]byte(p.ReceiptsRoot),
-		LogsBloom:     [256]byte(p.LogsBloom),
-		Random:        [32]byte(p.Random),
-		BlockNumber:   p.Number,
-		GasLimit:      p.GasLimit,
-		GasUsed:       p.GasUsed,
-		Timestamp:     p.Timestamp,
-		ExtraData:     hexutil.Bytes(p.ExtraData),
-		BaseFeePerGas: [32]byte(common.BytesToHash(p.BaseFeePerGas.Bytes())),
-		BlockHash:     [32]byte(p.BlockHash),
-		Transactions:  txs,
-	}, nil
-}
-
-func RESTPayloadToELPayload(p *ExecutionPayloadREST) (*ExecutionPayloadV1, error) {
-	txs := make([][]byte, len(p.Transactions))
-	for i, tx := range p.Transactions {
-		txs[i] = []byte(tx)
+func ELWithdrawalsToREST(ws Withdrawals) []*WithdrawalREST {
+	out := make([]*WithdrawalREST, len(ws))
+	for i, w := range ws {
+		out[i] = &WithdrawalREST{
+			Index:          w.Index,
+			ValidatorIndex: w.Validator,
+			Address:        Address(w.Address),
+			Amount:         w.Amount,
+		}
 	}
+	return out
+}
 
-	baseFeePerGas := new(big.Int)
-	baseFeePerGas.SetBytes(p.BaseFeePerGas[:])
-
-	return &ExecutionPayloadV1{
-		ParentHash:    common.Hash(p.ParentHash),
-		FeeRecipient:  common.Address(p.FeeRecipient),
-		StateRoot:     common.Hash(p.StateRoot),
-		ReceiptsRoot:  common.Hash(p.ReceiptsRoot),
-		LogsBloom:     types.Bloom(p.LogsBloom),
-		Random:        common.Hash(p.Random),
-		Number:        p.BlockNumber,
-		GasLimit:      p.GasLimit,
-		GasUsed:       p.GasUsed,
-		Timestamp:     p.Timestamp,
-		ExtraData:     hexutil.Bytes(p.ExtraData),
-		BaseFeePerGas: baseFeePerGas,
-		BlockHash:     common.Hash(p.BlockHash),
-		Transactions:  txs,
-	}, nil
+func RESTWithdrawalsToEL(ws []*WithdrawalREST) Withdrawals {
+	out := make(Withdrawals, len(ws))
+	for i, w := range ws {
+		out[i] = &Withdrawal{
+			Index:     w.Index,
+			Validator: w.ValidatorIndex,
+			Address:   common.Address(w.Address),
+			Amount:    w.Amount,
+		}
+	}
+	return out
+}
+
+// NewBidTrace returns the trace of a bid with the value on the payload, for the proposer.
+func NewBidTrace(slot uint64, payload *ExecutionPayloadV3, builder, proposer PublicKey, feeRecipient Address, value U256Str) *BidTraceV2 {
+	return &BidTraceV2{
+		BidTrace: BidTrace{
+			Slot:                 slot,
+			ParentHash:           Hash(payload.ParentHash),
+			BlockHash:            Hash(payload.BlockHash),
+			BuilderPubkey:        builder,
+			ProposerPubkey:       proposer,
+			ProposerFeeRecipient: feeRecipient,
+			GasLimit:             payload.GasLimit,
+			GasUsed:              payload.GasUsed,
+			Value:                value,
+		},
+		BlockNumber: payload.Number,
+		NumTx:       uint64(len(payload.Transactions)),
+	}
 }
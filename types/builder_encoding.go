@@ -1,5 +1,5 @@
 // Code generated by fastssz. DO NOT EDIT.
-// Hash: a8a57ef184414f615c7463c93dbcc20c69857f7b4db447ac83d6ac28c015543c
+// Hash: 6f00d703daf8a7871ef6487dea611bd841d4753f4a49895d32914740cfc527a8
 package types
 
 import (
@@ -873,6 +873,97 @@ func (d *Deposit) MarshalSSZ() ([]byte, error) {
 func (d *Deposit) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	dst = buf
 
+	// Field (0) 'Proof'
+	if len(d.Proof) != 33 {
+		err = ssz.ErrVectorLength
+		return
+	}
+	for ii := 0; ii < 33; ii++ {
+		dst = append(dst, d.Proof[ii][:]...)
+	}
+
+	// Field (1) 'Data'
+	if d.Data == nil {
+		d.Data = new(DepositData)
+	}
+	if dst, err = d.Data.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the Deposit object
+func (d *Deposit) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 1240 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Proof'
+	d.Proof = make([]Root, 33)
+	for ii := 0; ii < 33; ii++ {
+		copy(d.Proof[ii][:], buf[0:1056][ii*32:(ii+1)*32])
+	}
+
+	// Field (1) 'Data'
+	if d.Data == nil {
+		d.Data = new(DepositData)
+	}
+	if err = d.Data.UnmarshalSSZ(buf[1056:1240]); err != nil {
+		return err
+	}
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the Deposit object
+func (d *Deposit) SizeSSZ() (size int) {
+	size = 1240
+	return
+}
+
+// HashTreeRoot ssz hashes the Deposit object
+func (d *Deposit) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(d)
+}
+
+// HashTreeRootWith ssz hashes the Deposit object with a hasher
+func (d *Deposit) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Proof'
+	{
+		if len(d.Proof) != 33 {
+			err = ssz.ErrVectorLength
+			return
+		}
+		subIndx := hh.Index()
+		for _, i := range d.Proof {
+			hh.Append(i[:])
+		}
+		hh.Merkleize(subIndx)
+	}
+
+	// Field (1) 'Data'
+	if err = d.Data.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the DepositData object
+func (d *DepositData) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(d)
+}
+
+// MarshalSSZTo ssz marshals the DepositData object to a target array
+func (d *DepositData) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
 	// Field (0) 'Pubkey'
 	dst = append(dst, d.Pubkey[:]...)
 
@@ -888,8 +979,8 @@ func (d *Deposit) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	return
 }
 
-// UnmarshalSSZ ssz unmarshals the Deposit object
-func (d *Deposit) UnmarshalSSZ(buf []byte) error {
+// UnmarshalSSZ ssz unmarshals the DepositData object
+func (d *DepositData) UnmarshalSSZ(buf []byte) error {
 	var err error
 	size := uint64(len(buf))
 	if size != 184 {
@@ -911,19 +1002,19 @@ func (d *Deposit) UnmarshalSSZ(buf []byte) error {
 	return err
 }
 
-// SizeSSZ returns the ssz encoded size in bytes for the Deposit object
-func (d *Deposit) SizeSSZ() (size int) {
+// SizeSSZ returns the ssz encoded size in bytes for the DepositData object
+func (d *DepositData) SizeSSZ() (size int) {
 	size = 184
 	return
 }
 
-// HashTreeRoot ssz hashes the Deposit object
-func (d *Deposit) HashTreeRoot() ([32]byte, error) {
+// HashTreeRoot ssz hashes the DepositData object
+func (d *DepositData) HashTreeRoot() ([32]byte, error) {
 	return ssz.HashWithDefaultHasher(d)
 }
 
-// HashTreeRootWith ssz hashes the Deposit object with a hasher
-func (d *Deposit) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+// HashTreeRootWith ssz hashes the DepositData object with a hasher
+func (d *DepositData) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	indx := hh.Index()
 
 	// Field (0) 'Pubkey'
@@ -942,6 +1033,75 @@ func (d *Deposit) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	return
 }
 
+// MarshalSSZ ssz marshals the DepositMessage object
+func (d *DepositMessage) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(d)
+}
+
+// MarshalSSZTo ssz marshals the DepositMessage object to a target array
+func (d *DepositMessage) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Pubkey'
+	dst = append(dst, d.Pubkey[:]...)
+
+	// Field (1) 'WithdrawalCredentials'
+	dst = append(dst, d.WithdrawalCredentials[:]...)
+
+	// Field (2) 'Amount'
+	dst = ssz.MarshalUint64(dst, d.Amount)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the DepositMessage object
+func (d *DepositMessage) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 88 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Pubkey'
+	copy(d.Pubkey[:], buf[0:48])
+
+	// Field (1) 'WithdrawalCredentials'
+	copy(d.WithdrawalCredentials[:], buf[48:80])
+
+	// Field (2) 'Amount'
+	d.Amount = ssz.UnmarshallUint64(buf[80:88])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the DepositMessage object
+func (d *DepositMessage) SizeSSZ() (size int) {
+	size = 88
+	return
+}
+
+// HashTreeRoot ssz hashes the DepositMessage object
+func (d *DepositMessage) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(d)
+}
+
+// HashTreeRootWith ssz hashes the DepositMessage object with a hasher
+func (d *DepositMessage) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Pubkey'
+	hh.PutBytes(d.Pubkey[:])
+
+	// Field (1) 'WithdrawalCredentials'
+	hh.PutBytes(d.WithdrawalCredentials[:])
+
+	// Field (2) 'Amount'
+	hh.PutUint64(d.Amount)
+
+	hh.Merkleize(indx)
+	return
+}
+
 // MarshalSSZ ssz marshals the VoluntaryExit object
 func (v *VoluntaryExit) MarshalSSZ() ([]byte, error) {
 	return ssz.MarshalSSZ(v)
@@ -1116,7 +1276,7 @@ func (e *ExecutionPayloadHeader) MarshalSSZTo(buf []byte) (dst []byte, err error
 	dst = append(dst, e.TransactionsRoot[:]...)
 
 	// Field (10) 'ExtraData'
-	if len(e.ExtraData) > 2048 {
+	if len(e.ExtraData) > 32 {
 		err = ssz.ErrBytesLength
 		return
 	}
@@ -1187,7 +1347,7 @@ func (e *ExecutionPayloadHeader) UnmarshalSSZ(buf []byte) error {
 	// Field (10) 'ExtraData'
 	{
 		buf = tail[o10:]
-		if len(buf) > 2048 {
+		if len(buf) > 32 {
 			return ssz.ErrBytesLength
 		}
 		if cap(e.ExtraData) == 0 {
@@ -1251,7 +1411,7 @@ func (e *ExecutionPayloadHeader) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	{
 		elemIndx := hh.Index()
 		byteLen := uint64(len(e.ExtraData))
-		if byteLen > 2048 {
+		if byteLen > 32 {
 			err = ssz.ErrIncorrectListSize
 			return
 		}
@@ -1316,7 +1476,7 @@ func (b *BlindedBeaconBlockBody) MarshalSSZTo(buf []byte) (dst []byte, err error
 
 	// Offset (6) 'Deposits'
 	dst = ssz.WriteOffset(dst, offset)
-	offset += len(b.Deposits) * 184
+	offset += len(b.Deposits) * 1240
 
 	// Offset (7) 'VoluntaryExits'
 	dst = ssz.WriteOffset(dst, offset)
@@ -1546,7 +1706,7 @@ func (b *BlindedBeaconBlockBody) UnmarshalSSZ(buf []byte) error {
 	// Field (6) 'Deposits'
 	{
 		buf = tail[o6:o7]
-		num, err := ssz.DivideInt2(len(buf), 184, 16)
+		num, err := ssz.DivideInt2(len(buf), 1240, 16)
 		if err != nil {
 			return err
 		}
@@ -1555,7 +1715,7 @@ func (b *BlindedBeaconBlockBody) UnmarshalSSZ(buf []byte) error {
 			if b.Deposits[ii] == nil {
 				b.Deposits[ii] = new(Deposit)
 			}
-			if err = b.Deposits[ii].UnmarshalSSZ(buf[ii*184 : (ii+1)*184]); err != nil {
+			if err = b.Deposits[ii].UnmarshalSSZ(buf[ii*1240 : (ii+1)*1240]); err != nil {
 				return err
 			}
 		}
@@ -1612,7 +1772,7 @@ func (b *BlindedBeaconBlockBody) SizeSSZ() (size int) {
 	}
 
 	// Field (6) 'Deposits'
-	size += len(b.Deposits) * 184
+	size += len(b.Deposits) * 1240
 
 	// Field (7) 'VoluntaryExits'
 	size += len(b.VoluntaryExits) * 16
@@ -1943,6 +2103,78 @@ func (r *RegisterValidatorRequestMessage) HashTreeRootWith(hh *ssz.Hasher) (err
 	return
 }
 
+// MarshalSSZ ssz marshals the SignedValidatorRegistration object
+func (s *SignedValidatorRegistration) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SignedValidatorRegistration object to a target array
+func (s *SignedValidatorRegistration) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(RegisterValidatorRequestMessage)
+	}
+	if dst, err = s.Message.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	dst = append(dst, s.Signature[:]...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SignedValidatorRegistration object
+func (s *SignedValidatorRegistration) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 180 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(RegisterValidatorRequestMessage)
+	}
+	if err = s.Message.UnmarshalSSZ(buf[0:84]); err != nil {
+		return err
+	}
+
+	// Field (1) 'Signature'
+	copy(s.Signature[:], buf[84:180])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SignedValidatorRegistration object
+func (s *SignedValidatorRegistration) SizeSSZ() (size int) {
+	size = 180
+	return
+}
+
+// HashTreeRoot ssz hashes the SignedValidatorRegistration object
+func (s *SignedValidatorRegistration) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SignedValidatorRegistration object with a hasher
+func (s *SignedValidatorRegistration) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Message'
+	if err = s.Message.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	hh.PutBytes(s.Signature[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
 // MarshalSSZ ssz marshals the BuilderBid object
 func (b *BuilderBid) MarshalSSZ() ([]byte, error) {
 	return ssz.MarshalSSZ(b)
@@ -2148,122 +2380,3115 @@ func (s *SignedBuilderBid) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	return
 }
 
-// MarshalSSZ ssz marshals the transactions object
-func (t *transactions) MarshalSSZ() ([]byte, error) {
-	return ssz.MarshalSSZ(t)
+// MarshalSSZ ssz marshals the SignedBlindedBeaconBlock object
+func (s *SignedBlindedBeaconBlock) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
 }
 
-// MarshalSSZTo ssz marshals the transactions object to a target array
-func (t *transactions) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+// MarshalSSZTo ssz marshals the SignedBlindedBeaconBlock object to a target array
+func (s *SignedBlindedBeaconBlock) MarshalSSZTo(buf []byte) (dst []byte, err error) {
 	dst = buf
-	offset := int(4)
+	offset := int(100)
 
-	// Offset (0) 'Transactions'
+	// Offset (0) 'Message'
 	dst = ssz.WriteOffset(dst, offset)
-	for ii := 0; ii < len(t.Transactions); ii++ {
-		offset += 4
-		offset += len(t.Transactions[ii])
+	if s.Message == nil {
+		s.Message = new(BlindedBeaconBlock)
 	}
+	offset += s.Message.SizeSSZ()
 
-	// Field (0) 'Transactions'
-	if len(t.Transactions) > 1048576 {
-		err = ssz.ErrListTooBig
+	// Field (1) 'Signature'
+	dst = append(dst, s.Signature[:]...)
+
+	// Field (0) 'Message'
+	if dst, err = s.Message.MarshalSSZTo(dst); err != nil {
 		return
 	}
-	{
-		offset = 4 * len(t.Transactions)
-		for ii := 0; ii < len(t.Transactions); ii++ {
-			dst = ssz.WriteOffset(dst, offset)
-			offset += len(t.Transactions[ii])
-		}
-	}
-	for ii := 0; ii < len(t.Transactions); ii++ {
-		if len(t.Transactions[ii]) > 1073741824 {
-			err = ssz.ErrBytesLength
-			return
-		}
-		dst = append(dst, t.Transactions[ii]...)
-	}
 
 	return
 }
 
-// UnmarshalSSZ ssz unmarshals the transactions object
-func (t *transactions) UnmarshalSSZ(buf []byte) error {
+// UnmarshalSSZ ssz unmarshals the SignedBlindedBeaconBlock object
+func (s *SignedBlindedBeaconBlock) UnmarshalSSZ(buf []byte) error {
 	var err error
 	size := uint64(len(buf))
-	if size < 4 {
+	if size < 100 {
 		return ssz.ErrSize
 	}
 
 	tail := buf
 	var o0 uint64
 
-	// Offset (0) 'Transactions'
+	// Offset (0) 'Message'
 	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
 		return ssz.ErrOffset
 	}
 
-	if o0 < 4 {
+	if o0 < 100 {
 		return ssz.ErrInvalidVariableOffset
 	}
 
-	// Field (0) 'Transactions'
+	// Field (1) 'Signature'
+	copy(s.Signature[:], buf[4:100])
+
+	// Field (0) 'Message'
 	{
 		buf = tail[o0:]
-		num, err := ssz.DecodeDynamicLength(buf, 1048576)
-		if err != nil {
-			return err
+		if s.Message == nil {
+			s.Message = new(BlindedBeaconBlock)
 		}
-		t.Transactions = make([][]byte, num)
-		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
-			if len(buf) > 1073741824 {
-				return ssz.ErrBytesLength
-			}
-			if cap(t.Transactions[indx]) == 0 {
-				t.Transactions[indx] = make([]byte, 0, len(buf))
-			}
-			t.Transactions[indx] = append(t.Transactions[indx], buf...)
-			return nil
-		})
-		if err != nil {
+		if err = s.Message.UnmarshalSSZ(buf); err != nil {
 			return err
 		}
 	}
 	return err
 }
 
-// SizeSSZ returns the ssz encoded size in bytes for the transactions object
-func (t *transactions) SizeSSZ() (size int) {
-	size = 4
+// SizeSSZ returns the ssz encoded size in bytes for the SignedBlindedBeaconBlock object
+func (s *SignedBlindedBeaconBlock) SizeSSZ() (size int) {
+	size = 100
 
-	// Field (0) 'Transactions'
-	for ii := 0; ii < len(t.Transactions); ii++ {
-		size += 4
-		size += len(t.Transactions[ii])
-	}
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BlindedBeaconBlock)
+	}
+	size += s.Message.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the SignedBlindedBeaconBlock object
+func (s *SignedBlindedBeaconBlock) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SignedBlindedBeaconBlock object with a hasher
+func (s *SignedBlindedBeaconBlock) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Message'
+	if err = s.Message.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	hh.PutBytes(s.Signature[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the WithdrawalREST object
+func (w *WithdrawalREST) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(w)
+}
+
+// MarshalSSZTo ssz marshals the WithdrawalREST object to a target array
+func (w *WithdrawalREST) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Index'
+	dst = ssz.MarshalUint64(dst, w.Index)
+
+	// Field (1) 'ValidatorIndex'
+	dst = ssz.MarshalUint64(dst, w.ValidatorIndex)
+
+	// Field (2) 'Address'
+	dst = append(dst, w.Address[:]...)
+
+	// Field (3) 'Amount'
+	dst = ssz.MarshalUint64(dst, w.Amount)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the WithdrawalREST object
+func (w *WithdrawalREST) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 44 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Index'
+	w.Index = ssz.UnmarshallUint64(buf[0:8])
+
+	// Field (1) 'ValidatorIndex'
+	w.ValidatorIndex = ssz.UnmarshallUint64(buf[8:16])
+
+	// Field (2) 'Address'
+	copy(w.Address[:], buf[16:36])
+
+	// Field (3) 'Amount'
+	w.Amount = ssz.UnmarshallUint64(buf[36:44])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the WithdrawalREST object
+func (w *WithdrawalREST) SizeSSZ() (size int) {
+	size = 44
+	return
+}
+
+// HashTreeRoot ssz hashes the WithdrawalREST object
+func (w *WithdrawalREST) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(w)
+}
+
+// HashTreeRootWith ssz hashes the WithdrawalREST object with a hasher
+func (w *WithdrawalREST) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Index'
+	hh.PutUint64(w.Index)
+
+	// Field (1) 'ValidatorIndex'
+	hh.PutUint64(w.ValidatorIndex)
+
+	// Field (2) 'Address'
+	hh.PutBytes(w.Address[:])
+
+	// Field (3) 'Amount'
+	hh.PutUint64(w.Amount)
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the ExecutionPayloadHeaderCapella object
+func (e *ExecutionPayloadHeaderCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(e)
+}
+
+// MarshalSSZTo ssz marshals the ExecutionPayloadHeaderCapella object to a target array
+func (e *ExecutionPayloadHeaderCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(568)
+
+	// Field (0) 'ParentHash'
+	dst = append(dst, e.ParentHash[:]...)
+
+	// Field (1) 'FeeRecipient'
+	dst = append(dst, e.FeeRecipient[:]...)
+
+	// Field (2) 'StateRoot'
+	dst = append(dst, e.StateRoot[:]...)
+
+	// Field (3) 'ReceiptsRoot'
+	dst = append(dst, e.ReceiptsRoot[:]...)
+
+	// Field (4) 'LogsBloom'
+	dst = append(dst, e.LogsBloom[:]...)
+
+	// Field (5) 'Random'
+	dst = append(dst, e.Random[:]...)
+
+	// Field (6) 'BlockNumber'
+	dst = ssz.MarshalUint64(dst, e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	dst = ssz.MarshalUint64(dst, e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	dst = ssz.MarshalUint64(dst, e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	dst = ssz.MarshalUint64(dst, e.Timestamp)
+
+	// Offset (10) 'ExtraData'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.ExtraData)
+
+	// Field (11) 'BaseFeePerGas'
+	dst = append(dst, e.BaseFeePerGas[:]...)
+
+	// Field (12) 'BlockHash'
+	dst = append(dst, e.BlockHash[:]...)
+
+	// Field (13) 'TransactionsRoot'
+	dst = append(dst, e.TransactionsRoot[:]...)
+
+	// Field (14) 'WithdrawalsRoot'
+	dst = append(dst, e.WithdrawalsRoot[:]...)
+
+	// Field (10) 'ExtraData'
+	if len(e.ExtraData) > 32 {
+		err = ssz.ErrBytesLength
+		return
+	}
+	dst = append(dst, e.ExtraData...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the ExecutionPayloadHeaderCapella object
+func (e *ExecutionPayloadHeaderCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 568 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o10 uint64
+
+	// Field (0) 'ParentHash'
+	copy(e.ParentHash[:], buf[0:32])
+
+	// Field (1) 'FeeRecipient'
+	copy(e.FeeRecipient[:], buf[32:52])
+
+	// Field (2) 'StateRoot'
+	copy(e.StateRoot[:], buf[52:84])
+
+	// Field (3) 'ReceiptsRoot'
+	copy(e.ReceiptsRoot[:], buf[84:116])
+
+	// Field (4) 'LogsBloom'
+	copy(e.LogsBloom[:], buf[116:372])
+
+	// Field (5) 'Random'
+	copy(e.Random[:], buf[372:404])
+
+	// Field (6) 'BlockNumber'
+	e.BlockNumber = ssz.UnmarshallUint64(buf[404:412])
+
+	// Field (7) 'GasLimit'
+	e.GasLimit = ssz.UnmarshallUint64(buf[412:420])
+
+	// Field (8) 'GasUsed'
+	e.GasUsed = ssz.UnmarshallUint64(buf[420:428])
+
+	// Field (9) 'Timestamp'
+	e.Timestamp = ssz.UnmarshallUint64(buf[428:436])
+
+	// Offset (10) 'ExtraData'
+	if o10 = ssz.ReadOffset(buf[436:440]); o10 > size {
+		return ssz.ErrOffset
+	}
+
+	if o10 < 568 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	copy(e.BaseFeePerGas[:], buf[440:472])
+
+	// Field (12) 'BlockHash'
+	copy(e.BlockHash[:], buf[472:504])
+
+	// Field (13) 'TransactionsRoot'
+	copy(e.TransactionsRoot[:], buf[504:536])
+
+	// Field (14) 'WithdrawalsRoot'
+	copy(e.WithdrawalsRoot[:], buf[536:568])
+
+	// Field (10) 'ExtraData'
+	{
+		buf = tail[o10:]
+		if len(buf) > 32 {
+			return ssz.ErrBytesLength
+		}
+		if cap(e.ExtraData) == 0 {
+			e.ExtraData = make([]byte, 0, len(buf))
+		}
+		e.ExtraData = append(e.ExtraData, buf...)
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the ExecutionPayloadHeaderCapella object
+func (e *ExecutionPayloadHeaderCapella) SizeSSZ() (size int) {
+	size = 568
+
+	// Field (10) 'ExtraData'
+	size += len(e.ExtraData)
+
+	return
+}
+
+// HashTreeRoot ssz hashes the ExecutionPayloadHeaderCapella object
+func (e *ExecutionPayloadHeaderCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(e)
+}
+
+// HashTreeRootWith ssz hashes the ExecutionPayloadHeaderCapella object with a hasher
+func (e *ExecutionPayloadHeaderCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'ParentHash'
+	hh.PutBytes(e.ParentHash[:])
+
+	// Field (1) 'FeeRecipient'
+	hh.PutBytes(e.FeeRecipient[:])
+
+	// Field (2) 'StateRoot'
+	hh.PutBytes(e.StateRoot[:])
+
+	// Field (3) 'ReceiptsRoot'
+	hh.PutBytes(e.ReceiptsRoot[:])
+
+	// Field (4) 'LogsBloom'
+	hh.PutBytes(e.LogsBloom[:])
+
+	// Field (5) 'Random'
+	hh.PutBytes(e.Random[:])
+
+	// Field (6) 'BlockNumber'
+	hh.PutUint64(e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	hh.PutUint64(e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	hh.PutUint64(e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	hh.PutUint64(e.Timestamp)
+
+	// Field (10) 'ExtraData'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(e.ExtraData))
+		if byteLen > 32 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(e.ExtraData)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (32+31)/32)
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	hh.PutBytes(e.BaseFeePerGas[:])
+
+	// Field (12) 'BlockHash'
+	hh.PutBytes(e.BlockHash[:])
+
+	// Field (13) 'TransactionsRoot'
+	hh.PutBytes(e.TransactionsRoot[:])
+
+	// Field (14) 'WithdrawalsRoot'
+	hh.PutBytes(e.WithdrawalsRoot[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the ExecutionPayloadHeaderDeneb object
+func (e *ExecutionPayloadHeaderDeneb) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(e)
+}
+
+// MarshalSSZTo ssz marshals the ExecutionPayloadHeaderDeneb object to a target array
+func (e *ExecutionPayloadHeaderDeneb) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(584)
+
+	// Field (0) 'ParentHash'
+	dst = append(dst, e.ParentHash[:]...)
+
+	// Field (1) 'FeeRecipient'
+	dst = append(dst, e.FeeRecipient[:]...)
+
+	// Field (2) 'StateRoot'
+	dst = append(dst, e.StateRoot[:]...)
+
+	// Field (3) 'ReceiptsRoot'
+	dst = append(dst, e.ReceiptsRoot[:]...)
+
+	// Field (4) 'LogsBloom'
+	dst = append(dst, e.LogsBloom[:]...)
+
+	// Field (5) 'Random'
+	dst = append(dst, e.Random[:]...)
+
+	// Field (6) 'BlockNumber'
+	dst = ssz.MarshalUint64(dst, e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	dst = ssz.MarshalUint64(dst, e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	dst = ssz.MarshalUint64(dst, e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	dst = ssz.MarshalUint64(dst, e.Timestamp)
+
+	// Offset (10) 'ExtraData'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.ExtraData)
+
+	// Field (11) 'BaseFeePerGas'
+	dst = append(dst, e.BaseFeePerGas[:]...)
+
+	// Field (12) 'BlockHash'
+	dst = append(dst, e.BlockHash[:]...)
+
+	// Field (13) 'TransactionsRoot'
+	dst = append(dst, e.TransactionsRoot[:]...)
+
+	// Field (14) 'WithdrawalsRoot'
+	dst = append(dst, e.WithdrawalsRoot[:]...)
+
+	// Field (15) 'BlobGasUsed'
+	dst = ssz.MarshalUint64(dst, e.BlobGasUsed)
+
+	// Field (16) 'ExcessBlobGas'
+	dst = ssz.MarshalUint64(dst, e.ExcessBlobGas)
+
+	// Field (10) 'ExtraData'
+	if len(e.ExtraData) > 32 {
+		err = ssz.ErrBytesLength
+		return
+	}
+	dst = append(dst, e.ExtraData...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the ExecutionPayloadHeaderDeneb object
+func (e *ExecutionPayloadHeaderDeneb) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 584 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o10 uint64
+
+	// Field (0) 'ParentHash'
+	copy(e.ParentHash[:], buf[0:32])
+
+	// Field (1) 'FeeRecipient'
+	copy(e.FeeRecipient[:], buf[32:52])
+
+	// Field (2) 'StateRoot'
+	copy(e.StateRoot[:], buf[52:84])
+
+	// Field (3) 'ReceiptsRoot'
+	copy(e.ReceiptsRoot[:], buf[84:116])
+
+	// Field (4) 'LogsBloom'
+	copy(e.LogsBloom[:], buf[116:372])
+
+	// Field (5) 'Random'
+	copy(e.Random[:], buf[372:404])
+
+	// Field (6) 'BlockNumber'
+	e.BlockNumber = ssz.UnmarshallUint64(buf[404:412])
+
+	// Field (7) 'GasLimit'
+	e.GasLimit = ssz.UnmarshallUint64(buf[412:420])
+
+	// Field (8) 'GasUsed'
+	e.GasUsed = ssz.UnmarshallUint64(buf[420:428])
+
+	// Field (9) 'Timestamp'
+	e.Timestamp = ssz.UnmarshallUint64(buf[428:436])
+
+	// Offset (10) 'ExtraData'
+	if o10 = ssz.ReadOffset(buf[436:440]); o10 > size {
+		return ssz.ErrOffset
+	}
+
+	if o10 < 584 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	copy(e.BaseFeePerGas[:], buf[440:472])
+
+	// Field (12) 'BlockHash'
+	copy(e.BlockHash[:], buf[472:504])
+
+	// Field (13) 'TransactionsRoot'
+	copy(e.TransactionsRoot[:], buf[504:536])
+
+	// Field (14) 'WithdrawalsRoot'
+	copy(e.WithdrawalsRoot[:], buf[536:568])
+
+	// Field (15) 'BlobGasUsed'
+	e.BlobGasUsed = ssz.UnmarshallUint64(buf[568:576])
+
+	// Field (16) 'ExcessBlobGas'
+	e.ExcessBlobGas = ssz.UnmarshallUint64(buf[576:584])
+
+	// Field (10) 'ExtraData'
+	{
+		buf = tail[o10:]
+		if len(buf) > 32 {
+			return ssz.ErrBytesLength
+		}
+		if cap(e.ExtraData) == 0 {
+			e.ExtraData = make([]byte, 0, len(buf))
+		}
+		e.ExtraData = append(e.ExtraData, buf...)
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the ExecutionPayloadHeaderDeneb object
+func (e *ExecutionPayloadHeaderDeneb) SizeSSZ() (size int) {
+	size = 584
+
+	// Field (10) 'ExtraData'
+	size += len(e.ExtraData)
+
+	return
+}
+
+// HashTreeRoot ssz hashes the ExecutionPayloadHeaderDeneb object
+func (e *ExecutionPayloadHeaderDeneb) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(e)
+}
+
+// HashTreeRootWith ssz hashes the ExecutionPayloadHeaderDeneb object with a hasher
+func (e *ExecutionPayloadHeaderDeneb) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'ParentHash'
+	hh.PutBytes(e.ParentHash[:])
+
+	// Field (1) 'FeeRecipient'
+	hh.PutBytes(e.FeeRecipient[:])
+
+	// Field (2) 'StateRoot'
+	hh.PutBytes(e.StateRoot[:])
+
+	// Field (3) 'ReceiptsRoot'
+	hh.PutBytes(e.ReceiptsRoot[:])
+
+	// Field (4) 'LogsBloom'
+	hh.PutBytes(e.LogsBloom[:])
+
+	// Field (5) 'Random'
+	hh.PutBytes(e.Random[:])
+
+	// Field (6) 'BlockNumber'
+	hh.PutUint64(e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	hh.PutUint64(e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	hh.PutUint64(e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	hh.PutUint64(e.Timestamp)
+
+	// Field (10) 'ExtraData'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(e.ExtraData))
+		if byteLen > 32 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(e.ExtraData)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (32+31)/32)
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	hh.PutBytes(e.BaseFeePerGas[:])
+
+	// Field (12) 'BlockHash'
+	hh.PutBytes(e.BlockHash[:])
+
+	// Field (13) 'TransactionsRoot'
+	hh.PutBytes(e.TransactionsRoot[:])
+
+	// Field (14) 'WithdrawalsRoot'
+	hh.PutBytes(e.WithdrawalsRoot[:])
+
+	// Field (15) 'BlobGasUsed'
+	hh.PutUint64(e.BlobGasUsed)
+
+	// Field (16) 'ExcessBlobGas'
+	hh.PutUint64(e.ExcessBlobGas)
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the BuilderBidCapella object
+func (b *BuilderBidCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(b)
+}
+
+// MarshalSSZTo ssz marshals the BuilderBidCapella object to a target array
+func (b *BuilderBidCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(84)
+
+	// Offset (0) 'Header'
+	dst = ssz.WriteOffset(dst, offset)
+	if b.Header == nil {
+		b.Header = new(ExecutionPayloadHeaderCapella)
+	}
+	offset += b.Header.SizeSSZ()
+
+	// Field (1) 'Value'
+	dst = append(dst, b.Value[:]...)
+
+	// Field (2) 'Pubkey'
+	dst = append(dst, b.Pubkey[:]...)
+
+	// Field (0) 'Header'
+	if dst, err = b.Header.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the BuilderBidCapella object
+func (b *BuilderBidCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 84 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0 uint64
+
+	// Offset (0) 'Header'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 84 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (1) 'Value'
+	copy(b.Value[:], buf[4:36])
+
+	// Field (2) 'Pubkey'
+	copy(b.Pubkey[:], buf[36:84])
+
+	// Field (0) 'Header'
+	{
+		buf = tail[o0:]
+		if b.Header == nil {
+			b.Header = new(ExecutionPayloadHeaderCapella)
+		}
+		if err = b.Header.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the BuilderBidCapella object
+func (b *BuilderBidCapella) SizeSSZ() (size int) {
+	size = 84
+
+	// Field (0) 'Header'
+	if b.Header == nil {
+		b.Header = new(ExecutionPayloadHeaderCapella)
+	}
+	size += b.Header.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the BuilderBidCapella object
+func (b *BuilderBidCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(b)
+}
+
+// HashTreeRootWith ssz hashes the BuilderBidCapella object with a hasher
+func (b *BuilderBidCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Header'
+	if err = b.Header.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Value'
+	hh.PutBytes(b.Value[:])
+
+	// Field (2) 'Pubkey'
+	hh.PutBytes(b.Pubkey[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the SignedBuilderBidCapella object
+func (s *SignedBuilderBidCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SignedBuilderBidCapella object to a target array
+func (s *SignedBuilderBidCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(100)
+
+	// Offset (0) 'Message'
+	dst = ssz.WriteOffset(dst, offset)
+	if s.Message == nil {
+		s.Message = new(BuilderBidCapella)
+	}
+	offset += s.Message.SizeSSZ()
+
+	// Field (1) 'Signature'
+	dst = append(dst, s.Signature[:]...)
+
+	// Field (0) 'Message'
+	if dst, err = s.Message.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SignedBuilderBidCapella object
+func (s *SignedBuilderBidCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 100 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0 uint64
+
+	// Offset (0) 'Message'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 100 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (1) 'Signature'
+	copy(s.Signature[:], buf[4:100])
+
+	// Field (0) 'Message'
+	{
+		buf = tail[o0:]
+		if s.Message == nil {
+			s.Message = new(BuilderBidCapella)
+		}
+		if err = s.Message.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SignedBuilderBidCapella object
+func (s *SignedBuilderBidCapella) SizeSSZ() (size int) {
+	size = 100
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BuilderBidCapella)
+	}
+	size += s.Message.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the SignedBuilderBidCapella object
+func (s *SignedBuilderBidCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SignedBuilderBidCapella object with a hasher
+func (s *SignedBuilderBidCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Message'
+	if err = s.Message.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	hh.PutBytes(s.Signature[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the BidTrace object
+func (b *BidTrace) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(b)
+}
+
+// MarshalSSZTo ssz marshals the BidTrace object to a target array
+func (b *BidTrace) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Slot'
+	dst = ssz.MarshalUint64(dst, b.Slot)
+
+	// Field (1) 'ParentHash'
+	dst = append(dst, b.ParentHash[:]...)
+
+	// Field (2) 'BlockHash'
+	dst = append(dst, b.BlockHash[:]...)
+
+	// Field (3) 'BuilderPubkey'
+	dst = append(dst, b.BuilderPubkey[:]...)
+
+	// Field (4) 'ProposerPubkey'
+	dst = append(dst, b.ProposerPubkey[:]...)
+
+	// Field (5) 'ProposerFeeRecipient'
+	dst = append(dst, b.ProposerFeeRecipient[:]...)
+
+	// Field (6) 'GasLimit'
+	dst = ssz.MarshalUint64(dst, b.GasLimit)
+
+	// Field (7) 'GasUsed'
+	dst = ssz.MarshalUint64(dst, b.GasUsed)
+
+	// Field (8) 'Value'
+	dst = append(dst, b.Value[:]...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the BidTrace object
+func (b *BidTrace) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 236 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Slot'
+	b.Slot = ssz.UnmarshallUint64(buf[0:8])
+
+	// Field (1) 'ParentHash'
+	copy(b.ParentHash[:], buf[8:40])
+
+	// Field (2) 'BlockHash'
+	copy(b.BlockHash[:], buf[40:72])
+
+	// Field (3) 'BuilderPubkey'
+	copy(b.BuilderPubkey[:], buf[72:120])
+
+	// Field (4) 'ProposerPubkey'
+	copy(b.ProposerPubkey[:], buf[120:168])
+
+	// Field (5) 'ProposerFeeRecipient'
+	copy(b.ProposerFeeRecipient[:], buf[168:188])
+
+	// Field (6) 'GasLimit'
+	b.GasLimit = ssz.UnmarshallUint64(buf[188:196])
+
+	// Field (7) 'GasUsed'
+	b.GasUsed = ssz.UnmarshallUint64(buf[196:204])
+
+	// Field (8) 'Value'
+	copy(b.Value[:], buf[204:236])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the BidTrace object
+func (b *BidTrace) SizeSSZ() (size int) {
+	size = 236
+	return
+}
+
+// HashTreeRoot ssz hashes the BidTrace object
+func (b *BidTrace) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(b)
+}
+
+// HashTreeRootWith ssz hashes the BidTrace object with a hasher
+func (b *BidTrace) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Slot'
+	hh.PutUint64(b.Slot)
+
+	// Field (1) 'ParentHash'
+	hh.PutBytes(b.ParentHash[:])
+
+	// Field (2) 'BlockHash'
+	hh.PutBytes(b.BlockHash[:])
+
+	// Field (3) 'BuilderPubkey'
+	hh.PutBytes(b.BuilderPubkey[:])
+
+	// Field (4) 'ProposerPubkey'
+	hh.PutBytes(b.ProposerPubkey[:])
+
+	// Field (5) 'ProposerFeeRecipient'
+	hh.PutBytes(b.ProposerFeeRecipient[:])
+
+	// Field (6) 'GasLimit'
+	hh.PutUint64(b.GasLimit)
+
+	// Field (7) 'GasUsed'
+	hh.PutUint64(b.GasUsed)
+
+	// Field (8) 'Value'
+	hh.PutBytes(b.Value[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the SignedBidTrace object
+func (s *SignedBidTrace) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SignedBidTrace object to a target array
+func (s *SignedBidTrace) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BidTrace)
+	}
+	if dst, err = s.Message.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	dst = append(dst, s.Signature[:]...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SignedBidTrace object
+func (s *SignedBidTrace) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 332 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BidTrace)
+	}
+	if err = s.Message.UnmarshalSSZ(buf[0:236]); err != nil {
+		return err
+	}
+
+	// Field (1) 'Signature'
+	copy(s.Signature[:], buf[236:332])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SignedBidTrace object
+func (s *SignedBidTrace) SizeSSZ() (size int) {
+	size = 332
+	return
+}
+
+// HashTreeRoot ssz hashes the SignedBidTrace object
+func (s *SignedBidTrace) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SignedBidTrace object with a hasher
+func (s *SignedBidTrace) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Message'
+	if err = s.Message.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	hh.PutBytes(s.Signature[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the BLSToExecutionChange object
+func (b *BLSToExecutionChange) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(b)
+}
+
+// MarshalSSZTo ssz marshals the BLSToExecutionChange object to a target array
+func (b *BLSToExecutionChange) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'ValidatorIndex'
+	dst = ssz.MarshalUint64(dst, b.ValidatorIndex)
+
+	// Field (1) 'FromBLSPubkey'
+	dst = append(dst, b.FromBLSPubkey[:]...)
+
+	// Field (2) 'ToExecutionAddress'
+	dst = append(dst, b.ToExecutionAddress[:]...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the BLSToExecutionChange object
+func (b *BLSToExecutionChange) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 76 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'ValidatorIndex'
+	b.ValidatorIndex = ssz.UnmarshallUint64(buf[0:8])
+
+	// Field (1) 'FromBLSPubkey'
+	copy(b.FromBLSPubkey[:], buf[8:56])
+
+	// Field (2) 'ToExecutionAddress'
+	copy(b.ToExecutionAddress[:], buf[56:76])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the BLSToExecutionChange object
+func (b *BLSToExecutionChange) SizeSSZ() (size int) {
+	size = 76
+	return
+}
+
+// HashTreeRoot ssz hashes the BLSToExecutionChange object
+func (b *BLSToExecutionChange) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(b)
+}
+
+// HashTreeRootWith ssz hashes the BLSToExecutionChange object with a hasher
+func (b *BLSToExecutionChange) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'ValidatorIndex'
+	hh.PutUint64(b.ValidatorIndex)
+
+	// Field (1) 'FromBLSPubkey'
+	hh.PutBytes(b.FromBLSPubkey[:])
+
+	// Field (2) 'ToExecutionAddress'
+	hh.PutBytes(b.ToExecutionAddress[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the SignedBLSToExecutionChange object
+func (s *SignedBLSToExecutionChange) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SignedBLSToExecutionChange object to a target array
+func (s *SignedBLSToExecutionChange) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BLSToExecutionChange)
+	}
+	if dst, err = s.Message.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	dst = append(dst, s.Signature[:]...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SignedBLSToExecutionChange object
+func (s *SignedBLSToExecutionChange) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 172 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BLSToExecutionChange)
+	}
+	if err = s.Message.UnmarshalSSZ(buf[0:76]); err != nil {
+		return err
+	}
+
+	// Field (1) 'Signature'
+	copy(s.Signature[:], buf[76:172])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SignedBLSToExecutionChange object
+func (s *SignedBLSToExecutionChange) SizeSSZ() (size int) {
+	size = 172
+	return
+}
+
+// HashTreeRoot ssz hashes the SignedBLSToExecutionChange object
+func (s *SignedBLSToExecutionChange) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SignedBLSToExecutionChange object with a hasher
+func (s *SignedBLSToExecutionChange) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Message'
+	if err = s.Message.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	hh.PutBytes(s.Signature[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the BlindedBeaconBlockBodyCapella object
+func (b *BlindedBeaconBlockBodyCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(b)
+}
+
+// MarshalSSZTo ssz marshals the BlindedBeaconBlockBodyCapella object to a target array
+func (b *BlindedBeaconBlockBodyCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(388)
+
+	// Field (0) 'RandaoReveal'
+	dst = append(dst, b.RandaoReveal[:]...)
+
+	// Field (1) 'Eth1Data'
+	if b.Eth1Data == nil {
+		b.Eth1Data = new(Eth1Data)
+	}
+	if dst, err = b.Eth1Data.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (2) 'Graffiti'
+	dst = append(dst, b.Graffiti[:]...)
+
+	// Offset (3) 'ProposerSlashings'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(b.ProposerSlashings) * 416
+
+	// Offset (4) 'AttesterSlashings'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(b.AttesterSlashings); ii++ {
+		offset += 4
+		offset += b.AttesterSlashings[ii].SizeSSZ()
+	}
+
+	// Offset (5) 'Attestations'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(b.Attestations); ii++ {
+		offset += 4
+		offset += b.Attestations[ii].SizeSSZ()
+	}
+
+	// Offset (6) 'Deposits'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(b.Deposits) * 1240
+
+	// Offset (7) 'VoluntaryExits'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(b.VoluntaryExits) * 16
+
+	// Field (8) 'SyncAggregate'
+	if b.SyncAggregate == nil {
+		b.SyncAggregate = new(SyncAggregate)
+	}
+	if dst, err = b.SyncAggregate.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Offset (9) 'ExecutionPayloadHeader'
+	dst = ssz.WriteOffset(dst, offset)
+	if b.ExecutionPayloadHeader == nil {
+		b.ExecutionPayloadHeader = new(ExecutionPayloadHeaderCapella)
+	}
+	offset += b.ExecutionPayloadHeader.SizeSSZ()
+
+	// Offset (10) 'BLSToExecutionChanges'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(b.BLSToExecutionChanges) * 172
+
+	// Field (3) 'ProposerSlashings'
+	if len(b.ProposerSlashings) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(b.ProposerSlashings); ii++ {
+		if dst, err = b.ProposerSlashings[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	// Field (4) 'AttesterSlashings'
+	if len(b.AttesterSlashings) > 2 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	{
+		offset = 4 * len(b.AttesterSlashings)
+		for ii := 0; ii < len(b.AttesterSlashings); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += b.AttesterSlashings[ii].SizeSSZ()
+		}
+	}
+	for ii := 0; ii < len(b.AttesterSlashings); ii++ {
+		if dst, err = b.AttesterSlashings[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	// Field (5) 'Attestations'
+	if len(b.Attestations) > 128 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	{
+		offset = 4 * len(b.Attestations)
+		for ii := 0; ii < len(b.Attestations); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += b.Attestations[ii].SizeSSZ()
+		}
+	}
+	for ii := 0; ii < len(b.Attestations); ii++ {
+		if dst, err = b.Attestations[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	// Field (6) 'Deposits'
+	if len(b.Deposits) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(b.Deposits); ii++ {
+		if dst, err = b.Deposits[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	// Field (7) 'VoluntaryExits'
+	if len(b.VoluntaryExits) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(b.VoluntaryExits); ii++ {
+		if dst, err = b.VoluntaryExits[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	// Field (9) 'ExecutionPayloadHeader'
+	if dst, err = b.ExecutionPayloadHeader.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	// Field (10) 'BLSToExecutionChanges'
+	if len(b.BLSToExecutionChanges) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(b.BLSToExecutionChanges); ii++ {
+		if dst, err = b.BLSToExecutionChanges[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the BlindedBeaconBlockBodyCapella object
+func (b *BlindedBeaconBlockBodyCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 388 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o3, o4, o5, o6, o7, o9, o10 uint64
+
+	// Field (0) 'RandaoReveal'
+	copy(b.RandaoReveal[:], buf[0:96])
+
+	// Field (1) 'Eth1Data'
+	if b.Eth1Data == nil {
+		b.Eth1Data = new(Eth1Data)
+	}
+	if err = b.Eth1Data.UnmarshalSSZ(buf[96:168]); err != nil {
+		return err
+	}
+
+	// Field (2) 'Graffiti'
+	copy(b.Graffiti[:], buf[168:200])
+
+	// Offset (3) 'ProposerSlashings'
+	if o3 = ssz.ReadOffset(buf[200:204]); o3 > size {
+		return ssz.ErrOffset
+	}
+
+	if o3 < 388 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Offset (4) 'AttesterSlashings'
+	if o4 = ssz.ReadOffset(buf[204:208]); o4 > size || o3 > o4 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (5) 'Attestations'
+	if o5 = ssz.ReadOffset(buf[208:212]); o5 > size || o4 > o5 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (6) 'Deposits'
+	if o6 = ssz.ReadOffset(buf[212:216]); o6 > size || o5 > o6 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (7) 'VoluntaryExits'
+	if o7 = ssz.ReadOffset(buf[216:220]); o7 > size || o6 > o7 {
+		return ssz.ErrOffset
+	}
+
+	// Field (8) 'SyncAggregate'
+	if b.SyncAggregate == nil {
+		b.SyncAggregate = new(SyncAggregate)
+	}
+	if err = b.SyncAggregate.UnmarshalSSZ(buf[220:380]); err != nil {
+		return err
+	}
+
+	// Offset (9) 'ExecutionPayloadHeader'
+	if o9 = ssz.ReadOffset(buf[380:384]); o9 > size || o7 > o9 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (10) 'BLSToExecutionChanges'
+	if o10 = ssz.ReadOffset(buf[384:388]); o10 > size || o9 > o10 {
+		return ssz.ErrOffset
+	}
+
+	// Field (3) 'ProposerSlashings'
+	{
+		buf = tail[o3:o4]
+		num, err := ssz.DivideInt2(len(buf), 416, 16)
+		if err != nil {
+			return err
+		}
+		b.ProposerSlashings = make([]*ProposerSlashing, num)
+		for ii := 0; ii < num; ii++ {
+			if b.ProposerSlashings[ii] == nil {
+				b.ProposerSlashings[ii] = new(ProposerSlashing)
+			}
+			if err = b.ProposerSlashings[ii].UnmarshalSSZ(buf[ii*416 : (ii+1)*416]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Field (4) 'AttesterSlashings'
+	{
+		buf = tail[o4:o5]
+		num, err := ssz.DecodeDynamicLength(buf, 2)
+		if err != nil {
+			return err
+		}
+		b.AttesterSlashings = make([]*AttesterSlashing, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if b.AttesterSlashings[indx] == nil {
+				b.AttesterSlashings[indx] = new(AttesterSlashing)
+			}
+			if err = b.AttesterSlashings[indx].UnmarshalSSZ(buf); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Field (5) 'Attestations'
+	{
+		buf = tail[o5:o6]
+		num, err := ssz.DecodeDynamicLength(buf, 128)
+		if err != nil {
+			return err
+		}
+		b.Attestations = make([]*Attestation, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if b.Attestations[indx] == nil {
+				b.Attestations[indx] = new(Attestation)
+			}
+			if err = b.Attestations[indx].UnmarshalSSZ(buf); err != nil {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Field (6) 'Deposits'
+	{
+		buf = tail[o6:o7]
+		num, err := ssz.DivideInt2(len(buf), 1240, 16)
+		if err != nil {
+			return err
+		}
+		b.Deposits = make([]*Deposit, num)
+		for ii := 0; ii < num; ii++ {
+			if b.Deposits[ii] == nil {
+				b.Deposits[ii] = new(Deposit)
+			}
+			if err = b.Deposits[ii].UnmarshalSSZ(buf[ii*1240 : (ii+1)*1240]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Field (7) 'VoluntaryExits'
+	{
+		buf = tail[o7:o9]
+		num, err := ssz.DivideInt2(len(buf), 16, 16)
+		if err != nil {
+			return err
+		}
+		b.VoluntaryExits = make([]*VoluntaryExit, num)
+		for ii := 0; ii < num; ii++ {
+			if b.VoluntaryExits[ii] == nil {
+				b.VoluntaryExits[ii] = new(VoluntaryExit)
+			}
+			if err = b.VoluntaryExits[ii].UnmarshalSSZ(buf[ii*16 : (ii+1)*16]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Field (9) 'ExecutionPayloadHeader'
+	{
+		buf = tail[o9:o10]
+		if b.ExecutionPayloadHeader == nil {
+			b.ExecutionPayloadHeader = new(ExecutionPayloadHeaderCapella)
+		}
+		if err = b.ExecutionPayloadHeader.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+
+	// Field (10) 'BLSToExecutionChanges'
+	{
+		buf = tail[o10:]
+		num, err := ssz.DivideInt2(len(buf), 172, 16)
+		if err != nil {
+			return err
+		}
+		b.BLSToExecutionChanges = make([]*SignedBLSToExecutionChange, num)
+		for ii := 0; ii < num; ii++ {
+			if b.BLSToExecutionChanges[ii] == nil {
+				b.BLSToExecutionChanges[ii] = new(SignedBLSToExecutionChange)
+			}
+			if err = b.BLSToExecutionChanges[ii].UnmarshalSSZ(buf[ii*172 : (ii+1)*172]); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the BlindedBeaconBlockBodyCapella object
+func (b *BlindedBeaconBlockBodyCapella) SizeSSZ() (size int) {
+	size = 388
+
+	// Field (3) 'ProposerSlashings'
+	size += len(b.ProposerSlashings) * 416
+
+	// Field (4) 'AttesterSlashings'
+	for ii := 0; ii < len(b.AttesterSlashings); ii++ {
+		size += 4
+		size += b.AttesterSlashings[ii].SizeSSZ()
+	}
+
+	// Field (5) 'Attestations'
+	for ii := 0; ii < len(b.Attestations); ii++ {
+		size += 4
+		size += b.Attestations[ii].SizeSSZ()
+	}
+
+	// Field (6) 'Deposits'
+	size += len(b.Deposits) * 1240
+
+	// Field (7) 'VoluntaryExits'
+	size += len(b.VoluntaryExits) * 16
+
+	// Field (9) 'ExecutionPayloadHeader'
+	if b.ExecutionPayloadHeader == nil {
+		b.ExecutionPayloadHeader = new(ExecutionPayloadHeaderCapella)
+	}
+	size += b.ExecutionPayloadHeader.SizeSSZ()
+
+	// Field (10) 'BLSToExecutionChanges'
+	size += len(b.BLSToExecutionChanges) * 172
+
+	return
+}
+
+// HashTreeRoot ssz hashes the BlindedBeaconBlockBodyCapella object
+func (b *BlindedBeaconBlockBodyCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(b)
+}
+
+// HashTreeRootWith ssz hashes the BlindedBeaconBlockBodyCapella object with a hasher
+func (b *BlindedBeaconBlockBodyCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'RandaoReveal'
+	hh.PutBytes(b.RandaoReveal[:])
+
+	// Field (1) 'Eth1Data'
+	if err = b.Eth1Data.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (2) 'Graffiti'
+	hh.PutBytes(b.Graffiti[:])
+
+	// Field (3) 'ProposerSlashings'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(b.ProposerSlashings))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range b.ProposerSlashings {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	// Field (4) 'AttesterSlashings'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(b.AttesterSlashings))
+		if num > 2 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range b.AttesterSlashings {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 2)
+	}
+
+	// Field (5) 'Attestations'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(b.Attestations))
+		if num > 128 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range b.Attestations {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 128)
+	}
+
+	// Field (6) 'Deposits'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(b.Deposits))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range b.Deposits {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	// Field (7) 'VoluntaryExits'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(b.VoluntaryExits))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range b.VoluntaryExits {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	// Field (8) 'SyncAggregate'
+	if err = b.SyncAggregate.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (9) 'ExecutionPayloadHeader'
+	if err = b.ExecutionPayloadHeader.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (10) 'BLSToExecutionChanges'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(b.BLSToExecutionChanges))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range b.BLSToExecutionChanges {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the BlindedBeaconBlockCapella object
+func (b *BlindedBeaconBlockCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(b)
+}
+
+// MarshalSSZTo ssz marshals the BlindedBeaconBlockCapella object to a target array
+func (b *BlindedBeaconBlockCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(84)
+
+	// Field (0) 'Slot'
+	dst = ssz.MarshalUint64(dst, b.Slot)
+
+	// Field (1) 'ProposerIndex'
+	dst = ssz.MarshalUint64(dst, b.ProposerIndex)
+
+	// Field (2) 'ParentRoot'
+	dst = append(dst, b.ParentRoot[:]...)
+
+	// Field (3) 'StateRoot'
+	dst = append(dst, b.StateRoot[:]...)
+
+	// Offset (4) 'Body'
+	dst = ssz.WriteOffset(dst, offset)
+	if b.Body == nil {
+		b.Body = new(BlindedBeaconBlockBodyCapella)
+	}
+	offset += b.Body.SizeSSZ()
+
+	// Field (4) 'Body'
+	if dst, err = b.Body.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the BlindedBeaconBlockCapella object
+func (b *BlindedBeaconBlockCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 84 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o4 uint64
+
+	// Field (0) 'Slot'
+	b.Slot = ssz.UnmarshallUint64(buf[0:8])
+
+	// Field (1) 'ProposerIndex'
+	b.ProposerIndex = ssz.UnmarshallUint64(buf[8:16])
+
+	// Field (2) 'ParentRoot'
+	copy(b.ParentRoot[:], buf[16:48])
+
+	// Field (3) 'StateRoot'
+	copy(b.StateRoot[:], buf[48:80])
+
+	// Offset (4) 'Body'
+	if o4 = ssz.ReadOffset(buf[80:84]); o4 > size {
+		return ssz.ErrOffset
+	}
+
+	if o4 < 84 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (4) 'Body'
+	{
+		buf = tail[o4:]
+		if b.Body == nil {
+			b.Body = new(BlindedBeaconBlockBodyCapella)
+		}
+		if err = b.Body.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the BlindedBeaconBlockCapella object
+func (b *BlindedBeaconBlockCapella) SizeSSZ() (size int) {
+	size = 84
+
+	// Field (4) 'Body'
+	if b.Body == nil {
+		b.Body = new(BlindedBeaconBlockBodyCapella)
+	}
+	size += b.Body.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the BlindedBeaconBlockCapella object
+func (b *BlindedBeaconBlockCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(b)
+}
+
+// HashTreeRootWith ssz hashes the BlindedBeaconBlockCapella object with a hasher
+func (b *BlindedBeaconBlockCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Slot'
+	hh.PutUint64(b.Slot)
+
+	// Field (1) 'ProposerIndex'
+	hh.PutUint64(b.ProposerIndex)
+
+	// Field (2) 'ParentRoot'
+	hh.PutBytes(b.ParentRoot[:])
+
+	// Field (3) 'StateRoot'
+	hh.PutBytes(b.StateRoot[:])
+
+	// Field (4) 'Body'
+	if err = b.Body.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the SignedBlindedBeaconBlockCapella object
+func (s *SignedBlindedBeaconBlockCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SignedBlindedBeaconBlockCapella object to a target array
+func (s *SignedBlindedBeaconBlockCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(100)
+
+	// Offset (0) 'Message'
+	dst = ssz.WriteOffset(dst, offset)
+	if s.Message == nil {
+		s.Message = new(BlindedBeaconBlockCapella)
+	}
+	offset += s.Message.SizeSSZ()
+
+	// Field (1) 'Signature'
+	dst = append(dst, s.Signature[:]...)
+
+	// Field (0) 'Message'
+	if dst, err = s.Message.MarshalSSZTo(dst); err != nil {
+		return
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SignedBlindedBeaconBlockCapella object
+func (s *SignedBlindedBeaconBlockCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 100 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0 uint64
+
+	// Offset (0) 'Message'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 100 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (1) 'Signature'
+	copy(s.Signature[:], buf[4:100])
+
+	// Field (0) 'Message'
+	{
+		buf = tail[o0:]
+		if s.Message == nil {
+			s.Message = new(BlindedBeaconBlockCapella)
+		}
+		if err = s.Message.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SignedBlindedBeaconBlockCapella object
+func (s *SignedBlindedBeaconBlockCapella) SizeSSZ() (size int) {
+	size = 100
+
+	// Field (0) 'Message'
+	if s.Message == nil {
+		s.Message = new(BlindedBeaconBlockCapella)
+	}
+	size += s.Message.SizeSSZ()
+
+	return
+}
+
+// HashTreeRoot ssz hashes the SignedBlindedBeaconBlockCapella object
+func (s *SignedBlindedBeaconBlockCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SignedBlindedBeaconBlockCapella object with a hasher
+func (s *SignedBlindedBeaconBlockCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Message'
+	if err = s.Message.HashTreeRootWith(hh); err != nil {
+		return
+	}
+
+	// Field (1) 'Signature'
+	hh.PutBytes(s.Signature[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the transactions object
+func (t *transactions) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(t)
+}
+
+// MarshalSSZTo ssz marshals the transactions object to a target array
+func (t *transactions) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(4)
+
+	// Offset (0) 'Transactions'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(t.Transactions); ii++ {
+		offset += 4
+		offset += len(t.Transactions[ii])
+	}
+
+	// Field (0) 'Transactions'
+	if len(t.Transactions) > 1048576 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	{
+		offset = 4 * len(t.Transactions)
+		for ii := 0; ii < len(t.Transactions); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += len(t.Transactions[ii])
+		}
+	}
+	for ii := 0; ii < len(t.Transactions); ii++ {
+		if len(t.Transactions[ii]) > 1073741824 {
+			err = ssz.ErrBytesLength
+			return
+		}
+		dst = append(dst, t.Transactions[ii]...)
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the transactions object
+func (t *transactions) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 4 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0 uint64
+
+	// Offset (0) 'Transactions'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 4 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (0) 'Transactions'
+	{
+		buf = tail[o0:]
+		num, err := ssz.DecodeDynamicLength(buf, 1048576)
+		if err != nil {
+			return err
+		}
+		t.Transactions = make([][]byte, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if len(buf) > 1073741824 {
+				return ssz.ErrBytesLength
+			}
+			if cap(t.Transactions[indx]) == 0 {
+				t.Transactions[indx] = make([]byte, 0, len(buf))
+			}
+			t.Transactions[indx] = append(t.Transactions[indx], buf...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the transactions object
+func (t *transactions) SizeSSZ() (size int) {
+	size = 4
+
+	// Field (0) 'Transactions'
+	for ii := 0; ii < len(t.Transactions); ii++ {
+		size += 4
+		size += len(t.Transactions[ii])
+	}
+
+	return
+}
+
+// HashTreeRoot ssz hashes the transactions object
+func (t *transactions) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(t)
+}
+
+// HashTreeRootWith ssz hashes the transactions object with a hasher
+func (t *transactions) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Transactions'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(t.Transactions))
+		if num > 1048576 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range t.Transactions {
+			{
+				elemIndx := hh.Index()
+				byteLen := uint64(len(elem))
+				if byteLen > 1073741824 {
+					err = ssz.ErrIncorrectListSize
+					return
+				}
+				hh.AppendBytes32(elem)
+				hh.MerkleizeWithMixin(elemIndx, byteLen, (1073741824+31)/32)
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 1048576)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the withdrawals object
+func (w *withdrawals) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(w)
+}
+
+// MarshalSSZTo ssz marshals the withdrawals object to a target array
+func (w *withdrawals) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(4)
+
+	// Offset (0) 'Withdrawals'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(w.Withdrawals) * 44
+
+	// Field (0) 'Withdrawals'
+	if len(w.Withdrawals) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(w.Withdrawals); ii++ {
+		if dst, err = w.Withdrawals[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the withdrawals object
+func (w *withdrawals) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 4 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o0 uint64
+
+	// Offset (0) 'Withdrawals'
+	if o0 = ssz.ReadOffset(buf[0:4]); o0 > size {
+		return ssz.ErrOffset
+	}
+
+	if o0 < 4 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (0) 'Withdrawals'
+	{
+		buf = tail[o0:]
+		num, err := ssz.DivideInt2(len(buf), 44, 16)
+		if err != nil {
+			return err
+		}
+		w.Withdrawals = make([]*WithdrawalREST, num)
+		for ii := 0; ii < num; ii++ {
+			if w.Withdrawals[ii] == nil {
+				w.Withdrawals[ii] = new(WithdrawalREST)
+			}
+			if err = w.Withdrawals[ii].UnmarshalSSZ(buf[ii*44 : (ii+1)*44]); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the withdrawals object
+func (w *withdrawals) SizeSSZ() (size int) {
+	size = 4
+
+	// Field (0) 'Withdrawals'
+	size += len(w.Withdrawals) * 44
+
+	return
+}
+
+// HashTreeRoot ssz hashes the withdrawals object
+func (w *withdrawals) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(w)
+}
+
+// HashTreeRootWith ssz hashes the withdrawals object with a hasher
+func (w *withdrawals) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Withdrawals'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(w.Withdrawals))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range w.Withdrawals {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the executionPayload object
+func (e *executionPayload) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(e)
+}
+
+// MarshalSSZTo ssz marshals the executionPayload object to a target array
+func (e *executionPayload) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(508)
+
+	// Field (0) 'ParentHash'
+	dst = append(dst, e.ParentHash[:]...)
+
+	// Field (1) 'FeeRecipient'
+	dst = append(dst, e.FeeRecipient[:]...)
+
+	// Field (2) 'StateRoot'
+	dst = append(dst, e.StateRoot[:]...)
+
+	// Field (3) 'ReceiptsRoot'
+	dst = append(dst, e.ReceiptsRoot[:]...)
+
+	// Field (4) 'LogsBloom'
+	dst = append(dst, e.LogsBloom[:]...)
+
+	// Field (5) 'Random'
+	dst = append(dst, e.Random[:]...)
+
+	// Field (6) 'BlockNumber'
+	dst = ssz.MarshalUint64(dst, e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	dst = ssz.MarshalUint64(dst, e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	dst = ssz.MarshalUint64(dst, e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	dst = ssz.MarshalUint64(dst, e.Timestamp)
+
+	// Offset (10) 'ExtraData'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.ExtraData)
+
+	// Field (11) 'BaseFeePerGas'
+	dst = append(dst, e.BaseFeePerGas[:]...)
+
+	// Field (12) 'BlockHash'
+	dst = append(dst, e.BlockHash[:]...)
+
+	// Offset (13) 'Transactions'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		offset += 4
+		offset += len(e.Transactions[ii])
+	}
+
+	// Field (10) 'ExtraData'
+	if len(e.ExtraData) > 32 {
+		err = ssz.ErrBytesLength
+		return
+	}
+	dst = append(dst, e.ExtraData...)
+
+	// Field (13) 'Transactions'
+	if len(e.Transactions) > 1048576 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	{
+		offset = 4 * len(e.Transactions)
+		for ii := 0; ii < len(e.Transactions); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += len(e.Transactions[ii])
+		}
+	}
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		if len(e.Transactions[ii]) > 1073741824 {
+			err = ssz.ErrBytesLength
+			return
+		}
+		dst = append(dst, e.Transactions[ii]...)
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the executionPayload object
+func (e *executionPayload) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 508 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o10, o13 uint64
+
+	// Field (0) 'ParentHash'
+	copy(e.ParentHash[:], buf[0:32])
+
+	// Field (1) 'FeeRecipient'
+	copy(e.FeeRecipient[:], buf[32:52])
+
+	// Field (2) 'StateRoot'
+	copy(e.StateRoot[:], buf[52:84])
+
+	// Field (3) 'ReceiptsRoot'
+	copy(e.ReceiptsRoot[:], buf[84:116])
+
+	// Field (4) 'LogsBloom'
+	copy(e.LogsBloom[:], buf[116:372])
+
+	// Field (5) 'Random'
+	copy(e.Random[:], buf[372:404])
+
+	// Field (6) 'BlockNumber'
+	e.BlockNumber = ssz.UnmarshallUint64(buf[404:412])
+
+	// Field (7) 'GasLimit'
+	e.GasLimit = ssz.UnmarshallUint64(buf[412:420])
+
+	// Field (8) 'GasUsed'
+	e.GasUsed = ssz.UnmarshallUint64(buf[420:428])
+
+	// Field (9) 'Timestamp'
+	e.Timestamp = ssz.UnmarshallUint64(buf[428:436])
+
+	// Offset (10) 'ExtraData'
+	if o10 = ssz.ReadOffset(buf[436:440]); o10 > size {
+		return ssz.ErrOffset
+	}
+
+	if o10 < 508 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	copy(e.BaseFeePerGas[:], buf[440:472])
+
+	// Field (12) 'BlockHash'
+	copy(e.BlockHash[:], buf[472:504])
+
+	// Offset (13) 'Transactions'
+	if o13 = ssz.ReadOffset(buf[504:508]); o13 > size || o10 > o13 {
+		return ssz.ErrOffset
+	}
+
+	// Field (10) 'ExtraData'
+	{
+		buf = tail[o10:o13]
+		if len(buf) > 32 {
+			return ssz.ErrBytesLength
+		}
+		if cap(e.ExtraData) == 0 {
+			e.ExtraData = make([]byte, 0, len(buf))
+		}
+		e.ExtraData = append(e.ExtraData, buf...)
+	}
+
+	// Field (13) 'Transactions'
+	{
+		buf = tail[o13:]
+		num, err := ssz.DecodeDynamicLength(buf, 1048576)
+		if err != nil {
+			return err
+		}
+		e.Transactions = make([][]byte, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if len(buf) > 1073741824 {
+				return ssz.ErrBytesLength
+			}
+			if cap(e.Transactions[indx]) == 0 {
+				e.Transactions[indx] = make([]byte, 0, len(buf))
+			}
+			e.Transactions[indx] = append(e.Transactions[indx], buf...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the executionPayload object
+func (e *executionPayload) SizeSSZ() (size int) {
+	size = 508
+
+	// Field (10) 'ExtraData'
+	size += len(e.ExtraData)
+
+	// Field (13) 'Transactions'
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		size += 4
+		size += len(e.Transactions[ii])
+	}
+
+	return
+}
+
+// HashTreeRoot ssz hashes the executionPayload object
+func (e *executionPayload) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(e)
+}
+
+// HashTreeRootWith ssz hashes the executionPayload object with a hasher
+func (e *executionPayload) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'ParentHash'
+	hh.PutBytes(e.ParentHash[:])
+
+	// Field (1) 'FeeRecipient'
+	hh.PutBytes(e.FeeRecipient[:])
+
+	// Field (2) 'StateRoot'
+	hh.PutBytes(e.StateRoot[:])
+
+	// Field (3) 'ReceiptsRoot'
+	hh.PutBytes(e.ReceiptsRoot[:])
+
+	// Field (4) 'LogsBloom'
+	hh.PutBytes(e.LogsBloom[:])
+
+	// Field (5) 'Random'
+	hh.PutBytes(e.Random[:])
+
+	// Field (6) 'BlockNumber'
+	hh.PutUint64(e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	hh.PutUint64(e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	hh.PutUint64(e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	hh.PutUint64(e.Timestamp)
+
+	// Field (10) 'ExtraData'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(e.ExtraData))
+		if byteLen > 32 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(e.ExtraData)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (32+31)/32)
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	hh.PutBytes(e.BaseFeePerGas[:])
+
+	// Field (12) 'BlockHash'
+	hh.PutBytes(e.BlockHash[:])
+
+	// Field (13) 'Transactions'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(e.Transactions))
+		if num > 1048576 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range e.Transactions {
+			{
+				elemIndx := hh.Index()
+				byteLen := uint64(len(elem))
+				if byteLen > 1073741824 {
+					err = ssz.ErrIncorrectListSize
+					return
+				}
+				hh.AppendBytes32(elem)
+				hh.MerkleizeWithMixin(elemIndx, byteLen, (1073741824+31)/32)
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 1048576)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the executionPayloadCapella object
+func (e *executionPayloadCapella) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(e)
+}
+
+// MarshalSSZTo ssz marshals the executionPayloadCapella object to a target array
+func (e *executionPayloadCapella) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(512)
+
+	// Field (0) 'ParentHash'
+	dst = append(dst, e.ParentHash[:]...)
+
+	// Field (1) 'FeeRecipient'
+	dst = append(dst, e.FeeRecipient[:]...)
+
+	// Field (2) 'StateRoot'
+	dst = append(dst, e.StateRoot[:]...)
+
+	// Field (3) 'ReceiptsRoot'
+	dst = append(dst, e.ReceiptsRoot[:]...)
+
+	// Field (4) 'LogsBloom'
+	dst = append(dst, e.LogsBloom[:]...)
+
+	// Field (5) 'Random'
+	dst = append(dst, e.Random[:]...)
+
+	// Field (6) 'BlockNumber'
+	dst = ssz.MarshalUint64(dst, e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	dst = ssz.MarshalUint64(dst, e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	dst = ssz.MarshalUint64(dst, e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	dst = ssz.MarshalUint64(dst, e.Timestamp)
+
+	// Offset (10) 'ExtraData'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.ExtraData)
+
+	// Field (11) 'BaseFeePerGas'
+	dst = append(dst, e.BaseFeePerGas[:]...)
+
+	// Field (12) 'BlockHash'
+	dst = append(dst, e.BlockHash[:]...)
+
+	// Offset (13) 'Transactions'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		offset += 4
+		offset += len(e.Transactions[ii])
+	}
+
+	// Offset (14) 'Withdrawals'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.Withdrawals) * 44
+
+	// Field (10) 'ExtraData'
+	if len(e.ExtraData) > 32 {
+		err = ssz.ErrBytesLength
+		return
+	}
+	dst = append(dst, e.ExtraData...)
+
+	// Field (13) 'Transactions'
+	if len(e.Transactions) > 1048576 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	{
+		offset = 4 * len(e.Transactions)
+		for ii := 0; ii < len(e.Transactions); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += len(e.Transactions[ii])
+		}
+	}
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		if len(e.Transactions[ii]) > 1073741824 {
+			err = ssz.ErrBytesLength
+			return
+		}
+		dst = append(dst, e.Transactions[ii]...)
+	}
+
+	// Field (14) 'Withdrawals'
+	if len(e.Withdrawals) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(e.Withdrawals); ii++ {
+		if dst, err = e.Withdrawals[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the executionPayloadCapella object
+func (e *executionPayloadCapella) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 512 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o10, o13, o14 uint64
+
+	// Field (0) 'ParentHash'
+	copy(e.ParentHash[:], buf[0:32])
+
+	// Field (1) 'FeeRecipient'
+	copy(e.FeeRecipient[:], buf[32:52])
+
+	// Field (2) 'StateRoot'
+	copy(e.StateRoot[:], buf[52:84])
+
+	// Field (3) 'ReceiptsRoot'
+	copy(e.ReceiptsRoot[:], buf[84:116])
+
+	// Field (4) 'LogsBloom'
+	copy(e.LogsBloom[:], buf[116:372])
+
+	// Field (5) 'Random'
+	copy(e.Random[:], buf[372:404])
+
+	// Field (6) 'BlockNumber'
+	e.BlockNumber = ssz.UnmarshallUint64(buf[404:412])
+
+	// Field (7) 'GasLimit'
+	e.GasLimit = ssz.UnmarshallUint64(buf[412:420])
+
+	// Field (8) 'GasUsed'
+	e.GasUsed = ssz.UnmarshallUint64(buf[420:428])
+
+	// Field (9) 'Timestamp'
+	e.Timestamp = ssz.UnmarshallUint64(buf[428:436])
+
+	// Offset (10) 'ExtraData'
+	if o10 = ssz.ReadOffset(buf[436:440]); o10 > size {
+		return ssz.ErrOffset
+	}
+
+	if o10 < 512 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	copy(e.BaseFeePerGas[:], buf[440:472])
+
+	// Field (12) 'BlockHash'
+	copy(e.BlockHash[:], buf[472:504])
+
+	// Offset (13) 'Transactions'
+	if o13 = ssz.ReadOffset(buf[504:508]); o13 > size || o10 > o13 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (14) 'Withdrawals'
+	if o14 = ssz.ReadOffset(buf[508:512]); o14 > size || o13 > o14 {
+		return ssz.ErrOffset
+	}
+
+	// Field (10) 'ExtraData'
+	{
+		buf = tail[o10:o13]
+		if len(buf) > 32 {
+			return ssz.ErrBytesLength
+		}
+		if cap(e.ExtraData) == 0 {
+			e.ExtraData = make([]byte, 0, len(buf))
+		}
+		e.ExtraData = append(e.ExtraData, buf...)
+	}
+
+	// Field (13) 'Transactions'
+	{
+		buf = tail[o13:o14]
+		num, err := ssz.DecodeDynamicLength(buf, 1048576)
+		if err != nil {
+			return err
+		}
+		e.Transactions = make([][]byte, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if len(buf) > 1073741824 {
+				return ssz.ErrBytesLength
+			}
+			if cap(e.Transactions[indx]) == 0 {
+				e.Transactions[indx] = make([]byte, 0, len(buf))
+			}
+			e.Transactions[indx] = append(e.Transactions[indx], buf...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Field (14) 'Withdrawals'
+	{
+		buf = tail[o14:]
+		num, err := ssz.DivideInt2(len(buf), 44, 16)
+		if err != nil {
+			return err
+		}
+		e.Withdrawals = make([]*WithdrawalREST, num)
+		for ii := 0; ii < num; ii++ {
+			if e.Withdrawals[ii] == nil {
+				e.Withdrawals[ii] = new(WithdrawalREST)
+			}
+			if err = e.Withdrawals[ii].UnmarshalSSZ(buf[ii*44 : (ii+1)*44]); err != nil {
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the executionPayloadCapella object
+func (e *executionPayloadCapella) SizeSSZ() (size int) {
+	size = 512
+
+	// Field (10) 'ExtraData'
+	size += len(e.ExtraData)
+
+	// Field (13) 'Transactions'
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		size += 4
+		size += len(e.Transactions[ii])
+	}
+
+	// Field (14) 'Withdrawals'
+	size += len(e.Withdrawals) * 44
+
+	return
+}
+
+// HashTreeRoot ssz hashes the executionPayloadCapella object
+func (e *executionPayloadCapella) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(e)
+}
+
+// HashTreeRootWith ssz hashes the executionPayloadCapella object with a hasher
+func (e *executionPayloadCapella) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'ParentHash'
+	hh.PutBytes(e.ParentHash[:])
+
+	// Field (1) 'FeeRecipient'
+	hh.PutBytes(e.FeeRecipient[:])
+
+	// Field (2) 'StateRoot'
+	hh.PutBytes(e.StateRoot[:])
+
+	// Field (3) 'ReceiptsRoot'
+	hh.PutBytes(e.ReceiptsRoot[:])
+
+	// Field (4) 'LogsBloom'
+	hh.PutBytes(e.LogsBloom[:])
+
+	// Field (5) 'Random'
+	hh.PutBytes(e.Random[:])
+
+	// Field (6) 'BlockNumber'
+	hh.PutUint64(e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	hh.PutUint64(e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	hh.PutUint64(e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	hh.PutUint64(e.Timestamp)
+
+	// Field (10) 'ExtraData'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(e.ExtraData))
+		if byteLen > 32 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(e.ExtraData)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (32+31)/32)
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	hh.PutBytes(e.BaseFeePerGas[:])
+
+	// Field (12) 'BlockHash'
+	hh.PutBytes(e.BlockHash[:])
+
+	// Field (13) 'Transactions'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(e.Transactions))
+		if num > 1048576 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range e.Transactions {
+			{
+				elemIndx := hh.Index()
+				byteLen := uint64(len(elem))
+				if byteLen > 1073741824 {
+					err = ssz.ErrIncorrectListSize
+					return
+				}
+				hh.AppendBytes32(elem)
+				hh.MerkleizeWithMixin(elemIndx, byteLen, (1073741824+31)/32)
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 1048576)
+	}
+
+	// Field (14) 'Withdrawals'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(e.Withdrawals))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range e.Withdrawals {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the executionPayloadDeneb object
+func (e *executionPayloadDeneb) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(e)
+}
+
+// MarshalSSZTo ssz marshals the executionPayloadDeneb object to a target array
+func (e *executionPayloadDeneb) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+	offset := int(528)
+
+	// Field (0) 'ParentHash'
+	dst = append(dst, e.ParentHash[:]...)
+
+	// Field (1) 'FeeRecipient'
+	dst = append(dst, e.FeeRecipient[:]...)
+
+	// Field (2) 'StateRoot'
+	dst = append(dst, e.StateRoot[:]...)
+
+	// Field (3) 'ReceiptsRoot'
+	dst = append(dst, e.ReceiptsRoot[:]...)
+
+	// Field (4) 'LogsBloom'
+	dst = append(dst, e.LogsBloom[:]...)
+
+	// Field (5) 'Random'
+	dst = append(dst, e.Random[:]...)
+
+	// Field (6) 'BlockNumber'
+	dst = ssz.MarshalUint64(dst, e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	dst = ssz.MarshalUint64(dst, e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	dst = ssz.MarshalUint64(dst, e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	dst = ssz.MarshalUint64(dst, e.Timestamp)
+
+	// Offset (10) 'ExtraData'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.ExtraData)
+
+	// Field (11) 'BaseFeePerGas'
+	dst = append(dst, e.BaseFeePerGas[:]...)
+
+	// Field (12) 'BlockHash'
+	dst = append(dst, e.BlockHash[:]...)
+
+	// Offset (13) 'Transactions'
+	dst = ssz.WriteOffset(dst, offset)
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		offset += 4
+		offset += len(e.Transactions[ii])
+	}
+
+	// Offset (14) 'Withdrawals'
+	dst = ssz.WriteOffset(dst, offset)
+	offset += len(e.Withdrawals) * 44
+
+	// Field (15) 'BlobGasUsed'
+	dst = ssz.MarshalUint64(dst, e.BlobGasUsed)
+
+	// Field (16) 'ExcessBlobGas'
+	dst = ssz.MarshalUint64(dst, e.ExcessBlobGas)
+
+	// Field (10) 'ExtraData'
+	if len(e.ExtraData) > 32 {
+		err = ssz.ErrBytesLength
+		return
+	}
+	dst = append(dst, e.ExtraData...)
+
+	// Field (13) 'Transactions'
+	if len(e.Transactions) > 1048576 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	{
+		offset = 4 * len(e.Transactions)
+		for ii := 0; ii < len(e.Transactions); ii++ {
+			dst = ssz.WriteOffset(dst, offset)
+			offset += len(e.Transactions[ii])
+		}
+	}
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		if len(e.Transactions[ii]) > 1073741824 {
+			err = ssz.ErrBytesLength
+			return
+		}
+		dst = append(dst, e.Transactions[ii]...)
+	}
+
+	// Field (14) 'Withdrawals'
+	if len(e.Withdrawals) > 16 {
+		err = ssz.ErrListTooBig
+		return
+	}
+	for ii := 0; ii < len(e.Withdrawals); ii++ {
+		if dst, err = e.Withdrawals[ii].MarshalSSZTo(dst); err != nil {
+			return
+		}
+	}
 
 	return
 }
 
-// HashTreeRoot ssz hashes the transactions object
-func (t *transactions) HashTreeRoot() ([32]byte, error) {
-	return ssz.HashWithDefaultHasher(t)
+// UnmarshalSSZ ssz unmarshals the executionPayloadDeneb object
+func (e *executionPayloadDeneb) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size < 528 {
+		return ssz.ErrSize
+	}
+
+	tail := buf
+	var o10, o13, o14 uint64
+
+	// Field (0) 'ParentHash'
+	copy(e.ParentHash[:], buf[0:32])
+
+	// Field (1) 'FeeRecipient'
+	copy(e.FeeRecipient[:], buf[32:52])
+
+	// Field (2) 'StateRoot'
+	copy(e.StateRoot[:], buf[52:84])
+
+	// Field (3) 'ReceiptsRoot'
+	copy(e.ReceiptsRoot[:], buf[84:116])
+
+	// Field (4) 'LogsBloom'
+	copy(e.LogsBloom[:], buf[116:372])
+
+	// Field (5) 'Random'
+	copy(e.Random[:], buf[372:404])
+
+	// Field (6) 'BlockNumber'
+	e.BlockNumber = ssz.UnmarshallUint64(buf[404:412])
+
+	// Field (7) 'GasLimit'
+	e.GasLimit = ssz.UnmarshallUint64(buf[412:420])
+
+	// Field (8) 'GasUsed'
+	e.GasUsed = ssz.UnmarshallUint64(buf[420:428])
+
+	// Field (9) 'Timestamp'
+	e.Timestamp = ssz.UnmarshallUint64(buf[428:436])
+
+	// Offset (10) 'ExtraData'
+	if o10 = ssz.ReadOffset(buf[436:440]); o10 > size {
+		return ssz.ErrOffset
+	}
+
+	if o10 < 528 {
+		return ssz.ErrInvalidVariableOffset
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	copy(e.BaseFeePerGas[:], buf[440:472])
+
+	// Field (12) 'BlockHash'
+	copy(e.BlockHash[:], buf[472:504])
+
+	// Offset (13) 'Transactions'
+	if o13 = ssz.ReadOffset(buf[504:508]); o13 > size || o10 > o13 {
+		return ssz.ErrOffset
+	}
+
+	// Offset (14) 'Withdrawals'
+	if o14 = ssz.ReadOffset(buf[508:512]); o14 > size || o13 > o14 {
+		return ssz.ErrOffset
+	}
+
+	// Field (15) 'BlobGasUsed'
+	e.BlobGasUsed = ssz.UnmarshallUint64(buf[512:520])
+
+	// Field (16) 'ExcessBlobGas'
+	e.ExcessBlobGas = ssz.UnmarshallUint64(buf[520:528])
+
+	// Field (10) 'ExtraData'
+	{
+		buf = tail[o10:o13]
+		if len(buf) > 32 {
+			return ssz.ErrBytesLength
+		}
+		if cap(e.ExtraData) == 0 {
+			e.ExtraData = make([]byte, 0, len(buf))
+		}
+		e.ExtraData = append(e.ExtraData, buf...)
+	}
+
+	// Field (13) 'Transactions'
+	{
+		buf = tail[o13:o14]
+		num, err := ssz.DecodeDynamicLength(buf, 1048576)
+		if err != nil {
+			return err
+		}
+		e.Transactions = make([][]byte, num)
+		err = ssz.UnmarshalDynamic(buf, num, func(indx int, buf []byte) (err error) {
+			if len(buf) > 1073741824 {
+				return ssz.ErrBytesLength
+			}
+			if cap(e.Transactions[indx]) == 0 {
+				e.Transactions[indx] = make([]byte, 0, len(buf))
+			}
+			e.Transactions[indx] = append(e.Transactions[indx], buf...)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Field (14) 'Withdrawals'
+	{
+		buf = tail[o14:]
+		num, err := ssz.DivideInt2(len(buf), 44, 16)
+		if err != nil {
+			return err
+		}
+		e.Withdrawals = make([]*WithdrawalREST, num)
+		for ii := 0; ii < num; ii++ {
+			if e.Withdrawals[ii] == nil {
+				e.Withdrawals[ii] = new(WithdrawalREST)
+			}
+			if err = e.Withdrawals[ii].UnmarshalSSZ(buf[ii*44 : (ii+1)*44]); err != nil {
+				return err
+			}
+		}
+	}
+	return err
 }
 
-// HashTreeRootWith ssz hashes the transactions object with a hasher
-func (t *transactions) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+// SizeSSZ returns the ssz encoded size in bytes for the executionPayloadDeneb object
+func (e *executionPayloadDeneb) SizeSSZ() (size int) {
+	size = 528
+
+	// Field (10) 'ExtraData'
+	size += len(e.ExtraData)
+
+	// Field (13) 'Transactions'
+	for ii := 0; ii < len(e.Transactions); ii++ {
+		size += 4
+		size += len(e.Transactions[ii])
+	}
+
+	// Field (14) 'Withdrawals'
+	size += len(e.Withdrawals) * 44
+
+	return
+}
+
+// HashTreeRoot ssz hashes the executionPayloadDeneb object
+func (e *executionPayloadDeneb) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(e)
+}
+
+// HashTreeRootWith ssz hashes the executionPayloadDeneb object with a hasher
+func (e *executionPayloadDeneb) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	indx := hh.Index()
 
-	// Field (0) 'Transactions'
+	// Field (0) 'ParentHash'
+	hh.PutBytes(e.ParentHash[:])
+
+	// Field (1) 'FeeRecipient'
+	hh.PutBytes(e.FeeRecipient[:])
+
+	// Field (2) 'StateRoot'
+	hh.PutBytes(e.StateRoot[:])
+
+	// Field (3) 'ReceiptsRoot'
+	hh.PutBytes(e.ReceiptsRoot[:])
+
+	// Field (4) 'LogsBloom'
+	hh.PutBytes(e.LogsBloom[:])
+
+	// Field (5) 'Random'
+	hh.PutBytes(e.Random[:])
+
+	// Field (6) 'BlockNumber'
+	hh.PutUint64(e.BlockNumber)
+
+	// Field (7) 'GasLimit'
+	hh.PutUint64(e.GasLimit)
+
+	// Field (8) 'GasUsed'
+	hh.PutUint64(e.GasUsed)
+
+	// Field (9) 'Timestamp'
+	hh.PutUint64(e.Timestamp)
+
+	// Field (10) 'ExtraData'
+	{
+		elemIndx := hh.Index()
+		byteLen := uint64(len(e.ExtraData))
+		if byteLen > 32 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		hh.PutBytes(e.ExtraData)
+		hh.MerkleizeWithMixin(elemIndx, byteLen, (32+31)/32)
+	}
+
+	// Field (11) 'BaseFeePerGas'
+	hh.PutBytes(e.BaseFeePerGas[:])
+
+	// Field (12) 'BlockHash'
+	hh.PutBytes(e.BlockHash[:])
+
+	// Field (13) 'Transactions'
 	{
 		subIndx := hh.Index()
-		num := uint64(len(t.Transactions))
+		num := uint64(len(e.Transactions))
 		if num > 1048576 {
 			err = ssz.ErrIncorrectListSize
 			return
 		}
-		for _, elem := range t.Transactions {
+		for _, elem := range e.Transactions {
 			{
 				elemIndx := hh.Index()
 				byteLen := uint64(len(elem))
@@ -2278,6 +5503,28 @@ func (t *transactions) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 		hh.MerkleizeWithMixin(subIndx, num, 1048576)
 	}
 
+	// Field (14) 'Withdrawals'
+	{
+		subIndx := hh.Index()
+		num := uint64(len(e.Withdrawals))
+		if num > 16 {
+			err = ssz.ErrIncorrectListSize
+			return
+		}
+		for _, elem := range e.Withdrawals {
+			if err = elem.HashTreeRootWith(hh); err != nil {
+				return
+			}
+		}
+		hh.MerkleizeWithMixin(subIndx, num, 16)
+	}
+
+	// Field (15) 'BlobGasUsed'
+	hh.PutUint64(e.BlobGasUsed)
+
+	// Field (16) 'ExcessBlobGas'
+	hh.PutUint64(e.ExcessBlobGas)
+
 	hh.Merkleize(indx)
 	return
 }
@@ -300,3 +300,63 @@ func TestMerkelizePayload(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "87b57a69321ec21e8a83a39f2f0f885a3be9bbddb80794b3b2700c3cf8230aa1", common.Bytes2Hex(root[:]))
 }
+
+// TestSpecRoots pins the hash tree roots of the generated SSZ code, checked against the
+// consensus-spec-tested implementation of prysm.
+func TestSpecRoots(t *testing.T) {
+	data := &AttestationData{Slot: 7, Index: 1, BlockRoot: Root{0x08}, Source: &Checkpoint{Epoch: 1, Root: Root{0x09}}, Target: &Checkpoint{Epoch: 2, Root: Root{0x0a}}}
+	objs := map[string]HashTreeRoot{
+		"Checkpoint":         &Checkpoint{Epoch: 3, Root: Root{0x01}},
+		"Eth1Data":           &Eth1Data{DepositRoot: Root{0x02}, DepositCount: 5, BlockHash: Hash{0x03}},
+		"BeaconBlockHeader":  &BeaconBlockHeader{Slot: 1, ProposerIndex: 2, ParentRoot: Root{0x04}, StateRoot: Root{0x05}, BodyRoot: Root{0x06}},
+		"AttestationData":    data,
+		"Attestation":        &Attestation{AggregationBits: []byte{0x0d}, Data: data, Signature: Signature{0xaa}},
+		"IndexedAttestation": &IndexedAttestation{AttestingIndices: []uint64{1, 5, 9}, Data: data, Signature: Signature{0xab}},
+		"DepositData":        &DepositData{Pubkey: PublicKey{0x11}, WithdrawalCredentials: Hash{0x12}, Amount: 32_000_000_000, Signature: Signature{0x13}},
+		"VoluntaryExit":      &VoluntaryExit{Epoch: 4, ValidatorIndex: 9},
+		"SyncAggregate":      &SyncAggregate{CommitteeBits: CommitteeBits{0xff, 0x01}, CommitteeSignature: Signature{0xc0}},
+		"ExecutionPayloadHeader": &ExecutionPayloadHeader{
+			ParentHash: Hash{0x21}, FeeRecipient: Address{0x22}, StateRoot: Root{0x23}, ReceiptsRoot: Root{0x24},
+			LogsBloom: Bloom{0x25}, Random: Hash{0x26}, BlockNumber: 27, GasLimit: 30_000_000, GasUsed: 21_000,
+			Timestamp: 1_652_735_778, ExtraData: ExtraData{0x28, 0x29}, BaseFeePerGas: IntToU256(7),
+			BlockHash: Hash{0x2a}, TransactionsRoot: Root{0x2b},
+		},
+		"ExecutionPayload": &executionPayload{
+			ParentHash: Hash{0x21}, FeeRecipient: Address{0x22}, StateRoot: Root{0x23}, ReceiptsRoot: Root{0x24},
+			LogsBloom: Bloom{0x25}, Random: Hash{0x26}, BlockNumber: 27, GasLimit: 30_000_000, GasUsed: 21_000,
+			Timestamp: 1_652_735_778, ExtraData: ExtraData{0x28, 0x29}, BaseFeePerGas: IntToU256(7),
+			BlockHash: Hash{0x2a}, Transactions: [][]byte{{0x02, 0xf8}, make([]byte, 100)},
+		},
+		"SigningData": &SigningData{Root: Root{0x31}, Domain: Domain{0x32}},
+		"ForkData":    &forkData{CurrentVersion: 0x02000000, GenesisValidatorsRoot: Root{0x33}},
+	}
+	roots := map[string]string{
+		"Attestation":            "89ab8e676b113057ddadf762a814df60ffb71b829412e054cb961fa88fe7fbf7",
+		"AttestationData":        "3795475e06eb763f8e2ff35e2a47a7a40a1368bebf33a4920d0ce71bf37d9b4c",
+		"BeaconBlockHeader":      "397b868ed93c5e8d7b3b78ac6bce385f365c9b9fe68a13c54b8f4481ce359682",
+		"Checkpoint":             "5b0f47b11478610c5abcbc912b34ac5f7f5740b6d8b27169b1eb2aea9f1c909c",
+		"DepositData":            "c7cf234c4477e9547ba11f77eab5b084a79cb40765e722e68006ca1b830ff91d",
+		"Eth1Data":               "c95e680e66bab2e3bfd3bdae230332457a5320ac42d10721b7eafa33598249aa",
+		"ExecutionPayload":       "5ddfafa506cce3d590be818d66015cad9be71b99f67147b843423b713b29965e",
+		"ExecutionPayloadHeader": "fe6a6fcecaa30aac4844f4577ddb504df1d268184bbf423e38e5b220adbc4253",
+		"ForkData":               "9dcc62d99f618f5e2bceac51916dd0c5d118b349a973420ec27117d7d8493f33",
+		"IndexedAttestation":     "00edb679c3e69d7d511199db6d99349f65a4ef657eb76198d2ac5cc3a4dd7789",
+		"SigningData":            "fd214b6c74a61e5b1f0a2e21e9337b126b9117dcb82ca7e351da4707a14d9898",
+		"SyncAggregate":          "e9d86d6a916eb5f3890361d9e95544eb2a3acbe0e732fae13448906c08b22b1c",
+		"VoluntaryExit":          "c783abc3cb0050fdc01c7ab78497e11e214054a2268c2c6136c6c51bb66297e8",
+	}
+	require.Len(t, roots, len(objs))
+	for name, obj := range objs {
+		root, err := obj.HashTreeRoot()
+		require.NoError(t, err, name)
+		require.Equal(t, roots[name], common.Bytes2Hex(root[:]), name)
+	}
+
+	// single-field containers have the root of the field
+	root, err := (&SigningEpoch{Epoch: 0x0102}).HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{0x02, 0x01}, root)
+	root, err = (&SigningBlockRoot{Root: Root{0x41}}).HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, [32]byte{0x41}, root)
+}
@@ -5,12 +5,36 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
 )
 
 var (
 	ErrLength = fmt.Errorf("incorrect byte length")
+	ErrU256   = fmt.Errorf("not a uint256 value")
 )
 
+// decodeFixed decodes the 0x-prefixed hex text of a fixed-size value of the kind into dst,
+// which it must fill exactly.
+func decodeFixed(kind string, dst []byte, input []byte) error {
+	b, err := hexutil.Decode(string(input))
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", kind, input, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid %s %q: %w: got %d bytes, expected %d", kind, input, ErrLength, len(b), len(dst))
+	}
+	copy(dst, b)
+	return nil
+}
+
+// decodeFixedJSON decodes the JSON hex string of a fixed-size value like decodeFixed.
+func decodeFixedJSON(kind string, dst []byte, input []byte) error {
+	if len(input) < 2 || input[0] != '"' || input[len(input)-1] != '"' {
+		return fmt.Errorf("invalid %s %s: expected a hex string", kind, input)
+	}
+	return decodeFixed(kind, dst, input[1:len(input)-1])
+}
+
 type Signature [96]byte
 
 func (s Signature) MarshalText() ([]byte, error) {
@@ -18,30 +42,28 @@ func (s Signature) MarshalText() ([]byte, error) {
 }
 
 func (s *Signature) UnmarshalJSON(input []byte) error {
-	b := hexutil.Bytes(s[:])
-	err := b.UnmarshalJSON(input)
-	if err != nil {
+	var v Signature
+	if err := decodeFixedJSON("signature", v[:], input); err != nil {
 		return err
 	}
-	if len(b) != 96 {
-		return ErrLength
-	}
-	s.FromSlice(b)
-	return nil
+	return s.setChecked(v)
 }
 
 func (s *Signature) UnmarshalText(input []byte) error {
-	b := hexutil.Bytes(s[:])
-	err := b.UnmarshalText(input)
-	if err != nil {
+	var v Signature
+	if err := decodeFixed("signature", v[:], input); err != nil {
 		return err
 	}
-	if len(b) != 96 {
-		return ErrLength
-	}
-	s.FromSlice(b)
-	return nil
+	return s.setChecked(v)
+}
 
+// Set parses the hex value, for Signature command line flags.
+func (s *Signature) Set(value string) error {
+	return s.UnmarshalText([]byte(value))
+}
+
+func (s *Signature) Type() string {
+	return "signature"
 }
 
 func (s Signature) String() string {
@@ -59,24 +81,28 @@ func (p PublicKey) MarshalText() ([]byte, error) {
 }
 
 func (p *PublicKey) UnmarshalJSON(input []byte) error {
-	b := hexutil.Bytes(p[:])
-	b.UnmarshalJSON(input)
-	if len(b) != 48 {
-		return ErrLength
+	var v PublicKey
+	if err := decodeFixedJSON("public key", v[:], input); err != nil {
+		return err
 	}
-	p.FromSlice(b)
-	return nil
+	return p.setChecked(v)
 }
 
 func (p *PublicKey) UnmarshalText(input []byte) error {
-	b := hexutil.Bytes(p[:])
-	b.UnmarshalText(input)
-	if len(b) != 48 {
-		return ErrLength
+	var v PublicKey
+	if err := decodeFixed("public key", v[:], input); err != nil {
+		return err
 	}
-	p.FromSlice(b)
-	return nil
+	return p.setChecked(v)
+}
+
+// Set parses the hex value, for PublicKey command line flags.
+func (p *PublicKey) Set(value string) error {
+	return p.UnmarshalText([]byte(value))
+}
 
+func (p *PublicKey) Type() string {
+	return "public-key"
 }
 
 func (p PublicKey) String() string {
@@ -94,24 +120,20 @@ func (a Address) MarshalText() ([]byte, error) {
 }
 
 func (a *Address) UnmarshalJSON(input []byte) error {
-	b := hexutil.Bytes(a[:])
-	b.UnmarshalJSON(input)
-	if len(b) != 20 {
-		return ErrLength
-	}
-	a.FromSlice(b)
-	return nil
+	return decodeFixedJSON("address", a[:], input)
 }
 
 func (a *Address) UnmarshalText(input []byte) error {
-	b := hexutil.Bytes(a[:])
-	b.UnmarshalText(input)
-	if len(b) != 20 {
-		return ErrLength
-	}
-	a.FromSlice(b)
-	return nil
+	return decodeFixed("address", a[:], input)
+}
+
+// Set parses the hex value, for Address command line flags.
+func (a *Address) Set(value string) error {
+	return a.UnmarshalText([]byte(value))
+}
 
+func (a *Address) Type() string {
+	return "address"
 }
 
 func (a Address) String() string {
@@ -130,34 +152,30 @@ func (h Hash) MarshalText() ([]byte, error) {
 }
 
 func (h *Hash) UnmarshalJSON(input []byte) error {
-	b := hexutil.Bytes(h[:])
-	b.UnmarshalJSON(input)
-	if len(b) != 32 {
-		return ErrLength
-	}
-	h.FromSlice(b)
-	return nil
+	return decodeFixedJSON("hash", h[:], input)
 }
 
 func (h *Hash) UnmarshalText(input []byte) error {
-	b := hexutil.Bytes(h[:])
-	b.UnmarshalText(input)
-	if len(b) != 32 {
-		return ErrLength
-	}
-	h.FromSlice(b)
-	return nil
+	return decodeFixed("hash", h[:], input)
+}
 
+// Set parses the hex value, for Hash command line flags.
+func (h *Hash) Set(value string) error {
+	return h.UnmarshalText([]byte(value))
 }
 
-func (h *Hash) FromSlice(x []byte) {
-	copy(h[:], x)
+func (h *Hash) Type() string {
+	return "hash"
 }
 
 func (h Hash) String() string {
 	return hexutil.Bytes(h[:]).String()
 }
 
+func (h *Hash) FromSlice(x []byte) {
+	copy(h[:], x)
+}
+
 type CommitteeBits [64]byte
 
 func (c CommitteeBits) MarshalText() ([]byte, error) {
@@ -165,24 +183,20 @@ func (c CommitteeBits) MarshalText() ([]byte, error) {
 }
 
 func (c *CommitteeBits) UnmarshalJSON(input []byte) error {
-	b := hexutil.Bytes(c[:])
-	b.UnmarshalJSON(input)
-	if len(b) != 64 {
-		return ErrLength
-	}
-	c.FromSlice(b)
-	return nil
+	return decodeFixedJSON("committee bits", c[:], input)
 }
 
 func (c *CommitteeBits) UnmarshalText(input []byte) error {
-	b := hexutil.Bytes(c[:])
-	b.UnmarshalText(input)
-	if len(b) != 64 {
-		return ErrLength
-	}
-	c.FromSlice(b)
-	return nil
+	return decodeFixed("committee bits", c[:], input)
+}
+
+// Set parses the hex value, for CommitteeBits command line flags.
+func (c *CommitteeBits) Set(value string) error {
+	return c.UnmarshalText([]byte(value))
+}
 
+func (c *CommitteeBits) Type() string {
+	return "committee-bits"
 }
 
 func (c CommitteeBits) String() string {
@@ -200,23 +214,20 @@ func (b Bloom) MarshalText() ([]byte, error) {
 }
 
 func (b *Bloom) UnmarshalJSON(input []byte) error {
-	buf := hexutil.Bytes(b[:])
-	buf.UnmarshalJSON(input)
-	if len(b) != 256 {
-		return ErrLength
-	}
-	b.FromSlice(buf)
-	return nil
+	return decodeFixedJSON("bloom", b[:], input)
 }
 
 func (b *Bloom) UnmarshalText(input []byte) error {
-	buf := hexutil.Bytes(b[:])
-	buf.UnmarshalText(input)
-	if len(b) != 256 {
-		return ErrLength
-	}
-	b.FromSlice(buf)
-	return nil
+	return decodeFixed("bloom", b[:], input)
+}
+
+// Set parses the hex value, for Bloom command line flags.
+func (b *Bloom) Set(value string) error {
+	return b.UnmarshalText([]byte(value))
+}
+
+func (b *Bloom) Type() string {
+	return "bloom"
 }
 
 func (b Bloom) String() string {
@@ -240,7 +251,7 @@ func reverse(src []byte) []byte {
 }
 
 func (n U256Str) MarshalText() ([]byte, error) {
-	return []byte(new(big.Int).SetBytes(reverse(n[:])).String()), nil
+	return []byte(n.String()), nil
 }
 
 func (n *U256Str) UnmarshalJSON(input []byte) error {
@@ -252,8 +263,7 @@ func (n *U256Str) UnmarshalJSON(input []byte) error {
 	if err != nil {
 		return err
 	}
-	copy(n[:], reverse(x.FillBytes(n[:])))
-	return nil
+	return n.fromBig(x)
 }
 
 func (n *U256Str) UnmarshalText(input []byte) error {
@@ -262,22 +272,61 @@ func (n *U256Str) UnmarshalText(input []byte) error {
 	if err != nil {
 		return err
 	}
-	copy(n[:], reverse(x.FillBytes(n[:])))
-	return nil
+	return n.fromBig(x)
+}
 
+func (n *U256Str) fromBig(x *big.Int) error {
+	if x.Sign() < 0 {
+		return ErrU256
+	}
+	v, overflow := uint256.FromBig(x)
+	if overflow {
+		return ErrU256
+	}
+	*n = Uint256ToU256(v)
+	return nil
 }
 
 func (n *U256Str) String() string {
-	return new(big.Int).SetBytes(reverse(n[:])).String()
+	return n.BigInt().String()
+}
+
+// Set parses a decimal value, for U256Str command line flags.
+func (n *U256Str) Set(value string) error {
+	return n.UnmarshalText([]byte(value))
+}
+
+func (n *U256Str) Type() string {
+	return "uint256"
+}
+
+// BigInt returns the value as a big integer.
+func (n U256Str) BigInt() *big.Int {
+	return n.Uint256().ToBig()
+}
+
+// Uint256 returns the value as a 256-bit integer.
+func (n U256Str) Uint256() *uint256.Int {
+	return new(uint256.Int).SetBytes32(reverse(n[:]))
+}
+
+// Cmp compares the values, as -1, 0 or +1 for n less than, equal to or greater than m.
+func (n U256Str) Cmp(m U256Str) int {
+	return n.Uint256().Cmp(m.Uint256())
 }
 
 func (n *U256Str) FromSlice(x []byte) {
 	copy(n[:], x)
 }
 
-func IntToU256(i uint64) (ret U256Str) {
-	s := fmt.Sprint(i)
-	ret.UnmarshalText([]byte(s))
+func IntToU256(i uint64) U256Str {
+	return Uint256ToU256(uint256.NewInt(i))
+}
+
+// Uint256ToU256 returns the 256-bit integer as a U256Str.
+func Uint256ToU256(x *uint256.Int) (ret U256Str) {
+	b := x.Bytes32()
+	copy(ret[:], reverse(b[:]))
 	return
 }
 
@@ -3,6 +3,10 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -56,4 +60,69 @@ func TestU256Str(t *testing.T) {
 	// IntToU256
 	u := IntToU256(123)
 	require.Equal(t, "123", u.String())
+	u = IntToU256(math.MaxUint64)
+	require.Equal(t, "18446744073709551615", u.String())
+}
+
+func TestU256StrRange(t *testing.T) {
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	values := []*big.Int{new(big.Int), big.NewInt(1), new(big.Int).SetUint64(math.MaxUint64), new(big.Int).Lsh(big.NewInt(1), 64), max}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		values = append(values, new(big.Int).Rand(rng, max))
+	}
+	for _, x := range values {
+		var n U256Str
+		require.NoError(t, n.Set(x.String()))
+		require.Zero(t, x.Cmp(n.BigInt()), x)
+		require.Equal(t, x.String(), n.String())
+		require.Equal(t, x.String(), n.Uint256().ToBig().String())
+		require.Equal(t, n, Uint256ToU256(n.Uint256()))
+
+		b, err := json.Marshal(n)
+		require.NoError(t, err)
+		require.Equal(t, `"`+x.String()+`"`, string(b))
+		var dec U256Str
+		require.NoError(t, json.Unmarshal(b, &dec))
+		require.Equal(t, n, dec)
+		require.Zero(t, n.Cmp(dec))
+		if x.Sign() > 0 {
+			require.Equal(t, 1, n.Cmp(IntToU256(0)))
+			require.Equal(t, -1, IntToU256(0).Cmp(n))
+		}
+	}
+
+	var n U256Str
+	require.ErrorIs(t, n.Set(new(big.Int).Add(max, big.NewInt(1)).String()), ErrU256)
+	require.ErrorIs(t, n.Set("-1"), ErrU256)
+	require.Error(t, n.Set("ten"))
+}
+
+func TestFixedSizeParsing(t *testing.T) {
+	type fixed interface {
+		Set(string) error
+		String() string
+		UnmarshalJSON([]byte) error
+	}
+	for _, v := range []fixed{new(Signature), new(PublicKey), new(Address), new(Hash), new(CommitteeBits), new(Bloom)} {
+		size := len(v.String())/2 - 1
+		valid := "0x" + strings.Repeat("ab", size)
+		require.NoError(t, v.Set(valid))
+		require.Equal(t, valid, v.String())
+		require.NoError(t, v.UnmarshalJSON([]byte(`"`+valid+`"`)))
+
+		err := v.Set("0x" + strings.Repeat("ab", size-1))
+		require.ErrorIs(t, err, ErrLength)
+		require.Contains(t, err.Error(), fmt.Sprintf("got %d bytes, expected %d", size-1, size))
+		require.ErrorIs(t, v.Set(valid+"ab"), ErrLength)
+		require.Error(t, v.Set(strings.Repeat("ab", size)), "missing 0x prefix")
+		require.Error(t, v.Set("0x"+strings.Repeat("zz", size)), "invalid hex")
+		require.Error(t, v.UnmarshalJSON([]byte(`"0x12"`)))
+		require.Error(t, v.UnmarshalJSON([]byte(`12`)))
+		require.Equal(t, valid, v.String(), "failed parses leave the value")
+	}
+
+	var a Address
+	err := a.Set("0x12")
+	require.EqualError(t, err, `invalid address "0x12": incorrect byte length: got 1 bytes, expected 20`)
 }
@@ -0,0 +1,414 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Limits of execution payloads in the consensus specs.
+const (
+	MaxExtraDataBytes         = 32
+	MaxBytesPerTransaction    = 1 << 30
+	MaxTransactionsPerPayload = 1 << 20
+	MaxWithdrawalsPerPayload  = 16
+)
+
+var (
+	ErrPayloadLimit = errors.New("payload exceeds limit")
+	ErrForkFields   = errors.New("payload has fields the fork does not have")
+)
+
+// payloadConverter converts engine API payloads to the payload header and the builder API
+// payload of a fork, and builder API payloads of the fork back.
+type payloadConverter struct {
+	withdrawals bool
+	blobGas     bool
+
+	header  func(h *ExecutionPayloadHeaderDeneb) SSZObject
+	payload func(p *ExecutionPayloadDeneb) interface{}
+	// deneb returns the builder API payload of the fork as deneb payload, nil if it has the
+	// wrong type.
+	deneb func(payload interface{}) *ExecutionPayloadDeneb
+}
+
+// payloadConverters holds the converter of every fork. Conversions go through the deneb
+// types, which have all fields, and check that the fork has the fields set in the payload.
+var payloadConverters = map[string]payloadConverter{
+	VersionBellatrix: {
+		header: func(h *ExecutionPayloadHeaderDeneb) SSZObject {
+			return &ExecutionPayloadHeader{
+				ParentHash:       h.ParentHash,
+				FeeRecipient:     h.FeeRecipient,
+				StateRoot:        h.StateRoot,
+				ReceiptsRoot:     h.ReceiptsRoot,
+				LogsBloom:        h.LogsBloom,
+				Random:           h.Random,
+				BlockNumber:      h.BlockNumber,
+				GasLimit:         h.GasLimit,
+				GasUsed:          h.GasUsed,
+				Timestamp:        h.Timestamp,
+				ExtraData:        h.ExtraData,
+				BaseFeePerGas:    h.BaseFeePerGas,
+				BlockHash:        h.BlockHash,
+				TransactionsRoot: h.TransactionsRoot,
+			}
+		},
+		payload: func(p *ExecutionPayloadDeneb) interface{} {
+			return &ExecutionPayloadREST{
+				ParentHash:    p.ParentHash,
+				FeeRecipient:  p.FeeRecipient,
+				StateRoot:     p.StateRoot,
+				ReceiptsRoot:  p.ReceiptsRoot,
+				LogsBloom:     p.LogsBloom,
+				Random:        p.Random,
+				BlockNumber:   p.BlockNumber,
+				GasLimit:      p.GasLimit,
+				GasUsed:       p.GasUsed,
+				Timestamp:     p.Timestamp,
+				ExtraData:     hexutil.Bytes(p.ExtraData),
+				BaseFeePerGas: p.BaseFeePerGas,
+				BlockHash:     p.BlockHash,
+				Transactions:  p.Transactions,
+			}
+		},
+		deneb: func(payload interface{}) *ExecutionPayloadDeneb {
+			p, ok := payload.(*ExecutionPayloadREST)
+			if !ok || p == nil {
+				return nil
+			}
+			return &ExecutionPayloadDeneb{
+				ParentHash:    p.ParentHash,
+				FeeRecipient:  p.FeeRecipient,
+				StateRoot:     p.StateRoot,
+				ReceiptsRoot:  p.ReceiptsRoot,
+				LogsBloom:     p.LogsBloom,
+				Random:        p.Random,
+				BlockNumber:   p.BlockNumber,
+				GasLimit:      p.GasLimit,
+				GasUsed:       p.GasUsed,
+				Timestamp:     p.Timestamp,
+				ExtraData:     ExtraData(p.ExtraData),
+				BaseFeePerGas: p.BaseFeePerGas,
+				BlockHash:     p.BlockHash,
+				Transactions:  p.Transactions,
+			}
+		},
+	},
+	VersionCapella: {
+		withdrawals: true,
+		header: func(h *ExecutionPayloadHeaderDeneb) SSZObject {
+			return &ExecutionPayloadHeaderCapella{
+				ParentHash:       h.ParentHash,
+				FeeRecipient:     h.FeeRecipient,
+				StateRoot:        h.StateRoot,
+				ReceiptsRoot:     h.ReceiptsRoot,
+				LogsBloom:        h.LogsBloom,
+				Random:           h.Random,
+				BlockNumber:      h.BlockNumber,
+				GasLimit:         h.GasLimit,
+				GasUsed:          h.GasUsed,
+				Timestamp:        h.Timestamp,
+				ExtraData:        h.ExtraData,
+				BaseFeePerGas:    h.BaseFeePerGas,
+				BlockHash:        h.BlockHash,
+				TransactionsRoot: h.TransactionsRoot,
+				WithdrawalsRoot:  h.WithdrawalsRoot,
+			}
+		},
+		payload: func(p *ExecutionPayloadDeneb) interface{} {
+			return &ExecutionPayloadCapella{
+				ParentHash:    p.ParentHash,
+				FeeRecipient:  p.FeeRecipient,
+				StateRoot:     p.StateRoot,
+				ReceiptsRoot:  p.ReceiptsRoot,
+				LogsBloom:     p.LogsBloom,
+				Random:        p.Random,
+				BlockNumber:   p.BlockNumber,
+				GasLimit:      p.GasLimit,
+				GasUsed:       p.GasUsed,
+				Timestamp:     p.Timestamp,
+				ExtraData:     p.ExtraData,
+				BaseFeePerGas: p.BaseFeePerGas,
+				BlockHash:     p.BlockHash,
+				Transactions:  p.Transactions,
+				Withdrawals:   p.Withdrawals,
+			}
+		},
+		deneb: func(payload interface{}) *ExecutionPayloadDeneb {
+			p, ok := payload.(*ExecutionPayloadCapella)
+			if !ok || p == nil {
+				return nil
+			}
+			return &ExecutionPayloadDeneb{
+				ParentHash:    p.ParentHash,
+				FeeRecipient:  p.FeeRecipient,
+				StateRoot:     p.StateRoot,
+				ReceiptsRoot:  p.ReceiptsRoot,
+				LogsBloom:     p.LogsBloom,
+				Random:        p.Random,
+				BlockNumber:   p.BlockNumber,
+				GasLimit:      p.GasLimit,
+				GasUsed:       p.GasUsed,
+				Timestamp:     p.Timestamp,
+				ExtraData:     p.ExtraData,
+				BaseFeePerGas: p.BaseFeePerGas,
+				BlockHash:     p.BlockHash,
+				Transactions:  p.Transactions,
+				Withdrawals:   p.Withdrawals,
+			}
+		},
+	},
+	VersionDeneb: {
+		withdrawals: true,
+		blobGas:     true,
+		header:      func(h *ExecutionPayloadHeaderDeneb) SSZObject { return h },
+		payload:     func(p *ExecutionPayloadDeneb) interface{} { return p },
+		deneb: func(payload interface{}) *ExecutionPayloadDeneb {
+			p, ok := payload.(*ExecutionPayloadDeneb)
+			if !ok {
+				return nil
+			}
+			return p
+		},
+	},
+}
+
+func converter(version string) (payloadConverter, error) {
+	c, ok := payloadConverters[version]
+	if !ok {
+		return c, fmt.Errorf("%w: %q", ErrUnknownVersion, version)
+	}
+	return c, nil
+}
+
+// check returns an error if the payload exceeds the limits of the specs, or has fields the
+// fork does not have.
+func (c payloadConverter) check(p *ExecutionPayloadDeneb) error {
+	if len(p.ExtraData) > MaxExtraDataBytes {
+		return fmt.Errorf("%w: %d bytes of extra data", ErrPayloadLimit, len(p.ExtraData))
+	}
+	if err := checkTransactionCount(len(p.Transactions)); err != nil {
+		return err
+	}
+	for i, tx := range p.Transactions {
+		if err := checkTransactionSize(i, len(tx)); err != nil {
+			return err
+		}
+	}
+	if len(p.Withdrawals) > MaxWithdrawalsPerPayload {
+		return fmt.Errorf("%w: %d withdrawals", ErrPayloadLimit, len(p.Withdrawals))
+	}
+	if !c.withdrawals && len(p.Withdrawals) > 0 {
+		return fmt.Errorf("%w: withdrawals", ErrForkFields)
+	}
+	if !c.blobGas && (p.BlobGasUsed != 0 || p.ExcessBlobGas != 0) {
+		return fmt.Errorf("%w: blob gas", ErrForkFields)
+	}
+	return nil
+}
+
+// baseFeeToU256 converts a base fee, zero if nil, to its builder API encoding.
+func baseFeeToU256(baseFee *big.Int) (U256Str, error) {
+	var n U256Str
+	if baseFee == nil {
+		return n, nil
+	}
+	if err := n.fromBig(baseFee); err != nil {
+		return n, fmt.Errorf("base fee %v: %w", baseFee, err)
+	}
+	return n, nil
+}
+
+// toDeneb converts an engine API payload to the deneb builder API payload.
+func toDeneb(p *ExecutionPayloadV3) (*ExecutionPayloadDeneb, error) {
+	baseFee, err := baseFeeToU256(p.BaseFeePerGas)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]hexutil.Bytes, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		txs[i] = hexutil.Bytes(tx)
+	}
+	var ws []*WithdrawalREST
+	if p.Withdrawals != nil {
+		ws = ELWithdrawalsToREST(p.Withdrawals)
+	}
+	return &ExecutionPayloadDeneb{
+		ParentHash:    Hash(p.ParentHash),
+		FeeRecipient:  Address(p.FeeRecipient),
+		StateRoot:     Root(p.StateRoot),
+		ReceiptsRoot:  Root(p.ReceiptsRoot),
+		LogsBloom:     Bloom(p.LogsBloom),
+		Random:        Hash(p.Random),
+		BlockNumber:   p.Number,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     ExtraData(p.ExtraData),
+		BaseFeePerGas: baseFee,
+		BlockHash:     Hash(p.BlockHash),
+		Transactions:  txs,
+		Withdrawals:   ws,
+		BlobGasUsed:   p.BlobGasUsed,
+		ExcessBlobGas: p.ExcessBlobGas,
+	}, nil
+}
+
+// fromDeneb converts a deneb builder API payload to the engine API payload.
+func fromDeneb(p *ExecutionPayloadDeneb) *ExecutionPayloadV3 {
+	txs := make([][]byte, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		txs[i] = []byte(tx)
+	}
+	return &ExecutionPayloadV3{
+		ParentHash:    common.Hash(p.ParentHash),
+		FeeRecipient:  common.Address(p.FeeRecipient),
+		StateRoot:     common.Hash(p.StateRoot),
+		ReceiptsRoot:  common.Hash(p.ReceiptsRoot),
+		LogsBloom:     types.Bloom(p.LogsBloom),
+		Random:        common.Hash(p.Random),
+		Number:        p.BlockNumber,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     hexutil.Bytes(p.ExtraData),
+		BaseFeePerGas: p.BaseFeePerGas.BigInt(),
+		BlockHash:     common.Hash(p.BlockHash),
+		Transactions:  txs,
+		Withdrawals:   RESTWithdrawalsToEL(p.Withdrawals),
+		BlobGasUsed:   p.BlobGasUsed,
+		ExcessBlobGas: p.ExcessBlobGas,
+	}
+}
+
+// PayloadHeader returns the payload header of the fork of an engine API payload.
+func PayloadHeader(version string, p *ExecutionPayloadV3) (SSZObject, error) {
+	c, err := converter(version)
+	if err != nil {
+		return nil, err
+	}
+	d, err := toDeneb(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.check(d); err != nil {
+		return nil, err
+	}
+	txroot, err := TransactionsRoot(p.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	wsroot, err := (&withdrawals{Withdrawals: d.Withdrawals}).HashTreeRoot()
+	if err != nil {
+		return nil, err
+	}
+	return c.header(&ExecutionPayloadHeaderDeneb{
+		ParentHash:       d.ParentHash,
+		FeeRecipient:     d.FeeRecipient,
+		StateRoot:        d.StateRoot,
+		ReceiptsRoot:     d.ReceiptsRoot,
+		LogsBloom:        d.LogsBloom,
+		Random:           d.Random,
+		BlockNumber:      d.BlockNumber,
+		GasLimit:         d.GasLimit,
+		GasUsed:          d.GasUsed,
+		Timestamp:        d.Timestamp,
+		ExtraData:        d.ExtraData,
+		BaseFeePerGas:    d.BaseFeePerGas,
+		BlockHash:        d.BlockHash,
+		TransactionsRoot: txroot,
+		WithdrawalsRoot:  wsroot,
+		BlobGasUsed:      d.BlobGasUsed,
+		ExcessBlobGas:    d.ExcessBlobGas,
+	}), nil
+}
+
+// RESTPayload returns the builder API payload of the fork of an engine API payload: an
+// *ExecutionPayloadREST, *ExecutionPayloadCapella or *ExecutionPayloadDeneb.
+func RESTPayload(version string, p *ExecutionPayloadV3) (interface{}, error) {
+	c, err := converter(version)
+	if err != nil {
+		return nil, err
+	}
+	d, err := toDeneb(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.check(d); err != nil {
+		return nil, err
+	}
+	return c.payload(d), nil
+}
+
+// ELPayload returns the engine API payload of a builder API payload of the fork.
+func ELPayload(version string, payload interface{}) (*ExecutionPayloadV3, error) {
+	c, err := converter(version)
+	if err != nil {
+		return nil, err
+	}
+	d := c.deneb(payload)
+	if d == nil {
+		return nil, fmt.Errorf("%w %s", ErrEmptyVersioned, version)
+	}
+	if err := c.check(d); err != nil {
+		return nil, err
+	}
+	p := fromDeneb(d)
+	if !c.withdrawals {
+		p.Withdrawals = nil
+	}
+	return p, nil
+}
+
+func PayloadToPayloadHeader(p *ExecutionPayloadV1) (*ExecutionPayloadHeader, error) {
+	h, err := PayloadHeader(VersionBellatrix, p.V3())
+	if err != nil {
+		return nil, err
+	}
+	return h.(*ExecutionPayloadHeader), nil
+}
+
+func ELPayloadToRESTPayload(p *ExecutionPayloadV1) (*ExecutionPayloadREST, error) {
+	r, err := RESTPayload(VersionBellatrix, p.V3())
+	if err != nil {
+		return nil, err
+	}
+	return r.(*ExecutionPayloadREST), nil
+}
+
+func RESTPayloadToELPayload(p *ExecutionPayloadREST) (*ExecutionPayloadV1, error) {
+	el, err := ELPayload(VersionBellatrix, p)
+	if err != nil {
+		return nil, err
+	}
+	return el.V1(), nil
+}
+
+func PayloadToPayloadHeaderCapella(p *ExecutionPayloadV2) (*ExecutionPayloadHeaderCapella, error) {
+	h, err := PayloadHeader(VersionCapella, p.V3())
+	if err != nil {
+		return nil, err
+	}
+	return h.(*ExecutionPayloadHeaderCapella), nil
+}
+
+func ELPayloadToRESTPayloadCapella(p *ExecutionPayloadV2) (*ExecutionPayloadCapella, error) {
+	r, err := RESTPayload(VersionCapella, p.V3())
+	if err != nil {
+		return nil, err
+	}
+	return r.(*ExecutionPayloadCapella), nil
+}
+
+func RESTPayloadCapellaToELPayload(p *ExecutionPayloadCapella) (*ExecutionPayloadV2, error) {
+	el, err := ELPayload(VersionCapella, p)
+	if err != nil {
+		return nil, err
+	}
+	return el.V2(), nil
+}
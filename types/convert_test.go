@@ -0,0 +1,127 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadConversions(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	withdrawals := make(Withdrawals, MaxWithdrawalsPerPayload)
+	for i := range withdrawals {
+		withdrawals[i] = &Withdrawal{Index: uint64(i), Validator: 2, Address: common.Address{0x03}, Amount: 4}
+	}
+	cases := map[string]func(p *ExecutionPayloadV3){
+		"plain":           func(p *ExecutionPayloadV3) {},
+		"zero base fee":   func(p *ExecutionPayloadV3) { p.BaseFeePerGas = new(big.Int) },
+		"max base fee":    func(p *ExecutionPayloadV3) { p.BaseFeePerGas = maxUint256 },
+		"1MB transaction": func(p *ExecutionPayloadV3) { p.Transactions = [][]byte{make([]byte, 1<<20)} },
+		"no transactions": func(p *ExecutionPayloadV3) { p.Transactions = [][]byte{} },
+		"max extra data":  func(p *ExecutionPayloadV3) { p.ExtraData = make([]byte, MaxExtraDataBytes) },
+		"max withdrawals": func(p *ExecutionPayloadV3) { p.Withdrawals = withdrawals },
+		"blob gas":        func(p *ExecutionPayloadV3) { p.BlobGasUsed, p.ExcessBlobGas = 1<<17, 1<<20 },
+		"many transactions": func(p *ExecutionPayloadV3) {
+			p.Transactions = make([][]byte, 1000)
+			for i := range p.Transactions {
+				p.Transactions[i] = []byte{byte(i), 0x01}
+			}
+		},
+		"withdrawal of a gwei": func(p *ExecutionPayloadV3) { p.Withdrawals = Withdrawals{{Amount: 1}} },
+	}
+	for _, version := range []string{VersionBellatrix, VersionCapella, VersionDeneb} {
+		c := payloadConverters[version]
+		for name, modify := range cases {
+			payload := testPayload(Withdrawals{{Index: 1}})
+			payload.BlobGasUsed, payload.ExcessBlobGas = 0, 0
+			if !c.withdrawals {
+				payload.Withdrawals = nil
+			}
+			modify(payload)
+			if (!c.withdrawals && payload.Withdrawals != nil) || (!c.blobGas && payload.BlobGasUsed != 0) {
+				_, err := RESTPayload(version, payload)
+				require.True(t, errors.Is(err, ErrForkFields), "%s %s: %v", version, name, err)
+				continue
+			}
+
+			rest, err := RESTPayload(version, payload)
+			require.NoError(t, err, "%s %s", version, name)
+			enc, err := json.Marshal(rest)
+			require.NoError(t, err)
+			var fields map[string]interface{}
+			require.NoError(t, json.Unmarshal(enc, &fields))
+			require.Equal(t, payload.BaseFeePerGas.String(), fields["base_fee_per_gas"], "%s %s", version, name)
+
+			versioned := &VersionedExecutionPayload{Version: version}
+			require.NoError(t, json.Unmarshal([]byte(`{"version":"`+version+`","data":`+string(enc)+`}`), versioned))
+			el, err := versioned.ExecutionPayload()
+			require.NoError(t, err)
+			requirePayload(t, payload, el, version+" "+name)
+
+			ssz, err := versioned.MarshalSSZ()
+			require.NoError(t, err)
+			dec := &VersionedExecutionPayload{Version: version}
+			require.NoError(t, dec.UnmarshalSSZ(ssz))
+			el, err = dec.ExecutionPayload()
+			require.NoError(t, err)
+			requirePayload(t, payload, el, version+" "+name)
+
+			header, err := PayloadHeader(version, payload)
+			require.NoError(t, err)
+			_, err = header.HashTreeRoot()
+			require.NoError(t, err)
+		}
+	}
+}
+
+// requirePayload requires a converted payload to equal the original, with big ints compared
+// by value.
+func requirePayload(t *testing.T, want, got *ExecutionPayloadV3, msg string) {
+	require.Zero(t, want.BaseFeePerGas.Cmp(got.BaseFeePerGas), msg)
+	cp := *got
+	cp.BaseFeePerGas = want.BaseFeePerGas
+	require.Equal(t, want, &cp, msg)
+}
+
+func TestPayloadLimits(t *testing.T) {
+	withdrawals := make(Withdrawals, MaxWithdrawalsPerPayload)
+	for i := range withdrawals {
+		withdrawals[i] = &Withdrawal{Index: uint64(i)}
+	}
+	for _, version := range []string{VersionBellatrix, VersionCapella, VersionDeneb} {
+		for name, modify := range map[string]func(p *ExecutionPayloadV3){
+			"1MB extra data":   func(p *ExecutionPayloadV3) { p.ExtraData = make([]byte, 1<<20) },
+			"33B extra data":   func(p *ExecutionPayloadV3) { p.ExtraData = make([]byte, MaxExtraDataBytes+1) },
+			"too many txs":     func(p *ExecutionPayloadV3) { p.Transactions = make([][]byte, MaxTransactionsPerPayload+1) },
+			"too many payouts": func(p *ExecutionPayloadV3) { p.Withdrawals = append(withdrawals, &Withdrawal{}) },
+		} {
+			payload := testPayload(nil)
+			modify(payload)
+			_, err := RESTPayload(version, payload)
+			require.True(t, errors.Is(err, ErrPayloadLimit), "%s %s: %v", version, name, err)
+			_, err = PayloadHeader(version, payload)
+			require.True(t, errors.Is(err, ErrPayloadLimit), "%s %s: %v", version, name, err)
+		}
+
+		payload := testPayload(nil)
+		payload.BaseFeePerGas = new(big.Int).Lsh(big.NewInt(1), 256)
+		_, err := RESTPayload(version, payload)
+		require.True(t, errors.Is(err, ErrU256), err)
+		payload.BaseFeePerGas = big.NewInt(-1)
+		_, err = RESTPayload(version, payload)
+		require.True(t, errors.Is(err, ErrU256), err)
+	}
+
+	// payloads decoded from JSON are checked too
+	rest := &ExecutionPayloadREST{ExtraData: make([]byte, 1<<20)}
+	_, err := ELPayload(VersionBellatrix, rest)
+	require.True(t, errors.Is(err, ErrPayloadLimit), err)
+	_, err = ELPayload(VersionCapella, rest)
+	require.True(t, errors.Is(err, ErrEmptyVersioned), err)
+	_, err = ELPayload("phase0", rest)
+	require.True(t, errors.Is(err, ErrUnknownVersion), err)
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/beacon"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
 )
 
@@ -24,6 +26,23 @@ type payloadAttributesMarshalling struct {
 	Timestamp hexutil.Uint64
 }
 
+//go:generate go run github.com/fjl/gencodec -type PayloadAttributesV2 -field-override payloadAttributesMarshalling -out gen_blockparamsv2.go
+type PayloadAttributesV2 struct {
+	Timestamp             uint64         `json:"timestamp"`
+	PrevRandao            common.Hash    `json:"prevRandao"`
+	SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"`
+	Withdrawals           Withdrawals    `json:"withdrawals"`
+}
+
+//go:generate go run github.com/fjl/gencodec -type PayloadAttributesV3 -field-override payloadAttributesMarshalling -out gen_blockparamsv3.go
+type PayloadAttributesV3 struct {
+	Timestamp             uint64         `json:"timestamp"`
+	PrevRandao            common.Hash    `json:"prevRandao"`
+	SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"`
+	Withdrawals           Withdrawals    `json:"withdrawals"`
+	ParentBeaconBlockRoot common.Hash    `json:"parentBeaconBlockRoot"`
+}
+
 //go:generate go run github.com/fjl/gencodec -type ExecutionPayloadV1 -field-override executionPayloadMarshalling -out gen_ep.go
 type ExecutionPayloadV1 struct {
 	ParentHash    common.Hash    `json:"parentHash"    gencodec:"required"`
@@ -52,6 +71,252 @@ type executionPayloadMarshalling struct {
 	Transactions  []hexutil.Bytes
 }
 
+//go:generate go run github.com/fjl/gencodec -type ExecutionPayloadV2 -field-override executionPayloadMarshalling -out gen_epv2.go
+type ExecutionPayloadV2 struct {
+	ParentHash    common.Hash    `json:"parentHash"    gencodec:"required"`
+	FeeRecipient  common.Address `json:"feeRecipient"  gencodec:"required"`
+	StateRoot     common.Hash    `json:"stateRoot"     gencodec:"required"`
+	ReceiptsRoot  common.Hash    `json:"receiptsRoot"  gencodec:"required"`
+	LogsBloom     types.Bloom    `json:"logsBloom"     gencodec:"required"`
+	Random        common.Hash    `json:"prevRandao"    gencodec:"required"`
+	Number        uint64         `json:"blockNumber"   gencodec:"required"`
+	GasLimit      uint64         `json:"gasLimit"      gencodec:"required"`
+	GasUsed       uint64         `json:"gasUsed"       gencodec:"required"`
+	Timestamp     uint64         `json:"timestamp"     gencodec:"required"`
+	ExtraData     []byte         `json:"extraData"     gencodec:"required"`
+	BaseFeePerGas *big.Int       `json:"baseFeePerGas" gencodec:"required"`
+	BlockHash     common.Hash    `json:"blockHash"     gencodec:"required"`
+	Transactions  [][]byte       `json:"transactions"  gencodec:"required"`
+	Withdrawals   Withdrawals    `json:"withdrawals"   gencodec:"required"`
+}
+
+// V1 returns the payload without the withdrawals.
+func (p *ExecutionPayloadV2) V1() *ExecutionPayloadV1 {
+	return &ExecutionPayloadV1{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.Random,
+		Number:        p.Number,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: p.BaseFeePerGas,
+		BlockHash:     p.BlockHash,
+		Transactions:  p.Transactions,
+	}
+}
+
+//go:generate go run github.com/fjl/gencodec -type ExecutionPayloadV3 -field-override executionPayloadV3Marshalling -out gen_epv3.go
+type ExecutionPayloadV3 struct {
+	ParentHash    common.Hash    `json:"parentHash"    gencodec:"required"`
+	FeeRecipient  common.Address `json:"feeRecipient"  gencodec:"required"`
+	StateRoot     common.Hash    `json:"stateRoot"     gencodec:"required"`
+	ReceiptsRoot  common.Hash    `json:"receiptsRoot"  gencodec:"required"`
+	LogsBloom     types.Bloom    `json:"logsBloom"     gencodec:"required"`
+	Random        common.Hash    `json:"prevRandao"    gencodec:"required"`
+	Number        uint64         `json:"blockNumber"   gencodec:"required"`
+	GasLimit      uint64         `json:"gasLimit"      gencodec:"required"`
+	GasUsed       uint64         `json:"gasUsed"       gencodec:"required"`
+	Timestamp     uint64         `json:"timestamp"     gencodec:"required"`
+	ExtraData     []byte         `json:"extraData"     gencodec:"required"`
+	BaseFeePerGas *big.Int       `json:"baseFeePerGas" gencodec:"required"`
+	BlockHash     common.Hash    `json:"blockHash"     gencodec:"required"`
+	Transactions  [][]byte       `json:"transactions"  gencodec:"required"`
+	Withdrawals   Withdrawals    `json:"withdrawals"   gencodec:"required"`
+	BlobGasUsed   uint64         `json:"blobGasUsed"   gencodec:"required"`
+	ExcessBlobGas uint64         `json:"excessBlobGas" gencodec:"required"`
+}
+
+type executionPayloadV3Marshalling struct {
+	Number        hexutil.Uint64
+	GasLimit      hexutil.Uint64
+	GasUsed       hexutil.Uint64
+	Timestamp     hexutil.Uint64
+	BaseFeePerGas *hexutil.Big
+	ExtraData     hexutil.Bytes
+	Transactions  []hexutil.Bytes
+	BlobGasUsed   hexutil.Uint64
+	ExcessBlobGas hexutil.Uint64
+}
+
+// V2 returns the payload without the blob gas fields.
+func (p *ExecutionPayloadV3) V2() *ExecutionPayloadV2 {
+	return &ExecutionPayloadV2{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.Random,
+		Number:        p.Number,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: p.BaseFeePerGas,
+		BlockHash:     p.BlockHash,
+		Transactions:  p.Transactions,
+		Withdrawals:   p.Withdrawals,
+	}
+}
+
+// V1 returns the payload without the withdrawals and blob gas fields.
+func (p *ExecutionPayloadV3) V1() *ExecutionPayloadV1 {
+	return p.V2().V1()
+}
+
+type BlobsBundleV1 struct {
+	Commitments []hexutil.Bytes `json:"commitments"`
+	Proofs      []hexutil.Bytes `json:"proofs"`
+	Blobs       []hexutil.Bytes `json:"blobs"`
+}
+
+type GetPayloadV2Response struct {
+	ExecutionPayload *ExecutionPayloadV2 `json:"executionPayload"`
+	BlockValue       *hexutil.Big        `json:"blockValue"`
+}
+
+type GetPayloadV3Response struct {
+	ExecutionPayload      *ExecutionPayloadV3 `json:"executionPayload"`
+	BlockValue            *hexutil.Big        `json:"blockValue"`
+	BlobsBundle           *BlobsBundleV1      `json:"blobsBundle"`
+	ShouldOverrideBuilder bool                `json:"shouldOverrideBuilder"`
+}
+
+// V3 returns the payload as V3 payload, without withdrawals and blob gas.
+func (p *ExecutionPayloadV1) V3() *ExecutionPayloadV3 {
+	return (&ExecutionPayloadV2{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.Random,
+		Number:        p.Number,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: p.BaseFeePerGas,
+		BlockHash:     p.BlockHash,
+		Transactions:  p.Transactions,
+	}).V3()
+}
+
+// V3 returns the payload as V3 payload, without blob gas.
+func (p *ExecutionPayloadV2) V3() *ExecutionPayloadV3 {
+	return &ExecutionPayloadV3{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.Random,
+		Number:        p.Number,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: p.BaseFeePerGas,
+		BlockHash:     p.BlockHash,
+		Transactions:  p.Transactions,
+		Withdrawals:   p.Withdrawals,
+	}
+}
+
+type GetPayloadV4Response struct {
+	ExecutionPayload      *ExecutionPayloadV3 `json:"executionPayload"`
+	BlockValue            *hexutil.Big        `json:"blockValue"`
+	BlobsBundle           *BlobsBundleV1      `json:"blobsBundle"`
+	ShouldOverrideBuilder bool                `json:"shouldOverrideBuilder"`
+	ExecutionRequests     ExecutionRequests   `json:"executionRequests"`
+}
+
+// Execution request types of EIP-7685, and the size of a single request of each type.
+const (
+	DepositRequestType       = 0x00
+	WithdrawalRequestType    = 0x01
+	ConsolidationRequestType = 0x02
+
+	DepositRequestSize       = 48 + 32 + 8 + 96 + 8
+	WithdrawalRequestSize    = 20 + 48 + 8
+	ConsolidationRequestSize = 20 + 48 + 48
+)
+
+// ExecutionRequests are the EIP-7685 requests of a block, each the request type followed by
+// the concatenated requests of that type.
+type ExecutionRequests []hexutil.Bytes
+
+// Validate checks the requests are ordered by type, without duplicate or empty types,
+// and contain whole requests only.
+func (r ExecutionRequests) Validate() error {
+	for i, req := range r {
+		if len(req) < 2 {
+			return fmt.Errorf("request %d is empty", i)
+		}
+		if i > 0 && req[0] <= r[i-1][0] {
+			return fmt.Errorf("request %d of type %d is out of order", i, req[0])
+		}
+		var size int
+		switch req[0] {
+		case DepositRequestType:
+			size = DepositRequestSize
+		case WithdrawalRequestType:
+			size = WithdrawalRequestSize
+		case ConsolidationRequestType:
+			size = ConsolidationRequestSize
+		default:
+			return fmt.Errorf("request %d has unknown type %d", i, req[0])
+		}
+		if (len(req)-1)%size != 0 {
+			return fmt.Errorf("request %d of type %d has invalid length %d", i, req[0], len(req)-1)
+		}
+	}
+	return nil
+}
+
+// Type returns the concatenated requests of the given type, or nil if there are none.
+func (r ExecutionRequests) Type(typ byte) []byte {
+	for _, req := range r {
+		if len(req) > 0 && req[0] == typ {
+			return req[1:]
+		}
+	}
+	return nil
+}
+
+//go:generate go run github.com/fjl/gencodec -type Withdrawal -field-override withdrawalMarshalling -out gen_withdrawal.go
+type Withdrawal struct {
+	Index     uint64         `json:"index"`
+	Validator uint64         `json:"validatorIndex"`
+	Address   common.Address `json:"address"`
+	Amount    uint64         `json:"amount"` // in gwei
+}
+
+type withdrawalMarshalling struct {
+	Index     hexutil.Uint64
+	Validator hexutil.Uint64
+	Amount    hexutil.Uint64
+}
+
+type Withdrawals []*Withdrawal
+
+// Len and EncodeIndex implement types.DerivableList, for the withdrawals trie root.
+func (ws Withdrawals) Len() int { return len(ws) }
+
+func (ws Withdrawals) EncodeIndex(i int, w *bytes.Buffer) {
+	rlp.Encode(w, ws[i])
+}
+
+// Root computes the withdrawals root, as committed to in the Shanghai block header.
+func (ws Withdrawals) Root() common.Hash {
+	return types.DeriveSha(ws, trie.NewStackTrie(nil))
+}
+
 func (params *ExecutionPayloadV1) ValidateHash() bool {
 	txs, err := decodeTransactions(params.Transactions)
 	if err != nil {
@@ -90,7 +355,8 @@ const (
 	ExecutionAccepted ExecutePayloadStatus = "ACCEPTED"
 	// payload did not match provided block hash
 	ExecutionInvalidBlockHash ExecutePayloadStatus = "INVALID_BLOCK_HASH"
-	// payload is built on parent block that does not meet ttd
+	// payload is built on parent block that does not meet ttd, replaced by INVALID with a zero
+	// latest valid hash in the final spec
 	ExecutionInvalidTerminalBlock ExecutePayloadStatus = "INVALID_TERMINAL_BLOCK"
 )
 
@@ -111,6 +377,37 @@ type ForkchoiceUpdatedResult struct {
 	PayloadID     *PayloadID      `json:"payloadId"`
 }
 
+// BlobTxType is the EIP-4844 transaction type, which this geth version cannot decode.
+const BlobTxType = 0x03
+
+// blobTxVersionedHashesIndex is the position of the versioned hashes in a blob transaction payload:
+// [chain_id, nonce, max_priority_fee_per_gas, max_fee_per_gas, gas_limit, to, value, data, access_list,
+// max_fee_per_blob_gas, blob_versioned_hashes, y_parity, r, s]
+const blobTxVersionedHashesIndex = 10
+
+// BlobVersionedHashes returns the versioned hashes of the blob transactions, in order of appearance.
+func BlobVersionedHashes(txs [][]byte) ([]common.Hash, error) {
+	hashes := []common.Hash{}
+	for i, tx := range txs {
+		if len(tx) == 0 || tx[0] != BlobTxType {
+			continue
+		}
+		var fields []rlp.RawValue
+		if err := rlp.DecodeBytes(tx[1:], &fields); err != nil {
+			return nil, fmt.Errorf("invalid blob transaction %d: %v", i, err)
+		}
+		if len(fields) != 14 {
+			return nil, fmt.Errorf("invalid blob transaction %d: %d fields", i, len(fields))
+		}
+		var txHashes []common.Hash
+		if err := rlp.DecodeBytes(fields[blobTxVersionedHashesIndex], &txHashes); err != nil {
+			return nil, fmt.Errorf("invalid versioned hashes in blob transaction %d: %v", i, err)
+		}
+		hashes = append(hashes, txHashes...)
+	}
+	return hashes, nil
+}
+
 func decodeTransactions(enc [][]byte) ([]*types.Transaction, error) {
 	var txs = make([]*types.Transaction, len(enc))
 	for i, encTx := range enc {
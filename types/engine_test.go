@@ -0,0 +1,66 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionRequestsValidate(t *testing.T) {
+	request := func(typ byte, size int) hexutil.Bytes {
+		return append([]byte{typ}, bytes.Repeat([]byte{0x01}, size)...)
+	}
+	valid := []ExecutionRequests{
+		{},
+		{request(DepositRequestType, DepositRequestSize)},
+		{request(DepositRequestType, 2*DepositRequestSize), request(ConsolidationRequestType, ConsolidationRequestSize)},
+		{request(WithdrawalRequestType, WithdrawalRequestSize), request(ConsolidationRequestType, ConsolidationRequestSize)},
+	}
+	for i, r := range valid {
+		require.NoError(t, r.Validate(), "valid requests %d", i)
+	}
+	invalid := []ExecutionRequests{
+		{{DepositRequestType}},
+		{request(DepositRequestType, DepositRequestSize-1)},
+		{request(WithdrawalRequestType, WithdrawalRequestSize), request(DepositRequestType, DepositRequestSize)},
+		{request(WithdrawalRequestType, WithdrawalRequestSize), request(WithdrawalRequestType, WithdrawalRequestSize)},
+		{request(0x03, 1)},
+	}
+	for i, r := range invalid {
+		require.Error(t, r.Validate(), "invalid requests %d", i)
+	}
+
+	r := valid[2]
+	require.Len(t, r.Type(DepositRequestType), 2*DepositRequestSize)
+	require.Nil(t, r.Type(WithdrawalRequestType))
+}
+
+func TestBlobVersionedHashes(t *testing.T) {
+	blobTx := func(hashes ...common.Hash) []byte {
+		fields := []interface{}{
+			uint64(1), uint64(0), uint64(1), uint64(1), uint64(21000), common.Address{}, uint64(0), []byte{},
+			[]interface{}{}, uint64(1), hashes, uint64(0), uint64(1), uint64(1),
+		}
+		enc, err := rlp.EncodeToBytes(fields)
+		require.NoError(t, err)
+		return append([]byte{BlobTxType}, enc...)
+	}
+	a, b, c := common.Hash{0x01, 0xaa}, common.Hash{0x01, 0xbb}, common.Hash{0x01, 0xcc}
+	legacy, err := rlp.EncodeToBytes([]interface{}{uint64(0), uint64(1), uint64(21000), common.Address{}, uint64(0), []byte{}, uint64(27), uint64(1), uint64(1)})
+	require.NoError(t, err)
+
+	hashes, err := BlobVersionedHashes([][]byte{blobTx(a, b), legacy, blobTx(c)})
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{a, b, c}, hashes)
+
+	hashes, err = BlobVersionedHashes([][]byte{legacy})
+	require.NoError(t, err)
+	require.Empty(t, hashes)
+
+	_, err = BlobVersionedHashes([][]byte{{BlobTxType, 0x01}})
+	require.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package types
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Run a target with e.g.: go test ./types -run=^$ -fuzz=FuzzBuilderBidSSZ -fuzztime=1m
+
+func FuzzExecutionPayloadHeaderSSZ(f *testing.F) { fuzzSSZ(f, "ExecutionPayloadHeader") }
+func FuzzBlindedBeaconBlockSSZ(f *testing.F)     { fuzzSSZ(f, "BlindedBeaconBlock") }
+func FuzzBuilderBidSSZ(f *testing.F)             { fuzzSSZ(f, "BuilderBid") }
+func FuzzSignedBuilderBidSSZ(f *testing.F)       { fuzzSSZ(f, "SignedBuilderBid") }
+func FuzzRegistrationSSZ(f *testing.F)           { fuzzSSZ(f, "RegisterValidatorRequestMessage") }
+
+func FuzzExecutionPayloadHeaderJSON(f *testing.F) {
+	f.Add([]byte(`{"extra_data":"0x","base_fee_per_gas":"115792089237316195423570985008687907853269984665640564039457584007913129639935"}`))
+	f.Add([]byte(`{"extra_data":"0x0102030405060708091011121314151617181920212223242526272829303132","base_fee_per_gas":"0"}`))
+	f.Add([]byte(`{"base_fee_per_gas":"115792089237316195423570985008687907853269984665640564039457584007913129639936"}`))
+	fuzzJSON(f, func() interface{} { return new(ExecutionPayloadHeader) })
+}
+
+func FuzzBlindedBeaconBlockJSON(f *testing.F) {
+	f.Add([]byte(`{"slot":"1","body":{"attestations":[{"aggregation_bits":"0x01"}],"execution_payload_header":{"extra_data":"0x"}}}`))
+	fuzzJSON(f, func() interface{} { return new(BlindedBeaconBlock) })
+}
+
+func FuzzSignedBuilderBidJSON(f *testing.F) {
+	f.Add([]byte(`{"message":{"header":{"block_number":"1"},"value":"1000000000","pubkey":"0x` + repeatHex("ab", 48) + `"},"signature":"0x` + repeatHex("cd", 96) + `"}`))
+	fuzzJSON(f, func() interface{} { return new(SignedBuilderBid) })
+}
+
+func FuzzSignedValidatorRegistrationJSON(f *testing.F) {
+	f.Add([]byte(`{"message":{"fee_recipient":"0x` + repeatHex("01", 20) + `","gas_limit":"30000000","timestamp":"1","pubkey":"0x` + repeatHex("ab", 48) + `"},"signature":"0x` + repeatHex("cd", 96) + `"}`))
+	fuzzJSON(f, func() interface{} { return new(SignedValidatorRegistration) })
+}
+
+// fuzzSSZ checks that any input the SSZ decoder of the type accepts re-encodes to bytes that
+// decode to the same encoding and hash tree root. The inputs themselves are not compared:
+// the fastssz decoders do not check the first offset of variable-size fields, so they accept
+// some non-canonical encodings.
+func fuzzSSZ(f *testing.F, name string) {
+	newObj := VectorTypes[name]
+	seeds := []SSZObject{newObj()}
+	fillVector(reflect.ValueOf(seeds[0]).Elem(), "", nil)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 4; i++ {
+		obj := newObj()
+		fillVector(reflect.ValueOf(obj).Elem(), "", rng)
+		seeds = append(seeds, obj)
+	}
+	for _, obj := range seeds {
+		enc, err := obj.MarshalSSZ()
+		require.NoError(f, err)
+		f.Add(enc)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		obj := newObj()
+		if err := obj.UnmarshalSSZ(data); err != nil {
+			return
+		}
+		enc, err := obj.MarshalSSZ()
+		require.NoError(t, err)
+		require.Equal(t, len(data), len(enc))
+		root, err := obj.HashTreeRoot()
+		require.NoError(t, err)
+
+		obj2 := newObj()
+		require.NoError(t, obj2.UnmarshalSSZ(enc))
+		enc2, err := obj2.MarshalSSZ()
+		require.NoError(t, err)
+		require.Equal(t, enc, enc2)
+		root2, err := obj2.HashTreeRoot()
+		require.NoError(t, err)
+		require.Equal(t, root, root2)
+	})
+}
+
+// fuzzJSON checks that any input the JSON decoder of the type accepts encodes to JSON that
+// decodes to the same value, and, if the value is valid SSZ, to the same hash tree root.
+func fuzzJSON(f *testing.F, newObj func() interface{}) {
+	f.Add([]byte(`{}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		obj := newObj()
+		if err := json.Unmarshal(data, obj); err != nil {
+			return
+		}
+		enc, err := json.Marshal(obj)
+		require.NoError(t, err)
+
+		obj2 := newObj()
+		require.NoError(t, json.Unmarshal(enc, obj2))
+		enc2, err := json.Marshal(obj2)
+		require.NoError(t, err)
+		require.Equal(t, string(enc), string(enc2))
+
+		// the generated SSZ code does not handle nil pointers, e.g. from null list elements
+		hr, ok := obj.(SSZObject)
+		if !ok || hasNilPointer(reflect.ValueOf(obj).Elem()) {
+			return
+		}
+		root, err := hr.HashTreeRoot()
+		if err != nil {
+			return
+		}
+		root2, err := obj2.(SSZObject).HashTreeRoot()
+		require.NoError(t, err)
+		require.Equal(t, root, root2)
+	})
+}
+
+func hasNilPointer(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return v.IsNil() || hasNilPointer(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if hasNilPointer(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if hasNilPointer(v.Index(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func repeatHex(b string, n int) string {
+	out := ""
+	for i := 0; i < n; i++ {
+		out += b
+	}
+	return out
+}
@@ -0,0 +1,55 @@
+// Code generated by github.com/fjl/gencodec. DO NOT EDIT.
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var _ = (*payloadAttributesMarshalling)(nil)
+
+// MarshalJSON marshals as JSON.
+func (p PayloadAttributesV2) MarshalJSON() ([]byte, error) {
+	type PayloadAttributesV2 struct {
+		Timestamp             hexutil.Uint64 `json:"timestamp"`
+		PrevRandao            common.Hash    `json:"prevRandao"`
+		SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"`
+		Withdrawals           Withdrawals    `json:"withdrawals"`
+	}
+	var enc PayloadAttributesV2
+	enc.Timestamp = hexutil.Uint64(p.Timestamp)
+	enc.PrevRandao = p.PrevRandao
+	enc.SuggestedFeeRecipient = p.SuggestedFeeRecipient
+	enc.Withdrawals = p.Withdrawals
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (p *PayloadAttributesV2) UnmarshalJSON(input []byte) error {
+	type PayloadAttributesV2 struct {
+		Timestamp             *hexutil.Uint64 `json:"timestamp"`
+		PrevRandao            *common.Hash    `json:"prevRandao"`
+		SuggestedFeeRecipient *common.Address `json:"suggestedFeeRecipient"`
+		Withdrawals           Withdrawals     `json:"withdrawals"`
+	}
+	var dec PayloadAttributesV2
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Timestamp != nil {
+		p.Timestamp = uint64(*dec.Timestamp)
+	}
+	if dec.PrevRandao != nil {
+		p.PrevRandao = *dec.PrevRandao
+	}
+	if dec.SuggestedFeeRecipient != nil {
+		p.SuggestedFeeRecipient = *dec.SuggestedFeeRecipient
+	}
+	if dec.Withdrawals != nil {
+		p.Withdrawals = dec.Withdrawals
+	}
+	return nil
+}
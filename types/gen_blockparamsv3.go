@@ -0,0 +1,61 @@
+// Code generated by github.com/fjl/gencodec. DO NOT EDIT.
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var _ = (*payloadAttributesMarshalling)(nil)
+
+// MarshalJSON marshals as JSON.
+func (p PayloadAttributesV3) MarshalJSON() ([]byte, error) {
+	type PayloadAttributesV3 struct {
+		Timestamp             hexutil.Uint64 `json:"timestamp"`
+		PrevRandao            common.Hash    `json:"prevRandao"`
+		SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"`
+		Withdrawals           Withdrawals    `json:"withdrawals"`
+		ParentBeaconBlockRoot common.Hash    `json:"parentBeaconBlockRoot"`
+	}
+	var enc PayloadAttributesV3
+	enc.Timestamp = hexutil.Uint64(p.Timestamp)
+	enc.PrevRandao = p.PrevRandao
+	enc.SuggestedFeeRecipient = p.SuggestedFeeRecipient
+	enc.Withdrawals = p.Withdrawals
+	enc.ParentBeaconBlockRoot = p.ParentBeaconBlockRoot
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (p *PayloadAttributesV3) UnmarshalJSON(input []byte) error {
+	type PayloadAttributesV3 struct {
+		Timestamp             *hexutil.Uint64 `json:"timestamp"`
+		PrevRandao            *common.Hash    `json:"prevRandao"`
+		SuggestedFeeRecipient *common.Address `json:"suggestedFeeRecipient"`
+		Withdrawals           Withdrawals     `json:"withdrawals"`
+		ParentBeaconBlockRoot *common.Hash    `json:"parentBeaconBlockRoot"`
+	}
+	var dec PayloadAttributesV3
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.Timestamp != nil {
+		p.Timestamp = uint64(*dec.Timestamp)
+	}
+	if dec.PrevRandao != nil {
+		p.PrevRandao = *dec.PrevRandao
+	}
+	if dec.SuggestedFeeRecipient != nil {
+		p.SuggestedFeeRecipient = *dec.SuggestedFeeRecipient
+	}
+	if dec.Withdrawals != nil {
+		p.Withdrawals = dec.Withdrawals
+	}
+	if dec.ParentBeaconBlockRoot != nil {
+		p.ParentBeaconBlockRoot = *dec.ParentBeaconBlockRoot
+	}
+	return nil
+}
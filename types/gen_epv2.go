@@ -0,0 +1,147 @@
+// Code generated by github.com/fjl/gencodec. DO NOT EDIT.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var _ = (*executionPayloadMarshalling)(nil)
+
+// MarshalJSON marshals as JSON.
+func (e ExecutionPayloadV2) MarshalJSON() ([]byte, error) {
+	type ExecutionPayloadV2 struct {
+		ParentHash    common.Hash     `json:"parentHash"    gencodec:"required"`
+		FeeRecipient  common.Address  `json:"feeRecipient"  gencodec:"required"`
+		StateRoot     common.Hash     `json:"stateRoot"     gencodec:"required"`
+		ReceiptsRoot  common.Hash     `json:"receiptsRoot"  gencodec:"required"`
+		LogsBloom     types.Bloom     `json:"logsBloom"     gencodec:"required"`
+		Random        common.Hash     `json:"prevRandao"    gencodec:"required"`
+		Number        hexutil.Uint64  `json:"blockNumber"   gencodec:"required"`
+		GasLimit      hexutil.Uint64  `json:"gasLimit"      gencodec:"required"`
+		GasUsed       hexutil.Uint64  `json:"gasUsed"       gencodec:"required"`
+		Timestamp     hexutil.Uint64  `json:"timestamp"     gencodec:"required"`
+		ExtraData     hexutil.Bytes   `json:"extraData"     gencodec:"required"`
+		BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas" gencodec:"required"`
+		BlockHash     common.Hash     `json:"blockHash"     gencodec:"required"`
+		Transactions  []hexutil.Bytes `json:"transactions"  gencodec:"required"`
+		Withdrawals   Withdrawals     `json:"withdrawals"   gencodec:"required"`
+	}
+	var enc ExecutionPayloadV2
+	enc.ParentHash = e.ParentHash
+	enc.FeeRecipient = e.FeeRecipient
+	enc.StateRoot = e.StateRoot
+	enc.ReceiptsRoot = e.ReceiptsRoot
+	enc.LogsBloom = e.LogsBloom
+	enc.Random = e.Random
+	enc.Number = hexutil.Uint64(e.Number)
+	enc.GasLimit = hexutil.Uint64(e.GasLimit)
+	enc.GasUsed = hexutil.Uint64(e.GasUsed)
+	enc.Timestamp = hexutil.Uint64(e.Timestamp)
+	enc.ExtraData = e.ExtraData
+	enc.BaseFeePerGas = (*hexutil.Big)(e.BaseFeePerGas)
+	enc.BlockHash = e.BlockHash
+	if e.Transactions != nil {
+		enc.Transactions = make([]hexutil.Bytes, len(e.Transactions))
+		for k, v := range e.Transactions {
+			enc.Transactions[k] = v
+		}
+	}
+	enc.Withdrawals = e.Withdrawals
+	return json.Marshal(&enc)
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (e *ExecutionPayloadV2) UnmarshalJSON(input []byte) error {
+	type ExecutionPayloadV2 struct {
+		ParentHash    *common.Hash    `json:"parentHash"    gencodec:"required"`
+		FeeRecipient  *common.Address `json:"feeRecipient"  gencodec:"required"`
+		StateRoot     *common.Hash    `json:"stateRoot"     gencodec:"required"`
+		ReceiptsRoot  *common.Hash    `json:"receiptsRoot"  gencodec:"required"`
+		LogsBloom     *types.Bloom    `json:"logsBloom"     gencodec:"required"`
+		Random        *common.Hash    `json:"prevRandao"    gencodec:"required"`
+		Number        *hexutil.Uint64 `json:"blockNumber"   gencodec:"required"`
+		GasLimit      *hexutil.Uint64 `json:"gasLimit"      gencodec:"required"`
+		GasUsed       *hexutil.Uint64 `json:"gasUsed"       gencodec:"required"`
+		Timestamp     *hexutil.Uint64 `json:"timestamp"     gencodec:"required"`
+		ExtraData     *hexutil.Bytes  `json:"extraData"     gencodec:"required"`
+		BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas" gencodec:"required"`
+		BlockHash     *common.Hash    `json:"blockHash"     gencodec:"required"`
+		Transactions  []hexutil.Bytes `json:"transactions"  gencodec:"required"`
+		Withdrawals   Withdrawals     `json:"withdrawals"   gencodec:"required"`
+	}
+	var dec ExecutionPayloadV2
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.ParentHash == nil {
+		return errors.New("missing required field 'parentHash' for ExecutionPayloadV2")
+	}
+	e.ParentHash = *dec.ParentHash
+	if dec.FeeRecipient == nil {
+		return errors.New("missing required field 'feeRecipient' for ExecutionPayloadV2")
+	}
+	e.FeeRecipient = *dec.FeeRecipient
+	if dec.StateRoot == nil {
+		return errors.New("missing required field 'stateRoot' for ExecutionPayloadV2")
+	}
+	e.StateRoot = *dec.StateRoot
+	if dec.ReceiptsRoot == nil {
+		return errors.New("missing required field 'receiptsRoot' for ExecutionPayloadV2")
+	}
+	e.ReceiptsRoot = *dec.ReceiptsRoot
+	if dec.LogsBloom == nil {
+		return errors.New("missing required field 'logsBloom' for ExecutionPayloadV2")
+	}
+	e.LogsBloom = *dec.LogsBloom
+	if dec.Random == nil {
+		return errors.New("missing required field 'prevRandao' for ExecutionPayloadV2")
+	}
+	e.Random = *dec.Random
+	if dec.Number == nil {
+		return errors.New("missing required field 'blockNumber' for ExecutionPayloadV2")
+	}
+	e.Number = uint64(*dec.Number)
+	if dec.GasLimit == nil {
+		return errors.New("missing required field 'gasLimit' for ExecutionPayloadV2")
+	}
+	e.GasLimit = uint64(*dec.GasLimit)
+	if dec.GasUsed == nil {
+		return errors.New("missing required field 'gasUsed' for ExecutionPayloadV2")
+	}
+	e.GasUsed = uint64(*dec.GasUsed)
+	if dec.Timestamp == nil {
+		return errors.New("missing required field 'timestamp' for ExecutionPayloadV2")
+	}
+	e.Timestamp = uint64(*dec.Timestamp)
+	if dec.ExtraData == nil {
+		return errors.New("missing required field 'extraData' for ExecutionPayloadV2")
+	}
+	e.ExtraData = *dec.ExtraData
+	if dec.BaseFeePerGas == nil {
+		return errors.New("missing required field 'baseFeePerGas' for ExecutionPayloadV2")
+	}
+	e.BaseFeePerGas = (*big.Int)(dec.BaseFeePerGas)
+	if dec.BlockHash == nil {
+		return errors.New("missing required field 'blockHash' for ExecutionPayloadV2")
+	}
+	e.BlockHash = *dec.BlockHash
+	if dec.Transactions == nil {
+		return errors.New("missing required field 'transactions' for ExecutionPayloadV2")
+	}
+	e.Transactions = make([][]byte, len(dec.Transactions))
+	for k, v := range dec.Transactions {
+		e.Transactions[k] = v
+	}
+	if dec.Withdrawals == nil {
+		return errors.New("missing required field 'withdrawals' for ExecutionPayloadV2")
+	}
+	e.Withdrawals = dec.Withdrawals
+	return nil
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Conversions to and from the engine API types of go-ethereum. The go-ethereum version
+// mergemock is built on only has the Paris types of the core/beacon package: there is no
+// engine.ExecutionPayloadEnvelope, and no withdrawals or blob gas fields to convert.
+
+// ExecutableDataToPayload converts a go-ethereum payload to a V1 payload.
+func ExecutableDataToPayload(data *beacon.ExecutableDataV1) *ExecutionPayloadV1 {
+	return &ExecutionPayloadV1{
+		ParentHash:    data.ParentHash,
+		FeeRecipient:  data.FeeRecipient,
+		StateRoot:     data.StateRoot,
+		ReceiptsRoot:  data.ReceiptsRoot,
+		LogsBloom:     types.BytesToBloom(data.LogsBloom),
+		Random:        data.Random,
+		Number:        data.Number,
+		GasLimit:      data.GasLimit,
+		GasUsed:       data.GasUsed,
+		Timestamp:     data.Timestamp,
+		ExtraData:     data.ExtraData,
+		BaseFeePerGas: data.BaseFeePerGas,
+		BlockHash:     data.BlockHash,
+		Transactions:  data.Transactions,
+	}
+}
+
+// ExecutableData converts the payload to a go-ethereum payload.
+func (p *ExecutionPayloadV1) ExecutableData() *beacon.ExecutableDataV1 {
+	return &beacon.ExecutableDataV1{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom.Bytes(),
+		Random:        p.Random,
+		Number:        p.Number,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: p.BaseFeePerGas,
+		BlockHash:     p.BlockHash,
+		Transactions:  p.Transactions,
+	}
+}
+
+// Block converts the payload to a block with go-ethereum, which checks the extra data size
+// and the block hash.
+func (p *ExecutionPayloadV1) Block() (*types.Block, error) {
+	return beacon.ExecutableDataToBlock(*p.ExecutableData())
+}
+
+// PayloadAttributesFromGeth converts go-ethereum payload attributes.
+func PayloadAttributesFromGeth(attr *beacon.PayloadAttributesV1) *PayloadAttributesV1 {
+	return &PayloadAttributesV1{
+		Timestamp:             attr.Timestamp,
+		PrevRandao:            attr.Random,
+		SuggestedFeeRecipient: attr.SuggestedFeeRecipient,
+	}
+}
+
+// Geth converts the payload attributes to go-ethereum payload attributes.
+func (attr *PayloadAttributesV1) Geth() *beacon.PayloadAttributesV1 {
+	return &beacon.PayloadAttributesV1{
+		Timestamp:             attr.Timestamp,
+		Random:                attr.PrevRandao,
+		SuggestedFeeRecipient: attr.SuggestedFeeRecipient,
+	}
+}
+
+// ForkchoiceStateFromGeth converts a go-ethereum forkchoice state.
+func ForkchoiceStateFromGeth(state beacon.ForkchoiceStateV1) ForkchoiceStateV1 {
+	return ForkchoiceStateV1(state)
+}
+
+// Geth converts the forkchoice state to a go-ethereum forkchoice state.
+func (state ForkchoiceStateV1) Geth() beacon.ForkchoiceStateV1 {
+	return beacon.ForkchoiceStateV1(state)
+}
+
+// PayloadStatusFromGeth converts a go-ethereum payload status.
+func PayloadStatusFromGeth(status beacon.PayloadStatusV1) PayloadStatusV1 {
+	out := PayloadStatusV1{
+		Status:          ExecutePayloadStatus(status.Status),
+		LatestValidHash: status.LatestValidHash,
+	}
+	if status.ValidationError != nil {
+		out.ValidationError = *status.ValidationError
+	}
+	return out
+}
+
+// Geth converts the payload status to a go-ethereum payload status. An empty validation
+// error converts to nil.
+func (status PayloadStatusV1) Geth() beacon.PayloadStatusV1 {
+	out := beacon.PayloadStatusV1{
+		Status:          string(status.Status),
+		LatestValidHash: status.LatestValidHash,
+	}
+	if status.ValidationError != "" {
+		validationError := status.ValidationError
+		out.ValidationError = &validationError
+	}
+	return out
+}
+
+// ForkchoiceUpdatedResultFromGeth converts a go-ethereum forkchoice updated response.
+func ForkchoiceUpdatedResultFromGeth(resp beacon.ForkChoiceResponse) ForkchoiceUpdatedResult {
+	return ForkchoiceUpdatedResult{
+		PayloadStatus: PayloadStatusFromGeth(resp.PayloadStatus),
+		PayloadID:     resp.PayloadID,
+	}
+}
+
+// Geth converts the forkchoice updated response to a go-ethereum forkchoice updated response.
+func (res ForkchoiceUpdatedResult) Geth() beacon.ForkChoiceResponse {
+	return beacon.ForkChoiceResponse{
+		PayloadStatus: res.PayloadStatus.Geth(),
+		PayloadID:     res.PayloadID,
+	}
+}
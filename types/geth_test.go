@@ -0,0 +1,57 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGethConversions(t *testing.T) {
+	block := types.NewBlockWithHeader(&types.Header{
+		ParentHash:  common.Hash{0x01},
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    common.Address{0x02},
+		Root:        common.Hash{0x03},
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: common.Hash{0x04},
+		Bloom:       types.Bloom{0x05},
+		Difficulty:  common.Big0,
+		Number:      big.NewInt(6),
+		GasLimit:    30_000_000,
+		Time:        7,
+		Extra:       []byte{0x08},
+		MixDigest:   common.Hash{0x09},
+		BaseFee:     big.NewInt(10),
+	})
+	payload := ExecutableDataToPayload(beacon.BlockToExecutableData(block))
+	require.Equal(t, block.Hash(), payload.BlockHash)
+	require.Equal(t, types.Bloom{0x05}, payload.LogsBloom)
+	require.True(t, payload.ValidateHash())
+	require.Equal(t, payload, ExecutableDataToPayload(payload.ExecutableData()))
+
+	dec, err := payload.Block()
+	require.NoError(t, err)
+	require.Equal(t, block.Hash(), dec.Hash())
+	payload.GasUsed = 1
+	_, err = payload.Block()
+	require.Error(t, err)
+
+	attr := &PayloadAttributesV1{Timestamp: 1, PrevRandao: common.Hash{0x02}, SuggestedFeeRecipient: common.Address{0x03}}
+	require.Equal(t, attr, PayloadAttributesFromGeth(attr.Geth()))
+
+	state := ForkchoiceStateV1{HeadBlockHash: common.Hash{0x01}, SafeBlockHash: common.Hash{0x02}, FinalizedBlockHash: common.Hash{0x03}}
+	require.Equal(t, state, ForkchoiceStateFromGeth(state.Geth()))
+
+	id := PayloadID{0x01}
+	for _, res := range []ForkchoiceUpdatedResult{
+		{PayloadStatus: PayloadStatusV1{Status: ExecutionValid, LatestValidHash: &common.Hash{0x01}}, PayloadID: &id},
+		{PayloadStatus: PayloadStatusV1{Status: ExecutionInvalid, ValidationError: "bad block"}},
+	} {
+		require.Equal(t, res, ForkchoiceUpdatedResultFromGeth(res.Geth()))
+	}
+	require.Nil(t, PayloadStatusV1{Status: ExecutionSyncing}.Geth().ValidationError)
+}
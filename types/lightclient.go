@@ -0,0 +1,74 @@
+package types
+
+import ssz "github.com/ferranbt/fastssz"
+
+// SyncCommitteeSize is the number of members of a sync committee.
+const SyncCommitteeSize = 512
+
+// SyncCommittee https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/beacon-chain.md#synccommittee
+type SyncCommittee struct {
+	Pubkeys         []PublicKey `json:"pubkeys" ssz-size:"512,48"`
+	AggregatePubkey PublicKey   `json:"aggregate_pubkey" ssz-size:"48"`
+}
+
+// HashTreeRoot ssz hashes the SyncCommittee object. It is not generated with the other encodings,
+// as sszgen does not support vectors of named byte arrays.
+func (s *SyncCommittee) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SyncCommittee object with a hasher
+func (s *SyncCommittee) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Pubkeys'
+	{
+		if len(s.Pubkeys) != SyncCommitteeSize {
+			err = ssz.ErrVectorLength
+			return
+		}
+		subIndx := hh.Index()
+		for _, pk := range s.Pubkeys {
+			hh.PutBytes(pk[:])
+		}
+		hh.Merkleize(subIndx)
+	}
+
+	// Field (1) 'AggregatePubkey'
+	hh.PutBytes(s.AggregatePubkey[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
+// LightClientHeader https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/light-client/sync-protocol.md#lightclientheader
+type LightClientHeader struct {
+	Beacon *BeaconBlockHeader `json:"beacon"`
+}
+
+// LightClientUpdate https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/light-client/sync-protocol.md#lightclientupdate
+type LightClientUpdate struct {
+	AttestedHeader          *LightClientHeader `json:"attested_header"`
+	NextSyncCommittee       *SyncCommittee     `json:"next_sync_committee"`
+	NextSyncCommitteeBranch []Root             `json:"next_sync_committee_branch"`
+	FinalizedHeader         *LightClientHeader `json:"finalized_header"`
+	FinalityBranch          []Root             `json:"finality_branch"`
+	SyncAggregate           *SyncAggregate     `json:"sync_aggregate"`
+	SignatureSlot           uint64             `json:"signature_slot,string"`
+}
+
+// LightClientFinalityUpdate https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/light-client/sync-protocol.md#lightclientfinalityupdate
+type LightClientFinalityUpdate struct {
+	AttestedHeader  *LightClientHeader `json:"attested_header"`
+	FinalizedHeader *LightClientHeader `json:"finalized_header"`
+	FinalityBranch  []Root             `json:"finality_branch"`
+	SyncAggregate   *SyncAggregate     `json:"sync_aggregate"`
+	SignatureSlot   uint64             `json:"signature_slot,string"`
+}
+
+// LightClientOptimisticUpdate https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/light-client/sync-protocol.md#lightclientoptimisticupdate
+type LightClientOptimisticUpdate struct {
+	AttestedHeader *LightClientHeader `json:"attested_header"`
+	SyncAggregate  *SyncAggregate     `json:"sync_aggregate"`
+	SignatureSlot  uint64             `json:"signature_slot,string"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncCommitteeHashTreeRoot(t *testing.T) {
+	committee := &SyncCommittee{Pubkeys: make([]PublicKey, SyncCommitteeSize), AggregatePubkey: PublicKey{0xaa}}
+	for i := range committee.Pubkeys {
+		committee.Pubkeys[i] = PublicKey{byte(i), byte(i >> 8), 47: 0x01}
+	}
+	root, err := committee.HashTreeRoot()
+	require.NoError(t, err)
+
+	// the root of a 48 byte key is that of its two chunks, zero padded
+	pubkeyRoot := func(pk PublicKey) [32]byte {
+		var chunks [64]byte
+		copy(chunks[:], pk[:])
+		return sha256.Sum256(chunks[:])
+	}
+	layer := make([][32]byte, SyncCommitteeSize)
+	for i, pk := range committee.Pubkeys {
+		layer[i] = pubkeyRoot(pk)
+	}
+	for len(layer) > 1 {
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = sha256.Sum256(append(layer[2*i][:], layer[2*i+1][:]...))
+		}
+		layer = next
+	}
+	aggregate := pubkeyRoot(committee.AggregatePubkey)
+	require.Equal(t, sha256.Sum256(append(layer[0][:], aggregate[:]...)), root)
+
+	committee.Pubkeys = committee.Pubkeys[1:]
+	_, err = committee.HashTreeRoot()
+	require.Error(t, err)
+}
@@ -11,6 +11,10 @@ var (
 	DomainBuilder Domain
 
 	DomainTypeBeaconProposer DomainType = DomainType{0x00, 0x00, 0x00, 0x00}
+	DomainTypeBeaconAttester DomainType = DomainType{0x01, 0x00, 0x00, 0x00}
+	DomainTypeRandao         DomainType = DomainType{0x02, 0x00, 0x00, 0x00}
+	DomainTypeDeposit        DomainType = DomainType{0x03, 0x00, 0x00, 0x00}
+	DomainTypeSyncCommittee  DomainType = DomainType{0x07, 0x00, 0x00, 0x00}
 	DomainTypeAppBuilder     DomainType = DomainType{0x00, 0x00, 0x00, 0x01}
 )
 
@@ -23,11 +27,35 @@ type SigningData struct {
 	Domain Domain `ssz-size:"32"`
 }
 
+// SigningEpoch is an epoch to sign, as randao reveals do.
+type SigningEpoch struct {
+	Epoch uint64
+}
+
+// SigningBlockRoot is a block root to sign, as sync committee messages do.
+type SigningBlockRoot struct {
+	Root Root `ssz-size:"32"`
+}
+
 type forkData struct {
 	CurrentVersion        uint32
 	GenesisValidatorsRoot Root `ssz-size:"32"`
 }
 
+// ForkDigest identifies the fork and chain of p2p messages and records.
+type ForkDigest [4]byte
+
+// FarFutureEpoch is the epoch of forks that are not scheduled.
+const FarFutureEpoch = ^uint64(0)
+
+// ENRForkID is the eth2 entry of the node records of beacon nodes, with the fork digest and
+// the next scheduled fork.
+type ENRForkID struct {
+	CurrentForkDigest ForkDigest `ssz-size:"4"`
+	NextForkVersion   uint32
+	NextForkEpoch     uint64
+}
+
 type HashTreeRoot interface {
 	HashTreeRoot() ([32]byte, error)
 }
@@ -49,6 +77,19 @@ func ComputeDomain(dt DomainType, forkVersion uint32, genesisValidatorsRoot *Roo
 	return domain
 }
 
+// ComputeForkDigest returns the first four bytes of the fork data root of the fork version and
+// the genesis validators root.
+func ComputeForkDigest(forkVersion uint32, genesisValidatorsRoot Root) ForkDigest {
+	forkDataRoot, _ := (&forkData{
+		CurrentVersion:        forkVersion,
+		GenesisValidatorsRoot: genesisValidatorsRoot,
+	}).HashTreeRoot()
+
+	var digest ForkDigest
+	copy(digest[:], forkDataRoot[0:4])
+	return digest
+}
+
 func ComputeApplicationDomain(dt DomainType) [32]byte {
 	return ComputeDomain(dt, 0, nil)
 }
@@ -1,5 +1,5 @@
 // Code generated by fastssz. DO NOT EDIT.
-// Hash: d97cc9ae59b9cca39ab926b45f9dbece9f31bf50a9c3cd8bf3ddbc6fdb4a524e
+// Hash: 4a1bd58d6fbd824f27844f95159e601dd2a8f7aeb27575ec1ef95f5c74670f80
 package types
 
 import (
@@ -66,6 +66,108 @@ func (s *SigningData) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	return
 }
 
+// MarshalSSZ ssz marshals the SigningEpoch object
+func (s *SigningEpoch) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SigningEpoch object to a target array
+func (s *SigningEpoch) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Epoch'
+	dst = ssz.MarshalUint64(dst, s.Epoch)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SigningEpoch object
+func (s *SigningEpoch) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 8 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Epoch'
+	s.Epoch = ssz.UnmarshallUint64(buf[0:8])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SigningEpoch object
+func (s *SigningEpoch) SizeSSZ() (size int) {
+	size = 8
+	return
+}
+
+// HashTreeRoot ssz hashes the SigningEpoch object
+func (s *SigningEpoch) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SigningEpoch object with a hasher
+func (s *SigningEpoch) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Epoch'
+	hh.PutUint64(s.Epoch)
+
+	hh.Merkleize(indx)
+	return
+}
+
+// MarshalSSZ ssz marshals the SigningBlockRoot object
+func (s *SigningBlockRoot) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(s)
+}
+
+// MarshalSSZTo ssz marshals the SigningBlockRoot object to a target array
+func (s *SigningBlockRoot) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'Root'
+	dst = append(dst, s.Root[:]...)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the SigningBlockRoot object
+func (s *SigningBlockRoot) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 32 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'Root'
+	copy(s.Root[:], buf[0:32])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the SigningBlockRoot object
+func (s *SigningBlockRoot) SizeSSZ() (size int) {
+	size = 32
+	return
+}
+
+// HashTreeRoot ssz hashes the SigningBlockRoot object
+func (s *SigningBlockRoot) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(s)
+}
+
+// HashTreeRootWith ssz hashes the SigningBlockRoot object with a hasher
+func (s *SigningBlockRoot) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'Root'
+	hh.PutBytes(s.Root[:])
+
+	hh.Merkleize(indx)
+	return
+}
+
 // MarshalSSZ ssz marshals the forkData object
 func (f *forkData) MarshalSSZ() ([]byte, error) {
 	return ssz.MarshalSSZ(f)
@@ -125,3 +227,72 @@ func (f *forkData) HashTreeRootWith(hh *ssz.Hasher) (err error) {
 	hh.Merkleize(indx)
 	return
 }
+
+// MarshalSSZ ssz marshals the ENRForkID object
+func (e *ENRForkID) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(e)
+}
+
+// MarshalSSZTo ssz marshals the ENRForkID object to a target array
+func (e *ENRForkID) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	dst = buf
+
+	// Field (0) 'CurrentForkDigest'
+	dst = append(dst, e.CurrentForkDigest[:]...)
+
+	// Field (1) 'NextForkVersion'
+	dst = ssz.MarshalUint32(dst, e.NextForkVersion)
+
+	// Field (2) 'NextForkEpoch'
+	dst = ssz.MarshalUint64(dst, e.NextForkEpoch)
+
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the ENRForkID object
+func (e *ENRForkID) UnmarshalSSZ(buf []byte) error {
+	var err error
+	size := uint64(len(buf))
+	if size != 16 {
+		return ssz.ErrSize
+	}
+
+	// Field (0) 'CurrentForkDigest'
+	copy(e.CurrentForkDigest[:], buf[0:4])
+
+	// Field (1) 'NextForkVersion'
+	e.NextForkVersion = ssz.UnmarshallUint32(buf[4:8])
+
+	// Field (2) 'NextForkEpoch'
+	e.NextForkEpoch = ssz.UnmarshallUint64(buf[8:16])
+
+	return err
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the ENRForkID object
+func (e *ENRForkID) SizeSSZ() (size int) {
+	size = 16
+	return
+}
+
+// HashTreeRoot ssz hashes the ENRForkID object
+func (e *ENRForkID) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(e)
+}
+
+// HashTreeRootWith ssz hashes the ENRForkID object with a hasher
+func (e *ENRForkID) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	indx := hh.Index()
+
+	// Field (0) 'CurrentForkDigest'
+	hh.PutBytes(e.CurrentForkDigest[:])
+
+	// Field (1) 'NextForkVersion'
+	hh.PutUint32(e.NextForkVersion)
+
+	// Field (2) 'NextForkEpoch'
+	hh.PutUint64(e.NextForkEpoch)
+
+	hh.Merkleize(indx)
+	return
+}
@@ -31,3 +31,48 @@ func TestVerifySignature(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, ok)
 }
+
+func TestBidTraceSignature(t *testing.T) {
+	pk, sk := newKeypair(t)
+	trace := &BidTrace{
+		Slot:                 1,
+		ParentHash:           Hash{0x01},
+		BlockHash:            Hash{0x02},
+		ProposerFeeRecipient: Address{0x42},
+		GasLimit:             30_000_000,
+		GasUsed:              21_000,
+		Value:                IntToU256(1000),
+	}
+	copy(trace.BuilderPubkey[:], pk)
+	root, err := ComputeSigningRoot(trace, DomainBuilder)
+	require.NoError(t, err)
+	signed := &SignedBidTrace{Message: trace}
+	signed.Signature.FromSlice(sk.Sign(root[:]).Marshal())
+
+	enc, err := signed.MarshalSSZ()
+	require.NoError(t, err)
+	require.Len(t, enc, 8+32+32+48+48+20+8+8+32+96)
+	dec := new(SignedBidTrace)
+	require.NoError(t, dec.UnmarshalSSZ(enc))
+	require.Equal(t, signed, dec)
+
+	ok, err := VerifySignature(dec.Message, DomainBuilder, pk, dec.Signature[:])
+	require.NoError(t, err)
+	require.True(t, ok)
+	dec.Message.Value = IntToU256(2000)
+	ok, _ = VerifySignature(dec.Message, DomainBuilder, pk, dec.Signature[:])
+	require.False(t, ok)
+}
+
+func TestComputeForkDigest(t *testing.T) {
+	var gvr Root
+	require.NoError(t, gvr.UnmarshalText([]byte("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95")))
+	// the fork digests of mainnet, of the little endian fork versions
+	require.Equal(t, ForkDigest{0xb5, 0x30, 0x3f, 0x2a}, ComputeForkDigest(0, gvr))
+	require.Equal(t, ForkDigest{0xaf, 0xca, 0xab, 0xa0}, ComputeForkDigest(1, gvr))
+	require.Equal(t, ForkDigest{0x4a, 0x26, 0xc5, 0x8b}, ComputeForkDigest(2, gvr))
+
+	enc, err := (&ENRForkID{ComputeForkDigest(2, gvr), 2, FarFutureEpoch}).MarshalSSZ()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x4a, 0x26, 0xc5, 0x8b, 2, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, enc)
+}
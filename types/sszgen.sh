@@ -0,0 +1,15 @@
+#!/bin/sh
+# Generates the SSZ encodings of the listed types with fastssz, run by go generate in types.
+set -e
+
+OBJS=Eth1Data,BeaconBlockHeader,SignedBeaconBlockHeader,ProposerSlashing,Checkpoint,AttestationData,IndexedAttestation,AttesterSlashing,Attestation,Deposit,DepositData,DepositMessage,VoluntaryExit,SyncAggregate,ExecutionPayloadHeader,VersionedExecutionPayloadHeader,BlindedBeaconBlockBody,BlindedBeaconBlock,SignedBlindedBeaconBlock,RegisterValidatorRequestMessage,SignedValidatorRegistration,BuilderBid,SignedBuilderBid,WithdrawalREST,ExecutionPayloadHeaderCapella,BuilderBidCapella,SignedBuilderBidCapella,ExecutionPayloadHeaderDeneb,BidTrace,SignedBidTrace,BLSToExecutionChange,SignedBLSToExecutionChange,BlindedBeaconBlockBodyCapella,BlindedBeaconBlockCapella,SignedBlindedBeaconBlockCapella,SigningData,SigningEpoch,SigningBlockRoot,forkData,ENRForkID,transactions,withdrawals,executionPayload,executionPayloadCapella,executionPayloadDeneb
+
+rm -f builder_encoding.go signing_encoding.go
+go run github.com/ferranbt/fastssz/sszgen --path . --objs "$OBJS" \
+	--include "$(go list -m -f '{{.Dir}}' github.com/ethereum/go-ethereum)/common/hexutil"
+# sszgen generates the included package too, and imports it without using it
+rm -f common_encoding.go
+sed -i '/go-ethereum\/common\/hexutil"/d' builder_encoding.go
+# sszgen allocates vectors of named byte arrays with the underlying type
+sed -i 's/d.Proof = make(\[\]\[32\]byte, 33)/d.Proof = make([]Root, 33)/' builder_encoding.go
+gofmt -w builder_encoding.go signing_encoding.go
@@ -0,0 +1,193 @@
+package types
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	u256StrType         = reflect.TypeOf(U256Str{})
+)
+
+// DecodeStrict decodes the JSON input into v, after checking it with CheckStrict.
+func DecodeStrict(input []byte, v interface{}) error {
+	if err := CheckStrict(input, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	return json.Unmarshal(input, v)
+}
+
+// CheckStrict checks that the JSON input fits typ exactly, where the regular decoder would
+// silently zero-fill or skip: objects may not have unknown fields, and must have all fields
+// that are not omitempty, values may not be null, hex strings of byte arrays and vectors must
+// have the exact length and lists may not be longer than their SSZ max.
+func CheckStrict(input []byte, typ reflect.Type) error {
+	return checkStrict(input, typ, "", "$")
+}
+
+func checkStrict(input json.RawMessage, typ reflect.Type, tag reflect.StructTag, path string) error {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	input = bytes.TrimSpace(input)
+	if bytes.Equal(input, []byte("null")) {
+		return fmt.Errorf("%s: unexpected null", path)
+	}
+	if isJSONLeaf(typ) {
+		return checkStrictLeaf(input, typ, tag, path)
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(input, &fields); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		known := make(map[string]bool)
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name, optional, ok := jsonFieldName(f)
+			if !ok {
+				continue
+			}
+			known[name] = true
+			value, present := fields[name]
+			if !present {
+				if optional {
+					continue
+				}
+				return fmt.Errorf("%s: missing field %q", path, name)
+			}
+			if optional && bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+				continue
+			}
+			if err := checkStrict(value, f.Type, f.Tag, path+"."+name); err != nil {
+				return err
+			}
+		}
+		for name := range fields {
+			if !known[name] {
+				return fmt.Errorf("%s: unknown field %q", path, name)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return checkStrictLeaf(input, typ, tag, path)
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(input, &elems); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if max, ok := sszMax(tag); ok && len(elems) > max {
+			return fmt.Errorf("%s: %d elements exceed the max of %d", path, len(elems), max)
+		}
+		if typ.Kind() == reflect.Array && len(elems) != typ.Len() {
+			return fmt.Errorf("%s: expected %d elements, got %d", path, typ.Len(), len(elems))
+		}
+		if size, ok := sszSize(tag); ok && typ.Kind() == reflect.Slice && len(elems) != size {
+			return fmt.Errorf("%s: expected %d elements, got %d", path, size, len(elems))
+		}
+		for i, elem := range elems {
+			if err := checkStrict(elem, typ.Elem(), sszElemTag(tag), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkStrictLeaf checks the length of hex encoded byte arrays and lists, and leaves the
+// validation of other values to their decoders.
+func checkStrictLeaf(input json.RawMessage, typ reflect.Type, tag reflect.StructTag, path string) error {
+	if typ == u256StrType || (typ.Kind() != reflect.Array && typ.Kind() != reflect.Slice) || typ.Elem().Kind() != reflect.Uint8 {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(input, &s); err != nil {
+		return fmt.Errorf("%s: expected hex string", path)
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if typ.Kind() == reflect.Array && len(b) != typ.Len() {
+		return fmt.Errorf("%s: expected %d bytes, got %d", path, typ.Len(), len(b))
+	}
+	if max, ok := sszMax(tag); ok && typ.Kind() == reflect.Slice {
+		if tag.Get("ssz") == "bitlist" {
+			// the max is in bits, plus the length delimiting bit
+			max = max/8 + 1
+		}
+		if len(b) > max {
+			return fmt.Errorf("%s: %d bytes exceed the max of %d", path, len(b), max)
+		}
+	}
+	return nil
+}
+
+// isJSONLeaf returns true for types with their own JSON decoding that are not structs with
+// tagged fields, like hashes and big integers.
+func isJSONLeaf(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	if !ptr.Implements(jsonUnmarshalerType) && !ptr.Implements(textUnmarshalerType) {
+		return false
+	}
+	if typ.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if _, _, ok := jsonFieldName(typ.Field(i)); ok {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonFieldName(f reflect.StructField) (name string, optional bool, ok bool) {
+	if f.PkgPath != "" {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			optional = true
+		}
+	}
+	return name, optional, true
+}
+
+func sszMax(tag reflect.StructTag) (int, bool) {
+	max, err := strconv.Atoi(strings.Split(tag.Get("ssz-max"), ",")[0])
+	return max, err == nil
+}
+
+// sszSize returns the length of a vector that is a slice, e.g. a deposit proof.
+func sszSize(tag reflect.StructTag) (int, bool) {
+	size, err := strconv.Atoi(strings.Split(tag.Get("ssz-size"), ",")[0])
+	return size, err == nil
+}
+
+// sszElemTag returns the tag for the elements of a list of lists, e.g. the transactions.
+func sszElemTag(tag reflect.StructTag) reflect.StructTag {
+	maxes := strings.Split(tag.Get("ssz-max"), ",")
+	if len(maxes) < 2 {
+		return ""
+	}
+	return reflect.StructTag(`ssz-max:"` + strings.Join(maxes[1:], ",") + `"`)
+}
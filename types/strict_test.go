@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	reg := &SignedValidatorRegistration{
+		Message: &RegisterValidatorRequestMessage{
+			FeeRecipient: Address{0x01},
+			GasLimit:     30_000_000,
+			Timestamp:    1,
+			Pubkey:       PublicKey{0x02},
+		},
+		Signature: Signature{0x03},
+	}
+	valid, err := json.Marshal(reg)
+	require.NoError(t, err)
+	var decoded SignedValidatorRegistration
+	require.NoError(t, DecodeStrict(valid, &decoded))
+	require.Equal(t, reg, &decoded)
+
+	invalid := map[string]string{
+		"unknown field":     strings.Replace(string(valid), `"gas_limit"`, `"gasLimit"`, 1),
+		"missing field":     strings.Replace(string(valid), `"timestamp":"1",`, ``, 1),
+		"null message":      `{"message":null,"signature":"0x` + strings.Repeat("00", 96) + `"}`,
+		"short fee address": strings.Replace(string(valid), `0x0100000000000000000000000000000000000000`, `0x01`, 1),
+	}
+	for name, input := range invalid {
+		require.Error(t, DecodeStrict([]byte(input), &decoded), name)
+		// the regular decoder zero-fills or ignores these
+		if name != "short fee address" {
+			require.NoError(t, json.Unmarshal([]byte(input), &decoded), name)
+		}
+	}
+
+	payload := &ExecutionPayloadV1{ExtraData: []byte{}, BaseFeePerGas: big.NewInt(7), Transactions: [][]byte{{0x01}}}
+	enc, err := json.Marshal(payload)
+	require.NoError(t, err)
+	require.NoError(t, DecodeStrict(enc, new(ExecutionPayloadV1)))
+	require.Error(t, DecodeStrict([]byte(strings.Replace(string(enc), `"transactions":["0x01"]`, `"transactions":[null]`, 1)), new(ExecutionPayloadV1)))
+
+	header := &ExecutionPayloadHeader{}
+	enc, err = json.Marshal(header)
+	require.NoError(t, err)
+	require.NoError(t, DecodeStrict(enc, new(ExecutionPayloadHeader)))
+	long := strings.Replace(string(enc), `"extra_data":"0x"`, `"extra_data":"0x`+strings.Repeat("ab", 33)+`"`, 1)
+	require.Error(t, DecodeStrict([]byte(long), new(ExecutionPayloadHeader)))
+
+	deposit := &Deposit{Proof: make([]Root, 33), Data: &DepositData{}}
+	enc, err = json.Marshal(deposit)
+	require.NoError(t, err)
+	require.NoError(t, DecodeStrict(enc, new(Deposit)))
+	deposit.Proof = deposit.Proof[:32]
+	enc, err = json.Marshal(deposit)
+	require.NoError(t, err)
+	require.Error(t, DecodeStrict(enc, new(Deposit)))
+}
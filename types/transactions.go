@@ -0,0 +1,69 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	ErrTooManyTransactions = fmt.Errorf("%w: over %d transactions", ErrPayloadLimit, MaxTransactionsPerPayload)
+	ErrTransactionTooLarge = fmt.Errorf("%w: transaction over %d bytes", ErrPayloadLimit, MaxBytesPerTransaction)
+)
+
+// checkTransactionCount returns an error if a payload cannot have n transactions.
+func checkTransactionCount(n int) error {
+	if n > MaxTransactionsPerPayload {
+		return fmt.Errorf("%w: %d transactions", ErrTooManyTransactions, n)
+	}
+	return nil
+}
+
+// checkTransactionSize returns an error if transaction i of a payload cannot have size bytes.
+func checkTransactionSize(i, size int) error {
+	if size > MaxBytesPerTransaction {
+		return fmt.Errorf("%w: %d bytes in transaction %d", ErrTransactionTooLarge, size, i)
+	}
+	return nil
+}
+
+// check returns an error if the transactions exceed the limits of a payload, which the SSZ
+// encoding and hashing only report as a wrong list size.
+func (t *transactions) check() error {
+	if err := checkTransactionCount(len(t.Transactions)); err != nil {
+		return err
+	}
+	for i, tx := range t.Transactions {
+		if err := checkTransactionSize(i, len(tx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransactionsRoot returns the transactions root of a payload header with the encoded
+// transactions.
+func TransactionsRoot(txs [][]byte) (Root, error) {
+	t := &transactions{Transactions: txs}
+	if err := t.check(); err != nil {
+		return Root{}, err
+	}
+	return t.HashTreeRoot()
+}
+
+// GethTransactionsRoot returns the transactions root of a payload header with the transactions,
+// in their binary encoding.
+func GethTransactionsRoot(txs types.Transactions) (Root, error) {
+	if err := checkTransactionCount(len(txs)); err != nil {
+		return Root{}, err
+	}
+	enc := make([][]byte, len(txs))
+	for i, tx := range txs {
+		b, err := tx.MarshalBinary()
+		if err != nil {
+			return Root{}, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		enc[i] = b
+	}
+	return TransactionsRoot(enc)
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionLimits(t *testing.T) {
+	// the most transactions a payload can have still encode and decode
+	txs := make([][]byte, MaxTransactionsPerPayload)
+	for i := range txs {
+		txs[i] = []byte{}
+	}
+	max := &transactions{Transactions: txs}
+	require.NoError(t, max.check())
+	enc, err := max.MarshalSSZ()
+	require.NoError(t, err)
+	var dec transactions
+	require.NoError(t, dec.UnmarshalSSZ(enc))
+	require.Len(t, dec.Transactions, MaxTransactionsPerPayload)
+
+	_, err = TransactionsRoot(append(txs, []byte{}))
+	require.ErrorIs(t, err, ErrTooManyTransactions)
+	require.ErrorIs(t, err, ErrPayloadLimit)
+	require.Contains(t, err.Error(), "1048577 transactions")
+
+	// the buffers are not written, so they take no memory
+	large := &transactions{Transactions: [][]byte{{0x01}, make([]byte, MaxBytesPerTransaction)}}
+	require.NoError(t, large.check())
+	large.Transactions[1] = make([]byte, MaxBytesPerTransaction+1)
+	err = large.check()
+	require.ErrorIs(t, err, ErrTransactionTooLarge)
+	require.Contains(t, err.Error(), "1073741825 bytes in transaction 1")
+	_, err = TransactionsRoot(large.Transactions)
+	require.ErrorIs(t, err, ErrTransactionTooLarge)
+
+	root, err := TransactionsRoot(nil)
+	require.NoError(t, err)
+	require.Equal(t, "7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1", common.Bytes2Hex(root[:]))
+}
+
+func TestGethTransactionsRoot(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	var txs types.Transactions
+	var enc [][]byte
+	for i := uint64(0); i < 3; i++ {
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     i,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(10),
+			Gas:       21000,
+			To:        &common.Address{0x01},
+			Value:     big.NewInt(int64(i)),
+		})
+		require.NoError(t, err)
+		txs = append(txs, tx)
+		b, err := tx.MarshalBinary()
+		require.NoError(t, err)
+		enc = append(enc, b)
+	}
+	want, err := TransactionsRoot(enc)
+	require.NoError(t, err)
+	got, err := GethTransactionsRoot(txs)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	payload := &ExecutionPayloadV3{BaseFeePerGas: big.NewInt(7), Transactions: enc}
+	header, err := PayloadHeader(VersionBellatrix, payload)
+	require.NoError(t, err)
+	require.Equal(t, want, header.(*ExecutionPayloadHeader).TransactionsRoot)
+}
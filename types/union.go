@@ -0,0 +1,160 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ssz "github.com/ferranbt/fastssz"
+)
+
+// Union https://github.com/ethereum/consensus-specs/blob/dev/ssz/simple-serialize.md#union
+// is one value of the types of its options, chosen by the selector. A nil option is None,
+// which is only allowed as the first option. It is not generated, as sszgen does not support unions.
+type Union struct {
+	Selector uint8
+	Value    SSZObject
+
+	options []func() SSZObject
+}
+
+// NewUnion returns a union of the types the options make, with the zero value of the first option.
+func NewUnion(options ...func() SSZObject) *Union {
+	u := &Union{options: options}
+	if options[0] != nil {
+		u.Value = options[0]()
+	}
+	return u
+}
+
+// NewOptional returns an Optional of the type the option makes, a union of None and that type,
+// which is None.
+func NewOptional(option func() SSZObject) *Union {
+	return NewUnion(nil, option)
+}
+
+// Options returns the number of options of the union.
+func (u *Union) Options() int {
+	return len(u.options)
+}
+
+// Select sets the union to a zero value of the selected option.
+func (u *Union) Select(selector uint8) error {
+	if int(selector) >= len(u.options) {
+		return fmt.Errorf("union selector %d out of range", selector)
+	}
+	u.Selector = selector
+	u.Value = nil
+	if u.options[selector] != nil {
+		u.Value = u.options[selector]()
+	}
+	return nil
+}
+
+// MarshalSSZ ssz marshals the Union object
+func (u *Union) MarshalSSZ() ([]byte, error) {
+	return ssz.MarshalSSZ(u)
+}
+
+// MarshalSSZTo ssz marshals the Union object to a target array
+func (u *Union) MarshalSSZTo(buf []byte) (dst []byte, err error) {
+	if err = u.check(); err != nil {
+		return
+	}
+	dst = append(buf, u.Selector)
+	if u.Value != nil {
+		dst, err = u.Value.MarshalSSZTo(dst)
+	}
+	return
+}
+
+// UnmarshalSSZ ssz unmarshals the Union object
+func (u *Union) UnmarshalSSZ(buf []byte) error {
+	if len(buf) < 1 {
+		return ssz.ErrSize
+	}
+	if err := u.Select(buf[0]); err != nil {
+		return err
+	}
+	if u.Value == nil {
+		if len(buf) != 1 {
+			return ssz.ErrSize
+		}
+		return nil
+	}
+	return u.Value.UnmarshalSSZ(buf[1:])
+}
+
+// SizeSSZ returns the ssz encoded size in bytes for the Union object
+func (u *Union) SizeSSZ() int {
+	size := 1
+	if u.Value != nil {
+		size += u.Value.SizeSSZ()
+	}
+	return size
+}
+
+// HashTreeRoot ssz hashes the Union object
+func (u *Union) HashTreeRoot() ([32]byte, error) {
+	return ssz.HashWithDefaultHasher(u)
+}
+
+// HashTreeRootWith ssz hashes the Union object with a hasher, mixing the selector into the
+// root of the value, or into a zero root for None.
+func (u *Union) HashTreeRootWith(hh *ssz.Hasher) (err error) {
+	if err = u.check(); err != nil {
+		return
+	}
+	indx := hh.Index()
+	if u.Value == nil {
+		hh.PutBytes(make([]byte, 32))
+	} else if err = u.Value.HashTreeRootWith(hh); err != nil {
+		return
+	}
+	hh.PutUint8(u.Selector)
+	hh.Merkleize(indx)
+	return
+}
+
+// check returns an error if the value does not match the selected option.
+func (u *Union) check() error {
+	if int(u.Selector) >= len(u.options) {
+		return fmt.Errorf("union selector %d out of range", u.Selector)
+	}
+	if (u.options[u.Selector] == nil) != (u.Value == nil) {
+		return fmt.Errorf("union value does not match the option of selector %d", u.Selector)
+	}
+	return nil
+}
+
+type unionJSON struct {
+	Selector uint8           `json:"selector,string"`
+	Value    json.RawMessage `json:"value"`
+}
+
+func (u *Union) MarshalJSON() ([]byte, error) {
+	if err := u.check(); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(u.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&unionJSON{Selector: u.Selector, Value: value})
+}
+
+func (u *Union) UnmarshalJSON(input []byte) error {
+	var dec unionJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if err := u.Select(dec.Selector); err != nil {
+		return err
+	}
+	if u.Value == nil {
+		if len(dec.Value) != 0 && string(dec.Value) != "null" {
+			return fmt.Errorf("union value for None")
+		}
+		return nil
+	}
+	return json.Unmarshal(dec.Value, u.Value)
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptional(t *testing.T) {
+	opt := NewOptional(func() SSZObject { return new(Checkpoint) })
+
+	// None is the selector only, and mixes the selector into a zero root
+	enc, err := opt.MarshalSSZ()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0}, enc)
+	root, err := opt.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, sha256.Sum256(make([]byte, 64)), root)
+	value, err := json.Marshal(opt)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"selector":"0","value":null}`, string(value))
+
+	cp := &Checkpoint{Epoch: 3, Root: Root{0x42}}
+	require.NoError(t, opt.Select(1))
+	opt.Value = cp
+	enc, err = opt.MarshalSSZ()
+	require.NoError(t, err)
+	cpEnc, err := cp.MarshalSSZ()
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{1}, cpEnc...), enc)
+	cpRoot, err := cp.HashTreeRoot()
+	require.NoError(t, err)
+	var selector [32]byte
+	selector[0] = 1
+	root, err = opt.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, sha256.Sum256(append(cpRoot[:], selector[:]...)), root)
+
+	dec := NewOptional(func() SSZObject { return new(Checkpoint) })
+	require.NoError(t, dec.UnmarshalSSZ(enc))
+	require.Equal(t, cp, dec.Value)
+	value, err = json.Marshal(opt)
+	require.NoError(t, err)
+	dec = NewOptional(func() SSZObject { return new(Checkpoint) })
+	require.NoError(t, json.Unmarshal(value, dec))
+	require.Equal(t, cp, dec.Value)
+}
+
+func TestUnionInvalid(t *testing.T) {
+	opt := NewOptional(func() SSZObject { return new(Checkpoint) })
+	require.Error(t, opt.UnmarshalSSZ(nil))
+	require.Error(t, opt.UnmarshalSSZ([]byte{2}))
+	require.Error(t, opt.UnmarshalSSZ([]byte{0, 0}))
+	require.Error(t, json.Unmarshal([]byte(`{"selector":"0","value":{}}`), opt))
+
+	// the value must match the selected option
+	opt.Value = new(Checkpoint)
+	_, err := opt.MarshalSSZ()
+	require.Error(t, err)
+	_, err = opt.HashTreeRoot()
+	require.Error(t, err)
+}
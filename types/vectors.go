@@ -0,0 +1,222 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	ssz "github.com/ferranbt/fastssz"
+)
+
+//go:generate sh sszgen.sh
+
+// SSZObject is implemented by all types with generated SSZ encoding.
+type SSZObject interface {
+	ssz.Marshaler
+	ssz.Unmarshaler
+	ssz.HashRoot
+}
+
+// VectorTypes lists the types test vectors are generated for, by name.
+var VectorTypes = map[string]func() SSZObject{
+	"Eth1Data":                        func() SSZObject { return new(Eth1Data) },
+	"BeaconBlockHeader":               func() SSZObject { return new(BeaconBlockHeader) },
+	"SignedBeaconBlockHeader":         func() SSZObject { return new(SignedBeaconBlockHeader) },
+	"ProposerSlashing":                func() SSZObject { return new(ProposerSlashing) },
+	"Checkpoint":                      func() SSZObject { return new(Checkpoint) },
+	"AttestationData":                 func() SSZObject { return new(AttestationData) },
+	"IndexedAttestation":              func() SSZObject { return new(IndexedAttestation) },
+	"AttesterSlashing":                func() SSZObject { return new(AttesterSlashing) },
+	"Attestation":                     func() SSZObject { return new(Attestation) },
+	"Deposit":                         func() SSZObject { return new(Deposit) },
+	"DepositData":                     func() SSZObject { return new(DepositData) },
+	"DepositMessage":                  func() SSZObject { return new(DepositMessage) },
+	"VoluntaryExit":                   func() SSZObject { return new(VoluntaryExit) },
+	"SyncAggregate":                   func() SSZObject { return new(SyncAggregate) },
+	"ExecutionPayloadHeader":          func() SSZObject { return new(ExecutionPayloadHeader) },
+	"BlindedBeaconBlockBody":          func() SSZObject { return new(BlindedBeaconBlockBody) },
+	"BlindedBeaconBlock":              func() SSZObject { return new(BlindedBeaconBlock) },
+	"RegisterValidatorRequestMessage": func() SSZObject { return new(RegisterValidatorRequestMessage) },
+	"BuilderBid":                      func() SSZObject { return new(BuilderBid) },
+	"SignedBuilderBid":                func() SSZObject { return new(SignedBuilderBid) },
+	"Withdrawal":                      func() SSZObject { return new(WithdrawalREST) },
+	"ExecutionPayloadHeaderCapella":   func() SSZObject { return new(ExecutionPayloadHeaderCapella) },
+	"BuilderBidCapella":               func() SSZObject { return new(BuilderBidCapella) },
+	"SignedBuilderBidCapella":         func() SSZObject { return new(SignedBuilderBidCapella) },
+	"SigningData":                     func() SSZObject { return new(SigningData) },
+	"OptionalCheckpoint": func() SSZObject {
+		return NewOptional(func() SSZObject { return new(Checkpoint) })
+	},
+	"UnionEth1DataCheckpoint": func() SSZObject {
+		return NewUnion(func() SSZObject { return new(Eth1Data) }, func() SSZObject { return new(Checkpoint) })
+	},
+}
+
+// maxVectorListLen caps the length of lists in random vectors, to keep the fixtures small.
+const maxVectorListLen = 4
+
+// VectorRoots is the content of the roots.json file of a test vector.
+type VectorRoots struct {
+	Root Root `json:"root"`
+}
+
+// WriteTestVectors writes SSZ static test vectors for all VectorTypes into dir, in the layout of
+// the consensus-spec-tests: <dir>/<type>/<suite>/case_<i>/{serialized.ssz,value.json,roots.json}.
+// The ssz_zero suite has a single case with the zero value, the ssz_random suite has the given
+// number of cases, filled with randomness from seed. Unlike the spec tests, the SSZ is not
+// snappy-compressed and the value is JSON-encoded, as in the APIs.
+func WriteTestVectors(dir string, seed int64, cases int) error {
+	rng := rand.New(rand.NewSource(seed))
+	names := make([]string, 0, len(VectorTypes))
+	for name := range VectorTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		zero := VectorTypes[name]()
+		fillVector(reflect.ValueOf(zero).Elem(), "", nil)
+		if err := writeVector(filepath.Join(dir, name, "ssz_zero", "case_0"), zero); err != nil {
+			return fmt.Errorf("%s zero vector: %w", name, err)
+		}
+		for i := 0; i < cases; i++ {
+			obj := VectorTypes[name]()
+			fillVector(reflect.ValueOf(obj).Elem(), "", rng)
+			if err := writeVector(filepath.Join(dir, name, "ssz_random", "case_"+strconv.Itoa(i)), obj); err != nil {
+				return fmt.Errorf("%s random vector %d: %w", name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeVector(dir string, obj SSZObject) error {
+	serialized, err := obj.MarshalSSZ()
+	if err != nil {
+		return err
+	}
+	root, err := obj.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	value, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	roots, err := json.MarshalIndent(&VectorRoots{Root: root}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		"serialized.ssz": serialized,
+		"value.json":     value,
+		"roots.json":     roots,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillVector sets v to a value valid under the SSZ tags of its field. Pointers are always
+// allocated, and with a nil rng everything else is left zero.
+func fillVector(v reflect.Value, tag reflect.StructTag, rng *rand.Rand) {
+	if v.Kind() == reflect.Struct && v.CanAddr() {
+		if u, ok := v.Addr().Interface().(*Union); ok {
+			fillUnion(u, rng)
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillVector(v.Elem(), tag, rng)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fillVector(v.Field(i), v.Type().Field(i).Tag, rng)
+		}
+	case reflect.Array:
+		if rng == nil {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			fillVector(v.Index(i), "", rng)
+		}
+	case reflect.Slice:
+		if size, ok := sszSize(tag); ok {
+			v.Set(reflect.MakeSlice(v.Type(), size, size))
+			for i := 0; i < size; i++ {
+				fillVector(v.Index(i), "", rng)
+			}
+			return
+		}
+		max := vectorListLimit(v.Type(), tag)
+		if tag.Get("ssz") == "bitlist" {
+			v.SetBytes(randomBitlist(rng, max))
+			return
+		}
+		n := 0
+		if rng != nil {
+			n = rng.Intn(max + 1)
+		}
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+		for i := 0; i < n; i++ {
+			fillVector(v.Index(i), "", rng)
+		}
+	case reflect.Uint8, reflect.Uint32, reflect.Uint64:
+		if rng != nil {
+			v.SetUint(rng.Uint64())
+		}
+	}
+}
+
+// fillUnion selects a random option of the union, or keeps the first option with a nil rng,
+// and fills its value.
+func fillUnion(u *Union, rng *rand.Rand) {
+	if rng != nil {
+		_ = u.Select(uint8(rng.Intn(u.Options())))
+	}
+	if u.Value != nil {
+		fillVector(reflect.ValueOf(u.Value).Elem(), "", rng)
+	}
+}
+
+// vectorListLimit returns the max length of a list, capped at maxVectorListLen elements,
+// or at 8 times that for byte lists and bitlists.
+func vectorListLimit(typ reflect.Type, tag reflect.StructTag) int {
+	limit := maxVectorListLen
+	if typ.Elem().Kind() == reflect.Uint8 {
+		limit *= 8
+	}
+	max, err := strconv.Atoi(strings.Split(tag.Get("ssz-max"), ",")[0])
+	if err != nil || max > limit {
+		return limit
+	}
+	return max
+}
+
+// randomBitlist returns a bitlist of up to max bits, with the length delimiting bit set.
+func randomBitlist(rng *rand.Rand, max int) []byte {
+	n := 0
+	if rng != nil {
+		n = rng.Intn(max + 1)
+	}
+	out := make([]byte, n/8+1)
+	if rng != nil {
+		rng.Read(out)
+	}
+	// clear the bits past the length, and set the delimiting bit
+	out[n/8] &= byte(1<<(n%8)) - 1
+	out[n/8] |= 1 << (n % 8)
+	return out
+}
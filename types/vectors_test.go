@@ -0,0 +1,56 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTestVectors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, WriteTestVectors(dir, 42, 3))
+
+	cases, err := filepath.Glob(filepath.Join(dir, "*", "*", "case_*"))
+	require.NoError(t, err)
+	require.Len(t, cases, 4*len(VectorTypes))
+
+	for _, c := range cases {
+		name := filepath.Base(filepath.Dir(filepath.Dir(c)))
+		serialized, err := os.ReadFile(filepath.Join(c, "serialized.ssz"))
+		require.NoError(t, err)
+		value, err := os.ReadFile(filepath.Join(c, "value.json"))
+		require.NoError(t, err)
+		rootsJSON, err := os.ReadFile(filepath.Join(c, "roots.json"))
+		require.NoError(t, err)
+		var roots VectorRoots
+		require.NoError(t, json.Unmarshal(rootsJSON, &roots))
+
+		fromSSZ := VectorTypes[name]()
+		require.NoError(t, fromSSZ.UnmarshalSSZ(serialized), c)
+		root, err := fromSSZ.HashTreeRoot()
+		require.NoError(t, err)
+		require.Equal(t, roots.Root, Root(root), c)
+
+		fromJSON := VectorTypes[name]()
+		require.NoError(t, json.Unmarshal(value, fromJSON), c)
+		enc, err := fromJSON.MarshalSSZ()
+		require.NoError(t, err)
+		require.Equal(t, serialized, enc, c)
+	}
+
+	// the same seed gives the same vectors
+	again := t.TempDir()
+	require.NoError(t, WriteTestVectors(again, 42, 3))
+	for _, c := range cases {
+		rel, err := filepath.Rel(dir, c)
+		require.NoError(t, err)
+		a, err := os.ReadFile(filepath.Join(c, "serialized.ssz"))
+		require.NoError(t, err)
+		b, err := os.ReadFile(filepath.Join(again, rel, "serialized.ssz"))
+		require.NoError(t, err)
+		require.Equal(t, a, b, rel)
+	}
+}
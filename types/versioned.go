@@ -0,0 +1,619 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Fork versions, as in the version field of the builder API responses.
+const (
+	VersionBellatrix = "bellatrix"
+	VersionCapella   = "capella"
+	VersionDeneb     = "deneb"
+)
+
+var (
+	ErrUnknownVersion = errors.New("unknown version")
+	ErrEmptyVersioned = errors.New("no data for version")
+)
+
+type versionedJSON struct {
+	Version string          `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// unmarshalVersioned decodes the data of a {"version": ..., "data": ...} object into the
+// variant of the version.
+func unmarshalVersioned(input []byte, variants map[string]interface{}) (string, error) {
+	var dec versionedJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return "", err
+	}
+	variant, ok := variants[dec.Version]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownVersion, dec.Version)
+	}
+	if err := json.Unmarshal(dec.Data, variant); err != nil {
+		return "", err
+	}
+	return dec.Version, nil
+}
+
+// VersionedExecutionPayload is the execution payload of one of the forks. It encodes to JSON
+// like the getPayload response of the builder API.
+type VersionedExecutionPayload struct {
+	Version   string
+	Bellatrix *ExecutionPayloadREST
+	Capella   *ExecutionPayloadCapella
+	Deneb     *ExecutionPayloadDeneb
+}
+
+// NewVersionedExecutionPayload converts an engine API payload to the given version. The
+// fields of later forks are left out of payloads of earlier versions.
+func NewVersionedExecutionPayload(version string, p *ExecutionPayloadV3) (*VersionedExecutionPayload, error) {
+	switch version {
+	case VersionBellatrix:
+		p = p.V1().V3()
+	case VersionCapella:
+		p = p.V2().V3()
+	}
+	payload, err := RESTPayload(version, p)
+	if err != nil {
+		return nil, err
+	}
+	v := &VersionedExecutionPayload{Version: version}
+	switch payload := payload.(type) {
+	case *ExecutionPayloadREST:
+		v.Bellatrix = payload
+	case *ExecutionPayloadCapella:
+		v.Capella = payload
+	case *ExecutionPayloadDeneb:
+		v.Deneb = payload
+	}
+	return v, nil
+}
+
+func (v *VersionedExecutionPayload) data() (interface{}, error) {
+	var data interface{}
+	switch v.Version {
+	case VersionBellatrix:
+		if v.Bellatrix != nil {
+			data = v.Bellatrix
+		}
+	case VersionCapella:
+		if v.Capella != nil {
+			data = v.Capella
+		}
+	case VersionDeneb:
+		if v.Deneb != nil {
+			data = v.Deneb
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVersion, v.Version)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w %s", ErrEmptyVersioned, v.Version)
+	}
+	return data, nil
+}
+
+// ExecutionPayload converts the payload to the engine API payload.
+func (v *VersionedExecutionPayload) ExecutionPayload() (*ExecutionPayloadV3, error) {
+	data, err := v.data()
+	if err != nil {
+		return nil, err
+	}
+	return ELPayload(v.Version, data)
+}
+
+// BlockHash returns the block hash of the payload, or the zero hash if there is none.
+func (v *VersionedExecutionPayload) BlockHash() Hash {
+	data, err := v.data()
+	if err != nil {
+		return Hash{}
+	}
+	return payloadConverters[v.Version].deneb(data).BlockHash
+}
+
+func txsToSSZ(txs []hexutil.Bytes) [][]byte {
+	out := make([][]byte, len(txs))
+	for i, tx := range txs {
+		out[i] = tx
+	}
+	return out
+}
+
+func txsFromSSZ(txs [][]byte) []hexutil.Bytes {
+	out := make([]hexutil.Bytes, len(txs))
+	for i, tx := range txs {
+		out[i] = tx
+	}
+	return out
+}
+
+// MarshalSSZ encodes the payload as the execution payload of its fork.
+func (v *VersionedExecutionPayload) MarshalSSZ() ([]byte, error) {
+	data, err := v.data()
+	if err != nil {
+		return nil, err
+	}
+	p := payloadConverters[v.Version].deneb(data)
+	switch v.Version {
+	case VersionBellatrix:
+		return (&executionPayload{
+			ParentHash:    p.ParentHash,
+			FeeRecipient:  p.FeeRecipient,
+			StateRoot:     p.StateRoot,
+			ReceiptsRoot:  p.ReceiptsRoot,
+			LogsBloom:     p.LogsBloom,
+			Random:        p.Random,
+			BlockNumber:   p.BlockNumber,
+			GasLimit:      p.GasLimit,
+			GasUsed:       p.GasUsed,
+			Timestamp:     p.Timestamp,
+			ExtraData:     p.ExtraData,
+			BaseFeePerGas: p.BaseFeePerGas,
+			BlockHash:     p.BlockHash,
+			Transactions:  txsToSSZ(p.Transactions),
+		}).MarshalSSZ()
+	case VersionCapella:
+		return (&executionPayloadCapella{
+			ParentHash:    p.ParentHash,
+			FeeRecipient:  p.FeeRecipient,
+			StateRoot:     p.StateRoot,
+			ReceiptsRoot:  p.ReceiptsRoot,
+			LogsBloom:     p.LogsBloom,
+			Random:        p.Random,
+			BlockNumber:   p.BlockNumber,
+			GasLimit:      p.GasLimit,
+			GasUsed:       p.GasUsed,
+			Timestamp:     p.Timestamp,
+			ExtraData:     p.ExtraData,
+			BaseFeePerGas: p.BaseFeePerGas,
+			BlockHash:     p.BlockHash,
+			Transactions:  txsToSSZ(p.Transactions),
+			Withdrawals:   p.Withdrawals,
+		}).MarshalSSZ()
+	default:
+		return (&executionPayloadDeneb{
+			ParentHash:    p.ParentHash,
+			FeeRecipient:  p.FeeRecipient,
+			StateRoot:     p.StateRoot,
+			ReceiptsRoot:  p.ReceiptsRoot,
+			LogsBloom:     p.LogsBloom,
+			Random:        p.Random,
+			BlockNumber:   p.BlockNumber,
+			GasLimit:      p.GasLimit,
+			GasUsed:       p.GasUsed,
+			Timestamp:     p.Timestamp,
+			ExtraData:     p.ExtraData,
+			BaseFeePerGas: p.BaseFeePerGas,
+			BlockHash:     p.BlockHash,
+			Transactions:  txsToSSZ(p.Transactions),
+			Withdrawals:   p.Withdrawals,
+			BlobGasUsed:   p.BlobGasUsed,
+			ExcessBlobGas: p.ExcessBlobGas,
+		}).MarshalSSZ()
+	}
+}
+
+// UnmarshalSSZ decodes the execution payload of the fork in the Version field, which must be
+// set.
+func (v *VersionedExecutionPayload) UnmarshalSSZ(buf []byte) error {
+	var p executionPayloadDeneb
+	switch v.Version {
+	case VersionBellatrix:
+		var b executionPayload
+		if err := b.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+		p = executionPayloadDeneb{
+			ParentHash:    b.ParentHash,
+			FeeRecipient:  b.FeeRecipient,
+			StateRoot:     b.StateRoot,
+			ReceiptsRoot:  b.ReceiptsRoot,
+			LogsBloom:     b.LogsBloom,
+			Random:        b.Random,
+			BlockNumber:   b.BlockNumber,
+			GasLimit:      b.GasLimit,
+			GasUsed:       b.GasUsed,
+			Timestamp:     b.Timestamp,
+			ExtraData:     b.ExtraData,
+			BaseFeePerGas: b.BaseFeePerGas,
+			BlockHash:     b.BlockHash,
+			Transactions:  b.Transactions,
+		}
+	case VersionCapella:
+		var c executionPayloadCapella
+		if err := c.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+		p = executionPayloadDeneb{
+			ParentHash:    c.ParentHash,
+			FeeRecipient:  c.FeeRecipient,
+			StateRoot:     c.StateRoot,
+			ReceiptsRoot:  c.ReceiptsRoot,
+			LogsBloom:     c.LogsBloom,
+			Random:        c.Random,
+			BlockNumber:   c.BlockNumber,
+			GasLimit:      c.GasLimit,
+			GasUsed:       c.GasUsed,
+			Timestamp:     c.Timestamp,
+			ExtraData:     c.ExtraData,
+			BaseFeePerGas: c.BaseFeePerGas,
+			BlockHash:     c.BlockHash,
+			Transactions:  c.Transactions,
+			Withdrawals:   c.Withdrawals,
+		}
+	case VersionDeneb:
+		if err := p.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownVersion, v.Version)
+	}
+	payload := payloadConverters[v.Version].payload(&ExecutionPayloadDeneb{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.Random,
+		BlockNumber:   p.BlockNumber,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: p.BaseFeePerGas,
+		BlockHash:     p.BlockHash,
+		Transactions:  txsFromSSZ(p.Transactions),
+		Withdrawals:   p.Withdrawals,
+		BlobGasUsed:   p.BlobGasUsed,
+		ExcessBlobGas: p.ExcessBlobGas,
+	})
+	switch payload := payload.(type) {
+	case *ExecutionPayloadREST:
+		v.Bellatrix = payload
+	case *ExecutionPayloadCapella:
+		v.Capella = payload
+	case *ExecutionPayloadDeneb:
+		v.Deneb = payload
+	}
+	return nil
+}
+
+func (v VersionedExecutionPayload) MarshalJSON() ([]byte, error) {
+	data, err := v.data()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&versionedJSON{Version: v.Version, Data: enc})
+}
+
+func (v *VersionedExecutionPayload) UnmarshalJSON(input []byte) error {
+	var dec VersionedExecutionPayload
+	version, err := unmarshalVersioned(input, map[string]interface{}{
+		VersionBellatrix: &dec.Bellatrix,
+		VersionCapella:   &dec.Capella,
+		VersionDeneb:     &dec.Deneb,
+	})
+	if err != nil {
+		return err
+	}
+	dec.Version = version
+	if _, err := dec.data(); err != nil {
+		return err
+	}
+	*v = dec
+	return nil
+}
+
+// VersionedSignedBuilderBid is the signed builder bid of one of the forks. It encodes to JSON
+// like the getHeader response of the builder API, and to SSZ as the signed bid of its version.
+type VersionedSignedBuilderBid struct {
+	Version   string
+	Bellatrix *SignedBuilderBid
+	Capella   *SignedBuilderBidCapella
+}
+
+// NewVersionedBuilderBid returns an unsigned bid of the given version on an engine API payload.
+func NewVersionedBuilderBid(version string, p *ExecutionPayloadV3, value U256Str, pubkey PublicKey) (*VersionedSignedBuilderBid, error) {
+	switch version {
+	case VersionBellatrix:
+		header, err := PayloadToPayloadHeader(p.V1())
+		if err != nil {
+			return nil, err
+		}
+		return &VersionedSignedBuilderBid{
+			Version:   version,
+			Bellatrix: &SignedBuilderBid{Message: &BuilderBid{Header: header, Value: value, Pubkey: pubkey}},
+		}, nil
+	case VersionCapella:
+		header, err := PayloadToPayloadHeaderCapella(p.V2())
+		if err != nil {
+			return nil, err
+		}
+		return &VersionedSignedBuilderBid{
+			Version: version,
+			Capella: &SignedBuilderBidCapella{Message: &BuilderBidCapella{Header: header, Value: value, Pubkey: pubkey}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVersion, version)
+	}
+}
+
+func (b *VersionedSignedBuilderBid) signed() (SSZObject, error) {
+	var signed SSZObject
+	switch b.Version {
+	case VersionBellatrix:
+		if b.Bellatrix != nil && b.Bellatrix.Message != nil {
+			signed = b.Bellatrix
+		}
+	case VersionCapella:
+		if b.Capella != nil && b.Capella.Message != nil {
+			signed = b.Capella
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVersion, b.Version)
+	}
+	if signed == nil {
+		return nil, fmt.Errorf("%w %s", ErrEmptyVersioned, b.Version)
+	}
+	return signed, nil
+}
+
+// Message returns the bid, to compute the signing root of.
+func (b *VersionedSignedBuilderBid) Message() (HashTreeRoot, error) {
+	if _, err := b.signed(); err != nil {
+		return nil, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message, nil
+	}
+	return b.Capella.Message, nil
+}
+
+// Signature returns the signature of the bid.
+func (b *VersionedSignedBuilderBid) Signature() (Signature, error) {
+	if _, err := b.signed(); err != nil {
+		return Signature{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Signature, nil
+	}
+	return b.Capella.Signature, nil
+}
+
+// SetSignature sets the signature of the bid.
+func (b *VersionedSignedBuilderBid) SetSignature(sig Signature) error {
+	if _, err := b.signed(); err != nil {
+		return err
+	}
+	if b.Version == VersionBellatrix {
+		b.Bellatrix.Signature = sig
+	} else {
+		b.Capella.Signature = sig
+	}
+	return nil
+}
+
+// Value returns the value of the bid.
+func (b *VersionedSignedBuilderBid) Value() (U256Str, error) {
+	if _, err := b.signed(); err != nil {
+		return U256Str{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Value, nil
+	}
+	return b.Capella.Message.Value, nil
+}
+
+// Pubkey returns the public key of the builder that made the bid.
+func (b *VersionedSignedBuilderBid) Pubkey() (PublicKey, error) {
+	if _, err := b.signed(); err != nil {
+		return PublicKey{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Pubkey, nil
+	}
+	return b.Capella.Message.Pubkey, nil
+}
+
+// ParentHash returns the parent hash of the execution payload header in the bid.
+func (b *VersionedSignedBuilderBid) ParentHash() (Hash, error) {
+	if _, err := b.signed(); err != nil {
+		return Hash{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Header.ParentHash, nil
+	}
+	return b.Capella.Message.Header.ParentHash, nil
+}
+
+// BlockHash returns the block hash of the execution payload header in the bid.
+func (b *VersionedSignedBuilderBid) BlockHash() (Hash, error) {
+	if _, err := b.signed(); err != nil {
+		return Hash{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Header.BlockHash, nil
+	}
+	return b.Capella.Message.Header.BlockHash, nil
+}
+
+func (b *VersionedSignedBuilderBid) MarshalSSZ() ([]byte, error) {
+	signed, err := b.signed()
+	if err != nil {
+		return nil, err
+	}
+	return signed.MarshalSSZ()
+}
+
+// UnmarshalSSZ decodes the signed bid of the fork in the Version field, which must be set.
+func (b *VersionedSignedBuilderBid) UnmarshalSSZ(buf []byte) error {
+	switch b.Version {
+	case VersionBellatrix:
+		b.Bellatrix = new(SignedBuilderBid)
+		return b.Bellatrix.UnmarshalSSZ(buf)
+	case VersionCapella:
+		b.Capella = new(SignedBuilderBidCapella)
+		return b.Capella.UnmarshalSSZ(buf)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownVersion, b.Version)
+	}
+}
+
+func (b *VersionedSignedBuilderBid) HashTreeRoot() ([32]byte, error) {
+	signed, err := b.signed()
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return signed.HashTreeRoot()
+}
+
+func (b VersionedSignedBuilderBid) MarshalJSON() ([]byte, error) {
+	signed, err := b.signed()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := json.Marshal(signed)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&versionedJSON{Version: b.Version, Data: enc})
+}
+
+func (b *VersionedSignedBuilderBid) UnmarshalJSON(input []byte) error {
+	var dec VersionedSignedBuilderBid
+	version, err := unmarshalVersioned(input, map[string]interface{}{
+		VersionBellatrix: &dec.Bellatrix,
+		VersionCapella:   &dec.Capella,
+	})
+	if err != nil {
+		return err
+	}
+	dec.Version = version
+	if _, err := dec.signed(); err != nil {
+		return err
+	}
+	*b = dec
+	return nil
+}
+
+// VersionedSignedBlindedBeaconBlock is the signed blinded block of one of the forks, as in the
+// submitBlindedBlock request of the builder API.
+type VersionedSignedBlindedBeaconBlock struct {
+	Version   string
+	Bellatrix *SignedBlindedBeaconBlock
+	Capella   *SignedBlindedBeaconBlockCapella
+}
+
+func (b *VersionedSignedBlindedBeaconBlock) signed() (SSZObject, error) {
+	var signed SSZObject
+	switch b.Version {
+	case VersionBellatrix:
+		if b.Bellatrix != nil && b.Bellatrix.Message != nil && b.Bellatrix.Message.Body != nil && b.Bellatrix.Message.Body.ExecutionPayloadHeader != nil {
+			signed = b.Bellatrix
+		}
+	case VersionCapella:
+		if b.Capella != nil && b.Capella.Message != nil && b.Capella.Message.Body != nil && b.Capella.Message.Body.ExecutionPayloadHeader != nil {
+			signed = b.Capella
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownVersion, b.Version)
+	}
+	if signed == nil {
+		return nil, fmt.Errorf("%w %s", ErrEmptyVersioned, b.Version)
+	}
+	return signed, nil
+}
+
+// Message returns the blinded block, to compute the signing root of.
+func (b *VersionedSignedBlindedBeaconBlock) Message() (HashTreeRoot, error) {
+	if _, err := b.signed(); err != nil {
+		return nil, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message, nil
+	}
+	return b.Capella.Message, nil
+}
+
+// Signature returns the signature of the blinded block.
+func (b *VersionedSignedBlindedBeaconBlock) Signature() (Signature, error) {
+	if _, err := b.signed(); err != nil {
+		return Signature{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Signature, nil
+	}
+	return b.Capella.Signature, nil
+}
+
+// ParentHash returns the parent hash of the execution payload header in the blinded block.
+func (b *VersionedSignedBlindedBeaconBlock) ParentHash() (Hash, error) {
+	if _, err := b.signed(); err != nil {
+		return Hash{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Body.ExecutionPayloadHeader.ParentHash, nil
+	}
+	return b.Capella.Message.Body.ExecutionPayloadHeader.ParentHash, nil
+}
+
+// BlockHash returns the block hash of the execution payload header in the blinded block.
+func (b *VersionedSignedBlindedBeaconBlock) BlockHash() (Hash, error) {
+	if _, err := b.signed(); err != nil {
+		return Hash{}, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Body.ExecutionPayloadHeader.BlockHash, nil
+	}
+	return b.Capella.Message.Body.ExecutionPayloadHeader.BlockHash, nil
+}
+
+// Slot returns the slot of the blinded block.
+func (b *VersionedSignedBlindedBeaconBlock) Slot() (uint64, error) {
+	if _, err := b.signed(); err != nil {
+		return 0, err
+	}
+	if b.Version == VersionBellatrix {
+		return b.Bellatrix.Message.Slot, nil
+	}
+	return b.Capella.Message.Slot, nil
+}
+
+func (b *VersionedSignedBlindedBeaconBlock) MarshalSSZ() ([]byte, error) {
+	signed, err := b.signed()
+	if err != nil {
+		return nil, err
+	}
+	return signed.MarshalSSZ()
+}
+
+// UnmarshalSSZ decodes the signed blinded block of the fork in the Version field, which must be set.
+func (b *VersionedSignedBlindedBeaconBlock) UnmarshalSSZ(buf []byte) error {
+	switch b.Version {
+	case VersionBellatrix:
+		b.Bellatrix = new(SignedBlindedBeaconBlock)
+		if err := b.Bellatrix.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	case VersionCapella:
+		b.Capella = new(SignedBlindedBeaconBlockCapella)
+		if err := b.Capella.UnmarshalSSZ(buf); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownVersion, b.Version)
+	}
+	_, err := b.signed()
+	return err
+}
@@ -0,0 +1,175 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func testPayload(withdrawals Withdrawals) *ExecutionPayloadV3 {
+	p := &ExecutionPayloadV2{
+		ParentHash:    common.Hash{0x01},
+		FeeRecipient:  common.Address{0x02},
+		Number:        3,
+		GasLimit:      30_000_000,
+		Timestamp:     4,
+		ExtraData:     []byte{0x05},
+		BaseFeePerGas: big.NewInt(7),
+		BlockHash:     common.Hash{0x08},
+		Transactions:  [][]byte{{0x09}},
+		Withdrawals:   withdrawals,
+	}
+	return p.V3()
+}
+
+func TestVersionedExecutionPayload(t *testing.T) {
+	for version, withdrawals := range map[string]Withdrawals{
+		VersionBellatrix: nil,
+		VersionCapella:   {{Index: 1, Validator: 2, Address: common.Address{0x03}, Amount: 4}},
+	} {
+		payload := testPayload(withdrawals)
+		versioned, err := NewVersionedExecutionPayload(version, payload)
+		require.NoError(t, err)
+		require.Equal(t, version, versioned.Version)
+		require.Equal(t, Hash(payload.BlockHash), versioned.BlockHash())
+
+		enc, err := json.Marshal(versioned)
+		require.NoError(t, err)
+		var dec VersionedExecutionPayload
+		require.NoError(t, json.Unmarshal(enc, &dec))
+		require.Equal(t, versioned, &dec)
+
+		el, err := dec.ExecutionPayload()
+		require.NoError(t, err)
+		require.Equal(t, payload.BlockHash, el.BlockHash)
+		require.Equal(t, payload.BaseFeePerGas, el.BaseFeePerGas)
+		require.Equal(t, len(withdrawals), len(el.Withdrawals))
+
+		ssz, err := versioned.MarshalSSZ()
+		require.NoError(t, err)
+		sszDec := &VersionedExecutionPayload{Version: version}
+		require.NoError(t, sszDec.UnmarshalSSZ(ssz))
+		reenc, err := sszDec.MarshalSSZ()
+		require.NoError(t, err)
+		require.Equal(t, ssz, reenc)
+		require.Equal(t, versioned.BlockHash(), sszDec.BlockHash())
+	}
+
+	var dec VersionedExecutionPayload
+	err := json.Unmarshal([]byte(`{"version":"phase0","data":{}}`), &dec)
+	require.True(t, errors.Is(err, ErrUnknownVersion), err)
+	err = json.Unmarshal([]byte(`{"version":"capella","data":null}`), &dec)
+	require.True(t, errors.Is(err, ErrEmptyVersioned), err)
+	_, err = json.Marshal(&VersionedExecutionPayload{Version: VersionCapella, Bellatrix: &ExecutionPayloadREST{}})
+	require.Error(t, err)
+	_, err = NewVersionedExecutionPayload("phase0", testPayload(nil))
+	require.True(t, errors.Is(err, ErrUnknownVersion), err)
+}
+
+func TestVersionedSignedBuilderBid(t *testing.T) {
+	bellatrix, err := NewVersionedBuilderBid(VersionBellatrix, testPayload(nil), IntToU256(1), PublicKey{0x01})
+	require.NoError(t, err)
+	require.Equal(t, VersionBellatrix, bellatrix.Version)
+
+	withdrawals := Withdrawals{{Index: 1, Validator: 2, Address: common.Address{0x03}, Amount: 4}}
+	capella, err := NewVersionedBuilderBid(VersionCapella, testPayload(withdrawals), IntToU256(1), PublicKey{0x01})
+	require.NoError(t, err)
+	require.Equal(t, VersionCapella, capella.Version)
+	empty, err := NewVersionedBuilderBid(VersionCapella, testPayload(Withdrawals{}), IntToU256(1), PublicKey{0x01})
+	require.NoError(t, err)
+	require.NotEqual(t, empty.Capella.Message.Header.WithdrawalsRoot, capella.Capella.Message.Header.WithdrawalsRoot)
+
+	for _, bid := range []*VersionedSignedBuilderBid{bellatrix, capella} {
+		require.NoError(t, bid.SetSignature(Signature{0x02}))
+		sig, err := bid.Signature()
+		require.NoError(t, err)
+		require.Equal(t, Signature{0x02}, sig)
+		blockHash, err := bid.BlockHash()
+		require.NoError(t, err)
+		require.Equal(t, Hash{0x08}, blockHash)
+
+		enc, err := json.Marshal(bid)
+		require.NoError(t, err)
+		var dec VersionedSignedBuilderBid
+		require.NoError(t, json.Unmarshal(enc, &dec))
+		require.Equal(t, bid, &dec)
+
+		ssz, err := bid.MarshalSSZ()
+		require.NoError(t, err)
+		fromSSZ := VersionedSignedBuilderBid{Version: bid.Version}
+		require.NoError(t, fromSSZ.UnmarshalSSZ(ssz))
+		root, err := bid.HashTreeRoot()
+		require.NoError(t, err)
+		root2, err := fromSSZ.HashTreeRoot()
+		require.NoError(t, err)
+		require.Equal(t, root, root2)
+	}
+
+	// a bellatrix response decodes to the same bid
+	var resp GetHeaderResponse
+	enc, err := json.Marshal(bellatrix)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(enc, &resp))
+	require.Equal(t, bellatrix.Bellatrix, resp.Data)
+}
+
+func TestVersionedSignedBlindedBeaconBlock(t *testing.T) {
+	payload := testPayload(Withdrawals{{Index: 1, Validator: 2, Address: common.Address{0x03}, Amount: 4}})
+	header, err := PayloadToPayloadHeaderCapella(payload.V2())
+	require.NoError(t, err)
+	block := &VersionedSignedBlindedBeaconBlock{
+		Version: VersionCapella,
+		Capella: &SignedBlindedBeaconBlockCapella{
+			Message: &BlindedBeaconBlockCapella{
+				Slot: 1,
+				Body: &BlindedBeaconBlockBodyCapella{
+					Eth1Data:               &Eth1Data{},
+					SyncAggregate:          &SyncAggregate{},
+					ExecutionPayloadHeader: header,
+					BLSToExecutionChanges: []*SignedBLSToExecutionChange{
+						{Message: &BLSToExecutionChange{ValidatorIndex: 5, ToExecutionAddress: Address{0x06}}},
+					},
+				},
+			},
+			Signature: Signature{0x07},
+		},
+	}
+	enc, err := block.MarshalSSZ()
+	require.NoError(t, err)
+
+	dec := &VersionedSignedBlindedBeaconBlock{Version: VersionCapella}
+	require.NoError(t, dec.UnmarshalSSZ(enc))
+	reenc, err := dec.MarshalSSZ()
+	require.NoError(t, err)
+	require.Equal(t, enc, reenc)
+	parent, err := dec.ParentHash()
+	require.NoError(t, err)
+	require.Equal(t, Hash(payload.ParentHash), parent)
+	sig, err := dec.Signature()
+	require.NoError(t, err)
+	require.Equal(t, Signature{0x07}, sig)
+
+	// the bellatrix layout does not fit
+	require.Error(t, (&VersionedSignedBlindedBeaconBlock{Version: VersionBellatrix}).UnmarshalSSZ(enc))
+	err = (&VersionedSignedBlindedBeaconBlock{Version: "phase0"}).UnmarshalSSZ(enc)
+	require.True(t, errors.Is(err, ErrUnknownVersion), err)
+}
+
+func TestSignedValidatorRegistrations(t *testing.T) {
+	regs := []SignedValidatorRegistration{
+		{Message: &RegisterValidatorRequestMessage{FeeRecipient: Address{0x01}, GasLimit: 2, Timestamp: 3, Pubkey: PublicKey{0x04}}, Signature: Signature{0x05}},
+		{Message: &RegisterValidatorRequestMessage{FeeRecipient: Address{0x06}, GasLimit: 7, Timestamp: 8, Pubkey: PublicKey{0x09}}, Signature: Signature{0x0a}},
+	}
+	enc, err := MarshalSignedValidatorRegistrations(regs)
+	require.NoError(t, err)
+	require.Len(t, enc, 2*180)
+	dec, err := UnmarshalSignedValidatorRegistrations(enc)
+	require.NoError(t, err)
+	require.Equal(t, regs, dec)
+	_, err = UnmarshalSignedValidatorRegistrations(enc[:200])
+	require.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ExecutionWitness is the state a payload accesses, to execute it without the state of its
+// parent: the trie nodes of the accounts and storage slots it reads and writes, the code it runs,
+// and the headers of its parent and of the blocks it reads the hash of.
+//
+// Its RLP encoding is the opaque witness of engine_executeStatelessPayload.
+type ExecutionWitness struct {
+	Headers []*types.Header `json:"headers"`
+	Codes   []hexutil.Bytes `json:"codes"`
+	State   []hexutil.Bytes `json:"state"`
+}
+
+// DecodeExecutionWitness decodes the opaque witness of engine_executeStatelessPayload.
+func DecodeExecutionWitness(data []byte) (*ExecutionWitness, error) {
+	var w ExecutionWitness
+	if err := rlp.DecodeBytes(data, &w); err != nil {
+		return nil, fmt.Errorf("invalid execution witness: %v", err)
+	}
+	return &w, nil
+}
+
+// Encode returns the opaque witness of engine_executeStatelessPayload.
+func (w *ExecutionWitness) Encode() ([]byte, error) {
+	return rlp.EncodeToBytes(w)
+}
+
+// Header returns the header of the witness with the hash, nil if it has none.
+func (w *ExecutionWitness) Header(hash common.Hash) *types.Header {
+	for _, h := range w.Headers {
+		if h.Hash() == hash {
+			return h
+		}
+	}
+	return nil
+}
+
+// Nodes returns the trie nodes of the witness by their hash.
+func (w *ExecutionWitness) Nodes() map[common.Hash][]byte {
+	nodes := make(map[common.Hash][]byte, len(w.State))
+	for _, node := range w.State {
+		nodes[crypto.Keccak256Hash(node)] = node
+	}
+	return nodes
+}
+
+// StatelessPayloadStatusV1 is the result of engine_executeStatelessPayload: the status of the
+// payload, and the state and receipts roots the engine computed from the witness.
+type StatelessPayloadStatusV1 struct {
+	Status          ExecutePayloadStatus `json:"status"`
+	StateRoot       common.Hash          `json:"stateRoot"`
+	ReceiptsRoot    common.Hash          `json:"receiptsRoot"`
+	ValidationError string               `json:"validationError"`
+}